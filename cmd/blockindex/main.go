@@ -6,31 +6,74 @@ import (
 	"log"
 	"net"
 
+	"bharani/pkg/auth"
 	"bharani/pkg/blockindex"
+	"bharani/pkg/config"
+	"bharani/pkg/consensus"
 	blockindexpb "bharani/proto/blockindex"
+	consensuspb "bharani/proto/consensus"
 
 	"google.golang.org/grpc"
 )
 
 func main() {
 	port := flag.String("port", "9091", "BlockIndex server port")
-	dbPath := flag.String("db", "./data/blockindex.db", "Database file path")
+	dbPath := flag.String("db", "./data/blockindex.db", "Index database: a SQLite file path, or a sqlite://, bolt:// or memory:// URL")
+	raftID := flag.String("raft-id", "", "This replica's gRPC address as the other replicas reach it")
+	raftPeers := flag.String("raft-peers", "", "Comma-separated id=raftaddr pairs of every replica (empty to run unreplicated)")
+	raftDir := flag.String("raft-dir", "./data/blockindex-raft", "Raft log and snapshot directory")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
 	flag.Parse()
 
-	index, err := blockindex.NewIndex(*dbPath)
+	peers, err := consensus.ParsePeers(*raftPeers)
+	if err != nil {
+		log.Fatalf("Invalid raft peers: %v", err)
+	}
+	if len(peers) > 0 {
+		// A replica rebuilds its index from the raft snapshots and log
+		*dbPath, err = consensus.StatePath(*raftDir, "blockindex.db")
+		if err != nil {
+			log.Fatalf("Failed to prepare raft directory: %v", err)
+		}
+	}
+
+	index, err := blockindex.Open(*dbPath)
 	if err != nil {
 		log.Fatalf("Failed to create index: %v", err)
 	}
 	defer index.Close()
+	indexService := blockindex.NewBlockIndexService(index)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", *port))
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	indexService := blockindex.NewBlockIndexService(index)
+	opts, err := auth.ServerOptions(*tlsConfig, auth.DefaultPolicy())
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	var node *consensus.Node
+	if len(peers) > 0 {
+		node, err = consensus.NewNode(consensus.Config{ID: *raftID, Peers: peers, Dir: *raftDir, TLS: *tlsConfig}, index, consensus.Service{
+			Desc:  &blockindexpb.BlockIndexService_ServiceDesc,
+			Impl:  indexService,
+			Reads: blockindex.ReadMethods,
+			Stamp: blockindex.StampRequest,
+		})
+		if err != nil {
+			log.Fatalf("Failed to start raft: %v", err)
+		}
+		defer node.Close()
+		opts = append(opts, grpc.ChainUnaryInterceptor(node.UnaryServerInterceptor()))
+	}
+
+	s := grpc.NewServer(opts...)
 	blockindexpb.RegisterBlockIndexServiceServer(s, indexService)
+	if node != nil {
+		consensuspb.RegisterConsensusServiceServer(s, node)
+	}
 
 	log.Printf("BlockIndex server listening on :%s", *port)
 	if err := s.Serve(lis); err != nil {
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bharani/pkg/auth"
+)
+
+// identity is a certificate devca issues by default
+type identity struct {
+	name string
+	role auth.Role
+}
+
+// identities covers every service. The block index and replication table only
+// call their own replicas, when they run as a Raft group.
+var identities = []identity{
+	{"frontend", auth.RoleFrontend},
+	{"gateway", auth.RoleFrontend},
+	{"s3gateway", auth.RoleFrontend},
+	{"master", auth.RoleMaster},
+	{"osd", auth.RoleOSD},
+	{"blockindex", auth.RolePeer},
+	{"replication", auth.RolePeer},
+	{"gc", auth.RoleAdmin},
+	{"volumemanager", auth.RoleAdmin},
+	{"admin", auth.RoleAdmin},
+	{"client", auth.RoleClient},
+}
+
+func main() {
+	out := flag.String("out", "./certs", "Directory for the CA and certificates")
+	hosts := flag.String("hosts", "", "Extra comma-separated host names or IPs added to every certificate")
+	validFor := flag.Duration("valid", 365*24*time.Hour, "Certificate lifetime")
+	name := flag.String("name", "", "Issue a single certificate with this name instead of the default set")
+	role := flag.String("role", "", "Role of the certificate issued with -name")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+
+	ca, err := loadOrCreateCA(*out, *validFor)
+	if err != nil {
+		log.Fatalf("Failed to set up CA: %v", err)
+	}
+
+	issue := identities
+	if *name != "" {
+		issue = []identity{{*name, auth.Role(*role)}}
+	}
+
+	extra := make([]string, 0)
+	for _, host := range strings.Split(*hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			extra = append(extra, host)
+		}
+	}
+
+	for _, id := range issue {
+		sans := append([]string{id.name, "localhost", "127.0.0.1"}, extra...)
+		certPEM, keyPEM, err := ca.Issue(id.name, id.role, sans, *validFor)
+		if err != nil {
+			log.Fatalf("Failed to issue certificate for %s: %v", id.name, err)
+		}
+		if err := os.WriteFile(filepath.Join(*out, id.name+".pem"), certPEM, 0644); err != nil {
+			log.Fatalf("Failed to write certificate: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(*out, id.name+"-key.pem"), keyPEM, 0600); err != nil {
+			log.Fatalf("Failed to write key: %v", err)
+		}
+		fmt.Printf("Issued %s.pem (role %q)\n", id.name, id.role)
+	}
+}
+
+// loadOrCreateCA reuses the CA in dir so that reissuing certificates keeps
+// existing ones valid, or creates a new one
+func loadOrCreateCA(dir string, validFor time.Duration) (*auth.CA, error) {
+	certFile := filepath.Join(dir, "ca.pem")
+	keyFile := filepath.Join(dir, "ca-key.pem")
+
+	if _, err := os.Stat(certFile); err == nil {
+		return auth.LoadCA(certFile, keyFile)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ca, err := auth.NewCA("bharani dev CA", validFor)
+	if err != nil {
+		return nil, err
+	}
+	if err := ca.WriteFiles(dir); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Created CA in %s\n", certFile)
+	return ca, nil
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 
+	"bharani/pkg/auth"
 	"bharani/pkg/config"
 	"bharani/pkg/frontend"
 	frontendpb "bharani/proto/frontend"
@@ -18,9 +19,19 @@ func main() {
 	blockIndexAddr := flag.String("blockindex", "localhost:9091", "BlockIndex address")
 	replicationAddr := flag.String("replication", "localhost:9092", "ReplicationTable address")
 	masterAddr := flag.String("master", "localhost:9093", "Master address")
+	apiKeys := flag.String("api-keys", "", "Client API key file, one \"<key> <tenant> [admin]\" per line; defaults to API_KEYS_FILE")
+	limits := flag.String("limits", "", "Per-tenant quota and rate limit file; defaults to LIMITS_FILE")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
 	flag.Parse()
 
 	cfg := config.DefaultConfig()
+	cfg.TLS = *tlsConfig
+	if *apiKeys != "" {
+		cfg.APIKeysFile = *apiKeys
+	}
+	if *limits != "" {
+		cfg.LimitsFile = *limits
+	}
 
 	frontendInstance, err := frontend.NewFrontend(cfg, *blockIndexAddr, *replicationAddr, *masterAddr)
 	if err != nil {
@@ -32,7 +43,18 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
+	opts, err := auth.ServerOptions(*tlsConfig, auth.DefaultPolicy())
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	authenticator, err := auth.LoadAuthenticator(cfg.APIKeysFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatalf("Failed to load client credentials: %v", err)
+	}
+	opts = append(opts, authenticator.ServerOptions()...)
+
+	s := grpc.NewServer(opts...)
 	frontendService := frontend.NewFrontendService(frontendInstance)
 	frontendpb.RegisterFrontendServiceServer(s, frontendService)
 
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/config"
+	"bharani/pkg/frontend"
+	"bharani/pkg/gateway"
+)
+
+func main() {
+	port := flag.String("port", "8081", "HTTP gateway port")
+	blockIndexAddr := flag.String("blockindex", "localhost:9091", "BlockIndex address")
+	replicationAddr := flag.String("replication", "localhost:9092", "ReplicationTable address")
+	masterAddr := flag.String("master", "localhost:9093", "Master address")
+	apiKeys := flag.String("api-keys", "", "Client API key file, one \"<key> <tenant> [admin]\" per line; defaults to API_KEYS_FILE")
+	limits := flag.String("limits", "", "Per-tenant quota and rate limit file; defaults to LIMITS_FILE")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg := config.DefaultConfig()
+	cfg.TLS = *tlsConfig
+	if *apiKeys != "" {
+		cfg.APIKeysFile = *apiKeys
+	}
+	if *limits != "" {
+		cfg.LimitsFile = *limits
+	}
+
+	frontendInstance, err := frontend.NewFrontend(cfg, *blockIndexAddr, *replicationAddr, *masterAddr)
+	if err != nil {
+		log.Fatalf("Failed to create frontend: %v", err)
+	}
+
+	authenticator, err := auth.LoadAuthenticator(cfg.APIKeysFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatalf("Failed to load client credentials: %v", err)
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%s", *port),
+		Handler: authenticator.HTTPHandler(gateway.NewGateway(frontendInstance, cfg)),
+	}
+
+	log.Printf("HTTP gateway listening on :%s", *port)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
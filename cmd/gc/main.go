@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"bharani/pkg/config"
+	"bharani/pkg/gc"
+)
+
+func main() {
+	cellID := flag.String("cell", "cell1", "Cell ID")
+	blockIndexAddr := flag.String("blockindex", "localhost:9091", "BlockIndex address")
+	replicationAddr := flag.String("replication", "localhost:9092", "ReplicationTable address")
+	masterAddr := flag.String("master", "localhost:9093", "Master address")
+	grace := flag.Duration("grace", 0, "How long a block stays unreferenced before deletion (default from config)")
+	intentTimeout := flag.Duration("intent-timeout", 0, "Age after which an uncommitted Put is completed or cleaned up (default from config)")
+	threshold := flag.Float64("compact-threshold", 0, "Fraction of volume size below which closed volumes are compacted (default from config)")
+	interval := flag.Duration("interval", 10*time.Minute, "Time between collection passes")
+	once := flag.Bool("once", false, "Make a single pass and exit")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg := config.DefaultConfig()
+	cfg.TLS = *tlsConfig
+	cfg.CellID = *cellID
+	if *grace > 0 {
+		cfg.GCGracePeriod = *grace
+	}
+	if *intentTimeout > 0 {
+		cfg.IntentTimeout = *intentTimeout
+	}
+	if *threshold > 0 {
+		cfg.CompactThreshold = *threshold
+	}
+
+	collector, err := gc.NewCollector(cfg, *blockIndexAddr, *replicationAddr, *masterAddr)
+	if err != nil {
+		log.Fatalf("Failed to create collector: %v", err)
+	}
+
+	ctx := context.Background()
+	if *once {
+		stats, err := collector.RunOnce(ctx)
+		if err != nil {
+			log.Fatalf("Garbage collection failed: %v", err)
+		}
+		log.Printf("Deleted %d blocks (%d bytes), relocated %d, deleted %d volumes, committed %d and aborted %d intents, %d errors",
+			stats.BlocksDeleted, stats.BytesFreed, stats.BlocksRelocated, stats.VolumesDeleted,
+			stats.IntentsCommitted, stats.IntentsAborted, stats.Errors)
+		return
+	}
+
+	log.Printf("Garbage collector running every %v with a %v grace period", *interval, cfg.GCGracePeriod)
+	collector.Run(ctx, *interval)
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"time"
 
+	"bharani/pkg/auth"
 	"bharani/pkg/config"
 	"bharani/pkg/master"
 	masterpb "bharani/proto/master"
@@ -18,9 +20,13 @@ func main() {
 	port := flag.String("port", "9093", "Master server port")
 	cellID := flag.String("cell", "cell1", "Cell ID")
 	replicationAddr := flag.String("replication", "localhost:9092", "ReplicationTable address")
+	blockIndexAddr := flag.String("blockindex", "localhost:9091", "BlockIndex address serving the whole index until it is sharded")
+	shardsPath := flag.String("shards", "", "File keeping the block index shard map; empty disables sharding")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
 	flag.Parse()
 
 	cfg := config.DefaultConfig()
+	cfg.TLS = *tlsConfig
 	cfg.CellID = *cellID
 
 	masterInstance, err := master.NewMaster(cfg, *cellID, *replicationAddr)
@@ -30,14 +36,26 @@ func main() {
 	defer masterInstance.Close()
 
 	ctx := context.Background()
+	if *shardsPath != "" {
+		if err := masterInstance.LoadShards(*shardsPath, *blockIndexAddr); err != nil {
+			log.Fatalf("Failed to load index shard map: %v", err)
+		}
+		masterInstance.ResumeMigrations(ctx)
+	}
 	go masterInstance.MonitorOSDs(ctx)
+	go masterInstance.RunReplicaRepair(ctx, 30*time.Second)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", *port))
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
+	opts, err := auth.ServerOptions(*tlsConfig, auth.DefaultPolicy())
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	s := grpc.NewServer(opts...)
 	masterService := master.NewMasterService(masterInstance)
 	masterpb.RegisterMasterServiceServer(s, masterService)
 
@@ -46,4 +64,3 @@ func main() {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
-
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"time"
 
+	"bharani/pkg/auth"
 	"bharani/pkg/config"
 	"bharani/pkg/osd"
+	masterpb "bharani/proto/master"
 	osdpb "bharani/proto/osd"
 
 	"google.golang.org/grpc"
@@ -18,24 +22,49 @@ func main() {
 	address := flag.String("address", "localhost:9090", "OSD address")
 	cellID := flag.String("cell", "cell1", "Cell ID")
 	dataDir := flag.String("data-dir", "./data/osd", "Data directory for blocks")
+	masterAddr := flag.String("master", "localhost:9093", "Master address")
+	zoneID := flag.String("zone", "", "Zone (failure domain) of this OSD, defaults to ZONE_ID")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
 	flag.Parse()
 
 	cfg := config.DefaultConfig()
+	cfg.TLS = *tlsConfig
 	cfg.OSDPort = *port
 	cfg.OSDDataDir = *dataDir
 	cfg.CellID = *cellID
+	if *zoneID != "" {
+		cfg.ZoneID = *zoneID
+	}
 
 	osdInstance, err := osd.NewOSD(cfg, *address, *cellID)
 	if err != nil {
 		log.Fatalf("Failed to create OSD: %v", err)
 	}
 
+	dialOption, err := cfg.TLS.DialOption()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	masterConn, err := grpc.NewClient(*masterAddr, dialOption)
+	if err != nil {
+		log.Fatalf("Failed to connect to master: %v", err)
+	}
+	defer masterConn.Close()
+
+	go osdInstance.RunHeartbeat(context.Background(), masterpb.NewMasterServiceClient(masterConn), 10*time.Second)
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", *port))
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
+	opts, err := auth.ServerOptions(*tlsConfig, auth.DefaultPolicy())
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	s := grpc.NewServer(opts...)
 	osdService := osd.NewOSDService(osdInstance)
 	osdpb.RegisterOSDServiceServer(s, osdService)
 
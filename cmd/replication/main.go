@@ -6,7 +6,11 @@ import (
 	"log"
 	"net"
 
+	"bharani/pkg/auth"
+	"bharani/pkg/config"
+	"bharani/pkg/consensus"
 	"bharani/pkg/replication"
+	consensuspb "bharani/proto/consensus"
 	replicationpb "bharani/proto/replication"
 
 	"google.golang.org/grpc"
@@ -15,22 +19,61 @@ import (
 func main() {
 	port := flag.String("port", "9092", "ReplicationTable server port")
 	dbPath := flag.String("db", "./data/replication.db", "Database file path")
+	raftID := flag.String("raft-id", "", "This replica's gRPC address as the other replicas reach it")
+	raftPeers := flag.String("raft-peers", "", "Comma-separated id=raftaddr pairs of every replica (empty to run unreplicated)")
+	raftDir := flag.String("raft-dir", "./data/replication-raft", "Raft log and snapshot directory")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
 	flag.Parse()
 
+	peers, err := consensus.ParsePeers(*raftPeers)
+	if err != nil {
+		log.Fatalf("Invalid raft peers: %v", err)
+	}
+	if len(peers) > 0 {
+		// A replica rebuilds its table from the raft snapshots and log
+		*dbPath, err = consensus.StatePath(*raftDir, "replication.db")
+		if err != nil {
+			log.Fatalf("Failed to prepare raft directory: %v", err)
+		}
+	}
+
 	table, err := replication.NewTable(*dbPath)
 	if err != nil {
 		log.Fatalf("Failed to create table: %v", err)
 	}
 	defer table.Close()
+	tableService := replication.NewReplicationTableService(table)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", *port))
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	tableService := replication.NewReplicationTableService(table)
+	opts, err := auth.ServerOptions(*tlsConfig, auth.DefaultPolicy())
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	var node *consensus.Node
+	if len(peers) > 0 {
+		node, err = consensus.NewNode(consensus.Config{ID: *raftID, Peers: peers, Dir: *raftDir, TLS: *tlsConfig}, table, consensus.Service{
+			Desc:  &replicationpb.ReplicationTableService_ServiceDesc,
+			Impl:  tableService,
+			Reads: replication.ReadMethods,
+			Stamp: replication.StampRequest,
+		})
+		if err != nil {
+			log.Fatalf("Failed to start raft: %v", err)
+		}
+		defer node.Close()
+		opts = append(opts, grpc.ChainUnaryInterceptor(node.UnaryServerInterceptor()))
+	}
+
+	s := grpc.NewServer(opts...)
 	replicationpb.RegisterReplicationTableServiceServer(s, tableService)
+	if node != nil {
+		consensuspb.RegisterConsensusServiceServer(s, node)
+	}
 
 	log.Printf("ReplicationTable server listening on :%s", *port)
 	if err := s.Serve(lis); err != nil {
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/config"
+	"bharani/pkg/frontend"
+	"bharani/pkg/s3gateway"
+)
+
+func main() {
+	port := flag.String("port", "8082", "S3 gateway port")
+	dbPath := flag.String("db", "./s3gateway.db", "Object metadata database path")
+	memory := flag.Bool("memory", false, "Keep blocks in memory instead of storing them in the cluster")
+	blockIndexAddr := flag.String("blockindex", "localhost:9091", "BlockIndex address")
+	replicationAddr := flag.String("replication", "localhost:9092", "ReplicationTable address")
+	masterAddr := flag.String("master", "localhost:9093", "Master address")
+	apiKeys := flag.String("api-keys", "", "Client API key file, one \"<key> <tenant> [admin]\" per line; defaults to API_KEYS_FILE")
+	limits := flag.String("limits", "", "Per-tenant quota and rate limit file; defaults to LIMITS_FILE")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg := config.DefaultConfig()
+	cfg.TLS = *tlsConfig
+	if *apiKeys != "" {
+		cfg.APIKeysFile = *apiKeys
+	}
+	if *limits != "" {
+		cfg.LimitsFile = *limits
+	}
+
+	var backend s3gateway.Backend
+	if *memory {
+		backend = s3gateway.NewMemoryBackend(cfg.MaxBlockSize)
+	} else {
+		frontendInstance, err := frontend.NewFrontend(cfg, *blockIndexAddr, *replicationAddr, *masterAddr)
+		if err != nil {
+			log.Fatalf("Failed to create frontend: %v", err)
+		}
+		backend = frontendInstance
+	}
+
+	store, err := s3gateway.NewStore(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open metadata store: %v", err)
+	}
+	defer store.Close()
+
+	authenticator, err := auth.LoadAuthenticator(cfg.APIKeysFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatalf("Failed to load client credentials: %v", err)
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%s", *port),
+		Handler: authenticator.HTTPHandler(s3gateway.NewGateway(backend, store)),
+	}
+
+	log.Printf("S3 gateway listening on :%s", *port)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
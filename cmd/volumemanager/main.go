@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 
+	"bharani/pkg/auth"
 	"bharani/pkg/config"
 	"bharani/pkg/volumemanager"
 
@@ -14,9 +15,11 @@ import (
 
 func main() {
 	port := flag.String("port", "9094", "VolumeManager server port")
+	tlsConfig := config.RegisterTLSFlags(flag.CommandLine)
 	flag.Parse()
 
 	cfg := config.DefaultConfig()
+	cfg.TLS = *tlsConfig
 
 	_, err := volumemanager.NewManager(cfg)
 	if err != nil {
@@ -28,7 +31,12 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
+	opts, err := auth.ServerOptions(*tlsConfig, auth.DefaultPolicy())
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	s := grpc.NewServer(opts...)
 
 	log.Printf("VolumeManager server listening on :%s", *port)
 	if err := s.Serve(lis); err != nil {
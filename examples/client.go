@@ -1,3 +1,5 @@
+//go:build ignore
+
 package main
 
 import (
@@ -5,46 +7,30 @@ import (
 	"fmt"
 	"log"
 
-	"bharani/proto/frontend"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"bharani/pkg/client"
 )
 
 func main() {
-	conn, err := grpc.NewClient("localhost:8080", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	c, err := client.New("localhost:8080", client.DefaultOptions())
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
-	defer conn.Close()
+	defer c.Close()
 
-	client := frontend.NewFrontendServiceClient(conn)
 	ctx := context.Background()
 
 	putData := []byte("Hello, Magic Pocket!")
-	putResp, err := client.Put(ctx, &frontend.PutRequest{
-		Data: putData,
-	})
+	hash, err := c.Put(ctx, putData)
 	if err != nil {
 		log.Fatalf("Put failed: %v", err)
 	}
 
-	if !putResp.Success {
-		log.Fatalf("Put failed: %s", putResp.Error)
-	}
-
-	fmt.Printf("Put successful! Hash: %s\n", putResp.Hash)
+	fmt.Printf("Put successful! Hash: %s\n", hash)
 
-	getResp, err := client.Get(ctx, &frontend.GetRequest{
-		Hash: putResp.Hash,
-	})
+	data, err := c.Get(ctx, hash)
 	if err != nil {
 		log.Fatalf("Get failed: %v", err)
 	}
 
-	if !getResp.Success {
-		log.Fatalf("Get failed: %s", getResp.Error)
-	}
-
-	fmt.Printf("Get successful! Data: %s\n", string(getResp.Data))
+	fmt.Printf("Get successful! Data: %s\n", string(data))
 }
@@ -1,3 +1,5 @@
+//go:build ignore
+
 package main
 
 import (
@@ -9,7 +11,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"strings"
 
 	"bharani/proto/frontend"
 
@@ -17,7 +18,8 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-const MaxBlockSize = 3 * 1024 * 1024
+// uploadChunkSize is the size of each PutFile stream message
+const uploadChunkSize = 1024 * 1024
 
 type FileStorage struct {
 	client frontend.FrontendServiceClient
@@ -36,93 +38,89 @@ func NewFileStorage(addr string) (*FileStorage, error) {
 	}, nil
 }
 
-// PutFile uploads a file, splitting it into blocks if necessary
-func (fs *FileStorage) PutFile(filePath string) ([]string, error) {
+// PutFile streams a file to the frontend, which splits it into blocks and
+// returns the hash of the file's root manifest
+func (fs *FileStorage) PutFile(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return "", fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
 	fileInfo, err := file.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("failed to stat file: %w", err)
+		return "", fmt.Errorf("failed to stat file: %w", err)
 	}
 
 	fmt.Printf("Uploading file: %s (size: %d bytes)\n", filePath, fileInfo.Size())
 
-	var blockHashes []string
-	buffer := make([]byte, MaxBlockSize)
-	blockNum := 0
+	stream, err := fs.client.PutFile(fs.ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open upload stream: %w", err)
+	}
 
+	buffer := make([]byte, uploadChunkSize)
 	for {
 		n, err := file.Read(buffer)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to read file: %w", err)
+		if n > 0 {
+			if sendErr := stream.Send(&frontend.PutFileRequest{Data: buffer[:n]}); sendErr != nil {
+				return "", fmt.Errorf("failed to send file data: %w", sendErr)
+			}
 		}
-
-		if n == 0 {
+		if err == io.EOF {
 			break
 		}
-
-		blockData := buffer[:n]
-		blockNum++
-
-		fmt.Printf("  Uploading block %d (%d bytes)...\n", blockNum, len(blockData))
-
-		putResp, err := fs.client.Put(fs.ctx, &frontend.PutRequest{
-			Data: blockData,
-		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to put block %d: %w", blockNum, err)
+			return "", fmt.Errorf("failed to read file: %w", err)
 		}
+	}
 
-		if !putResp.Success {
-			return nil, fmt.Errorf("put block %d failed: %s", blockNum, putResp.Error)
-		}
+	putResp, err := stream.CloseAndRecv()
+	if err != nil {
+		return "", fmt.Errorf("failed to finish upload: %w", err)
+	}
 
-		blockHashes = append(blockHashes, putResp.Hash)
-		fmt.Printf("    Block %d stored with hash: %s\n", blockNum, putResp.Hash)
+	if !putResp.Success {
+		return "", fmt.Errorf("put file failed: %s", putResp.Error)
 	}
 
-	return blockHashes, nil
+	fmt.Printf("  Stored %d bytes in %d blocks\n", putResp.Size, putResp.BlockCount)
+	return putResp.Hash, nil
 }
 
-// GetFile downloads a file by its block hashes
-func (fs *FileStorage) GetFile(blockHashes []string, outputPath string) error {
+// GetFile downloads a file by its root manifest hash
+func (fs *FileStorage) GetFile(hash string, outputPath string) error {
 	outputFile, err := os.Create(outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer outputFile.Close()
 
-	fmt.Printf("Downloading file to: %s (%d blocks)\n", outputPath, len(blockHashes))
+	fmt.Printf("Downloading file to: %s (manifest: %s)\n", outputPath, hash)
 
-	for i, hash := range blockHashes {
-		fmt.Printf("  Downloading block %d/%d (hash: %s)...\n", i+1, len(blockHashes), hash)
+	stream, err := fs.client.GetFile(fs.ctx, &frontend.GetFileRequest{Hash: hash})
+	if err != nil {
+		return fmt.Errorf("failed to open download stream: %w", err)
+	}
 
-		getResp, err := fs.client.Get(fs.ctx, &frontend.GetRequest{
-			Hash: hash,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to get block %d: %w", i+1, err)
+	var total int64
+	for {
+		getResp, err := stream.Recv()
+		if err == io.EOF {
+			break
 		}
-
-		if !getResp.Success {
-			return fmt.Errorf("get block %d failed: %s", i+1, getResp.Error)
+		if err != nil {
+			return fmt.Errorf("failed to receive file data: %w", err)
 		}
 
-		_, err = outputFile.Write(getResp.Data)
+		n, err := outputFile.Write(getResp.Data)
 		if err != nil {
-			return fmt.Errorf("failed to write block %d: %w", i+1, err)
+			return fmt.Errorf("failed to write file data: %w", err)
 		}
-
-		fmt.Printf("    Block %d downloaded (%d bytes)\n", i+1, len(getResp.Data))
+		total += int64(n)
 	}
 
+	fmt.Printf("  Downloaded %d bytes\n", total)
 	return nil
 }
 
@@ -165,7 +163,7 @@ func main() {
 	action := flag.String("action", "put", "Action: put, get, test, or verify")
 	filePath := flag.String("file", "", "File path to upload/download")
 	outputPath := flag.String("output", "", "Output file path for download")
-	hashesFile := flag.String("hashes", "", "File containing block hashes (one per line)")
+	fileHash := flag.String("hash", "", "File hash returned by put")
 	addr := flag.String("addr", "localhost:8080", "Frontend address")
 	flag.Parse()
 
@@ -180,50 +178,23 @@ func main() {
 			log.Fatal("--file is required for put action")
 		}
 
-		blockHashes, err := fs.PutFile(*filePath)
+		hash, err := fs.PutFile(*filePath)
 		if err != nil {
 			log.Fatalf("Put failed: %v", err)
 		}
 
 		fmt.Printf("\n✓ Upload successful!\n")
-		fmt.Printf("Block hashes (%d blocks):\n", len(blockHashes))
-		for i, hash := range blockHashes {
-			fmt.Printf("  Block %d: %s\n", i+1, hash)
-		}
-
-		hashesOutput := *filePath + ".hashes"
-		hashesFile, err := os.Create(hashesOutput)
-		if err == nil {
-			for _, hash := range blockHashes {
-				fmt.Fprintln(hashesFile, hash)
-			}
-			hashesFile.Close()
-			fmt.Printf("\nHashes saved to: %s\n", hashesOutput)
-		}
+		fmt.Printf("File hash: %s\n", hash)
 
 	case "get":
-		if *hashesFile == "" {
-			log.Fatal("--hashes is required for get action")
+		if *fileHash == "" {
+			log.Fatal("--hash is required for get action")
 		}
 		if *outputPath == "" {
 			log.Fatal("--output is required for get action")
 		}
 
-		hashesData, err := os.ReadFile(*hashesFile)
-		if err != nil {
-			log.Fatalf("Failed to read hashes file: %v", err)
-		}
-
-		hashes := strings.Split(strings.TrimSpace(string(hashesData)), "\n")
-		var blockHashes []string
-		for _, h := range hashes {
-			h = strings.TrimSpace(h)
-			if h != "" {
-				blockHashes = append(blockHashes, h)
-			}
-		}
-
-		err = fs.GetFile(blockHashes, *outputPath)
+		err := fs.GetFile(*fileHash, *outputPath)
 		if err != nil {
 			log.Fatalf("Get failed: %v", err)
 		}
@@ -236,23 +207,14 @@ func main() {
 		}
 
 		fmt.Println("=== UPLOAD TEST ===")
-		blockHashes, err := fs.PutFile(*filePath)
+		hash, err := fs.PutFile(*filePath)
 		if err != nil {
 			log.Fatalf("Upload failed: %v", err)
 		}
 
-		hashesOutput := *filePath + ".hashes"
-		hashesFile, err := os.Create(hashesOutput)
-		if err == nil {
-			for _, hash := range blockHashes {
-				fmt.Fprintln(hashesFile, hash)
-			}
-			hashesFile.Close()
-		}
-
 		fmt.Println("\n=== DOWNLOAD TEST ===")
 		outputPath := *filePath + ".downloaded"
-		err = fs.GetFile(blockHashes, outputPath)
+		err = fs.GetFile(hash, outputPath)
 		if err != nil {
 			log.Fatalf("Download failed: %v", err)
 		}
@@ -266,7 +228,7 @@ func main() {
 		fmt.Printf("\n✓ All tests passed!\n")
 		fmt.Printf("Original: %s\n", *filePath)
 		fmt.Printf("Downloaded: %s\n", outputPath)
-		fmt.Printf("Hashes: %s\n", hashesOutput)
+		fmt.Printf("File hash: %s\n", hash)
 
 	case "verify":
 		if *filePath == "" || *outputPath == "" {
@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bharani/pkg/blockindex"
+	"bharani/pkg/config"
+	blockindexpb "bharani/proto/blockindex"
+	osdpb "bharani/proto/osd"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// writeIdentity issues a certificate for role and writes it with its key and
+// the CA certificate to dir, returning the matching TLS config
+func writeIdentity(t *testing.T, ca *CA, dir, name string, role Role) config.TLSConfig {
+	t.Helper()
+
+	certPEM, keyPEM, err := ca.Issue(name, role, []string{"localhost", "127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue %s certificate: %v", name, err)
+	}
+
+	tlsConfig := config.TLSConfig{
+		CAFile:   filepath.Join(dir, name+"-ca.pem"),
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+	for path, data := range map[string][]byte{
+		tlsConfig.CAFile:   ca.CertPEM(),
+		tlsConfig.CertFile: certPEM,
+		tlsConfig.KeyFile:  keyPEM,
+	} {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	return tlsConfig
+}
+
+// dial connects to addr with the given identity
+func dial(t *testing.T, addr string, tlsConfig config.TLSConfig) *grpc.ClientConn {
+	t.Helper()
+
+	dialOption, err := tlsConfig.DialOption()
+	if err != nil {
+		t.Fatalf("Failed to load client credentials: %v", err)
+	}
+	conn, err := grpc.NewClient(addr, dialOption)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestMutualTLSAuthorization(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := NewCA("test CA", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	if err := ca.WriteFiles(dir); err != nil {
+		t.Fatalf("Failed to write CA: %v", err)
+	}
+	if _, err := LoadCA(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem")); err != nil {
+		t.Fatalf("Failed to reload CA: %v", err)
+	}
+
+	index := blockindex.NewMemoryIndex()
+	defer index.Close()
+
+	opts, err := ServerOptions(writeIdentity(t, ca, dir, "blockindex", ""), DefaultPolicy())
+	if err != nil {
+		t.Fatalf("Failed to build server options: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer(opts...)
+	blockindexpb.RegisterBlockIndexServiceServer(s, blockindex.NewBlockIndexService(index))
+	osdpb.RegisterOSDServiceServer(s, osdpb.UnimplementedOSDServiceServer{})
+	go s.Serve(lis)
+	defer s.Stop()
+	addr := lis.Addr().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	getEntry := func(tlsConfig config.TLSConfig) error {
+		conn := dial(t, addr, tlsConfig)
+		_, err := blockindexpb.NewBlockIndexServiceClient(conn).GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: "missing"})
+		return err
+	}
+
+	tests := []struct {
+		name string
+		role Role
+		code codes.Code
+	}{
+		{"frontend", RoleFrontend, codes.OK},
+		{"admin", RoleAdmin, codes.OK},
+		{"osd", RoleOSD, codes.PermissionDenied},
+		{"client", RoleClient, codes.PermissionDenied},
+		{"norole", "", codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		err := getEntry(writeIdentity(t, ca, dir, tt.name, tt.role))
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.code, err)
+		}
+	}
+
+	// The frontend may write blocks to an OSD and reaches the handler, which
+	// is unimplemented here, but the master may not delete them
+	conn := dial(t, addr, writeIdentity(t, ca, dir, "frontend2", RoleFrontend))
+	_, err = osdpb.NewOSDServiceClient(conn).DeleteBlock(ctx, &osdpb.DeleteBlockRequest{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected frontend DeleteBlock to reach the handler, got %v", err)
+	}
+	conn = dial(t, addr, writeIdentity(t, ca, dir, "master", RoleMaster))
+	_, err = osdpb.NewOSDServiceClient(conn).DeleteBlock(ctx, &osdpb.DeleteBlockRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected master DeleteBlock to be denied, got %v", err)
+	}
+
+	// Clients without a certificate or with one from another CA cannot connect
+	if err := getEntry(config.TLSConfig{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected plaintext client to be rejected, got %v", err)
+	}
+	foreign, err := NewCA("foreign CA", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create foreign CA: %v", err)
+	}
+	foreignConfig := writeIdentity(t, foreign, dir, "intruder", RoleAdmin)
+	foreignConfig.CAFile = filepath.Join(dir, "blockindex-ca.pem")
+	if err := getEntry(foreignConfig); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected foreign certificate to be rejected, got %v", err)
+	}
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	p := DefaultPolicy()
+
+	tests := []struct {
+		method string
+		role   Role
+		want   bool
+	}{
+		{osdpb.OSDService_PutBlock_FullMethodName, RoleFrontend, true},
+		{osdpb.OSDService_PutBlock_FullMethodName, RoleMaster, true},
+		{osdpb.OSDService_PutBlock_FullMethodName, RoleClient, false},
+		{osdpb.OSDService_DeleteBlock_FullMethodName, RoleFrontend, true},
+		{osdpb.OSDService_DeleteBlock_FullMethodName, RoleMaster, false},
+		{osdpb.OSDService_DeleteBlock_FullMethodName, RoleAdmin, true},
+		{blockindexpb.BlockIndexService_PutEntry_FullMethodName, RoleOSD, false},
+		{"/unknown.Service/Call", RoleFrontend, false},
+		{"/unknown.Service/Call", RoleAdmin, true},
+	}
+	for _, tt := range tests {
+		if got := p.Allowed(tt.method, tt.role); got != tt.want {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", tt.method, tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestRoleFromCert(t *testing.T) {
+	ca, err := NewCA("test CA", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	if _, err := RoleFromCert(ca.cert); err == nil {
+		t.Errorf("Expected CA certificate to have no role")
+	}
+
+	ca.cert.Subject.OrganizationalUnit = []string{"ops", string(RoleOSD)}
+	role, err := RoleFromCert(ca.cert)
+	if err != nil || role != RoleOSD {
+		t.Errorf("Expected role osd, got %q (%v)", role, err)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	secret := []byte("test secret")
+	a := NewAuthenticator(nil, secret)
+
+	token, err := IssueToken(secret, Identity{Tenant: "red", Admin: true}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	id, err := a.Authenticate("", "Bearer "+token)
+	if err != nil || id.Tenant != "red" || !id.Admin {
+		t.Fatalf("Expected admin of red, got %+v (%v)", id, err)
+	}
+
+	expired, _ := IssueToken(secret, Identity{Tenant: "red"}, -time.Minute)
+	forged, _ := IssueToken([]byte("other secret"), Identity{Tenant: "red"}, time.Hour)
+	parts := strings.Split(token, ".")
+	unsigned := "eyJhbGciOiJub25lIn0." + parts[1] + "."
+
+	for name, header := range map[string]string{
+		"missing":   "",
+		"basic":     "Basic " + token,
+		"expired":   "Bearer " + expired,
+		"forged":    "Bearer " + forged,
+		"unsigned":  "Bearer " + unsigned,
+		"malformed": "Bearer not.a-token",
+	} {
+		if _, err := a.Authenticate("", header); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%s token: expected ErrUnauthenticated, got %v", name, err)
+		}
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	keys := "# team keys\nred-key red\n\nops-key ops admin\n"
+	if err := os.WriteFile(path, []byte(keys), 0600); err != nil {
+		t.Fatalf("Failed to write keys: %v", err)
+	}
+
+	a, err := LoadAuthenticator(path, "")
+	if err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+	if id, err := a.Authenticate("red-key", ""); err != nil || id.Tenant != "red" || id.Admin {
+		t.Errorf("Expected tenant red, got %+v (%v)", id, err)
+	}
+	if id, err := a.Authenticate("ops-key", ""); err != nil || !id.Admin {
+		t.Errorf("Expected admin, got %+v (%v)", id, err)
+	}
+	if _, err := a.Authenticate("blue-key", ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected unknown key to be rejected, got %v", err)
+	}
+	if _, err := a.Authenticate("", "Bearer token"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected tokens to be rejected without a secret, got %v", err)
+	}
+
+	for _, bad := range []string{"lonely-key\n", "key red superuser\n", "key red\nkey blue\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0600); err != nil {
+			t.Fatalf("Failed to write keys: %v", err)
+		}
+		if _, err := LoadAPIKeys(path); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	// The HTTP middleware rejects unauthenticated requests and passes the
+	// identity on to the handler
+	handler := a.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := IdentityFromContext(r.Context())
+		w.Write([]byte(id.Tenant))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/blocks/x", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a key, got %d", rec.Code)
+	}
+	req := httptest.NewRequest("GET", "/blocks/x", nil)
+	req.Header.Set("X-API-Key", "red-key")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "red" {
+		t.Errorf("Expected tenant red to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys carrying client credentials. A request sends either an API
+// key or a bearer JWT.
+const (
+	APIKeyHeader        = "x-api-key"
+	AuthorizationHeader = "authorization"
+)
+
+// ErrUnauthenticated is returned when a request carries no valid credentials
+var ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+// Identity is the tenant a request acts for. Blocks are referenced on behalf
+// of the tenant, and a tenant may only read blocks it references unless it is
+// an admin.
+type Identity struct {
+	Tenant string
+	Admin  bool
+}
+
+// identityKey is the context key of the authenticated identity
+type identityKey struct{}
+
+// WithIdentity returns a context carrying id
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the identity a request was authenticated as.
+// Requests from trusted in-process callers, or served with authentication
+// off, have none.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok && id != nil
+}
+
+// Authenticator checks the API keys and HS256 JWTs clients send in request
+// metadata
+type Authenticator struct {
+	keys   map[string]Identity
+	secret []byte
+}
+
+// NewAuthenticator creates an authenticator accepting the given API keys and
+// JWTs signed with secret. Either may be empty to disable that method.
+func NewAuthenticator(keys map[string]Identity, secret []byte) *Authenticator {
+	return &Authenticator{keys: keys, secret: secret}
+}
+
+// LoadAuthenticator creates an authenticator from an API key file and a JWT
+// secret, either of which may be empty
+func LoadAuthenticator(keysFile, secret string) (*Authenticator, error) {
+	keys := make(map[string]Identity)
+	if keysFile != "" {
+		var err error
+		if keys, err = LoadAPIKeys(keysFile); err != nil {
+			return nil, err
+		}
+	}
+	return NewAuthenticator(keys, []byte(secret)), nil
+}
+
+// LoadAPIKeys reads API keys from a file with one "<key> <tenant> [admin]"
+// line per key. Blank lines and lines starting with # are ignored.
+func LoadAPIKeys(path string) (map[string]Identity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys: %w", err)
+	}
+	defer file.Close()
+
+	keys := make(map[string]Identity)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 2 || len(fields) > 3 || (len(fields) == 3 && fields[2] != "admin") {
+			return nil, fmt.Errorf("%s:%d: expected \"<key> <tenant> [admin]\"", path, line)
+		}
+		if _, ok := keys[fields[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate key", path, line)
+		}
+		keys[fields[0]] = Identity{Tenant: fields[1], Admin: len(fields) == 3}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Enabled reports whether any API key or JWT secret is configured
+func (a *Authenticator) Enabled() bool {
+	return len(a.keys) > 0 || len(a.secret) > 0
+}
+
+// Authenticate returns the identity of an API key or of an Authorization
+// header value of the form "Bearer <jwt>". The API key is used when both are
+// given.
+func (a *Authenticator) Authenticate(apiKey, authorization string) (*Identity, error) {
+	if apiKey != "" {
+		for key, id := range a.keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+				return &id, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: unknown API key", ErrUnauthenticated)
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return nil, ErrUnauthenticated
+	}
+	if len(a.secret) == 0 {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrUnauthenticated)
+	}
+	return verifyToken(a.secret, token, time.Now())
+}
+
+// authenticateContext authenticates the credentials in incoming gRPC metadata
+func (a *Authenticator) authenticateContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	id, err := a.Authenticate(first(APIKeyHeader), first(AuthorizationHeader))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return WithIdentity(ctx, id), nil
+}
+
+// identityStream overrides the context of a server stream
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
+
+// ServerOptions returns interceptors that reject calls without valid
+// credentials and pass the caller's identity to handlers in the context. It
+// returns nil when no credentials are configured.
+func (a *Authenticator) ServerOptions() []grpc.ServerOption {
+	if !a.Enabled() {
+		return nil
+	}
+
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticateContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateContext(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary),
+		grpc.ChainStreamInterceptor(stream),
+	}
+}
+
+// HTTPHandler wraps an HTTP handler so that requests must carry an X-API-Key
+// or "Authorization: Bearer" header, and passes the caller's identity to next
+// in the request context. It returns next unchanged when no credentials are
+// configured.
+func (a *Authenticator) HTTPHandler(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := a.Authenticate(r.Header.Get(APIKeyHeader), r.Header.Get(AuthorizationHeader))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+	})
+}
+
+// tokenHeader is the JWT header of issued tokens
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// tokenClaims are the JWT claims read from a bearer token
+type tokenClaims struct {
+	Subject   string `json:"sub,omitempty"`
+	Tenant    string `json:"tenant"`
+	Admin     bool   `json:"admin,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// IssueToken returns an HS256 JWT for id that expires after ttl. Deployments
+// with their own identity provider only need to sign the same claims.
+func IssueToken(secret []byte, id Identity, ttl time.Duration) (string, error) {
+	if id.Tenant == "" {
+		return "", fmt.Errorf("token needs a tenant")
+	}
+
+	claims, err := json.Marshal(tokenClaims{
+		Subject:   id.Tenant,
+		Tenant:    id.Tenant,
+		Admin:     id.Admin,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	signed := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signed + "." + sign(secret, signed), nil
+}
+
+// verifyToken checks an HS256 JWT's signature and expiry and returns the
+// identity it names
+func verifyToken(secret []byte, token string, now time.Time) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token header", ErrUnauthenticated)
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil || h.Alg != "HS256" {
+		return nil, fmt.Errorf("%w: token must be signed with HS256", ErrUnauthenticated)
+	}
+
+	if !hmac.Equal([]byte(sign(secret, parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return nil, fmt.Errorf("%w: bad token signature", ErrUnauthenticated)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrUnauthenticated)
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrUnauthenticated)
+	}
+	if claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("%w: token expired", ErrUnauthenticated)
+	}
+	if claims.Tenant == "" {
+		return nil, fmt.Errorf("%w: token has no tenant", ErrUnauthenticated)
+	}
+
+	return &Identity{Tenant: claims.Tenant, Admin: claims.Admin}, nil
+}
+
+// sign returns the base64url HMAC-SHA256 of a token's header and claims
+func sign(secret []byte, signed string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+
+	"bharani/proto/blockindex"
+	"bharani/proto/consensus"
+	"bharani/proto/frontend"
+	"bharani/proto/master"
+	"bharani/proto/osd"
+	"bharani/proto/replication"
+)
+
+// Policy maps gRPC service names ("osd.OSDService") and full method names
+// ("/osd.OSDService/PutBlock") to the roles allowed to call them. A method
+// entry takes precedence over its service's entry, and admins may call
+// anything.
+type Policy map[string][]Role
+
+// DefaultPolicy grants each role the calls its service makes and nothing more
+func DefaultPolicy() Policy {
+	return Policy{
+		frontend.FrontendService_ServiceDesc.ServiceName: {RoleClient},
+
+		blockindex.BlockIndexService_ServiceDesc.ServiceName:       {RoleFrontend},
+		blockindex.BlockIndexService_BeginMigration_FullMethodName: {RoleMaster},
+		blockindex.BlockIndexService_ExportRange_FullMethodName:    {RoleMaster},
+		blockindex.BlockIndexService_ExportBlocks_FullMethodName:   {RoleMaster},
+		blockindex.BlockIndexService_ImportBlocks_FullMethodName:   {RoleMaster},
+		blockindex.BlockIndexService_TakeChanges_FullMethodName:    {RoleMaster},
+		blockindex.BlockIndexService_FreezeRange_FullMethodName:    {RoleMaster},
+		blockindex.BlockIndexService_EndMigration_FullMethodName:   {RoleMaster},
+
+		replication.ReplicationTableService_ServiceDesc.ServiceName:    {RoleMaster},
+		replication.ReplicationTableService_GetVolume_FullMethodName:   {RoleMaster, RoleFrontend},
+		replication.ReplicationTableService_ListVolumes_FullMethodName: {RoleMaster, RoleFrontend},
+
+		master.MasterService_ServiceDesc.ServiceName:              {},
+		master.MasterService_RegisterOSD_FullMethodName:           {RoleOSD},
+		master.MasterService_Heartbeat_FullMethodName:             {RoleOSD},
+		master.MasterService_ReserveSpace_FullMethodName:          {RoleFrontend},
+		master.MasterService_ListOSDs_FullMethodName:              {RoleFrontend},
+		master.MasterService_ReportUnderReplicated_FullMethodName: {RoleFrontend},
+		master.MasterService_ReportCorruption_FullMethodName:      {RoleFrontend},
+		master.MasterService_GetShardMap_FullMethodName:           {RoleFrontend},
+
+		osd.OSDService_ServiceDesc.ServiceName:    {RoleFrontend, RoleMaster},
+		osd.OSDService_DeleteBlock_FullMethodName: {RoleFrontend},
+
+		consensus.ConsensusService_ServiceDesc.ServiceName: {RolePeer},
+	}
+}
+
+// Allowed reports whether role may call the full method name
+func (p Policy) Allowed(fullMethod string, role Role) bool {
+	if role == RoleAdmin {
+		return true
+	}
+
+	if roles, ok := p[fullMethod]; ok {
+		return slices.Contains(roles, role)
+	}
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return slices.Contains(p[service], role)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Role is what a caller is allowed to do, taken from the organizational unit
+// of its client certificate
+type Role string
+
+const (
+	RoleFrontend Role = "frontend" // Frontends and the gateways that embed one
+	RoleMaster   Role = "master"   // The cell master
+	RoleOSD      Role = "osd"      // Storage daemons
+	RoleAdmin    Role = "admin"    // Operators and maintenance services such as gc; allowed everything
+	RoleClient   Role = "client"   // Applications storing and reading data through a frontend
+	RolePeer     Role = "peer"     // Replicas of the block index and replication table
+)
+
+// Roles lists every known role
+var Roles = []Role{RoleFrontend, RoleMaster, RoleOSD, RoleAdmin, RoleClient, RolePeer}
+
+// ErrNoRole is returned when a certificate names no known role
+var ErrNoRole = errors.New("certificate has no known role")
+
+// RoleFromCert returns the first organizational unit of a certificate that
+// names a known role
+func RoleFromCert(cert *x509.Certificate) (Role, error) {
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		for _, role := range Roles {
+			if Role(ou) == role {
+				return role, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNoRole, cert.Subject.CommonName)
+}
+
+// RoleFromContext returns the role of the verified client certificate of the
+// connection an RPC arrived on
+func RoleFromContext(ctx context.Context) (Role, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no peer in context")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", fmt.Errorf("connection is not using TLS")
+	}
+	if len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", fmt.Errorf("no verified client certificate")
+	}
+
+	return RoleFromCert(info.State.VerifiedChains[0][0])
+}
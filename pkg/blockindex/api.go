@@ -2,18 +2,69 @@ package blockindex
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"bharani/proto/blockindex"
+
+	"google.golang.org/protobuf/proto"
 )
 
-// BlockIndexService implements the gRPC BlockIndex service
+// BlockIndexService implements the gRPC BlockIndex service. When the index is
+// sharded, requests for blocks of ranges this node has handed to another
+// node fail with OutOfRange, and writes to a range that is being handed over
+// fail with Aborted while the range is frozen.
 type BlockIndexService struct {
-	index *Index
+	index       Index
+	lostChanges error        // Why a write to a migrating range could not be recorded
+	writeMu     sync.RWMutex // Held shared by writes, exclusively to freeze or move a range
+	mu          sync.Mutex   // Guards lostChanges
 	blockindex.UnimplementedBlockIndexServiceServer
 }
 
+// ReadMethods are the methods of the service that do not change the index
+var ReadMethods = []string{
+	"GetEntry", "Exists", "ExistsBatch", "GetEntries", "Referenced", "GetUsage",
+	"ListCollectable", "ListDeleting", "ListVolumeEntries", "GetVolumeUsage",
+	"ListIntents", "ExportRange", "ExportBlocks",
+}
+
+// StampRequest sets the timestamps a write request leaves unset to now. A
+// replicated index stamps each write once, on the leader, so that every
+// replica records the same times.
+func StampRequest(req proto.Message, now time.Time) {
+	stamp := func(at *int64) {
+		if *at == 0 {
+			*at = now.Unix()
+		}
+	}
+	switch req := req.(type) {
+	case *blockindex.PutEntryRequest:
+		stamp(&req.CreatedAt)
+	case *blockindex.AddRefsRequest:
+		stamp(&req.CreatedAt)
+	case *blockindex.ReleaseRequest:
+		stamp(&req.ReleasedAt)
+	case *blockindex.BeginPutRequest:
+		stamp(&req.CreatedAt)
+	case *blockindex.CommitPutRequest:
+		stamp(&req.CommittedAt)
+	}
+}
+
+// requestTime returns the time a request was stamped with, or the current
+// time for an unreplicated index that received it unstamped
+func requestTime(at int64) time.Time {
+	if at == 0 {
+		return time.Now()
+	}
+	return time.Unix(at, 0)
+}
+
 // NewBlockIndexService creates a new BlockIndex service
-func NewBlockIndexService(index *Index) *BlockIndexService {
+func NewBlockIndexService(index Index) *BlockIndexService {
 	return &BlockIndexService{
 		index: index,
 	}
@@ -21,28 +72,83 @@ func NewBlockIndexService(index *Index) *BlockIndexService {
 
 // PutEntry handles PutEntry requests
 func (s *BlockIndexService) PutEntry(ctx context.Context, req *blockindex.PutEntryRequest) (*blockindex.PutEntryResponse, error) {
+	entry := &Entry{
+		Hash:      req.Hash,
+		CellID:    req.CellId,
+		BucketID:  req.BucketId,
+		Checksum:  req.Checksum,
+		VolumeID:  req.VolumeId,
+		Size:      req.Size,
+		CreatedAt: requestTime(req.CreatedAt),
+	}
+
+	done, err := s.beginWrite(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	existing, err := s.index.PutEntry(entry)
+	if err != nil {
+		return &blockindex.PutEntryResponse{
+			Success:  false,
+			Error:    err.Error(),
+			Deleting: errors.Is(err, ErrDeleting),
+		}, nil
+	}
+
+	resp := &blockindex.PutEntryResponse{
+		Success: true,
+	}
+	if existing != nil {
+		resp.Existing = toProtoEntry(existing)
+	}
+	return resp, nil
+}
+
+// UpdateEntry handles UpdateEntry requests
+func (s *BlockIndexService) UpdateEntry(ctx context.Context, req *blockindex.UpdateEntryRequest) (*blockindex.UpdateEntryResponse, error) {
 	entry := &Entry{
 		Hash:     req.Hash,
 		CellID:   req.CellId,
 		BucketID: req.BucketId,
 		Checksum: req.Checksum,
+		VolumeID: req.VolumeId,
+		Size:     req.Size,
+	}
+	expected := Location{
+		CellID:   req.ExpectedCellId,
+		BucketID: req.ExpectedBucketId,
+		VolumeID: req.ExpectedVolumeId,
 	}
 
-	err := s.index.PutEntry(entry)
+	done, err := s.beginWrite(req.Hash)
 	if err != nil {
-		return &blockindex.PutEntryResponse{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
+		return nil, err
 	}
+	defer done()
 
-	return &blockindex.PutEntryResponse{
-		Success: true,
-	}, nil
+	current, err := s.index.UpdateEntry(entry, expected)
+	resp := &blockindex.UpdateEntryResponse{
+		Success:  err == nil,
+		Deleting: errors.Is(err, ErrDeleting),
+		Conflict: errors.Is(err, ErrLocationChanged),
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	if current != nil {
+		resp.Current = toProtoEntry(current)
+	}
+	return resp, nil
 }
 
 // GetEntry handles GetEntry requests
 func (s *BlockIndexService) GetEntry(ctx context.Context, req *blockindex.GetEntryRequest) (*blockindex.GetEntryResponse, error) {
+	if err := s.checkRead(req.Hash); err != nil {
+		return nil, err
+	}
+
 	entry, err := s.index.GetEntry(req.Hash)
 	if err != nil {
 		return &blockindex.GetEntryResponse{
@@ -57,16 +163,28 @@ func (s *BlockIndexService) GetEntry(ctx context.Context, req *blockindex.GetEnt
 		}, nil
 	}
 
-	return &blockindex.GetEntryResponse{
-		Found:    true,
-		CellId:   entry.CellID,
-		BucketId: entry.BucketID,
-		Checksum: entry.Checksum,
-	}, nil
+	resp := &blockindex.GetEntryResponse{
+		Found:     true,
+		CellId:    entry.CellID,
+		BucketId:  entry.BucketID,
+		Checksum:  entry.Checksum,
+		VolumeId:  entry.VolumeID,
+		Size:      entry.Size,
+		CreatedAt: entry.CreatedAt.Unix(),
+	}
+	if !entry.VerifiedAt.IsZero() {
+		resp.VerifiedAt = entry.VerifiedAt.Unix()
+	}
+
+	return resp, nil
 }
 
 // Exists handles Exists requests
 func (s *BlockIndexService) Exists(ctx context.Context, req *blockindex.ExistsRequest) (*blockindex.ExistsResponse, error) {
+	if err := s.checkRead(req.Hash); err != nil {
+		return nil, err
+	}
+
 	exists, err := s.index.Exists(req.Hash)
 	if err != nil {
 		return &blockindex.ExistsResponse{
@@ -79,3 +197,415 @@ func (s *BlockIndexService) Exists(ctx context.Context, req *blockindex.ExistsRe
 	}, nil
 }
 
+// ExistsBatch handles ExistsBatch requests
+func (s *BlockIndexService) ExistsBatch(ctx context.Context, req *blockindex.ExistsBatchRequest) (*blockindex.ExistsBatchResponse, error) {
+	if err := s.checkRead(req.Hashes...); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.index.ExistsBatch(req.Hashes)
+	if err != nil {
+		return &blockindex.ExistsBatchResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	hashes := make([]string, 0, len(existing))
+	for hash := range existing {
+		hashes = append(hashes, hash)
+	}
+
+	return &blockindex.ExistsBatchResponse{
+		Existing: hashes,
+	}, nil
+}
+
+// GetEntries handles GetEntries requests
+func (s *BlockIndexService) GetEntries(ctx context.Context, req *blockindex.GetEntriesRequest) (*blockindex.GetEntriesResponse, error) {
+	if err := s.checkRead(req.Hashes...); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.index.GetEntries(req.Hashes)
+	if err != nil {
+		return &blockindex.GetEntriesResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	resp := &blockindex.GetEntriesResponse{
+		Entries: make([]*blockindex.Entry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, toProtoEntry(entry))
+	}
+
+	return resp, nil
+}
+
+// toProtoEntry converts an index entry to its protobuf form
+func toProtoEntry(entry *Entry) *blockindex.Entry {
+	return &blockindex.Entry{
+		Hash:     entry.Hash,
+		CellId:   entry.CellID,
+		BucketId: entry.BucketID,
+		Checksum: entry.Checksum,
+		VolumeId: entry.VolumeID,
+		Size:     entry.Size,
+	}
+}
+
+// listEntriesResponse converts a list of index entries to a ListEntries response
+func listEntriesResponse(entries []*Entry, err error) *blockindex.ListEntriesResponse {
+	if err != nil {
+		return &blockindex.ListEntriesResponse{
+			Error: err.Error(),
+		}
+	}
+
+	resp := &blockindex.ListEntriesResponse{
+		Entries: make([]*blockindex.Entry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, toProtoEntry(entry))
+	}
+	return resp
+}
+
+// MarkVerified handles MarkVerified requests
+func (s *BlockIndexService) MarkVerified(ctx context.Context, req *blockindex.MarkVerifiedRequest) (*blockindex.MarkVerifiedResponse, error) {
+	done, err := s.beginWrite(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	found, err := s.index.MarkVerified(req.Hash, req.Size, time.Unix(req.VerifiedAt, 0))
+	if err != nil {
+		return &blockindex.MarkVerifiedResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if !found {
+		return &blockindex.MarkVerifiedResponse{
+			Success: false,
+			Error:   fmt.Sprintf("block %s is not indexed", req.Hash),
+		}, nil
+	}
+
+	return &blockindex.MarkVerifiedResponse{
+		Success: true,
+	}, nil
+}
+
+// AddRefs handles AddRefs requests
+func (s *BlockIndexService) AddRefs(ctx context.Context, req *blockindex.AddRefsRequest) (*blockindex.AddRefsResponse, error) {
+	done, err := s.beginWrite(req.Hashes...)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	result, err := s.index.AddRefs(req.Hashes, req.Tenant, req.Owner, requestTime(req.CreatedAt))
+	if err != nil {
+		return &blockindex.AddRefsResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.AddRefsResponse{
+		Found:    result.Found,
+		Deleting: result.Deleting,
+	}, nil
+}
+
+// Release handles Release requests
+func (s *BlockIndexService) Release(ctx context.Context, req *blockindex.ReleaseRequest) (*blockindex.ReleaseResponse, error) {
+	done, err := s.beginWrite(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	released, remaining, err := s.index.Release(req.Hash, req.Tenant, req.Owner, requestTime(req.ReleasedAt))
+	if err != nil {
+		return &blockindex.ReleaseResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.ReleaseResponse{
+		Released:  released,
+		Remaining: remaining,
+	}, nil
+}
+
+// Referenced handles Referenced requests
+func (s *BlockIndexService) Referenced(ctx context.Context, req *blockindex.ReferencedRequest) (*blockindex.ReferencedResponse, error) {
+	if err := s.checkRead(req.Hashes...); err != nil {
+		return nil, err
+	}
+
+	referenced, err := s.index.Referenced(req.Hashes, req.Tenant)
+	if err != nil {
+		return &blockindex.ReferencedResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	hashes := make([]string, 0, len(referenced))
+	for hash := range referenced {
+		hashes = append(hashes, hash)
+	}
+
+	return &blockindex.ReferencedResponse{
+		Referenced: hashes,
+	}, nil
+}
+
+// GetUsage handles GetUsage requests
+func (s *BlockIndexService) GetUsage(ctx context.Context, req *blockindex.GetUsageRequest) (*blockindex.GetUsageResponse, error) {
+	usage, err := s.index.GetUsage(req.Tenant)
+	if err != nil {
+		return &blockindex.GetUsageResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.GetUsageResponse{
+		Bytes:  usage.Bytes,
+		Blocks: usage.Blocks,
+	}, nil
+}
+
+// ListCollectable handles ListCollectable requests
+func (s *BlockIndexService) ListCollectable(ctx context.Context, req *blockindex.ListCollectableRequest) (*blockindex.ListEntriesResponse, error) {
+	entries, err := s.index.ListCollectable(time.Unix(req.UnreferencedBefore, 0), int(req.Limit))
+	return listEntriesResponse(entries, err), nil
+}
+
+// MarkDeleting handles MarkDeleting requests
+func (s *BlockIndexService) MarkDeleting(ctx context.Context, req *blockindex.MarkDeletingRequest) (*blockindex.MarkDeletingResponse, error) {
+	done, err := s.beginWrite(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	marked, err := s.index.MarkDeleting(req.Hash, time.Unix(req.UnreferencedBefore, 0))
+	if err != nil {
+		return &blockindex.MarkDeletingResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.MarkDeletingResponse{
+		Marked: marked,
+	}, nil
+}
+
+// ListDeleting handles ListDeleting requests
+func (s *BlockIndexService) ListDeleting(ctx context.Context, req *blockindex.ListDeletingRequest) (*blockindex.ListEntriesResponse, error) {
+	entries, err := s.index.ListDeleting(int(req.Limit))
+	return listEntriesResponse(entries, err), nil
+}
+
+// RemoveEntry handles RemoveEntry requests
+func (s *BlockIndexService) RemoveEntry(ctx context.Context, req *blockindex.RemoveEntryRequest) (*blockindex.RemoveEntryResponse, error) {
+	done, err := s.beginWrite(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	removed, err := s.index.RemoveEntry(req.Hash)
+	if err != nil {
+		return &blockindex.RemoveEntryResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if !removed {
+		return &blockindex.RemoveEntryResponse{
+			Success: false,
+			Error:   fmt.Sprintf("block %s is not being deleted", req.Hash),
+		}, nil
+	}
+
+	return &blockindex.RemoveEntryResponse{
+		Success: true,
+	}, nil
+}
+
+// ListVolumeEntries handles ListVolumeEntries requests
+func (s *BlockIndexService) ListVolumeEntries(ctx context.Context, req *blockindex.ListVolumeEntriesRequest) (*blockindex.ListEntriesResponse, error) {
+	entries, err := s.index.ListVolumeEntries(req.VolumeId, int(req.Limit))
+	return listEntriesResponse(entries, err), nil
+}
+
+// GetVolumeUsage handles GetVolumeUsage requests
+func (s *BlockIndexService) GetVolumeUsage(ctx context.Context, req *blockindex.GetVolumeUsageRequest) (*blockindex.GetVolumeUsageResponse, error) {
+	usage, unlocated, err := s.index.GetVolumeUsage(req.CellId)
+	if err != nil {
+		return &blockindex.GetVolumeUsageResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	resp := &blockindex.GetVolumeUsageResponse{
+		Volumes:   make([]*blockindex.VolumeUsage, 0, len(usage)),
+		Unlocated: unlocated,
+	}
+	for _, u := range usage {
+		resp.Volumes = append(resp.Volumes, &blockindex.VolumeUsage{
+			VolumeId:  u.VolumeID,
+			LiveBytes: u.LiveBytes,
+			Blocks:    u.Blocks,
+		})
+	}
+
+	return resp, nil
+}
+
+// BeginPut handles BeginPut requests
+func (s *BlockIndexService) BeginPut(ctx context.Context, req *blockindex.BeginPutRequest) (*blockindex.BeginPutResponse, error) {
+	hashes := make([]string, 0, len(req.Intents))
+	for _, intent := range req.Intents {
+		hashes = append(hashes, intent.Hash)
+	}
+	done, err := s.beginWrite(hashes...)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	createdAt := requestTime(req.CreatedAt)
+	intents := make([]*Intent, 0, len(req.Intents))
+	for _, intent := range req.Intents {
+		intents = append(intents, &Intent{
+			Hash:      intent.Hash,
+			CellID:    intent.CellId,
+			BucketID:  intent.BucketId,
+			VolumeID:  intent.VolumeId,
+			Size:      intent.Size,
+			Tenant:    intent.Tenant,
+			Owner:     intent.Owner,
+			CreatedAt: createdAt,
+		})
+	}
+
+	ids, err := s.index.BeginPut(intents)
+	if err != nil {
+		return &blockindex.BeginPutResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.BeginPutResponse{
+		Ids: ids,
+	}, nil
+}
+
+// CommitPut handles CommitPut requests. Intents recorded before their range
+// was frozen may still be committed, so the range can wait for them.
+func (s *BlockIndexService) CommitPut(ctx context.Context, req *blockindex.CommitPutRequest) (*blockindex.CommitPutResponse, error) {
+	done, err := s.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.index.CommitPut(req.Id, req.Claimed, requestTime(req.CommittedAt))
+	if err != nil {
+		done()
+		return &blockindex.CommitPutResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	done(result.Hash)
+
+	return &blockindex.CommitPutResponse{
+		Success:   !result.Deleting,
+		Deleting:  result.Deleting,
+		Duplicate: result.Duplicate,
+	}, nil
+}
+
+// AbortPut handles AbortPut requests. Like commits, aborts are accepted on a
+// frozen range so its intents can drain.
+func (s *BlockIndexService) AbortPut(ctx context.Context, req *blockindex.AbortPutRequest) (*blockindex.AbortPutResponse, error) {
+	done, err := s.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer done(req.Hash)
+
+	_, indexed, err := s.index.AbortPut(req.Id)
+	if err != nil {
+		return &blockindex.AbortPutResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &blockindex.AbortPutResponse{
+		Success: true,
+		Indexed: indexed,
+	}, nil
+}
+
+// ListIntents handles ListIntents requests
+func (s *BlockIndexService) ListIntents(ctx context.Context, req *blockindex.ListIntentsRequest) (*blockindex.ListIntentsResponse, error) {
+	intents, err := s.index.ListIntents(time.Unix(req.CreatedBefore, 0), int(req.Limit))
+	if err != nil {
+		return &blockindex.ListIntentsResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	resp := &blockindex.ListIntentsResponse{
+		Intents: make([]*blockindex.Intent, 0, len(intents)),
+	}
+	for _, intent := range intents {
+		resp.Intents = append(resp.Intents, &blockindex.Intent{
+			Id:        intent.ID,
+			Hash:      intent.Hash,
+			CellId:    intent.CellID,
+			BucketId:  intent.BucketID,
+			VolumeId:  intent.VolumeID,
+			Size:      intent.Size,
+			Tenant:    intent.Tenant,
+			Owner:     intent.Owner,
+			State:     intent.State,
+			CreatedAt: intent.CreatedAt.Unix(),
+		})
+	}
+
+	return resp, nil
+}
+
+// ClaimIntent handles ClaimIntent requests. Claims are refused on a frozen
+// range, which waits for the intent's writer instead; the sweeper retries
+// once the migration has finished or been started over.
+func (s *BlockIndexService) ClaimIntent(ctx context.Context, req *blockindex.ClaimIntentRequest) (*blockindex.ClaimIntentResponse, error) {
+	done, err := s.beginWrite(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	claimed, indexed, err := s.index.ClaimIntent(req.Id, time.Unix(req.CreatedBefore, 0))
+	if err != nil {
+		return &blockindex.ClaimIntentResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.ClaimIntentResponse{
+		Claimed: claimed,
+		Indexed: indexed,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package blockindex
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltStore keeps a KVIndex in a bbolt file
+type boltStore struct {
+	db   *bolt.DB
+	path string
+	mu   sync.RWMutex // Held exclusively while the file is replaced
+}
+
+// NewBoltIndex opens or creates an index in a bbolt file
+func NewBoltIndex(path string) (*KVIndex, error) {
+	db, err := openBolt(path)
+	if err != nil {
+		return nil, err
+	}
+	return newKVIndex(&boltStore{db: db, path: path})
+}
+
+// openBolt opens a bbolt file and creates the index buckets
+func openBolt(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range kvBuckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize buckets: %w", err)
+	}
+	return db, nil
+}
+
+func (s *boltStore) view(fn func(tx kvTx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (s *boltStore) update(fn func(tx kvTx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (s *boltStore) snapshot(path string) error {
+	return s.view(func(tx kvTx) error {
+		return tx.(boltTx).CopyFile(path, 0600)
+	})
+}
+
+func (s *boltStore) restore(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	if err := replaceFile(path, s.path); err != nil {
+		return err
+	}
+
+	db, err := openBolt(s.path)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *boltStore) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Close()
+}
+
+// boltTx is a bbolt transaction. bbolt does not store empty keys, which the
+// index uses for the default tenant and the first hash range, so every key is
+// stored after a one-byte prefix.
+type boltTx struct {
+	*bolt.Tx
+}
+
+// boltKey returns the stored form of a key
+func boltKey(key string) []byte {
+	return append([]byte{'k'}, key...)
+}
+
+func (tx boltTx) get(bucket, key string) []byte {
+	return tx.Bucket([]byte(bucket)).Get(boltKey(key))
+}
+
+func (tx boltTx) put(bucket, key string, value []byte) error {
+	return tx.Bucket([]byte(bucket)).Put(boltKey(key), value)
+}
+
+func (tx boltTx) delete(bucket, key string) error {
+	return tx.Bucket([]byte(bucket)).Delete(boltKey(key))
+}
+
+func (tx boltTx) scan(bucket, start string, fn func(key string, value []byte) bool) error {
+	c := tx.Bucket([]byte(bucket)).Cursor()
+	for k, v := c.Seek(boltKey(start)); k != nil; k, v = c.Next() {
+		if !fn(string(k[1:]), v) {
+			break
+		}
+	}
+	return nil
+}
@@ -1,129 +1,114 @@
 package blockindex
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
-	"sync"
+	"strings"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"bharani/pkg/shards"
 )
 
-// Index stores the mapping from block hash to storage location
-type Index struct {
-	db *sql.DB
-	mu sync.RWMutex
+// Index stores the mapping from block hash to storage location, together with
+// the references, intents and usage kept for each block. Every implementation
+// must behave the same way; the conformance tests in index_test.go run against
+// each of them.
+type Index interface {
+	PutEntry(entry *Entry) (*Entry, error)
+	UpdateEntry(entry *Entry, expected Location) (*Entry, error)
+	GetEntry(hash string) (*Entry, error)
+	MarkVerified(hash string, size int64, at time.Time) (bool, error)
+	Exists(hash string) (bool, error)
+	ExistsBatch(hashes []string) (map[string]bool, error)
+	GetEntries(hashes []string) (map[string]*Entry, error)
+
+	AddRefs(hashes []string, tenant, owner string, at time.Time) (*RefResult, error)
+	Release(hash, tenant, owner string, at time.Time) (bool, int64, error)
+	GetUsage(tenant string) (*TenantUsage, error)
+	Referenced(hashes []string, tenant string) (map[string]bool, error)
+	ListCollectable(cutoff time.Time, limit int) ([]*Entry, error)
+	MarkDeleting(hash string, cutoff time.Time) (bool, error)
+	ListDeleting(limit int) ([]*Entry, error)
+	RemoveEntry(hash string) (bool, error)
+	ListVolumeEntries(volumeID string, limit int) ([]*Entry, error)
+	GetVolumeUsage(cellID string) ([]*VolumeUsage, int64, error)
+
+	BeginPut(intents []*Intent) ([]int64, error)
+	CommitPut(id int64, claimed bool, at time.Time) (*CommitResult, error)
+	AbortPut(id int64) (bool, bool, error)
+	ListIntents(cutoff time.Time, limit int) ([]*Intent, error)
+	ClaimIntent(id int64, cutoff time.Time) (bool, bool, error)
+
+	ExportRange(r shards.Range, after string, limit int) ([]*Record, error)
+	ExportBlocks(hashes []string) ([]*Record, error)
+	ImportBlocks(records []*Record, span *shards.Range) error
+	CountIntents(r shards.Range) (int64, error)
+	DropRange(r shards.Range) error
+	Moved(hash string) bool
+
+	BeginMigration(r shards.Range) error
+	RecordChanges(hashes []string) error
+	TakeChanges(r shards.Range) ([]string, error)
+	FreezeRange(r shards.Range) error
+	Frozen(hash string) bool
+
+	// Snapshot writes a consistent copy of the index to path, which does
+	// not exist yet
+	Snapshot(path string) error
+
+	// Restore replaces the index with a copy written by Snapshot
+	Restore(path string) error
+
+	Close() error
 }
 
 // Entry represents a block index entry
 type Entry struct {
-	Hash     string
-	CellID   string
-	BucketID string
-	Checksum string
+	Hash       string
+	CellID     string
+	BucketID   string
+	Checksum   string
+	VolumeID   string
+	Size       int64     // Block length in bytes, 0 if unknown
+	CreatedAt  time.Time // When the block was first indexed
+	VerifiedAt time.Time // Last scrub that found every replica intact, zero if never
 }
 
-// NewIndex creates a new block index
-func NewIndex(dbPath string) (*Index, error) {
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	index := &Index{db: db}
-
-	if err := index.initSchema(); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	return index, nil
+// Location is where a block's replicas are stored
+type Location struct {
+	CellID   string
+	BucketID string
+	VolumeID string
 }
 
-// initSchema creates the database schema
-func (i *Index) initSchema() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS blocks (
-		hash TEXT PRIMARY KEY,
-		cell_id TEXT NOT NULL,
-		bucket_id TEXT NOT NULL,
-		checksum TEXT NOT NULL,
-		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_cell_bucket ON blocks(cell_id, bucket_id);
-	`
-
-	_, err := i.db.Exec(query)
-	return err
+// Location returns where the entry's block is stored
+func (e *Entry) Location() Location {
+	return Location{CellID: e.CellID, BucketID: e.BucketID, VolumeID: e.VolumeID}
 }
 
-// PutEntry adds or updates a block index entry
-func (i *Index) PutEntry(entry *Entry) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
-
-	query := `
-	INSERT OR REPLACE INTO blocks (hash, cell_id, bucket_id, checksum)
-	VALUES (?, ?, ?, ?)
-	`
-
-	_, err := i.db.Exec(query, entry.Hash, entry.CellID, entry.BucketID, entry.Checksum)
-	if err != nil {
-		return fmt.Errorf("failed to put entry: %w", err)
+// ErrLocationChanged is returned when updating an entry that is no longer at
+// the location the caller expected
+var ErrLocationChanged = errors.New("block location changed")
+
+// Open opens the index a URL names. The scheme selects the backend:
+//
+//	sqlite://path  SQLite database (the default for a plain path)
+//	bolt://path    bbolt key-value file, which needs no cgo
+//	memory://      in memory, lost when the process exits
+func Open(url string) (Index, error) {
+	scheme, path, ok := strings.Cut(url, "://")
+	if !ok {
+		scheme, path = "sqlite", url
 	}
 
-	return nil
-}
-
-// GetEntry retrieves a block index entry by hash
-func (i *Index) GetEntry(hash string) (*Entry, error) {
-	i.mu.RLock()
-	defer i.mu.RUnlock()
-
-	query := `
-	SELECT hash, cell_id, bucket_id, checksum
-	FROM blocks
-	WHERE hash = ?
-	`
-
-	var entry Entry
-	err := i.db.QueryRow(query, hash).Scan(
-		&entry.Hash,
-		&entry.CellID,
-		&entry.BucketID,
-		&entry.Checksum,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
+	switch scheme {
+	case "sqlite":
+		return NewSQLiteIndex(path)
+	case "bolt":
+		return NewBoltIndex(path)
+	case "memory":
+		return NewMemoryIndex(), nil
+	default:
+		return nil, fmt.Errorf("unknown index backend %q", scheme)
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get entry: %w", err)
-	}
-
-	return &entry, nil
-}
-
-// Exists checks if a block exists in the index
-func (i *Index) Exists(hash string) (bool, error) {
-	i.mu.RLock()
-	defer i.mu.RUnlock()
-
-	query := `SELECT 1 FROM blocks WHERE hash = ? LIMIT 1`
-	var exists int
-	err := i.db.QueryRow(query, hash).Scan(&exists)
-
-	if err == sql.ErrNoRows {
-		return false, nil
-	}
-	if err != nil {
-		return false, fmt.Errorf("failed to check existence: %w", err)
-	}
-
-	return true, nil
 }
-
-// Close closes the database connection
-func (i *Index) Close() error {
-	return i.db.Close()
-}
-
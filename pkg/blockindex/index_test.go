@@ -0,0 +1,909 @@
+package blockindex
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"bharani/pkg/shards"
+)
+
+// now is the time the tests stamp their writes with
+var now = time.Now()
+
+// backend is an Index implementation the conformance tests run against
+type backend struct {
+	name       string
+	persistent bool // Reopening an index finds what was written to it
+	open       func(path string) (Index, error)
+}
+
+var backends = []backend{
+	{"sqlite", true, func(path string) (Index, error) { return NewSQLiteIndex(path) }},
+	{"bolt", true, func(path string) (Index, error) { return NewBoltIndex(path) }},
+	{"memory", false, func(string) (Index, error) { return NewMemoryIndex(), nil }},
+}
+
+// forEachBackend runs a test against every backend
+func forEachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, b)
+		})
+	}
+}
+
+// openIndex opens an index of the backend at path, closing it when the test ends
+func (b backend) openIndex(t *testing.T, path string) Index {
+	t.Helper()
+
+	index, err := b.open(path)
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return index
+}
+
+// newIndex creates an empty index of the backend
+func (b backend) newIndex(t *testing.T) Index {
+	t.Helper()
+	return b.openIndex(t, filepath.Join(t.TempDir(), "index"))
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{filepath.Join(dir, "plain.db"), "*blockindex.SQLiteIndex"},
+		{"sqlite://" + filepath.Join(dir, "index.db"), "*blockindex.SQLiteIndex"},
+		{"bolt://" + filepath.Join(dir, "index.bolt"), "*blockindex.KVIndex"},
+		{"memory://", "*blockindex.KVIndex"},
+	}
+	for _, tt := range tests {
+		index, err := Open(tt.url)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", tt.url, err)
+		}
+		if got := fmt.Sprintf("%T", index); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.url, tt.want, got)
+		}
+		index.Close()
+	}
+
+	if _, err := Open("mysql://localhost/index"); err == nil {
+		t.Error("Opening an unknown backend should fail")
+	}
+}
+
+func TestPutEntryKeepsMetadata(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		// Created well in the past, so an update that reset the time would show
+		createdAt := time.Unix(1000, 0)
+		if _, err := index.PutEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v1", Size: 42, CreatedAt: createdAt}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+
+		verifiedAt := time.Unix(2000, 0)
+		found, err := index.MarkVerified("h", 99, verifiedAt)
+		if err != nil || !found {
+			t.Fatalf("Failed to mark entry verified: %v", err)
+		}
+		if found, _ := index.MarkVerified("missing", 1, verifiedAt); found {
+			t.Error("Marking a missing entry should report it as not found")
+		}
+
+		// Relocating the block keeps its size, creation and verification times
+		updated, err := index.UpdateEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v2"}, Location{CellID: "cell1", BucketID: "b1", VolumeID: "v1"})
+		if err != nil {
+			t.Fatalf("Failed to update entry: %v", err)
+		}
+
+		entry, err := index.GetEntry("h")
+		if err != nil || entry == nil {
+			t.Fatalf("Failed to get entry: %v", err)
+		}
+		if entry.VolumeID != "v2" || entry.Size != 42 {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+		if *updated != *entry {
+			t.Errorf("Update returned %+v, stored %+v", updated, entry)
+		}
+		if !entry.CreatedAt.Equal(createdAt) || !entry.VerifiedAt.Equal(verifiedAt) {
+			t.Errorf("Unexpected times: created %v (was %v), verified %v", entry.CreatedAt, createdAt, entry.VerifiedAt)
+		}
+
+		// Only the new volume lists the block
+		if entries, _ := index.ListVolumeEntries("v1", 10); len(entries) != 0 {
+			t.Errorf("Expected no entries in the old volume, got %+v", entries)
+		}
+		if entries, _ := index.ListVolumeEntries("v2", 10); len(entries) != 1 || entries[0].Hash != "h" {
+			t.Errorf("Expected the block in the new volume, got %+v", entries)
+		}
+	})
+}
+
+func TestPutEntryIfAbsent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		first := &Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v1", Size: 10, CreatedAt: now}
+		existing, err := index.PutEntry(first)
+		if err != nil || existing != nil {
+			t.Fatalf("Expected the entry to be added, got %+v %v", existing, err)
+		}
+
+		// A second writer storing the same block in another bucket learns where
+		// the first one put it, and the index keeps the first location
+		existing, err = index.PutEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b2", Checksum: "h", VolumeID: "v2", Size: 10, CreatedAt: now})
+		if err != nil || existing == nil {
+			t.Fatalf("Expected the existing entry, got %+v %v", existing, err)
+		}
+		if existing.Location() != first.Location() || existing.Size != 10 || existing.CreatedAt.IsZero() {
+			t.Errorf("Unexpected existing entry %+v", existing)
+		}
+		if entry, _ := index.GetEntry("h"); entry.BucketID != "b1" || entry.VolumeID != "v1" {
+			t.Errorf("The first location should be kept, got %+v", entry)
+		}
+		if entries, _ := index.ListVolumeEntries("v2", 10); len(entries) != 0 {
+			t.Errorf("Expected nothing in the losing volume, got %+v", entries)
+		}
+	})
+}
+
+func TestUpdateEntryConflict(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		if _, err := index.PutEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v1", Size: 10, CreatedAt: now}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+		v1 := Location{CellID: "cell1", BucketID: "b1", VolumeID: "v1"}
+
+		// Two writers both relocate the block from v1; only the first succeeds
+		if _, err := index.UpdateEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v2"}, v1); err != nil {
+			t.Fatalf("Failed to update entry: %v", err)
+		}
+		current, err := index.UpdateEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v3"}, v1)
+		if !errors.Is(err, ErrLocationChanged) {
+			t.Fatalf("Expected ErrLocationChanged, got %v", err)
+		}
+		if current == nil || current.VolumeID != "v2" || current.Size != 10 {
+			t.Errorf("Expected the current entry in v2, got %+v", current)
+		}
+		if entry, _ := index.GetEntry("h"); entry.VolumeID != "v2" {
+			t.Errorf("A conflicting update changed the entry to %+v", entry)
+		}
+
+		current, err = index.UpdateEntry(&Entry{Hash: "missing", CellID: "cell1", BucketID: "b1", Checksum: "missing", VolumeID: "v2"}, v1)
+		if !errors.Is(err, ErrLocationChanged) || current != nil {
+			t.Errorf("Expected ErrLocationChanged without an entry for a missing block, got %+v %v", current, err)
+		}
+
+		// A block being collected is not updated
+		index.AddRefs([]string{"h"}, "red", "photo", now)
+		index.Release("h", "red", "photo", time.Unix(1000, 0))
+		if marked, err := index.MarkDeleting("h", time.Now()); err != nil || !marked {
+			t.Fatalf("Failed to claim block: %v", err)
+		}
+		v2 := Location{CellID: "cell1", BucketID: "b1", VolumeID: "v2"}
+		if _, err := index.UpdateEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v3"}, v2); !errors.Is(err, ErrDeleting) {
+			t.Errorf("Expected ErrDeleting, got %v", err)
+		}
+	})
+}
+
+func TestBatchLookups(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		for _, hash := range []string{"a", "b", "c"} {
+			if _, err := index.PutEntry(&Entry{Hash: hash, CellID: "cell1", BucketID: "b1", Checksum: hash, VolumeID: "v1", Size: 1, CreatedAt: now}); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+
+		if exists, err := index.Exists("b"); err != nil || !exists {
+			t.Errorf("Expected b to exist: %v", err)
+		}
+		if exists, _ := index.Exists("z"); exists {
+			t.Error("Expected z not to exist")
+		}
+
+		existing, err := index.ExistsBatch([]string{"a", "z", "c"})
+		if err != nil || len(existing) != 2 || !existing["a"] || !existing["c"] {
+			t.Errorf("Expected a and c to exist, got %v %v", existing, err)
+		}
+
+		entries, err := index.GetEntries([]string{"c", "y", "a"})
+		if err != nil || len(entries) != 2 || entries["a"].BucketID != "b1" || entries["c"].Checksum != "c" {
+			t.Errorf("Expected entries for a and c, got %v %v", entries, err)
+		}
+
+		if entries, _ := index.ListVolumeEntries("v1", 2); len(entries) != 2 {
+			t.Errorf("Expected the listing to stop at the limit, got %d entries", len(entries))
+		}
+	})
+}
+
+func TestRefsAndCollection(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		for _, hash := range []string{"shared", "pinned", "legacy"} {
+			if _, err := index.PutEntry(&Entry{Hash: hash, CellID: "cell1", BucketID: "b1", Checksum: hash, VolumeID: "v1", Size: 10, CreatedAt: now}); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+		if _, err := index.AddRefs([]string{"shared", "pinned"}, "", "alice", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		if _, err := index.AddRefs([]string{"shared"}, "", "bob", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		result, err := index.AddRefs([]string{"pinned", "missing"}, "", "", now)
+		if err != nil {
+			t.Fatalf("Failed to pin: %v", err)
+		}
+		if len(result.Found) != 1 || result.Found[0] != "pinned" {
+			t.Errorf("Expected only the indexed block to be pinned, got %+v", result)
+		}
+
+		released := time.Unix(1000, 0)
+		cutoff := released.Add(time.Hour)
+		for _, hash := range []string{"shared", "pinned"} {
+			if ok, _, err := index.Release(hash, "", "alice", released); err != nil || !ok {
+				t.Fatalf("Failed to release %s: %v", hash, err)
+			}
+		}
+		if ok, _, _ := index.Release("shared", "", "alice", released); ok {
+			t.Error("Releasing twice should not release anything")
+		}
+		if _, _, err := index.Release("pinned", "", "", released); err == nil {
+			t.Error("Pins should not be releasable")
+		}
+
+		// Bob still references the shared block, the pinned block is pinned and
+		// the legacy block was never released
+		collectable, err := index.ListCollectable(cutoff, 10)
+		if err != nil || len(collectable) != 0 {
+			t.Fatalf("Expected nothing collectable, got %v %v", collectable, err)
+		}
+
+		_, remaining, err := index.Release("shared", "", "bob", released)
+		if err != nil || remaining != 0 {
+			t.Fatalf("Failed to release last reference: %v, %d remaining", err, remaining)
+		}
+		collectable, err = index.ListCollectable(cutoff, 10)
+		if err != nil || len(collectable) != 1 || collectable[0].Hash != "shared" || collectable[0].Size != 10 {
+			t.Fatalf("Expected shared to be collectable, got %v %v", collectable, err)
+		}
+		if marked, _ := index.MarkDeleting("shared", released); marked {
+			t.Error("A block released at the cutoff is still within the grace period")
+		}
+
+		// A new reference before the collector claims the block keeps it
+		if _, err := index.AddRefs([]string{"shared"}, "", "carol", now); err != nil {
+			t.Fatalf("Failed to add ref: %v", err)
+		}
+		if collectable, _ := index.ListCollectable(cutoff, 10); len(collectable) != 0 {
+			t.Errorf("A referenced block should not be collectable, got %v", collectable)
+		}
+		if marked, _ := index.MarkDeleting("shared", cutoff); marked {
+			t.Error("A referenced block must not be claimed")
+		}
+		index.Release("shared", "", "carol", released)
+
+		marked, err := index.MarkDeleting("shared", cutoff)
+		if err != nil || !marked {
+			t.Fatalf("Failed to claim block: %v", err)
+		}
+		if collectable, _ := index.ListCollectable(cutoff, 10); len(collectable) != 0 {
+			t.Errorf("A claimed block should not be collectable, got %v", collectable)
+		}
+
+		// Once claimed the block can neither gain references nor be updated
+		result, err = index.AddRefs([]string{"shared"}, "", "dave", now)
+		if err != nil || len(result.Found) != 0 || len(result.Deleting) != 1 {
+			t.Errorf("Expected the block to be reported deleting, got %+v %v", result, err)
+		}
+		_, err = index.PutEntry(&Entry{Hash: "shared", CellID: "cell1", BucketID: "b1", Checksum: "shared", VolumeID: "v2", CreatedAt: now})
+		if !errors.Is(err, ErrDeleting) {
+			t.Errorf("Expected ErrDeleting, got %v", err)
+		}
+
+		usage, unlocated, err := index.GetVolumeUsage("cell1")
+		if err != nil || unlocated != 0 || len(usage) != 1 || usage[0].VolumeID != "v1" || usage[0].Blocks != 2 || usage[0].LiveBytes != 20 {
+			t.Errorf("Unexpected usage %v, %d unlocated, %v", usage, unlocated, err)
+		}
+		if entries, _ := index.ListVolumeEntries("v1", 10); len(entries) != 2 {
+			t.Errorf("Expected the two live blocks in the volume, got %+v", entries)
+		}
+
+		deleting, err := index.ListDeleting(10)
+		if err != nil || len(deleting) != 1 {
+			t.Fatalf("Expected one deleting block, got %v %v", deleting, err)
+		}
+		if removed, _ := index.RemoveEntry("pinned"); removed {
+			t.Error("Live entries must not be removed")
+		}
+		if removed, err := index.RemoveEntry("shared"); err != nil || !removed {
+			t.Fatalf("Failed to remove entry: %v", err)
+		}
+		if entry, _ := index.GetEntry("shared"); entry != nil {
+			t.Error("Removed entry should be gone")
+		}
+		if deleting, _ := index.ListDeleting(10); len(deleting) != 0 {
+			t.Errorf("Expected no deleting blocks left, got %v", deleting)
+		}
+
+		// The hash can be stored again from scratch
+		if _, err := index.PutEntry(&Entry{Hash: "shared", CellID: "cell1", BucketID: "b1", Checksum: "shared", VolumeID: "v2", CreatedAt: now}); err != nil {
+			t.Errorf("Failed to store the hash again: %v", err)
+		}
+	})
+}
+
+func TestVolumeUsage(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		entries := []*Entry{
+			{Hash: "a", CellID: "cell1", BucketID: "b1", Checksum: "a", VolumeID: "v1", Size: 10, CreatedAt: now},
+			{Hash: "b", CellID: "cell1", BucketID: "b1", Checksum: "b", VolumeID: "v1", Size: 5, CreatedAt: now},
+			{Hash: "c", CellID: "cell1", BucketID: "b2", Checksum: "c", VolumeID: "v2", Size: 7, CreatedAt: now},
+			{Hash: "d", CellID: "cell1", BucketID: "b3", Checksum: "d", CreatedAt: now},
+			{Hash: "e", CellID: "cell2", BucketID: "b4", Checksum: "e", VolumeID: "v3", Size: 1},
+		}
+		for _, entry := range entries {
+			if _, err := index.PutEntry(entry); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+		// Scrubbing fills in the size of a block without one
+		if _, err := index.MarkVerified("d", 3, time.Now()); err != nil {
+			t.Fatalf("Failed to mark entry verified: %v", err)
+		}
+
+		usage, unlocated, err := index.GetVolumeUsage("cell1")
+		if err != nil {
+			t.Fatalf("Failed to get volume usage: %v", err)
+		}
+		byVolume := make(map[string]*VolumeUsage)
+		for _, u := range usage {
+			byVolume[u.VolumeID] = u
+		}
+		if unlocated != 1 || len(byVolume) != 2 {
+			t.Fatalf("Expected two volumes and one unlocated block, got %v and %d", usage, unlocated)
+		}
+		if u := byVolume["v1"]; u.LiveBytes != 15 || u.Blocks != 2 {
+			t.Errorf("Expected v1 to hold 15 bytes in 2 blocks, got %+v", u)
+		}
+		if u := byVolume["v2"]; u.LiveBytes != 7 || u.Blocks != 1 {
+			t.Errorf("Expected v2 to hold 7 bytes in 1 block, got %+v", u)
+		}
+	})
+}
+
+func TestPutIntents(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		ids, err := index.BeginPut([]*Intent{
+			{Hash: "h1", CellID: "cell1", BucketID: "b1", VolumeID: "v1", Size: 10, Owner: "alice", CreatedAt: now},
+			{Hash: "h1", CellID: "cell1", BucketID: "b2", VolumeID: "v2", Size: 10, Owner: "bob", CreatedAt: now},
+			{Hash: "h2", CellID: "cell1", BucketID: "b1", VolumeID: "v1", Size: 20, Owner: "alice", CreatedAt: now},
+		})
+		if err != nil || len(ids) != 3 {
+			t.Fatalf("Failed to begin put: %v %v", ids, err)
+		}
+
+		// Nothing is indexed until an intent is committed
+		if entry, _ := index.GetEntry("h1"); entry != nil {
+			t.Fatalf("Uncommitted block should not be indexed")
+		}
+
+		result, err := index.CommitPut(ids[0], false, now)
+		if err != nil || result.Deleting || result.Duplicate {
+			t.Fatalf("Failed to commit: %+v %v", result, err)
+		}
+		entry, err := index.GetEntry("h1")
+		if err != nil || entry == nil || entry.BucketID != "b1" || entry.Size != 10 {
+			t.Fatalf("Unexpected entry after commit: %+v %v", entry, err)
+		}
+		if _, err := index.CommitPut(ids[0], false, now); !errors.Is(err, ErrIntentNotFound) {
+			t.Errorf("Expected a committed intent to be gone, got %v", err)
+		}
+
+		// A second write of the same block keeps the first location, references
+		// the block and leaves its intent to be cleaned up
+		result, err = index.CommitPut(ids[1], false, now)
+		if err != nil || !result.Duplicate {
+			t.Fatalf("Expected a duplicate commit, got %+v %v", result, err)
+		}
+		if entry, _ := index.GetEntry("h1"); entry.BucketID != "b1" {
+			t.Errorf("Duplicate commit moved the block to %s", entry.BucketID)
+		}
+		if _, remaining, _ := index.Release("h1", "", "alice", time.Now()); remaining != 1 {
+			t.Errorf("Expected bob's reference to remain, got %d", remaining)
+		}
+
+		// Fresh intents are not listed or claimable; stale ones are, after which
+		// the writer can no longer commit them
+		if intents, _ := index.ListIntents(time.Unix(0, 0), 10); len(intents) != 0 {
+			t.Errorf("Expected no stale intents, got %d", len(intents))
+		}
+		if claimed, _, _ := index.ClaimIntent(ids[2], time.Unix(0, 0)); claimed {
+			t.Error("Claimed a fresh intent")
+		}
+		cutoff := time.Now().Add(time.Hour)
+		intents, err := index.ListIntents(cutoff, 10)
+		if err != nil || len(intents) != 2 || intents[0].ID != ids[1] || intents[1].State != IntentPending {
+			t.Fatalf("Unexpected stale intents %+v %v", intents, err)
+		}
+		if intents[0].Hash != "h1" || intents[0].BucketID != "b2" || intents[0].Owner != "bob" || intents[0].Size != 10 {
+			t.Errorf("Unexpected intent %+v", intents[0])
+		}
+		if intents, _ := index.ListIntents(cutoff, 1); len(intents) != 1 {
+			t.Errorf("Expected the listing to stop at the limit, got %d intents", len(intents))
+		}
+		if claimed, _, err := index.ClaimIntent(ids[2], cutoff); err != nil || !claimed {
+			t.Fatalf("Failed to claim intent: %v", err)
+		}
+		if _, err := index.CommitPut(ids[2], false, now); !errors.Is(err, ErrIntentClaimed) {
+			t.Errorf("Expected ErrIntentClaimed, got %v", err)
+		}
+		if intents, _ := index.ListIntents(time.Unix(0, 0), 10); len(intents) != 1 || intents[0].State != IntentSweeping {
+			t.Errorf("Expected the claimed intent to stay listed, got %+v", intents)
+		}
+		if _, err := index.CommitPut(ids[2], true, now); err != nil {
+			t.Fatalf("Failed to commit claimed intent: %v", err)
+		}
+
+		// The duplicate's bucket holds no indexed copy, so its replicas can go
+		if aborted, indexed, err := index.AbortPut(ids[1]); err != nil || !aborted || indexed {
+			t.Fatalf("Failed to abort intent: %v %v", indexed, err)
+		}
+		if aborted, _, _ := index.AbortPut(ids[1]); aborted {
+			t.Error("Aborting twice should report the intent missing")
+		}
+		if intents, _ := index.ListIntents(cutoff, 10); len(intents) != 0 {
+			t.Errorf("Expected no intents left, got %d", len(intents))
+		}
+
+		// IDs are not reused
+		more, err := index.BeginPut([]*Intent{{Hash: "h3", CellID: "cell1", BucketID: "b1", VolumeID: "v1", Size: 1, Owner: "alice", CreatedAt: now}})
+		if err != nil || len(more) != 1 || more[0] <= ids[2] {
+			t.Errorf("Expected a fresh intent ID, got %v %v", more, err)
+		}
+	})
+}
+
+func TestAbortPutKeepsCommittedLocation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		// Two Puts of one block were given the same bucket; the first commits
+		intent := &Intent{Hash: "h1", CellID: "cell1", BucketID: "b1", VolumeID: "v1", Size: 10, Owner: "alice", CreatedAt: now}
+		ids, err := index.BeginPut([]*Intent{intent, intent, intent})
+		if err != nil {
+			t.Fatalf("Failed to begin put: %v", err)
+		}
+		if claimed, indexed, err := index.ClaimIntent(ids[1], time.Unix(0, 0)); err != nil || claimed || indexed {
+			t.Fatalf("Expected an unclaimed, unindexed intent: %v %v %v", claimed, indexed, err)
+		}
+		if _, err := index.CommitPut(ids[0], false, now); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+
+		// The others share the committed location, so their replicas are kept
+		if aborted, indexed, err := index.AbortPut(ids[1]); err != nil || !aborted || !indexed {
+			t.Errorf("Expected the abort to report the indexed block: %v %v %v", aborted, indexed, err)
+		}
+		cutoff := time.Now().Add(time.Hour)
+		if claimed, indexed, err := index.ClaimIntent(ids[2], cutoff); err != nil || !claimed || !indexed {
+			t.Errorf("Expected the claim to report the indexed block: %v %v %v", claimed, indexed, err)
+		}
+	})
+}
+
+func TestCommitPutDeleting(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		if _, err := index.PutEntry(&Entry{Hash: "h", CellID: "cell1", BucketID: "b1", Checksum: "h", VolumeID: "v1", Size: 1, CreatedAt: now}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+		index.AddRefs([]string{"h"}, "red", "photo", now)
+		index.Release("h", "red", "photo", time.Unix(1000, 0))
+		if marked, err := index.MarkDeleting("h", time.Now()); err != nil || !marked {
+			t.Fatalf("Failed to claim block: %v", err)
+		}
+
+		ids, err := index.BeginPut([]*Intent{{Hash: "h", CellID: "cell1", BucketID: "b2", VolumeID: "v2", Size: 1, Tenant: "red", Owner: "doc", CreatedAt: now}})
+		if err != nil {
+			t.Fatalf("Failed to begin put: %v", err)
+		}
+		result, err := index.CommitPut(ids[0], false, now)
+		if err != nil || !result.Deleting {
+			t.Fatalf("Expected the commit to find the block deleting, got %+v %v", result, err)
+		}
+		if referenced, _ := index.Referenced([]string{"h"}, "red"); referenced["h"] {
+			t.Error("Nothing should be committed for a deleting block")
+		}
+		if intents, _ := index.ListIntents(time.Now().Add(time.Hour), 10); len(intents) != 1 {
+			t.Errorf("Expected the intent to be kept, got %d", len(intents))
+		}
+	})
+}
+
+func TestTenantRefs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		for _, hash := range []string{"legacy", "shared", "private"} {
+			if _, err := index.PutEntry(&Entry{Hash: hash, CellID: "cell1", BucketID: "b1", Checksum: hash, VolumeID: "v1", CreatedAt: now}); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+
+		// The same owner name in two tenants holds two separate references
+		if _, err := index.AddRefs([]string{"legacy"}, "", "alice", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		if _, err := index.AddRefs([]string{"shared", "private"}, "red", "photo", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		if _, err := index.AddRefs([]string{"shared"}, "blue", "photo", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+
+		hashes := []string{"legacy", "shared", "private", "missing"}
+		tests := []struct {
+			tenant string
+			want   []string
+		}{
+			{"red", []string{"shared", "private"}},
+			{"blue", []string{"shared"}},
+			{"", []string{"legacy"}},
+		}
+		for _, tt := range tests {
+			referenced, err := index.Referenced(hashes, tt.tenant)
+			if err != nil {
+				t.Fatalf("Failed to get references: %v", err)
+			}
+			if len(referenced) != len(tt.want) {
+				t.Errorf("Tenant %q: expected %v, got %v", tt.tenant, tt.want, referenced)
+			}
+			for _, hash := range tt.want {
+				if !referenced[hash] {
+					t.Errorf("Tenant %q should reference %s", tt.tenant, hash)
+				}
+			}
+		}
+
+		// A tenant cannot release another tenant's reference
+		if ok, _, _ := index.Release("private", "blue", "photo", time.Now()); ok {
+			t.Error("Released a reference held by another tenant")
+		}
+		ok, remaining, err := index.Release("shared", "blue", "photo", time.Now())
+		if err != nil || !ok || remaining != 1 {
+			t.Errorf("Expected release leaving one reference, got %v %d %v", ok, remaining, err)
+		}
+
+		// Intents commit their tenant's reference
+		ids, err := index.BeginPut([]*Intent{{Hash: "new", CellID: "cell1", BucketID: "b1", VolumeID: "v1", Size: 1, Tenant: "blue", Owner: "doc", CreatedAt: now}})
+		if err != nil {
+			t.Fatalf("Failed to begin put: %v", err)
+		}
+		if _, err := index.CommitPut(ids[0], false, now); err != nil {
+			t.Fatalf("Failed to commit put: %v", err)
+		}
+		if referenced, _ := index.Referenced([]string{"new"}, "blue"); !referenced["new"] {
+			t.Error("Committed intent should be referenced by its tenant")
+		}
+	})
+}
+
+func TestTenantUsage(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+
+		for hash, size := range map[string]int64{"a": 100, "b": 10} {
+			if _, err := index.PutEntry(&Entry{Hash: hash, CellID: "cell1", BucketID: "b1", Checksum: hash, VolumeID: "v1", Size: size, CreatedAt: now}); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+
+		expect := func(tenant string, bytes, blocks int64) {
+			t.Helper()
+			usage, err := index.GetUsage(tenant)
+			if err != nil {
+				t.Fatalf("Failed to get usage: %v", err)
+			}
+			if usage.Tenant != tenant || usage.Bytes != bytes || usage.Blocks != blocks {
+				t.Errorf("Tenant %q: expected %d bytes in %d blocks, got %d in %d", tenant, bytes, blocks, usage.Bytes, usage.Blocks)
+			}
+		}
+
+		// A block counts once per tenant however many of its owners reference it,
+		// and in full for every tenant sharing it
+		if _, err := index.AddRefs([]string{"a", "b"}, "red", "photo", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		if _, err := index.AddRefs([]string{"a"}, "red", "backup", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		if _, err := index.AddRefs([]string{"a"}, "blue", "photo", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		expect("red", 110, 2)
+		expect("blue", 100, 1)
+		expect("green", 0, 0)
+
+		index.Release("a", "red", "photo", time.Now())
+		expect("red", 110, 2)
+		index.Release("a", "red", "backup", time.Now())
+		expect("red", 10, 1)
+		index.Release("a", "red", "backup", time.Now())
+		expect("red", 10, 1)
+
+		ids, err := index.BeginPut([]*Intent{{Hash: "c", CellID: "cell1", BucketID: "b1", VolumeID: "v1", Size: 5, Tenant: "blue", Owner: "doc", CreatedAt: now}})
+		if err != nil {
+			t.Fatalf("Failed to begin put: %v", err)
+		}
+		expect("blue", 100, 1)
+		if _, err := index.CommitPut(ids[0], false, now); err != nil {
+			t.Fatalf("Failed to commit put: %v", err)
+		}
+		expect("blue", 105, 2)
+	})
+}
+
+func TestMoveRange(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		srcPath := filepath.Join(t.TempDir(), "src")
+		src, err := b.open(srcPath)
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		dst := b.newIndex(t)
+
+		for _, hash := range []string{"10", "50", "70", "90"} {
+			if _, err := src.PutEntry(&Entry{Hash: hash, CellID: "cell1", BucketID: "b1", Checksum: hash, VolumeID: "v1", Size: 10, CreatedAt: now}); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+		if _, err := src.AddRefs([]string{"10", "50", "70"}, "red", "photo", now); err != nil {
+			t.Fatalf("Failed to add refs: %v", err)
+		}
+		if _, err := src.BeginPut([]*Intent{{Hash: "60", CellID: "cell1", BucketID: "b1", VolumeID: "v1", Size: 1, Owner: "photo", CreatedAt: now}}); err != nil {
+			t.Fatalf("Failed to begin put: %v", err)
+		}
+
+		// A stale block left on the destination by an earlier attempt is removed
+		// by importing the range over it
+		if _, err := dst.PutEntry(&Entry{Hash: "60", CellID: "cell1", BucketID: "b1", Checksum: "60", VolumeID: "v1", Size: 10, CreatedAt: now}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+
+		r := shards.Range{Start: "50", End: "80"}
+		if n, err := src.CountIntents(r); err != nil || n != 1 {
+			t.Errorf("Expected one intent in the range, got %d %v", n, err)
+		}
+		records, err := src.ExportRange(r, "", 1)
+		if err != nil {
+			t.Fatalf("Failed to export range: %v", err)
+		}
+		if len(records) != 1 || records[0].Hash != "50" || len(records[0].Refs) != 1 {
+			t.Fatalf("Expected the first page to hold block 50 and its ref, got %+v", records)
+		}
+		if ref := records[0].Refs[0]; ref.Tenant != "red" || ref.Owner != "photo" || ref.CreatedAt.IsZero() {
+			t.Errorf("Unexpected exported ref %+v", ref)
+		}
+		if records, err = src.ExportRange(r, "50", 10); err != nil {
+			t.Fatalf("Failed to export range: %v", err)
+		}
+		if len(records) != 1 || records[0].Hash != "70" {
+			t.Fatalf("Expected the second page to hold block 70, got %+v", records)
+		}
+		if records, err = src.ExportRange(r, "", 10); err != nil {
+			t.Fatalf("Failed to export range: %v", err)
+		}
+
+		for range 2 {
+			if err := dst.ImportBlocks(records, &r); err != nil {
+				t.Fatalf("Failed to import blocks: %v", err)
+			}
+		}
+		if exists, _ := dst.Exists("60"); exists {
+			t.Error("Block 60 should have been removed by the import")
+		}
+		entry, err := dst.GetEntry("70")
+		if err != nil || entry == nil || entry.Checksum != "70" || entry.Size != 10 {
+			t.Fatalf("Expected block 70 to be imported, got %+v (%v)", entry, err)
+		}
+		usage, err := dst.GetUsage("red")
+		if err != nil {
+			t.Fatalf("Failed to get usage: %v", err)
+		}
+		if usage.Bytes != 20 || usage.Blocks != 2 {
+			t.Errorf("Expected imported usage of 20 bytes in 2 blocks, got %d in %d", usage.Bytes, usage.Blocks)
+		}
+		if referenced, _ := dst.Referenced([]string{"70"}, "red"); !referenced["70"] {
+			t.Error("Imported references should be kept")
+		}
+
+		// A block changed after the bulk copy is copied again, and a deleted one
+		// is removed by its missing record
+		src.Release("50", "red", "photo", time.Now().Add(-time.Hour))
+		if marked, err := src.MarkDeleting("50", time.Now()); err != nil || !marked {
+			t.Fatalf("Failed to mark block deleting: %v", err)
+		}
+		if removed, err := src.RemoveEntry("50"); err != nil || !removed {
+			t.Fatalf("Failed to remove entry: %v", err)
+		}
+		if records, err = src.ExportBlocks([]string{"70", "50", "70"}); err != nil {
+			t.Fatalf("Failed to export blocks: %v", err)
+		}
+		if len(records) != 2 || records[0].Hash != "50" || !records[0].Missing || records[1].Missing {
+			t.Fatalf("Expected missing block 50 and block 70, got %+v", records)
+		}
+		if err := dst.ImportBlocks(records, nil); err != nil {
+			t.Fatalf("Failed to import blocks: %v", err)
+		}
+		if exists, _ := dst.Exists("50"); exists {
+			t.Error("Block 50 should have been removed by its missing record")
+		}
+		if usage, err = dst.GetUsage("red"); err != nil {
+			t.Fatalf("Failed to get usage: %v", err)
+		}
+		if usage.Bytes != 10 || usage.Blocks != 1 {
+			t.Errorf("Expected usage of 10 bytes in 1 block after removing block 50, got %d in %d", usage.Bytes, usage.Blocks)
+		}
+
+		if err := src.DropRange(r); err != nil {
+			t.Fatalf("Failed to drop range: %v", err)
+		}
+		if exists, _ := src.Exists("70"); exists {
+			t.Error("Block 70 should have been dropped from the source")
+		}
+		if n, _ := src.CountIntents(r); n != 0 {
+			t.Errorf("Expected the intents of the range to be dropped, got %d", n)
+		}
+		if !src.Moved("70") || src.Moved("90") || src.Moved("10") {
+			t.Error("Expected only the dropped range to be moved")
+		}
+		if usage, err = src.GetUsage("red"); err != nil {
+			t.Fatalf("Failed to get usage: %v", err)
+		}
+		if usage.Bytes != 10 || usage.Blocks != 1 {
+			t.Errorf("Expected source usage of 10 bytes in 1 block, got %d in %d", usage.Bytes, usage.Blocks)
+		}
+
+		// Moved ranges are remembered across restarts
+		src.Close()
+		if !b.persistent {
+			return
+		}
+		src = b.openIndex(t, srcPath)
+		if !src.Moved("70") {
+			t.Error("Range should still be moved after reopening the index")
+		}
+	})
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		dir := t.TempDir()
+		index := b.newIndex(t)
+
+		put := func(hash string) {
+			t.Helper()
+			if _, err := index.PutEntry(&Entry{Hash: hash, CellID: "cell1", BucketID: "b1", Checksum: hash, VolumeID: "v1", Size: 10, CreatedAt: now}); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+			if _, err := index.AddRefs([]string{hash}, "red", "photo", now); err != nil {
+				t.Fatalf("Failed to add refs: %v", err)
+			}
+		}
+		put("10")
+		put("90")
+		for _, r := range []shards.Range{{End: "05"}, {Start: "80"}} {
+			if err := index.DropRange(r); err != nil {
+				t.Fatalf("Failed to drop range: %v", err)
+			}
+		}
+
+		snapshot := filepath.Join(dir, "snapshot")
+		if err := index.Snapshot(snapshot); err != nil {
+			t.Fatalf("Failed to snapshot: %v", err)
+		}
+		put("20")
+
+		// Restoring replaces the index, into the same or another instance
+		other := b.newIndex(t)
+		for _, restored := range []Index{index, other} {
+			if err := restored.Restore(snapshot); err != nil {
+				t.Fatalf("Failed to restore: %v", err)
+			}
+			existing, err := restored.ExistsBatch([]string{"10", "20", "90"})
+			if err != nil || len(existing) != 1 || !existing["10"] {
+				t.Errorf("Expected only block 10 after restoring, got %v %v", existing, err)
+			}
+			if usage, _ := restored.GetUsage("red"); usage.Blocks != 1 || usage.Bytes != 10 {
+				t.Errorf("Expected restored usage of 10 bytes in 1 block, got %+v", usage)
+			}
+			if !restored.Moved("01") || !restored.Moved("90") || restored.Moved("50") {
+				t.Error("Restored index should know the moved ranges")
+			}
+		}
+
+		// The restored index keeps working
+		put("30")
+		if exists, _ := index.Exists("30"); !exists {
+			t.Error("Failed to write after restoring")
+		}
+	})
+}
+
+func TestMigrationSurvivesSnapshot(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		index := b.newIndex(t)
+		r := shards.Range{Start: "50", End: "80"}
+
+		if _, err := index.TakeChanges(r); !errors.Is(err, ErrNotMigrating) {
+			t.Errorf("Expected ErrNotMigrating before the migration begins, got %v", err)
+		}
+		if err := index.BeginMigration(r); err != nil {
+			t.Fatalf("Failed to begin migration: %v", err)
+		}
+		if err := index.RecordChanges([]string{"60", "10", "70"}); err != nil {
+			t.Fatalf("Failed to record changes: %v", err)
+		}
+		if err := index.FreezeRange(r); err != nil {
+			t.Fatalf("Failed to freeze range: %v", err)
+		}
+
+		// A restored index carries on with the migration where it was
+		snapshot := filepath.Join(t.TempDir(), "snapshot")
+		if err := index.Snapshot(snapshot); err != nil {
+			t.Fatalf("Failed to snapshot: %v", err)
+		}
+		restored := b.newIndex(t)
+		if err := restored.Restore(snapshot); err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		if !restored.Frozen("60") || restored.Frozen("10") {
+			t.Error("Restored index should know the frozen range")
+		}
+		changes, err := restored.TakeChanges(r)
+		if err != nil || !slices.Equal(changes, []string{"60", "70"}) {
+			t.Errorf("Expected changes 60 and 70 after restoring, got %v %v", changes, err)
+		}
+		if changes, _ = restored.TakeChanges(r); len(changes) != 0 {
+			t.Errorf("Expected changes to be forgotten once taken, got %v", changes)
+		}
+
+		// Starting over unfreezes the range, and dropping it ends the migration
+		if err := restored.BeginMigration(r); err != nil {
+			t.Fatalf("Failed to begin migration: %v", err)
+		}
+		if restored.Frozen("60") {
+			t.Error("Starting a migration over should unfreeze its range")
+		}
+		if err := restored.DropRange(r); err != nil {
+			t.Fatalf("Failed to drop range: %v", err)
+		}
+		if err := restored.FreezeRange(r); !errors.Is(err, ErrNotMigrating) {
+			t.Errorf("Expected ErrNotMigrating after dropping the range, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+package blockindex
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Intent states. A pending intent belongs to a Put that is still writing
+// replicas. A sweeping intent has been claimed by the sweeper after its Put
+// timed out, and can no longer be committed by the writer.
+const (
+	IntentPending  = "pending"
+	IntentSweeping = "sweeping"
+)
+
+var (
+	// ErrIntentNotFound is returned when committing an intent that was
+	// committed or aborted already
+	ErrIntentNotFound = errors.New("intent not found")
+
+	// ErrIntentClaimed is returned when a writer commits an intent the
+	// sweeper has claimed
+	ErrIntentClaimed = errors.New("intent was claimed by the sweeper")
+)
+
+// Intent records a Put that is writing replicas to a bucket but has not been
+// indexed yet, so that replicas left behind by a failed or interrupted Put
+// can be found again
+type Intent struct {
+	ID        int64
+	Hash      string
+	CellID    string
+	BucketID  string
+	VolumeID  string
+	Size      int64
+	Tenant    string // Tenant the committed reference is held for
+	Owner     string // Reference added when the intent is committed
+	State     string
+	CreatedAt time.Time
+}
+
+// CommitResult reports the outcome of committing an intent
+type CommitResult struct {
+	// Hash is the intent's block
+	Hash string
+
+	// Deleting is set when the block is being garbage collected. Nothing was
+	// committed and the intent is kept.
+	Deleting bool
+
+	// Duplicate is set when the block was already indexed in another bucket.
+	// The owner's reference was added to the existing entry and the intent is
+	// kept, since its replicas are surplus and must be deleted before it is
+	// aborted.
+	Duplicate bool
+}
+
+// BeginPut records pending intents, created at their CreatedAt, and returns
+// their IDs in order
+func (i *SQLiteIndex) BeginPut(intents []*Intent) ([]int64, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO intents (hash, cell_id, bucket_id, volume_id, size, tenant, owner, state, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	ids := make([]int64, 0, len(intents))
+	for _, intent := range intents {
+		result, err := tx.Exec(query, intent.Hash, intent.CellID, intent.BucketID, intent.VolumeID, intent.Size, intent.Tenant, intent.Owner, IntentPending, intent.CreatedAt.Unix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to record intent: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to record intent: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit intents: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CommitPut indexes the block of an intent, adds the intent owner's reference
+// and removes the intent, all in one transaction. The block and reference are
+// recorded as created at at. Writers commit pending intents; the sweeper sets
+// claimed to commit an intent it has claimed.
+func (i *SQLiteIndex) CommitPut(id int64, claimed bool, at time.Time) (*CommitResult, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var intent Intent
+	err = tx.QueryRow(`SELECT hash, cell_id, bucket_id, volume_id, size, tenant, owner, state FROM intents WHERE id = ?`, id).Scan(
+		&intent.Hash,
+		&intent.CellID,
+		&intent.BucketID,
+		&intent.VolumeID,
+		&intent.Size,
+		&intent.Tenant,
+		&intent.Owner,
+		&intent.State,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrIntentNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get intent: %w", err)
+	}
+	if claimed != (intent.State == IntentSweeping) {
+		if claimed {
+			return nil, fmt.Errorf("intent %d has not been claimed", id)
+		}
+		return nil, fmt.Errorf("%w: %d", ErrIntentClaimed, id)
+	}
+
+	var state, bucketID string
+	err = tx.QueryRow(`SELECT state, bucket_id FROM blocks WHERE hash = ?`, intent.Hash).Scan(&state, &bucketID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get block state: %w", err)
+	}
+
+	result := &CommitResult{Hash: intent.Hash}
+	switch {
+	case state == StateDeleting:
+		result.Deleting = true
+		return result, nil
+	case err == sql.ErrNoRows:
+		query := `
+		INSERT INTO blocks (hash, cell_id, bucket_id, checksum, volume_id, size, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		`
+		_, err := tx.Exec(query, intent.Hash, intent.CellID, intent.BucketID, intent.Hash, intent.VolumeID, intent.Size, at.Unix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to put entry: %w", err)
+		}
+	case bucketID != intent.BucketID:
+		result.Duplicate = true
+	}
+
+	if err := addRef(tx, intent.Hash, intent.Tenant, intent.Owner, at); err != nil {
+		return nil, err
+	}
+
+	if !result.Duplicate {
+		if _, err := tx.Exec(`DELETE FROM intents WHERE id = ?`, id); err != nil {
+			return nil, fmt.Errorf("failed to remove intent: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit put: %w", err)
+	}
+
+	return result, nil
+}
+
+// AbortPut removes an intent whose replicas will not be committed. It reports
+// whether the intent existed, and whether the block is indexed in the
+// intent's bucket, in which case a concurrent Put of the same block committed
+// the replicas and they must not be deleted.
+func (i *SQLiteIndex) AbortPut(id int64) (bool, bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	indexed, err := intentIndexed(tx, id)
+	if err != nil {
+		return false, false, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM intents WHERE id = ?`, id)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to abort intent: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to abort intent: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, false, fmt.Errorf("failed to abort intent: %w", err)
+	}
+
+	return n > 0, indexed, nil
+}
+
+// intentIndexed reports whether the block of an intent is indexed in the
+// intent's bucket
+func intentIndexed(tx *sql.Tx, id int64) (bool, error) {
+	var indexed bool
+	err := tx.QueryRow(`
+	SELECT EXISTS (
+		SELECT 1 FROM intents JOIN blocks ON blocks.hash = intents.hash AND blocks.bucket_id = intents.bucket_id
+		WHERE intents.id = ?
+	)
+	`, id).Scan(&indexed)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up intent's block: %w", err)
+	}
+	return indexed, nil
+}
+
+// ListIntents returns pending intents created before cutoff, together with
+// claimed intents left behind by an interrupted sweep
+func (i *SQLiteIndex) ListIntents(cutoff time.Time, limit int) ([]*Intent, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	query := `
+	SELECT id, hash, cell_id, bucket_id, volume_id, size, tenant, owner, state, created_at
+	FROM intents
+	WHERE state = ? OR created_at < ?
+	ORDER BY id
+	LIMIT ?
+	`
+
+	rows, err := i.db.Query(query, IntentSweeping, cutoff.Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list intents: %w", err)
+	}
+	defer rows.Close()
+
+	intents := make([]*Intent, 0)
+	for rows.Next() {
+		var (
+			intent    Intent
+			createdAt int64
+		)
+		err := rows.Scan(
+			&intent.ID,
+			&intent.Hash,
+			&intent.CellID,
+			&intent.BucketID,
+			&intent.VolumeID,
+			&intent.Size,
+			&intent.Tenant,
+			&intent.Owner,
+			&intent.State,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan intent: %w", err)
+		}
+		intent.CreatedAt = time.Unix(createdAt, 0)
+		intents = append(intents, &intent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list intents: %w", err)
+	}
+
+	return intents, nil
+}
+
+// ClaimIntent hands an intent to the sweeper. It succeeds if the intent is
+// still pending and was created before cutoff, or was claimed already, so a
+// writer that is merely slow either commits first or finds its intent claimed.
+// Like AbortPut, it also reports whether the block is indexed in the intent's
+// bucket.
+func (i *SQLiteIndex) ClaimIntent(id int64, cutoff time.Time) (bool, bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE intents SET state = ?
+	WHERE id = ? AND (state = ? OR created_at < ?)
+	`
+
+	result, err := tx.Exec(query, IntentSweeping, id, IntentSweeping, cutoff.Unix())
+	if err != nil {
+		return false, false, fmt.Errorf("failed to claim intent: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to claim intent: %w", err)
+	}
+
+	indexed, err := intentIndexed(tx, id)
+	if err != nil {
+		return false, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, false, fmt.Errorf("failed to claim intent: %w", err)
+	}
+
+	return n > 0, indexed, nil
+}
@@ -0,0 +1,466 @@
+package blockindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"bharani/pkg/shards"
+)
+
+// kvStore is an ordered key-value store of named buckets with serializable
+// transactions, which KVIndex keeps its tables in
+type kvStore interface {
+	// view runs fn in a read-only transaction
+	view(fn func(tx kvTx) error) error
+
+	// update runs fn in a read-write transaction, which is rolled back if fn
+	// fails
+	update(fn func(tx kvTx) error) error
+
+	snapshot(path string) error
+	restore(path string) error
+	close() error
+}
+
+// kvTx is a transaction of a kvStore. Values are only valid until the
+// transaction ends, and a bucket must not be changed while it is scanned.
+type kvTx interface {
+	get(bucket, key string) []byte
+	put(bucket, key string, value []byte) error
+	delete(bucket, key string) error
+
+	// scan calls fn for each key of bucket from start onwards, in order,
+	// until fn returns false
+	scan(bucket, start string, fn func(key string, value []byte) bool) error
+}
+
+// Buckets of a KVIndex. Keys joining several fields separate them with a NUL
+// byte, which never appears in hashes, tenants, owners or volume IDs.
+const (
+	bucketBlocks       = "blocks"            // hash -> kvBlock
+	bucketRefs         = "refs"              // hash, tenant, owner -> creation time
+	bucketUsage        = "usage"             // tenant -> TenantUsage
+	bucketIntents      = "intents"           // intent ID -> kvIntent
+	bucketMoved        = "moved"             // range start -> range end
+	bucketMigrations   = "migrations"        // range start, range end -> "true" once frozen
+	bucketChanges      = "migration_changes" // range start, range end, hash written since -> nothing
+	bucketMeta         = "meta"              // counters
+	bucketVolumes      = "volumes"           // volume ID, hash -> nothing
+	bucketUnreferenced = "unreferenced"      // unreferenced time, hash of live blocks -> nothing
+	bucketDeleting     = "deleting"          // hash of deleting blocks -> nothing
+	bucketVolumeUsage  = "volume_usage"      // cell ID, volume ID -> VolumeUsage of live blocks
+)
+
+// kvBuckets lists every bucket, which engines create when they open
+var kvBuckets = []string{
+	bucketBlocks, bucketRefs, bucketUsage, bucketIntents, bucketMoved, bucketMeta,
+	bucketVolumes, bucketUnreferenced, bucketDeleting, bucketVolumeUsage,
+	bucketMigrations, bucketChanges,
+}
+
+// KVIndex is an index kept in an ordered key-value store. Besides the blocks
+// themselves it maintains the secondary indexes the SQLite backend gets from
+// its SQL indexes: blocks by volume, live blocks by unreferenced time,
+// deleting blocks, and live usage by volume.
+type KVIndex struct {
+	db         kvStore
+	moved      []shards.Range        // Ranges handed to other index nodes
+	migrations map[shards.Range]bool // Ranges being copied to other nodes, and whether they are frozen
+	mu         sync.RWMutex          // Guards moved and migrations
+}
+
+// kvBlock is the stored form of a block
+type kvBlock struct {
+	CellID         string `json:"cell_id"`
+	BucketID       string `json:"bucket_id"`
+	Checksum       string `json:"checksum"`
+	VolumeID       string `json:"volume_id"`
+	Size           int64  `json:"size"`
+	CreatedAt      int64  `json:"created_at"`
+	VerifiedAt     int64  `json:"verified_at"`
+	State          string `json:"state"`
+	UnreferencedAt int64  `json:"unreferenced_at"`
+}
+
+// newKVIndex opens an index in a store
+func newKVIndex(db kvStore) (*KVIndex, error) {
+	index := &KVIndex{db: db}
+	if err := index.loadRanges(); err != nil {
+		db.close()
+		return nil, err
+	}
+	return index, nil
+}
+
+// loadRanges reads the ranges this node has handed to other index nodes, and
+// those being copied to them
+func (i *KVIndex) loadRanges() error {
+	moved := make([]shards.Range, 0)
+	migrations := make(map[shards.Range]bool)
+	err := i.db.view(func(tx kvTx) error {
+		err := tx.scan(bucketMoved, "", func(start string, end []byte) bool {
+			moved = append(moved, shards.Range{Start: start, End: string(end)})
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return tx.scan(bucketMigrations, "", func(key string, frozen []byte) bool {
+			start, end, _ := strings.Cut(key, "\x00")
+			migrations[shards.Range{Start: start, End: end}] = string(frozen) == "true"
+			return true
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list moved ranges: %w", err)
+	}
+
+	i.mu.Lock()
+	i.moved = moved
+	i.migrations = migrations
+	i.mu.Unlock()
+	return nil
+}
+
+// joinKey joins the fields of a compound key
+func joinKey(fields ...string) string {
+	return strings.Join(fields, "\x00")
+}
+
+// timeKey encodes a Unix time so that keys sort in time order
+func timeKey(unix int64) string {
+	return fmt.Sprintf("%016x", uint64(unix))
+}
+
+// getJSON decodes a stored value into v, reporting whether the key exists
+func getJSON(tx kvTx, bucket, key string, v any) (bool, error) {
+	data := tx.get(bucket, key)
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode %s %q: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// putJSON stores v encoded as JSON
+func putJSON(tx kvTx, bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %q: %w", bucket, key, err)
+	}
+	return tx.put(bucket, key, data)
+}
+
+// scanPrefix calls fn for each key of bucket starting with prefix, in order
+func scanPrefix(tx kvTx, bucket, prefix string, fn func(key string, value []byte) bool) error {
+	return tx.scan(bucket, prefix, func(key string, value []byte) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return false
+		}
+		return fn(key, value)
+	})
+}
+
+// getBlock returns a stored block, or nil if it is not indexed
+func getBlock(tx kvTx, hash string) (*kvBlock, error) {
+	var block kvBlock
+	found, err := getJSON(tx, bucketBlocks, hash, &block)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// putBlock replaces the stored block old with block, keeping the secondary
+// indexes up to date. A nil old adds the block and a nil block removes it.
+func putBlock(tx kvTx, hash string, old, block *kvBlock) error {
+	if old != nil {
+		if err := tx.delete(bucketVolumes, joinKey(old.VolumeID, hash)); err != nil {
+			return err
+		}
+		if old.State == StateLive && old.UnreferencedAt > 0 {
+			if err := tx.delete(bucketUnreferenced, timeKey(old.UnreferencedAt)+hash); err != nil {
+				return err
+			}
+		}
+		if old.State == StateDeleting {
+			if err := tx.delete(bucketDeleting, hash); err != nil {
+				return err
+			}
+		}
+		if old.State == StateLive {
+			if err := addVolumeUsage(tx, old, -1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if block == nil {
+		return tx.delete(bucketBlocks, hash)
+	}
+
+	if err := putJSON(tx, bucketBlocks, hash, block); err != nil {
+		return err
+	}
+	if err := tx.put(bucketVolumes, joinKey(block.VolumeID, hash), []byte{}); err != nil {
+		return err
+	}
+	if block.State == StateLive && block.UnreferencedAt > 0 {
+		if err := tx.put(bucketUnreferenced, timeKey(block.UnreferencedAt)+hash, []byte{}); err != nil {
+			return err
+		}
+	}
+	if block.State == StateDeleting {
+		if err := tx.put(bucketDeleting, hash, []byte{}); err != nil {
+			return err
+		}
+	}
+	if block.State == StateLive {
+		if err := addVolumeUsage(tx, block, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addVolumeUsage adds (sign 1) or removes (sign -1) a live block from the
+// usage of its volume
+func addVolumeUsage(tx kvTx, block *kvBlock, sign int64) error {
+	key := joinKey(block.CellID, block.VolumeID)
+
+	var usage VolumeUsage
+	if _, err := getJSON(tx, bucketVolumeUsage, key, &usage); err != nil {
+		return err
+	}
+	usage.VolumeID = block.VolumeID
+	usage.LiveBytes += sign * block.Size
+	usage.Blocks += sign
+
+	if usage.Blocks == 0 {
+		return tx.delete(bucketVolumeUsage, key)
+	}
+	return putJSON(tx, bucketVolumeUsage, key, &usage)
+}
+
+// entry converts a stored block to an entry
+func (b *kvBlock) entry(hash string) *Entry {
+	entry := &Entry{
+		Hash:      hash,
+		CellID:    b.CellID,
+		BucketID:  b.BucketID,
+		Checksum:  b.Checksum,
+		VolumeID:  b.VolumeID,
+		Size:      b.Size,
+		CreatedAt: time.Unix(b.CreatedAt, 0),
+	}
+	if b.VerifiedAt > 0 {
+		entry.VerifiedAt = time.Unix(b.VerifiedAt, 0)
+	}
+	return entry
+}
+
+// PutEntry adds an entry for a block that is not indexed yet, created at
+// entry.CreatedAt. If the block is indexed already nothing is changed and the
+// existing entry is returned, so that writers racing to store the same block
+// agree on a single location. It returns ErrDeleting if the block is being
+// garbage collected, since its replicas may already be gone.
+func (i *KVIndex) PutEntry(entry *Entry) (*Entry, error) {
+	var existing *Entry
+	err := i.db.update(func(tx kvTx) error {
+		old, err := getBlock(tx, entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to put entry: %w", err)
+		}
+		if old != nil {
+			if old.State == StateDeleting {
+				return fmt.Errorf("%w: %s", ErrDeleting, entry.Hash)
+			}
+			existing = old.entry(entry.Hash)
+			return nil
+		}
+
+		block := &kvBlock{
+			CellID:    entry.CellID,
+			BucketID:  entry.BucketID,
+			Checksum:  entry.Checksum,
+			VolumeID:  entry.VolumeID,
+			Size:      entry.Size,
+			CreatedAt: entry.CreatedAt.Unix(),
+			State:     StateLive,
+		}
+		if err := putBlock(tx, entry.Hash, nil, block); err != nil {
+			return fmt.Errorf("failed to put entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// UpdateEntry moves an indexed block to the entry's location if it is still
+// at the expected one, and returns the updated entry. The block keeps its
+// creation and verification times, its references, and its size when
+// entry.Size is 0. If the block has moved since, nothing is changed and
+// ErrLocationChanged is returned with the entry as it is now, or with nil if
+// the block is no longer indexed. It returns ErrDeleting if the block is being
+// garbage collected.
+func (i *KVIndex) UpdateEntry(entry *Entry, expected Location) (*Entry, error) {
+	var current *Entry
+	err := i.db.update(func(tx kvTx) error {
+		old, err := getBlock(tx, entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to update entry: %w", err)
+		}
+		if old != nil && old.State == StateDeleting {
+			return fmt.Errorf("%w: %s", ErrDeleting, entry.Hash)
+		}
+		if old != nil {
+			current = old.entry(entry.Hash)
+		}
+		if current == nil || current.Location() != expected {
+			return fmt.Errorf("%w: %s", ErrLocationChanged, entry.Hash)
+		}
+
+		updated := *old
+		updated.CellID = entry.CellID
+		updated.BucketID = entry.BucketID
+		updated.Checksum = entry.Checksum
+		updated.VolumeID = entry.VolumeID
+		if entry.Size > 0 {
+			updated.Size = entry.Size
+		}
+		if err := putBlock(tx, entry.Hash, old, &updated); err != nil {
+			return fmt.Errorf("failed to update entry: %w", err)
+		}
+		current = updated.entry(entry.Hash)
+		return nil
+	})
+	if errors.Is(err, ErrLocationChanged) {
+		return current, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// GetEntry retrieves a block index entry by hash
+func (i *KVIndex) GetEntry(hash string) (*Entry, error) {
+	var entry *Entry
+	err := i.db.view(func(tx kvTx) error {
+		block, err := getBlock(tx, hash)
+		if block != nil {
+			entry = block.entry(hash)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	return entry, nil
+}
+
+// MarkVerified records that a scrub found every replica of a block intact,
+// filling in the block size if the entry does not have one yet. It reports
+// whether the entry exists.
+func (i *KVIndex) MarkVerified(hash string, size int64, at time.Time) (bool, error) {
+	var found bool
+	err := i.db.update(func(tx kvTx) error {
+		old, err := getBlock(tx, hash)
+		if err != nil || old == nil {
+			return err
+		}
+		found = true
+
+		block := *old
+		block.VerifiedAt = at.Unix()
+		if block.Size == 0 {
+			block.Size = size
+		}
+		return putBlock(tx, hash, old, &block)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark entry verified: %w", err)
+	}
+	return found, nil
+}
+
+// Exists checks if a block exists in the index
+func (i *KVIndex) Exists(hash string) (bool, error) {
+	var exists bool
+	err := i.db.view(func(tx kvTx) error {
+		exists = tx.get(bucketBlocks, hash) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+	return exists, nil
+}
+
+// ExistsBatch returns the subset of hashes present in the index
+func (i *KVIndex) ExistsBatch(hashes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	err := i.db.view(func(tx kvTx) error {
+		for _, hash := range hashes {
+			if tx.get(bucketBlocks, hash) != nil {
+				existing[hash] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existence: %w", err)
+	}
+	return existing, nil
+}
+
+// GetEntries retrieves the entries for the given hashes, omitting missing ones
+func (i *KVIndex) GetEntries(hashes []string) (map[string]*Entry, error) {
+	entries := make(map[string]*Entry)
+	err := i.db.view(func(tx kvTx) error {
+		for _, hash := range hashes {
+			block, err := getBlock(tx, hash)
+			if err != nil {
+				return err
+			}
+			if block != nil {
+				entries[hash] = block.entry(hash)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Snapshot writes a consistent copy of the index to path
+func (i *KVIndex) Snapshot(path string) error {
+	if err := i.db.snapshot(path); err != nil {
+		return fmt.Errorf("failed to snapshot index: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the index with a copy written by Snapshot
+func (i *KVIndex) Restore(path string) error {
+	if err := i.db.restore(path); err != nil {
+		return fmt.Errorf("failed to restore index: %w", err)
+	}
+	return i.loadRanges()
+}
+
+// Close closes the store
+func (i *KVIndex) Close() error {
+	return i.db.close()
+}
@@ -0,0 +1,255 @@
+package blockindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// keyLastIntent is the meta key holding the last intent ID handed out
+const keyLastIntent = "last_intent"
+
+// kvIntent is the stored form of an intent
+type kvIntent struct {
+	Hash      string `json:"hash"`
+	CellID    string `json:"cell_id"`
+	BucketID  string `json:"bucket_id"`
+	VolumeID  string `json:"volume_id"`
+	Size      int64  `json:"size"`
+	Tenant    string `json:"tenant"`
+	Owner     string `json:"owner"`
+	State     string `json:"state"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// intentKey is the key of an intent, which sorts in ID order
+func intentKey(id int64) string {
+	return fmt.Sprintf("%016x", uint64(id))
+}
+
+// intent converts a stored intent to an intent
+func (in *kvIntent) intent(id int64) *Intent {
+	return &Intent{
+		ID:        id,
+		Hash:      in.Hash,
+		CellID:    in.CellID,
+		BucketID:  in.BucketID,
+		VolumeID:  in.VolumeID,
+		Size:      in.Size,
+		Tenant:    in.Tenant,
+		Owner:     in.Owner,
+		State:     in.State,
+		CreatedAt: time.Unix(in.CreatedAt, 0),
+	}
+}
+
+// scanIntents calls fn for each intent in ID order until fn returns false
+func scanIntents(tx kvTx, fn func(id int64, intent *kvIntent) bool) error {
+	var decodeErr error
+	err := tx.scan(bucketIntents, "", func(key string, value []byte) bool {
+		id, err := strconv.ParseInt(key, 16, 64)
+		if err != nil {
+			decodeErr = fmt.Errorf("invalid intent key %q: %w", key, err)
+			return false
+		}
+		var intent kvIntent
+		if err := json.Unmarshal(value, &intent); err != nil {
+			decodeErr = fmt.Errorf("failed to decode intent %d: %w", id, err)
+			return false
+		}
+		return fn(id, &intent)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// BeginPut records pending intents, created at their CreatedAt, and returns
+// their IDs in order
+func (i *KVIndex) BeginPut(intents []*Intent) ([]int64, error) {
+	ids := make([]int64, 0, len(intents))
+	err := i.db.update(func(tx kvTx) error {
+		ids = ids[:0]
+		var last int64
+		if _, err := getJSON(tx, bucketMeta, keyLastIntent, &last); err != nil {
+			return err
+		}
+
+		for _, intent := range intents {
+			last++
+			stored := &kvIntent{
+				Hash:      intent.Hash,
+				CellID:    intent.CellID,
+				BucketID:  intent.BucketID,
+				VolumeID:  intent.VolumeID,
+				Size:      intent.Size,
+				Tenant:    intent.Tenant,
+				Owner:     intent.Owner,
+				State:     IntentPending,
+				CreatedAt: intent.CreatedAt.Unix(),
+			}
+			if err := putJSON(tx, bucketIntents, intentKey(last), stored); err != nil {
+				return err
+			}
+			ids = append(ids, last)
+		}
+		return putJSON(tx, bucketMeta, keyLastIntent, last)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record intent: %w", err)
+	}
+	return ids, nil
+}
+
+// CommitPut indexes the block of an intent, adds the intent owner's reference
+// and removes the intent, all in one transaction. The block and reference are
+// recorded as created at at. Writers commit pending intents; the sweeper sets
+// claimed to commit an intent it has claimed.
+func (i *KVIndex) CommitPut(id int64, claimed bool, at time.Time) (*CommitResult, error) {
+	var result *CommitResult
+	err := i.db.update(func(tx kvTx) error {
+		var intent kvIntent
+		found, err := getJSON(tx, bucketIntents, intentKey(id), &intent)
+		if err != nil {
+			return fmt.Errorf("failed to get intent: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("%w: %d", ErrIntentNotFound, id)
+		}
+		if claimed != (intent.State == IntentSweeping) {
+			if claimed {
+				return fmt.Errorf("intent %d has not been claimed", id)
+			}
+			return fmt.Errorf("%w: %d", ErrIntentClaimed, id)
+		}
+
+		block, err := getBlock(tx, intent.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to get block state: %w", err)
+		}
+
+		result = &CommitResult{Hash: intent.Hash}
+		switch {
+		case block == nil:
+			block = &kvBlock{
+				CellID:    intent.CellID,
+				BucketID:  intent.BucketID,
+				Checksum:  intent.Hash,
+				VolumeID:  intent.VolumeID,
+				Size:      intent.Size,
+				CreatedAt: at.Unix(),
+				State:     StateLive,
+			}
+			if err := putBlock(tx, intent.Hash, nil, block); err != nil {
+				return fmt.Errorf("failed to put entry: %w", err)
+			}
+		case block.State == StateDeleting:
+			result.Deleting = true
+			return nil
+		case block.BucketID != intent.BucketID:
+			result.Duplicate = true
+		}
+
+		if err := kvAddRef(tx, intent.Hash, block, intent.Tenant, intent.Owner, at); err != nil {
+			return err
+		}
+
+		if !result.Duplicate {
+			if err := tx.delete(bucketIntents, intentKey(id)); err != nil {
+				return fmt.Errorf("failed to remove intent: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AbortPut removes an intent whose replicas will not be committed. It reports
+// whether the intent existed, and whether the block is indexed in the
+// intent's bucket, in which case a concurrent Put of the same block committed
+// the replicas and they must not be deleted.
+func (i *KVIndex) AbortPut(id int64) (bool, bool, error) {
+	var existed, indexed bool
+	err := i.db.update(func(tx kvTx) error {
+		var intent kvIntent
+		found, err := getJSON(tx, bucketIntents, intentKey(id), &intent)
+		if err != nil || !found {
+			return err
+		}
+		existed = true
+		if indexed, err = kvIntentIndexed(tx, &intent); err != nil {
+			return err
+		}
+		return tx.delete(bucketIntents, intentKey(id))
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("failed to abort intent: %w", err)
+	}
+	return existed, indexed, nil
+}
+
+// kvIntentIndexed reports whether the block of an intent is indexed in the
+// intent's bucket
+func kvIntentIndexed(tx kvTx, intent *kvIntent) (bool, error) {
+	block, err := getBlock(tx, intent.Hash)
+	if err != nil {
+		return false, err
+	}
+	return block != nil && block.BucketID == intent.BucketID, nil
+}
+
+// ListIntents returns pending intents created before cutoff, together with
+// claimed intents left behind by an interrupted sweep
+func (i *KVIndex) ListIntents(cutoff time.Time, limit int) ([]*Intent, error) {
+	intents := make([]*Intent, 0)
+	err := i.db.view(func(tx kvTx) error {
+		return scanIntents(tx, func(id int64, intent *kvIntent) bool {
+			if len(intents) == limit {
+				return false
+			}
+			if intent.State == IntentSweeping || intent.CreatedAt < cutoff.Unix() {
+				intents = append(intents, intent.intent(id))
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list intents: %w", err)
+	}
+	return intents, nil
+}
+
+// ClaimIntent hands an intent to the sweeper. It succeeds if the intent is
+// still pending and was created before cutoff, or was claimed already, so a
+// writer that is merely slow either commits first or finds its intent claimed.
+// Like AbortPut, it also reports whether the block is indexed in the intent's
+// bucket.
+func (i *KVIndex) ClaimIntent(id int64, cutoff time.Time) (bool, bool, error) {
+	var claimed, indexed bool
+	err := i.db.update(func(tx kvTx) error {
+		var intent kvIntent
+		found, err := getJSON(tx, bucketIntents, intentKey(id), &intent)
+		if err != nil || !found {
+			return err
+		}
+		if indexed, err = kvIntentIndexed(tx, &intent); err != nil {
+			return err
+		}
+		if intent.State != IntentSweeping && intent.CreatedAt >= cutoff.Unix() {
+			return nil
+		}
+
+		claimed = true
+		intent.State = IntentSweeping
+		return putJSON(tx, bucketIntents, intentKey(id), &intent)
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("failed to claim intent: %w", err)
+	}
+	return claimed, indexed, nil
+}
@@ -0,0 +1,463 @@
+package blockindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bharani/pkg/shards"
+)
+
+// scanRange calls fn for each block of a range after after, in hash order,
+// until fn returns false
+func scanRange(tx kvTx, r shards.Range, after string, fn func(hash string, block *kvBlock) bool) error {
+	start := r.Start
+	if after >= start {
+		// The smallest key greater than after
+		start = after + "\x00"
+	}
+
+	var decodeErr error
+	err := tx.scan(bucketBlocks, start, func(hash string, value []byte) bool {
+		if r.End != "" && hash >= r.End {
+			return false
+		}
+		var block kvBlock
+		if decodeErr = json.Unmarshal(value, &block); decodeErr != nil {
+			return false
+		}
+		return fn(hash, &block)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// record converts a stored block to a record
+func (b *kvBlock) record(hash string) *Record {
+	return &Record{
+		Entry:          *b.entry(hash),
+		State:          b.State,
+		UnreferencedAt: b.UnreferencedAt,
+	}
+}
+
+// loadRefs fills in the references of indexed records
+func loadRefs(tx kvTx, records []*Record) error {
+	for _, record := range records {
+		if record.Missing {
+			continue
+		}
+
+		var parseErr error
+		err := scanPrefix(tx, bucketRefs, record.Hash+"\x00", func(key string, value []byte) bool {
+			fields := strings.Split(key, "\x00")
+			createdAt, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				parseErr = fmt.Errorf("invalid reference %q: %w", key, err)
+				return false
+			}
+			record.Refs = append(record.Refs, &Ref{Tenant: fields[1], Owner: fields[2], CreatedAt: time.Unix(createdAt, 0)})
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if parseErr != nil {
+			return parseErr
+		}
+	}
+	return nil
+}
+
+// ExportRange returns up to limit records of the range with hashes after
+// after, in hash order
+func (i *KVIndex) ExportRange(r shards.Range, after string, limit int) ([]*Record, error) {
+	records := make([]*Record, 0)
+	err := i.db.view(func(tx kvTx) error {
+		err := scanRange(tx, r, after, func(hash string, block *kvBlock) bool {
+			if len(records) == limit {
+				return false
+			}
+			records = append(records, block.record(hash))
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return loadRefs(tx, records)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export blocks: %w", err)
+	}
+	return records, nil
+}
+
+// ExportBlocks returns the records of the given blocks in hash order. Blocks
+// that are not indexed are returned as missing.
+func (i *KVIndex) ExportBlocks(hashes []string) ([]*Record, error) {
+	records := make([]*Record, 0, len(hashes))
+	err := i.db.view(func(tx kvTx) error {
+		seen := make(map[string]bool, len(hashes))
+		for _, hash := range hashes {
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+
+			block, err := getBlock(tx, hash)
+			if err != nil {
+				return err
+			}
+			if block == nil {
+				records = append(records, &Record{Entry: Entry{Hash: hash}, Missing: true})
+				continue
+			}
+			records = append(records, block.record(hash))
+		}
+		sort.Slice(records, func(a, b int) bool { return records[a].Hash < records[b].Hash })
+
+		return loadRefs(tx, records)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export blocks: %w", err)
+	}
+	return records, nil
+}
+
+// ImportBlocks replaces what the index stores about each record's block with
+// the record, removing blocks whose records are missing. If span is set, the
+// records are all the blocks of that range, and any other block in it is
+// removed too. Tenant usage is kept up to date, so importing the same records
+// again changes nothing.
+func (i *KVIndex) ImportBlocks(records []*Record, span *shards.Range) error {
+	return i.db.update(func(tx kvTx) error {
+		if span != nil {
+			keep := make(map[string]bool, len(records))
+			for _, record := range records {
+				keep[record.Hash] = true
+			}
+			stale := make([]string, 0)
+			err := scanRange(tx, *span, "", func(hash string, _ *kvBlock) bool {
+				if !keep[hash] {
+					stale = append(stale, hash)
+				}
+				return true
+			})
+			if err != nil {
+				return fmt.Errorf("failed to list blocks of range %s: %w", *span, err)
+			}
+			for _, hash := range stale {
+				if err := kvRemoveBlock(tx, hash); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, record := range records {
+			if err := kvRemoveBlock(tx, record.Hash); err != nil {
+				return err
+			}
+			if record.Missing {
+				continue
+			}
+
+			block := &kvBlock{
+				CellID:         record.CellID,
+				BucketID:       record.BucketID,
+				Checksum:       record.Checksum,
+				VolumeID:       record.VolumeID,
+				Size:           record.Size,
+				CreatedAt:      record.CreatedAt.Unix(),
+				State:          record.State,
+				UnreferencedAt: record.UnreferencedAt,
+			}
+			if !record.VerifiedAt.IsZero() {
+				block.VerifiedAt = record.VerifiedAt.Unix()
+			}
+			if err := putBlock(tx, record.Hash, nil, block); err != nil {
+				return fmt.Errorf("failed to import block %s: %w", record.Hash, err)
+			}
+
+			charged := make(map[string]bool)
+			for _, ref := range record.Refs {
+				key := refKey(record.Hash, ref.Tenant, ref.Owner)
+				if err := tx.put(bucketRefs, key, []byte(strconv.FormatInt(ref.CreatedAt.Unix(), 10))); err != nil {
+					return fmt.Errorf("failed to import reference to %s: %w", record.Hash, err)
+				}
+				if !charged[ref.Tenant] {
+					if err := kvChargeUsage(tx, block, ref.Tenant, 1); err != nil {
+						return err
+					}
+					charged[ref.Tenant] = true
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// kvRemoveBlock deletes a block and its references, taking it out of the
+// usage of every tenant that referenced it
+func kvRemoveBlock(tx kvTx, hash string) error {
+	block, err := getBlock(tx, hash)
+	if err != nil {
+		return err
+	}
+	if block != nil {
+		tenants, err := blockTenants(tx, hash)
+		if err != nil {
+			return fmt.Errorf("failed to list references: %w", err)
+		}
+		for _, tenant := range tenants {
+			if err := kvChargeUsage(tx, block, tenant, -1); err != nil {
+				return err
+			}
+		}
+		if err := putBlock(tx, hash, block, nil); err != nil {
+			return fmt.Errorf("failed to remove block: %w", err)
+		}
+	}
+	return deleteRefs(tx, hash)
+}
+
+// CountIntents returns the number of uncommitted Puts in a range
+func (i *KVIndex) CountIntents(r shards.Range) (int64, error) {
+	var count int64
+	err := i.db.view(func(tx kvTx) error {
+		return scanIntents(tx, func(_ int64, intent *kvIntent) bool {
+			if r.Contains(intent.Hash) {
+				count++
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count intents: %w", err)
+	}
+	return count, nil
+}
+
+// DropRange removes every block of a range that has moved to another index
+// node, together with its references and usage, and records the range as
+// moved
+func (i *KVIndex) DropRange(r shards.Range) error {
+	err := i.db.update(func(tx kvTx) error {
+		hashes := make([]string, 0)
+		err := scanRange(tx, r, "", func(hash string, _ *kvBlock) bool {
+			hashes = append(hashes, hash)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		for _, hash := range hashes {
+			if err := kvRemoveBlock(tx, hash); err != nil {
+				return err
+			}
+		}
+
+		// References and intents of blocks that are not indexed
+		refs := make([]string, 0)
+		err = tx.scan(bucketRefs, r.Start, func(key string, _ []byte) bool {
+			hash, _, _ := strings.Cut(key, "\x00")
+			if !r.Contains(hash) {
+				return false
+			}
+			refs = append(refs, key)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range refs {
+			if err := tx.delete(bucketRefs, key); err != nil {
+				return err
+			}
+		}
+
+		intents := make([]int64, 0)
+		err = scanIntents(tx, func(id int64, intent *kvIntent) bool {
+			if r.Contains(intent.Hash) {
+				intents = append(intents, id)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range intents {
+			if err := tx.delete(bucketIntents, intentKey(id)); err != nil {
+				return err
+			}
+		}
+
+		if err := kvEndMigration(tx, r); err != nil {
+			return err
+		}
+		return tx.put(bucketMoved, r.Start, []byte(r.End))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to drop range %s: %w", r, err)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if !slices.Contains(i.moved, r) {
+		i.moved = append(i.moved, r)
+	}
+	delete(i.migrations, r)
+	return nil
+}
+
+// migrationKey returns the key of a migrating range
+func migrationKey(r shards.Range) string {
+	return joinKey(r.Start, r.End)
+}
+
+// takeChanges removes the blocks written to a migrating range and returns
+// them in hash order
+func takeChanges(tx kvTx, r shards.Range) ([]string, error) {
+	prefix := migrationKey(r) + "\x00"
+	hashes := make([]string, 0)
+	err := scanPrefix(tx, bucketChanges, prefix, func(key string, _ []byte) bool {
+		hashes = append(hashes, strings.TrimPrefix(key, prefix))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range hashes {
+		if err := tx.delete(bucketChanges, prefix+hash); err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// kvEndMigration forgets a migration and the blocks written to its range
+func kvEndMigration(tx kvTx, r shards.Range) error {
+	if _, err := takeChanges(tx, r); err != nil {
+		return err
+	}
+	return tx.delete(bucketMigrations, migrationKey(r))
+}
+
+// BeginMigration starts recording the blocks written to a range that is about
+// to be copied to another node. Beginning a migration that is already under
+// way starts it over, forgetting its changes and unfreezing the range.
+func (i *KVIndex) BeginMigration(r shards.Range) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	err := i.db.update(func(tx kvTx) error {
+		if err := kvEndMigration(tx, r); err != nil {
+			return err
+		}
+		return tx.put(bucketMigrations, migrationKey(r), []byte{})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin migration of range %s: %w", r, err)
+	}
+
+	i.migrations[r] = false
+	return nil
+}
+
+// RecordChanges remembers written blocks for the migrations of their ranges
+func (i *KVIndex) RecordChanges(hashes []string) error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if len(i.migrations) == 0 {
+		return nil
+	}
+
+	err := i.db.update(func(tx kvTx) error {
+		for r := range i.migrations {
+			for _, hash := range hashes {
+				if !r.Contains(hash) {
+					continue
+				}
+				if err := tx.put(bucketChanges, joinKey(r.Start, r.End, hash), []byte{}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record changes: %w", err)
+	}
+	return nil
+}
+
+// TakeChanges returns the blocks of a migrating range written since the
+// migration began or since the last call, and forgets them
+func (i *KVIndex) TakeChanges(r shards.Range) ([]string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.migrations[r]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotMigrating, r)
+	}
+
+	var hashes []string
+	err := i.db.update(func(tx kvTx) error {
+		var err error
+		hashes, err = takeChanges(tx, r)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to take changes: %w", err)
+	}
+	return hashes, nil
+}
+
+// FreezeRange records that a migrating range accepts no new writes
+func (i *KVIndex) FreezeRange(r shards.Range) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.migrations[r]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotMigrating, r)
+	}
+
+	err := i.db.update(func(tx kvTx) error {
+		return tx.put(bucketMigrations, migrationKey(r), []byte("true"))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to freeze range %s: %w", r, err)
+	}
+
+	i.migrations[r] = true
+	return nil
+}
+
+// Frozen reports whether a hash belongs to a migrating range that accepts no
+// new writes
+func (i *KVIndex) Frozen(hash string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return inFrozenRange(i.migrations, hash)
+}
+
+// Moved reports whether a hash belongs to a range this node has handed to
+// another index node
+func (i *KVIndex) Moved(hash string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, r := range i.moved {
+		if r.Contains(hash) {
+			return true
+		}
+	}
+	return false
+}
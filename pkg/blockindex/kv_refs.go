@@ -0,0 +1,410 @@
+package blockindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// refKey is the key of owner's reference within tenant to a block
+func refKey(hash, tenant, owner string) string {
+	return joinKey(hash, tenant, owner)
+}
+
+// countRefs counts the references with a key prefix
+func countRefs(tx kvTx, prefix string) (int64, error) {
+	var n int64
+	err := scanPrefix(tx, bucketRefs, prefix, func(string, []byte) bool {
+		n++
+		return true
+	})
+	return n, err
+}
+
+// blockTenants returns the tenants holding references to a block
+func blockTenants(tx kvTx, hash string) ([]string, error) {
+	tenants := make([]string, 0)
+	err := scanPrefix(tx, bucketRefs, hash+"\x00", func(key string, _ []byte) bool {
+		fields := strings.Split(key, "\x00")
+		if len(tenants) == 0 || tenants[len(tenants)-1] != fields[1] {
+			tenants = append(tenants, fields[1])
+		}
+		return true
+	})
+	return tenants, err
+}
+
+// AddRefs records that owner, within tenant, references each of the given
+// blocks, and clears their unreferenced time so the garbage collector keeps
+// them. An empty owner pins the blocks: the pin is never released, so they
+// are never collected. New references are recorded as created at at. Hashes
+// that are not indexed are left out of the result.
+func (i *KVIndex) AddRefs(hashes []string, tenant, owner string, at time.Time) (*RefResult, error) {
+	var result *RefResult
+	err := i.db.update(func(tx kvTx) error {
+		result = &RefResult{}
+		for _, hash := range hashes {
+			block, err := getBlock(tx, hash)
+			if err != nil {
+				return fmt.Errorf("failed to get block state: %w", err)
+			}
+			if block == nil {
+				continue
+			}
+			if block.State == StateDeleting {
+				result.Deleting = append(result.Deleting, hash)
+				continue
+			}
+
+			if err := kvAddRef(tx, hash, block, tenant, owner, at); err != nil {
+				return err
+			}
+			result.Found = append(result.Found, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// kvAddRef records owner's reference within tenant to a live block and clears
+// its unreferenced time. A tenant's first reference to a block adds the block
+// to the tenant's usage.
+func kvAddRef(tx kvTx, hash string, block *kvBlock, tenant, owner string, at time.Time) error {
+	key := refKey(hash, tenant, owner)
+	if tx.get(bucketRefs, key) == nil {
+		held, err := countRefs(tx, joinKey(hash, tenant)+"\x00")
+		if err != nil {
+			return fmt.Errorf("failed to count references: %w", err)
+		}
+		if err := tx.put(bucketRefs, key, []byte(strconv.FormatInt(at.Unix(), 10))); err != nil {
+			return fmt.Errorf("failed to add reference: %w", err)
+		}
+		if held == 0 {
+			if err := kvChargeUsage(tx, block, tenant, 1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if block.UnreferencedAt != 0 {
+		referenced := *block
+		referenced.UnreferencedAt = 0
+		if err := putBlock(tx, hash, block, &referenced); err != nil {
+			return fmt.Errorf("failed to add reference: %w", err)
+		}
+	}
+	return nil
+}
+
+// kvChargeUsage adds (sign 1) or removes (sign -1) a block from a tenant's usage
+func kvChargeUsage(tx kvTx, block *kvBlock, tenant string, sign int64) error {
+	usage := TenantUsage{Tenant: tenant}
+	if _, err := getJSON(tx, bucketUsage, tenant, &usage); err != nil {
+		return fmt.Errorf("failed to update usage: %w", err)
+	}
+	usage.Bytes += sign * block.Size
+	usage.Blocks += sign
+	if err := putJSON(tx, bucketUsage, tenant, &usage); err != nil {
+		return fmt.Errorf("failed to update usage: %w", err)
+	}
+	return nil
+}
+
+// Release removes the reference owner holds within tenant to a block. When
+// the last reference goes, the block's unreferenced time is set and the
+// garbage collector may delete it once the grace period has passed. It
+// reports whether owner held a reference and how many references remain,
+// across every tenant.
+func (i *KVIndex) Release(hash, tenant, owner string, at time.Time) (bool, int64, error) {
+	if owner == "" {
+		return false, 0, fmt.Errorf("owner is required to release a reference")
+	}
+
+	var (
+		released  bool
+		remaining int64
+	)
+	err := i.db.update(func(tx kvTx) error {
+		key := refKey(hash, tenant, owner)
+		if tx.get(bucketRefs, key) == nil {
+			var err error
+			remaining, err = countRefs(tx, hash+"\x00")
+			return err
+		}
+		released = true
+		if err := tx.delete(bucketRefs, key); err != nil {
+			return fmt.Errorf("failed to release reference: %w", err)
+		}
+
+		var err error
+		if remaining, err = countRefs(tx, hash+"\x00"); err != nil {
+			return fmt.Errorf("failed to count references: %w", err)
+		}
+		tenantRemaining, err := countRefs(tx, joinKey(hash, tenant)+"\x00")
+		if err != nil {
+			return fmt.Errorf("failed to count references: %w", err)
+		}
+
+		block, err := getBlock(tx, hash)
+		if err != nil || block == nil {
+			return err
+		}
+
+		// The tenant stops being charged for the block with its last reference
+		if tenantRemaining == 0 {
+			if err := kvChargeUsage(tx, block, tenant, -1); err != nil {
+				return err
+			}
+		}
+
+		if remaining == 0 && block.State == StateLive && block.UnreferencedAt == 0 {
+			unreferenced := *block
+			unreferenced.UnreferencedAt = at.Unix()
+			if err := putBlock(tx, hash, block, &unreferenced); err != nil {
+				return fmt.Errorf("failed to mark block unreferenced: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return released, remaining, nil
+}
+
+// GetUsage returns a tenant's usage, which is zero for unknown tenants
+func (i *KVIndex) GetUsage(tenant string) (*TenantUsage, error) {
+	usage := &TenantUsage{Tenant: tenant}
+	err := i.db.view(func(tx kvTx) error {
+		_, err := getJSON(tx, bucketUsage, tenant, usage)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	usage.Tenant = tenant
+	return usage, nil
+}
+
+// Referenced returns the subset of hashes that tenant holds at least one
+// reference to, which is what entitles a tenant to read a block
+func (i *KVIndex) Referenced(hashes []string, tenant string) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	err := i.db.view(func(tx kvTx) error {
+		for _, hash := range hashes {
+			held, err := countRefs(tx, joinKey(hash, tenant)+"\x00")
+			if err != nil {
+				return err
+			}
+			if held > 0 {
+				referenced[hash] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get references: %w", err)
+	}
+	return referenced, nil
+}
+
+// ListCollectable returns live blocks that have had no references since before cutoff
+func (i *KVIndex) ListCollectable(cutoff time.Time, limit int) ([]*Entry, error) {
+	end := timeKey(cutoff.Unix())
+	return i.listEntries(bucketUnreferenced, "", limit, func(key string) (string, bool) {
+		if key >= end {
+			return "", false
+		}
+		return key[len(end):], true
+	})
+}
+
+// MarkDeleting claims an unreferenced block for deletion. It succeeds only if
+// the block is still live and has had no references since before cutoff, so a
+// concurrent AddRefs either keeps the block or sees it as deleting.
+func (i *KVIndex) MarkDeleting(hash string, cutoff time.Time) (bool, error) {
+	var marked bool
+	err := i.db.update(func(tx kvTx) error {
+		block, err := getBlock(tx, hash)
+		if err != nil || block == nil {
+			return err
+		}
+		if block.State != StateLive || block.UnreferencedAt == 0 || block.UnreferencedAt >= cutoff.Unix() {
+			return nil
+		}
+		refs, err := countRefs(tx, hash+"\x00")
+		if err != nil || refs > 0 {
+			return err
+		}
+
+		deleting := *block
+		deleting.State = StateDeleting
+		marked = true
+		return putBlock(tx, hash, block, &deleting)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark block deleting: %w", err)
+	}
+	return marked, nil
+}
+
+// ListDeleting returns blocks claimed for deletion whose entries have not been
+// removed yet, such as those left behind by an interrupted collection
+func (i *KVIndex) ListDeleting(limit int) ([]*Entry, error) {
+	return i.listEntries(bucketDeleting, "", limit, func(key string) (string, bool) {
+		return key, true
+	})
+}
+
+// RemoveEntry removes a block claimed for deletion once its replicas are gone.
+// It reports whether the entry was removed; live entries are never removed.
+func (i *KVIndex) RemoveEntry(hash string) (bool, error) {
+	var removed bool
+	err := i.db.update(func(tx kvTx) error {
+		block, err := getBlock(tx, hash)
+		if err != nil || block == nil || block.State != StateDeleting {
+			return err
+		}
+		if err := putBlock(tx, hash, block, nil); err != nil {
+			return err
+		}
+		removed = true
+		return deleteRefs(tx, hash)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove entry: %w", err)
+	}
+	return removed, nil
+}
+
+// deleteRefs removes every reference to a block
+func deleteRefs(tx kvTx, hash string) error {
+	keys := make([]string, 0)
+	err := scanPrefix(tx, bucketRefs, hash+"\x00", func(key string, _ []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := tx.delete(bucketRefs, key); err != nil {
+			return fmt.Errorf("failed to remove references: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListVolumeEntries returns live blocks stored in a volume
+func (i *KVIndex) ListVolumeEntries(volumeID string, limit int) ([]*Entry, error) {
+	prefix := volumeID + "\x00"
+	entries := make([]*Entry, 0)
+	err := i.db.view(func(tx kvTx) error {
+		var getErr error
+		err := scanPrefix(tx, bucketVolumes, prefix, func(key string, _ []byte) bool {
+			if len(entries) == limit {
+				return false
+			}
+			hash := key[len(prefix):]
+			block, err := getBlock(tx, hash)
+			if err != nil {
+				getErr = err
+				return false
+			}
+			// The volume index holds deleting blocks too
+			if block != nil && block.State == StateLive {
+				entries = append(entries, block.entry(hash))
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return getErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	return entries, nil
+}
+
+// GetVolumeUsage returns the live bytes and block count of every volume of a
+// cell holding at least one live block, and the number of live entries of the
+// cell with no recorded volume
+func (i *KVIndex) GetVolumeUsage(cellID string) ([]*VolumeUsage, int64, error) {
+	var (
+		usage     []*VolumeUsage
+		unlocated int64
+		decodeErr error
+	)
+	err := i.db.view(func(tx kvTx) error {
+		err := scanPrefix(tx, bucketVolumeUsage, cellID+"\x00", func(_ string, value []byte) bool {
+			var u VolumeUsage
+			if decodeErr = json.Unmarshal(value, &u); decodeErr != nil {
+				return false
+			}
+			if u.VolumeID == "" {
+				unlocated = u.Blocks
+				return true
+			}
+			usage = append(usage, &u)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return decodeErr
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get volume usage: %w", err)
+	}
+	return usage, unlocated, nil
+}
+
+// listEntries scans an index bucket from start, mapping each key to the hash
+// of a block until hash reports false, and returns up to limit of the blocks
+func (i *KVIndex) listEntries(bucket, start string, limit int, hash func(key string) (string, bool)) ([]*Entry, error) {
+	entries := make([]*Entry, 0)
+	err := i.db.view(func(tx kvTx) error {
+		hashes := make([]string, 0)
+		err := tx.scan(bucket, start, func(key string, _ []byte) bool {
+			if len(hashes) == limit {
+				return false
+			}
+			h, ok := hash(key)
+			if ok {
+				hashes = append(hashes, h)
+			}
+			return ok
+		})
+		if err != nil {
+			return err
+		}
+		entries, err = getEntries(tx, hashes)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	return entries, nil
+}
+
+// getEntries returns the entries of the indexed blocks among hashes, in order
+func getEntries(tx kvTx, hashes []string) ([]*Entry, error) {
+	entries := make([]*Entry, 0, len(hashes))
+	for _, hash := range hashes {
+		block, err := getBlock(tx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			entries = append(entries, block.entry(hash))
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,201 @@
+package blockindex
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// memoryStore keeps a KVIndex in memory. Writers are serialized, and a failed
+// transaction is rolled back by undoing its changes.
+type memoryStore struct {
+	buckets map[string]*memoryBucket
+	mu      sync.RWMutex
+}
+
+// memoryBucket holds the values of a bucket and its keys in order
+type memoryBucket struct {
+	values map[string][]byte
+	keys   []string
+}
+
+// NewMemoryIndex creates an empty index held in memory
+func NewMemoryIndex() *KVIndex {
+	index, _ := newKVIndex(newMemoryStore(nil))
+	return index
+}
+
+// newMemoryStore creates a store holding the given bucket contents
+func newMemoryStore(contents map[string]map[string][]byte) *memoryStore {
+	s := &memoryStore{}
+	s.load(contents)
+	return s
+}
+
+// load replaces the contents of every bucket
+func (s *memoryStore) load(contents map[string]map[string][]byte) {
+	s.buckets = make(map[string]*memoryBucket, len(kvBuckets))
+	for _, name := range kvBuckets {
+		bucket := &memoryBucket{values: contents[name]}
+		if bucket.values == nil {
+			bucket.values = make(map[string][]byte)
+		}
+		for key := range bucket.values {
+			bucket.keys = append(bucket.keys, key)
+		}
+		slices.Sort(bucket.keys)
+		s.buckets[name] = bucket
+	}
+}
+
+func (s *memoryStore) view(fn func(tx kvTx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(&memoryTx{store: s})
+}
+
+func (s *memoryStore) update(fn func(tx kvTx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryTx{store: s, writable: true}
+	err := fn(tx)
+	if err != nil {
+		tx.rollback()
+	}
+	return err
+}
+
+func (s *memoryStore) snapshot(path string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	contents := make(map[string]map[string][]byte, len(s.buckets))
+	for name, bucket := range s.buckets {
+		contents[name] = bucket.values
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(contents); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *memoryStore) restore(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var contents map[string]map[string][]byte
+	if err := gob.NewDecoder(file).Decode(&contents); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load(contents)
+	return nil
+}
+
+func (s *memoryStore) close() error {
+	return nil
+}
+
+// memoryChange is a change made by a transaction, kept so it can be undone
+type memoryChange struct {
+	bucket  string
+	key     string
+	value   []byte
+	existed bool
+}
+
+// memoryTx is a transaction of a memoryStore
+type memoryTx struct {
+	store    *memoryStore
+	writable bool
+	undo     []memoryChange
+}
+
+func (tx *memoryTx) get(bucket, key string) []byte {
+	return tx.store.buckets[bucket].values[key]
+}
+
+func (tx *memoryTx) put(bucket, key string, value []byte) error {
+	if !tx.writable {
+		return fmt.Errorf("transaction is read-only")
+	}
+	b := tx.store.buckets[bucket]
+	old, existed := b.values[key]
+	tx.undo = append(tx.undo, memoryChange{bucket: bucket, key: key, value: old, existed: existed})
+	b.set(key, slices.Clone(value))
+	return nil
+}
+
+func (tx *memoryTx) delete(bucket, key string) error {
+	if !tx.writable {
+		return fmt.Errorf("transaction is read-only")
+	}
+	b := tx.store.buckets[bucket]
+	old, existed := b.values[key]
+	if !existed {
+		return nil
+	}
+	tx.undo = append(tx.undo, memoryChange{bucket: bucket, key: key, value: old, existed: true})
+	b.remove(key)
+	return nil
+}
+
+func (tx *memoryTx) scan(bucket, start string, fn func(key string, value []byte) bool) error {
+	b := tx.store.buckets[bucket]
+	i, _ := slices.BinarySearch(b.keys, start)
+	for ; i < len(b.keys); i++ {
+		key := b.keys[i]
+		if !fn(key, b.values[key]) {
+			break
+		}
+	}
+	return nil
+}
+
+// rollback undoes the transaction's changes, latest first
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		change := tx.undo[i]
+		b := tx.store.buckets[change.bucket]
+		if change.existed {
+			b.set(change.key, change.value)
+		} else {
+			b.remove(change.key)
+		}
+	}
+}
+
+// set stores a value, adding its key in order if it is new
+func (b *memoryBucket) set(key string, value []byte) {
+	if _, ok := b.values[key]; !ok {
+		i, _ := slices.BinarySearch(b.keys, key)
+		b.keys = slices.Insert(b.keys, i, key)
+	}
+	b.values[key] = value
+}
+
+// remove deletes a key
+func (b *memoryBucket) remove(key string) {
+	if _, ok := b.values[key]; !ok {
+		return
+	}
+	delete(b.values, key)
+	if i, found := slices.BinarySearch(b.keys, key); found {
+		b.keys = slices.Delete(b.keys, i, i+1)
+	}
+}
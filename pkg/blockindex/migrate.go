@@ -0,0 +1,278 @@
+package blockindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bharani/pkg/shards"
+	"bharani/proto/blockindex"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkRead fails with OutOfRange if any hash belongs to a range this node has
+// handed to another index node, telling the caller to refresh its shard map
+func (s *BlockIndexService) checkRead(hashes ...string) error {
+	for _, hash := range hashes {
+		if s.index.Moved(hash) {
+			return status.Errorf(codes.OutOfRange, "block %s has moved to another index shard", hash)
+		}
+	}
+	return nil
+}
+
+// beginWrite admits a write of the given blocks. Writes of moved ranges fail
+// with OutOfRange, and writes of frozen ranges with Aborted, so the caller
+// retries once the migration completes. The returned function must be called
+// when the write has finished; it records the blocks as changed for any
+// migration of their range.
+func (s *BlockIndexService) beginWrite(hashes ...string) (func(changed ...string), error) {
+	s.writeMu.RLock()
+
+	// Checked under the lock, since EndMigration moves ranges holding it
+	if err := s.checkRead(hashes...); err != nil {
+		s.writeMu.RUnlock()
+		return nil, err
+	}
+
+	for _, hash := range hashes {
+		if s.index.Frozen(hash) {
+			s.writeMu.RUnlock()
+			return nil, status.Errorf(codes.Aborted, "index range of block %s is migrating", hash)
+		}
+	}
+
+	return func(changed ...string) {
+		if err := s.index.RecordChanges(append(changed, hashes...)); err != nil {
+			s.mu.Lock()
+			s.lostChanges = err
+			s.mu.Unlock()
+		}
+		s.writeMu.RUnlock()
+	}, nil
+}
+
+// migrationRange converts a migration request to a range
+func migrationRange(req *blockindex.MigrationRequest) shards.Range {
+	return shards.Range{Start: req.Start, End: req.End}
+}
+
+// BeginMigration starts tracking the blocks written to a range that is about
+// to be copied to another node. Beginning a migration that is already under
+// way starts it over, unfreezing the range. A range this node has already
+// handed over fails with OutOfRange, telling the master the move completed.
+// The migration is kept in the index, so it survives restarts and snapshots.
+func (s *BlockIndexService) BeginMigration(ctx context.Context, req *blockindex.MigrationRequest) (*blockindex.MigrationResponse, error) {
+	if err := s.checkRead(req.Start); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.index.BeginMigration(migrationRange(req)); err != nil {
+		return &blockindex.MigrationResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	s.lostChanges = nil
+
+	return &blockindex.MigrationResponse{
+		Success: true,
+	}, nil
+}
+
+// ExportRange handles ExportRange requests
+func (s *BlockIndexService) ExportRange(ctx context.Context, req *blockindex.ExportRangeRequest) (*blockindex.ExportResponse, error) {
+	records, err := s.index.ExportRange(shards.Range{Start: req.Start, End: req.End}, req.After, int(req.Limit))
+	return exportResponse(records, err), nil
+}
+
+// ExportBlocks handles ExportBlocks requests
+func (s *BlockIndexService) ExportBlocks(ctx context.Context, req *blockindex.ExportBlocksRequest) (*blockindex.ExportResponse, error) {
+	records, err := s.index.ExportBlocks(req.Hashes)
+	return exportResponse(records, err), nil
+}
+
+// exportResponse converts exported records to an Export response
+func exportResponse(records []*Record, err error) *blockindex.ExportResponse {
+	if err != nil {
+		return &blockindex.ExportResponse{
+			Error: err.Error(),
+		}
+	}
+
+	resp := &blockindex.ExportResponse{
+		Records: make([]*blockindex.BlockRecord, 0, len(records)),
+	}
+	for _, record := range records {
+		resp.Records = append(resp.Records, toProtoRecord(record))
+	}
+	return resp
+}
+
+// toProtoRecord converts a record to its protobuf form
+func toProtoRecord(record *Record) *blockindex.BlockRecord {
+	if record.Missing {
+		return &blockindex.BlockRecord{
+			Entry:   &blockindex.Entry{Hash: record.Hash},
+			Missing: true,
+		}
+	}
+
+	pb := &blockindex.BlockRecord{
+		Entry:          toProtoEntry(&record.Entry),
+		State:          record.State,
+		CreatedAt:      record.CreatedAt.Unix(),
+		UnreferencedAt: record.UnreferencedAt,
+		Refs:           make([]*blockindex.Ref, 0, len(record.Refs)),
+	}
+	if !record.VerifiedAt.IsZero() {
+		pb.VerifiedAt = record.VerifiedAt.Unix()
+	}
+	for _, ref := range record.Refs {
+		pb.Refs = append(pb.Refs, &blockindex.Ref{
+			Tenant:    ref.Tenant,
+			Owner:     ref.Owner,
+			CreatedAt: ref.CreatedAt.Unix(),
+		})
+	}
+	return pb
+}
+
+// fromProtoRecord converts a protobuf record to a record
+func fromProtoRecord(pb *blockindex.BlockRecord) *Record {
+	entry := pb.Entry
+	if entry == nil {
+		entry = &blockindex.Entry{}
+	}
+
+	record := &Record{
+		Entry: Entry{
+			Hash:      entry.Hash,
+			CellID:    entry.CellId,
+			BucketID:  entry.BucketId,
+			Checksum:  entry.Checksum,
+			VolumeID:  entry.VolumeId,
+			Size:      entry.Size,
+			CreatedAt: time.Unix(pb.CreatedAt, 0),
+		},
+		State:          pb.State,
+		UnreferencedAt: pb.UnreferencedAt,
+		Refs:           make([]*Ref, 0, len(pb.Refs)),
+		Missing:        pb.Missing,
+	}
+	if pb.VerifiedAt > 0 {
+		record.VerifiedAt = time.Unix(pb.VerifiedAt, 0)
+	}
+	for _, ref := range pb.Refs {
+		record.Refs = append(record.Refs, &Ref{
+			Tenant:    ref.Tenant,
+			Owner:     ref.Owner,
+			CreatedAt: time.Unix(ref.CreatedAt, 0),
+		})
+	}
+	return record
+}
+
+// ImportBlocks handles ImportBlocks requests
+func (s *BlockIndexService) ImportBlocks(ctx context.Context, req *blockindex.ImportBlocksRequest) (*blockindex.ImportBlocksResponse, error) {
+	records := make([]*Record, 0, len(req.Records))
+	for _, record := range req.Records {
+		records = append(records, fromProtoRecord(record))
+	}
+
+	var span *shards.Range
+	if req.ReplaceRange {
+		span = &shards.Range{Start: req.Start, End: req.End}
+	}
+
+	if err := s.index.ImportBlocks(records, span); err != nil {
+		return &blockindex.ImportBlocksResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &blockindex.ImportBlocksResponse{
+		Success: true,
+	}, nil
+}
+
+// TakeChanges returns the blocks of a migrating range written since the
+// migration began or since the last call, and forgets them. If a write could
+// not be recorded it fails until the migration is started over.
+func (s *BlockIndexService) TakeChanges(ctx context.Context, req *blockindex.MigrationRequest) (*blockindex.TakeChangesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lostChanges != nil {
+		return &blockindex.TakeChangesResponse{
+			Error: fmt.Sprintf("failed to record changes: %v", s.lostChanges),
+		}, nil
+	}
+
+	hashes, err := s.index.TakeChanges(migrationRange(req))
+	if err != nil {
+		return &blockindex.TakeChangesResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.TakeChangesResponse{
+		Hashes: hashes,
+	}, nil
+}
+
+// FreezeRange stops accepting new writes to a migrating range. Puts that have
+// already recorded an intent may still commit or abort, and the number of
+// intents left is returned so the caller can wait for them. Once it is zero,
+// and the changes have been taken, the range cannot change any more.
+func (s *BlockIndexService) FreezeRange(ctx context.Context, req *blockindex.MigrationRequest) (*blockindex.FreezeRangeResponse, error) {
+	r := migrationRange(req)
+
+	// Wait for writes in flight, so they have recorded their changes
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.index.FreezeRange(r); err != nil {
+		return &blockindex.FreezeRangeResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	intents, err := s.index.CountIntents(r)
+	if err != nil {
+		return &blockindex.FreezeRangeResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &blockindex.FreezeRangeResponse{
+		Intents: intents,
+	}, nil
+}
+
+// EndMigration completes the move of a range to another node: the range's
+// blocks and migration are dropped and any later request for them fails with
+// OutOfRange
+func (s *BlockIndexService) EndMigration(ctx context.Context, req *blockindex.MigrationRequest) (*blockindex.MigrationResponse, error) {
+	r := migrationRange(req)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.index.DropRange(r); err != nil {
+		return &blockindex.MigrationResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &blockindex.MigrationResponse{
+		Success: true,
+	}, nil
+}
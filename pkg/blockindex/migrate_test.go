@@ -0,0 +1,53 @@
+package blockindex
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"bharani/proto/blockindex"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIntentsDuringMigration(t *testing.T) {
+	index := NewMemoryIndex()
+	t.Cleanup(func() { index.Close() })
+	service := NewBlockIndexService(index)
+	ctx := context.Background()
+
+	begin, err := service.BeginPut(ctx, &blockindex.BeginPutRequest{Intents: []*blockindex.Intent{
+		{Hash: "60", CellId: "cell1", BucketId: "b1", VolumeId: "v1", Size: 10},
+	}})
+	if err != nil || begin.Error != "" {
+		t.Fatalf("Failed to begin put: %v %s", err, begin.GetError())
+	}
+
+	r := &blockindex.MigrationRequest{End: "8"}
+	if resp, err := service.BeginMigration(ctx, r); err != nil || !resp.Success {
+		t.Fatalf("Failed to begin migration: %v %s", err, resp.GetError())
+	}
+	if resp, err := service.FreezeRange(ctx, r); err != nil || resp.Intents != 1 {
+		t.Fatalf("Expected one intent to wait for: %v %v", resp, err)
+	}
+
+	// The sweeper may not start on a frozen range, but the writer may still
+	// abort, and the abort is recorded for the migration
+	cutoff := time.Now().Add(time.Hour).Unix()
+	_, err = service.ClaimIntent(ctx, &blockindex.ClaimIntentRequest{Id: begin.Ids[0], CreatedBefore: cutoff, Hash: "60"})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("Expected claims on a frozen range to be refused, got %v", err)
+	}
+	if resp, err := service.AbortPut(ctx, &blockindex.AbortPutRequest{Id: begin.Ids[0], Hash: "60"}); err != nil || !resp.Success {
+		t.Fatalf("Failed to abort intent: %v %s", err, resp.GetError())
+	}
+	if resp, err := service.FreezeRange(ctx, r); err != nil || resp.Intents != 0 {
+		t.Errorf("Expected the range to have drained: %v %v", resp, err)
+	}
+	changes, err := service.TakeChanges(ctx, r)
+	if err != nil || !slices.Contains(changes.Hashes, "60") {
+		t.Errorf("Expected the abort to be recorded as a change: %v %v", changes, err)
+	}
+}
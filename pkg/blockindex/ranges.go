@@ -0,0 +1,633 @@
+package blockindex
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"bharani/pkg/shards"
+)
+
+// Record is everything the index stores about a block, as copied between
+// index nodes when a range of hashes moves
+type Record struct {
+	Entry
+	State          string
+	UnreferencedAt int64 // Unix seconds, 0 while referenced
+	Refs           []*Ref
+	Missing        bool // The block is not indexed; importing the record removes it
+}
+
+// Ref is one owner's reference to a block
+type Ref struct {
+	Tenant    string
+	Owner     string
+	CreatedAt time.Time
+}
+
+// ErrNotMigrating is returned when taking the changes of, or freezing, a
+// range that is not being copied to another node
+var ErrNotMigrating = errors.New("range is not migrating")
+
+// initRanges creates the tables of ranges this node has handed to other index
+// nodes, and of ranges being copied to them together with the blocks written
+// to those since
+func (i *SQLiteIndex) initRanges() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS moved_ranges (
+		range_start TEXT PRIMARY KEY,
+		range_end TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS migrations (
+		range_start TEXT NOT NULL,
+		range_end TEXT NOT NULL,
+		frozen INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (range_start, range_end)
+	);
+
+	CREATE TABLE IF NOT EXISTS migration_changes (
+		range_start TEXT NOT NULL,
+		range_end TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		PRIMARY KEY (range_start, range_end, hash)
+	);
+	`
+	if _, err := i.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create range tables: %w", err)
+	}
+
+	rows, err := i.db.Query(`SELECT range_start, range_end FROM moved_ranges ORDER BY range_start`)
+	if err != nil {
+		return fmt.Errorf("failed to list moved ranges: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r shards.Range
+		if err := rows.Scan(&r.Start, &r.End); err != nil {
+			return fmt.Errorf("failed to scan moved range: %w", err)
+		}
+		i.moved = append(i.moved, r)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list moved ranges: %w", err)
+	}
+
+	return i.loadMigrations()
+}
+
+// loadMigrations reads the ranges being copied to other index nodes
+func (i *SQLiteIndex) loadMigrations() error {
+	rows, err := i.db.Query(`SELECT range_start, range_end, frozen FROM migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %w", err)
+	}
+	defer rows.Close()
+
+	i.migrations = make(map[shards.Range]bool)
+	for rows.Next() {
+		var (
+			r      shards.Range
+			frozen bool
+		)
+		if err := rows.Scan(&r.Start, &r.End, &frozen); err != nil {
+			return fmt.Errorf("failed to scan migration: %w", err)
+		}
+		i.migrations[r] = frozen
+	}
+	return rows.Err()
+}
+
+// recordColumns are the blocks columns scanned by scanRecords
+const recordColumns = `hash, cell_id, bucket_id, checksum, volume_id, size, created_at, verified_at, state, unreferenced_at`
+
+// rangeCondition matches hashes in a range bound as start, end, end
+const rangeCondition = `hash >= ? AND (? = '' OR hash < ?)`
+
+// ExportRange returns up to limit records of the range with hashes after
+// after, in hash order
+func (i *SQLiteIndex) ExportRange(r shards.Range, after string, limit int) ([]*Record, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	query := `
+	SELECT ` + recordColumns + `
+	FROM blocks
+	WHERE hash > ? AND ` + rangeCondition + `
+	ORDER BY hash
+	LIMIT ?
+	`
+
+	records, err := i.scanRecords(query, after, r.Start, r.End, r.End, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.loadRefs(records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ExportBlocks returns the records of the given blocks in hash order. Blocks
+// that are not indexed are returned as missing.
+func (i *SQLiteIndex) ExportBlocks(hashes []string) ([]*Record, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	found := make(map[string]*Record, len(hashes))
+	for start := 0; start < len(hashes); start += batchQuerySize {
+		batch := hashes[start:min(start+batchQuerySize, len(hashes))]
+
+		query := `SELECT ` + recordColumns + ` FROM blocks WHERE hash IN (` + placeholders(len(batch)) + `)`
+		records, err := i.scanRecords(query, stringArgs(batch)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			found[record.Hash] = record
+		}
+	}
+
+	records := make([]*Record, 0, len(hashes))
+	seen := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		record, ok := found[hash]
+		if !ok {
+			record = &Record{Entry: Entry{Hash: hash}, Missing: true}
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(a, b int) bool { return records[a].Hash < records[b].Hash })
+
+	if err := i.loadRefs(records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// scanRecords runs a query selecting recordColumns
+func (i *SQLiteIndex) scanRecords(query string, args ...any) ([]*Record, error) {
+	rows, err := i.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export blocks: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]*Record, 0)
+	for rows.Next() {
+		var (
+			record     Record
+			createdAt  int64
+			verifiedAt int64
+		)
+		err := rows.Scan(
+			&record.Hash,
+			&record.CellID,
+			&record.BucketID,
+			&record.Checksum,
+			&record.VolumeID,
+			&record.Size,
+			&createdAt,
+			&verifiedAt,
+			&record.State,
+			&record.UnreferencedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+		record.CreatedAt = time.Unix(createdAt, 0)
+		if verifiedAt > 0 {
+			record.VerifiedAt = time.Unix(verifiedAt, 0)
+		}
+		records = append(records, &record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export blocks: %w", err)
+	}
+
+	return records, nil
+}
+
+// loadRefs fills in the references of indexed records
+func (i *SQLiteIndex) loadRefs(records []*Record) error {
+	byHash := make(map[string]*Record, len(records))
+	hashes := make([]string, 0, len(records))
+	for _, record := range records {
+		if !record.Missing {
+			byHash[record.Hash] = record
+			hashes = append(hashes, record.Hash)
+		}
+	}
+
+	for start := 0; start < len(hashes); start += batchQuerySize {
+		batch := hashes[start:min(start+batchQuerySize, len(hashes))]
+
+		query := `SELECT hash, tenant, owner, created_at FROM refs WHERE hash IN (` + placeholders(len(batch)) + `) ORDER BY hash, tenant, owner`
+		rows, err := i.db.Query(query, stringArgs(batch)...)
+		if err != nil {
+			return fmt.Errorf("failed to export references: %w", err)
+		}
+
+		for rows.Next() {
+			var (
+				hash      string
+				ref       Ref
+				createdAt int64
+			)
+			if err := rows.Scan(&hash, &ref.Tenant, &ref.Owner, &createdAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan reference: %w", err)
+			}
+			ref.CreatedAt = time.Unix(createdAt, 0)
+			byHash[hash].Refs = append(byHash[hash].Refs, &ref)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to export references: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ImportBlocks replaces what the index stores about each record's block with
+// the record, removing blocks whose records are missing. If span is set, the
+// records are all the blocks of that range, and any other block in it is
+// removed too. Tenant usage is kept up to date, so importing the same records
+// again changes nothing.
+func (i *SQLiteIndex) ImportBlocks(records []*Record, span *shards.Range) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if span != nil {
+		if err := removeOthers(tx, *span, records); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range records {
+		if err := removeBlock(tx, record.Hash); err != nil {
+			return err
+		}
+		if record.Missing {
+			continue
+		}
+
+		query := `
+		INSERT INTO blocks (hash, cell_id, bucket_id, checksum, volume_id, size, created_at, verified_at, state, unreferenced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+		var verifiedAt int64
+		if !record.VerifiedAt.IsZero() {
+			verifiedAt = record.VerifiedAt.Unix()
+		}
+		_, err := tx.Exec(query, record.Hash, record.CellID, record.BucketID, record.Checksum, record.VolumeID, record.Size,
+			record.CreatedAt.Unix(), verifiedAt, record.State, record.UnreferencedAt)
+		if err != nil {
+			return fmt.Errorf("failed to import block %s: %w", record.Hash, err)
+		}
+
+		charged := make(map[string]bool)
+		for _, ref := range record.Refs {
+			query := `INSERT INTO refs (hash, tenant, owner, created_at) VALUES (?, ?, ?, ?)`
+			if _, err := tx.Exec(query, record.Hash, ref.Tenant, ref.Owner, ref.CreatedAt.Unix()); err != nil {
+				return fmt.Errorf("failed to import reference to %s: %w", record.Hash, err)
+			}
+			if !charged[ref.Tenant] {
+				if err := chargeUsage(tx, record.Hash, ref.Tenant, 1); err != nil {
+					return err
+				}
+				charged[ref.Tenant] = true
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return nil
+}
+
+// removeOthers removes the blocks of a range that have no record
+func removeOthers(tx *sql.Tx, r shards.Range, records []*Record) error {
+	keep := make(map[string]bool, len(records))
+	for _, record := range records {
+		keep[record.Hash] = true
+	}
+
+	rows, err := tx.Query(`SELECT hash FROM blocks WHERE `+rangeCondition, r.Start, r.End, r.End)
+	if err != nil {
+		return fmt.Errorf("failed to list blocks of range %s: %w", r, err)
+	}
+	stale := make([]string, 0)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan hash: %w", err)
+		}
+		if !keep[hash] {
+			stale = append(stale, hash)
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to list blocks of range %s: %w", r, err)
+	}
+
+	for _, hash := range stale {
+		if err := removeBlock(tx, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeBlock deletes a block and its references, taking it out of the usage
+// of every tenant that referenced it
+func removeBlock(tx *sql.Tx, hash string) error {
+	rows, err := tx.Query(`SELECT DISTINCT tenant FROM refs WHERE hash = ?`, hash)
+	if err != nil {
+		return fmt.Errorf("failed to list references: %w", err)
+	}
+	tenants := make([]string, 0)
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reference: %w", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to list references: %w", err)
+	}
+
+	for _, tenant := range tenants {
+		if err := chargeUsage(tx, hash, tenant, -1); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM refs WHERE hash = ?`, hash); err != nil {
+		return fmt.Errorf("failed to remove references: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM blocks WHERE hash = ?`, hash); err != nil {
+		return fmt.Errorf("failed to remove block: %w", err)
+	}
+	return nil
+}
+
+// CountIntents returns the number of uncommitted Puts in a range
+func (i *SQLiteIndex) CountIntents(r shards.Range) (int64, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var count int64
+	if err := i.db.QueryRow(`SELECT COUNT(*) FROM intents WHERE `+rangeCondition, r.Start, r.End, r.End).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count intents: %w", err)
+	}
+	return count, nil
+}
+
+// DropRange removes every block of a range that has moved to another index
+// node, together with its references and usage, and records the range as
+// moved
+func (i *SQLiteIndex) DropRange(r shards.Range) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO tenant_usage (tenant, bytes, blocks)
+	SELECT tenant, -SUM(size), -COUNT(*)
+	FROM (
+		SELECT DISTINCT refs.tenant, blocks.hash, blocks.size
+		FROM refs JOIN blocks ON blocks.hash = refs.hash
+		WHERE blocks.hash >= ? AND (? = '' OR blocks.hash < ?)
+	)
+	WHERE true
+	GROUP BY tenant
+	ON CONFLICT(tenant) DO UPDATE SET
+		bytes = tenant_usage.bytes + excluded.bytes,
+		blocks = tenant_usage.blocks + excluded.blocks
+	`
+	if _, err := tx.Exec(query, r.Start, r.End, r.End); err != nil {
+		return fmt.Errorf("failed to update usage: %w", err)
+	}
+
+	for _, table := range []string{"refs", "blocks", "intents"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE `+rangeCondition, r.Start, r.End, r.End); err != nil {
+			return fmt.Errorf("failed to drop %s of range %s: %w", table, r, err)
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO moved_ranges (range_start, range_end) VALUES (?, ?)`, r.Start, r.End); err != nil {
+		return fmt.Errorf("failed to record moved range: %w", err)
+	}
+	if err := endMigration(tx, r); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dropped range: %w", err)
+	}
+
+	if !slices.Contains(i.moved, r) {
+		i.moved = append(i.moved, r)
+	}
+	delete(i.migrations, r)
+	return nil
+}
+
+// endMigration forgets a migration and the blocks written to its range
+func endMigration(tx *sql.Tx, r shards.Range) error {
+	for _, table := range []string{"migrations", "migration_changes"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE range_start = ? AND range_end = ?`, r.Start, r.End); err != nil {
+			return fmt.Errorf("failed to end migration of range %s: %w", r, err)
+		}
+	}
+	return nil
+}
+
+// BeginMigration starts recording the blocks written to a range that is about
+// to be copied to another node. Beginning a migration that is already under
+// way starts it over, forgetting its changes and unfreezing the range.
+func (i *SQLiteIndex) BeginMigration(r shards.Range) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := endMigration(tx, r); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO migrations (range_start, range_end) VALUES (?, ?)`, r.Start, r.End); err != nil {
+		return fmt.Errorf("failed to begin migration of range %s: %w", r, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+
+	i.migrations[r] = false
+	return nil
+}
+
+// RecordChanges remembers written blocks for the migrations of their ranges
+func (i *SQLiteIndex) RecordChanges(hashes []string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.migrations) == 0 {
+		return nil
+	}
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT OR IGNORE INTO migration_changes (range_start, range_end, hash) VALUES (?, ?, ?)`
+	for r := range i.migrations {
+		for _, hash := range hashes {
+			if !r.Contains(hash) {
+				continue
+			}
+			if _, err := tx.Exec(query, r.Start, r.End, hash); err != nil {
+				return fmt.Errorf("failed to record change to %s: %w", hash, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return nil
+}
+
+// TakeChanges returns the blocks of a migrating range written since the
+// migration began or since the last call, and forgets them
+func (i *SQLiteIndex) TakeChanges(r shards.Range) ([]string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.migrations[r]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotMigrating, r)
+	}
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT hash FROM migration_changes WHERE range_start = ? AND range_end = ? ORDER BY hash`, r.Start, r.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	hashes := make([]string, 0)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan change: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM migration_changes WHERE range_start = ? AND range_end = ?`, r.Start, r.End); err != nil {
+		return nil, fmt.Errorf("failed to forget changes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return hashes, nil
+}
+
+// FreezeRange records that a migrating range accepts no new writes
+func (i *SQLiteIndex) FreezeRange(r shards.Range) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.migrations[r]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotMigrating, r)
+	}
+
+	if _, err := i.db.Exec(`UPDATE migrations SET frozen = 1 WHERE range_start = ? AND range_end = ?`, r.Start, r.End); err != nil {
+		return fmt.Errorf("failed to freeze range %s: %w", r, err)
+	}
+
+	i.migrations[r] = true
+	return nil
+}
+
+// Frozen reports whether a hash belongs to a migrating range that accepts no
+// new writes
+func (i *SQLiteIndex) Frozen(hash string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return inFrozenRange(i.migrations, hash)
+}
+
+// inFrozenRange reports whether a hash belongs to a frozen range of
+// migrations
+func inFrozenRange(migrations map[shards.Range]bool, hash string) bool {
+	for r, frozen := range migrations {
+		if frozen && r.Contains(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// Moved reports whether a hash belongs to a range this node has handed to
+// another index node
+func (i *SQLiteIndex) Moved(hash string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, r := range i.moved {
+		if r.Contains(hash) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,398 @@
+package blockindex
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Block states. A live block can be read and referenced. A deleting block has
+// been claimed by the garbage collector: its replicas are being removed and it
+// can no longer gain references, so writers must wait until it is gone.
+const (
+	StateLive     = "live"
+	StateDeleting = "deleting"
+)
+
+// ErrDeleting is returned when updating a block the garbage collector is deleting
+var ErrDeleting = errors.New("block is being deleted")
+
+// RefResult reports which blocks AddRefs referenced
+type RefResult struct {
+	Found    []string // Live blocks now referenced by the owner
+	Deleting []string // Blocks being deleted, which were not referenced
+}
+
+// AddRefs records that owner, within tenant, references each of the given
+// blocks, and clears their unreferenced time so the garbage collector keeps
+// them. An empty owner pins the blocks: the pin is never released, so they
+// are never collected. New references are recorded as created at at. Hashes
+// that are not indexed are left out of the result.
+func (i *SQLiteIndex) AddRefs(hashes []string, tenant, owner string, at time.Time) (*RefResult, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &RefResult{}
+	for _, hash := range hashes {
+		var state string
+		err := tx.QueryRow(`SELECT state FROM blocks WHERE hash = ?`, hash).Scan(&state)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block state: %w", err)
+		}
+		if state == StateDeleting {
+			result.Deleting = append(result.Deleting, hash)
+			continue
+		}
+
+		if err := addRef(tx, hash, tenant, owner, at); err != nil {
+			return nil, err
+		}
+		result.Found = append(result.Found, hash)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit references: %w", err)
+	}
+
+	return result, nil
+}
+
+// Release removes the reference owner holds within tenant to a block. When
+// the last reference goes, the block's unreferenced time is set and the
+// garbage collector may delete it once the grace period has passed. It
+// reports whether owner held a reference and how many references remain,
+// across every tenant.
+func (i *SQLiteIndex) Release(hash, tenant, owner string, at time.Time) (bool, int64, error) {
+	if owner == "" {
+		return false, 0, fmt.Errorf("owner is required to release a reference")
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM refs WHERE hash = ? AND tenant = ? AND owner = ?`, hash, tenant, owner)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to release reference: %w", err)
+	}
+	released, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to release reference: %w", err)
+	}
+
+	var remaining, tenantRemaining int64
+	query := `
+	SELECT COUNT(*), COALESCE(SUM(tenant = ?), 0)
+	FROM refs
+	WHERE hash = ?
+	`
+	if err := tx.QueryRow(query, tenant, hash).Scan(&remaining, &tenantRemaining); err != nil {
+		return false, 0, fmt.Errorf("failed to count references: %w", err)
+	}
+
+	// The tenant stops being charged for the block with its last reference
+	if released > 0 && tenantRemaining == 0 {
+		if err := chargeUsage(tx, hash, tenant, -1); err != nil {
+			return false, 0, err
+		}
+	}
+
+	if released > 0 && remaining == 0 {
+		query = `
+		UPDATE blocks SET unreferenced_at = ?
+		WHERE hash = ? AND state = ? AND unreferenced_at = 0
+		`
+		if _, err := tx.Exec(query, at.Unix(), hash, StateLive); err != nil {
+			return false, 0, fmt.Errorf("failed to mark block unreferenced: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("failed to commit release: %w", err)
+	}
+
+	return released > 0, remaining, nil
+}
+
+// addRef records owner's reference within tenant to a live block and clears
+// its unreferenced time. A tenant's first reference to a block adds the block
+// to the tenant's usage.
+func addRef(tx *sql.Tx, hash, tenant, owner string, at time.Time) error {
+	var held int64
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM refs WHERE hash = ? AND tenant = ?`, hash, tenant).Scan(&held); err != nil {
+		return fmt.Errorf("failed to count references: %w", err)
+	}
+
+	result, err := tx.Exec(`INSERT OR IGNORE INTO refs (hash, tenant, owner, created_at) VALUES (?, ?, ?, ?)`, hash, tenant, owner, at.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to add reference: %w", err)
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to add reference: %w", err)
+	}
+	if held == 0 && added > 0 {
+		if err := chargeUsage(tx, hash, tenant, 1); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE blocks SET unreferenced_at = 0 WHERE hash = ?`, hash); err != nil {
+		return fmt.Errorf("failed to add reference: %w", err)
+	}
+	return nil
+}
+
+// chargeUsage adds (sign 1) or removes (sign -1) a block from a tenant's usage
+func chargeUsage(tx *sql.Tx, hash, tenant string, sign int64) error {
+	query := `
+	INSERT INTO tenant_usage (tenant, bytes, blocks)
+	SELECT ?, ? * size, ? FROM blocks WHERE hash = ?
+	ON CONFLICT(tenant) DO UPDATE SET
+		bytes = tenant_usage.bytes + excluded.bytes,
+		blocks = tenant_usage.blocks + excluded.blocks
+	`
+	if _, err := tx.Exec(query, tenant, sign, sign, hash); err != nil {
+		return fmt.Errorf("failed to update usage: %w", err)
+	}
+	return nil
+}
+
+// TenantUsage is the logical storage a tenant uses: every block it holds a
+// reference to, counted once however many of its owners reference it and
+// whether or not other tenants share it
+type TenantUsage struct {
+	Tenant string
+	Bytes  int64
+	Blocks int64
+}
+
+// GetUsage returns a tenant's usage, which is zero for unknown tenants
+func (i *SQLiteIndex) GetUsage(tenant string) (*TenantUsage, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	usage := &TenantUsage{Tenant: tenant}
+	err := i.db.QueryRow(`SELECT bytes, blocks FROM tenant_usage WHERE tenant = ?`, tenant).Scan(&usage.Bytes, &usage.Blocks)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// Referenced returns the subset of hashes that tenant holds at least one
+// reference to, which is what entitles a tenant to read a block
+func (i *SQLiteIndex) Referenced(hashes []string, tenant string) (map[string]bool, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	referenced := make(map[string]bool)
+	for start := 0; start < len(hashes); start += batchQuerySize {
+		batch := hashes[start:min(start+batchQuerySize, len(hashes))]
+
+		query := `SELECT DISTINCT hash FROM refs WHERE tenant = ? AND hash IN (` + placeholders(len(batch)) + `)`
+		rows, err := i.db.Query(query, append([]any{tenant}, stringArgs(batch)...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get references: %w", err)
+		}
+
+		for rows.Next() {
+			var hash string
+			if err := rows.Scan(&hash); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan reference: %w", err)
+			}
+			referenced[hash] = true
+		}
+		rows.Close()
+	}
+
+	return referenced, nil
+}
+
+// ListCollectable returns live blocks that have had no references since before cutoff
+func (i *SQLiteIndex) ListCollectable(cutoff time.Time, limit int) ([]*Entry, error) {
+	query := `
+	SELECT hash, cell_id, bucket_id, checksum, volume_id, size
+	FROM blocks
+	WHERE state = ? AND unreferenced_at > 0 AND unreferenced_at < ?
+	ORDER BY unreferenced_at
+	LIMIT ?
+	`
+	return i.listEntries(query, StateLive, cutoff.Unix(), limit)
+}
+
+// MarkDeleting claims an unreferenced block for deletion. It succeeds only if
+// the block is still live and has had no references since before cutoff, so a
+// concurrent AddRefs either keeps the block or sees it as deleting.
+func (i *SQLiteIndex) MarkDeleting(hash string, cutoff time.Time) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	query := `
+	UPDATE blocks SET state = ?
+	WHERE hash = ? AND state = ? AND unreferenced_at > 0 AND unreferenced_at < ?
+		AND NOT EXISTS (SELECT 1 FROM refs WHERE refs.hash = blocks.hash)
+	`
+
+	result, err := i.db.Exec(query, StateDeleting, hash, StateLive, cutoff.Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to mark block deleting: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark block deleting: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// ListDeleting returns blocks claimed for deletion whose entries have not been
+// removed yet, such as those left behind by an interrupted collection
+func (i *SQLiteIndex) ListDeleting(limit int) ([]*Entry, error) {
+	query := `
+	SELECT hash, cell_id, bucket_id, checksum, volume_id, size
+	FROM blocks
+	WHERE state = ?
+	LIMIT ?
+	`
+	return i.listEntries(query, StateDeleting, limit)
+}
+
+// RemoveEntry removes a block claimed for deletion once its replicas are gone.
+// It reports whether the entry was removed; live entries are never removed.
+func (i *SQLiteIndex) RemoveEntry(hash string) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	tx, err := i.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM blocks WHERE hash = ? AND state = ?`, hash, StateDeleting)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove entry: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove entry: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM refs WHERE hash = ?`, hash); err != nil {
+		return false, fmt.Errorf("failed to remove references: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit removal: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListVolumeEntries returns live blocks stored in a volume
+func (i *SQLiteIndex) ListVolumeEntries(volumeID string, limit int) ([]*Entry, error) {
+	query := `
+	SELECT hash, cell_id, bucket_id, checksum, volume_id, size
+	FROM blocks
+	WHERE volume_id = ? AND state = ?
+	LIMIT ?
+	`
+	return i.listEntries(query, volumeID, StateLive, limit)
+}
+
+// VolumeUsage summarises the live blocks stored in a volume
+type VolumeUsage struct {
+	VolumeID  string
+	LiveBytes int64
+	Blocks    int64
+}
+
+// GetVolumeUsage returns the live bytes and block count of every volume of a
+// cell holding at least one live block, and the number of live entries of the
+// cell with no recorded volume
+func (i *SQLiteIndex) GetVolumeUsage(cellID string) ([]*VolumeUsage, int64, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	query := `
+	SELECT volume_id, SUM(size), COUNT(*)
+	FROM blocks
+	WHERE cell_id = ? AND state = ?
+	GROUP BY volume_id
+	`
+
+	rows, err := i.db.Query(query, cellID, StateLive)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get volume usage: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		usage     []*VolumeUsage
+		unlocated int64
+	)
+	for rows.Next() {
+		var u VolumeUsage
+		if err := rows.Scan(&u.VolumeID, &u.LiveBytes, &u.Blocks); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan volume usage: %w", err)
+		}
+		if u.VolumeID == "" {
+			unlocated = u.Blocks
+			continue
+		}
+		usage = append(usage, &u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get volume usage: %w", err)
+	}
+
+	return usage, unlocated, nil
+}
+
+// listEntries runs a query selecting entry columns and collects the rows
+func (i *SQLiteIndex) listEntries(query string, args ...any) ([]*Entry, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	rows, err := i.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		var entry Entry
+		if err := rows.Scan(&entry.Hash, &entry.CellID, &entry.BucketID, &entry.Checksum, &entry.VolumeID, &entry.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	return entries, nil
+}
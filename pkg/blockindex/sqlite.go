@@ -0,0 +1,569 @@
+package blockindex
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"bharani/pkg/shards"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteIndex is an index kept in a SQLite database
+type SQLiteIndex struct {
+	db         *sql.DB
+	path       string
+	moved      []shards.Range        // Ranges handed to other index nodes
+	migrations map[shards.Range]bool // Ranges being copied to other nodes, and whether they are frozen
+	mu         sync.RWMutex
+}
+
+// NewSQLiteIndex opens or creates an index in a SQLite database
+func NewSQLiteIndex(dbPath string) (*SQLiteIndex, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	index := &SQLiteIndex{db: db, path: dbPath}
+
+	if err := index.initSchema(); err != nil {
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return index, nil
+}
+
+// initSchema creates the database schema
+func (i *SQLiteIndex) initSchema() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS blocks (
+		hash TEXT PRIMARY KEY,
+		cell_id TEXT NOT NULL,
+		bucket_id TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_cell_bucket ON blocks(cell_id, bucket_id);
+	`
+
+	if _, err := i.db.Exec(query); err != nil {
+		return err
+	}
+
+	// Entries written before volumes were recorded keep an empty volume_id
+	if err := i.addColumn("blocks", "volume_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Sizes and scrub times are unknown (0) for older entries
+	if err := i.addColumn("blocks", "size", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := i.addColumn("blocks", "verified_at", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Existing entries are live and, having no unreferenced time, never collected
+	if err := i.addColumn("blocks", "state", "TEXT NOT NULL DEFAULT 'live'"); err != nil {
+		return err
+	}
+	if err := i.addColumn("blocks", "unreferenced_at", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	query = `
+	CREATE INDEX IF NOT EXISTS idx_volume ON blocks(volume_id);
+	CREATE INDEX IF NOT EXISTS idx_collectable ON blocks(state, unreferenced_at);
+
+	CREATE TABLE IF NOT EXISTS intents (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		hash TEXT NOT NULL,
+		cell_id TEXT NOT NULL,
+		bucket_id TEXT NOT NULL,
+		volume_id TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		owner TEXT NOT NULL,
+		state TEXT NOT NULL DEFAULT 'pending',
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_intents_created ON intents(state, created_at);
+	`
+
+	if _, err := i.db.Exec(query); err != nil {
+		return err
+	}
+
+	// Intents recorded before tenants existed commit untenanted references
+	if err := i.addColumn("intents", "tenant", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := i.initRefs(); err != nil {
+		return err
+	}
+
+	return i.initRanges()
+}
+
+// initRefs creates the refs table. References are keyed by tenant as well as
+// owner, so tenants cannot collide on owner names; tables from before tenants
+// existed are rebuilt with their references kept untenanted.
+func (i *SQLiteIndex) initRefs() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS refs (
+		hash TEXT NOT NULL,
+		tenant TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (hash, tenant, owner)
+	);
+	`
+	if _, err := i.db.Exec(query); err != nil {
+		return err
+	}
+
+	tenanted, err := i.hasColumn("refs", "tenant")
+	if err != nil {
+		return err
+	}
+	if !tenanted {
+		if err := i.addTenantToRefs(); err != nil {
+			return err
+		}
+	}
+
+	return i.initUsage()
+}
+
+// addTenantToRefs rebuilds a refs table from before tenants existed, keeping
+// its references untenanted
+func (i *SQLiteIndex) addTenantToRefs() error {
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	ALTER TABLE refs RENAME TO refs_untenanted;
+	CREATE TABLE refs (
+		hash TEXT NOT NULL,
+		tenant TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (hash, tenant, owner)
+	);
+	INSERT INTO refs (hash, owner, created_at) SELECT hash, owner, created_at FROM refs_untenanted;
+	DROP TABLE refs_untenanted;
+	`
+	if _, err := tx.Exec(query); err != nil {
+		return fmt.Errorf("failed to add tenants to refs: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// initUsage creates the per-tenant usage table, which AddRefs, CommitPut and
+// Release keep up to date. A new table is filled in from existing references.
+func (i *SQLiteIndex) initUsage() error {
+	var exists int
+	if err := i.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tenant_usage'`).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	query := `
+	CREATE TABLE tenant_usage (
+		tenant TEXT PRIMARY KEY,
+		bytes INTEGER NOT NULL DEFAULT 0,
+		blocks INTEGER NOT NULL DEFAULT 0
+	);
+
+	INSERT INTO tenant_usage (tenant, bytes, blocks)
+	SELECT tenant, SUM(size), COUNT(*)
+	FROM (SELECT DISTINCT refs.tenant, blocks.hash, blocks.size FROM refs JOIN blocks ON blocks.hash = refs.hash)
+	GROUP BY tenant;
+	`
+	if _, err := i.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create usage: %w", err)
+	}
+	return nil
+}
+
+// addColumn adds a column to an existing table unless it is already present
+func (i *SQLiteIndex) addColumn(table, column, definition string) error {
+	present, err := i.hasColumn(table, column)
+	if err != nil || present {
+		return err
+	}
+
+	if _, err := i.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// hasColumn reports whether a table has a column
+func (i *SQLiteIndex) hasColumn(table, column string) (bool, error) {
+	rows, err := i.db.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s schema: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan %s schema: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to read %s schema: %w", table, err)
+	}
+
+	return false, nil
+}
+
+// PutEntry adds an entry for a block that is not indexed yet, created at
+// entry.CreatedAt. If the block is indexed already nothing is changed and the
+// existing entry is returned, so that writers racing to store the same block
+// agree on a single location. It returns ErrDeleting if the block is being
+// garbage collected, since its replicas may already be gone.
+func (i *SQLiteIndex) PutEntry(entry *Entry) (*Entry, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	existing, state, err := i.getEntry(entry.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put entry: %w", err)
+	}
+	if state == StateDeleting {
+		return nil, fmt.Errorf("%w: %s", ErrDeleting, entry.Hash)
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	query := `
+	INSERT INTO blocks (hash, cell_id, bucket_id, checksum, volume_id, size, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = i.db.Exec(query, entry.Hash, entry.CellID, entry.BucketID, entry.Checksum, entry.VolumeID, entry.Size, entry.CreatedAt.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to put entry: %w", err)
+	}
+
+	return nil, nil
+}
+
+// UpdateEntry moves an indexed block to the entry's location if it is still
+// at the expected one, and returns the updated entry. The block keeps its
+// creation and verification times, its references, and its size when
+// entry.Size is 0. If the block has moved since, nothing is changed and
+// ErrLocationChanged is returned with the entry as it is now, or with nil if
+// the block is no longer indexed. It returns ErrDeleting if the block is being
+// garbage collected.
+func (i *SQLiteIndex) UpdateEntry(entry *Entry, expected Location) (*Entry, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	current, state, err := i.getEntry(entry.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update entry: %w", err)
+	}
+	if state == StateDeleting {
+		return nil, fmt.Errorf("%w: %s", ErrDeleting, entry.Hash)
+	}
+	if current == nil || current.Location() != expected {
+		return current, fmt.Errorf("%w: %s", ErrLocationChanged, entry.Hash)
+	}
+
+	query := `
+	UPDATE blocks SET
+		cell_id = ?,
+		bucket_id = ?,
+		checksum = ?,
+		volume_id = ?,
+		size = CASE WHEN ? > 0 THEN ? ELSE size END
+	WHERE hash = ?
+	`
+
+	_, err = i.db.Exec(query, entry.CellID, entry.BucketID, entry.Checksum, entry.VolumeID, entry.Size, entry.Size, entry.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update entry: %w", err)
+	}
+
+	current.CellID = entry.CellID
+	current.BucketID = entry.BucketID
+	current.Checksum = entry.Checksum
+	current.VolumeID = entry.VolumeID
+	if entry.Size > 0 {
+		current.Size = entry.Size
+	}
+	return current, nil
+}
+
+// GetEntry retrieves a block index entry by hash
+func (i *SQLiteIndex) GetEntry(hash string) (*Entry, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	entry, _, err := i.getEntry(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	return entry, nil
+}
+
+// getEntry retrieves a block index entry and the block's state. The caller
+// must hold i.mu.
+func (i *SQLiteIndex) getEntry(hash string) (*Entry, string, error) {
+	query := `
+	SELECT hash, cell_id, bucket_id, checksum, volume_id, size, created_at, verified_at, state
+	FROM blocks
+	WHERE hash = ?
+	`
+
+	var (
+		entry      Entry
+		createdAt  int64
+		verifiedAt int64
+		state      string
+	)
+	err := i.db.QueryRow(query, hash).Scan(
+		&entry.Hash,
+		&entry.CellID,
+		&entry.BucketID,
+		&entry.Checksum,
+		&entry.VolumeID,
+		&entry.Size,
+		&createdAt,
+		&verifiedAt,
+		&state,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	entry.CreatedAt = time.Unix(createdAt, 0)
+	if verifiedAt > 0 {
+		entry.VerifiedAt = time.Unix(verifiedAt, 0)
+	}
+
+	return &entry, state, nil
+}
+
+// MarkVerified records that a scrub found every replica of a block intact,
+// filling in the block size if the entry does not have one yet. It reports
+// whether the entry exists.
+func (i *SQLiteIndex) MarkVerified(hash string, size int64, at time.Time) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	query := `
+	UPDATE blocks
+	SET verified_at = ?, size = CASE WHEN size = 0 THEN ? ELSE size END
+	WHERE hash = ?
+	`
+
+	result, err := i.db.Exec(query, at.Unix(), size, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark entry verified: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark entry verified: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// Exists checks if a block exists in the index
+func (i *SQLiteIndex) Exists(hash string) (bool, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	query := `SELECT 1 FROM blocks WHERE hash = ? LIMIT 1`
+	var exists int
+	err := i.db.QueryRow(query, hash).Scan(&exists)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+
+	return true, nil
+}
+
+// batchQuerySize bounds the number of hashes bound into a single IN query
+const batchQuerySize = 500
+
+// ExistsBatch returns the subset of hashes present in the index
+func (i *SQLiteIndex) ExistsBatch(hashes []string) (map[string]bool, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	existing := make(map[string]bool)
+	for start := 0; start < len(hashes); start += batchQuerySize {
+		batch := hashes[start:min(start+batchQuerySize, len(hashes))]
+
+		query := `SELECT hash FROM blocks WHERE hash IN (` + placeholders(len(batch)) + `)`
+		rows, err := i.db.Query(query, stringArgs(batch)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existence: %w", err)
+		}
+
+		for rows.Next() {
+			var hash string
+			if err := rows.Scan(&hash); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan hash: %w", err)
+			}
+			existing[hash] = true
+		}
+		rows.Close()
+	}
+
+	return existing, nil
+}
+
+// GetEntries retrieves the entries for the given hashes, omitting missing ones
+func (i *SQLiteIndex) GetEntries(hashes []string) (map[string]*Entry, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	entries := make(map[string]*Entry)
+	for start := 0; start < len(hashes); start += batchQuerySize {
+		batch := hashes[start:min(start+batchQuerySize, len(hashes))]
+
+		query := `
+		SELECT hash, cell_id, bucket_id, checksum, volume_id, size
+		FROM blocks
+		WHERE hash IN (` + placeholders(len(batch)) + `)
+		`
+		rows, err := i.db.Query(query, stringArgs(batch)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entries: %w", err)
+		}
+
+		for rows.Next() {
+			var entry Entry
+			if err := rows.Scan(&entry.Hash, &entry.CellID, &entry.BucketID, &entry.Checksum, &entry.VolumeID, &entry.Size); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan entry: %w", err)
+			}
+			entries[entry.Hash] = &entry
+		}
+		rows.Close()
+	}
+
+	return entries, nil
+}
+
+// placeholders returns n comma-separated SQL bind placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// stringArgs converts strings to query arguments
+func stringArgs(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+// Snapshot writes a consistent copy of the index to path
+func (i *SQLiteIndex) Snapshot(path string) error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if _, err := i.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to snapshot index: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the index with a copy written by Snapshot
+func (i *SQLiteIndex) Restore(path string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.db.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	if err := replaceFile(path, i.path); err != nil {
+		return fmt.Errorf("failed to restore index: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", i.path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	i.db = db
+	i.moved = nil
+
+	if err := i.initSchema(); err != nil {
+		return fmt.Errorf("failed to initialize schema: %w", err)
+	}
+	return nil
+}
+
+// replaceFile copies src over dst atomically
+func replaceFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".restore"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// Close closes the database connection
+func (i *SQLiteIndex) Close() error {
+	return i.db.Close()
+}
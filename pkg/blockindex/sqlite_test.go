@@ -0,0 +1,151 @@
+package blockindex
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteMigratesVolumeColumn(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+
+	// Create a table with the schema used before volumes were recorded
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE blocks (
+		hash TEXT PRIMARY KEY,
+		cell_id TEXT NOT NULL,
+		bucket_id TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+	);
+	INSERT INTO blocks (hash, cell_id, bucket_id, checksum) VALUES ('old', 'cell1', 'bucket1', 'old');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	db.Close()
+
+	index, err := NewSQLiteIndex(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open legacy index: %v", err)
+	}
+	defer index.Close()
+
+	entry, err := index.GetEntry("old")
+	if err != nil || entry == nil {
+		t.Fatalf("Failed to get legacy entry: %v", err)
+	}
+	if entry.VolumeID != "" || entry.BucketID != "bucket1" || entry.Size != 0 || !entry.VerifiedAt.IsZero() {
+		t.Errorf("Unexpected legacy entry: %+v", entry)
+	}
+
+	_, err = index.PutEntry(&Entry{Hash: "new", CellID: "cell1", BucketID: "bucket2", Checksum: "new", VolumeID: "vol1", CreatedAt: now})
+	if err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	entries, err := index.GetEntries([]string{"old", "new"})
+	if err != nil {
+		t.Fatalf("Failed to get entries: %v", err)
+	}
+	if entries["new"].VolumeID != "vol1" {
+		t.Errorf("Expected volume vol1, got %q", entries["new"].VolumeID)
+	}
+
+	// Reopening an already migrated index must not fail
+	index.Close()
+	index, err = NewSQLiteIndex(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen index: %v", err)
+	}
+	index.Close()
+}
+
+func TestSQLiteMigratesUntenantedRefs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+
+	// Create a refs table from before tenants were recorded
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE refs (
+		hash TEXT NOT NULL,
+		owner TEXT NOT NULL,
+		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+		PRIMARY KEY (hash, owner)
+	);
+	INSERT INTO refs (hash, owner) VALUES ('legacy', 'alice');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	db.Close()
+
+	index, err := NewSQLiteIndex(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open legacy index: %v", err)
+	}
+	defer index.Close()
+
+	if _, err := index.PutEntry(&Entry{Hash: "legacy", CellID: "cell1", BucketID: "b1", Checksum: "legacy", VolumeID: "v1", CreatedAt: now}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if _, err := index.AddRefs([]string{"legacy"}, "red", "alice", now); err != nil {
+		t.Fatalf("Failed to add refs: %v", err)
+	}
+
+	// Legacy references belong to the default tenant
+	if referenced, _ := index.Referenced([]string{"legacy"}, ""); !referenced["legacy"] {
+		t.Error("Legacy reference should belong to the default tenant")
+	}
+	ok, remaining, err := index.Release("legacy", "", "alice", time.Now())
+	if err != nil || !ok || remaining != 1 {
+		t.Errorf("Expected release leaving one reference, got %v %d %v", ok, remaining, err)
+	}
+}
+
+func TestSQLiteRebuildsTenantUsage(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	index, err := NewSQLiteIndex(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	for hash, size := range map[string]int64{"a": 100, "b": 10} {
+		if _, err := index.PutEntry(&Entry{Hash: hash, CellID: "cell1", BucketID: "b1", Checksum: hash, VolumeID: "v1", Size: size, CreatedAt: now}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+	}
+	if _, err := index.AddRefs([]string{"a", "b"}, "red", "photo", now); err != nil {
+		t.Fatalf("Failed to add refs: %v", err)
+	}
+	if _, err := index.AddRefs([]string{"a"}, "blue", "photo", now); err != nil {
+		t.Fatalf("Failed to add refs: %v", err)
+	}
+
+	// Usage is rebuilt from references when the table is first created
+	if _, err := index.db.Exec(`DROP TABLE tenant_usage`); err != nil {
+		t.Fatalf("Failed to drop usage: %v", err)
+	}
+	index.Close()
+	if index, err = NewSQLiteIndex(dbPath); err != nil {
+		t.Fatalf("Failed to reopen index: %v", err)
+	}
+	defer index.Close()
+
+	for tenant, want := range map[string][2]int64{"red": {110, 2}, "blue": {100, 1}} {
+		usage, err := index.GetUsage(tenant)
+		if err != nil {
+			t.Fatalf("Failed to get usage: %v", err)
+		}
+		if usage.Bytes != want[0] || usage.Blocks != want[1] {
+			t.Errorf("Tenant %q: expected %d bytes in %d blocks, got %d in %d", tenant, want[0], want[1], usage.Bytes, usage.Blocks)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/config"
+	"bharani/pkg/storage"
+	"bharani/proto/frontend"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrNotFound is returned when the frontend has no block under a hash
+	ErrNotFound = errors.New("block not found")
+
+	// ErrHashMismatch is returned when data read or written does not hash to the expected value
+	ErrHashMismatch = errors.New("hash mismatch")
+
+	// ErrQuotaExceeded is returned when a write would take the client's tenant
+	// over its storage quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Options configures a Client
+type Options struct {
+	Timeout        time.Duration          // Deadline for each RPC attempt
+	MaxRetries     int                    // Retries after the first attempt of a retryable call
+	InitialBackoff time.Duration          // Delay before the first retry, doubled after each one
+	MaxBackoff     time.Duration          // Upper bound on the delay between retries
+	Parallelism    int                    // Blocks transferred concurrently by file operations
+	MaxBlockSize   int64                  // Largest block the frontend accepts
+	Chunker        storage.ChunkerOptions // Content-defined chunking bounds for PutFile
+	DialOptions    []grpc.DialOption      // Extra options for New, applied after the TLS credentials
+	TLS            config.TLSConfig       // Client certificate with the client role; plaintext when unset
+	APIKey         string                 // Sent as x-api-key to frontends that authenticate clients
+	Token          string                 // Bearer JWT sent instead of an API key
+	Owner          string                 // Holder of the references to blocks the client writes; empty keeps them forever
+	CheckExisting  bool                   // Ask the frontend which file blocks it already has and upload only the rest
+}
+
+// DefaultOptions returns options matching the default cluster configuration
+func DefaultOptions() Options {
+	cfg := config.DefaultConfig()
+	return Options{
+		Timeout:        30 * time.Second,
+		MaxRetries:     4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Parallelism:    8,
+		MaxBlockSize:   cfg.MaxBlockSize,
+		CheckExisting:  true,
+		Chunker: storage.ChunkerOptions{
+			MinSize: cfg.ChunkMinSize,
+			AvgSize: cfg.ChunkAvgSize,
+			MaxSize: cfg.ChunkMaxSize,
+		},
+	}
+}
+
+// Client talks to a frontend, retrying transient failures and verifying
+// every block it reads or writes against its hash
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  frontend.FrontendServiceClient
+	opts Options
+}
+
+// New connects to the frontend at addr
+func New(addr string, opts Options) (*Client, error) {
+	dialOption, err := opts.TLS.DialOption()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+
+	dialOpts := append([]grpc.DialOption{dialOption}, opts.DialOptions...)
+	conn, err := grpc.NewClient(addr, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to frontend: %w", err)
+	}
+
+	c, err := NewFromConn(conn, opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.conn = conn
+	return c, nil
+}
+
+// NewFromConn creates a client on an existing connection, which the caller keeps ownership of
+func NewFromConn(conn grpc.ClientConnInterface, opts Options) (*Client, error) {
+	if opts.Timeout <= 0 || opts.InitialBackoff <= 0 || opts.MaxBackoff < opts.InitialBackoff {
+		return nil, fmt.Errorf("invalid client options: timeout and backoff must be positive")
+	}
+	if opts.MaxRetries < 0 || opts.Parallelism < 1 {
+		return nil, fmt.Errorf("invalid client options: retries must be non-negative and parallelism at least 1")
+	}
+	if err := opts.Chunker.Validate(opts.MaxBlockSize); err != nil {
+		return nil, fmt.Errorf("invalid client options: %w", err)
+	}
+
+	return &Client{
+		rpc:  frontend.NewFrontendServiceClient(conn),
+		opts: opts,
+	}, nil
+}
+
+// Close closes the connection opened by New
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// withCredentials attaches the API key or token to outgoing calls
+func (c *Client) withCredentials(ctx context.Context) context.Context {
+	if c.opts.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, auth.APIKeyHeader, c.opts.APIKey)
+	}
+	if c.opts.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, auth.AuthorizationHeader, "Bearer "+c.opts.Token)
+	}
+	return ctx
+}
+
+// Put stores a block and returns its hash. The frontend is told the hash the
+// client computed and rejects data damaged on the way, which is then sent
+// again. The block is referenced by Options.Owner.
+func (c *Client) Put(ctx context.Context, data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", fmt.Errorf("block data cannot be empty")
+	}
+	if int64(len(data)) > c.opts.MaxBlockSize {
+		return "", fmt.Errorf("block of %d bytes exceeds max block size %d", len(data), c.opts.MaxBlockSize)
+	}
+	hash := storage.ComputeHash(data)
+
+	err := c.retry(ctx, func(ctx context.Context) error {
+		resp, err := c.rpc.Put(ctx, &frontend.PutRequest{Data: data, Owner: c.opts.Owner, ExpectedHash: hash})
+		if status.Code(err) == codes.InvalidArgument {
+			return fmt.Errorf("%w: frontend rejected block %s: %v", ErrHashMismatch, hash, err)
+		}
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("put failed: %s", resp.Error)
+		}
+		if resp.Hash != hash {
+			return fmt.Errorf("%w: frontend stored %s, expected %s", ErrHashMismatch, resp.Hash, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// Exists returns the hashes the frontend already stores and references them
+// for Options.Owner, so the caller can skip uploading those blocks
+func (c *Client) Exists(ctx context.Context, hashes []string) ([]string, error) {
+	var existing []string
+	err := c.retry(ctx, func(ctx context.Context) error {
+		resp, err := c.rpc.Exists(ctx, &frontend.ExistsRequest{Hashes: hashes, Owner: c.opts.Owner})
+		if err != nil {
+			return err
+		}
+		if resp.Error != "" {
+			return fmt.Errorf("exists failed: %s", resp.Error)
+		}
+		existing = resp.Existing
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// Get retrieves a block, rejecting data that does not match hash
+func (c *Client) Get(ctx context.Context, hash string) ([]byte, error) {
+	var data []byte
+	err := c.retry(ctx, func(ctx context.Context) error {
+		resp, err := c.rpc.Get(ctx, &frontend.GetRequest{Hash: hash})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			// The frontend reports a missing block as frontend.ErrNotFound's message
+			if strings.HasPrefix(resp.Error, ErrNotFound.Error()) {
+				return fmt.Errorf("%w: %s", ErrNotFound, hash)
+			}
+			return fmt.Errorf("get failed: %s", resp.Error)
+		}
+		if storage.ComputeHash(resp.Data) != hash {
+			return fmt.Errorf("%w: block %s", ErrHashMismatch, hash)
+		}
+		data = resp.Data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Usage is a tenant's storage usage and quota. Zero maximums are unlimited.
+type Usage struct {
+	Tenant    string
+	Bytes     int64
+	Blocks    int64
+	MaxBytes  int64
+	MaxBlocks int64
+}
+
+// Usage returns the storage used by the client's tenant
+func (c *Client) Usage(ctx context.Context) (*Usage, error) {
+	var resp *frontend.GetUsageResponse
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.rpc.GetUsage(ctx, &frontend.GetUsageRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("usage failed: %s", resp.Error)
+	}
+
+	return &Usage{
+		Tenant:    resp.Tenant,
+		Bytes:     resp.Bytes,
+		Blocks:    resp.Blocks,
+		MaxBytes:  resp.MaxBytes,
+		MaxBlocks: resp.MaxBlocks,
+	}, nil
+}
+
+// Release drops Options.Owner's reference to a block, reporting whether it
+// held one. Blocks without references are deleted by the garbage collector.
+func (c *Client) Release(ctx context.Context, hash string) (bool, error) {
+	released, err := c.release(ctx, hash, false)
+	return released > 0, err
+}
+
+// ReleaseFile drops Options.Owner's references to a file stored with PutFile
+// and to every block it is made of, returning the number released
+func (c *Client) ReleaseFile(ctx context.Context, hash string) (int, error) {
+	return c.release(ctx, hash, true)
+}
+
+// release calls the frontend's Release RPC
+func (c *Client) release(ctx context.Context, hash string, file bool) (int, error) {
+	if c.opts.Owner == "" {
+		return 0, fmt.Errorf("releasing requires an owner")
+	}
+
+	var released int
+	err := c.retry(ctx, func(ctx context.Context) error {
+		resp, err := c.rpc.Release(ctx, &frontend.ReleaseRequest{Hash: hash, Owner: c.opts.Owner, File: file})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("release failed: %s", resp.Error)
+		}
+		released = int(resp.Released)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return released, nil
+}
+
+// BlockInfo describes where a block is stored and the state of its replicas
+type BlockInfo struct {
+	Hash       string
+	Size       int64 // 0 if unknown for blocks indexed before sizes were recorded
+	CellID     string
+	VolumeID   string
+	BucketID   string
+	Replicas   []ReplicaInfo
+	CreatedAt  time.Time
+	VerifiedAt time.Time // Last verification that found every replica intact, zero if never
+}
+
+// ReplicaInfo describes one replica of a block
+type ReplicaInfo struct {
+	Address string
+	ZoneID  string
+	Healthy bool   // The master considers the OSD alive
+	Check   string // Result of reading the replica, set only by Verify
+}
+
+// Stat returns a block's metadata without reading its data
+func (c *Client) Stat(ctx context.Context, hash string) (*BlockInfo, error) {
+	return c.stat(ctx, hash, false)
+}
+
+// Verify reads every replica of a block on the frontend and reports each
+// replica's state. Damaged replicas are queued for repair.
+func (c *Client) Verify(ctx context.Context, hash string) (*BlockInfo, error) {
+	return c.stat(ctx, hash, true)
+}
+
+// stat calls the frontend's Stat RPC
+func (c *Client) stat(ctx context.Context, hash string, verify bool) (*BlockInfo, error) {
+	var resp *frontend.StatResponse
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.rpc.Stat(ctx, &frontend.StatRequest{Hash: hash, Verify: verify})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("stat failed: %s", resp.Error)
+	}
+	if !resp.Found {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+
+	info := &BlockInfo{
+		Hash:      hash,
+		Size:      resp.Size,
+		CellID:    resp.CellId,
+		VolumeID:  resp.VolumeId,
+		BucketID:  resp.BucketId,
+		Replicas:  make([]ReplicaInfo, 0, len(resp.Replicas)),
+		CreatedAt: time.Unix(resp.CreatedAt, 0),
+	}
+	if resp.VerifiedAt > 0 {
+		info.VerifiedAt = time.Unix(resp.VerifiedAt, 0)
+	}
+	for _, replica := range resp.Replicas {
+		info.Replicas = append(info.Replicas, ReplicaInfo{
+			Address: replica.Address,
+			ZoneID:  replica.ZoneId,
+			Healthy: replica.Healthy,
+			Check:   replica.Check,
+		})
+	}
+
+	return info, nil
+}
@@ -0,0 +1,485 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/storage"
+	"bharani/proto/frontend"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeFrontend stores blocks in memory and injects failures on demand
+type fakeFrontend struct {
+	frontend.UnimplementedFrontendServiceServer
+
+	mu          sync.Mutex
+	blocks      map[string][]byte
+	calls       int
+	unavailable int            // Next calls to fail with Unavailable
+	failCode    codes.Code     // Code returned by every call when set
+	corrupt     map[string]int // Hash -> number of Gets to answer with damaged data
+	inFlight    int            // Puts currently being served
+	maxInFlight int            // Highest number of concurrent Puts seen
+	putDelay    time.Duration  // Time each Put takes
+	corruptPuts int            // Next Puts whose data is damaged on arrival
+	puts        int            // Blocks stored by Put
+	overQuota   bool           // Puts fail as over the tenant's quota
+}
+
+// update changes the fake's settings or counters under its lock
+func (f *fakeFrontend) update(fn func(f *fakeFrontend)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+// callCount returns the number of calls served and resets the counter
+func (f *fakeFrontend) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = 0
+	return calls
+}
+
+func (f *fakeFrontend) begin() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.overQuota {
+		st, _ := status.New(codes.ResourceExhausted, "tenant quota exceeded").WithDetails(&errdetails.QuotaFailure{})
+		return st.Err()
+	}
+	if f.failCode != codes.OK {
+		return status.Error(f.failCode, "injected failure")
+	}
+	if f.unavailable > 0 {
+		f.unavailable--
+		return status.Error(codes.Unavailable, "injected outage")
+	}
+	return nil
+}
+
+func (f *fakeFrontend) Put(ctx context.Context, req *frontend.PutRequest) (*frontend.PutResponse, error) {
+	if err := f.begin(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	delay := f.putDelay
+	f.mu.Unlock()
+
+	time.Sleep(delay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+
+	data := req.Data
+	if f.corruptPuts > 0 {
+		f.corruptPuts--
+		data = bytes.Clone(data)
+		data[0] ^= 0xff
+	}
+
+	hash := storage.ComputeHash(data)
+	if req.ExpectedHash != "" && hash != req.ExpectedHash {
+		return nil, status.Error(codes.InvalidArgument, "block does not match expected hash")
+	}
+	f.blocks[hash] = bytes.Clone(data)
+	f.puts++
+	return &frontend.PutResponse{Success: true, Hash: hash}, nil
+}
+
+func (f *fakeFrontend) Exists(ctx context.Context, req *frontend.ExistsRequest) (*frontend.ExistsResponse, error) {
+	if err := f.begin(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	existing := make([]string, 0)
+	for _, hash := range req.Hashes {
+		if _, ok := f.blocks[hash]; ok {
+			existing = append(existing, hash)
+		}
+	}
+	return &frontend.ExistsResponse{Existing: existing}, nil
+}
+
+// putCount returns the number of blocks stored and resets the counter
+func (f *fakeFrontend) putCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	puts := f.puts
+	f.puts = 0
+	return puts
+}
+
+func (f *fakeFrontend) Get(ctx context.Context, req *frontend.GetRequest) (*frontend.GetResponse, error) {
+	if err := f.begin(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.blocks[req.Hash]
+	if !ok {
+		return &frontend.GetResponse{Success: false, Error: "block not found: " + req.Hash}, nil
+	}
+	if f.corrupt[req.Hash] > 0 {
+		f.corrupt[req.Hash]--
+		data = bytes.Clone(data)
+		data[0] ^= 0xff
+	}
+	return &frontend.GetResponse{Success: true, Data: data}, nil
+}
+
+func (f *fakeFrontend) Stat(ctx context.Context, req *frontend.StatRequest) (*frontend.StatResponse, error) {
+	if err := f.begin(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.blocks[req.Hash]
+	if !ok {
+		return &frontend.StatResponse{Found: false}, nil
+	}
+	return &frontend.StatResponse{Found: true, Size: int64(len(data)), CreatedAt: time.Now().Unix()}, nil
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeFrontend) {
+	t.Helper()
+
+	fake := &fakeFrontend{
+		blocks:  make(map[string][]byte),
+		corrupt: make(map[string]int),
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	frontend.RegisterFrontendServiceServer(server, fake)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	opts := DefaultOptions()
+	opts.InitialBackoff = time.Millisecond
+	opts.MaxBackoff = 5 * time.Millisecond
+	opts.Timeout = 5 * time.Second
+	opts.Parallelism = 4
+	opts.MaxBlockSize = 2048
+	opts.Chunker = storage.ChunkerOptions{MinSize: 128, AvgSize: 512, MaxSize: 2048}
+
+	c, err := New(lis.Addr().String(), opts)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c, fake
+}
+
+func TestRetries(t *testing.T) {
+	c, fake := newTestClient(t)
+	ctx := context.Background()
+
+	fake.update(func(f *fakeFrontend) { f.unavailable = 2 })
+	hash, err := c.Put(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Put should survive transient failures: %v", err)
+	}
+	if calls := fake.callCount(); calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+
+	fake.update(func(f *fakeFrontend) { f.corrupt[hash] = 1 })
+	data, err := c.Get(ctx, hash)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("Get should retry past a corrupt response: %q, %v", data, err)
+	}
+
+	fake.callCount()
+	if _, err := c.Get(ctx, storage.ComputeHash([]byte("missing"))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if calls := fake.callCount(); calls != 1 {
+		t.Errorf("A missing block should not be retried, got %d attempts", calls)
+	}
+
+	fake.update(func(f *fakeFrontend) { f.failCode = codes.InvalidArgument })
+	if _, err := c.Get(ctx, hash); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", err)
+	}
+	if calls := fake.callCount(); calls != 1 {
+		t.Errorf("InvalidArgument should not be retried, got %d attempts", calls)
+	}
+
+	fake.update(func(f *fakeFrontend) { f.failCode = codes.Unavailable })
+	if _, err := c.Get(ctx, hash); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable once retries run out, got %v", err)
+	}
+	if calls := fake.callCount(); calls != c.opts.MaxRetries+1 {
+		t.Errorf("Expected %d attempts, got %d", c.opts.MaxRetries+1, calls)
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	c, fake := newTestClient(t)
+	ctx := context.Background()
+
+	// Rate limiting is retried, but an over-quota write is not
+	fake.update(func(f *fakeFrontend) { f.failCode = codes.ResourceExhausted })
+	if _, err := c.Put(ctx, []byte("hello")); status.Code(err) != codes.ResourceExhausted || errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected rate limit error, got %v", err)
+	}
+	if calls := fake.callCount(); calls != c.opts.MaxRetries+1 {
+		t.Errorf("Expected %d attempts, got %d", c.opts.MaxRetries+1, calls)
+	}
+
+	fake.update(func(f *fakeFrontend) { f.failCode, f.overQuota = codes.OK, true })
+	if _, err := c.Put(ctx, []byte("hello")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if calls := fake.callCount(); calls != 1 {
+		t.Errorf("An over-quota write should not be retried, got %d attempts", calls)
+	}
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	c, fake := newTestClient(t)
+	ctx := context.Background()
+	fake.update(func(f *fakeFrontend) { f.putDelay = 5 * time.Millisecond })
+
+	data := randomData(200 * 1024)
+
+	info, err := c.PutFile(ctx, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+	if info.Size != int64(len(data)) || info.BlockCount < 100 {
+		t.Fatalf("Unexpected file info %+v", info)
+	}
+	var maxInFlight int
+	fake.update(func(f *fakeFrontend) { maxInFlight = f.maxInFlight })
+	if maxInFlight < 2 || maxInFlight > c.opts.Parallelism {
+		t.Errorf("Expected between 2 and %d concurrent uploads, saw %d", c.opts.Parallelism, maxInFlight)
+	}
+
+	// More blocks than fit in one 2KB manifest, so the root must point at child manifests
+	root, err := c.getManifest(ctx, info.Hash)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if !root.Entries[0].Indirect {
+		t.Error("Root manifest should reference child manifests")
+	}
+
+	stat, err := c.Stat(ctx, info.Hash)
+	if err != nil {
+		t.Fatalf("Failed to stat manifest: %v", err)
+	}
+	if stat.Size == 0 || stat.CreatedAt.IsZero() {
+		t.Errorf("Unexpected stat %+v", stat)
+	}
+	if _, err := c.Stat(ctx, storage.ComputeHash([]byte("missing"))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from Stat, got %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := c.GetFile(ctx, info.Hash, &out); err != nil {
+		t.Fatalf("Failed to get file: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatal("File data mismatch")
+	}
+
+	w := c.NewWriter(ctx)
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if w.Info().Hash != info.Hash {
+		t.Error("Writer should produce the same manifest as PutFile")
+	}
+
+	r, err := c.NewReader(ctx, info.Hash)
+	if err != nil {
+		t.Fatalf("Failed to open reader: %v", err)
+	}
+	defer r.Close()
+	if r.Size() != int64(len(data)) {
+		t.Errorf("Reader reports %d bytes", r.Size())
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("Reader data mismatch")
+	}
+}
+
+func TestGetFileRejectsCorruptBlock(t *testing.T) {
+	c, fake := newTestClient(t)
+	ctx := context.Background()
+
+	data := randomData(16 * 1024)
+	info, err := c.PutFile(ctx, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+
+	manifest, err := c.getManifest(ctx, info.Hash)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	fake.update(func(f *fakeFrontend) { f.corrupt[manifest.Entries[0].Hash] = c.opts.MaxRetries + 1 })
+
+	err = c.GetFile(ctx, info.Hash, io.Discard)
+	if !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Expected ErrHashMismatch for a block corrupt on every read, got %v", err)
+	}
+}
+
+func TestPutRetriesDataDamagedInTransit(t *testing.T) {
+	c, fake := newTestClient(t)
+	ctx := context.Background()
+
+	fake.update(func(f *fakeFrontend) { f.corruptPuts = 2 })
+	hash, err := c.Put(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Put should resend damaged data: %v", err)
+	}
+	if hash != storage.ComputeHash([]byte("hello")) {
+		t.Errorf("Unexpected hash %s", hash)
+	}
+	if calls, puts := fake.callCount(), fake.putCount(); calls != 3 || puts != 1 {
+		t.Errorf("Expected 3 attempts storing 1 block, got %d and %d", calls, puts)
+	}
+
+	fake.update(func(f *fakeFrontend) { f.corruptPuts = c.opts.MaxRetries + 1 })
+	if _, err := c.Put(ctx, []byte("world")); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Expected ErrHashMismatch once retries run out, got %v", err)
+	}
+	if puts := fake.putCount(); puts != 0 {
+		t.Errorf("Damaged data should never be stored, %d blocks were", puts)
+	}
+}
+
+func TestResumePutFile(t *testing.T) {
+	c, fake := newTestClient(t)
+	ctx := context.Background()
+
+	// An upload interrupted half way leaves the blocks of its first half behind
+	data := randomData(64 * 1024)
+	if _, err := c.PutFile(ctx, bytes.NewReader(data[:len(data)/2])); err != nil {
+		t.Fatalf("Failed to put first half: %v", err)
+	}
+	first := fake.putCount()
+
+	info, err := c.PutFile(ctx, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+	resumed := fake.putCount()
+	if resumed >= info.BlockCount {
+		t.Errorf("Resuming stored %d blocks of %d, expected the first half to be skipped", resumed, info.BlockCount)
+	}
+	if resumed+first < info.BlockCount {
+		t.Errorf("Only %d blocks were ever stored for a file of %d", resumed+first, info.BlockCount)
+	}
+
+	// Repeating a finished upload sends no data at all
+	if _, err := c.PutFile(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to repeat upload: %v", err)
+	}
+	if puts := fake.putCount(); puts != 0 {
+		t.Errorf("Repeated upload stored %d blocks", puts)
+	}
+
+	var out bytes.Buffer
+	if err := c.GetFile(ctx, info.Hash, &out); err != nil || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("Resumed file does not round trip: %v", err)
+	}
+}
+
+// randomData returns n reproducible pseudo-random bytes
+func randomData(n int) []byte {
+	data := make([]byte, n)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range data {
+		data[i] = byte(rng.Uint32())
+	}
+	return data
+}
+
+func TestCredentials(t *testing.T) {
+	secret := []byte("test secret")
+	authenticator := auth.NewAuthenticator(map[string]auth.Identity{"red-key": {Tenant: "red"}}, secret)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer(authenticator.ServerOptions()...)
+	frontend.RegisterFrontendServiceServer(server, &fakeFrontend{blocks: make(map[string][]byte)})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	token, err := auth.IssueToken(secret, auth.Identity{Tenant: "red"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		apiKey string
+		token  string
+		code   codes.Code
+	}{
+		{"none", "", "", codes.Unauthenticated},
+		{"api key", "red-key", "", codes.OK},
+		{"token", "", token, codes.OK},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.MaxRetries = 0
+		opts.APIKey = tt.apiKey
+		opts.Token = tt.token
+		c, err := New(lis.Addr().String(), opts)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+
+		_, err = c.Put(context.Background(), []byte("credentials test"))
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.code, err)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,308 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"bharani/pkg/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// manifestEntryBudget is a conservative upper bound on the encoded size of
+// one manifest entry, matching the frontend's manifest splitting
+const manifestEntryBudget = 128
+
+// FileInfo describes a file stored through PutFile
+type FileInfo struct {
+	Hash       string // Hash of the root manifest block
+	Size       int64  // Total file length in bytes
+	BlockCount int    // Number of data blocks the file was split into
+}
+
+// PutFile chunks a stream on the client, uploads up to Parallelism blocks at
+// a time and stores a manifest describing them. The result is the same
+// manifest layout the frontend's PutFile produces. With CheckExisting set,
+// blocks the frontend already stores are not sent again, so repeating an
+// interrupted upload only transfers what is missing.
+func (c *Client) PutFile(ctx context.Context, r io.Reader) (*FileInfo, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	digest := sha256.New()
+	chunker, err := storage.NewChunker(io.TeeReader(r, digest), c.opts.Chunker)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	sem := make(chan struct{}, c.opts.Parallelism)
+	entries := make([]*storage.ManifestEntry, 0)
+	var readErr error
+	for ctx.Err() == nil {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = fmt.Errorf("failed to read file: %w", err)
+			break
+		}
+
+		// The chunker reuses its buffer, so the upload needs its own copy
+		chunk = bytes.Clone(chunk)
+		entry := &storage.ManifestEntry{Size: int64(len(chunk))}
+		entries = append(entries, entry)
+		n := len(entries)
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			hash, err := c.putMissing(ctx, chunk)
+			if err != nil {
+				fail(fmt.Errorf("failed to store block %d: %w", n, err))
+				return
+			}
+			entry.Hash = hash
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	flat := make([]storage.ManifestEntry, len(entries))
+	for i, entry := range entries {
+		flat[i] = *entry
+	}
+
+	manifest := storage.NewManifest(flat, hex.EncodeToString(digest.Sum(nil)))
+	hash, err := c.putManifest(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileInfo{
+		Hash:       hash,
+		Size:       manifest.TotalSize,
+		BlockCount: len(flat),
+	}, nil
+}
+
+// putManifest stores a manifest, splitting it into child manifests when it
+// would not fit in a single block, and returns the root manifest hash
+func (c *Client) putManifest(ctx context.Context, manifest *storage.Manifest) (string, error) {
+	maxEntries := int(c.opts.MaxBlockSize / manifestEntryBudget)
+
+	for len(manifest.Entries) > maxEntries {
+		parents := make([]storage.ManifestEntry, 0, len(manifest.Entries)/maxEntries+1)
+		for start := 0; start < len(manifest.Entries); start += maxEntries {
+			end := min(start+maxEntries, len(manifest.Entries))
+			child := storage.NewManifest(manifest.Entries[start:end], "")
+
+			hash, err := c.putManifestBlock(ctx, child)
+			if err != nil {
+				return "", err
+			}
+			parents = append(parents, storage.ManifestEntry{Hash: hash, Size: child.TotalSize, Indirect: true})
+		}
+		manifest = storage.NewManifest(parents, manifest.Digest)
+	}
+
+	return c.putManifestBlock(ctx, manifest)
+}
+
+// putManifestBlock encodes a single manifest and stores it as a block
+func (c *Client) putManifestBlock(ctx context.Context, manifest *storage.Manifest) (string, error) {
+	data, err := manifest.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := c.putMissing(ctx, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to store manifest: %w", err)
+	}
+
+	return hash, nil
+}
+
+// putMissing stores a block unless CheckExisting is set and the frontend
+// already has it. Frontends without the Exists call get every block.
+func (c *Client) putMissing(ctx context.Context, data []byte) (string, error) {
+	if c.opts.CheckExisting {
+		hash := storage.ComputeHash(data)
+		existing, err := c.Exists(ctx, []string{hash})
+		if err != nil && status.Code(err) != codes.Unimplemented {
+			return "", err
+		}
+		if len(existing) == 1 && existing[0] == hash {
+			return hash, nil
+		}
+	}
+
+	return c.Put(ctx, data)
+}
+
+// GetFile writes the file stored under the manifest hash to w, downloading up
+// to Parallelism blocks ahead of the one being written
+func (c *Client) GetFile(ctx context.Context, hash string, w io.Writer) error {
+	file, err := c.openFile(ctx, hash)
+	if err != nil {
+		return err
+	}
+	return c.download(ctx, file, w)
+}
+
+// remoteFile is a file's root manifest with its child manifests resolved
+type remoteFile struct {
+	hash   string
+	digest string
+	blocks []storage.ManifestEntry
+}
+
+// openFile fetches a file's manifest and flattens it into the list of data blocks
+func (c *Client) openFile(ctx context.Context, hash string) (*remoteFile, error) {
+	manifest, err := c.getManifest(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &remoteFile{hash: hash, digest: manifest.Digest}
+	if err := c.flatten(ctx, manifest, file); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// flatten appends the data blocks of a manifest to file, descending into child manifests
+func (c *Client) flatten(ctx context.Context, manifest *storage.Manifest, file *remoteFile) error {
+	for _, entry := range manifest.Entries {
+		if !entry.Indirect {
+			file.blocks = append(file.blocks, entry)
+			continue
+		}
+
+		child, err := c.getManifest(ctx, entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to resolve child manifest %s: %w", entry.Hash, err)
+		}
+		if child.TotalSize != entry.Size {
+			return fmt.Errorf("child manifest %s covers %d bytes, expected %d", entry.Hash, child.TotalSize, entry.Size)
+		}
+		if err := c.flatten(ctx, child, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getManifest retrieves and decodes the manifest stored under hash
+func (c *Client) getManifest(ctx context.Context, hash string) (*storage.Manifest, error) {
+	data, err := c.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return storage.DecodeManifest(data)
+}
+
+// fetchResult is the outcome of downloading one block
+type fetchResult struct {
+	data []byte
+	err  error
+}
+
+// download fetches a file's blocks concurrently and writes them to w in order,
+// checking each block's size and the file digest
+func (c *Client) download(ctx context.Context, file *remoteFile, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]chan fetchResult, len(file.blocks))
+	for i := range results {
+		results[i] = make(chan fetchResult, 1)
+	}
+
+	// A slot is taken per block fetched and released once it is written, so
+	// at most Parallelism blocks are held in memory
+	slots := make(chan struct{}, c.opts.Parallelism)
+	go func() {
+		for i, entry := range file.blocks {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				data, err := c.Get(ctx, entry.Hash)
+				results[i] <- fetchResult{data: data, err: err}
+			}()
+		}
+	}()
+
+	digest := sha256.New()
+	out := io.MultiWriter(w, digest)
+	for i, entry := range file.blocks {
+		var result fetchResult
+		select {
+		case result = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-slots
+
+		if result.err != nil {
+			return fmt.Errorf("failed to get block %d: %w", i+1, result.err)
+		}
+		if int64(len(result.data)) != entry.Size {
+			return fmt.Errorf("block %s has %d bytes, manifest expects %d", entry.Hash, len(result.data), entry.Size)
+		}
+		if _, err := out.Write(result.data); err != nil {
+			return fmt.Errorf("failed to write block %d: %w", i+1, err)
+		}
+	}
+
+	if file.digest != "" && hex.EncodeToString(digest.Sum(nil)) != file.digest {
+		return fmt.Errorf("%w: file digest for manifest %s", ErrHashMismatch, file.hash)
+	}
+
+	return nil
+}
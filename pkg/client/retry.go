@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retry runs op with a per-attempt deadline, retrying retryable failures with
+// jittered exponential backoff until MaxRetries is exhausted or ctx ends
+func (c *Client) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	ctx = c.withCredentials(ctx)
+	backoff := c.opts.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+		err := op(attemptCtx)
+		cancel()
+
+		if quotaExceeded(err) {
+			return fmt.Errorf("%w: %v", ErrQuotaExceeded, err)
+		}
+
+		if err == nil || !retryable(err) || attempt == c.opts.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		// Sleep between half and all of the current backoff
+		delay := backoff/2 + rand.N(backoff/2+1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, c.opts.MaxBackoff)
+	}
+}
+
+// quotaExceeded reports whether err is a ResourceExhausted status carrying a
+// QuotaFailure, which the frontend uses for over-quota writes. Other
+// ResourceExhausted errors are rate limits and are retried.
+func quotaExceeded(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return false
+	}
+	for _, detail := range st.Details() {
+		if _, ok := detail.(*errdetails.QuotaFailure); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether a failed call may succeed if repeated. Data that
+// failed hash verification was most likely damaged in transit or read from a
+// bad replica, so it is retried as well.
+func retryable(err error) bool {
+	if errors.Is(err, ErrHashMismatch) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"io"
+)
+
+// Writer uploads everything written to it as one file. The file is stored
+// when the Writer is closed.
+type Writer struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	info *FileInfo
+	err  error
+}
+
+// NewWriter starts a file upload that runs until the returned Writer is closed
+func (c *Client) NewWriter(ctx context.Context) *Writer {
+	pr, pw := io.Pipe()
+	w := &Writer{
+		pw:   pw,
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		w.info, w.err = c.PutFile(ctx, pr)
+		// Unblock any Write still in progress if the upload stopped early
+		pr.CloseWithError(w.err)
+	}()
+
+	return w
+}
+
+// Write implements io.Writer. It fails with the upload's error if the upload stopped early.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the upload and waits for the manifest to be stored
+func (w *Writer) Close() error {
+	w.pw.Close()
+	<-w.done
+	return w.err
+}
+
+// Info returns the stored file once Close has succeeded
+func (w *Writer) Info() *FileInfo {
+	return w.info
+}
+
+// Reader streams a file, prefetching blocks ahead of the reader
+type Reader struct {
+	pr     *io.PipeReader
+	cancel context.CancelFunc
+	size   int64
+}
+
+// NewReader resolves the manifest stored under hash and starts downloading
+// the file behind it. Errors found while streaming are returned by Read.
+func (c *Client) NewReader(ctx context.Context, hash string) (*Reader, error) {
+	file, err := c.openFile(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.download(ctx, file, pw))
+	}()
+
+	var size int64
+	for _, block := range file.blocks {
+		size += block.Size
+	}
+
+	return &Reader{pr: pr, cancel: cancel, size: size}, nil
+}
+
+// Read implements io.Reader
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.pr.Read(p)
+}
+
+// Size returns the length of the file in bytes
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Close stops the download
+func (r *Reader) Close() error {
+	r.cancel()
+	return r.pr.Close()
+}
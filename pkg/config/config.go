@@ -2,24 +2,42 @@ package config
 
 import (
 	"os"
+	"time"
 )
 
 // Config holds the configuration for the storage system
 type Config struct {
-	MaxBlockSize       int64
-	BucketSize         int64
-	DataShards         int
-	ParityShards       int
-	ReplicationFactor  int
-	FrontendPort       string
-	OSDPort            string
-	BlockIndexPort     string
-	ReplicationPort    string
-	MasterPort         string
-	VolumeManagerPort  string
-	OSDDataDir         string
-	CellID             string
-	ZoneID             string
+	MaxBlockSize      int64
+	BucketSize        int64
+	VolumeSize        int64 // Bytes of buckets a volume holds before the master closes it
+	ChunkMinSize      int
+	ChunkAvgSize      int
+	ChunkMaxSize      int
+	DataShards        int
+	ParityShards      int
+	ReplicationFactor int
+	WriteQuorum       int           // Replica acks required before a Put succeeds
+	ReplicaTimeout    time.Duration // Deadline for each individual replica write
+	HedgePercentile   float64       // Read latency percentile after which a hedged read is sent
+	HedgeMinDelay     time.Duration // Lower bound on the hedge delay
+	GCGracePeriod     time.Duration // How long a block stays unreferenced before it is deleted
+	CompactThreshold  float64       // Fraction of VolumeSize below which a closed volume's live blocks are moved out
+	IntentTimeout     time.Duration // Age after which the sweeper completes or cleans up an uncommitted Put
+	LocationCacheSize int           // Block locations a frontend caches; 0 disables the cache
+	VolumeCacheSize   int           // Volume replica sets a frontend caches; 0 disables the cache
+	FrontendPort      string
+	OSDPort           string
+	BlockIndexPort    string
+	ReplicationPort   string
+	MasterPort        string
+	VolumeManagerPort string
+	OSDDataDir        string
+	CellID            string
+	ZoneID            string
+	TLS               TLSConfig // Mutual TLS between services; disabled when empty
+	APIKeysFile       string    // Client API keys accepted by frontends, one "<key> <tenant> [admin]" per line
+	JWTSecret         string    // HS256 secret of the bearer tokens accepted by frontends
+	LimitsFile        string    // Per-tenant quotas and rate limits enforced by frontends
 }
 
 // DefaultConfig returns a default configuration
@@ -27,9 +45,22 @@ func DefaultConfig() *Config {
 	return &Config{
 		MaxBlockSize:      4 * 1024 * 1024,
 		BucketSize:        1 * 1024 * 1024 * 1024,
+		VolumeSize:        8 * 1024 * 1024 * 1024,
+		ChunkMinSize:      256 * 1024,
+		ChunkAvgSize:      1024 * 1024,
+		ChunkMaxSize:      4 * 1024 * 1024,
 		DataShards:        10,
 		ParityShards:      4,
 		ReplicationFactor: 3,
+		WriteQuorum:       2,
+		ReplicaTimeout:    5 * time.Second,
+		HedgePercentile:   0.95,
+		HedgeMinDelay:     5 * time.Millisecond,
+		GCGracePeriod:     24 * time.Hour,
+		CompactThreshold:  0.25,
+		IntentTimeout:     10 * time.Minute,
+		LocationCacheSize: 100000,
+		VolumeCacheSize:   10000,
 		FrontendPort:      "8080",
 		OSDPort:           "9090",
 		BlockIndexPort:    "9091",
@@ -39,6 +70,10 @@ func DefaultConfig() *Config {
 		OSDDataDir:        "./data",
 		CellID:            getEnvOrDefault("CELL_ID", "cell1"),
 		ZoneID:            getEnvOrDefault("ZONE_ID", "zone1"),
+		TLS:               tlsFromEnv(),
+		APIKeysFile:       os.Getenv("API_KEYS_FILE"),
+		JWTSecret:         os.Getenv("JWT_SECRET"),
+		LimitsFile:        os.Getenv("LIMITS_FILE"),
 	}
 }
 
@@ -48,4 +83,3 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
-
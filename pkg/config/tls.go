@@ -0,0 +1,133 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TLSConfig locates the PEM files a service uses for mutual TLS. When no file
+// is set TLS is disabled and services talk plaintext, as in tests and local
+// development.
+type TLSConfig struct {
+	CAFile   string // CA that signs every service and client certificate
+	CertFile string // Certificate presented to peers, with the caller's role as its OU
+	KeyFile  string // Private key of CertFile
+}
+
+// tlsFromEnv reads the TLS files from TLS_CA_FILE, TLS_CERT_FILE and TLS_KEY_FILE
+func tlsFromEnv() TLSConfig {
+	return TLSConfig{
+		CAFile:   os.Getenv("TLS_CA_FILE"),
+		CertFile: os.Getenv("TLS_CERT_FILE"),
+		KeyFile:  os.Getenv("TLS_KEY_FILE"),
+	}
+}
+
+// RegisterTLSFlags adds -tls-ca, -tls-cert and -tls-key to fs, defaulting to
+// the TLS_* environment variables. The returned config is filled in when fs
+// is parsed.
+func RegisterTLSFlags(fs *flag.FlagSet) *TLSConfig {
+	t := tlsFromEnv()
+	fs.StringVar(&t.CAFile, "tls-ca", t.CAFile, "CA certificate file; enables mutual TLS")
+	fs.StringVar(&t.CertFile, "tls-cert", t.CertFile, "Certificate file of this service")
+	fs.StringVar(&t.KeyFile, "tls-key", t.KeyFile, "Private key file of this service")
+	return &t
+}
+
+// Enabled reports whether any TLS file is configured
+func (t TLSConfig) Enabled() bool {
+	return t.CAFile != "" || t.CertFile != "" || t.KeyFile != ""
+}
+
+// load reads the CA pool and the key pair, all of which are required
+func (t TLSConfig) load() (*x509.CertPool, tls.Certificate, error) {
+	if t.CAFile == "" || t.CertFile == "" || t.KeyFile == "" {
+		return nil, tls.Certificate{}, fmt.Errorf("TLS needs a CA, a certificate and a key")
+	}
+
+	caPEM, err := os.ReadFile(t.CAFile)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("failed to read CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, tls.Certificate{}, fmt.Errorf("no certificates found in %s", t.CAFile)
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	return pool, cert, nil
+}
+
+// ServerTLS returns a TLS config that presents the service certificate and
+// requires every client to present one signed by the CA
+func (t TLSConfig) ServerTLS() (*tls.Config, error) {
+	pool, cert, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ClientTLS returns a TLS config that presents the service certificate and
+// only trusts servers signed by the CA
+func (t TLSConfig) ClientTLS() (*tls.Config, error) {
+	pool, cert, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ServerCredentials returns gRPC transport credentials for ServerTLS
+func (t TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
+	cfg, err := t.ServerTLS()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// ClientCredentials returns gRPC transport credentials for ClientTLS
+func (t TLSConfig) ClientCredentials() (credentials.TransportCredentials, error) {
+	cfg, err := t.ClientTLS()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// DialOption returns the transport credentials for connections to other
+// services: mutual TLS when enabled, plaintext otherwise
+func (t TLSConfig) DialOption() (grpc.DialOption, error) {
+	if !t.Enabled() {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+
+	creds, err := t.ClientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(creds), nil
+}
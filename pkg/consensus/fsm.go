@@ -0,0 +1,165 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"bharani/proto/consensus"
+
+	"github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// Store is the state a group replicates. It must only be changed by the calls
+// the node applies from the log.
+type Store interface {
+	// Snapshot writes a consistent copy of the store to path, which does not
+	// exist yet
+	Snapshot(path string) error
+
+	// Restore replaces the store's contents with the copy at path
+	Restore(path string) error
+}
+
+// service is a gRPC service whose calls a node replicates
+type service struct {
+	impl    any
+	methods map[string]grpc.MethodDesc
+	reads   map[string]bool // Methods that do not change the store
+	stamp   func(req proto.Message, now time.Time)
+}
+
+// newService indexes the methods of a service
+func newService(s Service) *service {
+	svc := &service{
+		impl:    s.Impl,
+		methods: make(map[string]grpc.MethodDesc, len(s.Desc.Methods)),
+		reads:   make(map[string]bool, len(s.Reads)),
+		stamp:   s.Stamp,
+	}
+	for _, md := range s.Desc.Methods {
+		svc.methods[md.MethodName] = md
+	}
+	for _, method := range s.Reads {
+		svc.reads[method] = true
+	}
+	return svc
+}
+
+// invoke calls a method of the service with a serialized request
+func (s *service) invoke(ctx context.Context, method string, request []byte) (any, error) {
+	md, ok := s.methods[method]
+	if !ok {
+		return nil, fmt.Errorf("unknown method %s", method)
+	}
+	dec := func(v any) error {
+		return proto.Unmarshal(request, v.(proto.Message))
+	}
+	return md.Handler(s.impl, ctx, dec, nil)
+}
+
+// applyResult is what applying a command returned
+type applyResult struct {
+	resp any
+	err  error
+}
+
+// fsm applies the commands of the log by calling the services they name,
+// which change the store
+type fsm struct {
+	dir      string
+	store    Store
+	services map[string]*service
+}
+
+// Apply applies a committed command
+func (f *fsm) Apply(l *raft.Log) any {
+	var cmd consensus.Command
+	if err := proto.Unmarshal(l.Data, &cmd); err != nil {
+		return &applyResult{err: fmt.Errorf("failed to decode command: %w", err)}
+	}
+
+	name, method := splitMethod(cmd.Method)
+	svc, ok := f.services[name]
+	if !ok {
+		return &applyResult{err: fmt.Errorf("unknown service %s", name)}
+	}
+
+	resp, err := svc.invoke(context.Background(), method, cmd.Request)
+	return &applyResult{resp: resp, err: err}
+}
+
+// Snapshot copies the store. It runs between applies, so the copy matches
+// the log up to the last one.
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	dir, err := os.MkdirTemp(f.dir, "snapshot-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+
+	path := filepath.Join(dir, "state")
+	if err := f.store.Snapshot(path); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &storeSnapshot{dir: dir, path: path}, nil
+}
+
+// Restore replaces the store with a snapshot
+func (f *fsm) Restore(snapshot io.ReadCloser) error {
+	defer snapshot.Close()
+
+	dir, err := os.MkdirTemp(f.dir, "restore-")
+	if err != nil {
+		return fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state")
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+	if _, err := io.Copy(file, snapshot); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+
+	return f.store.Restore(path)
+}
+
+// storeSnapshot is a copy of the store waiting to be written to the snapshot
+// store
+type storeSnapshot struct {
+	dir  string
+	path string
+}
+
+// Persist writes the copy to the sink
+func (s *storeSnapshot) Persist(sink raft.SnapshotSink) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		sink.Cancel()
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(sink, file); err != nil {
+		sink.Cancel()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return sink.Close()
+}
+
+// Release removes the copy
+func (s *storeSnapshot) Release() {
+	os.RemoveAll(s.dir)
+}
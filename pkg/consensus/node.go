@@ -0,0 +1,524 @@
+// Package consensus replicates the state of a metadata service over a Raft
+// group. Calls that change the state are appended to the Raft log and applied
+// on every replica by the service's own handlers. Calls that only read it are
+// served by the leader once it has confirmed it still leads, and replicas
+// that are not the leader forward every call to the one that is.
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"bharani/pkg/config"
+	"bharani/proto/consensus"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	// applyTimeout bounds how long a call waits for the log to accept it
+	applyTimeout = 10 * time.Second
+
+	// leaderWait bounds how long a call waits for a leader to be elected, or
+	// for a new leader to apply the writes of earlier terms
+	leaderWait = 10 * time.Second
+
+	// snapshotsRetained is the number of snapshots kept on disk
+	snapshotsRetained = 2
+)
+
+// errNotLeader is returned by a replica asked to lead a call it cannot. No
+// change has been made, so the caller may try the new leader.
+var errNotLeader = status.Error(codes.FailedPrecondition, "not the raft leader")
+
+// Peer is a member of a Raft group
+type Peer struct {
+	ID       string // The replica's gRPC address, which other replicas forward calls to
+	RaftAddr string // Address of the replica's Raft transport
+}
+
+// ParsePeers parses a comma-separated list of id=raftaddr pairs
+func ParsePeers(s string) ([]Peer, error) {
+	peers := make([]Peer, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, addr, ok := strings.Cut(part, "=")
+		if !ok || id == "" || addr == "" {
+			return nil, fmt.Errorf("invalid raft peer %q, expected id=address", part)
+		}
+		peers = append(peers, Peer{ID: id, RaftAddr: addr})
+	}
+	return peers, nil
+}
+
+// Service is a gRPC service whose calls a group replicates
+type Service struct {
+	Desc  *grpc.ServiceDesc
+	Impl  any
+	Reads []string // Methods that do not change the store
+
+	// Stamp sets the timestamps a write request leaves unset to now. The
+	// leader stamps each write before appending it to the log, so every
+	// replica applies it with the same times instead of reading its own clock.
+	Stamp func(req proto.Message, now time.Time)
+}
+
+// Config configures a replica
+type Config struct {
+	ID    string // This replica's ID, which must appear in Peers
+	Peers []Peer // Every member of the group
+	Dir   string // Raft log and snapshots
+	TLS   config.TLSConfig
+}
+
+// Node is one replica of a Raft group. It implements the Consensus service,
+// through which the other replicas forward calls to it while it leads.
+type Node struct {
+	id         string
+	raft       *raft.Raft
+	fsm        *fsm
+	logStore   *raftboltdb.BoltStore
+	transport  io.Closer
+	dialOption grpc.DialOption
+	peers      map[string]consensus.ConsensusServiceClient
+	conns      []*grpc.ClientConn
+	ready      bool   // This replica leads and has applied every earlier write
+	term       uint64 // Incremented on every change of leadership
+	done       chan struct{}
+	mu         sync.Mutex
+	consensus.UnimplementedConsensusServiceServer
+}
+
+// StatePath returns the path of a store's state in the node directory,
+// removing any state left by an earlier run: a replica's store is rebuilt
+// from its snapshots and log every time it starts.
+func StatePath(dir, name string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create raft directory: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to remove stale state: %w", err)
+	}
+	return path, nil
+}
+
+// NewNode starts a replica of store, changed by calls to services. A group
+// that has never run is bootstrapped with Peers as its members. The gRPC
+// server of the services must use the node's interceptor.
+func NewNode(cfg Config, store Store, services ...Service) (*Node, error) {
+	var self *Peer
+	servers := make([]raft.Server, 0, len(cfg.Peers))
+	for i, peer := range cfg.Peers {
+		if peer.ID == cfg.ID {
+			self = &cfg.Peers[i]
+		}
+		servers = append(servers, raft.Server{
+			Suffrage: raft.Voter,
+			ID:       raft.ServerID(peer.ID),
+			Address:  raft.ServerAddress(peer.RaftAddr),
+		})
+	}
+	if self == nil {
+		return nil, fmt.Errorf("raft peers do not include %s", cfg.ID)
+	}
+
+	dialOption, err := cfg.TLS.DialOption()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create raft directory: %w", err)
+	}
+
+	logger := hclog.New(&hclog.LoggerOptions{Name: "raft", Level: hclog.Info})
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(cfg.ID)
+	raftConfig.Logger = logger
+
+	logStore, err := raftboltdb.NewBoltStore(filepath.Join(cfg.Dir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft log: %w", err)
+	}
+	snapshots, err := raft.NewFileSnapshotStoreWithLogger(cfg.Dir, snapshotsRetained, logger)
+	if err != nil {
+		logStore.Close()
+		return nil, fmt.Errorf("failed to open raft snapshots: %w", err)
+	}
+	transport, closer, err := newTransport(self.RaftAddr, cfg.TLS, logger)
+	if err != nil {
+		logStore.Close()
+		return nil, err
+	}
+
+	n := &Node{
+		id:         cfg.ID,
+		fsm:        &fsm{dir: cfg.Dir, store: store, services: make(map[string]*service)},
+		logStore:   logStore,
+		transport:  closer,
+		dialOption: dialOption,
+		peers:      make(map[string]consensus.ConsensusServiceClient),
+		done:       make(chan struct{}),
+	}
+	for _, s := range services {
+		n.fsm.services[s.Desc.ServiceName] = newService(s)
+	}
+
+	existing, err := raft.HasExistingState(logStore, logStore, snapshots)
+	if err != nil {
+		n.closeStores()
+		return nil, fmt.Errorf("failed to read raft state: %w", err)
+	}
+
+	r, err := raft.NewRaft(raftConfig, n.fsm, logStore, logStore, snapshots, transport)
+	if err != nil {
+		n.closeStores()
+		return nil, fmt.Errorf("failed to start raft: %w", err)
+	}
+	n.raft = r
+
+	// Every member bootstraps with the same configuration, which raft allows
+	if !existing {
+		if err := r.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil {
+			r.Shutdown().Error()
+			n.closeStores()
+			return nil, fmt.Errorf("failed to bootstrap raft group: %w", err)
+		}
+	}
+
+	go n.watchLeadership()
+	return n, nil
+}
+
+// watchLeadership tracks whether this replica leads. A new leader may not
+// have applied every write of earlier terms yet, so it serves reads only once
+// a barrier has gone through the log.
+func (n *Node) watchLeadership() {
+	for {
+		var leading bool
+		select {
+		case <-n.done:
+			return
+		case leading = <-n.raft.LeaderCh():
+		}
+
+		n.mu.Lock()
+		n.ready = false
+		n.term++
+		term := n.term
+		n.mu.Unlock()
+
+		if !leading {
+			continue
+		}
+		go func() {
+			if err := n.raft.Barrier(leaderWait).Error(); err != nil {
+				return
+			}
+			n.mu.Lock()
+			if n.term == term {
+				n.ready = true
+			}
+			n.mu.Unlock()
+		}()
+	}
+}
+
+// IsLeader reports whether this replica leads the group
+func (n *Node) IsLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+// Leader returns the ID of the replica leading the group, or "" if there is
+// no leader
+func (n *Node) Leader() string {
+	_, id := n.raft.LeaderWithID()
+	return string(id)
+}
+
+// Snapshot snapshots the store and compacts the log
+func (n *Node) Snapshot() error {
+	return n.raft.Snapshot().Error()
+}
+
+// Close stops the replica
+func (n *Node) Close() error {
+	close(n.done)
+	err := n.raft.Shutdown().Error()
+	n.closeStores()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, conn := range n.conns {
+		conn.Close()
+	}
+	return err
+}
+
+// closeStores closes the transport and the log
+func (n *Node) closeStores() {
+	n.transport.Close()
+	n.logStore.Close()
+}
+
+// UnaryServerInterceptor routes calls to registered services through the
+// group: reads are served once leadership is confirmed, writes are applied
+// through the log, and replicas that do not lead forward calls to the leader
+func (n *Node) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		name, method := splitMethod(info.FullMethod)
+		svc, ok := n.fsm.services[name]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		request, err := proto.Marshal(req.(proto.Message))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to encode request: %v", err)
+		}
+
+		if n.IsLeader() {
+			var resp any
+			if svc.reads[method] {
+				if err = n.verifyRead(ctx); err == nil {
+					resp, err = handler(ctx, req)
+				}
+			} else {
+				resp, err = n.apply(info.FullMethod, request)
+			}
+			if !notLeader(err) {
+				return resp, err
+			}
+		}
+
+		return n.forward(ctx, info.FullMethod, request)
+	}
+}
+
+// Forward runs a call forwarded by another replica
+func (n *Node) Forward(ctx context.Context, cmd *consensus.Command) (*consensus.ForwardResponse, error) {
+	name, method := splitMethod(cmd.Method)
+	svc, ok := n.fsm.services[name]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "service %s is not replicated", name)
+	}
+	if !n.IsLeader() {
+		return nil, errNotLeader
+	}
+
+	var (
+		resp any
+		err  error
+	)
+	if svc.reads[method] {
+		if err = n.verifyRead(ctx); err == nil {
+			resp, err = svc.invoke(ctx, method, cmd.Request)
+		}
+	} else {
+		resp, err = n.apply(cmd.Method, cmd.Request)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := proto.Marshal(resp.(proto.Message))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return &consensus.ForwardResponse{Response: response}, nil
+}
+
+// apply stamps a write, appends it to the log and returns what applying it
+// returned
+func (n *Node) apply(fullMethod string, request []byte) (any, error) {
+	name, _ := splitMethod(fullMethod)
+	if svc, ok := n.fsm.services[name]; ok && svc.stamp != nil {
+		req, err := newMessage(fullMethod, true)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+		if err := proto.Unmarshal(request, req); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to decode request: %v", err)
+		}
+		svc.stamp(req, time.Now())
+		if request, err = proto.Marshal(req); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to encode request: %v", err)
+		}
+	}
+
+	data, err := proto.Marshal(&consensus.Command{Method: fullMethod, Request: request})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode command: %v", err)
+	}
+
+	future := n.raft.Apply(data, applyTimeout)
+	if err := future.Error(); err != nil {
+		if errors.Is(err, raft.ErrNotLeader) {
+			return nil, errNotLeader
+		}
+		// The write may or may not have been committed
+		return nil, status.Errorf(codes.Unavailable, "failed to apply %s: %v", fullMethod, err)
+	}
+
+	result := future.Response().(*applyResult)
+	return result.resp, result.err
+}
+
+// verifyRead makes sure a read on this replica sees every write acknowledged
+// by any leader: the replica must have applied the writes of earlier terms,
+// and must still lead
+func (n *Node) verifyRead(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, leaderWait)
+	defer cancel()
+
+	for {
+		n.mu.Lock()
+		ready := n.ready
+		n.mu.Unlock()
+		if ready {
+			break
+		}
+		if !n.IsLeader() {
+			return errNotLeader
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.Error(codes.Unavailable, "raft leader has not caught up")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if err := n.raft.VerifyLeader().Error(); err != nil {
+		if errors.Is(err, raft.ErrNotLeader) {
+			return errNotLeader
+		}
+		return status.Errorf(codes.Unavailable, "failed to confirm raft leadership: %v", err)
+	}
+	return nil
+}
+
+// forward hands a call to the leader and decodes its response. Calls the
+// leader could not take because leadership moved are sent to the new leader.
+func (n *Node) forward(ctx context.Context, fullMethod string, request []byte) (any, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, leaderWait)
+	defer cancel()
+
+	for {
+		resp, err := n.forwardOnce(ctx, fullMethod, request)
+		if !notLeader(err) {
+			return resp, err
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return nil, status.Error(codes.Unavailable, "no raft leader")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// forwardOnce hands a call to the replica this one believes leads
+func (n *Node) forwardOnce(ctx context.Context, fullMethod string, request []byte) (any, error) {
+	leader := n.Leader()
+	if leader == "" || leader == n.id {
+		// Not elected yet, or this replica is taking over
+		return nil, errNotLeader
+	}
+
+	client, err := n.peer(leader)
+	if err != nil {
+		return nil, err
+	}
+	forwarded, err := client.Forward(ctx, &consensus.Command{Method: fullMethod, Request: request})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := newMessage(fullMethod, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(forwarded.Response, resp); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decode forwarded response: %v", err)
+	}
+	return resp, nil
+}
+
+// notLeader reports whether a call failed with errNotLeader, locally or on
+// another replica
+func notLeader(err error) bool {
+	s, ok := status.FromError(err)
+	return ok && err != nil && s.Code() == codes.FailedPrecondition && s.Message() == status.Convert(errNotLeader).Message()
+}
+
+// peer gets or creates a client for another replica
+func (n *Node) peer(id string) (consensus.ConsensusServiceClient, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if client, ok := n.peers[id]; ok {
+		return client, nil
+	}
+
+	conn, err := grpc.NewClient(id, n.dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to raft peer %s: %w", id, err)
+	}
+	client := consensus.NewConsensusServiceClient(conn)
+	n.peers[id] = client
+	n.conns = append(n.conns, conn)
+	return client, nil
+}
+
+// newMessage creates an empty request or response message for a full method
+// name
+func newMessage(fullMethod string, request bool) (proto.Message, error) {
+	name, method := splitMethod(fullMethod)
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown service %s: %w", name, err)
+	}
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", name)
+	}
+	md := svc.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("unknown method %s", fullMethod)
+	}
+	msgDesc := md.Output()
+	if request {
+		msgDesc = md.Input()
+	}
+	msgType, err := protoregistry.GlobalTypes.FindMessageByName(msgDesc.FullName())
+	if err != nil {
+		return nil, fmt.Errorf("unknown message type of %s: %w", fullMethod, err)
+	}
+	return msgType.New().Interface(), nil
+}
+
+// splitMethod splits "/package.Service/Method" into its service and method
+func splitMethod(fullMethod string) (string, string) {
+	name, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return name, method
+}
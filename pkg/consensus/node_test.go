@@ -0,0 +1,420 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bharani/pkg/blockindex"
+	"bharani/pkg/replication"
+	"bharani/pkg/shards"
+	blockindexpb "bharani/proto/blockindex"
+	"bharani/proto/consensus"
+	replicationpb "bharani/proto/replication"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+// testStore is a store the test can close once its replica stops
+type testStore interface {
+	Store
+	Close() error
+}
+
+// testReplica is a running member of a test group
+type testReplica struct {
+	node   *Node
+	server *grpc.Server
+	store  testStore
+}
+
+// testGroup runs a Raft group of in-process replicas on loopback ports
+type testGroup struct {
+	t        *testing.T
+	peers    []Peer
+	dirs     []string
+	replicas []*testReplica
+	conns    []*grpc.ClientConn
+	open     func(path string) (testStore, Service, func(*grpc.Server))
+}
+
+// freeAddr returns a loopback address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+// newTestGroup starts a group of n replicas of the stores open creates
+func newTestGroup(t *testing.T, n int, open func(path string) (testStore, Service, func(*grpc.Server))) *testGroup {
+	g := &testGroup{
+		t:        t,
+		replicas: make([]*testReplica, n),
+		open:     open,
+	}
+	for i := range n {
+		g.peers = append(g.peers, Peer{ID: freeAddr(t), RaftAddr: freeAddr(t)})
+		g.dirs = append(g.dirs, t.TempDir())
+
+		conn, err := grpc.NewClient(g.peers[i].ID, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("Failed to connect to replica %d: %v", i, err)
+		}
+		g.conns = append(g.conns, conn)
+	}
+	t.Cleanup(func() {
+		for i := range g.replicas {
+			if g.replicas[i] != nil {
+				g.stop(i)
+			}
+		}
+		for _, conn := range g.conns {
+			conn.Close()
+		}
+	})
+
+	for i := range n {
+		g.start(i)
+	}
+	return g
+}
+
+// start starts replica i, rebuilding its store from its snapshots and log
+func (g *testGroup) start(i int) {
+	g.t.Helper()
+
+	path, err := StatePath(g.dirs[i], "state.db")
+	if err != nil {
+		g.t.Fatalf("Failed to prepare replica %d: %v", i, err)
+	}
+	store, svc, register := g.open(path)
+
+	node, err := NewNode(Config{ID: g.peers[i].ID, Peers: g.peers, Dir: g.dirs[i]}, store, svc)
+	if err != nil {
+		store.Close()
+		g.t.Fatalf("Failed to start replica %d: %v", i, err)
+	}
+
+	lis, err := net.Listen("tcp", g.peers[i].ID)
+	if err != nil {
+		node.Close()
+		store.Close()
+		g.t.Fatalf("Failed to listen for replica %d: %v", i, err)
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(node.UnaryServerInterceptor()))
+	register(server)
+	consensus.RegisterConsensusServiceServer(server, node)
+	go server.Serve(lis)
+
+	g.replicas[i] = &testReplica{node: node, server: server, store: store}
+}
+
+// stop stops replica i
+func (g *testGroup) stop(i int) {
+	r := g.replicas[i]
+	r.server.Stop()
+	r.node.Close()
+	r.store.Close()
+	g.replicas[i] = nil
+}
+
+// waitLeader waits for a running replica to lead and returns it
+func (g *testGroup) waitLeader() int {
+	g.t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		for i, r := range g.replicas {
+			if r != nil && r.node.IsLeader() {
+				return i
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	g.t.Fatal("No replica became leader")
+	return -1
+}
+
+// followers returns the running replicas other than the leader
+func (g *testGroup) followers(leader int) []int {
+	followers := make([]int, 0)
+	for i, r := range g.replicas {
+		if r != nil && i != leader {
+			followers = append(followers, i)
+		}
+	}
+	return followers
+}
+
+// eventually retries check until it succeeds or the deadline passes
+func eventually(t *testing.T, check func() error) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// newIndexGroup starts a group replicating a block index
+func newIndexGroup(t *testing.T) *testGroup {
+	return newStampedIndexGroup(t, blockindex.StampRequest)
+}
+
+// newStampedIndexGroup starts a group replicating a block index whose writes
+// are stamped by stamp
+func newStampedIndexGroup(t *testing.T, stamp func(proto.Message, time.Time)) *testGroup {
+	return newTestGroup(t, 3, func(path string) (testStore, Service, func(*grpc.Server)) {
+		index, err := blockindex.NewSQLiteIndex(path)
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		svc := blockindex.NewBlockIndexService(index)
+		return index, Service{Desc: &blockindexpb.BlockIndexService_ServiceDesc, Impl: svc, Reads: blockindex.ReadMethods, Stamp: stamp}, func(s *grpc.Server) {
+			blockindexpb.RegisterBlockIndexServiceServer(s, svc)
+		}
+	})
+}
+
+func (g *testGroup) index(i int) blockindexpb.BlockIndexServiceClient {
+	return blockindexpb.NewBlockIndexServiceClient(g.conns[i])
+}
+
+func putEntry(ctx context.Context, client blockindexpb.BlockIndexServiceClient, hash string) error {
+	resp, err := client.PutEntry(ctx, &blockindexpb.PutEntryRequest{Hash: hash, CellId: "cell1", BucketId: "b1", Checksum: hash, VolumeId: "v1", Size: 10})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to put %s: %s", hash, resp.Error)
+	}
+	return nil
+}
+
+func getEntry(ctx context.Context, client blockindexpb.BlockIndexServiceClient, hash string) error {
+	resp, err := client.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+	if err != nil {
+		return err
+	}
+	if !resp.Found {
+		return fmt.Errorf("block %s not found", hash)
+	}
+	return nil
+}
+
+// hasEntry checks a replica's own copy of the index, bypassing the group
+func (g *testGroup) hasEntry(i int, hash string) error {
+	entry, err := g.replicas[i].store.(blockindex.Index).GetEntry(hash)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("replica %d is missing block %s", i, hash)
+	}
+	return nil
+}
+
+func TestIndexFailover(t *testing.T) {
+	ctx := context.Background()
+	g := newIndexGroup(t)
+
+	leader := g.waitLeader()
+	followers := g.followers(leader)
+
+	// A write through a follower is visible through another follower at once,
+	// and reaches every replica's copy
+	if err := putEntry(ctx, g.index(followers[0]), "aa"); err != nil {
+		t.Fatalf("Failed to write through a follower: %v", err)
+	}
+	if err := getEntry(ctx, g.index(followers[1]), "aa"); err != nil {
+		t.Fatalf("Failed to read through a follower: %v", err)
+	}
+	for i := range g.replicas {
+		eventually(t, func() error { return g.hasEntry(i, "aa") })
+	}
+
+	// Losing the leader loses no acknowledged write
+	g.stop(leader)
+	newLeader := g.waitLeader()
+	if newLeader == leader {
+		t.Fatal("Stopped replica should not lead")
+	}
+	for _, i := range g.followers(leader) {
+		if err := getEntry(ctx, g.index(i), "aa"); err != nil {
+			t.Fatalf("Failed to read from replica %d after failover: %v", i, err)
+		}
+	}
+	if err := putEntry(ctx, g.index(g.followers(newLeader)[0]), "bb"); err != nil {
+		t.Fatalf("Failed to write after failover: %v", err)
+	}
+
+	// The old leader catches up when it returns
+	g.start(leader)
+	eventually(t, func() error { return g.hasEntry(leader, "bb") })
+	if err := getEntry(ctx, g.index(leader), "bb"); err != nil {
+		t.Fatalf("Failed to read through the returning replica: %v", err)
+	}
+}
+
+func TestIndexRestoresSnapshot(t *testing.T) {
+	ctx := context.Background()
+	g := newIndexGroup(t)
+
+	leader := g.waitLeader()
+	for i := range 20 {
+		if err := putEntry(ctx, g.index(leader), fmt.Sprintf("%02x", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replica := g.followers(leader)[0]
+	eventually(t, func() error { return g.hasEntry(replica, "13") })
+	if err := g.replicas[replica].node.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	if err := putEntry(ctx, g.index(leader), "ff"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The replica's index is removed on restart and rebuilt from the snapshot
+	// and the log written after it
+	g.stop(replica)
+	g.start(replica)
+	for _, hash := range []string{"00", "13", "ff"} {
+		eventually(t, func() error { return g.hasEntry(replica, hash) })
+	}
+	if matches, _ := filepath.Glob(filepath.Join(g.dirs[replica], "snapshots", "*")); len(matches) == 0 {
+		t.Error("Expected the snapshot to be kept on disk")
+	}
+}
+
+func TestIndexRestoresMigrationFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	g := newIndexGroup(t)
+	r := &blockindexpb.MigrationRequest{Start: "50", End: "80"}
+
+	leader := g.waitLeader()
+	if resp, err := g.index(leader).BeginMigration(ctx, r); err != nil || !resp.Success {
+		t.Fatalf("Failed to begin migration: %v %s", err, resp.GetError())
+	}
+	if err := putEntry(ctx, g.index(leader), "60"); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := g.index(leader).FreezeRange(ctx, r); err != nil || resp.Error != "" {
+		t.Fatalf("Failed to freeze range: %v %s", err, resp.GetError())
+	}
+
+	replica := g.followers(leader)[0]
+	eventually(t, func() error {
+		if !g.replicas[replica].store.(blockindex.Index).Frozen("60") {
+			return fmt.Errorf("replica %d has not frozen the range", replica)
+		}
+		return nil
+	})
+	if err := g.replicas[replica].node.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+
+	// The replica rebuilt from the snapshot, with no log after it to replay,
+	// still tracks the migration
+	g.stop(replica)
+	g.start(replica)
+	index := g.replicas[replica].store.(blockindex.Index)
+	if !index.Frozen("60") {
+		t.Error("Restored replica should keep the range frozen")
+	}
+	changes, err := index.TakeChanges(shards.Range{Start: r.Start, End: r.End})
+	if err != nil || len(changes) != 1 || changes[0] != "60" {
+		t.Errorf("Expected the restored replica to hold change 60, got %v %v", changes, err)
+	}
+}
+
+func TestIndexAppliesLeaderTimestamps(t *testing.T) {
+	ctx := context.Background()
+	stamped := time.Unix(1000, 0)
+	g := newStampedIndexGroup(t, func(req proto.Message, _ time.Time) {
+		blockindex.StampRequest(req, stamped)
+	})
+
+	leader := g.waitLeader()
+	replica := g.followers(leader)[0]
+	g.stop(replica)
+	if err := putEntry(ctx, g.index(g.followers(leader)[0]), "aa"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every replica records the leader's time, including one that applies the
+	// write later while catching up
+	g.start(replica)
+	for i := range g.replicas {
+		eventually(t, func() error { return g.hasEntry(i, "aa") })
+		entry, err := g.replicas[i].store.(blockindex.Index).GetEntry("aa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !entry.CreatedAt.Equal(stamped) {
+			t.Errorf("Replica %d created the entry at %v, expected %v", i, entry.CreatedAt, stamped)
+		}
+	}
+}
+
+func TestTableFailover(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGroup(t, 3, func(path string) (testStore, Service, func(*grpc.Server)) {
+		table, err := replication.NewTable(path)
+		if err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+		svc := replication.NewReplicationTableService(table)
+		return table, Service{Desc: &replicationpb.ReplicationTableService_ServiceDesc, Impl: svc, Reads: replication.ReadMethods, Stamp: replication.StampRequest}, func(s *grpc.Server) {
+			replicationpb.RegisterReplicationTableServiceServer(s, svc)
+		}
+	})
+	client := func(i int) replicationpb.ReplicationTableServiceClient {
+		return replicationpb.NewReplicationTableServiceClient(g.conns[i])
+	}
+
+	leader := g.waitLeader()
+	created, err := client(g.followers(leader)[0]).CreateVolume(ctx, &replicationpb.CreateVolumeRequest{VolumeId: "v1", OsdAddresses: []string{"osd1", "osd2"}, CellId: "cell1"})
+	if err != nil || !created.Success {
+		t.Fatalf("Failed to create volume: %v %s", err, created.GetError())
+	}
+
+	g.stop(leader)
+	newLeader := g.waitLeader()
+	for _, i := range g.followers(leader) {
+		volume, err := client(i).GetVolume(ctx, &replicationpb.GetVolumeRequest{VolumeId: "v1"})
+		if err != nil {
+			t.Fatalf("Failed to get volume from replica %d: %v", i, err)
+		}
+		if !volume.Found || len(volume.OsdAddresses) != 2 {
+			t.Errorf("Replica %d: expected volume v1 on 2 OSDs after failover, got %+v", i, volume)
+		}
+	}
+
+	// A duplicate create fails the same way on every replica
+	dup, err := client(newLeader).CreateVolume(ctx, &replicationpb.CreateVolumeRequest{VolumeId: "v1", OsdAddresses: []string{"osd3"}, CellId: "cell1"})
+	if err != nil {
+		t.Fatalf("Failed to create volume: %v", err)
+	}
+	if dup.Success {
+		t.Error("Creating an existing volume should fail")
+	}
+}
@@ -0,0 +1,99 @@
+package consensus
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/config"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+)
+
+const (
+	// transportPool is the number of connections kept open to each peer
+	transportPool = 3
+
+	// transportTimeout bounds each write to a peer
+	transportTimeout = 10 * time.Second
+)
+
+// newTransport creates the Raft transport listening on addr. With TLS
+// enabled, peers must present certificates with the peer role.
+func newTransport(addr string, tlsConfig config.TLSConfig, logger hclog.Logger) (raft.Transport, io.Closer, error) {
+	if !tlsConfig.Enabled() {
+		transport, err := raft.NewTCPTransportWithLogger(addr, nil, transportPool, transportTimeout, logger)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start raft transport: %w", err)
+		}
+		return transport, transport, nil
+	}
+
+	serverTLS, err := tlsConfig.ServerTLS()
+	if err != nil {
+		return nil, nil, err
+	}
+	clientTLS, err := tlsConfig.ClientTLS()
+	if err != nil {
+		return nil, nil, err
+	}
+	serverTLS.VerifyConnection = requirePeer
+	clientTLS.VerifyConnection = requirePeer
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start raft transport: %w", err)
+	}
+	layer := &tlsStreamLayer{
+		Listener: tls.NewListener(lis, serverTLS),
+		addr:     lis.Addr(),
+		config:   clientTLS,
+	}
+	transport := raft.NewNetworkTransportWithLogger(layer, transportPool, transportTimeout, logger)
+	return transport, transport, nil
+}
+
+// requirePeer rejects connections from certificates without the peer role
+func requirePeer(state tls.ConnectionState) error {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return errors.New("no verified peer certificate")
+	}
+	role, err := auth.RoleFromCert(state.VerifiedChains[0][0])
+	if err != nil {
+		return err
+	}
+	if role != auth.RolePeer {
+		return fmt.Errorf("role %s may not join a raft group", role)
+	}
+	return nil
+}
+
+// tlsStreamLayer carries Raft traffic over mutual TLS
+type tlsStreamLayer struct {
+	net.Listener
+	addr   net.Addr
+	config *tls.Config
+}
+
+// Dial connects to a peer
+func (l *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(string(address))
+	if err != nil {
+		return nil, err
+	}
+	config := l.config.Clone()
+	config.ServerName = host
+
+	dialer := &net.Dialer{Timeout: timeout}
+	return tls.DialWithDialer(dialer, "tcp", string(address), config)
+}
+
+// Addr returns the address peers reach this node on
+func (l *tlsStreamLayer) Addr() net.Addr {
+	return l.addr
+}
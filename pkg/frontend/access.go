@@ -0,0 +1,61 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bharani/pkg/auth"
+	"bharani/proto/blockindex"
+)
+
+// ErrAccessDenied is returned when the caller's tenant holds no reference to
+// a block it asks for. Missing blocks are denied the same way, so tenants
+// cannot probe for hashes stored by others.
+var ErrAccessDenied = errors.New("access denied")
+
+// tenantOf returns the tenant a request acts for, empty when the request was
+// not authenticated
+func tenantOf(ctx context.Context) string {
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		return id.Tenant
+	}
+	return ""
+}
+
+// readable returns which of the given blocks the caller may read: those its
+// tenant references. It returns nil, allowing everything, for admins and for
+// requests without an identity, which come from trusted in-process callers or
+// a frontend serving without authentication.
+func (f *Frontend) readable(ctx context.Context, hashes []string) (map[string]bool, error) {
+	id, ok := auth.IdentityFromContext(ctx)
+	if !ok || id.Admin {
+		return nil, nil
+	}
+
+	resp, err := f.blockIndexClient.Referenced(ctx, &blockindex.ReferencedRequest{Hashes: hashes, Tenant: id.Tenant})
+	if err == nil && resp.Error != "" {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access: %w", err)
+	}
+
+	allowed := make(map[string]bool, len(resp.Referenced))
+	for _, hash := range resp.Referenced {
+		allowed[hash] = true
+	}
+	return allowed, nil
+}
+
+// authorizeRead returns ErrAccessDenied unless the caller may read the block
+func (f *Frontend) authorizeRead(ctx context.Context, hash string) error {
+	allowed, err := f.readable(ctx, []string{hash})
+	if err != nil {
+		return err
+	}
+	if allowed != nil && !allowed[hash] {
+		return fmt.Errorf("%w: %s", ErrAccessDenied, hash)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/storage"
+	frontendpb "bharani/proto/frontend"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTenantAccess(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	f := cluster.frontend
+
+	red := auth.WithIdentity(context.Background(), &auth.Identity{Tenant: "red"})
+	blue := auth.WithIdentity(context.Background(), &auth.Identity{Tenant: "blue"})
+	admin := auth.WithIdentity(context.Background(), &auth.Identity{Tenant: "ops", Admin: true})
+
+	data := []byte("red team secret")
+	hash, err := f.Put(red, data, "doc")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	missing := storage.ComputeHash([]byte("never stored"))
+
+	if got, err := f.Get(red, hash); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Owner tenant failed to read its block: %v", err)
+	}
+	if _, err := f.Get(blue, hash); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied for another tenant, got %v", err)
+	}
+	if _, err := f.Get(blue, missing); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Missing blocks should be denied like unowned ones, got %v", err)
+	}
+	if _, err := f.Stat(blue, hash, false); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected Stat to be denied, got %v", err)
+	}
+	if got, err := f.Get(admin, hash); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Admin failed to read block: %v", err)
+	}
+	if _, err := f.Get(context.Background(), hash); err != nil {
+		t.Errorf("Unauthenticated in-process read failed: %v", err)
+	}
+
+	// Knowing the hash is not enough to claim the block through Exists, and a
+	// tenant cannot release another tenant's reference
+	if existing, err := f.Exists(blue, []string{hash}, "doc"); err != nil || len(existing) != 0 {
+		t.Errorf("Expected Exists to hide another tenant's block, got %v %v", existing, err)
+	}
+	if released, _ := f.Release(blue, hash, "doc"); released {
+		t.Error("Released another tenant's reference")
+	}
+	if _, err := f.Get(blue, hash); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Exists must not grant access, got %v", err)
+	}
+
+	// Uploading the data proves possession: the write is deduplicated and the
+	// tenant gains its own reference
+	if _, err := f.Put(blue, data, "doc"); err != nil {
+		t.Fatalf("Failed to put block as second tenant: %v", err)
+	}
+	results := f.GetBatch(blue, []string{hash, missing})
+	if results[0].Err != nil || !errors.Is(results[1].Err, ErrAccessDenied) {
+		t.Errorf("Unexpected batch results: %v, %v", results[0].Err, results[1].Err)
+	}
+	if existing, err := f.Exists(blue, []string{hash}, "copy"); err != nil || len(existing) != 1 {
+		t.Errorf("Expected Exists to report the tenant's own block, got %v %v", existing, err)
+	}
+
+	// Over gRPC, credentials are required and denials map to PermissionDenied
+	authenticator := auth.NewAuthenticator(map[string]auth.Identity{
+		"red-key":  {Tenant: "red"},
+		"blue-key": {Tenant: "blue"},
+		"ops-key":  {Tenant: "ops", Admin: true},
+	}, nil)
+	addr, _ := serve(t, nil, func(s *grpc.Server) {
+		frontendpb.RegisterFrontendServiceServer(s, NewFrontendService(f))
+	}, authenticator.ServerOptions()...)
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := frontendpb.NewFrontendServiceClient(conn)
+
+	put, err := client.Put(metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, "red-key"),
+		&frontendpb.PutRequest{Data: []byte("red only"), Owner: "doc"})
+	if err != nil || !put.Success {
+		t.Fatalf("Failed to put over gRPC: %v %v", put, err)
+	}
+
+	tests := []struct {
+		key  string
+		code codes.Code
+	}{
+		{"", codes.Unauthenticated},
+		{"stolen-key", codes.Unauthenticated},
+		{"blue-key", codes.PermissionDenied},
+		{"red-key", codes.OK},
+		{"ops-key", codes.OK},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.key != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, auth.APIKeyHeader, tt.key)
+		}
+		_, err := client.Get(ctx, &frontendpb.GetRequest{Hash: put.Hash})
+		if status.Code(err) != tt.code {
+			t.Errorf("Key %q: expected %v, got %v", tt.key, tt.code, err)
+		}
+	}
+}
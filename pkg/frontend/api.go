@@ -2,8 +2,14 @@ package frontend
 
 import (
 	"context"
+	"errors"
+	"io"
 
 	"bharani/proto/frontend"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // FrontendService implements the gRPC Frontend service
@@ -21,7 +27,20 @@ func NewFrontendService(frontendInstance *Frontend) *FrontendService {
 
 // Put handles Put requests
 func (s *FrontendService) Put(ctx context.Context, req *frontend.PutRequest) (*frontend.PutResponse, error) {
-	hash, err := s.frontend.Put(ctx, req.Data)
+	if err := s.frontend.limitRequest(ctx); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	hash, err := s.frontend.PutExpected(ctx, req.Data, req.ExpectedHash, req.Owner)
+	if exhausted(err) {
+		return nil, exhaustedError(err)
+	}
+	if errors.Is(err, ErrBlockDeleting) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if errors.Is(err, ErrHashMismatch) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		return &frontend.PutResponse{
 			Success: false,
@@ -37,7 +56,20 @@ func (s *FrontendService) Put(ctx context.Context, req *frontend.PutRequest) (*f
 
 // Get handles Get requests
 func (s *FrontendService) Get(ctx context.Context, req *frontend.GetRequest) (*frontend.GetResponse, error) {
+	if err := s.frontend.limitRequest(ctx); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
 	data, err := s.frontend.Get(ctx, req.Hash)
+	if exhausted(err) {
+		return nil, exhaustedError(err)
+	}
+	if errors.Is(err, ErrDataLoss) {
+		return nil, status.Error(codes.DataLoss, err.Error())
+	}
+	if errors.Is(err, ErrAccessDenied) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
 	if err != nil {
 		return &frontend.GetResponse{
 			Success: false,
@@ -51,4 +83,339 @@ func (s *FrontendService) Get(ctx context.Context, req *frontend.GetRequest) (*f
 	}, nil
 }
 
+// PutFile handles streamed file uploads. The owner is taken from the first message.
+func (s *FrontendService) PutFile(stream frontend.FrontendService_PutFileServer) error {
+	if err := s.frontend.limitRequest(stream.Context()); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	reader := &putFileReader{stream: stream}
+	first, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if first != nil {
+		reader.pending = first.Data
+	}
+
+	info, err := s.frontend.PutFile(stream.Context(), reader, first.GetOwner())
+	if errors.Is(err, ErrBlockDeleting) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if exhausted(err) {
+		return exhaustedError(err)
+	}
+	if err != nil {
+		return stream.SendAndClose(&frontend.PutFileResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return stream.SendAndClose(&frontend.PutFileResponse{
+		Success:    true,
+		Hash:       info.Hash,
+		Size:       info.Size,
+		BlockCount: int32(info.BlockCount),
+	})
+}
+
+// GetFile handles streamed file downloads
+func (s *FrontendService) GetFile(req *frontend.GetFileRequest, stream frontend.FrontendService_GetFileServer) error {
+	if err := s.frontend.limitRequest(stream.Context()); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	err := s.frontend.GetFile(stream.Context(), req.Hash, &getFileWriter{stream: stream})
+	if errors.Is(err, ErrDataLoss) {
+		return status.Error(codes.DataLoss, err.Error())
+	}
+	if errors.Is(err, ErrAccessDenied) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if exhausted(err) {
+		return exhaustedError(err)
+	}
+	return err
+}
+
+// PutBatch handles PutBatch requests. A batch refused for the tenant's quota
+// or bandwidth fails as a whole.
+func (s *FrontendService) PutBatch(ctx context.Context, req *frontend.PutBatchRequest) (*frontend.PutBatchResponse, error) {
+	if err := s.frontend.limitRequest(ctx); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	results := s.frontend.PutBatch(ctx, req.Blocks, req.Owner)
+	for _, result := range results {
+		if exhausted(result.Err) {
+			return nil, exhaustedError(result.Err)
+		}
+	}
+
+	resp := &frontend.PutBatchResponse{
+		Results: make([]*frontend.PutResponse, len(results)),
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			resp.Results[i] = &frontend.PutResponse{
+				Success: false,
+				Hash:    result.Hash,
+				Error:   result.Err.Error(),
+			}
+			continue
+		}
+		resp.Results[i] = &frontend.PutResponse{
+			Success: true,
+			Hash:    result.Hash,
+		}
+	}
+
+	return resp, nil
+}
+
+// GetBatch handles GetBatch requests. A batch refused for the tenant's
+// bandwidth fails as a whole.
+func (s *FrontendService) GetBatch(ctx context.Context, req *frontend.GetBatchRequest) (*frontend.GetBatchResponse, error) {
+	if err := s.frontend.limitRequest(ctx); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	results := s.frontend.GetBatch(ctx, req.Hashes)
+	for _, result := range results {
+		if exhausted(result.Err) {
+			return nil, exhaustedError(result.Err)
+		}
+	}
+
+	resp := &frontend.GetBatchResponse{
+		Results: make([]*frontend.GetResponse, len(results)),
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			resp.Results[i] = &frontend.GetResponse{
+				Success: false,
+				Error:   result.Err.Error(),
+			}
+			continue
+		}
+		resp.Results[i] = &frontend.GetResponse{
+			Success: true,
+			Data:    result.Data,
+		}
+	}
+
+	return resp, nil
+}
+
+// Stat handles Stat requests
+func (s *FrontendService) Stat(ctx context.Context, req *frontend.StatRequest) (*frontend.StatResponse, error) {
+	if err := s.frontend.limitRequest(ctx); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	stat, err := s.frontend.Stat(ctx, req.Hash, req.Verify)
+	if exhausted(err) {
+		return nil, exhaustedError(err)
+	}
+	if errors.Is(err, ErrAccessDenied) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if errors.Is(err, ErrNotFound) {
+		return &frontend.StatResponse{
+			Found: false,
+		}, nil
+	}
+	if err != nil {
+		return &frontend.StatResponse{
+			Found: false,
+			Error: err.Error(),
+		}, nil
+	}
+
+	resp := &frontend.StatResponse{
+		Found:     true,
+		Size:      stat.Size,
+		CellId:    stat.CellID,
+		VolumeId:  stat.VolumeID,
+		BucketId:  stat.BucketID,
+		Replicas:  make([]*frontend.ReplicaStatus, 0, len(stat.Replicas)),
+		CreatedAt: stat.CreatedAt.Unix(),
+	}
+	if !stat.VerifiedAt.IsZero() {
+		resp.VerifiedAt = stat.VerifiedAt.Unix()
+	}
+	for _, replica := range stat.Replicas {
+		resp.Replicas = append(resp.Replicas, &frontend.ReplicaStatus{
+			Address: replica.Address,
+			ZoneId:  replica.ZoneID,
+			Healthy: replica.Healthy,
+			Check:   replica.Check,
+		})
+	}
+
+	return resp, nil
+}
+
+// Release handles Release requests
+func (s *FrontendService) Release(ctx context.Context, req *frontend.ReleaseRequest) (*frontend.ReleaseResponse, error) {
+	if err := s.frontend.limitRequest(ctx); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	var (
+		released int
+		err      error
+	)
+	if req.File {
+		released, err = s.frontend.ReleaseFile(ctx, req.Hash, req.Owner)
+	} else {
+		var ok bool
+		ok, err = s.frontend.Release(ctx, req.Hash, req.Owner)
+		if ok {
+			released = 1
+		}
+	}
+	if err != nil {
+		return &frontend.ReleaseResponse{
+			Success:  false,
+			Released: int32(released),
+			Error:    err.Error(),
+		}, nil
+	}
+
+	return &frontend.ReleaseResponse{
+		Success:  true,
+		Released: int32(released),
+	}, nil
+}
+
+// Exists handles Exists requests
+func (s *FrontendService) Exists(ctx context.Context, req *frontend.ExistsRequest) (*frontend.ExistsResponse, error) {
+	if err := s.frontend.limitRequest(ctx); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	existing, err := s.frontend.Exists(ctx, req.Hashes, req.Owner)
+	if err != nil {
+		return &frontend.ExistsResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	return &frontend.ExistsResponse{
+		Existing: existing,
+	}, nil
+}
+
+// GetUsage handles GetUsage requests. Usage requests are not rate limited, so
+// a limited tenant can still see why.
+func (s *FrontendService) GetUsage(ctx context.Context, req *frontend.GetUsageRequest) (*frontend.GetUsageResponse, error) {
+	usage, err := s.frontend.GetUsage(ctx, req.Tenant)
+	if errors.Is(err, ErrAccessDenied) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return &frontend.GetUsageResponse{
+			Error: err.Error(),
+		}, nil
+	}
 
+	return &frontend.GetUsageResponse{
+		Tenant:    usage.Tenant,
+		Bytes:     usage.Bytes,
+		Blocks:    usage.Blocks,
+		MaxBytes:  usage.MaxBytes,
+		MaxBlocks: usage.MaxBlocks,
+	}, nil
+}
+
+// GetCacheStats handles GetCacheStats requests. Like GetUsage, they are not
+// rate limited.
+func (s *FrontendService) GetCacheStats(ctx context.Context, req *frontend.GetCacheStatsRequest) (*frontend.GetCacheStatsResponse, error) {
+	stats, err := s.frontend.GetCacheStats(ctx)
+	if errors.Is(err, ErrAccessDenied) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return &frontend.GetCacheStatsResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	caches := make([]*frontend.CacheStats, 0, len(stats))
+	for _, cache := range stats {
+		caches = append(caches, &frontend.CacheStats{
+			Name:          cache.Name,
+			Size:          int64(cache.Size),
+			Capacity:      int64(cache.Capacity),
+			Hits:          cache.Hits,
+			Misses:        cache.Misses,
+			Evictions:     cache.Evictions,
+			Invalidations: cache.Invalidations,
+			HitRate:       cache.HitRate(),
+		})
+	}
+
+	return &frontend.GetCacheStatsResponse{
+		Caches: caches,
+	}, nil
+}
+
+// exhaustedError returns a ResourceExhausted status for a quota or rate limit
+// error. Quota errors carry a QuotaFailure detail so that clients can tell
+// them from rate limiting, which is worth retrying.
+func exhaustedError(err error) error {
+	st := status.New(codes.ResourceExhausted, err.Error())
+	if !errors.Is(err, ErrQuotaExceeded) {
+		return st.Err()
+	}
+
+	detailed, detailErr := st.WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: "tenant", Description: err.Error()}},
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// fileChunkSize bounds the payload of each GetFile response message
+const fileChunkSize = 1024 * 1024
+
+// putFileReader adapts a PutFile request stream to io.Reader
+type putFileReader struct {
+	stream  frontend.FrontendService_PutFileServer
+	pending []byte
+}
+
+func (r *putFileReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.pending = req.Data
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// getFileWriter adapts a GetFile response stream to io.Writer
+type getFileWriter struct {
+	stream frontend.FrontendService_GetFileServer
+}
+
+func (w *getFileWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		end := min(written+fileChunkSize, len(p))
+		if err := w.stream.Send(&frontend.GetFileResponse{Data: p[written:end]}); err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
@@ -0,0 +1,345 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"bharani/pkg/storage"
+	"bharani/proto/blockindex"
+	"bharani/proto/osd"
+)
+
+// batchWorkers bounds the number of concurrent block reads in GetBatch
+const batchWorkers = 16
+
+// BatchPutResult is the outcome of storing one block of a batch
+type BatchPutResult struct {
+	Hash string
+	Err  error
+}
+
+// BatchGetResult is the outcome of retrieving one block of a batch
+type BatchGetResult struct {
+	Data []byte
+	Err  error
+}
+
+// PutBatch stores many blocks at once, each referenced by owner as with Put.
+// Existing blocks are referenced with a single index call, new blocks are
+// written to one volume with each OSD receiving its blocks in parallel, and
+// every block gets its own result.
+func (f *Frontend) PutBatch(ctx context.Context, blocks [][]byte, owner string) []BatchPutResult {
+	results := make([]BatchPutResult, len(blocks))
+
+	pending := make(map[string]*storage.Block)
+	hashes := make([]string, 0, len(blocks))
+	for i, data := range blocks {
+		block, err := storage.NewBlock(data)
+		if err != nil {
+			results[i].Err = fmt.Errorf("failed to create block: %w", err)
+			continue
+		}
+		results[i].Hash = block.Hash
+		if _, seen := pending[block.Hash]; !seen {
+			pending[block.Hash] = block
+			hashes = append(hashes, block.Hash)
+		}
+	}
+
+	if len(hashes) == 0 {
+		return results
+	}
+
+	var size int64
+	for _, block := range pending {
+		size += block.Size()
+	}
+	err := f.limitBandwidth(ctx, size)
+	if err == nil {
+		err = f.checkQuota(ctx, size, int64(len(pending)))
+	}
+	if err != nil {
+		return failResults(results, err)
+	}
+
+	refsResp, err := f.blockIndexClient.AddRefs(ctx, &blockindex.AddRefsRequest{Hashes: hashes, Owner: owner, Tenant: tenantOf(ctx)})
+	if err == nil && refsResp.Error != "" {
+		err = fmt.Errorf("%s", refsResp.Error)
+	}
+	if err != nil {
+		return failResults(results, fmt.Errorf("failed to reference blocks: %w", err))
+	}
+
+	failed := make(map[string]error)
+	for _, hash := range refsResp.Found {
+		delete(pending, hash)
+	}
+	for _, hash := range refsResp.Deleting {
+		delete(pending, hash)
+		failed[hash] = fmt.Errorf("%w: %s", ErrBlockDeleting, hash)
+	}
+
+	for _, group := range bucketGroups(pending, f.config.BucketSize) {
+		maps.Copy(failed, f.putBlocks(ctx, group, owner))
+	}
+
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		if err, ok := failed[results[i].Hash]; ok {
+			results[i].Err = err
+		}
+	}
+
+	return results
+}
+
+// failResults sets err on every result that has not already failed
+func failResults(results []BatchPutResult, err error) []BatchPutResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = err
+		}
+	}
+	return results
+}
+
+// bucketGroups splits blocks into groups that each fit in one bucket
+func bucketGroups(blocks map[string]*storage.Block, bucketSize int64) []map[string]*storage.Block {
+	groups := make([]map[string]*storage.Block, 0)
+	var current map[string]*storage.Block
+	var size int64
+	for hash, block := range blocks {
+		if current == nil || size+block.Size() > bucketSize {
+			current = make(map[string]*storage.Block)
+			groups = append(groups, current)
+			size = 0
+		}
+		current[hash] = block
+		size += block.Size()
+	}
+	return groups
+}
+
+// putBlocks writes a group of new blocks to a single bucket and indexes the
+// ones that reached the write quorum, returning errors keyed by hash
+func (f *Frontend) putBlocks(ctx context.Context, blocks map[string]*storage.Block, owner string) map[string]error {
+	failed := make(map[string]error)
+
+	var size int64
+	for _, block := range blocks {
+		size += block.Size()
+	}
+
+	volume, bucketID, err := f.reserveSpace(ctx, size)
+	if err == nil && len(volume.OsdAddresses) == 0 {
+		err = fmt.Errorf("volume %s has no replicas", volume.VolumeId)
+	}
+	if err != nil {
+		for hash := range blocks {
+			failed[hash] = err
+		}
+		return failed
+	}
+
+	hashes := make([]string, 0, len(blocks))
+	intents := make([]*blockindex.Intent, 0, len(blocks))
+	for hash, block := range blocks {
+		hashes = append(hashes, hash)
+		intents = append(intents, &blockindex.Intent{
+			Hash:     hash,
+			CellId:   f.config.CellID,
+			BucketId: bucketID,
+			VolumeId: volume.VolumeId,
+			Size:     block.Size(),
+			Tenant:   tenantOf(ctx),
+			Owner:    owner,
+		})
+	}
+	ids, err := f.beginPut(ctx, intents)
+	if err != nil {
+		for hash := range blocks {
+			failed[hash] = err
+		}
+		return failed
+	}
+	intentIDs := make(map[string]int64, len(hashes))
+	for i, hash := range hashes {
+		intentIDs[hash] = ids[i]
+	}
+
+	var mu sync.Mutex
+	acked := make(map[string][]string)
+
+	var wg sync.WaitGroup
+	for _, osdAddr := range volume.OsdAddresses {
+		wg.Add(1)
+		go func(osdAddr string) {
+			defer wg.Done()
+
+			for hash, block := range blocks {
+				putReq := &osd.PutBlockRequest{
+					Hash:     hash,
+					Data:     block.Data,
+					BucketId: bucketID,
+					VolumeId: volume.VolumeId,
+				}
+
+				if err := f.writeReplica(ctx, osdAddr, putReq); err == nil {
+					mu.Lock()
+					acked[hash] = append(acked[hash], osdAddr)
+					mu.Unlock()
+				}
+			}
+		}(osdAddr)
+	}
+	wg.Wait()
+
+	quorum := f.writeQuorum(len(volume.OsdAddresses))
+	for hash, block := range blocks {
+		if len(acked[hash]) < quorum {
+			failed[hash] = fmt.Errorf("failed to replicate block: only %d/%d writes succeeded, need %d",
+				len(acked[hash]), len(volume.OsdAddresses), quorum)
+			// As in PutExpected, the sweeper cleans up after cancelled writes
+			if ctx.Err() == nil {
+				f.discardPut(intentIDs[hash], &osd.PutBlockRequest{
+					Hash:     hash,
+					BucketId: bucketID,
+					VolumeId: volume.VolumeId,
+				}, volume.OsdAddresses)
+			}
+			continue
+		}
+
+		indexed, err := f.commitPut(ctx, intentIDs[hash], block.Hash)
+		if err != nil {
+			failed[hash] = err
+			continue
+		}
+		if missing := missingReplicas(volume.OsdAddresses, acked[hash]); indexed && len(missing) > 0 {
+			go f.reportUnderReplicated(hash, volume.VolumeId, bucketID, missing)
+		}
+	}
+
+	return failed
+}
+
+// missingReplicas returns the replicas that are not in acked
+func missingReplicas(replicas, acked []string) []string {
+	missing := make([]string, 0)
+	for _, osdAddr := range replicas {
+		if !slices.Contains(acked, osdAddr) {
+			missing = append(missing, osdAddr)
+		}
+	}
+	return missing
+}
+
+// GetBatch retrieves many blocks at once, resolving their index entries in a
+// single query and reading blocks in parallel. Blocks the caller's tenant does
+// not reference fail with ErrAccessDenied, as with Get.
+func (f *Frontend) GetBatch(ctx context.Context, hashes []string) []BatchGetResult {
+	results := make([]BatchGetResult, len(hashes))
+
+	allowed, err := f.readable(ctx, hashes)
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	entries, err := f.lookupEntries(ctx, hashes)
+	if err != nil {
+		for i := range results {
+			results[i].Err = fmt.Errorf("failed to lookup block: %w", err)
+		}
+		return results
+	}
+
+	var size int64
+	for hash, entry := range entries {
+		if allowed == nil || allowed[hash] {
+			size += entry.Size
+		}
+	}
+	if err := f.limitBandwidth(ctx, size); err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	lookup := f.newVolumeLookup()
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(batchWorkers, len(hashes)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if allowed != nil && !allowed[hashes[i]] {
+					results[i].Err = fmt.Errorf("%w: %s", ErrAccessDenied, hashes[i])
+					continue
+				}
+				entry, ok := entries[hashes[i]]
+				if !ok {
+					results[i].Err = fmt.Errorf("%w: %s", ErrNotFound, hashes[i])
+					continue
+				}
+
+				data, err := f.readEntry(ctx, entry, lookup)
+				if err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].Data = data
+			}
+		}()
+	}
+
+	for i := range hashes {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+// lookupEntries returns the index entries of the given blocks, taking them
+// from the location cache where possible and querying the index for the rest
+// in one call. Unindexed blocks are left out.
+func (f *Frontend) lookupEntries(ctx context.Context, hashes []string) (map[string]*blockindex.Entry, error) {
+	entries := make(map[string]*blockindex.Entry, len(hashes))
+	misses := make([]string, 0)
+	for _, hash := range hashes {
+		if entry, ok := f.locations.get(hash); ok {
+			entries[hash] = entry
+		} else {
+			misses = append(misses, hash)
+		}
+	}
+	if len(misses) == 0 {
+		return entries, nil
+	}
+
+	entriesResp, err := f.blockIndexClient.GetEntries(ctx, &blockindex.GetEntriesRequest{Hashes: misses})
+	if err != nil {
+		return nil, err
+	}
+	if entriesResp.Error != "" {
+		return nil, fmt.Errorf("%s", entriesResp.Error)
+	}
+
+	for _, entry := range entriesResp.Entries {
+		entries[entry.Hash] = entry
+		f.locations.put(entry.Hash, entry)
+	}
+	return entries, nil
+}
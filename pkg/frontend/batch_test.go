@@ -0,0 +1,64 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPutBatchGetBatch(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	existing, err := cluster.frontend.Put(ctx, []byte("block 0"), "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+
+	blocks := make([][]byte, 0)
+	for i := 0; i < 10; i++ {
+		blocks = append(blocks, []byte(fmt.Sprintf("block %d", i)))
+	}
+	blocks = append(blocks, nil, []byte("block 3"))
+
+	results := cluster.frontend.PutBatch(ctx, blocks, "")
+	if len(results) != len(blocks) {
+		t.Fatalf("Expected %d results, got %d", len(blocks), len(results))
+	}
+	if results[0].Err != nil || results[0].Hash != existing {
+		t.Errorf("Existing block should dedup: %+v", results[0])
+	}
+	if results[10].Err == nil {
+		t.Error("Empty block should fail without failing the batch")
+	}
+
+	hashes := make([]string, 0)
+	for i, result := range results {
+		if i == 10 {
+			continue
+		}
+		if result.Err != nil {
+			t.Fatalf("Block %d failed: %v", i, result.Err)
+		}
+		hashes = append(hashes, result.Hash)
+	}
+	hashes = append(hashes, "missing")
+
+	got := cluster.frontend.GetBatch(ctx, hashes)
+	for i, result := range got[:len(got)-1] {
+		if result.Err != nil {
+			t.Fatalf("Get %d failed: %v", i, result.Err)
+		}
+		want := blocks[i]
+		if i == 10 {
+			want = blocks[11]
+		}
+		if !bytes.Equal(result.Data, want) {
+			t.Errorf("Get %d returned %q, want %q", i, result.Data, want)
+		}
+	}
+	if got[len(got)-1].Err == nil {
+		t.Error("Missing hash should return an error")
+	}
+}
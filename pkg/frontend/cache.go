@@ -0,0 +1,144 @@
+package frontend
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"bharani/pkg/auth"
+)
+
+// CacheStats reports the effectiveness of one of the frontend's caches
+type CacheStats struct {
+	Name          string
+	Size          int
+	Capacity      int
+	Hits          int64
+	Misses        int64
+	Evictions     int64 // Entries dropped to make room
+	Invalidations int64 // Entries dropped because they were found to be stale
+}
+
+// HitRate returns the fraction of lookups answered from the cache
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// lruCache is a fixed-capacity cache that evicts the least recently used
+// entry. A capacity of zero disables it.
+type lruCache[K comparable, V any] struct {
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List // Front is most recently used
+	stats    CacheStats
+	mu       sync.Mutex
+}
+
+// lruItem is a key and value held in the cache's recency list
+type lruItem[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRUCache creates an empty cache reporting its stats under name
+func newLRUCache[K comparable, V any](name string, capacity int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		capacity: max(capacity, 0),
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+		stats:    CacheStats{Name: name, Capacity: max(capacity, 0)},
+	}
+}
+
+// get returns the value cached under key and marks it recently used
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.stats.Hits++
+		c.order.MoveToFront(elem)
+		return elem.Value.(*lruItem[K, V]).value, true
+	}
+
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// peek returns the value cached under key without counting a lookup or
+// marking it used
+func (c *lruCache[K, V]) peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		return elem.Value.(*lruItem[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// put caches value under key, evicting the least recently used entry if the
+// cache is full
+func (c *lruCache[K, V]) put(key K, value V) {
+	if c.capacity == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruItem[K, V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruItem[K, V]{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem[K, V]).key)
+		c.stats.Evictions++
+	}
+}
+
+// invalidate drops a stale entry, reporting whether one was cached
+func (c *lruCache[K, V]) invalidate(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.order.Remove(elem)
+	delete(c.items, key)
+	c.stats.Invalidations++
+	return true
+}
+
+// snapshot returns the cache's current stats
+func (c *lruCache[K, V]) snapshot() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// GetCacheStats reports the frontend's location and volume caches. The
+// stats cover every tenant's reads, so only admins may see them.
+func (f *Frontend) GetCacheStats(ctx context.Context) ([]CacheStats, error) {
+	if id, ok := auth.IdentityFromContext(ctx); ok && !id.Admin {
+		return nil, fmt.Errorf("%w: cache stats", ErrAccessDenied)
+	}
+
+	return []CacheStats{f.locations.snapshot(), f.volumes.snapshot()}, nil
+}
@@ -0,0 +1,281 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	blockindexpb "bharani/proto/blockindex"
+	replicationpb "bharani/proto/replication"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache[string, int]("test", 2)
+
+	cache.put("a", 1)
+	cache.put("b", 2)
+	if v, ok := cache.get("a"); !ok || v != 1 {
+		t.Fatalf("get(a) = %d, %v, want 1, true", v, ok)
+	}
+
+	// b is now the least recently used
+	cache.put("c", 3)
+	if _, ok := cache.get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("a should still be cached")
+	}
+
+	if !cache.invalidate("c") {
+		t.Error("invalidate(c) should report a cached entry")
+	}
+	if cache.invalidate("c") {
+		t.Error("invalidate(c) should report nothing the second time")
+	}
+
+	stats := cache.snapshot()
+	want := CacheStats{Name: "test", Size: 1, Capacity: 2, Hits: 2, Misses: 1, Evictions: 1, Invalidations: 1}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+	if rate := stats.HitRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("HitRate() = %v, want 2/3", rate)
+	}
+
+	disabled := newLRUCache[string, int]("disabled", 0)
+	disabled.put("a", 1)
+	if _, ok := disabled.get("a"); ok {
+		t.Error("A cache of capacity 0 should hold nothing")
+	}
+}
+
+// cacheStats returns the stats of the frontend cache called name
+func (c *testCluster) cacheStats(name string) CacheStats {
+	c.t.Helper()
+
+	stats, err := c.frontend.GetCacheStats(context.Background())
+	if err != nil {
+		c.t.Fatalf("Failed to get cache stats: %v", err)
+	}
+	for _, cache := range stats {
+		if cache.Name == name {
+			return cache
+		}
+	}
+	c.t.Fatalf("No cache called %s", name)
+	return CacheStats{}
+}
+
+func TestCachedLocationsFollowMovedBlocks(t *testing.T) {
+	cluster := newTestCluster(t, 9)
+	ctx := context.Background()
+	f := cluster.frontend
+
+	data := []byte("cached location")
+	hash, err := f.Put(ctx, data, "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := f.Get(ctx, hash)
+		if err != nil {
+			t.Fatalf("Failed to get block: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Get returned %q, want %q", got, data)
+		}
+	}
+	if stats := cluster.cacheStats("locations"); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Location cache should answer the second Get, got %+v", stats)
+	}
+
+	entry, err := f.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+	if err != nil || !entry.Found {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	volume, err := f.replicationClient.GetVolume(ctx, &replicationpb.GetVolumeRequest{VolumeId: entry.VolumeId})
+	if err != nil || !volume.Found {
+		t.Fatalf("Failed to get volume: %v", err)
+	}
+
+	others := make([]string, 0)
+	for _, addr := range cluster.osdAddrs() {
+		if !slices.Contains(volume.OsdAddresses, addr) {
+			others = append(others, addr)
+		}
+	}
+
+	// Move the volume to new replicas, as re-replication does, and take the
+	// old ones down. The cached replica set is now stale.
+	moved := others[:3]
+	for _, addr := range moved {
+		if err := cluster.osdInstances[addr].PutBlock(ctx, hash, entry.BucketId, entry.VolumeId, data); err != nil {
+			t.Fatalf("Failed to copy block: %v", err)
+		}
+	}
+	resp, err := f.replicationClient.UpdateVolume(ctx, &replicationpb.UpdateVolumeRequest{
+		VolumeId:     entry.VolumeId,
+		OsdAddresses: moved,
+		Generation:   volume.Generation + 1,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("Failed to update volume: %v %v", resp, err)
+	}
+	for _, addr := range volume.OsdAddresses {
+		cluster.stopOSD(addr)
+	}
+
+	got, err := f.Get(ctx, hash)
+	if err != nil {
+		t.Fatalf("Failed to get block after its volume moved: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Get returned %q, want %q", got, data)
+	}
+	if stats := cluster.cacheStats("volumes"); stats.Invalidations != 1 {
+		t.Errorf("Stale replica set should be invalidated once, got %+v", stats)
+	}
+	cached, ok := f.volumes.peek(entry.VolumeId)
+	if !ok || cached.Generation != volume.Generation+1 {
+		t.Errorf("Volume cache should hold generation %d, got %v", volume.Generation+1, cached)
+	}
+
+	// Relocate the block to a volume of its own, as compaction does, and
+	// delete it from the old one. The cached location is now stale.
+	relocated := others[3:6]
+	createResp, err := f.replicationClient.CreateVolume(ctx, &replicationpb.CreateVolumeRequest{
+		VolumeId:     "relocated",
+		OsdAddresses: relocated,
+		CellId:       cluster.config.CellID,
+	})
+	if err != nil || !createResp.Success {
+		t.Fatalf("Failed to create volume: %v %v", createResp, err)
+	}
+	for _, addr := range relocated {
+		if err := cluster.osdInstances[addr].PutBlock(ctx, hash, entry.BucketId, "relocated", data); err != nil {
+			t.Fatalf("Failed to copy block: %v", err)
+		}
+	}
+	updateResp, err := f.blockIndexClient.UpdateEntry(ctx, &blockindexpb.UpdateEntryRequest{
+		Hash:             hash,
+		CellId:           entry.CellId,
+		BucketId:         entry.BucketId,
+		Checksum:         entry.Checksum,
+		VolumeId:         "relocated",
+		ExpectedCellId:   entry.CellId,
+		ExpectedBucketId: entry.BucketId,
+		ExpectedVolumeId: entry.VolumeId,
+	})
+	if err != nil || !updateResp.Success {
+		t.Fatalf("Failed to relocate entry: %v %v", updateResp, err)
+	}
+	for _, addr := range moved {
+		if err := cluster.osdInstances[addr].DeleteBlock(ctx, hash, entry.BucketId, entry.VolumeId); err != nil {
+			t.Fatalf("Failed to delete block: %v", err)
+		}
+	}
+
+	got, err = f.Get(ctx, hash)
+	if err != nil {
+		t.Fatalf("Failed to get relocated block: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Get returned %q, want %q", got, data)
+	}
+	if stats := cluster.cacheStats("locations"); stats.Invalidations != 1 {
+		t.Errorf("Stale location should be invalidated once, got %+v", stats)
+	}
+	if cached, ok := f.locations.peek(hash); !ok || cached.VolumeId != "relocated" {
+		t.Errorf("Location cache should point at the new volume, got %v", cached)
+	}
+}
+
+func TestFailedReadsKeepCachedLocation(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+	f := cluster.frontend
+
+	data := []byte("failed read")
+	hash, err := f.Put(ctx, data, "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	if _, err := f.Get(ctx, hash); err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+	entry, err := f.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+	if err != nil || !entry.Found {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+
+	// Corrupt replicas still hold the block, so its location is not stale
+	for _, addr := range cluster.osdAddrs() {
+		if err := cluster.osdInstances[addr].PutBlock(ctx, hash, entry.BucketId, entry.VolumeId, []byte("bit rot")); err != nil {
+			t.Fatalf("Failed to corrupt replica on %s: %v", addr, err)
+		}
+	}
+	if _, err := f.Get(ctx, hash); !errors.Is(err, ErrDataLoss) {
+		t.Errorf("Expected ErrDataLoss when every replica is corrupt, got %v", err)
+	}
+
+	// Nor does an unreachable replica set say anything about the location
+	for _, addr := range cluster.osdAddrs() {
+		cluster.stopOSD(addr)
+	}
+	if _, err := f.Get(ctx, hash); err == nil {
+		t.Error("Expected Get to fail with every replica down")
+	}
+
+	if stats := cluster.cacheStats("locations"); stats.Invalidations != 0 {
+		t.Errorf("Failed reads should not drop the cached location, got %+v", stats)
+	}
+	if cached, ok := f.locations.peek(hash); !ok || cached.VolumeId != entry.VolumeId {
+		t.Errorf("Location cache should still point at volume %s, got %v", entry.VolumeId, cached)
+	}
+}
+
+func TestRelocateEntryKeepsNewerLocation(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+	f := cluster.frontend
+
+	hash, err := f.Put(ctx, []byte("relocated twice"), "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	resp, err := f.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+	if err != nil || !resp.Found {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	stale := &blockindexpb.Entry{Hash: hash, CellId: resp.CellId, BucketId: resp.BucketId, Checksum: resp.Checksum, VolumeId: resp.VolumeId, Size: resp.Size}
+
+	// Another writer moves the block before this frontend records where it
+	// found it
+	updateResp, err := f.blockIndexClient.UpdateEntry(ctx, &blockindexpb.UpdateEntryRequest{
+		Hash:             hash,
+		CellId:           stale.CellId,
+		BucketId:         stale.BucketId,
+		Checksum:         stale.Checksum,
+		VolumeId:         "newer",
+		ExpectedCellId:   stale.CellId,
+		ExpectedBucketId: stale.BucketId,
+		ExpectedVolumeId: stale.VolumeId,
+	})
+	if err != nil || !updateResp.Success {
+		t.Fatalf("Failed to relocate entry: %v %v", updateResp, err)
+	}
+
+	f.relocateEntry(ctx, stale, "older")
+
+	entry, err := f.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+	if err != nil || entry.VolumeId != "newer" {
+		t.Errorf("The newer location should be kept, got %v %v", entry, err)
+	}
+	if cached, ok := f.locations.peek(hash); !ok || cached.VolumeId != "newer" {
+		t.Errorf("Location cache should point at the newer volume, got %v", cached)
+	}
+}
@@ -0,0 +1,208 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bharani/pkg/blockindex"
+	"bharani/pkg/config"
+	"bharani/pkg/master"
+	"bharani/pkg/osd"
+	"bharani/pkg/replication"
+	blockindexpb "bharani/proto/blockindex"
+	masterpb "bharani/proto/master"
+	osdpb "bharani/proto/osd"
+	replicationpb "bharani/proto/replication"
+
+	"google.golang.org/grpc"
+)
+
+// testCluster runs every metadata service and a set of OSDs on loopback listeners
+type testCluster struct {
+	t            *testing.T
+	config       *config.Config
+	frontend     *Frontend
+	master       *master.Master
+	osds         map[string]*grpc.Server
+	osdInstances map[string]*osd.OSD
+	osdZones     map[string]string
+	osdDelays    map[string]*atomic.Int64
+	indexFault   *atomic.Value // full name of a block index method that fails
+}
+
+// listen opens a listener on a free loopback port
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	return lis
+}
+
+// serve starts a gRPC server on a free loopback port and returns its address
+func serve(t *testing.T, lis net.Listener, register func(*grpc.Server), opts ...grpc.ServerOption) (string, *grpc.Server) {
+	t.Helper()
+
+	if lis == nil {
+		lis = listen(t)
+	}
+
+	s := grpc.NewServer(opts...)
+	register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String(), s
+}
+
+// newTestCluster starts osdCount OSDs spread over three zones and registers them with the master
+func newTestCluster(t *testing.T, osdCount int) *testCluster {
+	t.Helper()
+	dir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.CellID = "cell1"
+	cfg.ZoneID = "zone0"
+
+	index := blockindex.NewMemoryIndex()
+	t.Cleanup(func() { index.Close() })
+	indexFault := &atomic.Value{}
+	indexAddr, _ := serve(t, nil, func(s *grpc.Server) {
+		blockindexpb.RegisterBlockIndexServiceServer(s, blockindex.NewBlockIndexService(index))
+	}, grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if method, _ := indexFault.Load().(string); method == info.FullMethod {
+			return nil, fmt.Errorf("injected failure in %s", method)
+		}
+		return handler(ctx, req)
+	}))
+
+	table, err := replication.NewTable(filepath.Join(dir, "replication.db"))
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	t.Cleanup(func() { table.Close() })
+	tableAddr, _ := serve(t, nil, func(s *grpc.Server) {
+		replicationpb.RegisterReplicationTableServiceServer(s, replication.NewReplicationTableService(table))
+	})
+
+	m, err := master.NewMaster(cfg, cfg.CellID, tableAddr)
+	if err != nil {
+		t.Fatalf("Failed to create master: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	masterAddr, _ := serve(t, nil, func(s *grpc.Server) {
+		masterpb.RegisterMasterServiceServer(s, master.NewMasterService(m))
+	})
+
+	cluster := &testCluster{
+		t:            t,
+		config:       cfg,
+		master:       m,
+		osds:         make(map[string]*grpc.Server),
+		osdInstances: make(map[string]*osd.OSD),
+		osdZones:     make(map[string]string),
+		osdDelays:    make(map[string]*atomic.Int64),
+		indexFault:   indexFault,
+	}
+	for i := 0; i < osdCount; i++ {
+		osdCfg := *cfg
+		osdCfg.OSDDataDir = filepath.Join(dir, fmt.Sprintf("osd%d", i))
+		osdCfg.ZoneID = fmt.Sprintf("zone%d", i%3)
+
+		lis := listen(t)
+		addr := lis.Addr().String()
+
+		instance, err := osd.NewOSD(&osdCfg, addr, cfg.CellID)
+		if err != nil {
+			t.Fatalf("Failed to create OSD: %v", err)
+		}
+		cluster.osdInstances[addr] = instance
+		cluster.osdZones[addr] = osdCfg.ZoneID
+		cluster.osdDelays[addr] = &atomic.Int64{}
+		cluster.serveOSD(addr, lis)
+
+		_, err = m.RegisterOSD(context.Background(), &masterpb.RegisterOSDRequest{
+			OsdAddress:     addr,
+			CellId:         cfg.CellID,
+			ZoneId:         osdCfg.ZoneID,
+			AvailableSpace: 100 * cfg.BucketSize,
+		})
+		if err != nil {
+			t.Fatalf("Failed to register OSD: %v", err)
+		}
+	}
+
+	f, err := NewFrontend(cfg, indexAddr, tableAddr, masterAddr)
+	if err != nil {
+		t.Fatalf("Failed to create frontend: %v", err)
+	}
+	cluster.frontend = f
+
+	// Load zones now rather than racing the frontend's background refresh
+	if err := f.osdPool.refreshZones(context.Background(), f.masterClient, cfg.CellID); err != nil {
+		t.Fatalf("Failed to load OSD zones: %v", err)
+	}
+
+	return cluster
+}
+
+// stopOSD shuts down the gRPC server of an OSD, keeping its data
+func (c *testCluster) stopOSD(addr string) {
+	c.osds[addr].Stop()
+}
+
+// restartOSD serves a stopped OSD again on its original address
+func (c *testCluster) restartOSD(addr string) {
+	c.t.Helper()
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		c.t.Fatalf("Failed to listen on %s: %v", addr, err)
+	}
+	c.serveOSD(addr, lis)
+}
+
+// serveOSD serves an OSD instance, delaying each request by the OSD's injected delay
+func (c *testCluster) serveOSD(addr string, lis net.Listener) {
+	instance := c.osdInstances[addr]
+	delay := c.osdDelays[addr]
+
+	_, server := serve(c.t, lis, func(s *grpc.Server) {
+		osdpb.RegisterOSDServiceServer(s, osd.NewOSDService(instance))
+	}, grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		select {
+		case <-time.After(time.Duration(delay.Load())):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return handler(ctx, req)
+	}))
+	c.osds[addr] = server
+}
+
+// osdInZone returns the address of an OSD in the given zone
+func (c *testCluster) osdInZone(zoneID string) string {
+	for addr, zone := range c.osdZones {
+		if zone == zoneID {
+			return addr
+		}
+	}
+	c.t.Fatalf("No OSD in zone %s", zoneID)
+	return ""
+}
+
+// osdAddrs returns the addresses of all OSDs in the cluster
+func (c *testCluster) osdAddrs() []string {
+	addrs := make([]string, 0, len(c.osds))
+	for addr := range c.osds {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
@@ -0,0 +1,194 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"bharani/pkg/erasure"
+	"bharani/pkg/storage"
+	"bharani/proto/osd"
+	"bharani/proto/replication"
+)
+
+// encoderCache keeps one Reed-Solomon encoder per shard layout, since building
+// the coding matrices is more expensive than a read
+type encoderCache struct {
+	mu       sync.Mutex
+	encoders map[[2]int]*erasure.Encoder
+}
+
+// get returns the encoder for dataShards+parityShards shards
+func (c *encoderCache) get(dataShards, parityShards int) (*erasure.Encoder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := [2]int{dataShards, parityShards}
+	if encoder, ok := c.encoders[key]; ok {
+		return encoder, nil
+	}
+
+	encoder, err := erasure.NewEncoder(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+	if c.encoders == nil {
+		c.encoders = make(map[[2]int]*erasure.Encoder)
+	}
+	c.encoders[key] = encoder
+	return encoder, nil
+}
+
+// shardResult is the outcome of reading one shard
+type shardResult struct {
+	index int
+	data  []byte
+	err   error
+}
+
+// readShards reads a block from an erasure-coded volume, whose OSD addresses
+// hold shard i at index i. Data shards are read first, as many in parallel as
+// are needed to decode, and every failed read is replaced by a read of the
+// next shard, parity shards last. The decoded block is checked against its
+// hash. If it does not match, the remaining shards are read and the block is
+// decoded again leaving out one shard at a time, which recovers from a single
+// corrupt shard; otherwise ErrDataLoss is returned. If every shard's OSD
+// answered that it does not hold the block, errBlockMissing is returned.
+func (f *Frontend) readShards(ctx context.Context, req *osd.GetBlockRequest, volume *replication.GetVolumeResponse, size int64) ([]byte, error) {
+	dataShards, parityShards := int(volume.DataShards), int(volume.ParityShards)
+	if len(volume.OsdAddresses) != dataShards+parityShards {
+		return nil, fmt.Errorf("volume %s has %d OSDs for %d+%d shards", req.VolumeId, len(volume.OsdAddresses), dataShards, parityShards)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("block %s has no recorded size to decode from shards", req.Hash)
+	}
+
+	encoder, err := f.encoders.get(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+	shardSize := int((size + int64(dataShards) - 1) / int64(dataShards))
+
+	// Data shards need no decoding, so they are read before parity shards
+	order := make([]int, 0, len(volume.OsdAddresses))
+	for i := range dataShards {
+		order = append(order, i)
+	}
+	for _, addr := range f.osdPool.order(volume.OsdAddresses[dataShards:], f.config.ZoneID) {
+		order = append(order, dataShards+slices.Index(volume.OsdAddresses[dataShards:], addr))
+	}
+
+	shards := make([][]byte, len(volume.OsdAddresses))
+	next, read, missing := f.fetchShards(ctx, req, volume.OsdAddresses, order, shards, shardSize, dataShards)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if missing == len(order) {
+		return nil, fmt.Errorf("%w: no shards of block %s in volume %s", errBlockMissing, req.Hash, req.VolumeId)
+	}
+	if read < dataShards {
+		return nil, fmt.Errorf("not enough shards of block %s in volume %s: have %d, need %d", req.Hash, req.VolumeId, read, dataShards)
+	}
+
+	if data, ok := decodeShards(encoder, shards, size, req.Hash); ok {
+		return data, nil
+	}
+
+	f.fetchShards(ctx, req, volume.OsdAddresses, order[next:], shards, shardSize, len(order))
+	for skip := range shards {
+		if shards[skip] == nil {
+			continue
+		}
+		subset := slices.Clone(shards)
+		subset[skip] = nil
+		if data, ok := decodeShards(encoder, subset, size, req.Hash); ok {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: shards of block %s in volume %s do not decode to its hash", ErrDataLoss, req.Hash, req.VolumeId)
+}
+
+// fetchShards reads shards in the given order into shards, keeping up to want
+// reads in flight, until want of them succeeded or every shard was tried. It
+// returns how far into order it got, how many shards it read, and how many
+// OSDs answered that they do not hold the block.
+func (f *Frontend) fetchShards(ctx context.Context, req *osd.GetBlockRequest, addrs []string, order []int, shards [][]byte, shardSize, want int) (int, int, int) {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan shardResult, len(order))
+	next, inflight, read, missing := 0, 0, 0, 0
+	launch := func() {
+		index := order[next]
+		next++
+		inflight++
+		go func() {
+			data, err := f.readShard(readCtx, addrs[index], req, shardSize)
+			results <- shardResult{index: index, data: data, err: err}
+		}()
+	}
+
+	for next < len(order) && inflight < want {
+		launch()
+	}
+	for inflight > 0 {
+		result := <-results
+		inflight--
+		if result.err == nil {
+			shards[result.index] = result.data
+			read++
+			if read == want {
+				break
+			}
+			continue
+		}
+
+		if errors.Is(result.err, errBlockMissing) {
+			missing++
+		}
+		if ctx.Err() == nil {
+			f.osdPool.penalize(addrs[result.index], failurePenalty)
+		}
+		if next < len(order) {
+			launch()
+		}
+	}
+
+	return next, read, missing
+}
+
+// readShard reads one shard from an OSD, rejecting shards of the wrong length
+func (f *Frontend) readShard(ctx context.Context, osdAddr string, req *osd.GetBlockRequest, shardSize int) ([]byte, error) {
+	client, err := f.GetOSDClient(osdAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.GetBlock(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("%w: %s", errBlockMissing, resp.Error)
+	}
+	if len(resp.Data) != shardSize {
+		return nil, fmt.Errorf("OSD %s returned a shard of %d bytes for block %s, expected %d", osdAddr, len(resp.Data), req.Hash, shardSize)
+	}
+
+	return resp.Data, nil
+}
+
+// decodeShards reconstructs a block from its shards and reports whether it
+// matches hash. The shards are not modified.
+func decodeShards(encoder *erasure.Encoder, shards [][]byte, size int64, hash string) ([]byte, bool) {
+	data, err := encoder.Decode(slices.Clone(shards))
+	if err != nil || int64(len(data)) < size {
+		return nil, false
+	}
+
+	data = data[:size]
+	return data, storage.ComputeHash(data) == hash
+}
@@ -0,0 +1,131 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"bharani/pkg/config"
+	"bharani/pkg/erasure"
+	blockindexpb "bharani/proto/blockindex"
+	replicationpb "bharani/proto/replication"
+)
+
+// erasureCode rewrites a volume's blocks as shards spread over shardOSDs and
+// records the layout in the replication table, as the volume manager does
+func (c *testCluster) erasureCode(volumeID string, shardOSDs []string, blocks map[string][]byte) {
+	c.t.Helper()
+	ctx := context.Background()
+	f := c.frontend
+
+	encoder, err := erasure.NewEncoder(c.config.DataShards, c.config.ParityShards)
+	if err != nil {
+		c.t.Fatalf("Failed to create encoder: %v", err)
+	}
+	for hash, data := range blocks {
+		entry, err := f.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+		if err != nil || !entry.Found {
+			c.t.Fatalf("Failed to get entry of %s: %v", hash, err)
+		}
+		shards, err := encoder.Encode(data)
+		if err != nil {
+			c.t.Fatalf("Failed to encode %s: %v", hash, err)
+		}
+		for i, addr := range shardOSDs {
+			if err := c.osdInstances[addr].PutBlock(ctx, hash, entry.BucketId, volumeID, shards[i]); err != nil {
+				c.t.Fatalf("Failed to write shard %d of %s: %v", i, hash, err)
+			}
+		}
+	}
+
+	volume, err := f.replicationClient.GetVolume(ctx, &replicationpb.GetVolumeRequest{VolumeId: volumeID})
+	if err != nil || !volume.Found {
+		c.t.Fatalf("Failed to get volume %s: %v", volumeID, err)
+	}
+	resp, err := f.replicationClient.UpdateVolume(ctx, &replicationpb.UpdateVolumeRequest{
+		VolumeId:     volumeID,
+		OsdAddresses: shardOSDs,
+		Generation:   volume.Generation + 1,
+		DataShards:   int32(c.config.DataShards),
+		ParityShards: int32(c.config.ParityShards),
+	})
+	if err != nil || !resp.Success {
+		c.t.Fatalf("Failed to erasure code volume: %v %v", resp, err)
+	}
+}
+
+func TestErasureCodedRead(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cluster := newTestCluster(t, cfg.DataShards+cfg.ParityShards)
+	f := cluster.frontend
+	ctx := context.Background()
+
+	// Sizes that do and do not divide evenly into data shards
+	blocks := make(map[string][]byte)
+	var volumeID string
+	for _, size := range []int{1, 1000, 4096, 64*1024 + 7} {
+		data := bytes.Repeat([]byte{byte(size)}, size)
+		data[0] = 'x'
+		hash, err := f.Put(ctx, data, "doc")
+		if err != nil {
+			t.Fatalf("Failed to put block: %v", err)
+		}
+		blocks[hash] = data
+
+		entry, err := f.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+		if err != nil || (volumeID != "" && entry.VolumeId != volumeID) {
+			t.Fatalf("Expected blocks in one volume: %v %v", entry, err)
+		}
+		volumeID = entry.VolumeId
+	}
+
+	shardOSDs := cluster.osdAddrs()
+	slices.Sort(shardOSDs)
+	cluster.erasureCode(volumeID, shardOSDs, blocks)
+
+	check := func(when string) {
+		t.Helper()
+		for hash, want := range blocks {
+			got, err := f.Get(ctx, hash)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Fatalf("%s: failed to read %d byte block: %v", when, len(want), err)
+			}
+		}
+	}
+	check("all shards")
+
+	// A corrupt data shard is found by the hash check and left out
+	var some string
+	for hash := range blocks {
+		some = hash
+		break
+	}
+	entry, _ := f.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: some})
+	shard, err := cluster.osdInstances[shardOSDs[0]].GetBlock(ctx, some, entry.BucketId, volumeID)
+	if err != nil {
+		t.Fatalf("Failed to read shard: %v", err)
+	}
+	damaged := bytes.Clone(shard)
+	damaged[0] ^= 0xff
+	if err := cluster.osdInstances[shardOSDs[0]].PutBlock(ctx, some, entry.BucketId, volumeID, damaged); err != nil {
+		t.Fatalf("Failed to damage shard: %v", err)
+	}
+	check("corrupt shard")
+	if err := cluster.osdInstances[shardOSDs[0]].PutBlock(ctx, some, entry.BucketId, volumeID, shard); err != nil {
+		t.Fatalf("Failed to restore shard: %v", err)
+	}
+
+	// Losing up to ParityShards OSDs, data shards first so that every read
+	// has to decode, still returns the data
+	for i := 0; i < cfg.ParityShards; i++ {
+		cluster.stopOSD(shardOSDs[i])
+		check("OSDs stopped")
+	}
+
+	cluster.stopOSD(shardOSDs[cfg.ParityShards])
+	if _, err := f.Get(ctx, some); err == nil || errors.Is(err, ErrDataLoss) {
+		t.Errorf("Expected a read with too few shards to fail as unavailable, got %v", err)
+	}
+}
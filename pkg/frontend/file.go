@@ -0,0 +1,187 @@
+package frontend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"bharani/pkg/config"
+	"bharani/pkg/storage"
+)
+
+// manifestEntryBudget is a conservative upper bound on the encoded size of one manifest entry
+const manifestEntryBudget = 128
+
+// FileInfo describes a file stored through PutFile
+type FileInfo struct {
+	Hash       string // Hash of the root manifest block
+	Size       int64  // Total file length in bytes
+	BlockCount int    // Number of data blocks the file was split into
+}
+
+// ChunkerOptions returns the content-defined chunking parameters from the config
+func ChunkerOptions(cfg *config.Config) (storage.ChunkerOptions, error) {
+	opts := storage.ChunkerOptions{
+		MinSize: cfg.ChunkMinSize,
+		AvgSize: cfg.ChunkAvgSize,
+		MaxSize: cfg.ChunkMaxSize,
+	}
+
+	if err := opts.Validate(cfg.MaxBlockSize); err != nil {
+		return storage.ChunkerOptions{}, fmt.Errorf("invalid chunker config: %w", err)
+	}
+
+	return opts, nil
+}
+
+// PutFile splits a stream into content-defined blocks of at most MaxBlockSize,
+// stores them and a manifest describing their order, and returns the root
+// manifest hash. Every block, manifests included, is referenced by owner.
+func (f *Frontend) PutFile(ctx context.Context, r io.Reader, owner string) (*FileInfo, error) {
+	opts, err := ChunkerOptions(f.config)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.New()
+	chunker, err := storage.NewChunker(io.TeeReader(r, digest), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]storage.ManifestEntry, 0)
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		hash, err := f.Put(ctx, chunk, owner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store block %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, storage.ManifestEntry{Hash: hash, Size: int64(len(chunk))})
+	}
+
+	manifest := storage.NewManifest(entries, hex.EncodeToString(digest.Sum(nil)))
+	hash, err := f.PutManifest(ctx, manifest, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileInfo{
+		Hash:       hash,
+		Size:       manifest.TotalSize,
+		BlockCount: len(entries),
+	}, nil
+}
+
+// PutManifest stores a manifest, splitting it into child manifests when it
+// would not fit in a single block, and returns the root manifest hash. The
+// manifest blocks are referenced by owner.
+func (f *Frontend) PutManifest(ctx context.Context, manifest *storage.Manifest, owner string) (string, error) {
+	maxEntries := int(f.config.MaxBlockSize / manifestEntryBudget)
+
+	for len(manifest.Entries) > maxEntries {
+		parents := make([]storage.ManifestEntry, 0, len(manifest.Entries)/maxEntries+1)
+		for start := 0; start < len(manifest.Entries); start += maxEntries {
+			end := min(start+maxEntries, len(manifest.Entries))
+			child := storage.NewManifest(manifest.Entries[start:end], "")
+
+			hash, err := f.storeManifestBlock(ctx, child, owner)
+			if err != nil {
+				return "", err
+			}
+			parents = append(parents, storage.ManifestEntry{Hash: hash, Size: child.TotalSize, Indirect: true})
+		}
+		manifest = storage.NewManifest(parents, manifest.Digest)
+	}
+
+	return f.storeManifestBlock(ctx, manifest, owner)
+}
+
+// storeManifestBlock encodes a single manifest and stores it as a block
+func (f *Frontend) storeManifestBlock(ctx context.Context, manifest *storage.Manifest, owner string) (string, error) {
+	data, err := manifest.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	if int64(len(data)) > f.config.MaxBlockSize {
+		return "", fmt.Errorf("manifest too large: %d bytes exceeds max block size %d", len(data), f.config.MaxBlockSize)
+	}
+
+	hash, err := f.Put(ctx, data, owner)
+	if err != nil {
+		return "", fmt.Errorf("failed to store manifest: %w", err)
+	}
+
+	return hash, nil
+}
+
+// GetManifest retrieves and decodes the manifest stored under hash
+func (f *Frontend) GetManifest(ctx context.Context, hash string) (*storage.Manifest, error) {
+	data, err := f.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.DecodeManifest(data)
+}
+
+// GetFile resolves the manifest stored under hash and writes the file contents to w
+func (f *Frontend) GetFile(ctx context.Context, hash string, w io.Writer) error {
+	manifest, err := f.GetManifest(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.New()
+	if err := f.writeManifest(ctx, manifest, io.MultiWriter(w, digest)); err != nil {
+		return err
+	}
+
+	if manifest.Digest != "" && hex.EncodeToString(digest.Sum(nil)) != manifest.Digest {
+		return fmt.Errorf("file digest mismatch for manifest %s", hash)
+	}
+
+	return nil
+}
+
+// writeManifest writes the blocks referenced by a manifest in order, descending into child manifests
+func (f *Frontend) writeManifest(ctx context.Context, manifest *storage.Manifest, w io.Writer) error {
+	for i, entry := range manifest.Entries {
+		if entry.Indirect {
+			child, err := f.GetManifest(ctx, entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to resolve child manifest %s: %w", entry.Hash, err)
+			}
+			if child.TotalSize != entry.Size {
+				return fmt.Errorf("child manifest %s covers %d bytes, expected %d", entry.Hash, child.TotalSize, entry.Size)
+			}
+			if err := f.writeManifest(ctx, child, w); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := f.Get(ctx, entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to get block %d: %w", i+1, err)
+		}
+		if int64(len(data)) != entry.Size {
+			return fmt.Errorf("block %s has %d bytes, manifest expects %d", entry.Hash, len(data), entry.Size)
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write block %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -2,14 +2,16 @@ package frontend
 
 import (
 	"bharani/pkg/config"
+	"bharani/pkg/quota"
+	"bharani/pkg/shards"
 	"bharani/proto/blockindex"
 	"bharani/proto/master"
 	"bharani/proto/osd"
 	"bharani/proto/replication"
+	"context"
 	"fmt"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Frontend coordinates Put/Get operations
@@ -18,47 +20,57 @@ type Frontend struct {
 	blockIndexClient  blockindex.BlockIndexServiceClient
 	replicationClient replication.ReplicationTableServiceClient
 	masterClient      master.MasterServiceClient
-	osdClients        map[string]osd.OSDServiceClient
+	osdPool           *osdPool
+	readLatency       *latencyTracker
+	limiter           *quota.Limiter
+	encoders          encoderCache
+	locations         *lruCache[string, *blockindex.Entry]              // Index entries by block hash
+	volumes           *lruCache[string, *replication.GetVolumeResponse] // Replica sets by volume ID
 }
 
 // NewFrontend creates a new Frontend instance
 func NewFrontend(cfg *config.Config, blockIndexAddr, replicationAddr, masterAddr string) (*Frontend, error) {
-	blockIndexConn, err := grpc.NewClient(blockIndexAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialOption, err := cfg.TLS.DialOption()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to block index: %w", err)
+		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
 	}
 
-	replicationConn, err := grpc.NewClient(replicationAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	replicationConn, err := grpc.NewClient(replicationAddr, dialOption)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to replication table: %w", err)
 	}
 
-	masterConn, err := grpc.NewClient(masterAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	masterConn, err := grpc.NewClient(masterAddr, dialOption)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to master: %w", err)
 	}
+	masterClient := master.NewMasterServiceClient(masterConn)
 
-	return &Frontend{
+	limiter, err := newLimiter(cfg.LimitsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Frontend{
 		config:            cfg,
-		blockIndexClient:  blockindex.NewBlockIndexServiceClient(blockIndexConn),
+		blockIndexClient:  shards.NewClient(blockIndexAddr, masterClient, dialOption),
 		replicationClient: replication.NewReplicationTableServiceClient(replicationConn),
-		masterClient:      master.NewMasterServiceClient(masterConn),
-		osdClients:        make(map[string]osd.OSDServiceClient),
-	}, nil
+		masterClient:      masterClient,
+		osdPool:           newOSDPool(dialOption),
+		readLatency:       newLatencyTracker(),
+		limiter:           limiter,
+		locations:         newLRUCache[string, *blockindex.Entry]("locations", cfg.LocationCacheSize),
+		volumes:           newLRUCache[string, *replication.GetVolumeResponse]("volumes", cfg.VolumeCacheSize),
+	}
+
+	// Zones only order replicas by locality, so reads proceed without them
+	// until the first load finishes
+	go f.osdPool.watchZones(context.Background(), masterClient, cfg.CellID)
+
+	return f, nil
 }
 
 // GetOSDClient gets or creates a gRPC client for an OSD
 func (f *Frontend) GetOSDClient(osdAddress string) (osd.OSDServiceClient, error) {
-	if client, exists := f.osdClients[osdAddress]; exists {
-		return client, nil
-	}
-
-	conn, err := grpc.NewClient(osdAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to OSD %s: %w", osdAddress, err)
-	}
-
-	client := osd.NewOSDServiceClient(conn)
-	f.osdClients[osdAddress] = client
-	return client, nil
+	return f.osdPool.client(osdAddress)
 }
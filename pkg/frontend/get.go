@@ -2,59 +2,475 @@ package frontend
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
 
+	"bharani/pkg/storage"
 	"bharani/proto/blockindex"
+	"bharani/proto/master"
 	"bharani/proto/osd"
 	"bharani/proto/replication"
 )
 
-// Get retrieves a block from the system
+// ErrNotFound is returned when the block index has no entry for a hash
+var ErrNotFound = errors.New("block not found")
+
+// ErrDataLoss is returned when every replica of a block returned data that
+// does not match its hash
+var ErrDataLoss = errors.New("data loss: every replica of the block is corrupt")
+
+// ErrBlockDeleting is returned when writing a block whose previous copy the
+// garbage collector is deleting. The write succeeds once deletion finishes.
+var ErrBlockDeleting = errors.New("block is being deleted, retry later")
+
+// errBlockMissing is returned when every replica or shard of a volume answered
+// that it does not hold a block, which means the block's recorded location
+// is out of date
+var errBlockMissing = errors.New("block is not stored in its volume")
+
+// Get retrieves a block from the system. Data from each replica is checked
+// against the block hash, and corrupt replicas are skipped and reported to
+// the master for repair. Authenticated callers may only read blocks their
+// tenant references, and get ErrAccessDenied otherwise, and reads count
+// against the tenant's bandwidth.
 func (f *Frontend) Get(ctx context.Context, hash string) ([]byte, error) {
-	getEntryReq := &blockindex.GetEntryRequest{Hash: hash}
-	getEntryResp, err := f.blockIndexClient.GetEntry(ctx, getEntryReq)
+	if err := f.authorizeRead(ctx, hash); err != nil {
+		return nil, err
+	}
+
+	entry, ok := f.locations.get(hash)
+	if !ok {
+		var err error
+		if entry, err = f.fetchEntry(ctx, hash); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.limitBandwidth(ctx, entry.Size); err != nil {
+		return nil, err
+	}
+
+	return f.readEntry(ctx, entry, f.newVolumeLookup())
+}
+
+// fetchEntry reads a block's index entry and adds it to the location cache.
+// It returns ErrNotFound for unindexed blocks.
+func (f *Frontend) fetchEntry(ctx context.Context, hash string) (*blockindex.Entry, error) {
+	getEntryResp, err := f.blockIndexClient.GetEntry(ctx, &blockindex.GetEntryRequest{Hash: hash})
 	if err != nil {
 		return nil, fmt.Errorf("failed to lookup block: %w", err)
 	}
-
 	if !getEntryResp.Found {
-		return nil, fmt.Errorf("block not found: %s", hash)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+
+	entry := &blockindex.Entry{
+		Hash:     hash,
+		CellId:   getEntryResp.CellId,
+		BucketId: getEntryResp.BucketId,
+		Checksum: getEntryResp.Checksum,
+		VolumeId: getEntryResp.VolumeId,
+		Size:     getEntryResp.Size,
+	}
+	f.locations.put(hash, entry)
+	return entry, nil
+}
+
+// readEntry reads the block described by an index entry. Entries that record
+// their volume are read from that volume's replicas only. A failed read drops
+// the volume's cached replica set and, if the replication table has a newer
+// one, is retried on it. Only a volume that is unknown or answers that it
+// does not hold the block counts as a stale location: an entry from the
+// location cache is then checked against the index in case the block has
+// moved or been deleted. Any other failure, such as data loss or unreachable
+// replicas, is returned as is. Entries without a volume, or whose volume no
+// longer holds the block, fall back to scanning every volume in the cell, and
+// the index is corrected to point at the volume where the block was found.
+func (f *Frontend) readEntry(ctx context.Context, entry *blockindex.Entry, lookup *volumeLookup) ([]byte, error) {
+	if entry.VolumeId != "" {
+		if volume := lookup.volume(ctx, entry.VolumeId); volume != nil {
+			data, err := f.readFromVolume(ctx, entry, volume)
+			if err != nil && ctx.Err() == nil {
+				if fresh := lookup.refresh(ctx, volume); fresh != nil {
+					data, err = f.readFromVolume(ctx, entry, fresh)
+				}
+			}
+			if err == nil {
+				return data, nil
+			}
+			if !errors.Is(err, errBlockMissing) {
+				return nil, err
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if f.locations.invalidate(entry.Hash) {
+		fresh, err := f.fetchEntry(ctx, entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if fresh.VolumeId != entry.VolumeId || fresh.BucketId != entry.BucketId {
+			return f.readEntry(ctx, fresh, lookup)
+		}
+	}
+
+	volumes, err := lookup.cell(ctx, entry.CellId)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataLoss error
+	for _, volume := range volumes {
+		if volume.VolumeId == entry.VolumeId {
+			continue
+		}
+		data, err := f.readFromVolume(ctx, entry, volume)
+		if err == nil {
+			f.relocateEntry(ctx, entry, volume.VolumeId)
+			return data, nil
+		}
+		if errors.Is(err, ErrDataLoss) {
+			dataLoss = err
+		}
+	}
+
+	if dataLoss != nil {
+		return nil, dataLoss
+	}
+	return nil, fmt.Errorf("block not found on any OSD")
+}
+
+// relocateEntry records the volume a block was actually found on so later
+// reads go straight to it. If another writer has moved the block since entry
+// was read, the index keeps its location and the cache is pointed at it.
+func (f *Frontend) relocateEntry(ctx context.Context, entry *blockindex.Entry, volumeID string) {
+	updateReq := &blockindex.UpdateEntryRequest{
+		Hash:             entry.Hash,
+		CellId:           entry.CellId,
+		BucketId:         entry.BucketId,
+		Checksum:         entry.Checksum,
+		VolumeId:         volumeID,
+		ExpectedCellId:   entry.CellId,
+		ExpectedBucketId: entry.BucketId,
+		ExpectedVolumeId: entry.VolumeId,
+	}
+
+	resp, err := f.blockIndexClient.UpdateEntry(ctx, updateReq)
+	if err != nil {
+		log.Printf("Failed to record volume %s for block %s: %v", volumeID, entry.Hash, err)
+		return
+	}
+	if resp.Conflict && resp.Current != nil {
+		f.locations.put(entry.Hash, resp.Current)
+		return
+	}
+	if !resp.Success {
+		log.Printf("Failed to record volume %s for block %s: %s", volumeID, entry.Hash, resp.Error)
+		f.locations.invalidate(entry.Hash)
+		return
+	}
+
+	f.locations.put(entry.Hash, &blockindex.Entry{
+		Hash:     entry.Hash,
+		CellId:   entry.CellId,
+		BucketId: entry.BucketId,
+		Checksum: entry.Checksum,
+		VolumeId: volumeID,
+		Size:     entry.Size,
+	})
+}
+
+// volumeLookup fetches volume replica sets from the frontend's volume cache
+// or the replication service, remembering them for the duration of one
+// request so that a batch asks about each volume or cell only once
+type volumeLookup struct {
+	f       *Frontend
+	volumes map[string]*replication.GetVolumeResponse
+	cells   map[string][]*replication.GetVolumeResponse
+	mu      sync.Mutex
+}
+
+// newVolumeLookup creates an empty lookup
+func (f *Frontend) newVolumeLookup() *volumeLookup {
+	return &volumeLookup{
+		f:       f,
+		volumes: make(map[string]*replication.GetVolumeResponse),
+		cells:   make(map[string][]*replication.GetVolumeResponse),
+	}
+}
+
+// volume returns a volume's replica set, or nil if the volume is unknown
+func (l *volumeLookup) volume(ctx context.Context, volumeID string) *replication.GetVolumeResponse {
+	l.mu.Lock()
+	volume, cached := l.volumes[volumeID]
+	l.mu.Unlock()
+	if cached {
+		return volume
 	}
 
+	volume = l.f.cachedVolume(ctx, volumeID)
+
+	l.mu.Lock()
+	l.volumes[volumeID] = volume
+	l.mu.Unlock()
+	return volume
+}
+
+// refresh is called after a read from a volume's replica set failed, which
+// may mean the cached set is out of date. It drops the set from the volume
+// cache and fetches it again, returning the new set if its generation or
+// replicas changed and nil otherwise.
+func (l *volumeLookup) refresh(ctx context.Context, stale *replication.GetVolumeResponse) *replication.GetVolumeResponse {
+	if !l.f.volumes.invalidate(stale.VolumeId) {
+		return nil
+	}
+
+	volume := l.f.fetchVolume(ctx, stale.VolumeId)
+
+	l.mu.Lock()
+	l.volumes[stale.VolumeId] = volume
+	l.mu.Unlock()
+
+	if volume == nil || (volume.Generation == stale.Generation && slices.Equal(volume.OsdAddresses, stale.OsdAddresses)) {
+		return nil
+	}
+	return volume
+}
+
+// cell returns the replica sets of every volume in a cell
+func (l *volumeLookup) cell(ctx context.Context, cellID string) ([]*replication.GetVolumeResponse, error) {
+	l.mu.Lock()
+	volumes, cached := l.cells[cellID]
+	l.mu.Unlock()
+	if cached {
+		return volumes, nil
+	}
+
+	volumes, err := l.f.listCellVolumes(ctx, cellID)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.cells[cellID] = volumes
+	l.mu.Unlock()
+	return volumes, nil
+}
+
+// listCellVolumes fetches the replica sets of every volume in a cell
+func (f *Frontend) listCellVolumes(ctx context.Context, cellID string) ([]*replication.GetVolumeResponse, error) {
 	listVolumesReq := &replication.ListVolumesRequest{
-		CellId: getEntryResp.CellId,
+		CellId: cellID,
 	}
 	listVolumesResp, err := f.replicationClient.ListVolumes(ctx, listVolumesReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list volumes: %w", err)
 	}
 
+	volumes := make([]*replication.GetVolumeResponse, 0, len(listVolumesResp.VolumeIds))
 	for _, volumeID := range listVolumesResp.VolumeIds {
-		getVolumeReq := &replication.GetVolumeRequest{VolumeId: volumeID}
-		getVolumeResp, err := f.replicationClient.GetVolume(ctx, getVolumeReq)
-		if err != nil || !getVolumeResp.Found {
-			continue
+		if volume := f.cachedVolume(ctx, volumeID); volume != nil {
+			volumes = append(volumes, volume)
 		}
+	}
+
+	return volumes, nil
+}
+
+// cachedVolume returns a volume's replica set from the volume cache, fetching
+// it on a miss. It returns nil if the volume is unknown.
+func (f *Frontend) cachedVolume(ctx context.Context, volumeID string) *replication.GetVolumeResponse {
+	if volume, ok := f.volumes.get(volumeID); ok {
+		return volume
+	}
+	return f.fetchVolume(ctx, volumeID)
+}
 
-		for _, osdAddr := range getVolumeResp.OsdAddresses {
-			client, err := f.GetOSDClient(osdAddr)
-			if err != nil {
-				continue
+// fetchVolume reads a volume's replica set from the replication table and
+// caches it, returning nil if the volume is unknown or cannot be read
+func (f *Frontend) fetchVolume(ctx context.Context, volumeID string) *replication.GetVolumeResponse {
+	getVolumeResp, err := f.replicationClient.GetVolume(ctx, &replication.GetVolumeRequest{VolumeId: volumeID})
+	if err != nil || !getVolumeResp.Found {
+		return nil
+	}
+
+	f.cacheVolume(getVolumeResp)
+	return getVolumeResp
+}
+
+// cacheVolume caches a volume's replica set. A set of a different generation
+// than the cached one replaces it and counts as an invalidation.
+func (f *Frontend) cacheVolume(volume *replication.GetVolumeResponse) {
+	if cached, ok := f.volumes.peek(volume.VolumeId); ok && cached.Generation != volume.Generation {
+		f.volumes.invalidate(volume.VolumeId)
+	}
+	f.volumes.put(volume.VolumeId, volume)
+}
+
+// readFromVolume reads the block from the replicas of one volume, or decodes
+// it from the shards of an erasure-coded volume
+func (f *Frontend) readFromVolume(ctx context.Context, entry *blockindex.Entry, volume *replication.GetVolumeResponse) ([]byte, error) {
+	getBlockReq := &osd.GetBlockRequest{
+		Hash:     entry.Hash,
+		BucketId: entry.BucketId,
+		VolumeId: volume.VolumeId,
+	}
+
+	if volume.DataShards > 0 {
+		return f.readShards(ctx, getBlockReq, volume, entry.Size)
+	}
+	return f.hedgedRead(ctx, getBlockReq, volume.OsdAddresses)
+}
+
+// readResult is the outcome of reading a block from one replica
+type readResult struct {
+	osdAddr string
+	data    []byte
+	err     error
+	failed  bool // The OSD itself misbehaved, as opposed to not holding the block
+	corrupt bool // The OSD returned data that does not match the hash
+	missing bool // The OSD answered that it does not hold the block
+	elapsed time.Duration
+}
+
+// hedgeDelay returns how long to wait for the first replica before sending a hedged read
+func (f *Frontend) hedgeDelay() time.Duration {
+	delay, ok := f.readLatency.percentile(f.config.HedgePercentile)
+	if !ok {
+		delay = defaultHedgeDelay
+	}
+	return max(delay, f.config.HedgeMinDelay)
+}
+
+// hedgedRead reads a block from a volume's replicas, preferring replicas in
+// the frontend's zone and with low penalty scores. If the first replica has
+// not answered within the hedge delay a second read is sent to the next
+// replica; the first success wins and the slower read is cancelled. Replicas
+// that fail or return corrupt data are replaced immediately by the next one.
+// ErrDataLoss is returned only if every replica returned corrupt data, and
+// errBlockMissing only if every replica answered that it does not hold it.
+func (f *Frontend) hedgedRead(ctx context.Context, req *osd.GetBlockRequest, replicas []string) ([]byte, error) {
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("volume %s has no replicas", req.VolumeId)
+	}
+
+	ordered := f.osdPool.order(replicas, f.config.ZoneID)
+
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan readResult, len(ordered))
+	inflight := make(map[string]bool)
+	next := 0
+
+	launch := func() {
+		osdAddr := ordered[next]
+		next++
+		inflight[osdAddr] = true
+		go func() {
+			results <- f.readReplica(readCtx, osdAddr, req)
+		}()
+	}
+
+	launch()
+	hedge := time.NewTimer(f.hedgeDelay())
+	defer hedge.Stop()
+
+	var lastErr error
+	corrupt, missing := 0, 0
+	for len(inflight) > 0 {
+		select {
+		case result := <-results:
+			delete(inflight, result.osdAddr)
+			if result.err == nil {
+				f.readLatency.record(result.elapsed)
+				for osdAddr := range inflight {
+					f.osdPool.penalize(osdAddr, slowPenalty)
+				}
+				return result.data, nil
 			}
 
-			getBlockReq := &osd.GetBlockRequest{
-				Hash:     hash,
-				BucketId: getEntryResp.BucketId,
-				VolumeId: volumeID,
+			lastErr = result.err
+			if result.failed {
+				f.osdPool.penalize(result.osdAddr, failurePenalty)
+			}
+			if result.corrupt {
+				corrupt++
+				go f.reportCorruption(result.osdAddr, req)
+			}
+			if result.missing {
+				missing++
+			}
+			if next < len(ordered) {
+				launch()
 			}
 
-			getBlockResp, err := client.GetBlock(ctx, getBlockReq)
-			if err == nil && getBlockResp.Success {
-				return getBlockResp.Data, nil
+		case <-hedge.C:
+			if next < len(ordered) {
+				for osdAddr := range inflight {
+					f.osdPool.penalize(osdAddr, slowPenalty)
+				}
+				launch()
 			}
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
 
-	return nil, fmt.Errorf("block not found on any OSD")
+	if corrupt == len(ordered) {
+		return nil, fmt.Errorf("%w: block %s in volume %s", ErrDataLoss, req.Hash, req.VolumeId)
+	}
+	if missing == len(ordered) {
+		return nil, fmt.Errorf("%w: block %s in volume %s: %v", errBlockMissing, req.Hash, req.VolumeId, lastErr)
+	}
+	return nil, lastErr
 }
 
+// reportCorruption asks the master to repair a replica that returned corrupt data
+func (f *Frontend) reportCorruption(osdAddr string, req *osd.GetBlockRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.config.ReplicaTimeout)
+	defer cancel()
+
+	_, err := f.masterClient.ReportCorruption(ctx, &master.ReportCorruptionRequest{
+		Hash:       req.Hash,
+		VolumeId:   req.VolumeId,
+		BucketId:   req.BucketId,
+		OsdAddress: osdAddr,
+	})
+	if err != nil {
+		log.Printf("Failed to report corrupt replica of block %s on %s: %v", req.Hash, osdAddr, err)
+	}
+}
+
+// readReplica reads a block from a single OSD
+func (f *Frontend) readReplica(ctx context.Context, osdAddr string, req *osd.GetBlockRequest) readResult {
+	start := time.Now()
+
+	client, err := f.GetOSDClient(osdAddr)
+	if err != nil {
+		return readResult{osdAddr: osdAddr, err: err, failed: true}
+	}
+
+	resp, err := client.GetBlock(ctx, req)
+	elapsed := time.Since(start)
+	if err != nil {
+		// A read cancelled because another replica won is not the OSD's fault
+		return readResult{osdAddr: osdAddr, err: err, failed: ctx.Err() == nil, elapsed: elapsed}
+	}
+	if !resp.Success {
+		return readResult{osdAddr: osdAddr, err: fmt.Errorf("%s", resp.Error), missing: true, elapsed: elapsed}
+	}
+	if storage.ComputeHash(resp.Data) != req.Hash {
+		err := fmt.Errorf("OSD %s returned corrupt data for block %s", osdAddr, req.Hash)
+		return readResult{osdAddr: osdAddr, err: err, failed: true, corrupt: true, elapsed: elapsed}
+	}
+
+	return readResult{osdAddr: osdAddr, data: resp.Data, elapsed: elapsed}
+}
@@ -0,0 +1,166 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bharani/proto/blockindex"
+)
+
+func TestGetHedgesAroundSlowLocalReplica(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	data := []byte("hedged read")
+	hash, err := cluster.frontend.Put(ctx, data, "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+
+	local := cluster.osdInZone(cluster.config.ZoneID)
+	cluster.osdDelays[local].Store(int64(5 * time.Second))
+
+	start := time.Now()
+	got, err := cluster.frontend.Get(ctx, hash)
+	if err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Get returned %q, want %q", got, data)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Hedged read took %v, slow replica was not bypassed", elapsed)
+	}
+
+	if cluster.frontend.osdPool.score(local) <= 0 {
+		t.Error("Slow replica should have been penalized")
+	}
+
+	ordered := cluster.frontend.osdPool.order(cluster.osdAddrs(), cluster.config.ZoneID)
+	if ordered[0] != local {
+		t.Errorf("Local replica should still be preferred over remote ones, got %v", ordered)
+	}
+}
+
+func TestGetRecordsVolumeForLegacyEntry(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	data := []byte("legacy entry")
+	hash, err := cluster.frontend.Put(ctx, data, "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+
+	entry, err := cluster.frontend.blockIndexClient.GetEntry(ctx, &blockindex.GetEntryRequest{Hash: hash})
+	if err != nil || !entry.Found {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	volumeID := entry.VolumeId
+	if volumeID == "" {
+		t.Fatal("Put should record the block's volume")
+	}
+
+	// Simulate an entry written before volumes were recorded
+	updateResp, err := cluster.frontend.blockIndexClient.UpdateEntry(ctx, &blockindex.UpdateEntryRequest{
+		Hash:             hash,
+		CellId:           entry.CellId,
+		BucketId:         entry.BucketId,
+		Checksum:         entry.Checksum,
+		ExpectedCellId:   entry.CellId,
+		ExpectedBucketId: entry.BucketId,
+		ExpectedVolumeId: volumeID,
+	})
+	if err != nil || !updateResp.Success {
+		t.Fatalf("Failed to rewrite entry: %v %v", updateResp, err)
+	}
+
+	got, err := cluster.frontend.Get(ctx, hash)
+	if err != nil {
+		t.Fatalf("Failed to get legacy block: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Legacy block data mismatch")
+	}
+
+	entry, err = cluster.frontend.blockIndexClient.GetEntry(ctx, &blockindex.GetEntryRequest{Hash: hash})
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry.VolumeId != volumeID {
+		t.Errorf("Get should record volume %s for the legacy entry, got %q", volumeID, entry.VolumeId)
+	}
+}
+
+func TestGetSkipsAndRepairsCorruptReplicas(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	data := []byte("verified read")
+	hash, err := cluster.frontend.Put(ctx, data, "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	entry, err := cluster.frontend.blockIndexClient.GetEntry(ctx, &blockindex.GetEntryRequest{Hash: hash})
+	if err != nil || !entry.Found {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+
+	corrupt := func(addr string) {
+		err := cluster.osdInstances[addr].PutBlock(ctx, hash, entry.BucketId, entry.VolumeId, []byte("bit rot"))
+		if err != nil {
+			t.Fatalf("Failed to corrupt replica on %s: %v", addr, err)
+		}
+	}
+
+	// The local replica is read first, so corrupting it forces a failover
+	local := cluster.osdInZone(cluster.config.ZoneID)
+	corrupt(local)
+
+	got, err := cluster.frontend.Get(ctx, hash)
+	if err != nil {
+		t.Fatalf("Get should fail over from the corrupt replica: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("Get returned corrupt data")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cluster.master.PendingReplicaRepairs() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if repaired := cluster.master.RepairUnderReplicated(ctx); repaired != 1 {
+		t.Fatalf("Expected the corrupt replica to be repaired, repaired %d", repaired)
+	}
+
+	repairedData, err := cluster.osdInstances[local].GetBlock(ctx, hash, entry.BucketId, entry.VolumeId)
+	if err != nil || !bytes.Equal(repairedData, data) {
+		t.Errorf("Corrupt replica was not repaired: %v", err)
+	}
+
+	for _, addr := range cluster.osdAddrs() {
+		corrupt(addr)
+	}
+	if _, err := cluster.frontend.Get(ctx, hash); !errors.Is(err, ErrDataLoss) {
+		t.Errorf("Expected ErrDataLoss when every replica is corrupt, got %v", err)
+	}
+}
+
+func TestLatencyTrackerPercentile(t *testing.T) {
+	tracker := newLatencyTracker()
+	if _, ok := tracker.percentile(0.95); ok {
+		t.Error("Percentile should not be trusted without samples")
+	}
+
+	for i := 1; i <= 100; i++ {
+		tracker.record(time.Duration(i) * time.Millisecond)
+	}
+
+	p95, ok := tracker.percentile(0.95)
+	if !ok || p95 != 95*time.Millisecond {
+		t.Errorf("Expected p95 of 95ms, got %v", p95)
+	}
+}
@@ -0,0 +1,62 @@
+package frontend
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWindow is the number of recent read latencies kept for percentiles
+	latencyWindow = 256
+
+	// minLatencySamples is the number of samples needed before percentiles are trusted
+	minLatencySamples = 16
+
+	// defaultHedgeDelay is used until enough latency samples have been collected
+	defaultHedgeDelay = 50 * time.Millisecond
+)
+
+// latencyTracker keeps a sliding window of recent successful read latencies
+type latencyTracker struct {
+	samples []time.Duration
+	next    int
+	mu      sync.Mutex
+}
+
+// newLatencyTracker creates an empty tracker
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{
+		samples: make([]time.Duration, 0, latencyWindow),
+	}
+}
+
+// record adds a latency sample, evicting the oldest once the window is full
+func (l *latencyTracker) record(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.samples) < latencyWindow {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % latencyWindow
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of the window and
+// whether enough samples exist for it to be meaningful
+func (l *latencyTracker) percentile(p float64) (time.Duration, bool) {
+	l.mu.Lock()
+	sorted := slices.Clone(l.samples)
+	l.mu.Unlock()
+
+	if len(sorted) < minLatencySamples {
+		return 0, false
+	}
+
+	slices.Sort(sorted)
+	i := int(p*float64(len(sorted))+0.5) - 1
+	i = max(0, min(i, len(sorted)-1))
+	return sorted[i], true
+}
@@ -0,0 +1,180 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"bharani/proto/master"
+	"bharani/proto/osd"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// penaltyHalfLife controls how quickly an OSD's penalty score is forgiven
+	penaltyHalfLife = 10 * time.Second
+
+	// failurePenalty is added when a read from an OSD fails outright
+	failurePenalty = 10.0
+
+	// slowPenalty is added when an OSD loses a hedged read or needs a hedge
+	slowPenalty = 1.0
+
+	// demoteScore is the penalty at which a local OSD loses its locality preference
+	demoteScore = 5.0
+
+	// zoneRefreshInterval is how often the OSD zone map is reloaded from the master
+	zoneRefreshInterval = 30 * time.Second
+)
+
+// penalty is an exponentially decaying score of recent misbehaviour
+type penalty struct {
+	score   float64
+	updated time.Time
+}
+
+// decayed returns the score as of now
+func (p *penalty) decayed(now time.Time) float64 {
+	elapsed := now.Sub(p.updated)
+	return p.score * math.Pow(0.5, float64(elapsed)/float64(penaltyHalfLife))
+}
+
+// osdPool caches OSD clients, the zone of each OSD, and short-term penalty
+// scores used to steer reads away from slow or failing OSDs
+type osdPool struct {
+	dialOption grpc.DialOption
+	clients    map[string]osd.OSDServiceClient
+	penalties  map[string]*penalty
+	zones      map[string]string
+	mu         sync.Mutex
+}
+
+// newOSDPool creates an empty pool that dials OSDs with dialOption
+func newOSDPool(dialOption grpc.DialOption) *osdPool {
+	return &osdPool{
+		dialOption: dialOption,
+		clients:    make(map[string]osd.OSDServiceClient),
+		penalties:  make(map[string]*penalty),
+		zones:      make(map[string]string),
+	}
+}
+
+// client gets or creates a gRPC client for an OSD
+func (p *osdPool) client(osdAddress string) (osd.OSDServiceClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, exists := p.clients[osdAddress]; exists {
+		return client, nil
+	}
+
+	conn, err := grpc.NewClient(osdAddress, p.dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to OSD %s: %w", osdAddress, err)
+	}
+
+	client := osd.NewOSDServiceClient(conn)
+	p.clients[osdAddress] = client
+	return client, nil
+}
+
+// penalize adds amount to an OSD's decaying penalty score
+func (p *osdPool) penalize(osdAddress string, amount float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	current, exists := p.penalties[osdAddress]
+	if !exists {
+		p.penalties[osdAddress] = &penalty{score: amount, updated: now}
+		return
+	}
+
+	current.score = current.decayed(now) + amount
+	current.updated = now
+}
+
+// score returns an OSD's current penalty score
+func (p *osdPool) score(osdAddress string) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current, exists := p.penalties[osdAddress]
+	if !exists {
+		return 0
+	}
+	return current.decayed(time.Now())
+}
+
+// watchZones loads the OSD zone map from the master and then reloads it
+// every zoneRefreshInterval until ctx is cancelled, so that reads never wait
+// on the master
+func (p *osdPool) watchZones(ctx context.Context, masterClient master.MasterServiceClient, cellID string) {
+	ticker := time.NewTicker(zoneRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.refreshZones(ctx, masterClient, cellID); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to refresh OSD zones: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshZones reloads the OSD zone map from the master
+func (p *osdPool) refreshZones(ctx context.Context, masterClient master.MasterServiceClient, cellID string) error {
+	ctx, cancel := context.WithTimeout(ctx, zoneRefreshInterval)
+	defer cancel()
+
+	resp, err := masterClient.ListOSDs(ctx, &master.ListOSDsRequest{CellId: cellID})
+	if err != nil {
+		return err
+	}
+
+	zones := make(map[string]string, len(resp.Osds))
+	for _, status := range resp.Osds {
+		zones[status.Address] = status.ZoneId
+	}
+
+	p.mu.Lock()
+	p.zones = zones
+	p.mu.Unlock()
+	return nil
+}
+
+// order sorts replicas so that OSDs in localZone come first and, within
+// each group, OSDs with lower penalty scores come first. A local OSD whose
+// score has reached demoteScore is treated as remote until it recovers.
+func (p *osdPool) order(replicas []string, localZone string) []string {
+	p.mu.Lock()
+	now := time.Now()
+	remote := make(map[string]bool, len(replicas))
+	scores := make(map[string]float64, len(replicas))
+	for _, addr := range replicas {
+		if current, exists := p.penalties[addr]; exists {
+			scores[addr] = current.decayed(now)
+		}
+		remote[addr] = p.zones[addr] != localZone || scores[addr] >= demoteScore
+	}
+	p.mu.Unlock()
+
+	ordered := append([]string{}, replicas...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if remote[a] != remote[b] {
+			return !remote[a]
+		}
+		return scores[a] < scores[b]
+	})
+	return ordered
+}
@@ -2,111 +2,250 @@ package frontend
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
 	"bharani/pkg/storage"
 	"bharani/proto/blockindex"
 	"bharani/proto/master"
 	"bharani/proto/osd"
 	"bharani/proto/replication"
-
-	"github.com/google/uuid"
 )
 
-// Put stores a block in the system
-func (f *Frontend) Put(ctx context.Context, data []byte) (string, error) {
+// ErrHashMismatch is returned when a block does not hash to the value the
+// writer expected, which usually means it was damaged in transit
+var ErrHashMismatch = errors.New("block does not match expected hash")
+
+// Put stores a block in the system and records a reference to it held by
+// owner. Blocks written with an empty owner are pinned and never collected.
+// If the garbage collector is deleting an earlier copy of the block, Put
+// returns ErrBlockDeleting and the write should be retried.
+func (f *Frontend) Put(ctx context.Context, data []byte, owner string) (string, error) {
+	return f.PutExpected(ctx, data, "", owner)
+}
+
+// PutExpected stores a block like Put, but first rejects it with
+// ErrHashMismatch unless it hashes to expectedHash. An empty expectedHash
+// skips the check. Writes over the tenant's quota or bandwidth fail with
+// ErrQuotaExceeded or ErrRateLimited.
+func (f *Frontend) PutExpected(ctx context.Context, data []byte, expectedHash, owner string) (string, error) {
 	block, err := storage.NewBlock(data)
 	if err != nil {
 		return "", fmt.Errorf("failed to create block: %w", err)
 	}
+	if expectedHash != "" && block.Hash != expectedHash {
+		return "", fmt.Errorf("%w: got %s, expected %s", ErrHashMismatch, block.Hash, expectedHash)
+	}
+	if err := f.limitBandwidth(ctx, block.Size()); err != nil {
+		return "", err
+	}
+
+	// The quota is checked before referencing, since referencing a block
+	// stored by another tenant charges it to this one
+	if err := f.checkQuota(ctx, block.Size(), 1); err != nil {
+		return "", err
+	}
 
-	existsReq := &blockindex.ExistsRequest{Hash: block.Hash}
-	existsResp, err := f.blockIndexClient.Exists(ctx, existsReq)
-	if err == nil && existsResp.Exists {
+	// Referencing an existing block both deduplicates the write and keeps the
+	// garbage collector from claiming the block
+	refsResp, err := f.blockIndexClient.AddRefs(ctx, &blockindex.AddRefsRequest{Hashes: []string{block.Hash}, Owner: owner, Tenant: tenantOf(ctx)})
+	if err == nil && refsResp.Error != "" {
+		err = fmt.Errorf("%s", refsResp.Error)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to reference block: %w", err)
+	}
+	if len(refsResp.Deleting) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrBlockDeleting, block.Hash)
+	}
+	if len(refsResp.Found) > 0 {
 		return block.Hash, nil
 	}
 
-	openVolumesReq := &master.GetOpenVolumesRequest{
-		CellId: f.config.CellID,
+	volume, bucketID, err := f.reserveSpace(ctx, block.Size())
+	if err != nil {
+		return "", err
+	}
+	volumeID := volume.VolumeId
+
+	intent := &blockindex.Intent{
+		Hash:     block.Hash,
+		CellId:   f.config.CellID,
+		BucketId: bucketID,
+		VolumeId: volumeID,
+		Size:     block.Size(),
+		Tenant:   tenantOf(ctx),
+		Owner:    owner,
 	}
-	openVolumesResp, err := f.masterClient.GetOpenVolumes(ctx, openVolumesReq)
+	ids, err := f.beginPut(ctx, []*blockindex.Intent{intent})
 	if err != nil {
-		return "", fmt.Errorf("failed to get open volumes: %w", err)
-	}
-
-	var volumeID string
-	if len(openVolumesResp.VolumeIds) > 0 {
-		volumeID = openVolumesResp.VolumeIds[0]
-	} else {
-		volumeID = uuid.New().String()
-		healthyOSDs := f.getHealthyOSDs(ctx)
-		if len(healthyOSDs) < f.config.ReplicationFactor {
-			return "", fmt.Errorf("not enough healthy OSDs: need %d, have %d",
-				f.config.ReplicationFactor, len(healthyOSDs))
-		}
+		return "", err
+	}
 
-		selectedOSDs := healthyOSDs[:f.config.ReplicationFactor]
+	putReq := &osd.PutBlockRequest{
+		Hash:     block.Hash,
+		Data:     block.Data,
+		BucketId: bucketID,
+		VolumeId: volumeID,
+	}
 
-		createReq := &replication.CreateVolumeRequest{
-			VolumeId:     volumeID,
-			OsdAddresses: selectedOSDs,
-			CellId:       f.config.CellID,
-		}
-		_, err = f.replicationClient.CreateVolume(ctx, createReq)
-		if err != nil {
-			return "", fmt.Errorf("failed to create volume: %w", err)
+	stragglers, err := f.writeReplicas(ctx, putReq, volume)
+	if err != nil {
+		// Once cancelled, replica writes may still be in flight and the
+		// sweeper cleans up after them instead
+		if ctx.Err() == nil {
+			f.discardPut(ids[0], putReq, volume.OsdAddresses)
 		}
+		return "", err
 	}
 
-	getVolumeReq := &replication.GetVolumeRequest{VolumeId: volumeID}
-	getVolumeResp, err := f.replicationClient.GetVolume(ctx, getVolumeReq)
-	if err != nil || !getVolumeResp.Found {
-		return "", fmt.Errorf("failed to get volume info: %w", err)
+	indexed, err := f.commitPut(ctx, ids[0], block.Hash)
+	if err != nil {
+		return "", err
+	}
+	if indexed {
+		go f.reportStragglers(putReq, stragglers)
 	}
 
-	bucketID := uuid.New().String()
-
-	successCount := 0
-	for _, osdAddr := range getVolumeResp.OsdAddresses {
-		client, err := f.GetOSDClient(osdAddr)
-		if err != nil {
-			continue
-		}
+	return block.Hash, nil
+}
 
-		putReq := &osd.PutBlockRequest{
-			Hash:     block.Hash,
-			Data:     block.Data,
-			BucketId: bucketID,
-			VolumeId: volumeID,
+// Exists reports which of the given blocks are already stored and adds
+// owner's reference to each of them, exactly as a Put of the same data would.
+// A client can then skip uploading those blocks without the garbage collector
+// deleting them in between. Blocks being deleted are reported as missing, and
+// so are blocks the caller's tenant does not reference yet: knowing a hash is
+// not proof of holding the data, so those must be uploaded, and are then
+// deduplicated by Put.
+func (f *Frontend) Exists(ctx context.Context, hashes []string, owner string) ([]string, error) {
+	allowed, err := f.readable(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if allowed != nil {
+		owned := make([]string, 0, len(allowed))
+		for _, hash := range hashes {
+			if allowed[hash] {
+				owned = append(owned, hash)
+			}
 		}
+		hashes = owned
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
 
-		putResp, err := client.PutBlock(ctx, putReq)
-		if err == nil && putResp.Success {
-			successCount++
-		}
+	resp, err := f.blockIndexClient.AddRefs(ctx, &blockindex.AddRefsRequest{Hashes: hashes, Owner: owner, Tenant: tenantOf(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup blocks: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("failed to lookup blocks: %s", resp.Error)
 	}
 
-	if successCount < f.config.ReplicationFactor {
-		return "", fmt.Errorf("failed to replicate block: only %d/%d writes succeeded",
-			successCount, f.config.ReplicationFactor)
+	return resp.Found, nil
+}
+
+// beginPut records intents for blocks about to be written, so that replicas
+// of a Put that fails or never commits are found and removed by the sweeper
+func (f *Frontend) beginPut(ctx context.Context, intents []*blockindex.Intent) ([]int64, error) {
+	resp, err := f.blockIndexClient.BeginPut(ctx, &blockindex.BeginPutRequest{Intents: intents})
+	if err == nil && resp.Error != "" {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record put intent: %w", err)
 	}
+	if len(resp.Ids) != len(intents) {
+		return nil, fmt.Errorf("failed to record put intent: got %d ids for %d intents", len(resp.Ids), len(intents))
+	}
+	return resp.Ids, nil
+}
 
-	putEntryReq := &blockindex.PutEntryRequest{
-		Hash:     block.Hash,
-		CellId:   f.config.CellID,
-		BucketId: bucketID,
-		Checksum: block.Hash,
+// commitPut indexes a written block and adds the intent owner's reference to
+// it, reporting whether the index now points at the written replicas. A block
+// that was already indexed elsewhere, or is being deleted, leaves the intent
+// in place; the sweeper removes its surplus replicas once every write has
+// finished.
+func (f *Frontend) commitPut(ctx context.Context, id int64, hash string) (bool, error) {
+	resp, err := f.blockIndexClient.CommitPut(ctx, &blockindex.CommitPutRequest{Id: id, Hash: hash})
+	if err != nil {
+		return false, fmt.Errorf("block stored but index update failed: %w", err)
+	}
+	if resp.Deleting {
+		return false, fmt.Errorf("%w: %s", ErrBlockDeleting, hash)
 	}
+	if !resp.Success {
+		return false, fmt.Errorf("block stored but index update failed: %s", resp.Error)
+	}
+	return !resp.Duplicate, nil
+}
 
-	_, err = f.blockIndexClient.PutEntry(ctx, putEntryReq)
+// discardPut aborts the intent of a Put that will not be committed and
+// deletes its replicas. It runs after every replica write has finished. The
+// intent is aborted first, since a concurrent Put of the same block may have
+// been given the same bucket and committed it, and then the replicas are its
+// own and are kept. A delete that fails leaves an orphaned replica, which
+// takes space but is never read.
+func (f *Frontend) discardPut(id int64, req *osd.PutBlockRequest, osds []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.config.ReplicaTimeout)
+	defer cancel()
+
+	resp, err := f.blockIndexClient.AbortPut(ctx, &blockindex.AbortPutRequest{Id: id, Hash: req.Hash})
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("%s", resp.Error)
+	}
 	if err != nil {
-		return "", fmt.Errorf("block stored but index update failed: %w", err)
+		log.Printf("Failed to abort intent for block %s, leaving it for the sweeper: %v", req.Hash, err)
+		return
+	}
+	if resp.Indexed {
+		return
 	}
 
-	return block.Hash, nil
+	deleteReq := &osd.DeleteBlockRequest{
+		Hash:     req.Hash,
+		BucketId: req.BucketId,
+		VolumeId: req.VolumeId,
+	}
+	for _, osdAddr := range osds {
+		client, err := f.GetOSDClient(osdAddr)
+		if err != nil {
+			log.Printf("Failed to discard block %s: %v", req.Hash, err)
+			return
+		}
+		resp, err := client.DeleteBlock(ctx, deleteReq)
+		if err == nil && !resp.Success {
+			err = fmt.Errorf("%s", resp.Error)
+		}
+		if err != nil {
+			log.Printf("Failed to discard block %s from %s: %v", req.Hash, osdAddr, err)
+		}
+	}
 }
 
-// getHealthyOSDs gets list of healthy OSDs
-func (f *Frontend) getHealthyOSDs(ctx context.Context) []string {
-	return []string{"localhost:9090", "localhost:9095", "localhost:9096"}
+// reserveSpace asks the master for room for size bytes, returning the open
+// volume and the bucket within it that the write should go to
+func (f *Frontend) reserveSpace(ctx context.Context, size int64) (*replication.GetVolumeResponse, string, error) {
+	reserveReq := &master.ReserveSpaceRequest{
+		CellId:            f.config.CellID,
+		Size:              size,
+		ReplicationFactor: int32(f.config.ReplicationFactor),
+	}
+	reserveResp, err := f.masterClient.ReserveSpace(ctx, reserveReq)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to reserve space: %w", err)
+	}
+	if !reserveResp.Success {
+		return nil, "", fmt.Errorf("failed to reserve space: %s", reserveResp.Error)
+	}
+
+	volume := &replication.GetVolumeResponse{
+		Found:        true,
+		VolumeId:     reserveResp.VolumeId,
+		OsdAddresses: reserveResp.OsdAddresses,
+		State:        "open",
+	}
+	return volume, reserveResp.BucketId, nil
 }
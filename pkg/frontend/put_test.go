@@ -0,0 +1,71 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bharani/pkg/storage"
+	blockindexpb "bharani/proto/blockindex"
+)
+
+func TestPutExpectedHashAndExists(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	data := []byte("expected hash test block")
+	hash := storage.ComputeHash(data)
+	missing := storage.ComputeHash([]byte("never stored"))
+
+	if _, err := cluster.frontend.PutExpected(ctx, data, missing, "alice"); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("Expected ErrHashMismatch, got %v", err)
+	}
+	if _, err := cluster.frontend.Get(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Rejected block should not be stored, got %v", err)
+	}
+
+	if got, err := cluster.frontend.PutExpected(ctx, data, hash, "alice"); err != nil || got != hash {
+		t.Fatalf("Failed to put block with its own hash: %s %v", got, err)
+	}
+
+	existing, err := cluster.frontend.Exists(ctx, []string{hash, missing}, "bob")
+	if err != nil {
+		t.Fatalf("Failed to check blocks: %v", err)
+	}
+	if len(existing) != 1 || existing[0] != hash {
+		t.Fatalf("Expected only %s to exist, got %v", hash, existing)
+	}
+
+	// Exists referenced the block for bob, so it outlives alice's release
+	if released, err := cluster.frontend.Release(ctx, hash, "alice"); err != nil || !released {
+		t.Fatalf("Failed to release alice's reference: %v", err)
+	}
+	if released, err := cluster.frontend.Release(ctx, hash, "bob"); err != nil || !released {
+		t.Errorf("Expected Exists to reference the block for bob: %v", err)
+	}
+}
+
+func TestPutFailsWhenReferencingFails(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	data := []byte("unreferenced block")
+	hash := storage.ComputeHash(data)
+
+	// A failed lookup must not be mistaken for a missing block and written
+	// again in full
+	cluster.indexFault.Store(blockindexpb.BlockIndexService_AddRefs_FullMethodName)
+	if _, err := cluster.frontend.Put(ctx, data, "alice"); err == nil {
+		t.Fatal("Expected Put to fail when AddRefs fails")
+	}
+	for _, result := range cluster.frontend.PutBatch(ctx, [][]byte{data}, "alice") {
+		if result.Err == nil {
+			t.Fatal("Expected PutBatch to fail when AddRefs fails")
+		}
+	}
+	cluster.indexFault.Store("")
+
+	if _, err := cluster.frontend.Get(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Block should not be written when AddRefs fails, got %v", err)
+	}
+}
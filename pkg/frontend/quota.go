@@ -0,0 +1,127 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/quota"
+	"bharani/proto/blockindex"
+)
+
+// ErrQuotaExceeded is returned when a write would take the caller's tenant
+// over its byte or block quota
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// ErrRateLimited is returned when the caller's tenant is over its request or
+// bandwidth rate. The request may be retried after backing off.
+var ErrRateLimited = errors.New("tenant rate limit exceeded")
+
+// exhausted reports whether err is a quota or rate limit error, which the API
+// returns as codes.ResourceExhausted
+func exhausted(err error) bool {
+	return errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrRateLimited)
+}
+
+// Usage is a tenant's storage usage and quota. Zero maximums are unlimited.
+type Usage struct {
+	Tenant    string
+	Bytes     int64
+	Blocks    int64
+	MaxBytes  int64
+	MaxBlocks int64
+}
+
+// limitRequest takes a request from the caller's request rate. Requests
+// without an identity are not limited.
+func (f *Frontend) limitRequest(ctx context.Context) error {
+	id, ok := auth.IdentityFromContext(ctx)
+	if ok && !f.limiter.AllowRequest(id.Tenant) {
+		return fmt.Errorf("%w: too many requests", ErrRateLimited)
+	}
+	return nil
+}
+
+// limitBandwidth takes n bytes from the caller's bandwidth
+func (f *Frontend) limitBandwidth(ctx context.Context, n int64) error {
+	id, ok := auth.IdentityFromContext(ctx)
+	if ok && !f.limiter.AllowBytes(id.Tenant, n) {
+		return fmt.Errorf("%w: bandwidth", ErrRateLimited)
+	}
+	return nil
+}
+
+// checkQuota returns ErrQuotaExceeded if adding blocks totalling bytes to the
+// caller's usage would exceed its quota. Writes are counted as new data even
+// if the tenant already holds the blocks, and concurrent writes are checked
+// against the same usage, so a tenant may overshoot by its in-flight writes.
+func (f *Frontend) checkQuota(ctx context.Context, bytes, blocks int64) error {
+	id, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	limits := f.limiter.Limits(id.Tenant)
+	if !limits.HasQuota() {
+		return nil
+	}
+
+	usage, err := f.tenantUsage(ctx, id.Tenant)
+	if err != nil {
+		return err
+	}
+	if limits.MaxBytes > 0 && usage.Bytes+bytes > limits.MaxBytes {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, usage.Bytes, limits.MaxBytes)
+	}
+	if limits.MaxBlocks > 0 && usage.Blocks+blocks > limits.MaxBlocks {
+		return fmt.Errorf("%w: %d of %d blocks used", ErrQuotaExceeded, usage.Blocks, limits.MaxBlocks)
+	}
+	return nil
+}
+
+// GetUsage returns a tenant's usage and quota. An empty tenant means the
+// caller's own, and only admins may ask for other tenants.
+func (f *Frontend) GetUsage(ctx context.Context, tenant string) (*Usage, error) {
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		if tenant == "" {
+			tenant = id.Tenant
+		}
+		if tenant != id.Tenant && !id.Admin {
+			return nil, fmt.Errorf("%w: usage of tenant %s", ErrAccessDenied, tenant)
+		}
+	}
+
+	return f.tenantUsage(ctx, tenant)
+}
+
+// tenantUsage reads a tenant's usage from the index
+func (f *Frontend) tenantUsage(ctx context.Context, tenant string) (*Usage, error) {
+	resp, err := f.blockIndexClient.GetUsage(ctx, &blockindex.GetUsageRequest{Tenant: tenant})
+	if err == nil && resp.Error != "" {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	limits := f.limiter.Limits(tenant)
+	return &Usage{
+		Tenant:    tenant,
+		Bytes:     resp.Bytes,
+		Blocks:    resp.Blocks,
+		MaxBytes:  limits.MaxBytes,
+		MaxBlocks: limits.MaxBlocks,
+	}, nil
+}
+
+// newLimiter loads the per-tenant limits named by the config
+func newLimiter(limitsFile string) (*quota.Limiter, error) {
+	if limitsFile == "" {
+		return quota.NewLimiter(nil), nil
+	}
+	limits, err := quota.LoadLimits(limitsFile)
+	if err != nil {
+		return nil, err
+	}
+	return quota.NewLimiter(limits), nil
+}
@@ -0,0 +1,119 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bharani/pkg/auth"
+	"bharani/pkg/quota"
+	frontendpb "bharani/proto/frontend"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTenantQuota(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	f := cluster.frontend
+	f.limiter = quota.NewLimiter(map[string]quota.Limits{
+		"red":  {MaxBytes: 20, MaxBlocks: 2},
+		"blue": {Requests: 1},
+	})
+
+	red := auth.WithIdentity(context.Background(), &auth.Identity{Tenant: "red"})
+	blue := auth.WithIdentity(context.Background(), &auth.Identity{Tenant: "blue"})
+
+	first, err := f.Put(red, []byte("0123456789"), "doc")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	if _, err := f.Put(red, []byte("abcdefghij"), "doc"); err != nil {
+		t.Fatalf("Failed to put block up to the quota: %v", err)
+	}
+	if _, err := f.Put(red, []byte("x"), "doc"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	// Usage is charged once per block, and releasing the tenant's last
+	// reference frees its share
+	if _, err := f.Exists(red, []string{first}, "copy"); err != nil {
+		t.Fatalf("Failed to reference block: %v", err)
+	}
+	usage, err := f.GetUsage(red, "")
+	if err != nil || usage.Bytes != 20 || usage.Blocks != 2 || usage.MaxBytes != 20 {
+		t.Fatalf("Unexpected usage %+v (%v)", usage, err)
+	}
+	f.Release(red, first, "doc")
+	f.Release(red, first, "copy")
+	if _, err := f.Put(red, []byte("x"), "doc"); err != nil {
+		t.Errorf("Expected put after release to fit the quota: %v", err)
+	}
+	if _, err := f.GetUsage(red, "blue"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected another tenant's usage to be denied, got %v", err)
+	}
+
+	// Over gRPC, over-quota writes and rate limited calls are ResourceExhausted
+	authenticator := auth.NewAuthenticator(map[string]auth.Identity{
+		"red-key":  {Tenant: "red"},
+		"blue-key": {Tenant: "blue"},
+	}, nil)
+	addr, _ := serve(t, nil, func(s *grpc.Server) {
+		frontendpb.RegisterFrontendServiceServer(s, NewFrontendService(f))
+	}, authenticator.ServerOptions()...)
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := frontendpb.NewFrontendServiceClient(conn)
+	redCtx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, "red-key")
+	blueCtx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, "blue-key")
+
+	_, err = client.Put(redCtx, &frontendpb.PutRequest{Data: []byte("too much data"), Owner: "doc"})
+	if status.Code(err) != codes.ResourceExhausted || len(status.Convert(err).Details()) == 0 {
+		t.Errorf("Expected ResourceExhausted with a quota failure, got %v", err)
+	}
+	resp, err := client.GetUsage(redCtx, &frontendpb.GetUsageRequest{})
+	if err != nil || resp.Tenant != "red" || resp.Bytes != 11 || resp.MaxBlocks != 2 {
+		t.Errorf("Unexpected usage %v (%v)", resp, err)
+	}
+
+	if _, err := client.Put(blueCtx, &frontendpb.PutRequest{Data: []byte("blue"), Owner: "doc"}); err != nil {
+		t.Fatalf("Failed to put within the rate: %v", err)
+	}
+	_, err = client.Put(blueCtx, &frontendpb.PutRequest{Data: []byte("blue again"), Owner: "doc"})
+	if status.Code(err) != codes.ResourceExhausted || len(status.Convert(err).Details()) != 0 {
+		t.Errorf("Expected rate limited ResourceExhausted, got %v", err)
+	}
+	if _, err := f.Put(blue, []byte("in process"), "doc"); err != nil {
+		t.Errorf("Request rate should only be limited at the API: %v", err)
+	}
+}
+
+func TestStatVerifyChargesBandwidth(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	f := cluster.frontend
+	f.limiter = quota.NewLimiter(map[string]quota.Limits{
+		"red": {Bandwidth: 100},
+	})
+	red := auth.WithIdentity(context.Background(), &auth.Identity{Tenant: "red"})
+
+	hash, err := f.Put(red, make([]byte, 30), "doc")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+
+	// Verifying reads all three replicas, more than the bandwidth left after
+	// the Put, while a plain Stat reads none
+	if _, err := f.Stat(red, hash, true); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited verifying three replicas, got %v", err)
+	}
+	if _, err := f.Stat(red, hash, false); err != nil {
+		t.Errorf("Stat without verify should not be charged: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+
+	"bharani/pkg/storage"
+	"bharani/proto/blockindex"
+)
+
+// Release removes the reference owner holds, within the caller's tenant, to a
+// block and reports whether owner held one. Once a block has no references
+// left the garbage collector deletes it after the grace period. An owner holds
+// at most one reference to a block, so owners should name a single object
+// rather than a whole account.
+func (f *Frontend) Release(ctx context.Context, hash, owner string) (bool, error) {
+	resp, err := f.blockIndexClient.Release(ctx, &blockindex.ReleaseRequest{Hash: hash, Owner: owner, Tenant: tenantOf(ctx)})
+	if err != nil {
+		return false, fmt.Errorf("failed to release block: %w", err)
+	}
+	if resp.Error != "" {
+		return false, fmt.Errorf("failed to release block: %s", resp.Error)
+	}
+
+	return resp.Released, nil
+}
+
+// ReleaseFile releases owner's references to a file: the blocks and child
+// manifests it is made of, then the root manifest. The root goes last so an
+// interrupted release can be retried, and it returns the number of
+// references removed.
+func (f *Frontend) ReleaseFile(ctx context.Context, hash, owner string) (int, error) {
+	manifest, err := f.GetManifest(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	hashes := make([]string, 0)
+	if err := f.collectManifestHashes(ctx, manifest, &hashes); err != nil {
+		return 0, err
+	}
+	hashes = append(hashes, hash)
+
+	released := 0
+	seen := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+
+		ok, err := f.Release(ctx, h, owner)
+		if err != nil {
+			return released, err
+		}
+		if ok {
+			released++
+		}
+	}
+
+	return released, nil
+}
+
+// collectManifestHashes appends the hashes of every block a manifest refers
+// to, descending into child manifests
+func (f *Frontend) collectManifestHashes(ctx context.Context, manifest *storage.Manifest, hashes *[]string) error {
+	for _, entry := range manifest.Entries {
+		*hashes = append(*hashes, entry.Hash)
+		if !entry.Indirect {
+			continue
+		}
+
+		child, err := f.GetManifest(ctx, entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to resolve child manifest %s: %w", entry.Hash, err)
+		}
+		if err := f.collectManifestHashes(ctx, child, hashes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
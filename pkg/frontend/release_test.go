@@ -0,0 +1,48 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"testing"
+)
+
+func TestReleaseFile(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	data := make([]byte, 3*cluster.config.ChunkAvgSize)
+	rand.Read(data)
+
+	info, err := cluster.frontend.PutFile(ctx, bytes.NewReader(data), "file1")
+	if err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+
+	// The same content under another owner shares every block
+	if _, err := cluster.frontend.PutFile(ctx, bytes.NewReader(data), "file2"); err != nil {
+		t.Fatalf("Failed to put file again: %v", err)
+	}
+
+	released, err := cluster.frontend.ReleaseFile(ctx, info.Hash, "file1")
+	if err != nil {
+		t.Fatalf("Failed to release file: %v", err)
+	}
+	if released != info.BlockCount+1 {
+		t.Errorf("Expected %d references released, got %d", info.BlockCount+1, released)
+	}
+
+	// Releasing again is a no-op
+	if released, err = cluster.frontend.ReleaseFile(ctx, info.Hash, "file1"); err != nil || released != 0 {
+		t.Errorf("Expected nothing to release, got %d: %v", released, err)
+	}
+
+	var out bytes.Buffer
+	if err := cluster.frontend.GetFile(ctx, info.Hash, &out); err != nil || !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("File should still be readable through its other owner: %v", err)
+	}
+
+	if _, err := cluster.frontend.Release(ctx, info.Hash, ""); err == nil {
+		t.Error("Releasing without an owner should fail")
+	}
+}
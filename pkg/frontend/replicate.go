@@ -0,0 +1,141 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+
+	"bharani/proto/master"
+	"bharani/proto/osd"
+	"bharani/proto/replication"
+)
+
+// replicaResult is the outcome of writing a block to one OSD
+type replicaResult struct {
+	osdAddr string
+	err     error
+}
+
+// writeQuorum returns the number of replica acks a write needs
+func (f *Frontend) writeQuorum(replicas int) int {
+	quorum := f.config.WriteQuorum
+	if quorum <= 0 || quorum > replicas {
+		quorum = replicas
+	}
+	return quorum
+}
+
+// writeReplicas writes a block to every replica of a volume concurrently and
+// returns as soon as a write quorum has acknowledged. Each replica write has
+// its own deadline and keeps running after the quorum is reached. The
+// returned channel delivers the replicas that missed the block once every
+// write has finished, for reportStragglers to pass on after the commit.
+func (f *Frontend) writeReplicas(ctx context.Context, req *osd.PutBlockRequest, volume *replication.GetVolumeResponse) (<-chan []string, error) {
+	replicas := volume.OsdAddresses
+	quorum := f.writeQuorum(len(replicas))
+	if quorum == 0 {
+		return nil, fmt.Errorf("volume %s has no replicas", volume.VolumeId)
+	}
+
+	// Writes may outlive this call, so they must not share the caller's buffer
+	req = &osd.PutBlockRequest{
+		Hash:     req.Hash,
+		Data:     bytes.Clone(req.Data),
+		BucketId: req.BucketId,
+		VolumeId: req.VolumeId,
+	}
+
+	results := make(chan replicaResult, len(replicas))
+	for _, osdAddr := range replicas {
+		go func(osdAddr string) {
+			results <- replicaResult{osdAddr: osdAddr, err: f.writeReplica(ctx, osdAddr, req)}
+		}(osdAddr)
+	}
+
+	stragglers := make(chan []string, 1)
+	acked := 0
+	received := 0
+	missing := make([]string, 0)
+	for acked < quorum && received < len(replicas) {
+		select {
+		case result := <-results:
+			received++
+			if result.err == nil {
+				acked++
+			} else {
+				missing = append(missing, result.osdAddr)
+			}
+		case <-ctx.Done():
+			go collectStragglers(results, len(replicas)-received, missing, stragglers)
+			return nil, ctx.Err()
+		}
+	}
+
+	go collectStragglers(results, len(replicas)-received, missing, stragglers)
+	if acked < quorum {
+		return nil, fmt.Errorf("failed to replicate block: only %d/%d writes succeeded, need %d",
+			acked, len(replicas), quorum)
+	}
+	return stragglers, nil
+}
+
+// writeReplica writes a block to a single OSD under the per-replica deadline.
+// The write is detached from the caller's cancellation so that replicas still
+// in flight when the quorum is reached can complete.
+func (f *Frontend) writeReplica(ctx context.Context, osdAddr string, req *osd.PutBlockRequest) error {
+	client, err := f.GetOSDClient(osdAddr)
+	if err != nil {
+		return err
+	}
+
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), f.config.ReplicaTimeout)
+	defer cancel()
+
+	resp, err := client.PutBlock(writeCtx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	return nil
+}
+
+// collectStragglers waits for the remaining replica writes and sends every
+// replica that missed the block on stragglers, which is buffered so nobody
+// has to receive
+func collectStragglers(results <-chan replicaResult, remaining int, missing []string, stragglers chan<- []string) {
+	for i := 0; i < remaining; i++ {
+		if result := <-results; result.err != nil {
+			missing = append(missing, result.osdAddr)
+		}
+	}
+	stragglers <- missing
+}
+
+// reportStragglers waits for the replicas writeReplicas found missing the
+// block and reports them to the master for repair. It is only called once the
+// write is committed, so repairs are never requested for a block the index
+// does not hold.
+func (f *Frontend) reportStragglers(req *osd.PutBlockRequest, stragglers <-chan []string) {
+	if missing := <-stragglers; len(missing) > 0 {
+		f.reportUnderReplicated(req.Hash, req.VolumeId, req.BucketId, missing)
+	}
+}
+
+// reportUnderReplicated asks the master to copy a block to replicas that missed it
+func (f *Frontend) reportUnderReplicated(hash, volumeID, bucketID string, missing []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.config.ReplicaTimeout)
+	defer cancel()
+
+	_, err := f.masterClient.ReportUnderReplicated(ctx, &master.ReportUnderReplicatedRequest{
+		Hash:        hash,
+		VolumeId:    volumeID,
+		BucketId:    bucketID,
+		MissingOsds: missing,
+	})
+	if err != nil {
+		log.Printf("Failed to report under-replicated block %s: %v", hash, err)
+	}
+}
@@ -0,0 +1,97 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"bharani/proto/blockindex"
+	"bharani/proto/osd"
+)
+
+func TestPutSucceedsWithQuorumAndRepairs(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	// Allocate the volume while every OSD is up so all three are replicas
+	if _, err := cluster.frontend.Put(ctx, []byte("first block"), ""); err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+
+	down := cluster.osdAddrs()[0]
+	cluster.stopOSD(down)
+
+	data := []byte("written with one replica down")
+	hash, err := cluster.frontend.Put(ctx, data, "")
+	if err != nil {
+		t.Fatalf("Put should succeed with a write quorum: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cluster.master.PendingReplicaRepairs() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Missing replica was never reported to the master")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if repaired := cluster.master.RepairUnderReplicated(ctx); repaired != 0 {
+		t.Fatalf("Repair should wait for the target OSD, repaired %d", repaired)
+	}
+
+	// The master's connection to the restarted OSD reconnects with backoff
+	cluster.restartOSD(down)
+	deadline = time.Now().Add(10 * time.Second)
+	for cluster.master.RepairUnderReplicated(ctx) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Replica was not repaired after the OSD came back")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	entry, err := cluster.frontend.blockIndexClient.GetEntry(ctx, &blockindex.GetEntryRequest{Hash: hash})
+	if err != nil || !entry.Found {
+		t.Fatalf("Failed to look up block: %v", err)
+	}
+
+	client, err := cluster.frontend.GetOSDClient(down)
+	if err != nil {
+		t.Fatalf("Failed to get OSD client: %v", err)
+	}
+	resp, err := client.GetBlock(ctx, &osd.GetBlockRequest{Hash: hash, BucketId: entry.BucketId})
+	if err != nil || !resp.Success || !bytes.Equal(resp.Data, data) {
+		t.Fatalf("Repaired replica is missing or wrong: %v %+v", err, resp)
+	}
+}
+
+func TestUncommittedPutIsNotReportedForRepair(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	if _, err := cluster.frontend.Put(ctx, []byte("first block"), ""); err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	cluster.stopOSD(cluster.osdAddrs()[0])
+
+	cluster.indexFault.Store(blockindex.BlockIndexService_CommitPut_FullMethodName)
+	if _, err := cluster.frontend.Put(ctx, []byte("never committed"), ""); err == nil {
+		t.Fatal("Expected Put to fail when the commit fails")
+	}
+	cluster.indexFault.Store("")
+
+	// The committed write is reported; the failed one before it must not be
+	if _, err := cluster.frontend.Put(ctx, []byte("committed"), ""); err != nil {
+		t.Fatalf("Put should succeed with a write quorum: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for cluster.master.PendingReplicaRepairs() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Missing replica was never reported to the master")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pending := cluster.master.PendingReplicaRepairs(); pending != 1 {
+		t.Errorf("Expected only the committed block to be reported, got %d repairs", pending)
+	}
+}
@@ -0,0 +1,204 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bharani/proto/blockindex"
+	"bharani/proto/master"
+	"bharani/proto/osd"
+)
+
+// Replica check results reported by a verifying Stat
+const (
+	ReplicaOK          = "ok"
+	ReplicaMissing     = "missing"
+	ReplicaCorrupt     = "corrupt"
+	ReplicaUnreachable = "unreachable"
+)
+
+// BlockStat describes where a block is stored and the state of its replicas
+type BlockStat struct {
+	Hash       string
+	Size       int64 // 0 if unknown for blocks indexed before sizes were recorded
+	CellID     string
+	VolumeID   string
+	BucketID   string
+	Replicas   []ReplicaStat
+	CreatedAt  time.Time
+	VerifiedAt time.Time // Last verification that found every replica intact, zero if never
+}
+
+// ReplicaStat describes one replica of a block
+type ReplicaStat struct {
+	Address string
+	ZoneID  string
+	Healthy bool   // The master considers the OSD alive
+	Check   string // Result of reading the replica, set only when verifying
+}
+
+// Stat returns a block's metadata from the block index and replication table
+// without reading it. With verify set, every replica is read and checked
+// against the hash: corrupt and missing replicas are reported to the master
+// for repair, and if all replicas are intact the verification time is
+// recorded in the index. Verifying counts every replica read against the
+// tenant's bandwidth and fails with ErrRateLimited when it would exceed it.
+// It returns ErrNotFound for unindexed hashes, and like Get, ErrAccessDenied
+// for blocks the caller's tenant does not reference.
+func (f *Frontend) Stat(ctx context.Context, hash string, verify bool) (*BlockStat, error) {
+	if err := f.authorizeRead(ctx, hash); err != nil {
+		return nil, err
+	}
+
+	getEntryResp, err := f.blockIndexClient.GetEntry(ctx, &blockindex.GetEntryRequest{Hash: hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup block: %w", err)
+	}
+	if !getEntryResp.Found {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+
+	stat := &BlockStat{
+		Hash:      hash,
+		Size:      getEntryResp.Size,
+		CellID:    getEntryResp.CellId,
+		VolumeID:  getEntryResp.VolumeId,
+		BucketID:  getEntryResp.BucketId,
+		CreatedAt: time.Unix(getEntryResp.CreatedAt, 0),
+	}
+	if getEntryResp.VerifiedAt > 0 {
+		stat.VerifiedAt = time.Unix(getEntryResp.VerifiedAt, 0)
+	}
+
+	// Entries from before volumes were recorded have no known replicas until
+	// a read locates the block and writes its volume back to the index
+	if stat.VolumeID == "" {
+		return stat, nil
+	}
+
+	volume := f.newVolumeLookup().volume(ctx, stat.VolumeID)
+	if volume == nil {
+		return stat, nil
+	}
+
+	osds := f.osdStatuses(ctx, stat.CellID)
+	for _, addr := range volume.OsdAddresses {
+		replica := ReplicaStat{Address: addr}
+		if status, ok := osds[addr]; ok {
+			replica.ZoneID = status.ZoneId
+			replica.Healthy = status.Healthy
+		}
+		stat.Replicas = append(stat.Replicas, replica)
+	}
+
+	// The OSDs of an erasure-coded volume hold shards, which cannot be checked
+	// against the block hash one by one
+	if verify && volume.DataShards == 0 {
+		// Entries from before sizes were recorded are charged as the largest
+		// block they could be
+		size := stat.Size
+		if size == 0 {
+			size = f.config.MaxBlockSize
+		}
+		if err := f.limitBandwidth(ctx, size*int64(len(stat.Replicas))); err != nil {
+			return nil, err
+		}
+		f.verifyReplicas(ctx, stat)
+	}
+
+	return stat, nil
+}
+
+// osdStatuses returns the master's view of the OSDs in a cell, keyed by address
+func (f *Frontend) osdStatuses(ctx context.Context, cellID string) map[string]*master.OSDStatus {
+	statuses := make(map[string]*master.OSDStatus)
+
+	resp, err := f.masterClient.ListOSDs(ctx, &master.ListOSDsRequest{CellId: cellID})
+	if err != nil {
+		log.Printf("Failed to list OSDs: %v", err)
+		return statuses
+	}
+
+	for _, status := range resp.Osds {
+		statuses[status.Address] = status
+	}
+	return statuses
+}
+
+// verifyReplicas reads every replica of a block in parallel, records the
+// result on each ReplicaStat and acts on the findings
+func (f *Frontend) verifyReplicas(ctx context.Context, stat *BlockStat) {
+	req := &osd.GetBlockRequest{
+		Hash:     stat.Hash,
+		BucketId: stat.BucketID,
+		VolumeId: stat.VolumeID,
+	}
+
+	var (
+		wg   sync.WaitGroup
+		size int64
+		mu   sync.Mutex
+	)
+	for i := range stat.Replicas {
+		replica := &stat.Replicas[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := f.readReplica(ctx, replica.Address, req)
+			switch {
+			case result.err == nil:
+				replica.Check = ReplicaOK
+				mu.Lock()
+				size = int64(len(result.data))
+				mu.Unlock()
+			case result.corrupt:
+				replica.Check = ReplicaCorrupt
+			case result.failed:
+				replica.Check = ReplicaUnreachable
+			default:
+				// The OSD answered but does not have the block
+				replica.Check = ReplicaMissing
+			}
+		}()
+	}
+	wg.Wait()
+
+	missing := make([]string, 0)
+	intact := true
+	for _, replica := range stat.Replicas {
+		switch replica.Check {
+		case ReplicaCorrupt:
+			go f.reportCorruption(replica.Address, req)
+		case ReplicaMissing:
+			missing = append(missing, replica.Address)
+		}
+		intact = intact && replica.Check == ReplicaOK
+	}
+	if len(missing) > 0 {
+		go f.reportUnderReplicated(stat.Hash, stat.VolumeID, stat.BucketID, missing)
+	}
+
+	if !intact || len(stat.Replicas) == 0 {
+		return
+	}
+
+	now := time.Now()
+	resp, err := f.blockIndexClient.MarkVerified(ctx, &blockindex.MarkVerifiedRequest{
+		Hash:       stat.Hash,
+		Size:       size,
+		VerifiedAt: now.Unix(),
+	})
+	if err != nil || !resp.Success {
+		log.Printf("Failed to record verification of block %s: %v %s", stat.Hash, err, resp.GetError())
+		return
+	}
+
+	stat.VerifiedAt = time.Unix(now.Unix(), 0)
+	if stat.Size == 0 {
+		stat.Size = size
+	}
+}
@@ -0,0 +1,90 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStat(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	if _, err := cluster.frontend.Stat(ctx, "missing", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	data := []byte("block with metadata")
+	before := time.Now().Add(-time.Second)
+	hash, err := cluster.frontend.Put(ctx, data, "")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+
+	stat, err := cluster.frontend.Stat(ctx, hash, false)
+	if err != nil {
+		t.Fatalf("Failed to stat block: %v", err)
+	}
+	if stat.Size != int64(len(data)) || stat.VolumeID == "" || stat.BucketID == "" || stat.CellID != "cell1" {
+		t.Errorf("Unexpected stat %+v", stat)
+	}
+	if stat.CreatedAt.Before(before) || !stat.VerifiedAt.IsZero() {
+		t.Errorf("Unexpected times: created %v, verified %v", stat.CreatedAt, stat.VerifiedAt)
+	}
+	if len(stat.Replicas) != 3 {
+		t.Fatalf("Expected 3 replicas, got %d", len(stat.Replicas))
+	}
+	for _, replica := range stat.Replicas {
+		if !replica.Healthy || replica.ZoneID == "" || replica.Check != "" {
+			t.Errorf("Unexpected replica %+v", replica)
+		}
+	}
+
+	stat, err = cluster.frontend.Stat(ctx, hash, true)
+	if err != nil {
+		t.Fatalf("Failed to verify block: %v", err)
+	}
+	for _, replica := range stat.Replicas {
+		if replica.Check != ReplicaOK {
+			t.Errorf("Replica %s should verify, got %s", replica.Address, replica.Check)
+		}
+	}
+	if stat.VerifiedAt.IsZero() {
+		t.Fatal("A clean verification should be recorded")
+	}
+	verifiedAt := stat.VerifiedAt
+
+	// One corrupt and one stopped replica: both show up, and the last clean
+	// verification is kept
+	corrupt := stat.Replicas[0].Address
+	err = cluster.osdInstances[corrupt].PutBlock(ctx, hash, stat.BucketID, stat.VolumeID, []byte("bit rot"))
+	if err != nil {
+		t.Fatalf("Failed to corrupt replica: %v", err)
+	}
+	stopped := stat.Replicas[1].Address
+	cluster.stopOSD(stopped)
+
+	stat, err = cluster.frontend.Stat(ctx, hash, true)
+	if err != nil {
+		t.Fatalf("Failed to verify block: %v", err)
+	}
+	checks := make(map[string]string)
+	for _, replica := range stat.Replicas {
+		checks[replica.Address] = replica.Check
+	}
+	if checks[corrupt] != ReplicaCorrupt || checks[stopped] != ReplicaUnreachable || checks[stat.Replicas[2].Address] != ReplicaOK {
+		t.Errorf("Unexpected replica checks %v", checks)
+	}
+	if !stat.VerifiedAt.Equal(verifiedAt) {
+		t.Errorf("A failed verification should not update the verification time")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cluster.master.PendingReplicaRepairs() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cluster.master.PendingReplicaRepairs() == 0 {
+		t.Error("The corrupt replica should be queued for repair")
+	}
+}
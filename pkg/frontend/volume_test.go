@@ -0,0 +1,78 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"bharani/proto/blockindex"
+	"bharani/proto/master"
+)
+
+func TestPutFillsBucketsAndClosesVolumes(t *testing.T) {
+	cluster := newTestCluster(t, 3)
+	ctx := context.Background()
+
+	// Two 30KB blocks per bucket, two buckets per volume
+	cluster.config.BucketSize = 64 * 1024
+	cluster.config.VolumeSize = 128 * 1024
+
+	blocks := make([][]byte, 10)
+	entries := make([]*blockindex.GetEntryResponse, len(blocks))
+	for i := range blocks {
+		blocks[i] = bytes.Repeat([]byte(fmt.Sprintf("%d", i)), 30*1024)
+		hash, err := cluster.frontend.Put(ctx, blocks[i], "")
+		if err != nil {
+			t.Fatalf("Failed to put block %d: %v", i, err)
+		}
+
+		entries[i], err = cluster.frontend.blockIndexClient.GetEntry(ctx, &blockindex.GetEntryRequest{Hash: hash})
+		if err != nil || !entries[i].Found {
+			t.Fatalf("Failed to get entry %d: %v", i, err)
+		}
+
+		got, err := cluster.frontend.Get(ctx, hash)
+		if err != nil || !bytes.Equal(got, blocks[i]) {
+			t.Fatalf("Failed to read back block %d: %v", i, err)
+		}
+	}
+
+	for i := 0; i < len(blocks); i += 2 {
+		if i+1 < len(blocks) && entries[i].BucketId != entries[i+1].BucketId {
+			t.Errorf("Blocks %d and %d should share a bucket", i, i+1)
+		}
+		if i >= 2 && entries[i].BucketId == entries[i-2].BucketId {
+			t.Errorf("Block %d should start a new bucket", i)
+		}
+	}
+
+	for i := range blocks {
+		sameVolume := entries[i].VolumeId == entries[i/4*4].VolumeId
+		if !sameVolume {
+			t.Errorf("Block %d should be in the same volume as block %d", i, i/4*4)
+		}
+	}
+	if entries[0].VolumeId == entries[4].VolumeId || entries[4].VolumeId == entries[8].VolumeId {
+		t.Error("Full volumes should be replaced by new ones")
+	}
+
+	openResp, err := cluster.master.GetOpenVolumes(ctx, &master.GetOpenVolumesRequest{CellId: cluster.config.CellID})
+	if err != nil {
+		t.Fatalf("Failed to get open volumes: %v", err)
+	}
+	if len(openResp.VolumeIds) != 1 || openResp.VolumeIds[0] != entries[8].VolumeId {
+		t.Errorf("Only the newest volume should be open, got %v", openResp.VolumeIds)
+	}
+
+	usage := cluster.master.VolumeUsage()
+	if usage[entries[8].VolumeId] != 2*30*1024 {
+		t.Errorf("Expected 60KB used in the open volume, got %d", usage[entries[8].VolumeId])
+	}
+
+	// Closed volumes still serve reads
+	got, err := cluster.frontend.Get(ctx, entries[0].Checksum)
+	if err != nil || !bytes.Equal(got, blocks[0]) {
+		t.Errorf("Failed to read block from closed volume: %v", err)
+	}
+}
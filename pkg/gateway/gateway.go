@@ -0,0 +1,284 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"bharani/pkg/config"
+	"bharani/pkg/frontend"
+	"bharani/pkg/storage"
+)
+
+// Backend is the subset of the frontend the gateway serves requests from
+type Backend interface {
+	Put(ctx context.Context, data []byte, owner string) (string, error)
+	Get(ctx context.Context, hash string) ([]byte, error)
+	PutFile(ctx context.Context, r io.Reader, owner string) (*frontend.FileInfo, error)
+	GetManifest(ctx context.Context, hash string) (*storage.Manifest, error)
+	Release(ctx context.Context, hash, owner string) (bool, error)
+	ReleaseFile(ctx context.Context, hash, owner string) (int, error)
+}
+
+// Gateway exposes block and file storage over plain HTTP:
+//
+//	PUT      /blocks             store the request body as one block
+//	GET/HEAD /blocks/{hash}      read a block
+//	DELETE   /blocks/{hash}      release a reference to a block
+//	PUT      /files              store a streamed request body as a file
+//	GET/HEAD /files/{hash}       read a file by its manifest hash
+//	DELETE   /files/{hash}       release references to a file and its blocks
+//	GET      /manifests/{hash}   describe a file's blocks as JSON
+//
+// Writes and deletes take the reference owner from the owner query parameter;
+// writes without one are kept forever. Reads support Range and If-None-Match,
+// and the ETag of every object is its hash.
+type Gateway struct {
+	backend      Backend
+	maxBlockSize int64
+	mux          *http.ServeMux
+}
+
+// NewGateway creates a gateway serving from backend
+func NewGateway(backend Backend, cfg *config.Config) *Gateway {
+	g := &Gateway{
+		backend:      backend,
+		maxBlockSize: cfg.MaxBlockSize,
+		mux:          http.NewServeMux(),
+	}
+
+	g.mux.HandleFunc("PUT /blocks", g.putBlock)
+	g.mux.HandleFunc("GET /blocks/{hash}", g.getBlock)
+	g.mux.HandleFunc("DELETE /blocks/{hash}", g.releaseBlock)
+	g.mux.HandleFunc("PUT /files", g.putFile)
+	g.mux.HandleFunc("GET /files/{hash}", g.getFile)
+	g.mux.HandleFunc("DELETE /files/{hash}", g.releaseFile)
+	g.mux.HandleFunc("GET /manifests/{hash}", g.getManifest)
+
+	return g
+}
+
+// ServeHTTP implements http.Handler
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// putBlockResponse is the body returned by PUT /blocks
+type putBlockResponse struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// putFileResponse is the body returned by PUT /files
+type putFileResponse struct {
+	Hash       string `json:"hash"`
+	Size       int64  `json:"size"`
+	BlockCount int    `json:"block_count"`
+}
+
+// releaseResponse is the body returned by DELETE requests
+type releaseResponse struct {
+	Released int `json:"released"`
+}
+
+// putBlock stores the request body as a single block
+func (g *Gateway) putBlock(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, g.maxBlockSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("block exceeds max block size %d", g.maxBlockSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("failed to read body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(data) == 0 {
+		http.Error(w, "block data cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := g.backend.Put(r.Context(), data, r.URL.Query().Get("owner"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(hash))
+	writeJSON(w, http.StatusCreated, putBlockResponse{Hash: hash, Size: int64(len(data))})
+}
+
+// getBlock serves a block, honouring Range and conditional headers
+func (g *Gateway) getBlock(w http.ResponseWriter, r *http.Request) {
+	hash, ok := pathHash(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := g.backend.Get(r.Context(), hash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	serveObject(w, r, hash, bytes.NewReader(data))
+}
+
+// putFile stores a streamed request body as a chunked file
+func (g *Gateway) putFile(w http.ResponseWriter, r *http.Request) {
+	info, err := g.backend.PutFile(r.Context(), r.Body, r.URL.Query().Get("owner"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(info.Hash))
+	writeJSON(w, http.StatusCreated, putFileResponse{
+		Hash:       info.Hash,
+		Size:       info.Size,
+		BlockCount: info.BlockCount,
+	})
+}
+
+// getFile serves a file by its manifest hash. Only the blocks overlapping
+// the requested range are fetched, one at a time as the response is written.
+func (g *Gateway) getFile(w http.ResponseWriter, r *http.Request) {
+	hash, ok := pathHash(w, r)
+	if !ok {
+		return
+	}
+
+	reader, err := NewFileReader(r.Context(), g.backend, hash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	serveObject(w, r, hash, reader)
+}
+
+// releaseBlock drops the owner's reference to a block
+func (g *Gateway) releaseBlock(w http.ResponseWriter, r *http.Request) {
+	hash, owner, ok := releaseParams(w, r)
+	if !ok {
+		return
+	}
+
+	released, err := g.backend.Release(r.Context(), hash, owner)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := releaseResponse{}
+	if released {
+		resp.Released = 1
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// releaseFile drops the owner's references to a file's manifests and blocks
+func (g *Gateway) releaseFile(w http.ResponseWriter, r *http.Request) {
+	hash, owner, ok := releaseParams(w, r)
+	if !ok {
+		return
+	}
+
+	released, err := g.backend.ReleaseFile(r.Context(), hash, owner)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, releaseResponse{Released: released})
+}
+
+// releaseParams extracts the hash and the required owner of a DELETE request
+func releaseParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	hash, ok := pathHash(w, r)
+	if !ok {
+		return "", "", false
+	}
+
+	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		http.Error(w, "owner query parameter is required", http.StatusBadRequest)
+		return "", "", false
+	}
+	return hash, owner, true
+}
+
+// getManifest returns a file's manifest as JSON
+func (g *Gateway) getManifest(w http.ResponseWriter, r *http.Request) {
+	hash, ok := pathHash(w, r)
+	if !ok {
+		return
+	}
+
+	manifest, err := g.backend.GetManifest(r.Context(), hash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(hash))
+	writeJSON(w, http.StatusOK, manifest)
+}
+
+// serveObject writes immutable content addressed by hash
+func serveObject(w http.ResponseWriter, r *http.Request, hash string, content io.ReadSeeker) {
+	w.Header().Set("ETag", etag(hash))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", time.Time{}, content)
+}
+
+// pathHash extracts and validates the {hash} path parameter
+func pathHash(w http.ResponseWriter, r *http.Request) (string, bool) {
+	hash := r.PathValue("hash")
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != 32 {
+		http.Error(w, "hash must be 64 hex characters", http.StatusBadRequest)
+		return "", false
+	}
+	return hash, true
+}
+
+// etag formats a hash as a strong entity tag
+func etag(hash string) string {
+	return `"` + hash + `"`
+}
+
+// writeError maps a backend error to an HTTP status
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, frontend.ErrNotFound), errors.Is(err, storage.ErrNotManifest):
+		status = http.StatusNotFound
+	case errors.Is(err, frontend.ErrAccessDenied):
+		status = http.StatusForbidden
+	case errors.Is(err, frontend.ErrQuotaExceeded):
+		status = http.StatusInsufficientStorage
+	case errors.Is(err, frontend.ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, frontend.ErrBlockDeleting), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusServiceUnavailable
+	}
+
+	http.Error(w, err.Error(), status)
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
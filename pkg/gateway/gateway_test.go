@@ -0,0 +1,308 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"bharani/pkg/config"
+	"bharani/pkg/frontend"
+	"bharani/pkg/storage"
+)
+
+// memBackend stores blocks in memory and splits files into fixed-size blocks,
+// putting the first half of them behind a child manifest
+type memBackend struct {
+	blocks map[string][]byte
+	refs   map[string]map[string]bool
+	gets   int
+	mu     sync.Mutex
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{blocks: make(map[string][]byte), refs: make(map[string]map[string]bool)}
+}
+
+func (b *memBackend) Put(ctx context.Context, data []byte, owner string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hash := storage.ComputeHash(data)
+	b.blocks[hash] = bytes.Clone(data)
+	if b.refs[hash] == nil {
+		b.refs[hash] = make(map[string]bool)
+	}
+	b.refs[hash][owner] = true
+	return hash, nil
+}
+
+func (b *memBackend) Release(ctx context.Context, hash, owner string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	released := b.refs[hash][owner]
+	delete(b.refs[hash], owner)
+	return released, nil
+}
+
+func (b *memBackend) ReleaseFile(ctx context.Context, hash, owner string) (int, error) {
+	manifest, err := b.GetManifest(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, h := range append([]string{hash}, manifest.Entries[0].Hash) {
+		if ok, _ := b.Release(ctx, h, owner); ok {
+			released++
+		}
+	}
+	return released, nil
+}
+
+func (b *memBackend) Get(ctx context.Context, hash string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.gets++
+	data, ok := b.blocks[hash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", frontend.ErrNotFound, hash)
+	}
+	return data, nil
+}
+
+func (b *memBackend) PutFile(ctx context.Context, r io.Reader, owner string) (*frontend.FileInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]storage.ManifestEntry, 0)
+	for start := 0; start < len(data); start += 5 {
+		chunk := data[start:min(start+5, len(data))]
+		hash, _ := b.Put(ctx, chunk, owner)
+		entries = append(entries, storage.ManifestEntry{Hash: hash, Size: int64(len(chunk))})
+	}
+
+	half := len(entries) / 2
+	child, _ := storage.NewManifest(entries[:half], "").Encode()
+	childHash, _ := b.Put(ctx, child, owner)
+	childEntry := storage.ManifestEntry{Hash: childHash, Size: int64(half * 5), Indirect: true}
+
+	root, _ := storage.NewManifest(append([]storage.ManifestEntry{childEntry}, entries[half:]...), storage.ComputeHash(data)).Encode()
+	hash, _ := b.Put(ctx, root, owner)
+	return &frontend.FileInfo{Hash: hash, Size: int64(len(data)), BlockCount: len(entries)}, nil
+}
+
+func (b *memBackend) GetManifest(ctx context.Context, hash string) (*storage.Manifest, error) {
+	data, err := b.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return storage.DecodeManifest(data)
+}
+
+func newTestGateway(t *testing.T) (*httptest.Server, *memBackend) {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.MaxBlockSize = 64
+
+	backend := newMemBackend()
+	server := httptest.NewServer(NewGateway(backend, cfg))
+	t.Cleanup(server.Close)
+	return server, backend
+}
+
+func request(t *testing.T, method, url string, body io.Reader, header map[string]string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return resp, data
+}
+
+func TestBlocks(t *testing.T) {
+	server, _ := newTestGateway(t)
+	data := []byte("hello, gateway")
+
+	resp, body := request(t, http.MethodPut, server.URL+"/blocks", bytes.NewReader(data), nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT /blocks returned %d: %s", resp.StatusCode, body)
+	}
+	var put putBlockResponse
+	if err := json.Unmarshal(body, &put); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if put.Hash != storage.ComputeHash(data) {
+		t.Fatalf("Unexpected hash %s", put.Hash)
+	}
+
+	url := server.URL + "/blocks/" + put.Hash
+	resp, body = request(t, http.MethodGet, url, nil, nil)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("GET returned %d: %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("ETag") != `"`+put.Hash+`"` {
+		t.Errorf("ETag should be the hash, got %s", resp.Header.Get("ETag"))
+	}
+
+	resp, body = request(t, http.MethodHead, url, nil, nil)
+	if resp.StatusCode != http.StatusOK || len(body) != 0 || resp.ContentLength != int64(len(data)) {
+		t.Errorf("HEAD returned %d with length %d", resp.StatusCode, resp.ContentLength)
+	}
+
+	resp, body = request(t, http.MethodGet, url, nil, map[string]string{"Range": "bytes=7-13"})
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "gateway" {
+		t.Errorf("Range GET returned %d: %q", resp.StatusCode, body)
+	}
+
+	resp, _ = request(t, http.MethodGet, url, nil, map[string]string{"If-None-Match": `"` + put.Hash + `"`})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Conditional GET returned %d", resp.StatusCode)
+	}
+
+	missing := storage.ComputeHash([]byte("missing"))
+	if resp, _ = request(t, http.MethodGet, server.URL+"/blocks/"+missing, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Missing block returned %d", resp.StatusCode)
+	}
+	if resp, _ = request(t, http.MethodGet, server.URL+"/blocks/nothex", nil, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid hash returned %d", resp.StatusCode)
+	}
+
+	large := bytes.Repeat([]byte("x"), 65)
+	if resp, _ = request(t, http.MethodPut, server.URL+"/blocks", bytes.NewReader(large), nil); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Oversized block returned %d", resp.StatusCode)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	server, backend := newTestGateway(t)
+	data := []byte(strings.Repeat("0123456789", 10))
+
+	// io.MultiReader hides the length so the body is sent chunked
+	resp, body := request(t, http.MethodPut, server.URL+"/files", io.MultiReader(bytes.NewReader(data)), nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT /files returned %d: %s", resp.StatusCode, body)
+	}
+	var put putFileResponse
+	if err := json.Unmarshal(body, &put); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if put.Size != int64(len(data)) || put.BlockCount != 20 {
+		t.Errorf("Unexpected file info %+v", put)
+	}
+
+	url := server.URL + "/files/" + put.Hash
+	resp, body = request(t, http.MethodGet, url, nil, nil)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("GET returned %d: %q", resp.StatusCode, body)
+	}
+
+	// A range spanning the child manifest and the root's own blocks
+	backend.gets = 0
+	resp, body = request(t, http.MethodGet, url, nil, map[string]string{"Range": "bytes=42-61"})
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[42:62]) {
+		t.Fatalf("Range GET returned %d: %q", resp.StatusCode, body)
+	}
+	// Root and child manifests plus blocks 8 through 12
+	if backend.gets != 2+5 {
+		t.Errorf("Range read should fetch only overlapping blocks, made %d gets", backend.gets)
+	}
+
+	resp, _ = request(t, http.MethodHead, url, nil, nil)
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len(data)) {
+		t.Errorf("HEAD returned %d with length %d", resp.StatusCode, resp.ContentLength)
+	}
+
+	resp, body = request(t, http.MethodGet, server.URL+"/manifests/"+put.Hash, nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /manifests returned %d: %s", resp.StatusCode, body)
+	}
+	var manifest storage.Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+	if manifest.TotalSize != int64(len(data)) || !manifest.Entries[0].Indirect {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+
+	block := manifest.Entries[1].Hash
+	if resp, _ = request(t, http.MethodGet, server.URL+"/files/"+block, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Reading a plain block as a file returned %d", resp.StatusCode)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	server, backend := newTestGateway(t)
+	data := []byte("owned block")
+
+	resp, body := request(t, http.MethodPut, server.URL+"/blocks?owner=alice", bytes.NewReader(data), nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT /blocks returned %d: %s", resp.StatusCode, body)
+	}
+	hash := storage.ComputeHash(data)
+	if !backend.refs[hash]["alice"] {
+		t.Fatal("The block should be referenced by the owner")
+	}
+
+	url := server.URL + "/blocks/" + hash
+	if resp, _ = request(t, http.MethodDelete, url, nil, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("DELETE without owner returned %d", resp.StatusCode)
+	}
+
+	for _, want := range []int{1, 0} {
+		resp, body = request(t, http.MethodDelete, url+"?owner=alice", nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("DELETE returned %d: %s", resp.StatusCode, body)
+		}
+		var release releaseResponse
+		if err := json.Unmarshal(body, &release); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if release.Released != want {
+			t.Errorf("Expected %d references released, got %d", want, release.Released)
+		}
+	}
+
+	resp, body = request(t, http.MethodPut, server.URL+"/files?owner=bob", bytes.NewReader([]byte(strings.Repeat("a", 20))), nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT /files returned %d: %s", resp.StatusCode, body)
+	}
+	var put putFileResponse
+	if err := json.Unmarshal(body, &put); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	resp, body = request(t, http.MethodDelete, server.URL+"/files/"+put.Hash+"?owner=bob", nil, nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"released":2`) {
+		t.Errorf("DELETE /files returned %d: %s", resp.StatusCode, body)
+	}
+	if backend.refs[put.Hash]["bob"] {
+		t.Error("The file's reference should be released")
+	}
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"bharani/pkg/storage"
+)
+
+// FileReader is an io.ReadSeeker over a file stored as a manifest of blocks.
+// The manifest tree is resolved up front; blocks are fetched lazily as reads
+// reach them, so a range request only touches the blocks it overlaps.
+type FileReader struct {
+	ctx     context.Context
+	backend Backend
+	blocks  []storage.ManifestEntry // Leaf blocks in file order
+	offsets []int64                 // File offset of the first byte of each block
+	size    int64
+	pos     int64
+
+	current     int // Index of the block held in currentData, or -1
+	currentData []byte
+}
+
+// NewFileReader resolves the manifest stored under hash into a reader
+func NewFileReader(ctx context.Context, backend Backend, hash string) (*FileReader, error) {
+	manifest, err := backend.GetManifest(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileReader{
+		ctx:     ctx,
+		backend: backend,
+		size:    manifest.TotalSize,
+		current: -1,
+	}
+	if err := r.addBlocks(manifest); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// addBlocks appends the leaf blocks of a manifest, descending into child manifests
+func (r *FileReader) addBlocks(manifest *storage.Manifest) error {
+	for _, entry := range manifest.Entries {
+		if entry.Indirect {
+			child, err := r.backend.GetManifest(r.ctx, entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to resolve child manifest %s: %w", entry.Hash, err)
+			}
+			if child.TotalSize != entry.Size {
+				return fmt.Errorf("child manifest %s covers %d bytes, expected %d", entry.Hash, child.TotalSize, entry.Size)
+			}
+			if err := r.addBlocks(child); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var offset int64
+		if n := len(r.blocks); n > 0 {
+			offset = r.offsets[n-1] + r.blocks[n-1].Size
+		}
+		r.blocks = append(r.blocks, entry)
+		r.offsets = append(r.offsets, offset)
+	}
+
+	return nil
+}
+
+// Read implements io.Reader
+func (r *FileReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	// The last block starting at or before pos holds it
+	i := sort.Search(len(r.offsets), func(i int) bool { return r.offsets[i] > r.pos }) - 1
+	if err := r.load(i); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, r.currentData[r.pos-r.offsets[i]:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// load fetches block i unless it is already held
+func (r *FileReader) load(i int) error {
+	if r.current == i {
+		return nil
+	}
+
+	entry := r.blocks[i]
+	data, err := r.backend.Get(r.ctx, entry.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to get block %d: %w", i+1, err)
+	}
+	if int64(len(data)) != entry.Size {
+		return fmt.Errorf("block %s has %d bytes, manifest expects %d", entry.Hash, len(data), entry.Size)
+	}
+
+	r.current = i
+	r.currentData = data
+	return nil
+}
+
+// Seek implements io.Seeker
+func (r *FileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.pos = offset
+	return offset, nil
+}
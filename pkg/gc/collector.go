@@ -0,0 +1,531 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"bharani/pkg/config"
+	"bharani/pkg/shards"
+	"bharani/pkg/storage"
+	"bharani/proto/blockindex"
+	"bharani/proto/master"
+	"bharani/proto/osd"
+	"bharani/proto/replication"
+
+	"google.golang.org/grpc"
+)
+
+// listBatchSize bounds the number of index entries fetched per call
+const listBatchSize = 500
+
+// Collector deletes blocks that have had no references for the grace period,
+// compacts closed volumes that hold little live data, and sweeps the intents
+// of Puts that never committed.
+//
+// Deletion is a three step protocol against the block index: a block is
+// claimed with MarkDeleting, which only succeeds while it is still
+// unreferenced, then removed from every OSD of its volume, and finally its
+// entry is removed. A claimed block can no longer gain references, so a
+// concurrent Put of the same hash is told to retry instead of deduplicating
+// against data that is about to disappear. If the collector stops part way,
+// the next pass resumes every block still marked as deleting.
+type Collector struct {
+	config            *config.Config
+	blockIndexClient  blockindex.BlockIndexServiceClient
+	replicationClient replication.ReplicationTableServiceClient
+	masterClient      master.MasterServiceClient
+	osdClients        map[string]osd.OSDServiceClient
+	dialOption        grpc.DialOption
+	now               func() time.Time
+	mu                sync.Mutex
+}
+
+// Stats summarises one collection pass
+type Stats struct {
+	BlocksDeleted    int
+	BytesFreed       int64
+	BlocksRelocated  int
+	VolumesDeleted   int
+	IntentsCommitted int
+	IntentsAborted   int
+	Errors           int
+}
+
+// NewCollector creates a collector for the cell in cfg
+func NewCollector(cfg *config.Config, blockIndexAddr, replicationAddr, masterAddr string) (*Collector, error) {
+	dialOption, err := cfg.TLS.DialOption()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+
+	replicationConn, err := grpc.NewClient(replicationAddr, dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to replication table: %w", err)
+	}
+
+	masterConn, err := grpc.NewClient(masterAddr, dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to master: %w", err)
+	}
+	masterClient := master.NewMasterServiceClient(masterConn)
+
+	return &Collector{
+		config:            cfg,
+		blockIndexClient:  shards.NewClient(blockIndexAddr, masterClient, dialOption),
+		replicationClient: replication.NewReplicationTableServiceClient(replicationConn),
+		masterClient:      masterClient,
+		osdClients:        make(map[string]osd.OSDServiceClient),
+		dialOption:        dialOption,
+		now:               time.Now,
+	}, nil
+}
+
+// getOSDClient gets or creates a gRPC client for an OSD
+func (c *Collector) getOSDClient(osdAddress string) (osd.OSDServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, exists := c.osdClients[osdAddress]; exists {
+		return client, nil
+	}
+
+	conn, err := grpc.NewClient(osdAddress, c.dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to OSD %s: %w", osdAddress, err)
+	}
+
+	client := osd.NewOSDServiceClient(conn)
+	c.osdClients[osdAddress] = client
+	return client, nil
+}
+
+// Run makes a collection pass every interval until ctx is cancelled
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := c.RunOnce(ctx)
+			if err != nil {
+				log.Printf("Garbage collection failed: %v", err)
+				continue
+			}
+			log.Printf("Garbage collection: deleted %d blocks (%d bytes), relocated %d, deleted %d volumes, committed %d and aborted %d intents, %d errors",
+				stats.BlocksDeleted, stats.BytesFreed, stats.BlocksRelocated, stats.VolumesDeleted,
+				stats.IntentsCommitted, stats.IntentsAborted, stats.Errors)
+		}
+	}
+}
+
+// RunOnce sweeps stale put intents, finishes deletions left over from an
+// earlier pass, deletes blocks that have been unreferenced for longer than the
+// grace period, and then compacts sparse closed volumes. Failures on
+// individual blocks are logged, counted in the stats and retried on the next
+// pass.
+func (c *Collector) RunOnce(ctx context.Context) (*Stats, error) {
+	stats := &Stats{}
+	cutoff := c.now().Add(-c.config.GCGracePeriod)
+
+	if err := c.sweepIntents(ctx, stats); err != nil {
+		return stats, err
+	}
+
+	err := c.drain(ctx, stats, func() (*blockindex.ListEntriesResponse, error) {
+		return c.blockIndexClient.ListDeleting(ctx, &blockindex.ListDeletingRequest{Limit: listBatchSize})
+	}, func(entry *blockindex.Entry) (bool, error) {
+		return true, c.deleteBlock(ctx, entry)
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	err = c.drain(ctx, stats, func() (*blockindex.ListEntriesResponse, error) {
+		return c.blockIndexClient.ListCollectable(ctx, &blockindex.ListCollectableRequest{
+			UnreferencedBefore: cutoff.Unix(),
+			Limit:              listBatchSize,
+		})
+	}, func(entry *blockindex.Entry) (bool, error) {
+		resp, err := c.blockIndexClient.MarkDeleting(ctx, &blockindex.MarkDeletingRequest{
+			Hash:               entry.Hash,
+			UnreferencedBefore: cutoff.Unix(),
+		})
+		if err == nil && resp.Error != "" {
+			err = fmt.Errorf("%s", resp.Error)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to claim block: %w", err)
+		}
+		if !resp.Marked {
+			// Referenced again since it was listed
+			return false, nil
+		}
+		return true, c.deleteBlock(ctx, entry)
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	if err := c.compact(ctx, cutoff, stats); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// drain repeatedly lists entries and deletes them until a listing comes back
+// short or a whole batch fails, so blocks that keep failing end the pass
+// rather than being retried forever
+func (c *Collector) drain(ctx context.Context, stats *Stats, list func() (*blockindex.ListEntriesResponse, error), del func(*blockindex.Entry) (bool, error)) error {
+	for {
+		resp, err := list()
+		if err == nil && resp.Error != "" {
+			err = fmt.Errorf("%s", resp.Error)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list blocks: %w", err)
+		}
+
+		progress := false
+		for _, entry := range resp.Entries {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			deleted, err := del(entry)
+			if err != nil {
+				log.Printf("Failed to delete block %s: %v", entry.Hash, err)
+				stats.Errors++
+				continue
+			}
+			progress = true
+			if deleted {
+				stats.BlocksDeleted++
+				stats.BytesFreed += entry.Size
+			}
+		}
+
+		if len(resp.Entries) < listBatchSize || !progress {
+			return nil
+		}
+	}
+}
+
+// deleteBlock removes a block claimed for deletion from every OSD that may
+// hold it and then removes its index entry. If any OSD the master still
+// reports healthy fails, the entry stays marked as deleting and the next pass
+// tries again.
+func (c *Collector) deleteBlock(ctx context.Context, entry *blockindex.Entry) error {
+	osds, err := c.blockOSDs(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	if err := c.deleteReplicas(ctx, entry, osds); err != nil {
+		return err
+	}
+
+	resp, err := c.blockIndexClient.RemoveEntry(ctx, &blockindex.RemoveEntryRequest{Hash: entry.Hash})
+	if err != nil {
+		return fmt.Errorf("failed to remove entry: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to remove entry: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// blockOSDs returns the OSDs that may hold a block: the replicas of its
+// volume, or every OSD of the cell if the volume is unknown
+func (c *Collector) blockOSDs(ctx context.Context, entry *blockindex.Entry) ([]string, error) {
+	if entry.VolumeId != "" {
+		resp, err := c.replicationClient.GetVolume(ctx, &replication.GetVolumeRequest{VolumeId: entry.VolumeId})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get volume: %w", err)
+		}
+		if resp.Found {
+			return resp.OsdAddresses, nil
+		}
+	}
+
+	resp, err := c.masterClient.ListOSDs(ctx, &master.ListOSDsRequest{CellId: entry.CellId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OSDs: %w", err)
+	}
+
+	osds := make([]string, 0, len(resp.Osds))
+	for _, status := range resp.Osds {
+		osds = append(osds, status.Address)
+	}
+	return osds, nil
+}
+
+// deleteReplicas deletes a block's copies from the given OSDs. Failures on
+// OSDs that the master no longer lists or reports unhealthy are logged and
+// skipped, since a dead or removed OSD would otherwise hold up the deletion
+// forever. A copy left on such an OSD is orphaned: it takes space but no
+// index entry points at it.
+func (c *Collector) deleteReplicas(ctx context.Context, entry *blockindex.Entry, osds []string) error {
+	req := &osd.DeleteBlockRequest{
+		Hash:     entry.Hash,
+		BucketId: entry.BucketId,
+		VolumeId: entry.VolumeId,
+	}
+
+	failed := make(map[string]error)
+	for _, osdAddr := range osds {
+		if err := c.deleteReplica(ctx, osdAddr, req); err != nil {
+			failed[osdAddr] = err
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	resp, err := c.masterClient.ListOSDs(ctx, &master.ListOSDsRequest{CellId: entry.CellId})
+	if err != nil {
+		return fmt.Errorf("failed to list OSDs: %w", err)
+	}
+	healthy := make(map[string]bool, len(resp.Osds))
+	for _, status := range resp.Osds {
+		healthy[status.Address] = status.Healthy
+	}
+
+	for _, osdAddr := range osds {
+		if err := failed[osdAddr]; err != nil && healthy[osdAddr] {
+			return err
+		}
+	}
+	for osdAddr, err := range failed {
+		log.Printf("Skipping block %s on unavailable OSD %s: %v", entry.Hash, osdAddr, err)
+	}
+	return nil
+}
+
+// deleteReplica deletes a block's copy from one OSD
+func (c *Collector) deleteReplica(ctx context.Context, osdAddr string, req *osd.DeleteBlockRequest) error {
+	client, err := c.getOSDClient(osdAddr)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.DeleteBlock(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to delete block from %s: %w", osdAddr, err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to delete block from %s: %s", osdAddr, resp.Error)
+	}
+	return nil
+}
+
+// compact moves the live blocks out of closed volumes that hold less than
+// CompactThreshold of VolumeSize and deletes the emptied volumes. Volumes must
+// have been closed before cutoff so that no write reserved in them is still
+// in flight. Compaction is skipped while the cell has entries without a
+// recorded volume, since those may live in any volume.
+func (c *Collector) compact(ctx context.Context, cutoff time.Time, stats *Stats) error {
+	usageResp, err := c.blockIndexClient.GetVolumeUsage(ctx, &blockindex.GetVolumeUsageRequest{CellId: c.config.CellID})
+	if err == nil && usageResp.Error != "" {
+		err = fmt.Errorf("%s", usageResp.Error)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get volume usage: %w", err)
+	}
+	if usageResp.Unlocated > 0 {
+		log.Printf("Skipping compaction: %d blocks have no recorded volume", usageResp.Unlocated)
+		return nil
+	}
+
+	liveBytes := make(map[string]int64, len(usageResp.Volumes))
+	for _, usage := range usageResp.Volumes {
+		liveBytes[usage.VolumeId] = usage.LiveBytes
+	}
+
+	listResp, err := c.replicationClient.ListVolumes(ctx, &replication.ListVolumesRequest{CellId: c.config.CellID})
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	threshold := int64(c.config.CompactThreshold * float64(c.config.VolumeSize))
+	for _, volumeID := range listResp.VolumeIds {
+		if liveBytes[volumeID] >= threshold {
+			continue
+		}
+
+		volume, err := c.replicationClient.GetVolume(ctx, &replication.GetVolumeRequest{VolumeId: volumeID})
+		if err != nil || !volume.Found {
+			continue
+		}
+		if volume.State != "closed" || time.Unix(volume.UpdatedAt, 0).After(cutoff) {
+			continue
+		}
+
+		if err := c.compactVolume(ctx, volume, stats); err != nil {
+			log.Printf("Failed to compact volume %s: %v", volumeID, err)
+			stats.Errors++
+		}
+	}
+
+	return nil
+}
+
+// compactVolume relocates every live block of a volume and then deletes it
+func (c *Collector) compactVolume(ctx context.Context, volume *replication.GetVolumeResponse, stats *Stats) error {
+	for {
+		resp, err := c.blockIndexClient.ListVolumeEntries(ctx, &blockindex.ListVolumeEntriesRequest{
+			VolumeId: volume.VolumeId,
+			Limit:    listBatchSize,
+		})
+		if err == nil && resp.Error != "" {
+			err = fmt.Errorf("%s", resp.Error)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list volume entries: %w", err)
+		}
+		if len(resp.Entries) == 0 {
+			break
+		}
+
+		for _, entry := range resp.Entries {
+			if err := c.relocate(ctx, entry, volume); err != nil {
+				// Leave the volume in place; the next pass tries again
+				return fmt.Errorf("failed to relocate block %s: %w", entry.Hash, err)
+			}
+			stats.BlocksRelocated++
+		}
+	}
+
+	resp, err := c.replicationClient.DeleteVolume(ctx, &replication.DeleteVolumeRequest{VolumeId: volume.VolumeId})
+	if err != nil {
+		return fmt.Errorf("failed to delete volume: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to delete volume: %s", resp.Error)
+	}
+
+	stats.VolumesDeleted++
+	return nil
+}
+
+// relocate copies a block to every replica of an open volume, points its
+// index entry at the new volume and deletes the old copies.
+//
+// The block keeps its bucket ID, so a reader holding the old entry misses it
+// in the old volume and finds it by scanning the cell. The old and new
+// volumes may share OSDs, whose single copy now belongs to the new volume and
+// is kept. A crash after the index update leaves the old copies as orphaned
+// files, which take space but are never read.
+func (c *Collector) relocate(ctx context.Context, entry *blockindex.Entry, from *replication.GetVolumeResponse) error {
+	data, err := c.readBlock(ctx, entry, from.OsdAddresses)
+	if err != nil {
+		return err
+	}
+
+	reserveResp, err := c.masterClient.ReserveSpace(ctx, &master.ReserveSpaceRequest{
+		CellId:            entry.CellId,
+		Size:              int64(len(data)),
+		ReplicationFactor: int32(c.config.ReplicationFactor),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reserve space: %w", err)
+	}
+	if !reserveResp.Success {
+		return fmt.Errorf("failed to reserve space: %s", reserveResp.Error)
+	}
+
+	putReq := &osd.PutBlockRequest{
+		Hash:     entry.Hash,
+		Data:     data,
+		BucketId: entry.BucketId,
+		VolumeId: reserveResp.VolumeId,
+	}
+	for _, osdAddr := range reserveResp.OsdAddresses {
+		client, err := c.getOSDClient(osdAddr)
+		if err != nil {
+			return err
+		}
+		resp, err := client.PutBlock(ctx, putReq)
+		if err != nil {
+			return fmt.Errorf("failed to write block to %s: %w", osdAddr, err)
+		}
+		if !resp.Success {
+			return fmt.Errorf("failed to write block to %s: %s", osdAddr, resp.Error)
+		}
+	}
+
+	updateResp, err := c.blockIndexClient.UpdateEntry(ctx, &blockindex.UpdateEntryRequest{
+		Hash:             entry.Hash,
+		CellId:           entry.CellId,
+		BucketId:         entry.BucketId,
+		Checksum:         entry.Checksum,
+		VolumeId:         reserveResp.VolumeId,
+		Size:             int64(len(data)),
+		ExpectedCellId:   entry.CellId,
+		ExpectedBucketId: entry.BucketId,
+		ExpectedVolumeId: entry.VolumeId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update entry: %w", err)
+	}
+	if updateResp.Deleting || updateResp.Conflict {
+		// Claimed for deletion or moved by another writer since it was listed.
+		// Whoever did so owns the copies in the old volume, so drop the ones
+		// just written.
+		return c.deleteReplicas(ctx, entry, exclude(reserveResp.OsdAddresses, from.OsdAddresses))
+	}
+	if !updateResp.Success {
+		return fmt.Errorf("failed to update entry: %s", updateResp.Error)
+	}
+
+	if err := c.deleteReplicas(ctx, entry, exclude(from.OsdAddresses, reserveResp.OsdAddresses)); err != nil {
+		log.Printf("Relocated block %s but failed to delete old copies: %v", entry.Hash, err)
+	}
+
+	return nil
+}
+
+// readBlock reads a block from the first replica holding intact data
+func (c *Collector) readBlock(ctx context.Context, entry *blockindex.Entry, osds []string) ([]byte, error) {
+	req := &osd.GetBlockRequest{
+		Hash:     entry.Hash,
+		BucketId: entry.BucketId,
+		VolumeId: entry.VolumeId,
+	}
+
+	for _, osdAddr := range osds {
+		client, err := c.getOSDClient(osdAddr)
+		if err != nil {
+			continue
+		}
+		resp, err := client.GetBlock(ctx, req)
+		if err != nil || !resp.Success {
+			continue
+		}
+		if storage.ComputeHash(resp.Data) != entry.Hash {
+			continue
+		}
+		return resp.Data, nil
+	}
+
+	return nil, fmt.Errorf("no intact replica of block %s", entry.Hash)
+}
+
+// exclude returns the addresses in osds that are not in other
+func exclude(osds, other []string) []string {
+	result := make([]string, 0, len(osds))
+	for _, osdAddr := range osds {
+		if !slices.Contains(other, osdAddr) {
+			result = append(result, osdAddr)
+		}
+	}
+	return result
+}
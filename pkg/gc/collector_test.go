@@ -0,0 +1,372 @@
+package gc
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bharani/pkg/blockindex"
+	"bharani/pkg/config"
+	"bharani/pkg/frontend"
+	"bharani/pkg/master"
+	"bharani/pkg/osd"
+	"bharani/pkg/replication"
+	blockindexpb "bharani/proto/blockindex"
+	masterpb "bharani/proto/master"
+	osdpb "bharani/proto/osd"
+	replicationpb "bharani/proto/replication"
+
+	"google.golang.org/grpc"
+)
+
+// testEnv runs the metadata services, three OSDs, a frontend and a collector
+type testEnv struct {
+	frontend  *frontend.Frontend
+	collector *Collector
+	master    *master.Master
+	osds      map[string]*osd.OSD
+	servers   map[string]*grpc.Server
+}
+
+// serve starts a gRPC server on a free loopback port and returns its address
+func serve(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := grpc.NewServer()
+	register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String()
+}
+
+// newTestEnv creates a cell whose volumes close after two 4 KB buckets
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	dir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.CellID = "cell1"
+	cfg.BucketSize = 4096
+	cfg.VolumeSize = 8192
+
+	index := blockindex.NewMemoryIndex()
+	t.Cleanup(func() { index.Close() })
+	indexAddr := serve(t, func(s *grpc.Server) {
+		blockindexpb.RegisterBlockIndexServiceServer(s, blockindex.NewBlockIndexService(index))
+	})
+
+	table, err := replication.NewTable(filepath.Join(dir, "replication.db"))
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	t.Cleanup(func() { table.Close() })
+	tableAddr := serve(t, func(s *grpc.Server) {
+		replicationpb.RegisterReplicationTableServiceServer(s, replication.NewReplicationTableService(table))
+	})
+
+	m, err := master.NewMaster(cfg, cfg.CellID, tableAddr)
+	if err != nil {
+		t.Fatalf("Failed to create master: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	masterAddr := serve(t, func(s *grpc.Server) {
+		masterpb.RegisterMasterServiceServer(s, master.NewMasterService(m))
+	})
+
+	env := &testEnv{
+		master:  m,
+		osds:    make(map[string]*osd.OSD),
+		servers: make(map[string]*grpc.Server),
+	}
+	for i := 0; i < 3; i++ {
+		osdCfg := *cfg
+		osdCfg.OSDDataDir = filepath.Join(dir, fmt.Sprintf("osd%d", i))
+		osdCfg.ZoneID = fmt.Sprintf("zone%d", i)
+
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		addr := lis.Addr().String()
+
+		instance, err := osd.NewOSD(&osdCfg, addr, cfg.CellID)
+		if err != nil {
+			t.Fatalf("Failed to create OSD: %v", err)
+		}
+		s := grpc.NewServer()
+		osdpb.RegisterOSDServiceServer(s, osd.NewOSDService(instance))
+		go s.Serve(lis)
+		t.Cleanup(s.Stop)
+		env.osds[addr] = instance
+		env.servers[addr] = s
+
+		_, err = m.RegisterOSD(context.Background(), &masterpb.RegisterOSDRequest{
+			OsdAddress:     addr,
+			CellId:         cfg.CellID,
+			ZoneId:         osdCfg.ZoneID,
+			AvailableSpace: 100 * cfg.VolumeSize,
+		})
+		if err != nil {
+			t.Fatalf("Failed to register OSD: %v", err)
+		}
+	}
+
+	env.frontend, err = frontend.NewFrontend(cfg, indexAddr, tableAddr, masterAddr)
+	if err != nil {
+		t.Fatalf("Failed to create frontend: %v", err)
+	}
+	env.collector, err = NewCollector(cfg, indexAddr, tableAddr, masterAddr)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	return env
+}
+
+// put stores random data and returns its hash
+func (e *testEnv) put(t *testing.T, size int, owner string) string {
+	t.Helper()
+
+	data := make([]byte, size)
+	rand.Read(data)
+	hash, err := e.frontend.Put(context.Background(), data, owner)
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	return hash
+}
+
+// release drops a reference and fails the test if owner did not hold one
+func (e *testEnv) release(t *testing.T, hash, owner string) {
+	t.Helper()
+
+	released, err := e.frontend.Release(context.Background(), hash, owner)
+	if err != nil || !released {
+		t.Fatalf("Failed to release %s for %s: %v", hash, owner, err)
+	}
+}
+
+// runAt makes a collection pass as if it were now+offset
+func (e *testEnv) runAt(t *testing.T, offset time.Duration) *Stats {
+	t.Helper()
+
+	e.collector.now = func() time.Time { return time.Now().Add(offset) }
+	stats, err := e.collector.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	return stats
+}
+
+// storedCopies counts the OSDs holding a file for the block's current entry
+func (e *testEnv) storedCopies(t *testing.T, hash, bucketID string) int {
+	t.Helper()
+
+	copies := 0
+	for _, instance := range e.osds {
+		if _, err := instance.GetBlock(context.Background(), hash, bucketID, ""); err == nil {
+			copies++
+		}
+	}
+	return copies
+}
+
+func TestCollectUnreferencedBlocks(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	grace := env.collector.config.GCGracePeriod
+
+	shared := env.put(t, 100, "alice")
+	if _, err := env.frontend.Put(ctx, mustGet(t, env, shared), "bob"); err != nil {
+		t.Fatalf("Failed to put shared block: %v", err)
+	}
+	pinned := env.put(t, 100, "")
+	stat, err := env.frontend.Stat(ctx, shared, false)
+	if err != nil {
+		t.Fatalf("Failed to stat block: %v", err)
+	}
+
+	env.release(t, shared, "alice")
+	if stats := env.runAt(t, 2*grace); stats.BlocksDeleted != 0 {
+		t.Fatalf("A block still referenced by bob was deleted")
+	}
+
+	env.release(t, shared, "bob")
+	if stats := env.runAt(t, 0); stats.BlocksDeleted != 0 {
+		t.Fatalf("A block within the grace period was deleted")
+	}
+
+	stats := env.runAt(t, 2*grace)
+	if stats.BlocksDeleted != 1 || stats.BytesFreed != 100 || stats.Errors != 0 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	if _, err := env.frontend.Get(ctx, shared); !errors.Is(err, frontend.ErrNotFound) {
+		t.Errorf("Expected deleted block to be gone, got %v", err)
+	}
+	if copies := env.storedCopies(t, shared, stat.BucketID); copies != 0 {
+		t.Errorf("Expected every replica to be deleted, %d remain", copies)
+	}
+	if _, err := env.frontend.Get(ctx, pinned); err != nil {
+		t.Errorf("Pinned block should survive collection: %v", err)
+	}
+}
+
+func TestPutWaitsForDeletion(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	data := make([]byte, 100)
+	rand.Read(data)
+	hash, err := env.frontend.Put(ctx, data, "alice")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	env.release(t, hash, "alice")
+
+	// Claim the block as a collector would, then stop before deleting it
+	resp, err := env.collector.blockIndexClient.MarkDeleting(ctx, &blockindexpb.MarkDeletingRequest{
+		Hash:               hash,
+		UnreferencedBefore: time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil || !resp.Marked {
+		t.Fatalf("Failed to claim block: %v %s", err, resp.GetError())
+	}
+
+	if _, err := env.frontend.Put(ctx, data, "bob"); !errors.Is(err, frontend.ErrBlockDeleting) {
+		t.Fatalf("Expected ErrBlockDeleting, got %v", err)
+	}
+
+	// The next pass resumes the deletion even though the grace period has
+	// not passed, after which the block can be stored again
+	if stats := env.runAt(t, 0); stats.BlocksDeleted != 1 {
+		t.Fatalf("Expected the claimed block to be deleted, got %+v", stats)
+	}
+	if _, err := env.frontend.Put(ctx, data, "bob"); err != nil {
+		t.Fatalf("Failed to store the block again: %v", err)
+	}
+	if got := mustGet(t, env, hash); string(got) != string(data) {
+		t.Error("Stored block does not match")
+	}
+}
+
+func TestDeleteSkipsDeadOSDs(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	data := make([]byte, 100)
+	rand.Read(data)
+	hash, err := env.frontend.Put(ctx, data, "alice")
+	if err != nil {
+		t.Fatalf("Failed to put block: %v", err)
+	}
+	env.release(t, hash, "alice")
+
+	var dead string
+	for addr := range env.servers {
+		dead = addr
+		break
+	}
+	env.servers[dead].Stop()
+
+	// An OSD that is down but still reported healthy may come back, so the
+	// deletion waits for it
+	grace := env.collector.config.GCGracePeriod
+	if stats := env.runAt(t, 2*grace); stats.BlocksDeleted != 0 || stats.Errors != 1 {
+		t.Fatalf("Expected the deletion to wait for a healthy OSD, got %+v", stats)
+	}
+
+	// Once the master reports it dead, its copy is left behind
+	_, err = env.master.Heartbeat(ctx, &masterpb.HeartbeatRequest{
+		OsdAddress: dead,
+		CellId:     env.collector.config.CellID,
+		Healthy:    false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to mark OSD unhealthy: %v", err)
+	}
+	if stats := env.runAt(t, 2*grace); stats.BlocksDeleted != 1 || stats.Errors != 0 {
+		t.Fatalf("Expected the block to be deleted, got %+v", stats)
+	}
+	if _, err := env.frontend.Put(ctx, data, "bob"); err != nil {
+		t.Fatalf("Failed to store the block again: %v", err)
+	}
+}
+
+func TestCompactSparseVolume(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	grace := env.collector.config.GCGracePeriod
+
+	// Two 4 KB buckets of 1000 byte blocks fill the first volume, and the
+	// next block closes it
+	kept := env.put(t, 1000, "")
+	first, err := env.frontend.Stat(ctx, kept, false)
+	if err != nil {
+		t.Fatalf("Failed to stat block: %v", err)
+	}
+	filler := make([]string, 0)
+	for i := 0; i < 8; i++ {
+		filler = append(filler, env.put(t, 1000, "filler"))
+	}
+	last, err := env.frontend.Stat(ctx, filler[len(filler)-1], false)
+	if err != nil || last.VolumeID == first.VolumeID {
+		t.Fatalf("Expected the first volume to be closed, got %+v %v", last, err)
+	}
+
+	for _, hash := range filler[:len(filler)-1] {
+		env.release(t, hash, "filler")
+	}
+
+	// Within the grace period the volume is neither collected nor compacted
+	if stats := env.runAt(t, 0); stats.BlocksDeleted != 0 || stats.BlocksRelocated != 0 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	stats := env.runAt(t, 2*grace)
+	if stats.BlocksDeleted != 7 || stats.BlocksRelocated != 1 || stats.VolumesDeleted != 1 || stats.Errors != 0 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	moved, err := env.frontend.Stat(ctx, kept, true)
+	if err != nil {
+		t.Fatalf("Failed to stat relocated block: %v", err)
+	}
+	if moved.VolumeID == first.VolumeID || moved.BucketID != first.BucketID {
+		t.Errorf("Expected the block to move volume and keep its bucket, got %+v", moved)
+	}
+	for _, replica := range moved.Replicas {
+		if replica.Check != frontend.ReplicaOK {
+			t.Errorf("Relocated replica %s is %s", replica.Address, replica.Check)
+		}
+	}
+
+	volume, err := env.collector.replicationClient.GetVolume(ctx, &replicationpb.GetVolumeRequest{VolumeId: first.VolumeID})
+	if err != nil || volume.Found {
+		t.Errorf("Expected the compacted volume to be deleted, got %v %v", volume, err)
+	}
+	if _, err := env.frontend.Get(ctx, filler[len(filler)-1]); err != nil {
+		t.Errorf("Block in the open volume should be untouched: %v", err)
+	}
+}
+
+// mustGet reads a block through the frontend
+func mustGet(t *testing.T, env *testEnv, hash string) []byte {
+	t.Helper()
+
+	data, err := env.frontend.Get(context.Background(), hash)
+	if err != nil {
+		t.Fatalf("Failed to get block %s: %v", hash, err)
+	}
+	return data
+}
@@ -0,0 +1,191 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"bharani/pkg/storage"
+	"bharani/proto/blockindex"
+	"bharani/proto/master"
+	"bharani/proto/osd"
+	"bharani/proto/replication"
+)
+
+// sweepIntents resolves Puts that recorded an intent more than IntentTimeout
+// ago and neither committed nor aborted it, because the frontend crashed, the
+// index update failed, or the surplus replicas of a duplicate write were left
+// for the sweeper. Each intent is claimed first so its writer can no longer
+// commit it, then completed if a write quorum of its volume holds the block
+// intact, and otherwise cleaned up by deleting its replicas.
+func (c *Collector) sweepIntents(ctx context.Context, stats *Stats) error {
+	cutoff := c.now().Add(-c.config.IntentTimeout)
+
+	for {
+		resp, err := c.blockIndexClient.ListIntents(ctx, &blockindex.ListIntentsRequest{
+			CreatedBefore: cutoff.Unix(),
+			Limit:         listBatchSize,
+		})
+		if err == nil && resp.Error != "" {
+			err = fmt.Errorf("%s", resp.Error)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list intents: %w", err)
+		}
+
+		progress := false
+		for _, intent := range resp.Intents {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if err := c.sweepIntent(ctx, intent, cutoff, stats); err != nil {
+				log.Printf("Failed to sweep intent %d for block %s: %v", intent.Id, intent.Hash, err)
+				stats.Errors++
+				continue
+			}
+			progress = true
+		}
+
+		if len(resp.Intents) < listBatchSize || !progress {
+			return nil
+		}
+	}
+}
+
+// sweepIntent claims and resolves a single stale intent. Replicas are only
+// deleted when the block is not indexed in the intent's bucket, since another
+// Put of the same block may have been given that bucket and committed it.
+func (c *Collector) sweepIntent(ctx context.Context, intent *blockindex.Intent, cutoff time.Time, stats *Stats) error {
+	claimResp, err := c.blockIndexClient.ClaimIntent(ctx, &blockindex.ClaimIntentRequest{
+		Id:            intent.Id,
+		CreatedBefore: cutoff.Unix(),
+		Hash:          intent.Hash,
+	})
+	if err == nil && claimResp.Error != "" {
+		err = fmt.Errorf("%s", claimResp.Error)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim intent: %w", err)
+	}
+	if !claimResp.Claimed {
+		// Committed or aborted since it was listed
+		return nil
+	}
+
+	entry := &blockindex.Entry{
+		Hash:     intent.Hash,
+		CellId:   intent.CellId,
+		BucketId: intent.BucketId,
+		Checksum: intent.Hash,
+		VolumeId: intent.VolumeId,
+		Size:     intent.Size,
+	}
+
+	volume, err := c.replicationClient.GetVolume(ctx, &replication.GetVolumeRequest{VolumeId: intent.VolumeId})
+	if err != nil {
+		return fmt.Errorf("failed to get volume: %w", err)
+	}
+	if volume.Found {
+		committed, err := c.completeIntent(ctx, intent, entry, volume.OsdAddresses)
+		if err != nil {
+			return err
+		}
+		if committed {
+			stats.IntentsCommitted++
+			return nil
+		}
+	}
+
+	if !claimResp.Indexed {
+		osds, err := c.blockOSDs(ctx, entry)
+		if err != nil {
+			return err
+		}
+		if err := c.deleteReplicas(ctx, entry, osds); err != nil {
+			return err
+		}
+	}
+
+	abortResp, err := c.blockIndexClient.AbortPut(ctx, &blockindex.AbortPutRequest{Id: intent.Id, Hash: intent.Hash})
+	if err != nil {
+		return fmt.Errorf("failed to abort intent: %w", err)
+	}
+	if !abortResp.Success {
+		return fmt.Errorf("failed to abort intent: %s", abortResp.Error)
+	}
+
+	stats.IntentsAborted++
+	return nil
+}
+
+// completeIntent commits a claimed intent if a write quorum of the volume
+// holds the block intact, and asks the master to repair the other replicas.
+// It reports false when the replicas must be deleted instead: too few are
+// intact, the block was indexed elsewhere meanwhile, or it is being deleted.
+func (c *Collector) completeIntent(ctx context.Context, intent *blockindex.Intent, entry *blockindex.Entry, replicas []string) (bool, error) {
+	intact := c.intactReplicas(ctx, entry, replicas)
+	if len(intact) < c.writeQuorum(len(replicas)) {
+		return false, nil
+	}
+
+	resp, err := c.blockIndexClient.CommitPut(ctx, &blockindex.CommitPutRequest{Id: intent.Id, Claimed: true, Hash: intent.Hash})
+	if err != nil {
+		return false, fmt.Errorf("failed to commit intent: %w", err)
+	}
+	if resp.Deleting || resp.Duplicate {
+		return false, nil
+	}
+	if !resp.Success {
+		return false, fmt.Errorf("failed to commit intent: %s", resp.Error)
+	}
+
+	if missing := exclude(replicas, intact); len(missing) > 0 {
+		_, err := c.masterClient.ReportUnderReplicated(ctx, &master.ReportUnderReplicatedRequest{
+			Hash:        intent.Hash,
+			VolumeId:    intent.VolumeId,
+			BucketId:    intent.BucketId,
+			MissingOsds: missing,
+		})
+		if err != nil {
+			log.Printf("Failed to report under-replicated block %s: %v", intent.Hash, err)
+		}
+	}
+
+	return true, nil
+}
+
+// intactReplicas returns the OSDs holding an intact copy of a block
+func (c *Collector) intactReplicas(ctx context.Context, entry *blockindex.Entry, osds []string) []string {
+	req := &osd.GetBlockRequest{
+		Hash:     entry.Hash,
+		BucketId: entry.BucketId,
+		VolumeId: entry.VolumeId,
+	}
+
+	intact := make([]string, 0, len(osds))
+	for _, osdAddr := range osds {
+		client, err := c.getOSDClient(osdAddr)
+		if err != nil {
+			continue
+		}
+		resp, err := client.GetBlock(ctx, req)
+		if err != nil || !resp.Success {
+			continue
+		}
+		if storage.ComputeHash(resp.Data) == entry.Hash {
+			intact = append(intact, osdAddr)
+		}
+	}
+	return intact
+}
+
+// writeQuorum returns the number of intact replicas a Put needs to commit
+func (c *Collector) writeQuorum(replicas int) int {
+	quorum := c.config.WriteQuorum
+	if quorum <= 0 || quorum > replicas {
+		quorum = replicas
+	}
+	return quorum
+}
@@ -0,0 +1,139 @@
+package gc
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"bharani/pkg/storage"
+	blockindexpb "bharani/proto/blockindex"
+	masterpb "bharani/proto/master"
+)
+
+// crashedPut writes a block to the first `replicas` OSDs of a fresh
+// reservation after recording its intent, as a frontend that stopped before
+// committing would, and returns the intent
+func (e *testEnv) crashedPut(t *testing.T, replicas int, owner string) *blockindexpb.Intent {
+	t.Helper()
+	ctx := context.Background()
+
+	data := make([]byte, 100)
+	rand.Read(data)
+	block, err := storage.NewBlock(data)
+	if err != nil {
+		t.Fatalf("Failed to create block: %v", err)
+	}
+
+	reserveResp, err := e.collector.masterClient.ReserveSpace(ctx, &masterpb.ReserveSpaceRequest{
+		CellId:            e.collector.config.CellID,
+		Size:              block.Size(),
+		ReplicationFactor: int32(e.collector.config.ReplicationFactor),
+	})
+	if err != nil || !reserveResp.Success {
+		t.Fatalf("Failed to reserve space: %v %s", err, reserveResp.GetError())
+	}
+
+	intent := &blockindexpb.Intent{
+		Hash:     block.Hash,
+		CellId:   e.collector.config.CellID,
+		BucketId: reserveResp.BucketId,
+		VolumeId: reserveResp.VolumeId,
+		Size:     block.Size(),
+		Owner:    owner,
+	}
+	beginResp, err := e.collector.blockIndexClient.BeginPut(ctx, &blockindexpb.BeginPutRequest{Intents: []*blockindexpb.Intent{intent}})
+	if err != nil || len(beginResp.Ids) != 1 {
+		t.Fatalf("Failed to begin put: %v %s", err, beginResp.GetError())
+	}
+	intent.Id = beginResp.Ids[0]
+
+	for _, osdAddr := range reserveResp.OsdAddresses[:replicas] {
+		if err := e.osds[osdAddr].PutBlock(ctx, block.Hash, intent.BucketId, intent.VolumeId, data); err != nil {
+			t.Fatalf("Failed to write replica: %v", err)
+		}
+	}
+	return intent
+}
+
+func TestSweepIntents(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	timeout := env.collector.config.IntentTimeout
+
+	written := env.crashedPut(t, 2, "alice")
+	partial := env.crashedPut(t, 1, "alice")
+	committed := env.put(t, 100, "alice")
+
+	// Puts that may still be in flight are left alone
+	if stats := env.runAt(t, 0); stats.IntentsCommitted != 0 || stats.IntentsAborted != 0 {
+		t.Fatalf("Swept fresh intents: %+v", stats)
+	}
+
+	stats := env.runAt(t, 2*timeout)
+	if stats.IntentsCommitted != 1 || stats.IntentsAborted != 1 || stats.Errors != 0 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// A block that reached a write quorum is completed with its owner's
+	// reference, and one that did not is removed from every OSD
+	mustGet(t, env, written.Hash)
+	resp, err := env.collector.blockIndexClient.Release(ctx, &blockindexpb.ReleaseRequest{Hash: written.Hash, Owner: "alice"})
+	if err != nil || !resp.Released {
+		t.Errorf("Expected the completed block to be referenced by alice: %v %v", resp, err)
+	}
+	if _, err := env.frontend.Get(ctx, partial.Hash); err == nil {
+		t.Error("Expected the partial write not to be indexed")
+	}
+	if copies := env.storedCopies(t, partial.Hash, partial.BucketId); copies != 0 {
+		t.Errorf("Expected the partial write to be deleted, %d copies remain", copies)
+	}
+	mustGet(t, env, committed)
+
+	listResp, err := env.collector.blockIndexClient.ListIntents(ctx, &blockindexpb.ListIntentsRequest{
+		CreatedBefore: env.collector.now().Unix(),
+		Limit:         10,
+	})
+	if err != nil || len(listResp.Intents) != 0 {
+		t.Errorf("Expected no intents left, got %v %v", listResp, err)
+	}
+}
+
+func TestSweepKeepsCommittedLocation(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	// A Put of the same block that was given the committed block's bucket and
+	// crashed before reaching a write quorum
+	hash := env.put(t, 100, "alice")
+	entry, err := env.collector.blockIndexClient.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+	if err != nil || !entry.Found {
+		t.Fatalf("Failed to look up block: %v", err)
+	}
+	intent := &blockindexpb.Intent{
+		Hash:     hash,
+		CellId:   entry.CellId,
+		BucketId: entry.BucketId,
+		VolumeId: entry.VolumeId,
+		Size:     entry.Size,
+		Owner:    "bob",
+	}
+	if _, err := env.collector.blockIndexClient.BeginPut(ctx, &blockindexpb.BeginPutRequest{Intents: []*blockindexpb.Intent{intent}}); err != nil {
+		t.Fatalf("Failed to begin put: %v", err)
+	}
+
+	// Leave a single intact copy, below the write quorum
+	deleted := 0
+	for _, instance := range env.osds {
+		if deleted < 2 && instance.DeleteBlock(ctx, hash, entry.BucketId, entry.VolumeId) == nil {
+			deleted++
+		}
+	}
+
+	stats := env.runAt(t, 2*env.collector.config.IntentTimeout)
+	if stats.IntentsAborted != 1 || stats.Errors != 0 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	if copies := env.storedCopies(t, hash, entry.BucketId); copies != 1 {
+		t.Errorf("Sweeping the intent deleted the committed block, %d copies remain", copies)
+	}
+}
@@ -44,4 +44,42 @@ func (s *MasterService) TriggerRepair(ctx context.Context, req *master.TriggerRe
 	return s.master.TriggerRepair(ctx, req)
 }
 
+// AllocateVolume handles AllocateVolume requests
+func (s *MasterService) AllocateVolume(ctx context.Context, req *master.AllocateVolumeRequest) (*master.AllocateVolumeResponse, error) {
+	return s.master.AllocateVolume(ctx, req)
+}
+
+// ReserveSpace handles ReserveSpace requests
+func (s *MasterService) ReserveSpace(ctx context.Context, req *master.ReserveSpaceRequest) (*master.ReserveSpaceResponse, error) {
+	return s.master.ReserveSpace(ctx, req)
+}
+
+// ReportCorruption handles ReportCorruption requests
+func (s *MasterService) ReportCorruption(ctx context.Context, req *master.ReportCorruptionRequest) (*master.ReportCorruptionResponse, error) {
+	return s.master.ReportCorruption(ctx, req)
+}
+
+// DrainOSD handles DrainOSD requests
+func (s *MasterService) DrainOSD(ctx context.Context, req *master.DrainOSDRequest) (*master.DrainOSDResponse, error) {
+	return s.master.DrainOSD(ctx, req)
+}
 
+// ReportUnderReplicated handles ReportUnderReplicated requests
+func (s *MasterService) ReportUnderReplicated(ctx context.Context, req *master.ReportUnderReplicatedRequest) (*master.ReportUnderReplicatedResponse, error) {
+	return s.master.ReportUnderReplicated(ctx, req)
+}
+
+// ListOSDs handles ListOSDs requests
+func (s *MasterService) ListOSDs(ctx context.Context, req *master.ListOSDsRequest) (*master.ListOSDsResponse, error) {
+	return s.master.ListOSDs(ctx, req)
+}
+
+// GetShardMap handles GetShardMap requests
+func (s *MasterService) GetShardMap(ctx context.Context, req *master.GetShardMapRequest) (*master.GetShardMapResponse, error) {
+	return s.master.GetShardMap(ctx, req)
+}
+
+// AddIndexShard handles AddIndexShard requests
+func (s *MasterService) AddIndexShard(ctx context.Context, req *master.AddIndexShardRequest) (*master.AddIndexShardResponse, error) {
+	return s.master.AddIndexShard(ctx, req)
+}
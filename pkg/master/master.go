@@ -3,41 +3,60 @@ package master
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
 	"bharani/pkg/config"
+	"bharani/pkg/shards"
+	"bharani/pkg/storage"
+	"bharani/proto/blockindex"
 	"bharani/proto/master"
 	"bharani/proto/osd"
 	"bharani/proto/replication"
 
+	"github.com/google/uuid"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // OSDInfo tracks OSD health and metadata
 type OSDInfo struct {
 	Address        string
 	CellID         string
+	ZoneID         string
 	AvailableSpace int64
 	LastHeartbeat  time.Time
 	Healthy        bool
+	Draining       bool // Excluded from new placements while its data moves elsewhere
 }
 
 // Master coordinates repairs, volume management, and OSD monitoring
 type Master struct {
 	config          *config.Config
 	cellID          string
-	osds            map[string]*OSDInfo // OSD address -> info
-	openVolumes     map[string]bool     // Volume ID -> is open
+	osds            map[string]*OSDInfo    // OSD address -> info
+	openVolumes     map[string]*openVolume // Volume ID -> space accounting
 	replicationConn *grpc.ClientConn
+	dialOption      grpc.DialOption
 	osdClients      map[string]osd.OSDServiceClient
+	underReplicated map[string]*UnderReplicatedBlock // hash/bucket/target OSD -> pending repair
+	rng             *rand.Rand
+	reserveMu       sync.Mutex  // Serializes space reservations and the volume changes they cause
+	shardMap        *shards.Map // Block index shards; nil unless sharding is enabled
+	shardPath       string
+	indexClients    map[string]blockindex.BlockIndexServiceClient
+	shardMu         sync.Mutex // Guards the shard map and index clients
 	mu              sync.RWMutex
 }
 
 // NewMaster creates a new Master instance
 func NewMaster(cfg *config.Config, cellID, replicationAddr string) (*Master, error) {
-	replicationConn, err := grpc.NewClient(replicationAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialOption, err := cfg.TLS.DialOption()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+
+	replicationConn, err := grpc.NewClient(replicationAddr, dialOption)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to replication table: %w", err)
 	}
@@ -46,9 +65,13 @@ func NewMaster(cfg *config.Config, cellID, replicationAddr string) (*Master, err
 		config:          cfg,
 		cellID:          cellID,
 		osds:            make(map[string]*OSDInfo),
-		openVolumes:     make(map[string]bool),
+		openVolumes:     make(map[string]*openVolume),
 		replicationConn: replicationConn,
+		dialOption:      dialOption,
 		osdClients:      make(map[string]osd.OSDServiceClient),
+		underReplicated: make(map[string]*UnderReplicatedBlock),
+		indexClients:    make(map[string]blockindex.BlockIndexServiceClient),
+		rng:             rand.New(rand.NewSource(time.Now().UnixNano())),
 	}, nil
 }
 
@@ -60,15 +83,19 @@ func (m *Master) RegisterOSD(ctx context.Context, req *master.RegisterOSDRequest
 	osdInfo := &OSDInfo{
 		Address:        req.OsdAddress,
 		CellID:         req.CellId,
+		ZoneID:         req.ZoneId,
 		AvailableSpace: req.AvailableSpace,
 		LastHeartbeat:  time.Now(),
 		Healthy:        true,
 	}
+	if existing, ok := m.osds[req.OsdAddress]; ok {
+		osdInfo.Draining = existing.Draining
+	}
 
 	m.osds[req.OsdAddress] = osdInfo
 
 	// Create gRPC client for this OSD
-	conn, err := grpc.NewClient(req.OsdAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(req.OsdAddress, m.dialOption)
 	if err != nil {
 		return &master.RegisterOSDResponse{
 			Success: false,
@@ -91,15 +118,21 @@ func (m *Master) Heartbeat(ctx context.Context, req *master.HeartbeatRequest) (*
 	if !exists {
 		// Auto-register if not exists
 		osdInfo = &OSDInfo{
-			Address:       req.OsdAddress,
-			LastHeartbeat: time.Now(),
-			Healthy:       req.Healthy,
+			Address:        req.OsdAddress,
+			CellID:         req.CellId,
+			ZoneID:         req.ZoneId,
+			AvailableSpace: req.AvailableSpace,
+			LastHeartbeat:  time.Now(),
+			Healthy:        req.Healthy,
 		}
 		m.osds[req.OsdAddress] = osdInfo
 	} else {
 		osdInfo.LastHeartbeat = time.Now()
 		osdInfo.Healthy = req.Healthy
 		osdInfo.AvailableSpace = req.AvailableSpace
+		if req.ZoneId != "" {
+			osdInfo.ZoneID = req.ZoneId
+		}
 	}
 
 	return &master.HeartbeatResponse{
@@ -139,26 +172,35 @@ func (m *Master) GetOpenVolumes(ctx context.Context, req *master.GetOpenVolumesR
 
 // CloseVolume closes a volume
 func (m *Master) CloseVolume(ctx context.Context, req *master.CloseVolumeRequest) (*master.CloseVolumeResponse, error) {
-	replicationClient := replication.NewReplicationTableServiceClient(m.replicationConn)
-
-	getReq := &replication.GetVolumeRequest{VolumeId: req.VolumeId}
-	getResp, err := replicationClient.GetVolume(ctx, getReq)
-	if err != nil {
+	if err := m.closeVolume(ctx, req.VolumeId); err != nil {
 		return &master.CloseVolumeResponse{
 			Success: false,
 			Error:   err.Error(),
 		}, nil
 	}
 
+	return &master.CloseVolumeResponse{
+		Success: true,
+	}, nil
+}
+
+// closeVolume marks a volume closed in the replication table and stops
+// accounting for it
+func (m *Master) closeVolume(ctx context.Context, volumeID string) error {
+	replicationClient := replication.NewReplicationTableServiceClient(m.replicationConn)
+
+	getReq := &replication.GetVolumeRequest{VolumeId: volumeID}
+	getResp, err := replicationClient.GetVolume(ctx, getReq)
+	if err != nil {
+		return err
+	}
+
 	if !getResp.Found {
-		return &master.CloseVolumeResponse{
-			Success: false,
-			Error:   "volume not found",
-		}, nil
+		return fmt.Errorf("volume not found")
 	}
 
 	updateReq := &replication.UpdateVolumeRequest{
-		VolumeId:     req.VolumeId,
+		VolumeId:     volumeID,
 		OsdAddresses: getResp.OsdAddresses,
 		Generation:   getResp.Generation,
 		State:        "closed",
@@ -166,21 +208,134 @@ func (m *Master) CloseVolume(ctx context.Context, req *master.CloseVolumeRequest
 
 	_, err = replicationClient.UpdateVolume(ctx, updateReq)
 	if err != nil {
-		return &master.CloseVolumeResponse{
+		return err
+	}
+
+	m.mu.Lock()
+	if open, ok := m.openVolumes[volumeID]; ok {
+		open.volume.Close()
+		delete(m.openVolumes, volumeID)
+	}
+	m.mu.Unlock()
+
+	return nil
+}
+
+// AllocateVolume creates a new volume whose replicas are placed on healthy
+// OSDs of the cell, spread across zones and weighted by free space
+func (m *Master) AllocateVolume(ctx context.Context, req *master.AllocateVolumeRequest) (*master.AllocateVolumeResponse, error) {
+	cellID := req.CellId
+	if cellID == "" {
+		cellID = m.cellID
+	}
+
+	volume, err := m.allocateVolume(ctx, cellID, int(req.ReplicationFactor))
+	if err != nil {
+		return &master.AllocateVolumeResponse{
 			Success: false,
 			Error:   err.Error(),
 		}, nil
 	}
 
+	return &master.AllocateVolumeResponse{
+		Success:      true,
+		VolumeId:     volume.volume.ID,
+		OsdAddresses: volume.volume.GetOSDs(),
+	}, nil
+}
+
+// allocateVolume places and creates a new open volume in a cell
+func (m *Master) allocateVolume(ctx context.Context, cellID string, replicationFactor int) (*openVolume, error) {
+	if replicationFactor <= 0 {
+		replicationFactor = m.config.ReplicationFactor
+	}
+
 	m.mu.Lock()
-	delete(m.openVolumes, req.VolumeId)
+	candidates := make([]*OSDInfo, 0, len(m.osds))
+	for _, info := range m.osds {
+		if info.CellID == cellID {
+			candidates = append(candidates, info)
+		}
+	}
+	selected, err := PlaceReplicas(candidates, replicationFactor, m.config.VolumeSize, m.rng)
 	m.mu.Unlock()
 
-	return &master.CloseVolumeResponse{
+	if err != nil {
+		return nil, err
+	}
+
+	volumeID := uuid.New().String()
+	replicationClient := replication.NewReplicationTableServiceClient(m.replicationConn)
+
+	createReq := &replication.CreateVolumeRequest{
+		VolumeId:     volumeID,
+		OsdAddresses: selected,
+		CellId:       cellID,
+	}
+	createResp, err := replicationClient.CreateVolume(ctx, createReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume: %v", err)
+	}
+	if !createResp.Success {
+		return nil, fmt.Errorf("failed to create volume: %s", createResp.Error)
+	}
+
+	volume := storage.NewVolume(volumeID, cellID)
+	volume.SetOSDs(selected)
+	open := &openVolume{volume: volume}
+
+	m.mu.Lock()
+	m.openVolumes[volumeID] = open
+	m.mu.Unlock()
+
+	return open, nil
+}
+
+// DrainOSD marks an OSD as draining so no new volumes are placed on it
+func (m *Master) DrainOSD(ctx context.Context, req *master.DrainOSDRequest) (*master.DrainOSDResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	osdInfo, exists := m.osds[req.OsdAddress]
+	if !exists {
+		return &master.DrainOSDResponse{
+			Success: false,
+			Error:   "OSD not registered",
+		}, nil
+	}
+
+	osdInfo.Draining = req.Draining
+
+	return &master.DrainOSDResponse{
 		Success: true,
 	}, nil
 }
 
+// ListOSDs returns the registered OSDs with their zone and health
+func (m *Master) ListOSDs(ctx context.Context, req *master.ListOSDsRequest) (*master.ListOSDsResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	osds := make([]*master.OSDStatus, 0, len(m.osds))
+	for _, info := range m.osds {
+		if req.CellId != "" && info.CellID != req.CellId {
+			continue
+		}
+		osds = append(osds, &master.OSDStatus{
+			Address:        info.Address,
+			CellId:         info.CellID,
+			ZoneId:         info.ZoneID,
+			Healthy:        info.Healthy,
+			Draining:       info.Draining,
+			AvailableSpace: info.AvailableSpace,
+		})
+	}
+
+	return &master.ListOSDsResponse{
+		Osds: osds,
+	}, nil
+}
+
 // TriggerRepair triggers a repair operation for a failed OSD
 func (m *Master) TriggerRepair(ctx context.Context, req *master.TriggerRepairRequest) (*master.TriggerRepairResponse, error) {
 	return &master.TriggerRepairResponse{
@@ -213,16 +368,15 @@ func (m *Master) checkOSDHealth(ctx context.Context) {
 	m.mu.RUnlock()
 
 	for _, addr := range osdAddresses {
-		m.mu.RLock()
+		m.mu.Lock()
 		osdInfo := m.osds[addr]
-		m.mu.RUnlock()
-
-		if osdInfo == nil {
-			continue
+		expired := osdInfo != nil && time.Since(osdInfo.LastHeartbeat) > 2*time.Minute
+		if expired {
+			osdInfo.Healthy = false
 		}
+		m.mu.Unlock()
 
-		if time.Since(osdInfo.LastHeartbeat) > 2*time.Minute {
-			osdInfo.Healthy = false
+		if expired {
 			go m.triggerRepairForOSD(ctx, addr)
 		}
 	}
@@ -233,6 +387,25 @@ func (m *Master) triggerRepairForOSD(ctx context.Context, osdAddress string) {
 	fmt.Printf("Triggering repair for OSD: %s\n", osdAddress)
 }
 
+// getOSDClient gets or creates a gRPC client for an OSD
+func (m *Master) getOSDClient(osdAddress string) (osd.OSDServiceClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if client, exists := m.osdClients[osdAddress]; exists {
+		return client, nil
+	}
+
+	conn, err := grpc.NewClient(osdAddress, m.dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to OSD %s: %w", osdAddress, err)
+	}
+
+	client := osd.NewOSDServiceClient(conn)
+	m.osdClients[osdAddress] = client
+	return client, nil
+}
+
 // GetHealthyOSDs returns list of healthy OSD addresses
 func (m *Master) GetHealthyOSDs() []string {
 	m.mu.RLock()
@@ -0,0 +1,99 @@
+package master
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// PlaceReplicas chooses count OSDs for a new volume. Draining, unhealthy and
+// full OSDs (less than minFree bytes available) are skipped. Replicas are
+// spread across zones as evenly as possible, and within a zone OSDs with more
+// free space are proportionally more likely to be picked.
+func PlaceReplicas(candidates []*OSDInfo, count int, minFree int64, rng *rand.Rand) ([]string, error) {
+	zones := make(map[string][]*OSDInfo)
+	eligible := 0
+	for _, info := range candidates {
+		if !info.Healthy || info.Draining || info.AvailableSpace < minFree {
+			continue
+		}
+		zones[info.ZoneID] = append(zones[info.ZoneID], info)
+		eligible++
+	}
+
+	if eligible < count {
+		return nil, fmt.Errorf("not enough healthy OSDs: need %d, have %d", count, eligible)
+	}
+
+	zoneIDs := make([]string, 0, len(zones))
+	for zoneID := range zones {
+		zoneIDs = append(zoneIDs, zoneID)
+	}
+	sort.Strings(zoneIDs)
+
+	used := make(map[string]int)
+	selected := make([]string, 0, count)
+	for len(selected) < count {
+		zoneID := pickZone(zoneIDs, zones, used, rng)
+		osds := zones[zoneID]
+
+		i := pickWeighted(osds, rng)
+		selected = append(selected, osds[i].Address)
+		zones[zoneID] = append(osds[:i:i], osds[i+1:]...)
+		used[zoneID]++
+	}
+
+	return selected, nil
+}
+
+// pickZone returns a zone with remaining OSDs and the fewest replicas placed
+// so far, breaking ties by free space
+func pickZone(zoneIDs []string, zones map[string][]*OSDInfo, used map[string]int, rng *rand.Rand) string {
+	best := -1
+	ties := make([]*OSDInfo, 0)
+	tieZones := make([]string, 0)
+
+	for _, zoneID := range zoneIDs {
+		if len(zones[zoneID]) == 0 {
+			continue
+		}
+		if best != -1 && used[zoneID] > best {
+			continue
+		}
+		if best == -1 || used[zoneID] < best {
+			best = used[zoneID]
+			ties = ties[:0]
+			tieZones = tieZones[:0]
+		}
+
+		var free int64
+		for _, info := range zones[zoneID] {
+			free += info.AvailableSpace
+		}
+		ties = append(ties, &OSDInfo{AvailableSpace: free})
+		tieZones = append(tieZones, zoneID)
+	}
+
+	return tieZones[pickWeighted(ties, rng)]
+}
+
+// pickWeighted returns the index of an OSD chosen with probability
+// proportional to its available space
+func pickWeighted(osds []*OSDInfo, rng *rand.Rand) int {
+	var total int64
+	for _, info := range osds {
+		total += info.AvailableSpace
+	}
+	if total <= 0 {
+		return rng.Intn(len(osds))
+	}
+
+	target := rng.Int63n(total)
+	for i, info := range osds {
+		target -= info.AvailableSpace
+		if target < 0 {
+			return i
+		}
+	}
+	return len(osds) - 1
+}
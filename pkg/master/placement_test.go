@@ -0,0 +1,93 @@
+package master
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func testOSDs(zones, perZone int, space int64) []*OSDInfo {
+	osds := make([]*OSDInfo, 0)
+	for z := 0; z < zones; z++ {
+		for i := 0; i < perZone; i++ {
+			osds = append(osds, &OSDInfo{
+				Address:        fmt.Sprintf("osd-%d-%d", z, i),
+				ZoneID:         fmt.Sprintf("zone%d", z),
+				AvailableSpace: space,
+				Healthy:        true,
+			})
+		}
+	}
+	return osds
+}
+
+func TestPlaceReplicasSpreadsZones(t *testing.T) {
+	osds := testOSDs(3, 4, 1000)
+	zoneOf := make(map[string]string)
+	for _, info := range osds {
+		zoneOf[info.Address] = info.ZoneID
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		selected, err := PlaceReplicas(osds, 3, 10, rng)
+		if err != nil {
+			t.Fatalf("Failed to place replicas: %v", err)
+		}
+
+		zones := make(map[string]bool)
+		for _, addr := range selected {
+			zones[zoneOf[addr]] = true
+		}
+		if len(zones) != 3 {
+			t.Fatalf("Replicas should land in 3 distinct zones, got %v", selected)
+		}
+	}
+}
+
+func TestPlaceReplicasSkipsIneligible(t *testing.T) {
+	osds := testOSDs(1, 5, 1000)
+	osds[0].Healthy = false
+	osds[1].Draining = true
+	osds[2].AvailableSpace = 5
+
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		selected, err := PlaceReplicas(osds, 2, 10, rng)
+		if err != nil {
+			t.Fatalf("Failed to place replicas: %v", err)
+		}
+		for _, addr := range selected {
+			if addr == osds[0].Address || addr == osds[1].Address || addr == osds[2].Address {
+				t.Fatalf("Ineligible OSD %s was selected", addr)
+			}
+		}
+		if selected[0] == selected[1] {
+			t.Fatalf("The same OSD was selected twice: %v", selected)
+		}
+	}
+
+	if _, err := PlaceReplicas(osds, 3, 10, rng); err == nil {
+		t.Error("Placement should fail with too few eligible OSDs")
+	}
+}
+
+func TestPlaceReplicasWeightsByFreeSpace(t *testing.T) {
+	osds := testOSDs(1, 2, 100)
+	osds[0].AvailableSpace = 900
+
+	rng := rand.New(rand.NewSource(3))
+	counts := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		selected, err := PlaceReplicas(osds, 1, 10, rng)
+		if err != nil {
+			t.Fatalf("Failed to place replicas: %v", err)
+		}
+		counts[selected[0]]++
+	}
+
+	share := float64(counts[osds[0].Address]) / 2000
+	if share < 0.85 || share > 0.95 {
+		t.Errorf("OSD with 90%% of free space chosen %.2f of the time", share)
+	}
+}
@@ -3,16 +3,21 @@ package master
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
+	"bharani/pkg/storage"
+	"bharani/proto/master"
+	"bharani/proto/osd"
 	"bharani/proto/replication"
 )
 
 // RepairPlan represents a plan for repairing volumes after OSD failure
 type RepairPlan struct {
-	FailedOSD    string
-	Volumes      []VolumeRepair
-	SourceOSDs   []string
-	TargetOSDs   []string
+	FailedOSD  string
+	Volumes    []VolumeRepair
+	SourceOSDs []string
+	TargetOSDs []string
 }
 
 // VolumeRepair represents repair information for a single volume
@@ -90,3 +95,168 @@ func (m *Master) BuildRepairPlan(ctx context.Context, failedOSD string) (*Repair
 	}, nil
 }
 
+// UnderReplicatedBlock records replicas that missed a committed write. It acts
+// as a hint that is replayed once the target OSD is reachable again.
+type UnderReplicatedBlock struct {
+	Hash      string
+	VolumeID  string
+	BucketID  string
+	TargetOSD string
+	Attempts  int
+}
+
+// ReportUnderReplicated queues repair of replicas that missed a write
+func (m *Master) ReportUnderReplicated(ctx context.Context, req *master.ReportUnderReplicatedRequest) (*master.ReportUnderReplicatedResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, osdAddr := range req.MissingOsds {
+		key := req.Hash + "/" + req.BucketId + "/" + osdAddr
+		if _, exists := m.underReplicated[key]; exists {
+			continue
+		}
+		m.underReplicated[key] = &UnderReplicatedBlock{
+			Hash:      req.Hash,
+			VolumeID:  req.VolumeId,
+			BucketID:  req.BucketId,
+			TargetOSD: osdAddr,
+		}
+	}
+
+	return &master.ReportUnderReplicatedResponse{
+		Success: true,
+	}, nil
+}
+
+// ReportCorruption queues repair of a replica whose data no longer matches
+// its hash. The repair overwrites it with a copy verified on another replica.
+func (m *Master) ReportCorruption(ctx context.Context, req *master.ReportCorruptionRequest) (*master.ReportCorruptionResponse, error) {
+	log.Printf("Corrupt replica of block %s in volume %s on OSD %s", req.Hash, req.VolumeId, req.OsdAddress)
+
+	_, err := m.ReportUnderReplicated(ctx, &master.ReportUnderReplicatedRequest{
+		Hash:        req.Hash,
+		VolumeId:    req.VolumeId,
+		BucketId:    req.BucketId,
+		MissingOsds: []string{req.OsdAddress},
+	})
+	if err != nil {
+		return &master.ReportCorruptionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &master.ReportCorruptionResponse{
+		Success: true,
+	}, nil
+}
+
+// PendingReplicaRepairs returns the number of queued replica repairs
+func (m *Master) PendingReplicaRepairs() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.underReplicated)
+}
+
+// RunReplicaRepair periodically copies under-replicated blocks to the replicas that missed them
+func (m *Master) RunReplicaRepair(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.RepairUnderReplicated(ctx)
+		}
+	}
+}
+
+// RepairUnderReplicated makes one pass over the repair queue and returns the
+// number of replicas restored. Targets that are down stay queued.
+func (m *Master) RepairUnderReplicated(ctx context.Context) int {
+	m.mu.RLock()
+	pending := make(map[string]*UnderReplicatedBlock, len(m.underReplicated))
+	for key, item := range m.underReplicated {
+		pending[key] = item
+	}
+	m.mu.RUnlock()
+
+	repaired := 0
+	for key, item := range pending {
+		if err := m.repairReplica(ctx, item); err != nil {
+			m.mu.Lock()
+			item.Attempts++
+			m.mu.Unlock()
+			continue
+		}
+
+		m.mu.Lock()
+		delete(m.underReplicated, key)
+		m.mu.Unlock()
+		repaired++
+	}
+
+	return repaired
+}
+
+// repairReplica copies one block from a replica that has it to the target OSD
+func (m *Master) repairReplica(ctx context.Context, item *UnderReplicatedBlock) error {
+	replicationClient := replication.NewReplicationTableServiceClient(m.replicationConn)
+
+	getResp, err := replicationClient.GetVolume(ctx, &replication.GetVolumeRequest{VolumeId: item.VolumeID})
+	if err != nil {
+		return fmt.Errorf("failed to get volume: %w", err)
+	}
+	if !getResp.Found {
+		return fmt.Errorf("volume %s not found", item.VolumeID)
+	}
+
+	target, err := m.getOSDClient(item.TargetOSD)
+	if err != nil {
+		return err
+	}
+
+	for _, osdAddr := range getResp.OsdAddresses {
+		if osdAddr == item.TargetOSD {
+			continue
+		}
+
+		source, err := m.getOSDClient(osdAddr)
+		if err != nil {
+			continue
+		}
+
+		getBlockResp, err := source.GetBlock(ctx, &osd.GetBlockRequest{
+			Hash:     item.Hash,
+			BucketId: item.BucketID,
+			VolumeId: item.VolumeID,
+		})
+		if err != nil || !getBlockResp.Success {
+			continue
+		}
+
+		if storage.ComputeHash(getBlockResp.Data) != item.Hash {
+			continue
+		}
+
+		putResp, err := target.PutBlock(ctx, &osd.PutBlockRequest{
+			Hash:     item.Hash,
+			Data:     getBlockResp.Data,
+			BucketId: item.BucketID,
+			VolumeId: item.VolumeID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write replica to %s: %w", item.TargetOSD, err)
+		}
+		if !putResp.Success {
+			return fmt.Errorf("failed to write replica to %s: %s", item.TargetOSD, putResp.Error)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no healthy source replica for block %s", item.Hash)
+}
@@ -0,0 +1,404 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"bharani/pkg/shards"
+	"bharani/proto/blockindex"
+	"bharani/proto/master"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// migrationPage is the number of blocks copied per call while moving a
+	// range between index nodes
+	migrationPage = 1000
+
+	// migrationCatchUpRounds bounds the copies of blocks written during the
+	// bulk copy made before the range is frozen
+	migrationCatchUpRounds = 5
+
+	// migrationFreezeTimeout bounds how long a range stays frozen waiting for
+	// its uncommitted Puts. If they do not finish the range is unfrozen and
+	// the move starts over.
+	migrationFreezeTimeout = 10 * time.Second
+
+	// migrationRetryInterval is the delay before a failed move starts over
+	migrationRetryInterval = 5 * time.Second
+)
+
+// errRangeMoved reports that the source node has already handed a range over
+var errRangeMoved = errors.New("range has already moved")
+
+// LoadShards enables index sharding, keeping the shard map in the file at
+// path. Without a saved map the whole hash space is served by initialAddr.
+func (m *Master) LoadShards(path, initialAddr string) error {
+	shardMap, err := shards.LoadMap(path)
+	if err != nil {
+		return err
+	}
+	if len(shardMap.Shards) == 0 {
+		shardMap = shards.NewMap(initialAddr)
+	}
+
+	m.shardMu.Lock()
+	defer m.shardMu.Unlock()
+
+	m.shardMap = shardMap
+	m.shardPath = path
+	return nil
+}
+
+// GetShardMap returns the block index shard map, which is empty when the
+// index is not sharded
+func (m *Master) GetShardMap(ctx context.Context, req *master.GetShardMapRequest) (*master.GetShardMapResponse, error) {
+	m.shardMu.Lock()
+	defer m.shardMu.Unlock()
+
+	if m.shardMap == nil {
+		return &master.GetShardMapResponse{}, nil
+	}
+	return m.shardMap.ToProto(), nil
+}
+
+// AddIndexShard splits the widest range that is not already moving and moves
+// its upper half to a new index node. The move runs in the background; until
+// it completes the range's requests keep going to the node it moves from.
+func (m *Master) AddIndexShard(ctx context.Context, req *master.AddIndexShardRequest) (*master.AddIndexShardResponse, error) {
+	shard, err := m.splitShard(req.Address)
+	if err != nil {
+		return &master.AddIndexShardResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	log.Printf("Moving index range %s from %s to %s", shard.Range, shard.Source, shard.Address)
+	go m.migrateShard(context.Background(), shard)
+
+	return &master.AddIndexShardResponse{
+		Success: true,
+		Start:   shard.Start,
+		End:     shard.End,
+	}, nil
+}
+
+// splitShard adds a shard for address to the map, taking half of the widest
+// range, and saves the map. The new shard keeps the range's node as its
+// source until the move completes.
+func (m *Master) splitShard(address string) (shards.Shard, error) {
+	if address == "" {
+		return shards.Shard{}, fmt.Errorf("index node address is required")
+	}
+
+	m.shardMu.Lock()
+	defer m.shardMu.Unlock()
+
+	if m.shardMap == nil {
+		return shards.Shard{}, fmt.Errorf("index sharding is not enabled")
+	}
+
+	i := m.shardMap.Widest()
+	if i == -1 {
+		return shards.Shard{}, fmt.Errorf("every index range is already moving")
+	}
+	widest := m.shardMap.Shards[i]
+	if widest.Address == address {
+		return shards.Shard{}, fmt.Errorf("index node %s already serves the widest range %s", address, widest.Range)
+	}
+	mid, err := widest.Split()
+	if err != nil {
+		return shards.Shard{}, err
+	}
+
+	shard := shards.Shard{
+		Range:   shards.Range{Start: mid, End: widest.End},
+		Address: address,
+		Source:  widest.Address,
+	}
+
+	updated := m.shardMap.Clone()
+	updated.Shards[i].End = mid
+	updated.Shards = append(updated.Shards[:i+1], append([]shards.Shard{shard}, updated.Shards[i+1:]...)...)
+	updated.Version++
+	if err := updated.Save(m.shardPath); err != nil {
+		return shards.Shard{}, err
+	}
+	m.shardMap = updated
+
+	return shard, nil
+}
+
+// ResumeMigrations restarts the moves of ranges that were under way when the
+// master stopped
+func (m *Master) ResumeMigrations(ctx context.Context) {
+	m.shardMu.Lock()
+	var moving []shards.Shard
+	if m.shardMap != nil {
+		for _, shard := range m.shardMap.Shards {
+			if shard.Source != "" {
+				moving = append(moving, shard)
+			}
+		}
+	}
+	m.shardMu.Unlock()
+
+	for _, shard := range moving {
+		log.Printf("Resuming move of index range %s from %s to %s", shard.Range, shard.Source, shard.Address)
+		go m.migrateShard(ctx, shard)
+	}
+}
+
+// migrateShard moves a shard's range from its source, starting over until
+// the move succeeds, then points the map at the new node
+func (m *Master) migrateShard(ctx context.Context, shard shards.Shard) {
+	for {
+		err := m.moveRange(ctx, shard)
+		if err == nil || errors.Is(err, errRangeMoved) {
+			break
+		}
+		log.Printf("Failed to move index range %s to %s, starting over: %v", shard.Range, shard.Address, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(migrationRetryInterval):
+		}
+	}
+
+	for {
+		err := m.finishShard(shard)
+		if err == nil {
+			break
+		}
+		log.Printf("Failed to save index shard map: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(migrationRetryInterval):
+		}
+	}
+	log.Printf("Moved index range %s to %s", shard.Range, shard.Address)
+}
+
+// finishShard records that a shard's range is served by its new node
+func (m *Master) finishShard(shard shards.Shard) error {
+	m.shardMu.Lock()
+	defer m.shardMu.Unlock()
+
+	updated := m.shardMap.Clone()
+	for i := range updated.Shards {
+		if updated.Shards[i].Range == shard.Range && updated.Shards[i].Source == shard.Source {
+			updated.Shards[i].Source = ""
+		}
+	}
+	updated.Version++
+	if err := updated.Save(m.shardPath); err != nil {
+		return err
+	}
+	m.shardMap = updated
+	return nil
+}
+
+// moveRange copies a range from the shard's source to its new node while the
+// source keeps serving it. Blocks written during the bulk copy are copied
+// again until few are left, then the source stops accepting writes to the
+// range, waits for its uncommitted Puts, hands over the last changes, and
+// drops the range. Copies replace what the new node holds, so a move that
+// fails part way can simply start over.
+func (m *Master) moveRange(ctx context.Context, shard shards.Shard) error {
+	src, err := m.getIndexClient(shard.Source)
+	if err != nil {
+		return err
+	}
+	dst, err := m.getIndexClient(shard.Address)
+	if err != nil {
+		return err
+	}
+	r := &blockindex.MigrationRequest{Start: shard.Start, End: shard.End}
+
+	resp, err := src.BeginMigration(ctx, r)
+	if status.Code(err) == codes.OutOfRange {
+		return errRangeMoved
+	}
+	if err := migrationError(resp, err); err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+
+	if err := copyRange(ctx, src, dst, shard.Range); err != nil {
+		return err
+	}
+
+	for range migrationCatchUpRounds {
+		copied, err := copyChanges(ctx, src, dst, r)
+		if err != nil {
+			return err
+		}
+		if copied < migrationPage {
+			break
+		}
+	}
+
+	if err := waitFrozen(ctx, src, r); err != nil {
+		return err
+	}
+	if _, err := copyChanges(ctx, src, dst, r); err != nil {
+		return err
+	}
+
+	resp, err = src.EndMigration(ctx, r)
+	if err := migrationError(resp, err); err != nil {
+		return fmt.Errorf("failed to end migration: %w", err)
+	}
+	return nil
+}
+
+// copyRange copies every block of a range, a page at a time. Each page
+// replaces the part of the range it covers, removing blocks the new node
+// holds from an earlier attempt that have since gone.
+func copyRange(ctx context.Context, src, dst blockindex.BlockIndexServiceClient, r shards.Range) error {
+	start, after := r.Start, ""
+	for {
+		resp, err := src.ExportRange(ctx, &blockindex.ExportRangeRequest{
+			Start: r.Start,
+			End:   r.End,
+			After: after,
+			Limit: migrationPage,
+		})
+		if err == nil && resp.Error != "" {
+			err = errors.New(resp.Error)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export range %s: %w", r, err)
+		}
+
+		// A full page covers up to its last block; the last page covers the
+		// rest of the range
+		end := r.End
+		full := len(resp.Records) == migrationPage
+		if full {
+			after = resp.Records[len(resp.Records)-1].Entry.Hash
+			end = after + "\x00"
+		}
+
+		if err := importBlocks(ctx, dst, &blockindex.ImportBlocksRequest{
+			Records:      resp.Records,
+			ReplaceRange: true,
+			Start:        start,
+			End:          end,
+		}); err != nil {
+			return err
+		}
+		if !full {
+			return nil
+		}
+		start = end
+	}
+}
+
+// copyChanges copies the blocks of a migrating range written since the last
+// call, returning how many there were
+func copyChanges(ctx context.Context, src, dst blockindex.BlockIndexServiceClient, r *blockindex.MigrationRequest) (int, error) {
+	resp, err := src.TakeChanges(ctx, r)
+	if err == nil && resp.Error != "" {
+		err = errors.New(resp.Error)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to take changes: %w", err)
+	}
+
+	for i := 0; i < len(resp.Hashes); i += migrationPage {
+		hashes := resp.Hashes[i:min(i+migrationPage, len(resp.Hashes))]
+		exported, err := src.ExportBlocks(ctx, &blockindex.ExportBlocksRequest{Hashes: hashes})
+		if err == nil && exported.Error != "" {
+			err = errors.New(exported.Error)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to export changed blocks: %w", err)
+		}
+
+		if err := importBlocks(ctx, dst, &blockindex.ImportBlocksRequest{Records: exported.Records}); err != nil {
+			return 0, err
+		}
+	}
+	return len(resp.Hashes), nil
+}
+
+// importBlocks imports records into the new node
+func importBlocks(ctx context.Context, dst blockindex.BlockIndexServiceClient, req *blockindex.ImportBlocksRequest) error {
+	resp, err := dst.ImportBlocks(ctx, req)
+	if err == nil && !resp.Success {
+		err = errors.New(resp.Error)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import blocks: %w", err)
+	}
+	return nil
+}
+
+// waitFrozen freezes a migrating range and waits for its uncommitted Puts to
+// finish. If they take too long the range is unfrozen by starting the
+// migration over, and an error is returned.
+func waitFrozen(ctx context.Context, src blockindex.BlockIndexServiceClient, r *blockindex.MigrationRequest) error {
+	deadline := time.Now().Add(migrationFreezeTimeout)
+	for {
+		resp, err := src.FreezeRange(ctx, r)
+		if err == nil && resp.Error != "" {
+			err = errors.New(resp.Error)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to freeze range: %w", err)
+		}
+		if resp.Intents == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			src.BeginMigration(ctx, r)
+			return fmt.Errorf("%d uncommitted puts did not finish", resp.Intents)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// migrationError returns the error of a migration call
+func migrationError(resp *blockindex.MigrationResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// getIndexClient gets or creates a gRPC client for an index node
+func (m *Master) getIndexClient(addr string) (blockindex.BlockIndexServiceClient, error) {
+	m.shardMu.Lock()
+	defer m.shardMu.Unlock()
+
+	if client, exists := m.indexClients[addr]; exists {
+		return client, nil
+	}
+
+	conn, err := grpc.NewClient(addr, m.dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to index node %s: %w", addr, err)
+	}
+
+	client := blockindex.NewBlockIndexServiceClient(conn)
+	m.indexClients[addr] = client
+	return client, nil
+}
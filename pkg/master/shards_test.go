@@ -0,0 +1,222 @@
+package master
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"bharani/pkg/blockindex"
+	"bharani/pkg/config"
+	"bharani/pkg/shards"
+	blockindexpb "bharani/proto/blockindex"
+	masterpb "bharani/proto/master"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// serveIndex starts a block index node on a free loopback port
+func serveIndex(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	index, err := blockindex.NewSQLiteIndex(filepath.Join(dir, name+".db"))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	t.Cleanup(func() { index.Close() })
+
+	return serveTest(t, func(s *grpc.Server) {
+		blockindexpb.RegisterBlockIndexServiceServer(s, blockindex.NewBlockIndexService(index))
+	})
+}
+
+// serveTest starts a gRPC server on a free loopback port and returns its address
+func serveTest(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String()
+}
+
+func testHash(i int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("block-%d", i)))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAddIndexShardMovesRange(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	dialOption := grpc.WithTransportCredentials(insecure.NewCredentials())
+
+	firstAddr := serveIndex(t, dir, "first")
+	secondAddr := serveIndex(t, dir, "second")
+
+	m, err := NewMaster(config.DefaultConfig(), "cell1", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create master: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	shardsPath := filepath.Join(dir, "shards.json")
+	if err := m.LoadShards(shardsPath, firstAddr); err != nil {
+		t.Fatalf("Failed to load shards: %v", err)
+	}
+	masterAddr := serveTest(t, func(s *grpc.Server) {
+		masterpb.RegisterMasterServiceServer(s, NewMasterService(m))
+	})
+	masterConn, err := grpc.NewClient(masterAddr, dialOption)
+	if err != nil {
+		t.Fatalf("Failed to connect to master: %v", err)
+	}
+	defer masterConn.Close()
+	client := shards.NewClient(firstAddr, masterpb.NewMasterServiceClient(masterConn), dialOption)
+
+	put := func(i int) error {
+		hash := testHash(i)
+		resp, err := client.PutEntry(ctx, &blockindexpb.PutEntryRequest{Hash: hash, CellId: "cell1", BucketId: "b1", Checksum: hash, VolumeId: "v1", Size: 10})
+		if err == nil && !resp.Success {
+			err = fmt.Errorf("%s", resp.Error)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to put block %d: %w", i, err)
+		}
+
+		refs, err := client.AddRefs(ctx, &blockindexpb.AddRefsRequest{Hashes: []string{hash}, Tenant: "red", Owner: "photo"})
+		if err == nil && len(refs.Found) != 1 {
+			err = fmt.Errorf("block not found: %s", refs.Error)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to reference block %d: %w", i, err)
+		}
+		return nil
+	}
+
+	const initial = 2500
+	for i := range initial {
+		if err := put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Keep writing while the range moves
+	stop := make(chan struct{})
+	var (
+		written  = initial
+		writeErr error
+		wg       sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if writeErr = put(written); writeErr != nil {
+				return
+			}
+			written++
+		}
+	}()
+
+	resp, err := m.AddIndexShard(ctx, &masterpb.AddIndexShardRequest{Address: secondAddr})
+	if err != nil || !resp.Success {
+		t.Fatalf("Failed to add index shard: %v %s", err, resp.GetError())
+	}
+	if resp.Start != "8000000000000000" || resp.End != "" {
+		t.Fatalf("Expected the upper half of the hash space to move, got [%s, %s)", resp.Start, resp.End)
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		shardMap, _ := m.GetShardMap(ctx, &masterpb.GetShardMapRequest{})
+		if len(shardMap.Shards) == 2 && shardMap.Shards[1].Source == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Range did not finish moving: %+v", shardMap.Shards)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Let writes continue with the new map before stopping
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	if writeErr != nil {
+		t.Fatalf("Write during the move failed: %v", writeErr)
+	}
+
+	hashes := make([]string, written)
+	for i := range written {
+		hashes[i] = testHash(i)
+	}
+	entries, err := client.GetEntries(ctx, &blockindexpb.GetEntriesRequest{Hashes: hashes})
+	if err != nil || entries.Error != "" {
+		t.Fatalf("Failed to get entries: %v %s", err, entries.GetError())
+	}
+	if len(entries.Entries) != written {
+		t.Errorf("Expected all %d blocks to be indexed, found %d", written, len(entries.Entries))
+	}
+
+	usage, err := client.GetUsage(ctx, &blockindexpb.GetUsageRequest{Tenant: "red"})
+	if err != nil || usage.Error != "" {
+		t.Fatalf("Failed to get usage: %v %s", err, usage.GetError())
+	}
+	if usage.Blocks != int64(written) || usage.Bytes != int64(10*written) {
+		t.Errorf("Expected usage of %d bytes in %d blocks, got %d in %d", 10*written, written, usage.Bytes, usage.Blocks)
+	}
+
+	// The first node no longer serves the moved range and the second holds it
+	first, err := m.getIndexClient(firstAddr)
+	if err != nil {
+		t.Fatalf("Failed to connect to index: %v", err)
+	}
+	second, err := m.getIndexClient(secondAddr)
+	if err != nil {
+		t.Fatalf("Failed to connect to index: %v", err)
+	}
+	for _, hash := range hashes[:100] {
+		moved := hash >= resp.Start
+		_, err := first.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+		if moved && status.Code(err) != codes.OutOfRange {
+			t.Errorf("Expected OutOfRange for moved block %s from the first node, got %v", hash, err)
+		}
+		if !moved && err != nil {
+			t.Errorf("Failed to get block %s from the first node: %v", hash, err)
+		}
+		entry, err := second.GetEntry(ctx, &blockindexpb.GetEntryRequest{Hash: hash})
+		if err != nil {
+			t.Fatalf("Failed to get block %s from the second node: %v", hash, err)
+		}
+		if entry.Found != moved {
+			t.Errorf("Block %s: expected found=%v on the second node, got %v", hash, moved, entry.Found)
+		}
+	}
+
+	// The map survives a restart of the master
+	saved, err := shards.LoadMap(shardsPath)
+	if err != nil {
+		t.Fatalf("Failed to load saved map: %v", err)
+	}
+	if len(saved.Shards) != 2 || saved.Shards[1].Address != secondAddr || saved.Shards[1].Source != "" {
+		t.Errorf("Expected the saved map to hold the finished move, got %+v", saved.Shards)
+	}
+}
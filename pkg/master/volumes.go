@@ -0,0 +1,163 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"bharani/pkg/storage"
+	"bharani/proto/master"
+
+	"github.com/google/uuid"
+)
+
+// openVolume is the master's space accounting for a volume accepting writes.
+// Writes go to the volume's active bucket until it reaches BucketSize, after
+// which a new bucket is started. Once another bucket would take the volume
+// past VolumeSize the volume is closed.
+type openVolume struct {
+	volume *storage.Volume
+	active *storage.Bucket
+}
+
+// ReserveSpace reserves room for a write in the active bucket of an open
+// volume in the cell, starting new buckets and volumes as they fill
+func (m *Master) ReserveSpace(ctx context.Context, req *master.ReserveSpaceRequest) (*master.ReserveSpaceResponse, error) {
+	cellID := req.CellId
+	if cellID == "" {
+		cellID = m.cellID
+	}
+
+	if req.Size <= 0 || req.Size > m.config.BucketSize {
+		return &master.ReserveSpaceResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid reservation size %d (bucket size %d)", req.Size, m.config.BucketSize),
+		}, nil
+	}
+
+	m.reserveMu.Lock()
+	defer m.reserveMu.Unlock()
+
+	open, bucket, err := m.reserve(ctx, cellID, req.Size, int(req.ReplicationFactor))
+	if err != nil {
+		return &master.ReserveSpaceResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &master.ReserveSpaceResponse{
+		Success:      true,
+		VolumeId:     open.volume.ID,
+		BucketId:     bucket.ID,
+		OsdAddresses: open.volume.GetOSDs(),
+	}, nil
+}
+
+// reserve finds or creates a bucket with room for size bytes, closing volumes
+// that cannot take another bucket. The caller must hold reserveMu.
+func (m *Master) reserve(ctx context.Context, cellID string, size int64, replicationFactor int) (*openVolume, *storage.Bucket, error) {
+	tracked := m.cellOpenVolumes(cellID)
+
+	for _, open := range tracked {
+		if open.active != nil && open.active.Reserve(size) == nil {
+			return open, open.active, nil
+		}
+
+		if open.volume.Size()+m.config.BucketSize <= m.config.VolumeSize {
+			bucket, err := m.startBucket(open, size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return open, bucket, nil
+		}
+
+		if err := m.closeVolume(ctx, open.volume.ID); err != nil {
+			log.Printf("Failed to close full volume %s: %v", open.volume.ID, err)
+		}
+	}
+
+	if len(tracked) == 0 {
+		// Open volumes the master has no accounting for, e.g. from before a
+		// restart, may be arbitrarily full and are closed rather than reused
+		m.closeUntrackedVolumes(ctx, cellID)
+	}
+
+	open, err := m.allocateVolume(ctx, cellID, replicationFactor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bucket, err := m.startBucket(open, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return open, bucket, nil
+}
+
+// startBucket adds a new active bucket to a volume and reserves size bytes in it
+func (m *Master) startBucket(open *openVolume, size int64) (*storage.Bucket, error) {
+	bucket := storage.NewBucket(uuid.New().String(), m.config.BucketSize)
+	if err := open.volume.AddBucket(bucket); err != nil {
+		return nil, err
+	}
+	if err := bucket.Reserve(size); err != nil {
+		return nil, err
+	}
+
+	open.active = bucket
+	return bucket, nil
+}
+
+// cellOpenVolumes returns the tracked open volumes of a cell, oldest ID first
+func (m *Master) cellOpenVolumes(cellID string) []*openVolume {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	volumes := make([]*openVolume, 0)
+	for _, open := range m.openVolumes {
+		if open.volume.CellID == cellID {
+			volumes = append(volumes, open)
+		}
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].volume.ID < volumes[j].volume.ID
+	})
+	return volumes
+}
+
+// closeUntrackedVolumes closes every open volume of a cell that the master
+// is not accounting for
+func (m *Master) closeUntrackedVolumes(ctx context.Context, cellID string) {
+	openResp, err := m.GetOpenVolumes(ctx, &master.GetOpenVolumesRequest{CellId: cellID})
+	if err != nil {
+		log.Printf("Failed to list open volumes: %v", err)
+		return
+	}
+
+	for _, volumeID := range openResp.VolumeIds {
+		m.mu.RLock()
+		_, tracked := m.openVolumes[volumeID]
+		m.mu.RUnlock()
+		if tracked {
+			continue
+		}
+
+		if err := m.closeVolume(ctx, volumeID); err != nil {
+			log.Printf("Failed to close untracked volume %s: %v", volumeID, err)
+		}
+	}
+}
+
+// VolumeUsage returns the bytes reserved in each tracked open volume
+func (m *Master) VolumeUsage() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usage := make(map[string]int64, len(m.openVolumes))
+	for volumeID, open := range m.openVolumes {
+		usage[volumeID] = open.volume.Size()
+	}
+	return usage
+}
@@ -50,6 +50,21 @@ func (s *OSDService) GetBlock(ctx context.Context, req *osd.GetBlockRequest) (*o
 	}, nil
 }
 
+// DeleteBlock handles DeleteBlock requests
+func (s *OSDService) DeleteBlock(ctx context.Context, req *osd.DeleteBlockRequest) (*osd.DeleteBlockResponse, error) {
+	err := s.osd.DeleteBlock(ctx, req.Hash, req.BucketId, req.VolumeId)
+	if err != nil {
+		return &osd.DeleteBlockResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &osd.DeleteBlockResponse{
+		Success: true,
+	}, nil
+}
+
 // HealthCheck handles health check requests
 func (s *OSDService) HealthCheck(ctx context.Context, req *osd.HealthCheckRequest) (*osd.HealthCheckResponse, error) {
 	healthy := s.osd.HealthCheck()
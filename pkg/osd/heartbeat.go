@@ -0,0 +1,75 @@
+package osd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"bharani/proto/master"
+)
+
+// Register announces this OSD, its zone and free space to the master
+func (o *OSD) Register(ctx context.Context, masterClient master.MasterServiceClient) error {
+	available, err := o.GetAvailableSpace()
+	if err != nil {
+		return fmt.Errorf("failed to get available space: %w", err)
+	}
+
+	resp, err := masterClient.RegisterOSD(ctx, &master.RegisterOSDRequest{
+		OsdAddress:     o.address,
+		CellId:         o.cellID,
+		ZoneId:         o.config.ZoneID,
+		AvailableSpace: available,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register with master: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("master rejected registration: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// RunHeartbeat registers with the master and then reports health and free
+// space every interval until ctx is cancelled
+func (o *OSD) RunHeartbeat(ctx context.Context, masterClient master.MasterServiceClient, interval time.Duration) {
+	if err := o.Register(ctx, masterClient); err != nil {
+		log.Printf("OSD %s: %v", o.address, err)
+	} else {
+		o.SetHealthy(true)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			available, err := o.GetAvailableSpace()
+			if err != nil {
+				log.Printf("OSD %s: failed to get available space: %v", o.address, err)
+				continue
+			}
+
+			_, err = masterClient.Heartbeat(ctx, &master.HeartbeatRequest{
+				OsdAddress:     o.address,
+				Healthy:        o.HealthCheck(),
+				AvailableSpace: available,
+				CellId:         o.cellID,
+				ZoneId:         o.config.ZoneID,
+			})
+			if err != nil {
+				log.Printf("OSD %s: heartbeat failed: %v", o.address, err)
+				continue
+			}
+
+			// An OSD that cannot reach the master for too long marks itself
+			// unhealthy in HealthCheck; a successful heartbeat brings it back
+			o.SetHealthy(true)
+		}
+	}
+}
@@ -61,6 +61,18 @@ func (o *OSD) GetBlock(ctx context.Context, hash, bucketID, volumeID string) ([]
 	return o.storage.GetBlock(o.cellID, bucketID, hash)
 }
 
+// DeleteBlock removes a block from this OSD
+func (o *OSD) DeleteBlock(ctx context.Context, hash, bucketID, volumeID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.healthy {
+		return fmt.Errorf("OSD is not healthy")
+	}
+
+	return o.storage.DeleteBlock(o.cellID, bucketID, hash)
+}
+
 // HealthCheck returns the health status
 func (o *OSD) HealthCheck() bool {
 	o.mu.RLock()
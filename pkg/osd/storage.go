@@ -70,6 +70,19 @@ func (s *Storage) GetBlock(cellID, bucketID, hash string) ([]byte, error) {
 	return data, nil
 }
 
+// DeleteBlock removes a block from disk. Deleting a missing block succeeds.
+func (s *Storage) DeleteBlock(cellID, bucketID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blockPath := s.getBlockPath(cellID, bucketID, hash)
+	if err := os.Remove(blockPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete block: %w", err)
+	}
+
+	return nil
+}
+
 // HasBlock checks if a block exists
 func (s *Storage) HasBlock(cellID, bucketID, hash string) bool {
 	s.mu.RLock()
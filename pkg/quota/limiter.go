@@ -0,0 +1,111 @@
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter holds each tenant's limits and the token buckets that enforce its
+// request and bandwidth rates
+type Limiter struct {
+	limits map[string]Limits
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tenantBuckets
+}
+
+// tenantBuckets are the rate limits of one tenant
+type tenantBuckets struct {
+	requests  *bucket
+	bandwidth *bucket
+}
+
+// NewLimiter creates a limiter for the given per-tenant limits, which may be
+// nil to limit nothing
+func NewLimiter(limits map[string]Limits) *Limiter {
+	return &Limiter{
+		limits:  limits,
+		now:     time.Now,
+		buckets: make(map[string]*tenantBuckets),
+	}
+}
+
+// Limits returns a tenant's limits, falling back to the default tenant's
+func (l *Limiter) Limits(tenant string) Limits {
+	if limits, ok := l.limits[tenant]; ok {
+		return limits
+	}
+	return l.limits[DefaultTenant]
+}
+
+// AllowRequest takes one request from the tenant's request rate and reports
+// whether the request may proceed
+func (l *Limiter) AllowRequest(tenant string) bool {
+	b := l.tenantBuckets(tenant).requests
+	return b == nil || b.take(1, l.now())
+}
+
+// AllowBytes takes n bytes from the tenant's bandwidth and reports whether the
+// transfer may proceed. Transfers larger than a second's worth of bandwidth
+// wait for a full bucket and leave it in debt, so they are slowed rather than
+// refused forever.
+func (l *Limiter) AllowBytes(tenant string, n int64) bool {
+	b := l.tenantBuckets(tenant).bandwidth
+	return b == nil || b.take(float64(n), l.now())
+}
+
+// tenantBuckets returns the tenant's buckets, creating them full on first use
+func (l *Limiter) tenantBuckets(tenant string) *tenantBuckets {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if tb, ok := l.buckets[tenant]; ok {
+		return tb
+	}
+
+	limits := l.Limits(tenant)
+	tb := &tenantBuckets{
+		requests:  newBucket(limits.Requests, l.now()),
+		bandwidth: newBucket(limits.Bandwidth, l.now()),
+	}
+	l.buckets[tenant] = tb
+	return tb
+}
+
+// bucket is a token bucket refilled at rate tokens per second and holding up
+// to a second's worth of tokens
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newBucket returns a full bucket, or nil when rate is unlimited
+func newBucket(rate float64, now time.Time) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	burst := max(rate, 1)
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// take removes n tokens if the bucket holds them, or holds a full burst when n
+// exceeds it
+func (b *bucket) take(n float64, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+
+	if b.tokens < min(n, b.burst) {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
@@ -0,0 +1,126 @@
+package quota
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultTenant names the limits applied to tenants without their own line
+const DefaultTenant = "*"
+
+// Limits caps a tenant's storage and request rates. Zero fields are
+// unlimited.
+type Limits struct {
+	MaxBytes  int64   // total size of the blocks the tenant references
+	MaxBlocks int64   // number of blocks the tenant references
+	Requests  float64 // requests per second
+	Bandwidth float64 // bytes read and written per second
+}
+
+// HasQuota reports whether storage usage is capped
+func (l Limits) HasQuota() bool {
+	return l.MaxBytes > 0 || l.MaxBlocks > 0
+}
+
+// LoadLimits reads per-tenant limits from a file with one line per tenant:
+//
+//	<tenant> [bytes=<size>] [blocks=<n>] [rps=<n>] [bandwidth=<size>]
+//
+// Sizes take an optional K, M, G or T suffix (powers of 1024). The tenant "*"
+// sets the limits of tenants without their own line. Blank lines and lines
+// starting with # are ignored.
+func LoadLimits(path string) (map[string]Limits, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open limits: %w", err)
+	}
+	defer file.Close()
+
+	limits := make(map[string]Limits)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		tenant := fields[0]
+		if _, ok := limits[tenant]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate tenant %s", path, line, tenant)
+		}
+
+		var l Limits
+		for _, field := range fields[1:] {
+			if err := l.set(field); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		limits[tenant] = l
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+// set parses one key=value field of a limits line
+func (l *Limits) set(field string) error {
+	key, value, ok := strings.Cut(field, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", field)
+	}
+
+	var err error
+	switch key {
+	case "bytes":
+		l.MaxBytes, err = parseSize(value)
+	case "blocks":
+		l.MaxBlocks, err = strconv.ParseInt(value, 10, 64)
+	case "rps":
+		l.Requests, err = strconv.ParseFloat(value, 64)
+	case "bandwidth":
+		var size int64
+		size, err = parseSize(value)
+		l.Bandwidth = float64(size)
+	default:
+		return fmt.Errorf("unknown limit %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if l.MaxBytes < 0 || l.MaxBlocks < 0 || l.Requests < 0 || l.Bandwidth < 0 {
+		return fmt.Errorf("%s must not be negative", key)
+	}
+	return nil
+}
+
+// parseSize parses a byte count with an optional K, M, G or T suffix
+func parseSize(s string) (int64, error) {
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch strings.ToUpper(s[n-1:]) {
+		case "K":
+			multiplier = 1 << 10
+		case "M":
+			multiplier = 1 << 20
+		case "G":
+			multiplier = 1 << 30
+		case "T":
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,85 @@
+package quota
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits")
+	text := "# per-team limits\nred bytes=10G blocks=1000 rps=50 bandwidth=8M\n\n* rps=5\n"
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatalf("Failed to write limits: %v", err)
+	}
+
+	limits, err := LoadLimits(path)
+	if err != nil {
+		t.Fatalf("Failed to load limits: %v", err)
+	}
+	want := Limits{MaxBytes: 10 << 30, MaxBlocks: 1000, Requests: 50, Bandwidth: 8 << 20}
+	if limits["red"] != want {
+		t.Errorf("Expected %+v, got %+v", want, limits["red"])
+	}
+
+	l := NewLimiter(limits)
+	if got := l.Limits("blue"); got != (Limits{Requests: 5}) || got.HasQuota() {
+		t.Errorf("Expected default limits for blue, got %+v", got)
+	}
+	if got := NewLimiter(nil).Limits("red"); got != (Limits{}) {
+		t.Errorf("Expected no limits, got %+v", got)
+	}
+
+	for _, bad := range []string{"red bytes\n", "red bytes=lots\n", "red color=blue\n", "red blocks=-1\n", "red\nred\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0600); err != nil {
+			t.Fatalf("Failed to write limits: %v", err)
+		}
+		if _, err := LoadLimits(path); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewLimiter(map[string]Limits{"red": {Requests: 2, Bandwidth: 100}})
+	l.now = func() time.Time { return now }
+
+	// A full bucket allows a burst of one second's requests, then refills at
+	// the configured rate
+	for i := 0; i < 2; i++ {
+		if !l.AllowRequest("red") {
+			t.Fatalf("Request %d should be allowed", i)
+		}
+	}
+	if l.AllowRequest("red") {
+		t.Error("Expected request over the rate to be refused")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if !l.AllowRequest("red") {
+		t.Error("Expected request after refill to be allowed")
+	}
+
+	// Tenants have their own buckets, and tenants without limits are not
+	// limited
+	for i := 0; i < 100; i++ {
+		if !l.AllowRequest("blue") {
+			t.Fatal("Unlimited tenant was refused")
+		}
+	}
+
+	// A transfer larger than the burst goes through from a full bucket and
+	// leaves it in debt until refilled
+	if !l.AllowBytes("red", 250) {
+		t.Fatal("Expected large transfer from a full bucket to be allowed")
+	}
+	now = now.Add(time.Second)
+	if l.AllowBytes("red", 1) {
+		t.Error("Expected transfer to be refused while in debt")
+	}
+	now = now.Add(time.Second)
+	if !l.AllowBytes("red", 1) {
+		t.Error("Expected transfer after the debt is repaid to be allowed")
+	}
+}
@@ -2,8 +2,11 @@ package replication
 
 import (
 	"context"
+	"time"
 
 	"bharani/proto/replication"
+
+	"google.golang.org/protobuf/proto"
 )
 
 // ReplicationTableService implements the gRPC ReplicationTable service
@@ -12,6 +15,34 @@ type ReplicationTableService struct {
 	replication.UnimplementedReplicationTableServiceServer
 }
 
+// ReadMethods are the methods of the service that do not change the table
+var ReadMethods = []string{"GetVolume", "ListVolumes"}
+
+// StampRequest sets the timestamps a write request leaves unset to now. A
+// replicated table stamps each write once, on the leader, so that every
+// replica records the same times.
+func StampRequest(req proto.Message, now time.Time) {
+	switch req := req.(type) {
+	case *replication.CreateVolumeRequest:
+		if req.CreatedAt == 0 {
+			req.CreatedAt = now.Unix()
+		}
+	case *replication.UpdateVolumeRequest:
+		if req.UpdatedAt == 0 {
+			req.UpdatedAt = now.Unix()
+		}
+	}
+}
+
+// requestTime returns the time a request was stamped with, or the current
+// time for an unreplicated table that received it unstamped
+func requestTime(at int64) time.Time {
+	if at == 0 {
+		return time.Now()
+	}
+	return time.Unix(at, 0)
+}
+
 // NewReplicationTableService creates a new ReplicationTable service
 func NewReplicationTableService(table *Table) *ReplicationTableService {
 	return &ReplicationTableService{
@@ -21,7 +52,7 @@ func NewReplicationTableService(table *Table) *ReplicationTableService {
 
 // CreateVolume handles CreateVolume requests
 func (s *ReplicationTableService) CreateVolume(ctx context.Context, req *replication.CreateVolumeRequest) (*replication.CreateVolumeResponse, error) {
-	err := s.table.CreateVolume(req.VolumeId, req.OsdAddresses, req.CellId)
+	err := s.table.CreateVolume(req.VolumeId, req.OsdAddresses, req.CellId, requestTime(req.CreatedAt))
 	if err != nil {
 		return &replication.CreateVolumeResponse{
 			Success: false,
@@ -57,12 +88,21 @@ func (s *ReplicationTableService) GetVolume(ctx context.Context, req *replicatio
 		Generation:   info.Generation,
 		CellId:       info.CellID,
 		State:        info.State,
+		UpdatedAt:    info.UpdatedAt.Unix(),
+		DataShards:   int32(info.DataShards),
+		ParityShards: int32(info.ParityShards),
 	}, nil
 }
 
-// UpdateVolume handles UpdateVolume requests
+// UpdateVolume handles UpdateVolume requests. Requests with data shards
+// convert the volume to erasure coding.
 func (s *ReplicationTableService) UpdateVolume(ctx context.Context, req *replication.UpdateVolumeRequest) (*replication.UpdateVolumeResponse, error) {
-	err := s.table.UpdateVolume(req.VolumeId, req.OsdAddresses, req.Generation, req.State)
+	var err error
+	if req.DataShards > 0 {
+		err = s.table.ErasureCodeVolume(req.VolumeId, req.OsdAddresses, req.Generation, int(req.DataShards), int(req.ParityShards), requestTime(req.UpdatedAt))
+	} else {
+		err = s.table.UpdateVolume(req.VolumeId, req.OsdAddresses, req.Generation, req.State, requestTime(req.UpdatedAt))
+	}
 	if err != nil {
 		return &replication.UpdateVolumeResponse{
 			Success: false,
@@ -75,6 +115,21 @@ func (s *ReplicationTableService) UpdateVolume(ctx context.Context, req *replica
 	}, nil
 }
 
+// DeleteVolume handles DeleteVolume requests
+func (s *ReplicationTableService) DeleteVolume(ctx context.Context, req *replication.DeleteVolumeRequest) (*replication.DeleteVolumeResponse, error) {
+	err := s.table.DeleteVolume(req.VolumeId)
+	if err != nil {
+		return &replication.DeleteVolumeResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &replication.DeleteVolumeResponse{
+		Success: true,
+	}, nil
+}
+
 // ListVolumes handles ListVolumes requests
 func (s *ReplicationTableService) ListVolumes(ctx context.Context, req *replication.ListVolumesRequest) (*replication.ListVolumesResponse, error) {
 	volumeIDs, err := s.table.ListVolumes(req.CellId)
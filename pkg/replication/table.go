@@ -3,15 +3,21 @@ package replication
 import (
 	"database/sql"
 	"fmt"
+	"io"
+	"os"
 	"sync"
+	"time"
+
+	"bharani/pkg/storage"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // Table stores the mapping from volumes to OSDs
 type Table struct {
-	db *sql.DB
-	mu sync.RWMutex
+	db   *sql.DB
+	path string
+	mu   sync.RWMutex
 }
 
 // VolumeInfo represents volume information
@@ -21,6 +27,12 @@ type VolumeInfo struct {
 	Generation  int64
 	CellID      string
 	State       string
+	UpdatedAt   time.Time // Last change to the volume's state or replicas
+
+	// Erasure-coded volumes hold shard i of each block on OSDAddresses[i];
+	// both counts are 0 for replicated volumes
+	DataShards   int
+	ParityShards int
 }
 
 // NewTable creates a new replication table
@@ -30,7 +42,7 @@ func NewTable(dbPath string) (*Table, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	table := &Table{db: db}
+	table := &Table{db: db, path: dbPath}
 
 	if err := table.initSchema(); err != nil {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
@@ -47,8 +59,8 @@ func (t *Table) initSchema() error {
 		cell_id TEXT NOT NULL,
 		generation INTEGER NOT NULL DEFAULT 1,
 		state TEXT NOT NULL DEFAULT 'open',
-		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
-		updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
 	);
 	
 	CREATE TABLE IF NOT EXISTS volume_osds (
@@ -61,12 +73,57 @@ func (t *Table) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_cell ON volumes(cell_id);
 	`
 
-	_, err := t.db.Exec(query)
-	return err
+	if _, err := t.db.Exec(query); err != nil {
+		return err
+	}
+
+	if err := t.addColumn("volumes", "data_shards", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := t.addColumn("volumes", "parity_shards", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return t.addColumn("volume_osds", "shard_index", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// addColumn adds a column to an existing table unless it is already present
+func (t *Table) addColumn(table, column, definition string) error {
+	var present int
+	err := t.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&present)
+	if err != nil {
+		return fmt.Errorf("failed to read %s schema: %w", table, err)
+	}
+	if present > 0 {
+		return nil
+	}
+
+	if _, err := t.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// setOSDs replaces a volume's OSD addresses, recording each one's position as
+// its shard index
+func setOSDs(tx *sql.Tx, volumeID string, osdAddresses []string) error {
+	if _, err := tx.Exec(`DELETE FROM volume_osds WHERE volume_id = ?`, volumeID); err != nil {
+		return fmt.Errorf("failed to delete old OSD addresses: %w", err)
+	}
+
+	for i, osdAddr := range osdAddresses {
+		_, err := tx.Exec(`
+			INSERT INTO volume_osds (volume_id, osd_address, shard_index)
+			VALUES (?, ?, ?)
+		`, volumeID, osdAddr, i)
+		if err != nil {
+			return fmt.Errorf("failed to add OSD address: %w", err)
+		}
+	}
+	return nil
 }
 
-// CreateVolume creates a new volume with OSD addresses
-func (t *Table) CreateVolume(volumeID string, osdAddresses []string, cellID string) error {
+// CreateVolume creates a new volume with OSD addresses, created at at
+func (t *Table) CreateVolume(volumeID string, osdAddresses []string, cellID string, at time.Time) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -77,21 +134,15 @@ func (t *Table) CreateVolume(volumeID string, osdAddresses []string, cellID stri
 	defer tx.Rollback()
 
 	_, err = tx.Exec(`
-		INSERT INTO volumes (volume_id, cell_id, generation, state)
-		VALUES (?, ?, 1, 'open')
-	`, volumeID, cellID)
+		INSERT INTO volumes (volume_id, cell_id, generation, state, created_at, updated_at)
+		VALUES (?, ?, 1, 'open', ?, ?)
+	`, volumeID, cellID, at.Unix(), at.Unix())
 	if err != nil {
 		return fmt.Errorf("failed to create volume: %w", err)
 	}
 
-	for _, osdAddr := range osdAddresses {
-		_, err = tx.Exec(`
-			INSERT INTO volume_osds (volume_id, osd_address)
-			VALUES (?, ?)
-		`, volumeID, osdAddr)
-		if err != nil {
-			return fmt.Errorf("failed to add OSD address: %w", err)
-		}
+	if err := setOSDs(tx, volumeID, osdAddresses); err != nil {
+		return err
 	}
 
 	return tx.Commit()
@@ -104,13 +155,13 @@ func (t *Table) GetVolume(volumeID string) (*VolumeInfo, error) {
 
 	var info VolumeInfo
 	var cellID, state string
-	var generation int64
+	var generation, updatedAt int64
 
 	err := t.db.QueryRow(`
-		SELECT volume_id, cell_id, generation, state
+		SELECT volume_id, cell_id, generation, state, updated_at, data_shards, parity_shards
 		FROM volumes
 		WHERE volume_id = ?
-	`, volumeID).Scan(&info.VolumeID, &cellID, &generation, &state)
+	`, volumeID).Scan(&info.VolumeID, &cellID, &generation, &state, &updatedAt, &info.DataShards, &info.ParityShards)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -122,11 +173,13 @@ func (t *Table) GetVolume(volumeID string) (*VolumeInfo, error) {
 	info.CellID = cellID
 	info.Generation = generation
 	info.State = state
+	info.UpdatedAt = time.Unix(updatedAt, 0)
 
 	rows, err := t.db.Query(`
 		SELECT osd_address
 		FROM volume_osds
 		WHERE volume_id = ?
+		ORDER BY shard_index, rowid
 	`, volumeID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get OSD addresses: %w", err)
@@ -146,8 +199,9 @@ func (t *Table) GetVolume(volumeID string) (*VolumeInfo, error) {
 	return &info, nil
 }
 
-// UpdateVolume updates volume information
-func (t *Table) UpdateVolume(volumeID string, osdAddresses []string, generation int64, state string) error {
+// UpdateVolume updates volume information, recording at as the time of the
+// change
+func (t *Table) UpdateVolume(volumeID string, osdAddresses []string, generation int64, state string, at time.Time) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -160,33 +214,86 @@ func (t *Table) UpdateVolume(volumeID string, osdAddresses []string, generation
 	if state != "" {
 		_, err = tx.Exec(`
 			UPDATE volumes
-			SET generation = ?, state = ?, updated_at = strftime('%s', 'now')
+			SET generation = ?, state = ?, updated_at = ?
 			WHERE volume_id = ?
-		`, generation, state, volumeID)
+		`, generation, state, at.Unix(), volumeID)
 	} else {
 		_, err = tx.Exec(`
 			UPDATE volumes
-			SET generation = ?, updated_at = strftime('%s', 'now')
+			SET generation = ?, updated_at = ?
 			WHERE volume_id = ?
-		`, generation, volumeID)
+		`, generation, at.Unix(), volumeID)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to update volume: %w", err)
 	}
 
-	_, err = tx.Exec(`DELETE FROM volume_osds WHERE volume_id = ?`, volumeID)
-	if err != nil {
-		return fmt.Errorf("failed to delete old OSD addresses: %w", err)
+	if err := setOSDs(tx, volumeID, osdAddresses); err != nil {
+		return err
 	}
 
-	for _, osdAddr := range osdAddresses {
-		_, err = tx.Exec(`
-			INSERT INTO volume_osds (volume_id, osd_address)
-			VALUES (?, ?)
-		`, volumeID, osdAddr)
-		if err != nil {
-			return fmt.Errorf("failed to add OSD address: %w", err)
+	return tx.Commit()
+}
+
+// ErasureCodeVolume records that a volume's blocks are now stored as
+// dataShards+parityShards erasure-coded shards, shard i on shardOSDs[i]. The
+// volume's state becomes erasure_coded, changed at at.
+func (t *Table) ErasureCodeVolume(volumeID string, shardOSDs []string, generation int64, dataShards, parityShards int, at time.Time) error {
+	if dataShards <= 0 || parityShards < 0 || len(shardOSDs) != dataShards+parityShards {
+		return fmt.Errorf("erasure-coded volume needs one OSD per shard: have %d for %d+%d shards",
+			len(shardOSDs), dataShards, parityShards)
+	}
+	seen := make(map[string]bool, len(shardOSDs))
+	for _, osdAddr := range shardOSDs {
+		if seen[osdAddr] {
+			return fmt.Errorf("OSD %s cannot hold two shards of a volume", osdAddr)
 		}
+		seen[osdAddr] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tx, err := t.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE volumes
+		SET generation = ?, state = ?, data_shards = ?, parity_shards = ?, updated_at = ?
+		WHERE volume_id = ?
+	`, generation, string(storage.VolumeStateErasureCoded), dataShards, parityShards, at.Unix(), volumeID)
+	if err != nil {
+		return fmt.Errorf("failed to update volume: %w", err)
+	}
+
+	if err := setOSDs(tx, volumeID, shardOSDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteVolume removes a volume and its OSD addresses. Deleting a missing
+// volume succeeds.
+func (t *Table) DeleteVolume(volumeID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tx, err := t.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Foreign keys are not enforced by default, so the cascade cannot be relied on
+	if _, err := tx.Exec(`DELETE FROM volume_osds WHERE volume_id = ?`, volumeID); err != nil {
+		return fmt.Errorf("failed to delete OSD addresses: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM volumes WHERE volume_id = ?`, volumeID); err != nil {
+		return fmt.Errorf("failed to delete volume: %w", err)
 	}
 
 	return tx.Commit()
@@ -227,6 +334,66 @@ func (t *Table) ListVolumes(cellID string) ([]string, error) {
 	return volumeIDs, nil
 }
 
+// Snapshot writes a consistent copy of the table to path
+func (t *Table) Snapshot(path string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if _, err := t.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to snapshot table: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the table with a copy written by Snapshot
+func (t *Table) Restore(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.db.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	if err := replaceFile(path, t.path); err != nil {
+		return fmt.Errorf("failed to restore table: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	t.db = db
+
+	if err := t.initSchema(); err != nil {
+		return fmt.Errorf("failed to initialize schema: %w", err)
+	}
+	return nil
+}
+
+// replaceFile copies src over dst atomically
+func replaceFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".restore"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
 // Close closes the database connection
 func (t *Table) Close() error {
 	return t.db.Close()
@@ -0,0 +1,132 @@
+package s3gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxChunkHeader bounds the length of an aws-chunked chunk header line
+const maxChunkHeader = 4096
+
+// requestBody returns the object data of a request, decoding the aws-chunked
+// framing that S3 clients use for streaming uploads and trailing checksums.
+// Chunk signatures and trailers are not verified.
+func requestBody(r *http.Request) io.Reader {
+	streaming := strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-")
+	if streaming || strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") {
+		return &chunkedReader{r: bufio.NewReader(r.Body)}
+	}
+	return r.Body
+}
+
+// chunkedReader decodes an aws-chunked body: a sequence of
+// "<hex size>[;extensions]\r\n<data>\r\n" chunks ended by a zero-size chunk
+// and optional trailer lines
+type chunkedReader struct {
+	r         *bufio.Reader
+	remaining int64 // Bytes left in the current chunk
+	done      bool
+}
+
+// Read implements io.Reader
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+
+	if c.remaining == 0 {
+		size, err := c.nextChunk()
+		if err != nil {
+			return 0, err
+		}
+		if size == 0 {
+			c.done = true
+			return 0, c.skipTrailers()
+		}
+		c.remaining = size
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if c.remaining == 0 {
+		if err := c.expectCRLF(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// nextChunk reads a chunk header and returns the chunk size
+func (c *chunkedReader) nextChunk() (int64, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return 0, err
+	}
+
+	sizeField, _, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid aws-chunked chunk header %q", line)
+	}
+	return size, nil
+}
+
+// skipTrailers consumes trailer lines up to the terminating empty line
+func (c *chunkedReader) skipTrailers() error {
+	for {
+		line, err := c.readLine()
+		if err == io.EOF {
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			return io.EOF
+		}
+	}
+}
+
+// expectCRLF consumes the line break that ends a chunk's data
+func (c *chunkedReader) expectCRLF() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if line != "" {
+		return fmt.Errorf("malformed aws-chunked body: expected end of chunk")
+	}
+	return nil
+}
+
+// readLine reads one CRLF- or LF-terminated line without the terminator
+func (c *chunkedReader) readLine() (string, error) {
+	var line []byte
+	for {
+		fragment, isPrefix, err := c.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, fragment...)
+		if len(line) > maxChunkHeader {
+			return "", fmt.Errorf("aws-chunked header line too long")
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package s3gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"bharani/pkg/frontend"
+	"bharani/pkg/gateway"
+	"bharani/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultMaxKeys is the ListObjectsV2 page size when max-keys is not given
+	defaultMaxKeys = 1000
+
+	// listBatchSize is the number of objects read from the store at a time while listing
+	listBatchSize = 1000
+)
+
+// bucketNamePattern accepts DNS-compatible S3 bucket names
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// Backend stores object data as files described by block manifests
+type Backend interface {
+	gateway.Backend
+	PutManifest(ctx context.Context, manifest *storage.Manifest, owner string) (string, error)
+}
+
+// Gateway serves a subset of the S3 REST API with path-style addressing.
+// Object data is stored through the backend as chunked files and the object
+// namespace is kept in the metadata store. Request signatures are accepted
+// without verification. Each object write and each uploaded part references
+// its data under an owner of its own, which is released once the object is
+// deleted or replaced, or the part is replaced, abandoned or aborted, so the
+// garbage collector can reclaim blocks nothing else uses.
+type Gateway struct {
+	backend Backend
+	store   *Store
+}
+
+// NewGateway creates an S3 gateway
+func NewGateway(backend Backend, store *Store) *Gateway {
+	return &Gateway{
+		backend: backend,
+		store:   store,
+	}
+}
+
+// ServeHTTP routes a request by bucket, key, method and sub-resource
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+
+	switch {
+	case bucket == "":
+		if r.Method == http.MethodGet {
+			g.listBuckets(w, r)
+			return
+		}
+
+	case key == "":
+		if !bucketNamePattern.MatchString(bucket) {
+			writeError(w, r, errInvalidBucket)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			g.createBucket(w, r, bucket)
+			return
+		case http.MethodHead:
+			g.headBucket(w, r, bucket)
+			return
+		case http.MethodDelete:
+			g.deleteBucket(w, r, bucket)
+			return
+		case http.MethodGet:
+			if query.Has("location") {
+				g.getBucketLocation(w, r, bucket)
+				return
+			}
+			if query.Get("list-type") == "2" {
+				g.listObjectsV2(w, r, bucket)
+				return
+			}
+		}
+
+	default:
+		switch r.Method {
+		case http.MethodPut:
+			if r.Header.Get("X-Amz-Copy-Source") != "" {
+				break
+			}
+			if query.Has("uploadId") {
+				g.uploadPart(w, r, bucket, key)
+				return
+			}
+			g.putObject(w, r, bucket, key)
+			return
+		case http.MethodGet, http.MethodHead:
+			if !query.Has("uploadId") && !query.Has("partNumber") {
+				g.getObject(w, r, bucket, key)
+				return
+			}
+		case http.MethodDelete:
+			if query.Has("uploadId") {
+				g.abortMultipartUpload(w, r, bucket, key)
+				return
+			}
+			g.deleteObject(w, r, bucket, key)
+			return
+		case http.MethodPost:
+			if query.Has("uploads") {
+				g.createMultipartUpload(w, r, bucket, key)
+				return
+			}
+			if query.Has("uploadId") {
+				g.completeMultipartUpload(w, r, bucket, key)
+				return
+			}
+		}
+	}
+
+	writeError(w, r, errNotImplemented)
+}
+
+// listBuckets handles ListBuckets
+func (g *Gateway) listBuckets(w http.ResponseWriter, r *http.Request) {
+	buckets, err := g.store.ListBuckets()
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	result := listBucketsResult{
+		Xmlns:   s3Namespace,
+		Owner:   owner{ID: "bharani", DisplayName: "bharani"},
+		Buckets: make([]bucketEntry, 0, len(buckets)),
+	}
+	for _, bucket := range buckets {
+		result.Buckets = append(result.Buckets, bucketEntry{
+			Name:         bucket.Name,
+			CreationDate: s3Time(bucket.Created),
+		})
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+// createBucket handles CreateBucket
+func (g *Gateway) createBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if err := g.store.CreateBucket(bucket); err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	w.Header().Set("Location", "/"+bucket)
+	w.WriteHeader(http.StatusOK)
+}
+
+// headBucket handles HeadBucket
+func (g *Gateway) headBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	exists, err := g.store.BucketExists(bucket)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+	if !exists {
+		writeError(w, r, errNoSuchBucket)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteBucket handles DeleteBucket
+func (g *Gateway) deleteBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if err := g.store.DeleteBucket(bucket); err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getBucketLocation handles GetBucketLocation; the gateway has no regions
+func (g *Gateway) getBucketLocation(w http.ResponseWriter, r *http.Request, bucket string) {
+	exists, err := g.store.BucketExists(bucket)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+	if !exists {
+		writeError(w, r, errNoSuchBucket)
+		return
+	}
+
+	writeXML(w, http.StatusOK, locationConstraint{Xmlns: s3Namespace})
+}
+
+// putObject handles PutObject, storing the body as a chunked file
+func (g *Gateway) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if exists, err := g.store.BucketExists(bucket); err != nil || !exists {
+		writeError(w, r, toS3Error(orNoSuchBucket(err)))
+		return
+	}
+
+	owner := objectOwner(bucket, key, uuid.New().String())
+	info, sum, s3err := g.storeBody(r, owner)
+	if s3err != nil {
+		writeError(w, r, s3err)
+		return
+	}
+
+	obj := &Object{
+		Bucket:       bucket,
+		Key:          key,
+		ManifestHash: info.Hash,
+		Owner:        owner,
+		Size:         info.Size,
+		ETag:         hex.EncodeToString(sum),
+		ContentType:  contentType(r),
+		Modified:     time.Now(),
+	}
+	replaced, err := g.store.PutObject(obj)
+	if err != nil {
+		g.releaseFile(r.Context(), info.Hash, owner)
+		writeError(w, r, toS3Error(err))
+		return
+	}
+	g.releaseObject(r.Context(), replaced)
+
+	w.Header().Set("ETag", quote(obj.ETag))
+	w.WriteHeader(http.StatusOK)
+}
+
+// storeBody stores a request body as a file referenced by owner and returns
+// its MD5, checking it against Content-MD5 when the client sent one
+func (g *Gateway) storeBody(r *http.Request, owner string) (*frontend.FileInfo, []byte, *s3Error) {
+	digest := md5.New()
+	info, err := g.backend.PutFile(r.Context(), io.TeeReader(requestBody(r), digest), owner)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return nil, nil, errIncompleteBody
+		}
+		return nil, nil, toS3Error(err)
+	}
+	sum := digest.Sum(nil)
+
+	if expected := r.Header.Get("Content-MD5"); expected != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(expected); err != nil || !bytes.Equal(decoded, sum) {
+			g.releaseFile(r.Context(), info.Hash, owner)
+			return nil, nil, errBadDigest
+		}
+	}
+
+	return info, sum, nil
+}
+
+// objectOwner names the reference owner of data written for an object. Every
+// write gets a version of its own, so releasing the data of one write never
+// drops the references of another that stored the same blocks.
+func objectOwner(bucket, key, version string) string {
+	return bucket + "/" + key + "@" + version
+}
+
+// releaseFile drops owner's references to a file. Files written before owners
+// were recorded have none and stay pinned. Releases run after the namespace
+// change they follow has committed, so the request still succeeds if one
+// fails: the failure is logged and the file's blocks stay referenced, which
+// keeps them stored rather than losing data.
+func (g *Gateway) releaseFile(ctx context.Context, hash, owner string) {
+	if owner == "" {
+		return
+	}
+	if _, err := g.backend.ReleaseFile(ctx, hash, owner); err != nil {
+		log.Printf("Failed to release file %s for %s: %v", hash, owner, err)
+	}
+}
+
+// releaseObject drops the references a deleted or replaced object held to its
+// manifest and parts
+func (g *Gateway) releaseObject(ctx context.Context, obj *Object) {
+	if obj == nil {
+		return
+	}
+	g.releaseFile(ctx, obj.ManifestHash, obj.Owner)
+	g.releaseParts(ctx, obj.Parts)
+}
+
+// releaseParts drops the references held to the files of uploaded parts
+func (g *Gateway) releaseParts(ctx context.Context, parts []*Part) {
+	for _, part := range parts {
+		g.releaseFile(ctx, part.ManifestHash, part.Owner)
+	}
+}
+
+// getObject handles GetObject and HeadObject, honouring Range and conditional headers
+func (g *Gateway) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	obj, err := g.store.GetObject(bucket, key)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	reader, err := gateway.NewFileReader(r.Context(), g.backend, obj.ManifestHash)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	w.Header().Set("ETag", quote(obj.ETag))
+	w.Header().Set("Content-Type", obj.ContentType)
+	http.ServeContent(w, r, "", obj.Modified, reader)
+}
+
+// deleteObject handles DeleteObject, releasing the object's data
+func (g *Gateway) deleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	deleted, err := g.store.DeleteObject(bucket, key)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+	g.releaseObject(r.Context(), deleted)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listObjectsV2 handles ListObjectsV2, rolling keys that contain the
+// delimiter after the prefix up into common prefixes
+func (g *Gateway) listObjectsV2(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+
+	maxKeys := defaultMaxKeys
+	if v := query.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, errInvalidArgument)
+			return
+		}
+		maxKeys = min(n, defaultMaxKeys)
+	}
+
+	start := query.Get("start-after")
+	token := query.Get("continuation-token")
+	if token != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			writeError(w, r, errInvalidArgument)
+			return
+		}
+		start = string(decoded)
+	}
+
+	result := listObjectsV2Result{
+		Xmlns:             s3Namespace,
+		Name:              bucket,
+		Prefix:            prefix,
+		Delimiter:         delimiter,
+		StartAfter:        query.Get("start-after"),
+		ContinuationToken: token,
+		MaxKeys:           maxKeys,
+		Contents:          make([]objectEntry, 0),
+		CommonPrefixes:    make([]commonPrefix, 0),
+	}
+
+	// A start position ending in the delimiter is a common prefix returned on
+	// an earlier page, so the keys under it must be skipped as well
+	skipPrefix := ""
+	if delimiter != "" && strings.HasPrefix(start, prefix) && strings.HasSuffix(start, delimiter) {
+		skipPrefix = start
+	}
+
+	last := ""
+	for {
+		objects, err := g.store.ListObjects(bucket, prefix, start, listBatchSize)
+		if err != nil {
+			writeError(w, r, toS3Error(err))
+			return
+		}
+
+		for _, obj := range objects {
+			start = obj.Key
+			if skipPrefix != "" && strings.HasPrefix(obj.Key, skipPrefix) {
+				continue
+			}
+
+			if result.KeyCount == maxKeys {
+				result.IsTruncated = true
+				break
+			}
+
+			if delimiter != "" {
+				if i := strings.Index(obj.Key[len(prefix):], delimiter); i >= 0 {
+					common := obj.Key[:len(prefix)+i+len(delimiter)]
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: common})
+					result.KeyCount++
+					skipPrefix = common
+					last = common
+					continue
+				}
+			}
+
+			result.Contents = append(result.Contents, objectEntry{
+				Key:          obj.Key,
+				LastModified: s3Time(obj.Modified),
+				ETag:         quote(obj.ETag),
+				Size:         obj.Size,
+				StorageClass: "STANDARD",
+			})
+			result.KeyCount++
+			last = obj.Key
+		}
+
+		if result.IsTruncated || len(objects) < listBatchSize {
+			break
+		}
+	}
+
+	if result.IsTruncated {
+		result.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+	}
+
+	writeXML(w, http.StatusOK, result)
+}
+
+// orNoSuchBucket returns err, or ErrNoSuchBucket when err is nil
+func orNoSuchBucket(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrNoSuchBucket
+}
+
+// contentType returns the request's content type, defaulting to binary
+func contentType(r *http.Request) string {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		return ct
+	}
+	return "binary/octet-stream"
+}
+
+// quote formats an ETag value as S3 returns it
+func quote(etag string) string {
+	return `"` + etag + `"`
+}
+
+// unquote strips the quotes clients may include around an ETag
+func unquote(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+// objectURL returns the path-style location of an object
+func objectURL(r *http.Request, bucket, key string) string {
+	u := url.URL{Scheme: "http", Host: r.Host, Path: "/" + bucket + "/" + key}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
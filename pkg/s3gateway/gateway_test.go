@@ -0,0 +1,515 @@
+package s3gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"bharani/pkg/auth"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func newTestClient(t *testing.T) *s3.Client {
+	client, _ := newTestGateway(t)
+	return client
+}
+
+// newTestGateway serves a gateway over an in-memory backend and returns a
+// client for it along with the backend
+func newTestGateway(t *testing.T) (*s3.Client, *MemoryBackend) {
+	t.Helper()
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "s3.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	backend := NewMemoryBackend(4 * 1024 * 1024)
+	server := httptest.NewServer(NewGateway(backend, store))
+	t.Cleanup(server.Close)
+
+	return newS3Client(server.URL, ""), backend
+}
+
+// newS3Client creates a client for a gateway at url, sending apiKey with each
+// request unless it is empty
+func newS3Client(url, apiKey string) *s3.Client {
+	options := s3.Options{
+		BaseEndpoint: aws.String(url),
+		Region:       "us-east-1",
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("access", "secret", ""),
+	}
+	if apiKey != "" {
+		options.HTTPClient = apiKeyClient(apiKey)
+	}
+	return s3.New(options)
+}
+
+// apiKeyClient sends requests with an X-API-Key header
+type apiKeyClient string
+
+func (key apiKeyClient) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set(auth.APIKeyHeader, string(key))
+	return http.DefaultClient.Do(req)
+}
+
+func createBucket(t *testing.T, client *s3.Client, bucket string) {
+	t.Helper()
+
+	if _, err := client.CreateBucket(context.Background(), &s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		t.Fatalf("Failed to create bucket: %v", err)
+	}
+}
+
+func putObject(t *testing.T, client *s3.Client, bucket, key string, data []byte) *s3.PutObjectOutput {
+	t.Helper()
+
+	out, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("text/plain"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to put %s: %v", key, err)
+	}
+	return out
+}
+
+func getObject(t *testing.T, client *s3.Client, input *s3.GetObjectInput) ([]byte, *s3.GetObjectOutput) {
+	t.Helper()
+
+	out, err := client.GetObject(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", aws.ToString(input.Key), err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", aws.ToString(input.Key), err)
+	}
+	return data, out
+}
+
+func TestObjects(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	createBucket(t, client, "photos")
+
+	data := []byte(strings.Repeat("the quick brown fox ", 1000))
+	put := putObject(t, client, "photos", "2024/cat.txt", data)
+	if aws.ToString(put.ETag) == "" {
+		t.Error("PutObject should return an ETag")
+	}
+
+	got, out := getObject(t, client, &s3.GetObjectInput{Bucket: aws.String("photos"), Key: aws.String("2024/cat.txt")})
+	if !bytes.Equal(got, data) {
+		t.Fatal("Object data mismatch")
+	}
+	if aws.ToString(out.ETag) != aws.ToString(put.ETag) || aws.ToString(out.ContentType) != "text/plain" {
+		t.Errorf("Unexpected object headers: etag %s, type %s", aws.ToString(out.ETag), aws.ToString(out.ContentType))
+	}
+
+	got, _ = getObject(t, client, &s3.GetObjectInput{
+		Bucket: aws.String("photos"),
+		Key:    aws.String("2024/cat.txt"),
+		Range:  aws.String("bytes=4-8"),
+	})
+	if string(got) != "quick" {
+		t.Errorf("Range read returned %q", got)
+	}
+
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("photos"), Key: aws.String("2024/cat.txt")})
+	if err != nil {
+		t.Fatalf("Failed to head object: %v", err)
+	}
+	if aws.ToInt64(head.ContentLength) != int64(len(data)) {
+		t.Errorf("HeadObject reported %d bytes", aws.ToInt64(head.ContentLength))
+	}
+
+	empty := putObject(t, client, "photos", "empty", nil)
+	if got, _ := getObject(t, client, &s3.GetObjectInput{Bucket: aws.String("photos"), Key: aws.String("empty")}); len(got) != 0 {
+		t.Errorf("Empty object returned %d bytes", len(got))
+	}
+	if aws.ToString(empty.ETag) != `"d41d8cd98f00b204e9800998ecf8427e"` {
+		t.Errorf("Empty object ETag should be the MD5 of no data, got %s", aws.ToString(empty.ETag))
+	}
+
+	if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String("photos"), Key: aws.String("2024/cat.txt")}); err != nil {
+		t.Fatalf("Failed to delete object: %v", err)
+	}
+	_, err = client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("photos"), Key: aws.String("2024/cat.txt")})
+	var noSuchKey *types.NoSuchKey
+	if !errors.As(err, &noSuchKey) {
+		t.Errorf("Expected NoSuchKey after delete, got %v", err)
+	}
+
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("missing"), Key: aws.String("k"), Body: bytes.NewReader(data)})
+	if err == nil || !strings.Contains(err.Error(), "NoSuchBucket") {
+		t.Errorf("Expected NoSuchBucket, got %v", err)
+	}
+
+	if _, err := client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String("photos")}); err == nil {
+		t.Error("Deleting a non-empty bucket should fail")
+	}
+}
+
+func TestListObjectsV2(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	createBucket(t, client, "logs")
+
+	keys := []string{"a.txt", "dir/1", "dir/2", "dir/sub/3", "other/4", "z.txt"}
+	for _, key := range keys {
+		putObject(t, client, "logs", key, []byte(key))
+	}
+
+	out, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String("logs"), Delimiter: aws.String("/")})
+	if err != nil {
+		t.Fatalf("Failed to list objects: %v", err)
+	}
+	if got := objectKeys(out); got != "a.txt,z.txt" {
+		t.Errorf("Unexpected keys %s", got)
+	}
+	if got := commonPrefixes(out); got != "dir/,other/" {
+		t.Errorf("Unexpected common prefixes %s", got)
+	}
+
+	out, err = client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:    aws.String("logs"),
+		Prefix:    aws.String("dir/"),
+		Delimiter: aws.String("/"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to list objects: %v", err)
+	}
+	if objectKeys(out) != "dir/1,dir/2" || commonPrefixes(out) != "dir/sub/" {
+		t.Errorf("Unexpected listing under dir/: %s / %s", objectKeys(out), commonPrefixes(out))
+	}
+
+	// Page through everything two entries at a time
+	seen := make([]string, 0)
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String("logs"),
+		Delimiter: aws.String("/"),
+		MaxKeys:   aws.Int32(2),
+	})
+	pages := 0
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			t.Fatalf("Failed to list page: %v", err)
+		}
+		pages++
+		for _, obj := range page.Contents {
+			seen = append(seen, aws.ToString(obj.Key))
+		}
+		for _, prefix := range page.CommonPrefixes {
+			seen = append(seen, aws.ToString(prefix.Prefix))
+		}
+	}
+	slices.Sort(seen)
+	if pages != 2 || strings.Join(seen, ",") != "a.txt,dir/,other/,z.txt" {
+		t.Errorf("Paginated listing returned %v over %d pages", seen, pages)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	createBucket(t, client, "backups")
+
+	create, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String("backups"),
+		Key:    aws.String("db.tar"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	var whole bytes.Buffer
+	completed := make([]types.CompletedPart, 0)
+	for i := 1; i <= 3; i++ {
+		part := []byte(strings.Repeat(fmt.Sprintf("part %d ", i), 5000))
+		whole.Write(part)
+
+		out, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String("backups"),
+			Key:        aws.String("db.tar"),
+			UploadId:   create.UploadId,
+			PartNumber: aws.Int32(int32(i)),
+			Body:       bytes.NewReader(part),
+		})
+		if err != nil {
+			t.Fatalf("Failed to upload part %d: %v", i, err)
+		}
+		completed = append(completed, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(int32(i))})
+	}
+
+	_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String("backups"),
+		Key:             aws.String("db.tar"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: []types.CompletedPart{completed[1], completed[0]}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "InvalidPartOrder") {
+		t.Errorf("Expected InvalidPartOrder, got %v", err)
+	}
+
+	complete, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String("backups"),
+		Key:             aws.String("db.tar"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		t.Fatalf("Failed to complete upload: %v", err)
+	}
+	if !strings.HasSuffix(aws.ToString(complete.ETag), `-3"`) {
+		t.Errorf("Multipart ETag should end with the part count, got %s", aws.ToString(complete.ETag))
+	}
+
+	got, _ := getObject(t, client, &s3.GetObjectInput{Bucket: aws.String("backups"), Key: aws.String("db.tar")})
+	if !bytes.Equal(got, whole.Bytes()) {
+		t.Fatal("Assembled object does not match the uploaded parts")
+	}
+
+	// A range crossing the boundary between the first and second part
+	boundary := len(whole.Bytes()) / 3
+	got, _ = getObject(t, client, &s3.GetObjectInput{
+		Bucket: aws.String("backups"),
+		Key:    aws.String("db.tar"),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", boundary-3, boundary+5)),
+	})
+	if !bytes.Equal(got, whole.Bytes()[boundary-3:boundary+6]) {
+		t.Errorf("Range across parts returned %q", got)
+	}
+
+	aborted, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String("backups"),
+		Key:    aws.String("tmp"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	if _, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("backups"),
+		Key:      aws.String("tmp"),
+		UploadId: aborted.UploadId,
+	}); err != nil {
+		t.Fatalf("Failed to abort upload: %v", err)
+	}
+	_, err = client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String("backups"),
+		Key:        aws.String("tmp"),
+		UploadId:   aborted.UploadId,
+		PartNumber: aws.Int32(1),
+		Body:       bytes.NewReader([]byte("late")),
+	})
+	if err == nil || !strings.Contains(err.Error(), "NoSuchUpload") {
+		t.Errorf("Expected NoSuchUpload after abort, got %v", err)
+	}
+}
+
+func TestObjectDataIsReleased(t *testing.T) {
+	client, backend := newTestGateway(t)
+	ctx := context.Background()
+	createBucket(t, client, "data")
+
+	expectReferences := func(want int, after string) {
+		t.Helper()
+		if got := backend.References(); got != want {
+			t.Errorf("Expected %d references after %s, got %d", want, after, got)
+		}
+	}
+
+	putObject(t, client, "data", "a", []byte("first"))
+	expectReferences(2, "put")
+
+	// The same content under another key is referenced by both objects
+	putObject(t, client, "data", "b", []byte("first"))
+	putObject(t, client, "data", "a", []byte("second"))
+	expectReferences(4, "overwrite")
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String("data"), Key: aws.String(key)}); err != nil {
+			t.Fatalf("Failed to delete %s: %v", key, err)
+		}
+	}
+	expectReferences(0, "DeleteObject")
+
+	upload := func(key string, parts ...string) (*s3.CreateMultipartUploadOutput, []types.CompletedPart) {
+		t.Helper()
+		create, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: aws.String("data"), Key: aws.String(key)})
+		if err != nil {
+			t.Fatalf("Failed to create upload: %v", err)
+		}
+		completed := make([]types.CompletedPart, 0, len(parts))
+		for i, part := range parts {
+			out, err := client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     aws.String("data"),
+				Key:        aws.String(key),
+				UploadId:   create.UploadId,
+				PartNumber: aws.Int32(int32(i + 1)),
+				Body:       bytes.NewReader([]byte(part)),
+			})
+			if err != nil {
+				t.Fatalf("Failed to upload part %d: %v", i+1, err)
+			}
+			completed = append(completed, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(int32(i + 1))})
+		}
+		return create, completed
+	}
+
+	aborted, _ := upload("tmp", "one", "two")
+	if _, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("data"),
+		Key:      aws.String("tmp"),
+		UploadId: aborted.UploadId,
+	}); err != nil {
+		t.Fatalf("Failed to abort upload: %v", err)
+	}
+	expectReferences(0, "AbortMultipartUpload")
+
+	// Only the first part is used, so the second is released on completion
+	create, completed := upload("big", "one", "two")
+	if _, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String("data"),
+		Key:             aws.String("big"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed[:1]},
+	}); err != nil {
+		t.Fatalf("Failed to complete upload: %v", err)
+	}
+	expectReferences(3, "CompleteMultipartUpload")
+
+	if got, _ := getObject(t, client, &s3.GetObjectInput{Bucket: aws.String("data"), Key: aws.String("big")}); string(got) != "one" {
+		t.Errorf("Expected multipart object to read back, got %q", got)
+	}
+
+	if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String("data"), Key: aws.String("big")}); err != nil {
+		t.Fatalf("Failed to delete multipart object: %v", err)
+	}
+	expectReferences(0, "deleting the multipart object")
+}
+
+// failingReleases is a backend whose references can never be released
+type failingReleases struct {
+	*MemoryBackend
+}
+
+func (b failingReleases) ReleaseFile(ctx context.Context, hash, owner string) (int, error) {
+	return 0, errors.New("block index unavailable")
+}
+
+func TestReleaseFailuresDoNotFailRequests(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "s3.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	backend := NewMemoryBackend(4 * 1024 * 1024)
+	server := httptest.NewServer(NewGateway(failingReleases{backend}, store))
+	t.Cleanup(server.Close)
+	client := newS3Client(server.URL, "")
+	ctx := context.Background()
+	createBucket(t, client, "data")
+
+	// Each release follows a namespace change that has already committed, so
+	// a failed release leaves the data referenced instead of failing the request
+	putObject(t, client, "data", "a", []byte("first"))
+	putObject(t, client, "data", "a", []byte("second"))
+	if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String("data"), Key: aws.String("a")}); err != nil {
+		t.Errorf("DeleteObject failed: %v", err)
+	}
+
+	create, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: aws.String("data"), Key: aws.String("big")})
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	for _, part := range []string{"one", "two"} {
+		_, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String("data"),
+			Key:        aws.String("big"),
+			UploadId:   create.UploadId,
+			PartNumber: aws.Int32(1),
+			Body:       bytes.NewReader([]byte(part)),
+		})
+		if err != nil {
+			t.Errorf("UploadPart failed: %v", err)
+		}
+	}
+	if _, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("data"),
+		Key:      aws.String("big"),
+		UploadId: create.UploadId,
+	}); err != nil {
+		t.Errorf("AbortMultipartUpload failed: %v", err)
+	}
+
+	if backend.References() == 0 {
+		t.Error("Expected data whose release failed to stay referenced")
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "s3.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	authenticator := auth.NewAuthenticator(map[string]auth.Identity{"red-key": {Tenant: "red"}}, nil)
+	server := httptest.NewServer(authenticator.HTTPHandler(NewGateway(NewMemoryBackend(4*1024*1024), store)))
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	// Requests without credentials are rejected rather than trusted
+	for _, key := range []string{"", "wrong-key"} {
+		_, err := newS3Client(server.URL, key).CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String("red")})
+		var respErr *awshttp.ResponseError
+		if !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with key %q, got %v", key, err)
+		}
+	}
+
+	client := newS3Client(server.URL, "red-key")
+	createBucket(t, client, "red")
+	putObject(t, client, "red", "a", []byte("data"))
+	if got, _ := getObject(t, client, &s3.GetObjectInput{Bucket: aws.String("red"), Key: aws.String("a")}); string(got) != "data" {
+		t.Errorf("Unexpected object %q", got)
+	}
+}
+
+func objectKeys(out *s3.ListObjectsV2Output) string {
+	keys := make([]string, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		keys = append(keys, aws.ToString(obj.Key))
+	}
+	return strings.Join(keys, ",")
+}
+
+func commonPrefixes(out *s3.ListObjectsV2Output) string {
+	prefixes := make([]string, 0, len(out.CommonPrefixes))
+	for _, prefix := range out.CommonPrefixes {
+		prefixes = append(prefixes, aws.ToString(prefix.Prefix))
+	}
+	return strings.Join(prefixes, ",")
+}
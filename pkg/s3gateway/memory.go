@@ -0,0 +1,206 @@
+package s3gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"bharani/pkg/frontend"
+	"bharani/pkg/storage"
+)
+
+// MemoryBackend is an in-process stand-in for the frontend that keeps blocks
+// in memory. Files are chunked and described by manifests exactly as the
+// frontend does, so the gateway behaves the same without a running cluster.
+// A block is deleted as soon as its last reference is released, and blocks
+// stored without an owner are pinned.
+type MemoryBackend struct {
+	blocks map[string][]byte
+	refs   map[string]map[string]bool // block hash -> owners referencing it
+	opts   storage.ChunkerOptions
+	mu     sync.RWMutex
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend(maxBlockSize int64) *MemoryBackend {
+	return &MemoryBackend{
+		blocks: make(map[string][]byte),
+		refs:   make(map[string]map[string]bool),
+		opts:   storage.DefaultChunkerOptions(maxBlockSize),
+	}
+}
+
+// Put stores a block
+func (b *MemoryBackend) Put(ctx context.Context, data []byte, owner string) (string, error) {
+	block, err := storage.NewBlock(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to create block: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.blocks[block.Hash]; !exists {
+		b.blocks[block.Hash] = bytes.Clone(data)
+		b.refs[block.Hash] = make(map[string]bool)
+	}
+	b.refs[block.Hash][owner] = true
+	return block.Hash, nil
+}
+
+// Get retrieves a block
+func (b *MemoryBackend) Get(ctx context.Context, hash string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	data, exists := b.blocks[hash]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", frontend.ErrNotFound, hash)
+	}
+	return data, nil
+}
+
+// PutFile chunks a stream into blocks and stores a manifest describing them
+func (b *MemoryBackend) PutFile(ctx context.Context, r io.Reader, owner string) (*frontend.FileInfo, error) {
+	digest := sha256.New()
+	chunker, err := storage.NewChunker(io.TeeReader(r, digest), b.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]storage.ManifestEntry, 0)
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		hash, err := b.Put(ctx, chunk, owner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store block %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, storage.ManifestEntry{Hash: hash, Size: int64(len(chunk))})
+	}
+
+	manifest := storage.NewManifest(entries, hex.EncodeToString(digest.Sum(nil)))
+	hash, err := b.PutManifest(ctx, manifest, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &frontend.FileInfo{
+		Hash:       hash,
+		Size:       manifest.TotalSize,
+		BlockCount: len(entries),
+	}, nil
+}
+
+// PutManifest stores a manifest as a single block
+func (b *MemoryBackend) PutManifest(ctx context.Context, manifest *storage.Manifest, owner string) (string, error) {
+	data, err := manifest.Encode()
+	if err != nil {
+		return "", err
+	}
+	return b.Put(ctx, data, owner)
+}
+
+// Release removes owner's reference to a block, deleting the block once no
+// references are left, and reports whether owner held one
+func (b *MemoryBackend) Release(ctx context.Context, hash, owner string) (bool, error) {
+	if owner == "" {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	owners := b.refs[hash]
+	if !owners[owner] {
+		return false, nil
+	}
+	delete(owners, owner)
+	if len(owners) == 0 {
+		delete(b.refs, hash)
+		delete(b.blocks, hash)
+	}
+	return true, nil
+}
+
+// ReleaseFile releases owner's references to a file's blocks and child
+// manifests, then to the root manifest, and returns the number removed
+func (b *MemoryBackend) ReleaseFile(ctx context.Context, hash, owner string) (int, error) {
+	manifest, err := b.GetManifest(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	hashes := make([]string, 0)
+	if err := b.collectManifestHashes(ctx, manifest, &hashes); err != nil {
+		return 0, err
+	}
+	hashes = append(hashes, hash)
+
+	released := 0
+	seen := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+
+		if ok, _ := b.Release(ctx, h, owner); ok {
+			released++
+		}
+	}
+
+	return released, nil
+}
+
+// collectManifestHashes appends the hashes of every block a manifest refers
+// to, descending into child manifests
+func (b *MemoryBackend) collectManifestHashes(ctx context.Context, manifest *storage.Manifest, hashes *[]string) error {
+	for _, entry := range manifest.Entries {
+		*hashes = append(*hashes, entry.Hash)
+		if !entry.Indirect {
+			continue
+		}
+
+		child, err := b.GetManifest(ctx, entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to resolve child manifest %s: %w", entry.Hash, err)
+		}
+		if err := b.collectManifestHashes(ctx, child, hashes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// References returns the number of references held to blocks in the backend
+func (b *MemoryBackend) References() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	count := 0
+	for _, owners := range b.refs {
+		count += len(owners)
+	}
+	return count
+}
+
+// GetManifest retrieves and decodes the manifest stored under hash
+func (b *MemoryBackend) GetManifest(ctx context.Context, hash string) (*storage.Manifest, error) {
+	data, err := b.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return storage.DecodeManifest(data)
+}
@@ -0,0 +1,662 @@
+package s3gateway
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrNoSuchBucket is returned for operations on a bucket that does not exist
+	ErrNoSuchBucket = errors.New("bucket does not exist")
+
+	// ErrNoSuchKey is returned for operations on an object that does not exist
+	ErrNoSuchKey = errors.New("object does not exist")
+
+	// ErrNoSuchUpload is returned for operations on an unknown multipart upload
+	ErrNoSuchUpload = errors.New("multipart upload does not exist")
+
+	// ErrBucketExists is returned when creating a bucket that already exists
+	ErrBucketExists = errors.New("bucket already exists")
+
+	// ErrBucketNotEmpty is returned when deleting a bucket that still has objects
+	ErrBucketNotEmpty = errors.New("bucket is not empty")
+)
+
+// Object is the metadata of one stored object. Its data is the file
+// described by the manifest stored under ManifestHash, referenced by Owner.
+// A multipart object's manifest points at the files of its parts, which it
+// keeps referenced under the parts' own owners.
+type Object struct {
+	Bucket       string
+	Key          string
+	ManifestHash string
+	Owner        string // Reference owner of the manifest, empty if pinned
+	Size         int64
+	ETag         string // MD5-based S3 ETag, without quotes
+	ContentType  string
+	Modified     time.Time
+	Parts        []*Part // Parts of a multipart object
+}
+
+// Bucket is a namespace of objects
+type Bucket struct {
+	Name    string
+	Created time.Time
+}
+
+// Part is one uploaded part of a multipart upload
+type Part struct {
+	Number       int
+	ManifestHash string
+	Owner        string // Reference owner of the part's file
+	Size         int64
+	ETag         string
+}
+
+// Upload is an in-progress multipart upload
+type Upload struct {
+	ID          string
+	Bucket      string
+	Key         string
+	ContentType string
+}
+
+// Store keeps the object namespace: buckets, objects and multipart uploads
+type Store struct {
+	db *sql.DB
+	mu sync.RWMutex
+}
+
+// NewStore opens the metadata store at dbPath
+func NewStore(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	store := &Store{db: db}
+
+	if err := store.initSchema(); err != nil {
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return store, nil
+}
+
+// initSchema creates the database schema
+func (s *Store) initSchema() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS buckets (
+		name TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS objects (
+		bucket TEXT NOT NULL,
+		key TEXT NOT NULL,
+		manifest_hash TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		etag TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		modified_at INTEGER NOT NULL,
+		PRIMARY KEY (bucket, key)
+	);
+
+	CREATE TABLE IF NOT EXISTS uploads (
+		upload_id TEXT PRIMARY KEY,
+		bucket TEXT NOT NULL,
+		key TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS parts (
+		upload_id TEXT NOT NULL,
+		part_number INTEGER NOT NULL,
+		manifest_hash TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		etag TEXT NOT NULL,
+		PRIMARY KEY (upload_id, part_number)
+	);
+
+	CREATE TABLE IF NOT EXISTS object_parts (
+		bucket TEXT NOT NULL,
+		key TEXT NOT NULL,
+		part_number INTEGER NOT NULL,
+		manifest_hash TEXT NOT NULL,
+		owner TEXT NOT NULL,
+		PRIMARY KEY (bucket, key, part_number)
+	);
+	`
+
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	// Data stored before owners were recorded was pinned, and stays so
+	if err := s.addColumn("objects", "owner", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return s.addColumn("parts", "owner", "TEXT NOT NULL DEFAULT ''")
+}
+
+// addColumn adds a column to a table unless it is already there
+func (s *Store) addColumn(table, column, definition string) error {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return fmt.Errorf("failed to read %s schema: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to scan %s schema: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s schema: %w", table, err)
+	}
+
+	if _, err := s.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// CreateBucket creates an empty bucket
+func (s *Store) CreateBucket(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`INSERT INTO buckets (name, created_at) VALUES (?, ?)`, name, time.Now().UnixNano())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrBucketExists
+		}
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	return nil
+}
+
+// BucketExists checks whether a bucket exists
+func (s *Store) BucketExists(name string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.bucketExists(name)
+}
+
+// bucketExists checks whether a bucket exists; the caller must hold mu
+func (s *Store) bucketExists(name string) (bool, error) {
+	var exists int
+	err := s.db.QueryRow(`SELECT 1 FROM buckets WHERE name = ?`, name).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check bucket: %w", err)
+	}
+	return true, nil
+}
+
+// ListBuckets returns all buckets sorted by name
+func (s *Store) ListBuckets() ([]*Bucket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT name, created_at FROM buckets ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buckets: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]*Bucket, 0)
+	for rows.Next() {
+		bucket := &Bucket{}
+		var createdAt int64
+		if err := rows.Scan(&bucket.Name, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bucket: %w", err)
+		}
+		bucket.Created = time.Unix(0, createdAt)
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
+
+// DeleteBucket removes an empty bucket
+func (s *Store) DeleteBucket(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exists, err := s.bucketExists(name); err != nil {
+		return err
+	} else if !exists {
+		return ErrNoSuchBucket
+	}
+
+	var objects int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM objects WHERE bucket = ?`, name).Scan(&objects); err != nil {
+		return fmt.Errorf("failed to count objects: %w", err)
+	}
+	if objects > 0 {
+		return ErrBucketNotEmpty
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM buckets WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete bucket: %w", err)
+	}
+	return nil
+}
+
+// PutObject creates or replaces an object, returning the object it replaced
+// so that its data can be released
+func (s *Store) PutObject(obj *Object) (*Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exists, err := s.bucketExists(obj.Bucket); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, ErrNoSuchBucket
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	replaced, err := replaceObject(tx, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit object: %w", err)
+	}
+	return replaced, nil
+}
+
+// replaceObject stores an object and its parts within a transaction,
+// removing and returning the object it replaces, if any
+func replaceObject(tx *sql.Tx, obj *Object) (*Object, error) {
+	replaced, err := removeObject(tx, obj.Bucket, obj.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO objects (bucket, key, manifest_hash, owner, size, etag, content_type, modified_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.Exec(query, obj.Bucket, obj.Key, obj.ManifestHash, obj.Owner, obj.Size, obj.ETag, obj.ContentType, obj.Modified.UnixNano()); err != nil {
+		return nil, fmt.Errorf("failed to put object: %w", err)
+	}
+
+	for _, part := range obj.Parts {
+		query := `
+		INSERT INTO object_parts (bucket, key, part_number, manifest_hash, owner)
+		VALUES (?, ?, ?, ?, ?)
+		`
+		if _, err := tx.Exec(query, obj.Bucket, obj.Key, part.Number, part.ManifestHash, part.Owner); err != nil {
+			return nil, fmt.Errorf("failed to put object part: %w", err)
+		}
+	}
+
+	return replaced, nil
+}
+
+// removeObject deletes an object and its parts within a transaction and
+// returns what was deleted, or nil if there was no such object
+func removeObject(tx *sql.Tx, bucket, key string) (*Object, error) {
+	obj := &Object{Bucket: bucket, Key: key}
+	err := tx.QueryRow(`SELECT manifest_hash, owner FROM objects WHERE bucket = ? AND key = ?`, bucket, key).Scan(&obj.ManifestHash, &obj.Owner)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT part_number, manifest_hash, owner FROM object_parts WHERE bucket = ? AND key = ? ORDER BY part_number`, bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list object parts: %w", err)
+	}
+	for rows.Next() {
+		part := &Part{}
+		if err := rows.Scan(&part.Number, &part.ManifestHash, &part.Owner); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan object part: %w", err)
+		}
+		obj.Parts = append(obj.Parts, part)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list object parts: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM object_parts WHERE bucket = ? AND key = ?`, bucket, key); err != nil {
+		return nil, fmt.Errorf("failed to delete object parts: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM objects WHERE bucket = ? AND key = ?`, bucket, key); err != nil {
+		return nil, fmt.Errorf("failed to delete object: %w", err)
+	}
+	return obj, nil
+}
+
+// GetObject retrieves an object's metadata
+func (s *Store) GetObject(bucket, key string) (*Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+	SELECT manifest_hash, size, etag, content_type, modified_at
+	FROM objects
+	WHERE bucket = ? AND key = ?
+	`
+
+	obj := &Object{Bucket: bucket, Key: key}
+	var modified int64
+	err := s.db.QueryRow(query, bucket, key).Scan(&obj.ManifestHash, &obj.Size, &obj.ETag, &obj.ContentType, &modified)
+	if err == sql.ErrNoRows {
+		if exists, err := s.bucketExists(bucket); err != nil {
+			return nil, err
+		} else if !exists {
+			return nil, ErrNoSuchBucket
+		}
+		return nil, ErrNoSuchKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	obj.Modified = time.Unix(0, modified)
+	return obj, nil
+}
+
+// DeleteObject removes an object and returns it so that its data can be
+// released. Deleting a missing key is not an error and returns nil.
+func (s *Store) DeleteObject(bucket, key string) (*Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exists, err := s.bucketExists(bucket); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, ErrNoSuchBucket
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted, err := removeObject(tx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit object deletion: %w", err)
+	}
+	return deleted, nil
+}
+
+// ListObjects returns up to limit objects whose keys start with prefix and
+// sort after startAfter, in key order
+func (s *Store) ListObjects(bucket, prefix, startAfter string, limit int) ([]*Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if exists, err := s.bucketExists(bucket); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, ErrNoSuchBucket
+	}
+
+	query := `
+	SELECT key, manifest_hash, size, etag, content_type, modified_at
+	FROM objects
+	WHERE bucket = ? AND key > ? AND substr(CAST(key AS BLOB), 1, ?) = CAST(? AS BLOB)
+	ORDER BY key
+	LIMIT ?
+	`
+
+	rows, err := s.db.Query(query, bucket, startAfter, len(prefix), prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	defer rows.Close()
+
+	objects := make([]*Object, 0)
+	for rows.Next() {
+		obj := &Object{Bucket: bucket}
+		var modified int64
+		if err := rows.Scan(&obj.Key, &obj.ManifestHash, &obj.Size, &obj.ETag, &obj.ContentType, &modified); err != nil {
+			return nil, fmt.Errorf("failed to scan object: %w", err)
+		}
+		obj.Modified = time.Unix(0, modified)
+		objects = append(objects, obj)
+	}
+
+	return objects, rows.Err()
+}
+
+// CreateUpload starts a multipart upload
+func (s *Store) CreateUpload(upload *Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exists, err := s.bucketExists(upload.Bucket); err != nil {
+		return err
+	} else if !exists {
+		return ErrNoSuchBucket
+	}
+
+	query := `
+	INSERT INTO uploads (upload_id, bucket, key, content_type, created_at)
+	VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query, upload.ID, upload.Bucket, upload.Key, upload.ContentType, time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	return nil
+}
+
+// GetUpload retrieves a multipart upload of the given object
+func (s *Store) GetUpload(uploadID, bucket, key string) (*Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	upload := &Upload{ID: uploadID}
+	err := s.db.QueryRow(`SELECT bucket, key, content_type FROM uploads WHERE upload_id = ?`, uploadID).
+		Scan(&upload.Bucket, &upload.Key, &upload.ContentType)
+	if err == sql.ErrNoRows || (err == nil && (upload.Bucket != bucket || upload.Key != key)) {
+		return nil, ErrNoSuchUpload
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	return upload, nil
+}
+
+// PutPart records an uploaded part, replacing any earlier upload of the same
+// part number, which is returned so that its data can be released. It fails
+// with ErrNoSuchUpload once the upload has been completed or aborted.
+func (s *Store) PutPart(uploadID string, part *Part) (*Part, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`SELECT 1 FROM uploads WHERE upload_id = ?`, uploadID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoSuchUpload
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	replaced := &Part{Number: part.Number}
+	err = tx.QueryRow(`SELECT manifest_hash, owner FROM parts WHERE upload_id = ? AND part_number = ?`, uploadID, part.Number).
+		Scan(&replaced.ManifestHash, &replaced.Owner)
+	if err == sql.ErrNoRows {
+		replaced = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get part: %w", err)
+	}
+
+	query := `
+	INSERT OR REPLACE INTO parts (upload_id, part_number, manifest_hash, owner, size, etag)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.Exec(query, uploadID, part.Number, part.ManifestHash, part.Owner, part.Size, part.ETag); err != nil {
+		return nil, fmt.Errorf("failed to put part: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit part: %w", err)
+	}
+	return replaced, nil
+}
+
+// ListParts returns the parts of an upload keyed by part number
+func (s *Store) ListParts(uploadID string) (map[int]*Part, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return listParts(s.db, uploadID)
+}
+
+// querier runs queries on a database or within a transaction
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// listParts returns the parts of an upload keyed by part number
+func listParts(db querier, uploadID string) (map[int]*Part, error) {
+	rows, err := db.Query(`SELECT part_number, manifest_hash, owner, size, etag FROM parts WHERE upload_id = ?`, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+	defer rows.Close()
+
+	parts := make(map[int]*Part)
+	for rows.Next() {
+		part := &Part{}
+		if err := rows.Scan(&part.Number, &part.ManifestHash, &part.Owner, &part.Size, &part.ETag); err != nil {
+			return nil, fmt.Errorf("failed to scan part: %w", err)
+		}
+		parts[part.Number] = part
+	}
+
+	return parts, rows.Err()
+}
+
+// CompleteUpload atomically stores the assembled object, whose Parts are
+// taken from the upload, and removes the upload. It returns the object it
+// replaced and the uploaded parts the object does not use, so that their data
+// can be released.
+func (s *Store) CompleteUpload(uploadID string, obj *Object) (*Object, []*Part, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	unused, err := deleteUpload(tx, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	replaced, err := replaceObject(tx, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, part := range obj.Parts {
+		delete(unused, part.Number)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit upload: %w", err)
+	}
+	return replaced, slices.Collect(maps.Values(unused)), nil
+}
+
+// AbortUpload removes an upload and returns its parts so that their data can
+// be released
+func (s *Store) AbortUpload(uploadID string) ([]*Part, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	parts, err := deleteUpload(tx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit abort: %w", err)
+	}
+	return slices.Collect(maps.Values(parts)), nil
+}
+
+// deleteUpload removes an upload and its parts within a transaction and
+// returns the parts keyed by part number. An upload that is already gone
+// fails with ErrNoSuchUpload, so its parts are handed out only once.
+func deleteUpload(tx *sql.Tx, uploadID string) (map[int]*Part, error) {
+	result, err := tx.Exec(`DELETE FROM uploads WHERE upload_id = ?`, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete upload: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to delete upload: %w", err)
+	} else if n == 0 {
+		return nil, ErrNoSuchUpload
+	}
+
+	parts, err := listParts(tx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM parts WHERE upload_id = ?`, uploadID); err != nil {
+		return nil, fmt.Errorf("failed to delete parts: %w", err)
+	}
+	return parts, nil
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,193 @@
+package s3gateway
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bharani/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// maxPartNumber is the highest part number S3 allows
+const maxPartNumber = 10000
+
+// createMultipartUpload handles CreateMultipartUpload
+func (g *Gateway) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	upload := &Upload{
+		ID:          uuid.New().String(),
+		Bucket:      bucket,
+		Key:         key,
+		ContentType: contentType(r),
+	}
+	if err := g.store.CreateUpload(upload); err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: upload.ID,
+	})
+}
+
+// uploadPart handles UploadPart. Each part is stored as a file of its own,
+// and uploading a part number again releases the file it replaces.
+func (g *Gateway) uploadPart(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	query := r.URL.Query()
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+
+	upload, err := g.store.GetUpload(query.Get("uploadId"), bucket, key)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	owner := objectOwner(bucket, key, fmt.Sprintf("%s/%d/%s", upload.ID, partNumber, uuid.New()))
+	info, sum, s3err := g.storeBody(r, owner)
+	if s3err != nil {
+		writeError(w, r, s3err)
+		return
+	}
+
+	part := &Part{
+		Number:       partNumber,
+		ManifestHash: info.Hash,
+		Owner:        owner,
+		Size:         info.Size,
+		ETag:         hex.EncodeToString(sum),
+	}
+	replaced, err := g.store.PutPart(upload.ID, part)
+	if err != nil {
+		g.releaseFile(r.Context(), info.Hash, owner)
+		writeError(w, r, toS3Error(err))
+		return
+	}
+	if replaced != nil {
+		g.releaseFile(r.Context(), replaced.ManifestHash, replaced.Owner)
+	}
+
+	w.Header().Set("ETag", quote(part.ETag))
+	w.WriteHeader(http.StatusOK)
+}
+
+// completeMultipartUpload handles CompleteMultipartUpload. The object's
+// manifest references each part's manifest in order, so no data is copied,
+// and the object keeps the parts' references. Parts it leaves out, and any
+// object it replaces, are released.
+func (g *Gateway) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	upload, err := g.store.GetUpload(r.URL.Query().Get("uploadId"), bucket, key)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	var req completeMultipartUpload
+	if err := xml.NewDecoder(requestBody(r)).Decode(&req); err != nil || len(req.Parts) == 0 {
+		writeError(w, r, errMalformedXML)
+		return
+	}
+
+	uploaded, err := g.store.ListParts(upload.ID)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	// The multipart ETag is the MD5 of the concatenated part MD5s plus the part count
+	etags := md5.New()
+	entries := make([]storage.ManifestEntry, 0, len(req.Parts))
+	parts := make([]*Part, 0, len(req.Parts))
+	for i, requested := range req.Parts {
+		if i > 0 && requested.PartNumber <= req.Parts[i-1].PartNumber {
+			writeError(w, r, errInvalidPartOrder)
+			return
+		}
+
+		part, ok := uploaded[requested.PartNumber]
+		if !ok || unquote(requested.ETag) != part.ETag {
+			writeError(w, r, errInvalidPart)
+			return
+		}
+
+		sum, err := hex.DecodeString(part.ETag)
+		if err != nil {
+			writeError(w, r, errInternal)
+			return
+		}
+		etags.Write(sum)
+
+		parts = append(parts, part)
+		entries = append(entries, storage.ManifestEntry{
+			Hash:     part.ManifestHash,
+			Size:     part.Size,
+			Indirect: true,
+		})
+	}
+
+	manifest := storage.NewManifest(entries, "")
+	owner := objectOwner(bucket, key, uuid.New().String())
+	hash, err := g.backend.PutManifest(r.Context(), manifest, owner)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	obj := &Object{
+		Bucket:       bucket,
+		Key:          key,
+		ManifestHash: hash,
+		Owner:        owner,
+		Size:         manifest.TotalSize,
+		ETag:         fmt.Sprintf("%s-%d", hex.EncodeToString(etags.Sum(nil)), len(entries)),
+		ContentType:  upload.ContentType,
+		Modified:     time.Now(),
+		Parts:        parts,
+	}
+	replaced, unused, err := g.store.CompleteUpload(upload.ID, obj)
+	if err != nil {
+		g.releaseFile(r.Context(), hash, owner)
+		writeError(w, r, toS3Error(err))
+		return
+	}
+	g.releaseParts(r.Context(), unused)
+	g.releaseObject(r.Context(), replaced)
+
+	writeXML(w, http.StatusOK, completeMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: objectURL(r, bucket, key),
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     quote(obj.ETag),
+	})
+}
+
+// abortMultipartUpload handles AbortMultipartUpload, releasing the uploaded
+// parts
+func (g *Gateway) abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	upload, err := g.store.GetUpload(r.URL.Query().Get("uploadId"), bucket, key)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+
+	parts, err := g.store.AbortUpload(upload.ID)
+	if err != nil {
+		writeError(w, r, toS3Error(err))
+		return
+	}
+	g.releaseParts(r.Context(), parts)
+
+	w.WriteHeader(http.StatusNoContent)
+}
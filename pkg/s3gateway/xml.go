@@ -0,0 +1,183 @@
+package s3gateway
+
+import (
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"bharani/pkg/frontend"
+)
+
+// s3Namespace is the XML namespace of S3 response documents
+const s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// s3Error is an S3 error code with its HTTP status
+type s3Error struct {
+	Code    string
+	Status  int
+	Message string
+}
+
+var (
+	errInternal         = &s3Error{"InternalError", http.StatusInternalServerError, "We encountered an internal error. Please try again."}
+	errAccessDenied     = &s3Error{"AccessDenied", http.StatusForbidden, "Access Denied"}
+	errQuotaExceeded    = &s3Error{"QuotaExceeded", http.StatusForbidden, "The request would exceed your storage quota."}
+	errSlowDown         = &s3Error{"SlowDown", http.StatusServiceUnavailable, "Please reduce your request rate."}
+	errNoSuchBucket     = &s3Error{"NoSuchBucket", http.StatusNotFound, "The specified bucket does not exist."}
+	errNoSuchKey        = &s3Error{"NoSuchKey", http.StatusNotFound, "The specified key does not exist."}
+	errNoSuchUpload     = &s3Error{"NoSuchUpload", http.StatusNotFound, "The specified multipart upload does not exist."}
+	errBucketExists     = &s3Error{"BucketAlreadyOwnedByYou", http.StatusConflict, "Your previous request to create the named bucket succeeded and you already own it."}
+	errBucketNotEmpty   = &s3Error{"BucketNotEmpty", http.StatusConflict, "The bucket you tried to delete is not empty."}
+	errInvalidBucket    = &s3Error{"InvalidBucketName", http.StatusBadRequest, "The specified bucket is not valid."}
+	errInvalidArgument  = &s3Error{"InvalidArgument", http.StatusBadRequest, "Invalid argument."}
+	errInvalidPart      = &s3Error{"InvalidPart", http.StatusBadRequest, "One or more of the specified parts could not be found or the ETag did not match."}
+	errInvalidPartOrder = &s3Error{"InvalidPartOrder", http.StatusBadRequest, "The list of parts was not in ascending order."}
+	errMalformedXML     = &s3Error{"MalformedXML", http.StatusBadRequest, "The XML you provided was not well-formed."}
+	errBadDigest        = &s3Error{"BadDigest", http.StatusBadRequest, "The Content-MD5 you specified did not match what we received."}
+	errIncompleteBody   = &s3Error{"IncompleteBody", http.StatusBadRequest, "The request body could not be read."}
+	errNotImplemented   = &s3Error{"NotImplemented", http.StatusNotImplemented, "This operation is not supported by the gateway."}
+)
+
+// toS3Error maps a metadata store or backend error to an S3 error
+func toS3Error(err error) *s3Error {
+	switch {
+	case errors.Is(err, ErrNoSuchBucket):
+		return errNoSuchBucket
+	case errors.Is(err, ErrNoSuchKey):
+		return errNoSuchKey
+	case errors.Is(err, ErrNoSuchUpload):
+		return errNoSuchUpload
+	case errors.Is(err, ErrBucketExists):
+		return errBucketExists
+	case errors.Is(err, ErrBucketNotEmpty):
+		return errBucketNotEmpty
+	case errors.Is(err, frontend.ErrAccessDenied):
+		return errAccessDenied
+	case errors.Is(err, frontend.ErrQuotaExceeded):
+		return errQuotaExceeded
+	case errors.Is(err, frontend.ErrRateLimited):
+		return errSlowDown
+	}
+
+	log.Printf("S3 gateway error: %v", err)
+	return errInternal
+}
+
+// errorResponse is the body of an S3 error response
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+// writeError writes an S3 error document. HEAD responses carry only the status.
+func writeError(w http.ResponseWriter, r *http.Request, e *s3Error) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(e.Status)
+		return
+	}
+
+	writeXML(w, e.Status, errorResponse{
+		Code:     e.Code,
+		Message:  e.Message,
+		Resource: r.URL.Path,
+	})
+}
+
+// writeXML writes v as an XML response body
+func writeXML(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// s3Time formats a time the way S3 XML documents do
+func s3Time(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+type bucketEntry struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+type listBucketsResult struct {
+	XMLName xml.Name      `xml:"ListAllMyBucketsResult"`
+	Xmlns   string        `xml:"xmlns,attr"`
+	Owner   owner         `xml:"Owner"`
+	Buckets []bucketEntry `xml:"Buckets>Bucket"`
+}
+
+type locationConstraint struct {
+	XMLName xml.Name `xml:"LocationConstraint"`
+	Xmlns   string   `xml:"xmlns,attr"`
+}
+
+type objectEntry struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listObjectsV2Result struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	KeyCount              int            `xml:"KeyCount"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []objectEntry  `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
@@ -0,0 +1,651 @@
+package shards
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bharani/proto/blockindex"
+	"bharani/proto/master"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// mapRefreshInterval bounds how long a client routes with a shard map
+	// before asking the master for a new one
+	mapRefreshInterval = 10 * time.Second
+
+	// routeAttempts bounds the calls made for one request while the range it
+	// touches is moving between index nodes
+	routeAttempts = 8
+
+	// Delays between those calls, doubled after each one
+	routeBackoff    = 50 * time.Millisecond
+	maxRouteBackoff = time.Second
+)
+
+// Client is a block index client that routes each call to the index node
+// serving the blocks it names, using the shard map served by the master.
+// Calls naming blocks of several shards are split, calls naming none go to
+// every shard and their results are merged, and calls for ranges that are
+// moving are retried with a fresh map. Until the master has a shard map, every
+// call goes to the fallback address.
+type Client struct {
+	master       master.MasterServiceClient
+	dialOption   grpc.DialOption
+	fallbackAddr string
+	shards       *Map
+	fetched      time.Time
+	clients      map[string]blockindex.BlockIndexServiceClient
+	mu           sync.Mutex
+}
+
+// NewClient creates a client that routes with the master's shard map
+func NewClient(fallbackAddr string, masterClient master.MasterServiceClient, dialOption grpc.DialOption) *Client {
+	return &Client{
+		master:       masterClient,
+		dialOption:   dialOption,
+		fallbackAddr: fallbackAddr,
+		shards:       NewMap(fallbackAddr),
+		clients:      make(map[string]blockindex.BlockIndexServiceClient),
+	}
+}
+
+// shardMap returns the shard map, fetching a new one from the master when the
+// current one is old or refresh is set. If the master cannot be reached the
+// current map is kept until the next refresh.
+func (c *Client) shardMap(ctx context.Context, refresh bool) *Map {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !refresh && time.Since(c.fetched) < mapRefreshInterval {
+		return c.shards
+	}
+
+	c.fetched = time.Now()
+	resp, err := c.master.GetShardMap(ctx, &master.GetShardMapRequest{})
+	if err != nil {
+		log.Printf("Failed to fetch index shard map: %v", err)
+		return c.shards
+	}
+
+	if len(resp.Shards) == 0 {
+		c.shards = NewMap(c.fallbackAddr)
+		return c.shards
+	}
+	m, err := FromProto(resp)
+	if err != nil {
+		log.Printf("Ignoring index shard map: %v", err)
+		return c.shards
+	}
+	c.shards = m
+	return c.shards
+}
+
+// client returns the index client for an address, connecting on first use
+func (c *Client) client(addr string) (blockindex.BlockIndexServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, ok := c.clients[addr]; ok {
+		return client, nil
+	}
+
+	conn, err := grpc.NewClient(addr, c.dialOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to index shard %s: %w", addr, err)
+	}
+	client := blockindex.NewBlockIndexServiceClient(conn)
+	c.clients[addr] = client
+	return client, nil
+}
+
+// moving reports whether a call failed because its range has moved or is
+// frozen for a move, so it should be retried with a fresh map
+func moving(err error) bool {
+	switch status.Code(err) {
+	case codes.OutOfRange, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// retry runs op with the current shard map, and again with a fresh map while
+// it fails because a range is moving
+func (c *Client) retry(ctx context.Context, op func(m *Map) error) error {
+	refresh := false
+	backoff := routeBackoff
+	for attempt := 1; ; attempt++ {
+		err := op(c.shardMap(ctx, refresh))
+		if !moving(err) || attempt == routeAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxRouteBackoff)
+		refresh = true
+	}
+}
+
+// route calls the shard serving hash
+func route[T any](c *Client, ctx context.Context, hash string, call func(blockindex.BlockIndexServiceClient) (T, error)) (T, error) {
+	var resp T
+	err := c.retry(ctx, func(m *Map) error {
+		client, err := c.client(m.Lookup(hash).Serving())
+		if err != nil {
+			return err
+		}
+		resp, err = call(client)
+		return err
+	})
+	return resp, err
+}
+
+// split groups the hashes at the given positions by the address of the shard
+// serving them
+func split(m *Map, hashes []string, positions []int) map[string][]int {
+	groups := make(map[string][]int)
+	for _, pos := range positions {
+		addr := m.Lookup(hashes[pos]).Serving()
+		groups[addr] = append(groups[addr], pos)
+	}
+	return groups
+}
+
+// servingAddrs returns the address of every node serving a shard
+func servingAddrs(m *Map) []string {
+	addrs := make([]string, 0, len(m.Shards))
+	seen := make(map[string]bool)
+	for _, shard := range m.Shards {
+		if addr := shard.Serving(); !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// each calls every address concurrently, returning the first error
+func (c *Client) each(addrs []string, call func(addr string, client blockindex.BlockIndexServiceClient) error) error {
+	errs := make([]error, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := c.client(addr)
+			if err == nil {
+				err = call(addr, client)
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batch splits hashes by shard and calls each shard with its share. A retry
+// only calls again with the shares whose shard failed, so the results of the
+// others are kept and calls that are not idempotent, such as BeginPut, are
+// made once per hash.
+func (c *Client) batch(ctx context.Context, hashes []string, call func(client blockindex.BlockIndexServiceClient, positions []int) error) error {
+	pending := make([]int, len(hashes))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	return c.retry(ctx, func(m *Map) error {
+		groups := split(m, hashes, pending)
+		addrs := make([]string, 0, len(groups))
+		for addr := range groups {
+			addrs = append(addrs, addr)
+		}
+
+		var mu sync.Mutex
+		failed := make([]int, 0)
+		err := c.each(addrs, func(addr string, client blockindex.BlockIndexServiceClient) error {
+			err := call(client, groups[addr])
+			if err != nil {
+				mu.Lock()
+				failed = append(failed, groups[addr]...)
+				mu.Unlock()
+			}
+			return err
+		})
+		pending = failed
+		return err
+	})
+}
+
+// all calls every shard. reset is called before every attempt to discard
+// results merged by an earlier one. The call is told the map so it can drop
+// results for blocks the node it called does not serve.
+func (c *Client) all(ctx context.Context, reset func(), call func(m *Map, addr string, client blockindex.BlockIndexServiceClient) error) error {
+	return c.retry(ctx, func(m *Map) error {
+		reset()
+		return c.each(servingAddrs(m), func(addr string, client blockindex.BlockIndexServiceClient) error {
+			return call(m, addr, client)
+		})
+	})
+}
+
+// pick returns the hashes at the given positions
+func pick(hashes []string, positions []int) []string {
+	picked := make([]string, len(positions))
+	for i, pos := range positions {
+		picked[i] = hashes[pos]
+	}
+	return picked
+}
+
+// PutEntry routes PutEntry to the block's shard
+func (c *Client) PutEntry(ctx context.Context, in *blockindex.PutEntryRequest, opts ...grpc.CallOption) (*blockindex.PutEntryResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.PutEntryResponse, error) {
+		return client.PutEntry(ctx, in, opts...)
+	})
+}
+
+// UpdateEntry routes UpdateEntry to the block's shard
+func (c *Client) UpdateEntry(ctx context.Context, in *blockindex.UpdateEntryRequest, opts ...grpc.CallOption) (*blockindex.UpdateEntryResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.UpdateEntryResponse, error) {
+		return client.UpdateEntry(ctx, in, opts...)
+	})
+}
+
+// GetEntry routes GetEntry to the block's shard
+func (c *Client) GetEntry(ctx context.Context, in *blockindex.GetEntryRequest, opts ...grpc.CallOption) (*blockindex.GetEntryResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.GetEntryResponse, error) {
+		return client.GetEntry(ctx, in, opts...)
+	})
+}
+
+// Exists routes Exists to the block's shard
+func (c *Client) Exists(ctx context.Context, in *blockindex.ExistsRequest, opts ...grpc.CallOption) (*blockindex.ExistsResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.ExistsResponse, error) {
+		return client.Exists(ctx, in, opts...)
+	})
+}
+
+// ExistsBatch asks each shard about its blocks
+func (c *Client) ExistsBatch(ctx context.Context, in *blockindex.ExistsBatchRequest, opts ...grpc.CallOption) (*blockindex.ExistsBatchResponse, error) {
+	merged := &blockindex.ExistsBatchResponse{}
+	var mu sync.Mutex
+	err := c.batch(ctx, in.Hashes, func(client blockindex.BlockIndexServiceClient, positions []int) error {
+		resp, err := client.ExistsBatch(ctx, &blockindex.ExistsBatchRequest{Hashes: pick(in.Hashes, positions)}, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		merged.Existing = append(merged.Existing, resp.Existing...)
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	return merged, err
+}
+
+// GetEntries asks each shard for its blocks' entries
+func (c *Client) GetEntries(ctx context.Context, in *blockindex.GetEntriesRequest, opts ...grpc.CallOption) (*blockindex.GetEntriesResponse, error) {
+	merged := &blockindex.GetEntriesResponse{}
+	var mu sync.Mutex
+	err := c.batch(ctx, in.Hashes, func(client blockindex.BlockIndexServiceClient, positions []int) error {
+		resp, err := client.GetEntries(ctx, &blockindex.GetEntriesRequest{Hashes: pick(in.Hashes, positions)}, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		merged.Entries = append(merged.Entries, resp.Entries...)
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	return merged, err
+}
+
+// MarkVerified routes MarkVerified to the block's shard
+func (c *Client) MarkVerified(ctx context.Context, in *blockindex.MarkVerifiedRequest, opts ...grpc.CallOption) (*blockindex.MarkVerifiedResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.MarkVerifiedResponse, error) {
+		return client.MarkVerified(ctx, in, opts...)
+	})
+}
+
+// AddRefs adds the references on each shard
+func (c *Client) AddRefs(ctx context.Context, in *blockindex.AddRefsRequest, opts ...grpc.CallOption) (*blockindex.AddRefsResponse, error) {
+	merged := &blockindex.AddRefsResponse{}
+	var mu sync.Mutex
+	err := c.batch(ctx, in.Hashes, func(client blockindex.BlockIndexServiceClient, positions []int) error {
+		req := &blockindex.AddRefsRequest{Hashes: pick(in.Hashes, positions), Owner: in.Owner, Tenant: in.Tenant}
+		resp, err := client.AddRefs(ctx, req, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		merged.Found = append(merged.Found, resp.Found...)
+		merged.Deleting = append(merged.Deleting, resp.Deleting...)
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	return merged, err
+}
+
+// Release routes Release to the block's shard
+func (c *Client) Release(ctx context.Context, in *blockindex.ReleaseRequest, opts ...grpc.CallOption) (*blockindex.ReleaseResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.ReleaseResponse, error) {
+		return client.Release(ctx, in, opts...)
+	})
+}
+
+// Referenced asks each shard about its blocks
+func (c *Client) Referenced(ctx context.Context, in *blockindex.ReferencedRequest, opts ...grpc.CallOption) (*blockindex.ReferencedResponse, error) {
+	merged := &blockindex.ReferencedResponse{}
+	var mu sync.Mutex
+	err := c.batch(ctx, in.Hashes, func(client blockindex.BlockIndexServiceClient, positions []int) error {
+		req := &blockindex.ReferencedRequest{Hashes: pick(in.Hashes, positions), Tenant: in.Tenant}
+		resp, err := client.Referenced(ctx, req, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		merged.Referenced = append(merged.Referenced, resp.Referenced...)
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	return merged, err
+}
+
+// GetUsage sums a tenant's usage over every shard. While a range is moving,
+// the blocks already copied are counted by both nodes.
+func (c *Client) GetUsage(ctx context.Context, in *blockindex.GetUsageRequest, opts ...grpc.CallOption) (*blockindex.GetUsageResponse, error) {
+	var (
+		merged *blockindex.GetUsageResponse
+		mu     sync.Mutex
+	)
+	reset := func() { merged = &blockindex.GetUsageResponse{} }
+	err := c.all(ctx, reset, func(m *Map, addr string, client blockindex.BlockIndexServiceClient) error {
+		resp, err := client.GetUsage(ctx, in, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		merged.Bytes += resp.Bytes
+		merged.Blocks += resp.Blocks
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	return merged, err
+}
+
+// listAll makes a list call on every shard and merges the entries each shard
+// serves, up to limit
+func (c *Client) listAll(ctx context.Context, limit int32, list func(client blockindex.BlockIndexServiceClient) (*blockindex.ListEntriesResponse, error)) (*blockindex.ListEntriesResponse, error) {
+	var (
+		merged *blockindex.ListEntriesResponse
+		mu     sync.Mutex
+	)
+	reset := func() { merged = &blockindex.ListEntriesResponse{} }
+	err := c.all(ctx, reset, func(m *Map, addr string, client blockindex.BlockIndexServiceClient) error {
+		resp, err := list(client)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, entry := range resp.Entries {
+			if m.Lookup(entry.Hash).Serving() == addr {
+				merged.Entries = append(merged.Entries, entry)
+			}
+		}
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	if err == nil && limit > 0 && len(merged.Entries) > int(limit) {
+		merged.Entries = merged.Entries[:limit]
+	}
+	return merged, err
+}
+
+// ListCollectable lists collectable blocks of every shard
+func (c *Client) ListCollectable(ctx context.Context, in *blockindex.ListCollectableRequest, opts ...grpc.CallOption) (*blockindex.ListEntriesResponse, error) {
+	return c.listAll(ctx, in.Limit, func(client blockindex.BlockIndexServiceClient) (*blockindex.ListEntriesResponse, error) {
+		return client.ListCollectable(ctx, in, opts...)
+	})
+}
+
+// MarkDeleting routes MarkDeleting to the block's shard
+func (c *Client) MarkDeleting(ctx context.Context, in *blockindex.MarkDeletingRequest, opts ...grpc.CallOption) (*blockindex.MarkDeletingResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.MarkDeletingResponse, error) {
+		return client.MarkDeleting(ctx, in, opts...)
+	})
+}
+
+// ListDeleting lists blocks being deleted on every shard
+func (c *Client) ListDeleting(ctx context.Context, in *blockindex.ListDeletingRequest, opts ...grpc.CallOption) (*blockindex.ListEntriesResponse, error) {
+	return c.listAll(ctx, in.Limit, func(client blockindex.BlockIndexServiceClient) (*blockindex.ListEntriesResponse, error) {
+		return client.ListDeleting(ctx, in, opts...)
+	})
+}
+
+// RemoveEntry routes RemoveEntry to the block's shard
+func (c *Client) RemoveEntry(ctx context.Context, in *blockindex.RemoveEntryRequest, opts ...grpc.CallOption) (*blockindex.RemoveEntryResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.RemoveEntryResponse, error) {
+		return client.RemoveEntry(ctx, in, opts...)
+	})
+}
+
+// ListVolumeEntries lists a volume's blocks on every shard
+func (c *Client) ListVolumeEntries(ctx context.Context, in *blockindex.ListVolumeEntriesRequest, opts ...grpc.CallOption) (*blockindex.ListEntriesResponse, error) {
+	return c.listAll(ctx, in.Limit, func(client blockindex.BlockIndexServiceClient) (*blockindex.ListEntriesResponse, error) {
+		return client.ListVolumeEntries(ctx, in, opts...)
+	})
+}
+
+// GetVolumeUsage sums volume usage over every shard. While a range is
+// moving, the blocks already copied are counted by both nodes.
+func (c *Client) GetVolumeUsage(ctx context.Context, in *blockindex.GetVolumeUsageRequest, opts ...grpc.CallOption) (*blockindex.GetVolumeUsageResponse, error) {
+	var (
+		merged  *blockindex.GetVolumeUsageResponse
+		volumes map[string]*blockindex.VolumeUsage
+		mu      sync.Mutex
+	)
+	reset := func() {
+		merged = &blockindex.GetVolumeUsageResponse{}
+		volumes = make(map[string]*blockindex.VolumeUsage)
+	}
+	err := c.all(ctx, reset, func(m *Map, addr string, client blockindex.BlockIndexServiceClient) error {
+		resp, err := client.GetVolumeUsage(ctx, in, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, usage := range resp.Volumes {
+			total, ok := volumes[usage.VolumeId]
+			if !ok {
+				total = &blockindex.VolumeUsage{VolumeId: usage.VolumeId}
+				volumes[usage.VolumeId] = total
+				merged.Volumes = append(merged.Volumes, total)
+			}
+			total.LiveBytes += usage.LiveBytes
+			total.Blocks += usage.Blocks
+		}
+		merged.Unlocated += resp.Unlocated
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	return merged, err
+}
+
+// BeginPut records each intent on its block's shard. Intent IDs are assigned
+// per shard, so calls about an intent must name its block.
+func (c *Client) BeginPut(ctx context.Context, in *blockindex.BeginPutRequest, opts ...grpc.CallOption) (*blockindex.BeginPutResponse, error) {
+	hashes := make([]string, len(in.Intents))
+	for i, intent := range in.Intents {
+		hashes[i] = intent.Hash
+	}
+
+	merged := &blockindex.BeginPutResponse{Ids: make([]int64, len(in.Intents))}
+	var mu sync.Mutex
+	err := c.batch(ctx, hashes, func(client blockindex.BlockIndexServiceClient, positions []int) error {
+		intents := make([]*blockindex.Intent, len(positions))
+		for i, pos := range positions {
+			intents[i] = in.Intents[pos]
+		}
+		resp, err := client.BeginPut(ctx, &blockindex.BeginPutRequest{Intents: intents}, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if resp.Error != "" {
+			merged.Error = resp.Error
+			return nil
+		}
+		if len(resp.Ids) != len(positions) {
+			merged.Error = fmt.Sprintf("got %d ids for %d intents", len(resp.Ids), len(positions))
+			return nil
+		}
+		for i, pos := range positions {
+			merged.Ids[pos] = resp.Ids[i]
+		}
+		return nil
+	})
+	if err == nil && merged.Error != "" {
+		merged.Ids = nil
+	}
+	return merged, err
+}
+
+// CommitPut routes CommitPut to the shard of the intent's block
+func (c *Client) CommitPut(ctx context.Context, in *blockindex.CommitPutRequest, opts ...grpc.CallOption) (*blockindex.CommitPutResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.CommitPutResponse, error) {
+		return client.CommitPut(ctx, in, opts...)
+	})
+}
+
+// AbortPut routes AbortPut to the shard of the intent's block
+func (c *Client) AbortPut(ctx context.Context, in *blockindex.AbortPutRequest, opts ...grpc.CallOption) (*blockindex.AbortPutResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.AbortPutResponse, error) {
+		return client.AbortPut(ctx, in, opts...)
+	})
+}
+
+// ListIntents lists the intents of every shard
+func (c *Client) ListIntents(ctx context.Context, in *blockindex.ListIntentsRequest, opts ...grpc.CallOption) (*blockindex.ListIntentsResponse, error) {
+	var (
+		merged *blockindex.ListIntentsResponse
+		mu     sync.Mutex
+	)
+	reset := func() { merged = &blockindex.ListIntentsResponse{} }
+	err := c.all(ctx, reset, func(m *Map, addr string, client blockindex.BlockIndexServiceClient) error {
+		resp, err := client.ListIntents(ctx, in, opts...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, intent := range resp.Intents {
+			if m.Lookup(intent.Hash).Serving() == addr {
+				merged.Intents = append(merged.Intents, intent)
+			}
+		}
+		if resp.Error != "" {
+			merged.Error = resp.Error
+		}
+		return nil
+	})
+	if err == nil && in.Limit > 0 && len(merged.Intents) > int(in.Limit) {
+		merged.Intents = merged.Intents[:in.Limit]
+	}
+	return merged, err
+}
+
+// ClaimIntent routes ClaimIntent to the shard of the intent's block
+func (c *Client) ClaimIntent(ctx context.Context, in *blockindex.ClaimIntentRequest, opts ...grpc.CallOption) (*blockindex.ClaimIntentResponse, error) {
+	return route(c, ctx, in.Hash, func(client blockindex.BlockIndexServiceClient) (*blockindex.ClaimIntentResponse, error) {
+		return client.ClaimIntent(ctx, in, opts...)
+	})
+}
+
+// errMigrationCall is returned by the migration calls, which the master makes
+// on individual index nodes rather than through a client
+var errMigrationCall = status.Error(codes.Unimplemented, "range migration calls must be made on an index node")
+
+// BeginMigration is not routed
+func (c *Client) BeginMigration(ctx context.Context, in *blockindex.MigrationRequest, opts ...grpc.CallOption) (*blockindex.MigrationResponse, error) {
+	return nil, errMigrationCall
+}
+
+// ExportRange is not routed
+func (c *Client) ExportRange(ctx context.Context, in *blockindex.ExportRangeRequest, opts ...grpc.CallOption) (*blockindex.ExportResponse, error) {
+	return nil, errMigrationCall
+}
+
+// ExportBlocks is not routed
+func (c *Client) ExportBlocks(ctx context.Context, in *blockindex.ExportBlocksRequest, opts ...grpc.CallOption) (*blockindex.ExportResponse, error) {
+	return nil, errMigrationCall
+}
+
+// ImportBlocks is not routed
+func (c *Client) ImportBlocks(ctx context.Context, in *blockindex.ImportBlocksRequest, opts ...grpc.CallOption) (*blockindex.ImportBlocksResponse, error) {
+	return nil, errMigrationCall
+}
+
+// TakeChanges is not routed
+func (c *Client) TakeChanges(ctx context.Context, in *blockindex.MigrationRequest, opts ...grpc.CallOption) (*blockindex.TakeChangesResponse, error) {
+	return nil, errMigrationCall
+}
+
+// FreezeRange is not routed
+func (c *Client) FreezeRange(ctx context.Context, in *blockindex.MigrationRequest, opts ...grpc.CallOption) (*blockindex.FreezeRangeResponse, error) {
+	return nil, errMigrationCall
+}
+
+// EndMigration is not routed
+func (c *Client) EndMigration(ctx context.Context, in *blockindex.MigrationRequest, opts ...grpc.CallOption) (*blockindex.MigrationResponse, error) {
+	return nil, errMigrationCall
+}
+
+var _ blockindex.BlockIndexServiceClient = (*Client)(nil)
@@ -0,0 +1,79 @@
+package shards
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"bharani/proto/blockindex"
+	"bharani/proto/master"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeMaster serves a fixed shard map
+type fakeMaster struct {
+	master.MasterServiceClient
+	shards *Map
+}
+
+func (m *fakeMaster) GetShardMap(ctx context.Context, in *master.GetShardMapRequest, opts ...grpc.CallOption) (*master.GetShardMapResponse, error) {
+	return m.shards.ToProto(), nil
+}
+
+// fakeShard records the intents it is asked to begin, failing the first
+// `frozen` calls as a node whose range is frozen for a move would
+type fakeShard struct {
+	blockindex.BlockIndexServiceClient
+	frozen  int
+	intents []string
+	mu      sync.Mutex
+}
+
+func (s *fakeShard) BeginPut(ctx context.Context, in *blockindex.BeginPutRequest, opts ...grpc.CallOption) (*blockindex.BeginPutResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.frozen > 0 {
+		s.frozen--
+		return nil, status.Error(codes.Aborted, "range is frozen")
+	}
+
+	resp := &blockindex.BeginPutResponse{}
+	for _, intent := range in.Intents {
+		s.intents = append(s.intents, intent.Hash)
+		resp.Ids = append(resp.Ids, int64(len(s.intents)))
+	}
+	return resp, nil
+}
+
+func TestBatchRetriesOnlyFailedShards(t *testing.T) {
+	low, high := &fakeShard{}, &fakeShard{frozen: 1}
+	client := NewClient("low", &fakeMaster{shards: &Map{Version: 1, Shards: []Shard{
+		{Range: Range{End: "8"}, Address: "low"},
+		{Range: Range{Start: "8"}, Address: "high"},
+	}}}, nil)
+	client.clients["low"] = low
+	client.clients["high"] = high
+
+	resp, err := client.BeginPut(context.Background(), &blockindex.BeginPutRequest{Intents: []*blockindex.Intent{
+		{Hash: "1000"}, {Hash: "9000"}, {Hash: "2000"},
+	}})
+	if err != nil || resp.Error != "" {
+		t.Fatalf("Failed to begin put: %v %s", err, resp.GetError())
+	}
+
+	// The shard that succeeded is not asked again, so it holds no duplicates
+	if got := fmt.Sprint(low.intents); got != "[1000 2000]" {
+		t.Errorf("Expected each intent once on the low shard, got %s", got)
+	}
+	if got := fmt.Sprint(high.intents); got != "[9000]" {
+		t.Errorf("Expected the high shard's intent after the retry, got %s", got)
+	}
+	if got := fmt.Sprint(resp.Ids); got != "[1 1 2]" {
+		t.Errorf("Unexpected intent IDs %s", got)
+	}
+}
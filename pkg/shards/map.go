@@ -0,0 +1,267 @@
+// Package shards partitions the block index across index nodes by ranges of
+// the hash space, and routes index calls to the node serving each hash.
+package shards
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"bharani/proto/master"
+)
+
+// splitDigits is the number of leading hex digits of a hash used to pick a
+// split point, which is far finer than any realistic number of shards
+const splitDigits = 16
+
+// Range is a contiguous range of block hashes. Start is inclusive and End
+// exclusive; an empty End extends to the end of the hash space.
+type Range struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Contains reports whether hash falls in the range
+func (r Range) Contains(hash string) bool {
+	return hash >= r.Start && (r.End == "" || hash < r.End)
+}
+
+// String formats the range for logs and errors
+func (r Range) String() string {
+	end := r.End
+	if end == "" {
+		end = "end"
+	}
+	start := r.Start
+	if start == "" {
+		start = "start"
+	}
+	return fmt.Sprintf("[%s, %s)", start, end)
+}
+
+// Split returns the hex prefix halfway through the range, so that
+// [Start, mid) and [mid, End) each hold about half of the SHA-256 hashes in
+// it. Ranges too narrow to split return an error.
+func (r Range) Split() (string, error) {
+	start, err := prefixValue(r.Start)
+	if err != nil {
+		return "", err
+	}
+	width, err := r.width()
+	if err != nil {
+		return "", err
+	}
+
+	mid := new(big.Int).Rsh(width, 1)
+	mid.Add(mid, start)
+	if mid.Cmp(start) <= 0 {
+		return "", fmt.Errorf("range %s is too narrow to split", r)
+	}
+
+	return fmt.Sprintf("%0*x", splitDigits, mid), nil
+}
+
+// prefixValue reads the first splitDigits hex digits of a boundary, padding
+// shorter boundaries with zeros
+func prefixValue(boundary string) (*big.Int, error) {
+	digits := boundary + strings.Repeat("0", max(splitDigits-len(boundary), 0))
+	value, ok := new(big.Int).SetString(digits[:splitDigits], 16)
+	if !ok {
+		return nil, fmt.Errorf("range boundary %q is not a hex prefix", boundary)
+	}
+	return value, nil
+}
+
+// Shard is a range served by one index node. While the range is being moved
+// to Address, Source is the node it is moving from, which keeps serving it
+// until the move completes.
+type Shard struct {
+	Range
+	Address string `json:"address"`
+	Source  string `json:"source,omitempty"`
+}
+
+// Serving returns the address of the node requests for the shard go to
+func (s *Shard) Serving() string {
+	if s.Source != "" {
+		return s.Source
+	}
+	return s.Address
+}
+
+// Map assigns every hash to exactly one shard. Each change increments its
+// version. An empty map means the index is not sharded.
+type Map struct {
+	Version int64   `json:"version"`
+	Shards  []Shard `json:"shards"` // Sorted by Start
+}
+
+// NewMap returns a map of a single shard covering the whole hash space
+func NewMap(address string) *Map {
+	return &Map{Version: 1, Shards: []Shard{{Address: address}}}
+}
+
+// Lookup returns the shard a hash belongs to, or nil if the map is empty
+func (m *Map) Lookup(hash string) *Shard {
+	i := sort.Search(len(m.Shards), func(i int) bool { return m.Shards[i].Start > hash })
+	if i == 0 {
+		return nil
+	}
+	return &m.Shards[i-1]
+}
+
+// Validate checks that the shards are sorted and cover the hash space
+// without gaps or overlaps
+func (m *Map) Validate() error {
+	if len(m.Shards) == 0 {
+		return nil
+	}
+	if m.Shards[0].Start != "" {
+		return fmt.Errorf("first shard starts at %q instead of the start of the hash space", m.Shards[0].Start)
+	}
+	for i, shard := range m.Shards {
+		if shard.Address == "" {
+			return fmt.Errorf("shard %s has no address", shard.Range)
+		}
+		if i == len(m.Shards)-1 {
+			if shard.End != "" {
+				return fmt.Errorf("last shard ends at %q instead of the end of the hash space", shard.End)
+			}
+			break
+		}
+		if shard.End == "" || shard.End <= shard.Start || m.Shards[i+1].Start != shard.End {
+			return fmt.Errorf("shard %s does not end where shard %s starts", shard.Range, m.Shards[i+1].Range)
+		}
+	}
+	return nil
+}
+
+// Widest returns the index of the widest shard that is not being moved, or -1
+// if every shard is moving
+func (m *Map) Widest() int {
+	widest, best := -1, new(big.Int)
+	for i, shard := range m.Shards {
+		if shard.Source != "" {
+			continue
+		}
+		width, err := shard.width()
+		if err != nil {
+			continue
+		}
+		if widest == -1 || width.Cmp(best) > 0 {
+			widest, best = i, width
+		}
+	}
+	return widest
+}
+
+// width returns the number of splitDigits prefixes in the range
+func (r Range) width() (*big.Int, error) {
+	start, err := prefixValue(r.Start)
+	if err != nil {
+		return nil, err
+	}
+	end := new(big.Int).Lsh(big.NewInt(1), 4*splitDigits)
+	if r.End != "" {
+		if end, err = prefixValue(r.End); err != nil {
+			return nil, err
+		}
+	}
+	return end.Sub(end, start), nil
+}
+
+// Clone returns a copy of the map that can be changed independently
+func (m *Map) Clone() *Map {
+	return &Map{Version: m.Version, Shards: append([]Shard(nil), m.Shards...)}
+}
+
+// LoadMap reads a map saved with Save. A missing file returns an empty map.
+func LoadMap(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Map{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shard map: %w", err)
+	}
+
+	var m Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse shard map %s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid shard map %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Save writes the map to path, replacing the previous file atomically
+func (m *Map) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode shard map: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save shard map: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save shard map: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save shard map: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save shard map: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save shard map: %w", err)
+	}
+	return nil
+}
+
+// ToProto converts the map to its protobuf form
+func (m *Map) ToProto() *master.GetShardMapResponse {
+	resp := &master.GetShardMapResponse{
+		Version: m.Version,
+		Shards:  make([]*master.IndexShard, 0, len(m.Shards)),
+	}
+	for _, shard := range m.Shards {
+		resp.Shards = append(resp.Shards, &master.IndexShard{
+			Start:   shard.Start,
+			End:     shard.End,
+			Address: shard.Address,
+			Source:  shard.Source,
+		})
+	}
+	return resp
+}
+
+// FromProto converts a GetShardMap response to a map
+func FromProto(resp *master.GetShardMapResponse) (*Map, error) {
+	m := &Map{
+		Version: resp.Version,
+		Shards:  make([]Shard, 0, len(resp.Shards)),
+	}
+	for _, shard := range resp.Shards {
+		m.Shards = append(m.Shards, Shard{
+			Range:   Range{Start: shard.Start, End: shard.End},
+			Address: shard.Address,
+			Source:  shard.Source,
+		})
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid shard map: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,149 @@
+package shards
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	m := &Map{Version: 3, Shards: []Shard{
+		{Range: Range{End: "4"}, Address: "a"},
+		{Range: Range{Start: "4", End: "8"}, Address: "b", Source: "a"},
+		{Range: Range{Start: "8"}, Address: "c"},
+	}}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Map should be valid: %v", err)
+	}
+
+	tests := []struct {
+		hash    string
+		address string
+		serving string
+	}{
+		{"0000", "a", "a"},
+		{"3fff", "a", "a"},
+		{"4", "b", "a"},
+		{"7fff", "b", "a"},
+		{"8000", "c", "c"},
+		{"ffff", "c", "c"},
+	}
+	for _, tt := range tests {
+		shard := m.Lookup(tt.hash)
+		if shard.Address != tt.address || shard.Serving() != tt.serving {
+			t.Errorf("Hash %s: expected shard %s served by %s, got %s served by %s", tt.hash, tt.address, tt.serving, shard.Address, shard.Serving())
+		}
+	}
+
+	if shard := (&Map{}).Lookup("00"); shard != nil {
+		t.Errorf("Empty map should have no shards, got %+v", shard)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		r   Range
+		mid string
+	}{
+		{Range{}, "8000000000000000"},
+		{Range{End: "8"}, "4000000000000000"},
+		{Range{Start: "8"}, "c000000000000000"},
+		{Range{Start: "4000000000000000", End: "8000000000000000"}, "6000000000000000"},
+	}
+	for _, tt := range tests {
+		mid, err := tt.r.Split()
+		if err != nil {
+			t.Fatalf("Failed to split %s: %v", tt.r, err)
+		}
+		if mid != tt.mid {
+			t.Errorf("Range %s: expected midpoint %s, got %s", tt.r, tt.mid, mid)
+		}
+		if !tt.r.Contains(mid) {
+			t.Errorf("Range %s should contain its midpoint %s", tt.r, mid)
+		}
+	}
+
+	if _, err := (Range{Start: "0000000000000000", End: "0000000000000001"}).Split(); err == nil {
+		t.Error("Splitting a range of one prefix should fail")
+	}
+	if _, err := (Range{Start: "xyz"}).Split(); err == nil {
+		t.Error("Splitting a range with a non-hex boundary should fail")
+	}
+}
+
+func TestWidest(t *testing.T) {
+	m := &Map{Shards: []Shard{
+		{Range: Range{End: "2"}, Address: "a"},
+		{Range: Range{Start: "2", End: "8"}, Address: "b", Source: "a"},
+		{Range: Range{Start: "8", End: "c"}, Address: "c"},
+		{Range: Range{Start: "c"}, Address: "d"},
+	}}
+	if i := m.Widest(); i != 2 {
+		t.Errorf("Expected the widest shard not moving to be 2, got %d", i)
+	}
+
+	m.Shards[2].Source = "a"
+	m.Shards[3].Source = "a"
+	if i := m.Widest(); i != 0 {
+		t.Errorf("Expected the widest shard not moving to be 0, got %d", i)
+	}
+
+	m.Shards[0].Source = "b"
+	if i := m.Widest(); i != -1 {
+		t.Errorf("Expected no shard when every shard is moving, got %d", i)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		shards []Shard
+	}{
+		{"gap", []Shard{{Range: Range{End: "4"}, Address: "a"}, {Range: Range{Start: "5"}, Address: "b"}}},
+		{"overlap", []Shard{{Range: Range{End: "5"}, Address: "a"}, {Range: Range{Start: "4"}, Address: "b"}}},
+		{"late start", []Shard{{Range: Range{Start: "1"}, Address: "a"}}},
+		{"early end", []Shard{{Range: Range{End: "f"}, Address: "a"}}},
+		{"empty range", []Shard{{Range: Range{End: "4"}, Address: "a"}, {Range: Range{Start: "4", End: "4"}, Address: "b"}, {Range: Range{Start: "4"}, Address: "c"}}},
+		{"no address", []Shard{{}}},
+	}
+	for _, tt := range tests {
+		if err := (&Map{Shards: tt.shards}).Validate(); err == nil {
+			t.Errorf("Map with %s should be invalid", tt.name)
+		}
+	}
+}
+
+func TestSaveAndLoadMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shards.json")
+
+	m, err := LoadMap(path)
+	if err != nil {
+		t.Fatalf("Failed to load missing map: %v", err)
+	}
+	if len(m.Shards) != 0 {
+		t.Fatalf("Missing map should be empty, got %+v", m)
+	}
+
+	m = &Map{Version: 2, Shards: []Shard{
+		{Range: Range{End: "8"}, Address: "a"},
+		{Range: Range{Start: "8"}, Address: "b", Source: "a"},
+	}}
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Failed to save map: %v", err)
+	}
+
+	loaded, err := LoadMap(path)
+	if err != nil {
+		t.Fatalf("Failed to load map: %v", err)
+	}
+	if loaded.Version != 2 || len(loaded.Shards) != 2 || loaded.Shards[1] != m.Shards[1] {
+		t.Errorf("Expected %+v, got %+v", m, loaded)
+	}
+
+	converted, err := FromProto(m.ToProto())
+	if err != nil {
+		t.Fatalf("Failed to convert map: %v", err)
+	}
+	if converted.Version != 2 || converted.Shards[0] != m.Shards[0] || converted.Shards[1] != m.Shards[1] {
+		t.Errorf("Expected %+v after conversion, got %+v", m, converted)
+	}
+}
@@ -42,6 +42,28 @@ func (b *Bucket) AddBlock(block *Block) error {
 	return nil
 }
 
+// Reserve accounts for n bytes of blocks stored in the bucket elsewhere,
+// failing if they would not fit
+func (b *Bucket) Reserve(n int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Size+n > b.MaxSize {
+		return fmt.Errorf("bucket %s is full (size: %d, max: %d)", b.ID, b.Size, b.MaxSize)
+	}
+
+	b.Size += n
+	return nil
+}
+
+// GetSize returns the number of bytes stored or reserved in the bucket
+func (b *Bucket) GetSize() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.Size
+}
+
 // GetBlock retrieves a block by hash
 func (b *Bucket) GetBlock(hash string) (*Block, bool) {
 	b.mu.RLock()
@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// gearSeed seeds the gear table; changing it changes every chunk boundary
+const gearSeed = 0x62686172616e6921
+
+// gearTable maps each byte value to a pseudo-random 64-bit value for the rolling hash
+var gearTable = newGearTable(gearSeed)
+
+// newGearTable fills a gear table from a splitmix64 sequence
+func newGearTable(seed uint64) [256]uint64 {
+	var table [256]uint64
+	state := seed
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}
+
+// ChunkerOptions bounds the sizes of chunks produced by a Chunker
+type ChunkerOptions struct {
+	MinSize int // No boundary is placed before MinSize bytes
+	AvgSize int // Target average chunk size, must be a power of two
+	MaxSize int // Chunks are cut at MaxSize regardless of content
+}
+
+// DefaultChunkerOptions returns options with MaxSize equal to maxBlockSize,
+// an average of a quarter of that and a minimum of a quarter of the average
+func DefaultChunkerOptions(maxBlockSize int64) ChunkerOptions {
+	avg := 1 << (bits.Len64(uint64(maxBlockSize)/4) - 1)
+	return ChunkerOptions{
+		MinSize: avg / 4,
+		AvgSize: avg,
+		MaxSize: int(maxBlockSize),
+	}
+}
+
+// Validate checks that the options are consistent and MaxSize does not exceed maxBlockSize
+func (o ChunkerOptions) Validate(maxBlockSize int64) error {
+	if err := o.validateSizes(); err != nil {
+		return err
+	}
+	if int64(o.MaxSize) > maxBlockSize {
+		return fmt.Errorf("chunk max size %d exceeds max block size %d", o.MaxSize, maxBlockSize)
+	}
+	return nil
+}
+
+// validateSizes checks that the chunk sizes are consistent with each other
+func (o ChunkerOptions) validateSizes() error {
+	if o.MinSize < 64 {
+		return fmt.Errorf("chunk min size %d is below 64 bytes", o.MinSize)
+	}
+	if o.AvgSize <= 0 || o.AvgSize&(o.AvgSize-1) != 0 {
+		return fmt.Errorf("chunk average size %d is not a power of two", o.AvgSize)
+	}
+	if o.MinSize >= o.AvgSize || o.AvgSize >= o.MaxSize {
+		return fmt.Errorf("chunk sizes must satisfy min < avg < max (got %d, %d, %d)", o.MinSize, o.AvgSize, o.MaxSize)
+	}
+	return nil
+}
+
+// Chunker splits a stream into content-defined chunks using FastCDC with
+// normalized chunking, so an insertion only changes the chunks around it
+type Chunker struct {
+	reader    io.Reader
+	opts      ChunkerOptions
+	maskSmall uint64 // Harder mask used before the average size
+	maskLarge uint64 // Easier mask used after the average size
+	buf       []byte
+	start     int
+	end       int
+	eof       bool
+}
+
+// NewChunker creates a chunker reading from r. It returns an error if the
+// chunk sizes in opts are inconsistent.
+func NewChunker(r io.Reader, opts ChunkerOptions) (*Chunker, error) {
+	if err := opts.validateSizes(); err != nil {
+		return nil, fmt.Errorf("invalid chunker options: %w", err)
+	}
+	avgBits := bits.Len(uint(opts.AvgSize)) - 1
+
+	return &Chunker{
+		reader:    r,
+		opts:      opts,
+		maskSmall: highMask(avgBits + 2),
+		maskLarge: highMask(avgBits - 2),
+		buf:       make([]byte, 2*opts.MaxSize),
+	}, nil
+}
+
+// highMask returns a mask of the n most significant bits; the gear hash mixes
+// older bytes into the high bits, so those carry the most context
+func highMask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return ^uint64(0) << (64 - n)
+}
+
+// Next returns the next chunk, or io.EOF once the stream is exhausted.
+// The returned slice is only valid until the following call to Next.
+func (c *Chunker) Next() ([]byte, error) {
+	if err := c.fill(); err != nil {
+		return nil, err
+	}
+
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+
+	n := c.cut(c.buf[c.start:c.end])
+	chunk := c.buf[c.start : c.start+n]
+	c.start += n
+	return chunk, nil
+}
+
+// fill tops up the buffer until it holds at least MaxSize bytes or the reader is drained
+func (c *Chunker) fill() error {
+	if c.eof || c.end-c.start >= c.opts.MaxSize {
+		return nil
+	}
+
+	if c.start > 0 {
+		c.end = copy(c.buf, c.buf[c.start:c.end])
+		c.start = 0
+	}
+
+	for c.end < len(c.buf) {
+		n, err := c.reader.Read(c.buf[c.end:])
+		c.end += n
+		if err == io.EOF {
+			c.eof = true
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read chunk data: %w", err)
+		}
+		if c.end-c.start >= c.opts.MaxSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// cut returns the length of the next chunk at the start of data
+func (c *Chunker) cut(data []byte) int {
+	n := len(data)
+	if n <= c.opts.MinSize {
+		return n
+	}
+	if n > c.opts.MaxSize {
+		n = c.opts.MaxSize
+	}
+
+	normal := c.opts.AvgSize
+	if n < normal {
+		normal = n
+	}
+
+	var hash uint64
+	i := c.opts.MinSize
+	for ; i < normal; i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if hash&c.maskSmall == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if hash&c.maskLarge == 0 {
+			return i + 1
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+var testChunkerOptions = ChunkerOptions{MinSize: 2 * 1024, AvgSize: 8 * 1024, MaxSize: 64 * 1024}
+
+func randomData(size int, seed int64) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func chunkAll(t testing.TB, data []byte, opts ChunkerOptions) [][]byte {
+	chunker, err := NewChunker(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks := make([][]byte, 0)
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to chunk data: %v", err)
+		}
+		chunks = append(chunks, append([]byte{}, chunk...))
+	}
+	return chunks
+}
+
+// dedupRatio returns the fraction of bytes in b whose chunks also appear in a
+func dedupRatio(a, b [][]byte) float64 {
+	known := make(map[string]bool)
+	for _, chunk := range a {
+		known[ComputeHash(chunk)] = true
+	}
+
+	shared, total := 0, 0
+	for _, chunk := range b {
+		total += len(chunk)
+		if known[ComputeHash(chunk)] {
+			shared += len(chunk)
+		}
+	}
+	return float64(shared) / float64(total)
+}
+
+func fixedChunks(data []byte, size int) [][]byte {
+	chunks := make([][]byte, 0)
+	for start := 0; start < len(data); start += size {
+		chunks = append(chunks, data[start:min(start+size, len(data))])
+	}
+	return chunks
+}
+
+func TestChunkerReassembles(t *testing.T) {
+	data := randomData(1024*1024+17, 1)
+	chunks := chunkAll(t, data, testChunkerOptions)
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("Chunks do not reassemble to the original data")
+	}
+
+	for i, chunk := range chunks {
+		if len(chunk) > testChunkerOptions.MaxSize {
+			t.Errorf("Chunk %d exceeds max size: %d", i, len(chunk))
+		}
+		if i < len(chunks)-1 && len(chunk) < testChunkerOptions.MinSize {
+			t.Errorf("Chunk %d below min size: %d", i, len(chunk))
+		}
+	}
+
+	avg := len(data) / len(chunks)
+	if avg < testChunkerOptions.AvgSize/2 || avg > testChunkerOptions.AvgSize*2 {
+		t.Errorf("Average chunk size %d too far from target %d", avg, testChunkerOptions.AvgSize)
+	}
+}
+
+func TestChunkerDeterministic(t *testing.T) {
+	data := randomData(256*1024, 2)
+	first := chunkAll(t, data, testChunkerOptions)
+	second := chunkAll(t, data, testChunkerOptions)
+
+	if dedupRatio(first, second) != 1 {
+		t.Error("Chunking the same data twice should produce identical chunks")
+	}
+}
+
+func TestChunkerDedupOnShiftedInput(t *testing.T) {
+	data := randomData(4*1024*1024, 3)
+	shifted := append([]byte{0x42}, data...)
+
+	cdc := dedupRatio(chunkAll(t, data, testChunkerOptions), chunkAll(t, shifted, testChunkerOptions))
+	fixed := dedupRatio(fixedChunks(data, testChunkerOptions.AvgSize), fixedChunks(shifted, testChunkerOptions.AvgSize))
+
+	if cdc < 0.95 {
+		t.Errorf("Content-defined dedup ratio after one-byte insert too low: %.3f", cdc)
+	}
+	if fixed > 0.01 {
+		t.Errorf("Fixed-size dedup ratio after one-byte insert unexpectedly high: %.3f", fixed)
+	}
+}
+
+func TestChunkerDedupOnMidstreamEdit(t *testing.T) {
+	data := randomData(4*1024*1024, 4)
+	edited := make([]byte, 0, len(data)+100)
+	edited = append(edited, data[:len(data)/2]...)
+	edited = append(edited, randomData(100, 5)...)
+	edited = append(edited, data[len(data)/2:]...)
+
+	ratio := dedupRatio(chunkAll(t, data, testChunkerOptions), chunkAll(t, edited, testChunkerOptions))
+	if ratio < 0.95 {
+		t.Errorf("Dedup ratio after mid-stream insert too low: %.3f", ratio)
+	}
+}
+
+func TestChunkerOptionsValidate(t *testing.T) {
+	maxBlockSize := int64(4 * 1024 * 1024)
+
+	if err := DefaultChunkerOptions(maxBlockSize).Validate(maxBlockSize); err != nil {
+		t.Errorf("Default options should be valid: %v", err)
+	}
+
+	invalid := []ChunkerOptions{
+		{MinSize: 32, AvgSize: 1024, MaxSize: 4096},
+		{MinSize: 1024, AvgSize: 3000, MaxSize: 8192},
+		{MinSize: 4096, AvgSize: 4096, MaxSize: 8192},
+		{MinSize: 1024, AvgSize: 4096, MaxSize: 8 * 1024 * 1024},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(maxBlockSize); err == nil {
+			t.Errorf("Options %+v should be rejected", opts)
+		}
+	}
+}
+
+func TestNewChunkerRejectsInvalidOptions(t *testing.T) {
+	invalid := []ChunkerOptions{
+		{MinSize: 8192, AvgSize: 4096, MaxSize: 65536},
+		{MinSize: 1024, AvgSize: 65536, MaxSize: 8192},
+		{MinSize: 1024, AvgSize: 0, MaxSize: 8192},
+	}
+	for _, opts := range invalid {
+		if _, err := NewChunker(bytes.NewReader(nil), opts); err == nil {
+			t.Errorf("Options %+v should be rejected", opts)
+		}
+	}
+}
+
+func BenchmarkChunker(b *testing.B) {
+	data := randomData(32*1024*1024, 6)
+	opts := DefaultChunkerOptions(4 * 1024 * 1024)
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chunker, err := NewChunker(bytes.NewReader(data), opts)
+		if err != nil {
+			b.Fatalf("Failed to create chunker: %v", err)
+		}
+		for {
+			if _, err := chunker.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkChunkerSmall(b *testing.B) {
+	data := randomData(8*1024*1024, 7)
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chunker, err := NewChunker(bytes.NewReader(data), testChunkerOptions)
+		if err != nil {
+			b.Fatalf("Failed to create chunker: %v", err)
+		}
+		for {
+			if _, err := chunker.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
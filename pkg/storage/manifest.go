@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotManifest is returned when decoding block data that is not a manifest
+var ErrNotManifest = errors.New("block is not a file manifest")
+
+// manifestMagic prefixes every encoded manifest so it can be told apart from file data
+var manifestMagic = []byte("BHMF1\n")
+
+// ManifestEntry references one block of a file, or a child manifest when Indirect is set
+type ManifestEntry struct {
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`               // Bytes of file data covered by this entry
+	Indirect bool   `json:"indirect,omitempty"` // Hash points to a child manifest
+}
+
+// Manifest describes a file as an ordered list of blocks
+type Manifest struct {
+	Entries   []ManifestEntry `json:"entries"`
+	TotalSize int64           `json:"total_size"`       // Total file length in bytes
+	Digest    string          `json:"digest,omitempty"` // SHA-256 of the whole file (root manifest only)
+}
+
+// NewManifest creates a manifest from entries, computing the total size
+func NewManifest(entries []ManifestEntry, digest string) *Manifest {
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	return &Manifest{
+		Entries:   entries,
+		TotalSize: total,
+		Digest:    digest,
+	}
+}
+
+// Encode serializes the manifest into block data
+func (m *Manifest) Encode() ([]byte, error) {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	return append(append([]byte{}, manifestMagic...), body...), nil
+}
+
+// DecodeManifest parses block data produced by Manifest.Encode
+func DecodeManifest(data []byte) (*Manifest, error) {
+	if !IsManifest(data) {
+		return nil, ErrNotManifest
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data[len(manifestMagic):], &m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	var total int64
+	for _, entry := range m.Entries {
+		if entry.Size < 0 {
+			return nil, fmt.Errorf("manifest entry %s has negative size", entry.Hash)
+		}
+		total += entry.Size
+	}
+	if total != m.TotalSize {
+		return nil, fmt.Errorf("manifest size mismatch: entries cover %d bytes, header says %d", total, m.TotalSize)
+	}
+
+	return &m, nil
+}
+
+// IsManifest reports whether block data looks like an encoded manifest
+func IsManifest(data []byte) bool {
+	return bytes.HasPrefix(data, manifestMagic)
+}
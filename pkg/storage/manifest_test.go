@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestManifestEncodeDecode(t *testing.T) {
+	entries := []ManifestEntry{
+		{Hash: ComputeHash([]byte("a")), Size: 4},
+		{Hash: ComputeHash([]byte("b")), Size: 6, Indirect: true},
+	}
+	manifest := NewManifest(entries, ComputeHash([]byte("file")))
+
+	if manifest.TotalSize != 10 {
+		t.Errorf("Total size mismatch: got %d, want 10", manifest.TotalSize)
+	}
+
+	data, err := manifest.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode manifest: %v", err)
+	}
+
+	if !IsManifest(data) {
+		t.Fatal("Encoded manifest should be recognized as a manifest")
+	}
+
+	decoded, err := DecodeManifest(data)
+	if err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+
+	if len(decoded.Entries) != 2 || decoded.Entries[1] != entries[1] {
+		t.Errorf("Decoded entries mismatch: got %+v", decoded.Entries)
+	}
+
+	if decoded.Digest != manifest.Digest {
+		t.Errorf("Digest mismatch: got %s, want %s", decoded.Digest, manifest.Digest)
+	}
+}
+
+func TestDecodeManifestRejectsData(t *testing.T) {
+	if _, err := DecodeManifest([]byte("plain block data")); err == nil {
+		t.Error("Decoding plain data should fail")
+	}
+
+	manifest := NewManifest([]ManifestEntry{{Hash: "h", Size: 5}}, "")
+	manifest.TotalSize = 7
+	data, err := manifest.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode manifest: %v", err)
+	}
+
+	if _, err := DecodeManifest(data); err == nil {
+		t.Error("Decoding a manifest with inconsistent size should fail")
+	}
+}
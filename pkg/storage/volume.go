@@ -11,6 +11,10 @@ type VolumeState string
 const (
 	VolumeStateOpen   VolumeState = "open"   // Accepting new writes
 	VolumeStateClosed VolumeState = "closed" // No longer accepting writes
+
+	// VolumeStateErasureCoded volumes hold each block as Reed-Solomon shards,
+	// one per OSD, instead of whole replicas
+	VolumeStateErasureCoded VolumeState = "erasure_coded"
 )
 
 // Volume represents one or more buckets replicated across OSDs
@@ -62,6 +66,18 @@ func (v *Volume) GetBucket(bucketID string) (*Bucket, bool) {
 	return bucket, exists
 }
 
+// Size returns the total size of the volume's buckets
+func (v *Volume) Size() int64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var size int64
+	for _, bucket := range v.Buckets {
+		size += bucket.GetSize()
+	}
+	return size
+}
+
 // Close marks the volume as closed
 func (v *Volume) Close() {
 	v.mu.Lock()
@@ -9,7 +9,6 @@ import (
 	"bharani/proto/osd"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Manager handles volume operations like transfer and erasure coding
@@ -17,6 +16,7 @@ type Manager struct {
 	config     *config.Config
 	encoder    *erasure.Encoder
 	osdClients map[string]osd.OSDServiceClient
+	dialOption grpc.DialOption
 }
 
 // NewManager creates a new Volume Manager
@@ -26,10 +26,16 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 		return nil, fmt.Errorf("failed to create erasure encoder: %w", err)
 	}
 
+	dialOption, err := cfg.TLS.DialOption()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+
 	return &Manager{
 		config:     cfg,
 		encoder:    encoder,
 		osdClients: make(map[string]osd.OSDServiceClient),
+		dialOption: dialOption,
 	}, nil
 }
 
@@ -39,7 +45,7 @@ func (m *Manager) GetOSDClient(osdAddress string) (osd.OSDServiceClient, error)
 		return client, nil
 	}
 
-	conn, err := grpc.NewClient(osdAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(osdAddress, m.dialOption)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to OSD %s: %w", osdAddress, err)
 	}
@@ -21,12 +21,16 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// PutEntryRequest adds an entry for a block that is not indexed yet
 type PutEntryRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
 	CellId        string                 `protobuf:"bytes,2,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
 	BucketId      string                 `protobuf:"bytes,3,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
 	Checksum      string                 `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,5,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	Size          int64                  `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`                            // block length in bytes
+	CreatedAt     int64                  `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix seconds; 0 is stamped with the time the index applies the call
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,10 +93,33 @@ func (x *PutEntryRequest) GetChecksum() string {
 	return ""
 }
 
+func (x *PutEntryRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *PutEntryRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PutEntryRequest) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
 type PutEntryResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Deleting      bool                   `protobuf:"varint,3,opt,name=deleting,proto3" json:"deleting,omitempty"` // the block is being garbage collected and was not updated
+	Existing      *Entry                 `protobuf:"bytes,4,opt,name=existing,proto3" json:"existing,omitempty"`  // set when the block was already indexed; nothing was changed
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -141,6 +168,206 @@ func (x *PutEntryResponse) GetError() string {
 	return ""
 }
 
+func (x *PutEntryResponse) GetDeleting() bool {
+	if x != nil {
+		return x.Deleting
+	}
+	return false
+}
+
+func (x *PutEntryResponse) GetExisting() *Entry {
+	if x != nil {
+		return x.Existing
+	}
+	return nil
+}
+
+// UpdateEntryRequest moves a block to a new location if it is still at the
+// expected one
+type UpdateEntryRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Hash             string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	CellId           string                 `protobuf:"bytes,2,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	BucketId         string                 `protobuf:"bytes,3,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
+	Checksum         string                 `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	VolumeId         string                 `protobuf:"bytes,5,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	Size             int64                  `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"` // block length in bytes; 0 keeps the size already recorded
+	ExpectedCellId   string                 `protobuf:"bytes,7,opt,name=expected_cell_id,json=expectedCellId,proto3" json:"expected_cell_id,omitempty"`
+	ExpectedBucketId string                 `protobuf:"bytes,8,opt,name=expected_bucket_id,json=expectedBucketId,proto3" json:"expected_bucket_id,omitempty"`
+	ExpectedVolumeId string                 `protobuf:"bytes,9,opt,name=expected_volume_id,json=expectedVolumeId,proto3" json:"expected_volume_id,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *UpdateEntryRequest) Reset() {
+	*x = UpdateEntryRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateEntryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateEntryRequest) ProtoMessage() {}
+
+func (x *UpdateEntryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateEntryRequest.ProtoReflect.Descriptor instead.
+func (*UpdateEntryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UpdateEntryRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *UpdateEntryRequest) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+func (x *UpdateEntryRequest) GetBucketId() string {
+	if x != nil {
+		return x.BucketId
+	}
+	return ""
+}
+
+func (x *UpdateEntryRequest) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *UpdateEntryRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *UpdateEntryRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *UpdateEntryRequest) GetExpectedCellId() string {
+	if x != nil {
+		return x.ExpectedCellId
+	}
+	return ""
+}
+
+func (x *UpdateEntryRequest) GetExpectedBucketId() string {
+	if x != nil {
+		return x.ExpectedBucketId
+	}
+	return ""
+}
+
+func (x *UpdateEntryRequest) GetExpectedVolumeId() string {
+	if x != nil {
+		return x.ExpectedVolumeId
+	}
+	return ""
+}
+
+type UpdateEntryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Deleting      bool                   `protobuf:"varint,3,opt,name=deleting,proto3" json:"deleting,omitempty"` // the block is being garbage collected and was not updated
+	Conflict      bool                   `protobuf:"varint,4,opt,name=conflict,proto3" json:"conflict,omitempty"` // the block was no longer at the expected location and was not updated
+	Current       *Entry                 `protobuf:"bytes,5,opt,name=current,proto3" json:"current,omitempty"`    // the entry as stored after the call, unset if the block is not indexed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateEntryResponse) Reset() {
+	*x = UpdateEntryResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateEntryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateEntryResponse) ProtoMessage() {}
+
+func (x *UpdateEntryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateEntryResponse.ProtoReflect.Descriptor instead.
+func (*UpdateEntryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UpdateEntryResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UpdateEntryResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UpdateEntryResponse) GetDeleting() bool {
+	if x != nil {
+		return x.Deleting
+	}
+	return false
+}
+
+func (x *UpdateEntryResponse) GetConflict() bool {
+	if x != nil {
+		return x.Conflict
+	}
+	return false
+}
+
+func (x *UpdateEntryResponse) GetCurrent() *Entry {
+	if x != nil {
+		return x.Current
+	}
+	return nil
+}
+
 type GetEntryRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
@@ -150,7 +377,7 @@ type GetEntryRequest struct {
 
 func (x *GetEntryRequest) Reset() {
 	*x = GetEntryRequest{}
-	mi := &file_proto_blockindex_proto_msgTypes[2]
+	mi := &file_proto_blockindex_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -162,7 +389,7 @@ func (x *GetEntryRequest) String() string {
 func (*GetEntryRequest) ProtoMessage() {}
 
 func (x *GetEntryRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_blockindex_proto_msgTypes[2]
+	mi := &file_proto_blockindex_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -175,7 +402,7 @@ func (x *GetEntryRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetEntryRequest.ProtoReflect.Descriptor instead.
 func (*GetEntryRequest) Descriptor() ([]byte, []int) {
-	return file_proto_blockindex_proto_rawDescGZIP(), []int{2}
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *GetEntryRequest) GetHash() string {
@@ -192,13 +419,17 @@ type GetEntryResponse struct {
 	BucketId      string                 `protobuf:"bytes,3,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
 	Checksum      string                 `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
 	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,6,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`        // empty for entries written before volumes were recorded
+	Size          int64                  `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"`                               // 0 for entries written before sizes were recorded
+	CreatedAt     int64                  `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`    // unix seconds
+	VerifiedAt    int64                  `protobuf:"varint,9,opt,name=verified_at,json=verifiedAt,proto3" json:"verified_at,omitempty"` // unix seconds of the last scrub that found every replica intact, 0 if never
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetEntryResponse) Reset() {
 	*x = GetEntryResponse{}
-	mi := &file_proto_blockindex_proto_msgTypes[3]
+	mi := &file_proto_blockindex_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -210,7 +441,7 @@ func (x *GetEntryResponse) String() string {
 func (*GetEntryResponse) ProtoMessage() {}
 
 func (x *GetEntryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_blockindex_proto_msgTypes[3]
+	mi := &file_proto_blockindex_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -223,7 +454,7 @@ func (x *GetEntryResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetEntryResponse.ProtoReflect.Descriptor instead.
 func (*GetEntryResponse) Descriptor() ([]byte, []int) {
-	return file_proto_blockindex_proto_rawDescGZIP(), []int{3}
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetEntryResponse) GetFound() bool {
@@ -261,6 +492,34 @@ func (x *GetEntryResponse) GetError() string {
 	return ""
 }
 
+func (x *GetEntryResponse) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *GetEntryResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *GetEntryResponse) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *GetEntryResponse) GetVerifiedAt() int64 {
+	if x != nil {
+		return x.VerifiedAt
+	}
+	return 0
+}
+
 type ExistsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
@@ -270,7 +529,7 @@ type ExistsRequest struct {
 
 func (x *ExistsRequest) Reset() {
 	*x = ExistsRequest{}
-	mi := &file_proto_blockindex_proto_msgTypes[4]
+	mi := &file_proto_blockindex_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -282,7 +541,7 @@ func (x *ExistsRequest) String() string {
 func (*ExistsRequest) ProtoMessage() {}
 
 func (x *ExistsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_blockindex_proto_msgTypes[4]
+	mi := &file_proto_blockindex_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -295,7 +554,7 @@ func (x *ExistsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExistsRequest.ProtoReflect.Descriptor instead.
 func (*ExistsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_blockindex_proto_rawDescGZIP(), []int{4}
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ExistsRequest) GetHash() string {
@@ -314,7 +573,7 @@ type ExistsResponse struct {
 
 func (x *ExistsResponse) Reset() {
 	*x = ExistsResponse{}
-	mi := &file_proto_blockindex_proto_msgTypes[5]
+	mi := &file_proto_blockindex_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -326,7 +585,7 @@ func (x *ExistsResponse) String() string {
 func (*ExistsResponse) ProtoMessage() {}
 
 func (x *ExistsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_blockindex_proto_msgTypes[5]
+	mi := &file_proto_blockindex_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -339,7 +598,7 @@ func (x *ExistsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExistsResponse.ProtoReflect.Descriptor instead.
 func (*ExistsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_blockindex_proto_rawDescGZIP(), []int{5}
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ExistsResponse) GetExists() bool {
@@ -349,36 +608,3036 @@ func (x *ExistsResponse) GetExists() bool {
 	return false
 }
 
-var File_proto_blockindex_proto protoreflect.FileDescriptor
+type ExistsBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hashes        []string               `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_blockindex_proto_rawDesc = "" +
-	"\n" +
-	"\x16proto/blockindex.proto\x12\n" +
-	"blockindex\"w\n" +
-	"\x0fPutEntryRequest\x12\x12\n" +
-	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x17\n" +
-	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12\x1b\n" +
-	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12\x1a\n" +
-	"\bchecksum\x18\x04 \x01(\tR\bchecksum\"B\n" +
-	"\x10PutEntryResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"%\n" +
-	"\x0fGetEntryRequest\x12\x12\n" +
-	"\x04hash\x18\x01 \x01(\tR\x04hash\"\x90\x01\n" +
-	"\x10GetEntryResponse\x12\x14\n" +
-	"\x05found\x18\x01 \x01(\bR\x05found\x12\x17\n" +
-	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12\x1b\n" +
-	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12\x1a\n" +
-	"\bchecksum\x18\x04 \x01(\tR\bchecksum\x12\x14\n" +
-	"\x05error\x18\x05 \x01(\tR\x05error\"#\n" +
-	"\rExistsRequest\x12\x12\n" +
-	"\x04hash\x18\x01 \x01(\tR\x04hash\"(\n" +
-	"\x0eExistsResponse\x12\x16\n" +
-	"\x06exists\x18\x01 \x01(\bR\x06exists2\xe2\x01\n" +
-	"\x11BlockIndexService\x12E\n" +
-	"\bPutEntry\x12\x1b.blockindex.PutEntryRequest\x1a\x1c.blockindex.PutEntryResponse\x12E\n" +
-	"\bGetEntry\x12\x1b.blockindex.GetEntryRequest\x1a\x1c.blockindex.GetEntryResponse\x12?\n" +
-	"\x06Exists\x12\x19.blockindex.ExistsRequest\x1a\x1a.blockindex.ExistsResponseB\x1aZ\x18bharani/proto/blockindexb\x06proto3"
+func (x *ExistsBatchRequest) Reset() {
+	*x = ExistsBatchRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExistsBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExistsBatchRequest) ProtoMessage() {}
+
+func (x *ExistsBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExistsBatchRequest.ProtoReflect.Descriptor instead.
+func (*ExistsBatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ExistsBatchRequest) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+type ExistsBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Existing      []string               `protobuf:"bytes,1,rep,name=existing,proto3" json:"existing,omitempty"` // subset of the requested hashes present in the index
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExistsBatchResponse) Reset() {
+	*x = ExistsBatchResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExistsBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExistsBatchResponse) ProtoMessage() {}
+
+func (x *ExistsBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExistsBatchResponse.ProtoReflect.Descriptor instead.
+func (*ExistsBatchResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ExistsBatchResponse) GetExisting() []string {
+	if x != nil {
+		return x.Existing
+	}
+	return nil
+}
+
+func (x *ExistsBatchResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Entry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	CellId        string                 `protobuf:"bytes,2,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	BucketId      string                 `protobuf:"bytes,3,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
+	Checksum      string                 `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,5,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	Size          int64                  `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_proto_blockindex_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Entry) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Entry) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+func (x *Entry) GetBucketId() string {
+	if x != nil {
+		return x.BucketId
+	}
+	return ""
+}
+
+func (x *Entry) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *Entry) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *Entry) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type GetEntriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hashes        []string               `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEntriesRequest) Reset() {
+	*x = GetEntriesRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEntriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEntriesRequest) ProtoMessage() {}
+
+func (x *GetEntriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEntriesRequest.ProtoReflect.Descriptor instead.
+func (*GetEntriesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetEntriesRequest) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+type GetEntriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"` // only hashes that were found
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEntriesResponse) Reset() {
+	*x = GetEntriesResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEntriesResponse) ProtoMessage() {}
+
+func (x *GetEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEntriesResponse.ProtoReflect.Descriptor instead.
+func (*GetEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetEntriesResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *GetEntriesResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type MarkVerifiedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`                               // verified block length, recorded for entries that lack it
+	VerifiedAt    int64                  `protobuf:"varint,3,opt,name=verified_at,json=verifiedAt,proto3" json:"verified_at,omitempty"` // unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkVerifiedRequest) Reset() {
+	*x = MarkVerifiedRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkVerifiedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkVerifiedRequest) ProtoMessage() {}
+
+func (x *MarkVerifiedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkVerifiedRequest.ProtoReflect.Descriptor instead.
+func (*MarkVerifiedRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MarkVerifiedRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *MarkVerifiedRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *MarkVerifiedRequest) GetVerifiedAt() int64 {
+	if x != nil {
+		return x.VerifiedAt
+	}
+	return 0
+}
+
+type MarkVerifiedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkVerifiedResponse) Reset() {
+	*x = MarkVerifiedResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkVerifiedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkVerifiedResponse) ProtoMessage() {}
+
+func (x *MarkVerifiedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkVerifiedResponse.ProtoReflect.Descriptor instead.
+func (*MarkVerifiedResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *MarkVerifiedResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *MarkVerifiedResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type AddRefsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hashes        []string               `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`                           // empty pins the blocks so they are never collected
+	Tenant        string                 `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`                         // tenant the references are held for; empty for untenanted callers
+	CreatedAt     int64                  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix seconds; 0 is stamped with the time the index applies the call
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRefsRequest) Reset() {
+	*x = AddRefsRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRefsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRefsRequest) ProtoMessage() {}
+
+func (x *AddRefsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRefsRequest.ProtoReflect.Descriptor instead.
+func (*AddRefsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *AddRefsRequest) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+func (x *AddRefsRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *AddRefsRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *AddRefsRequest) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type AddRefsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         []string               `protobuf:"bytes,1,rep,name=found,proto3" json:"found,omitempty"`       // live blocks now referenced by owner
+	Deleting      []string               `protobuf:"bytes,2,rep,name=deleting,proto3" json:"deleting,omitempty"` // blocks being garbage collected, not referenced
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRefsResponse) Reset() {
+	*x = AddRefsResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRefsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRefsResponse) ProtoMessage() {}
+
+func (x *AddRefsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRefsResponse.ProtoReflect.Descriptor instead.
+func (*AddRefsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *AddRefsResponse) GetFound() []string {
+	if x != nil {
+		return x.Found
+	}
+	return nil
+}
+
+func (x *AddRefsResponse) GetDeleting() []string {
+	if x != nil {
+		return x.Deleting
+	}
+	return nil
+}
+
+func (x *AddRefsResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ReleaseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`                              // must be set; pins cannot be released
+	Tenant        string                 `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`                            // tenant the reference is held for
+	ReleasedAt    int64                  `protobuf:"varint,4,opt,name=released_at,json=releasedAt,proto3" json:"released_at,omitempty"` // unix seconds; 0 is stamped with the time the index applies the call
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseRequest) Reset() {
+	*x = ReleaseRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseRequest) ProtoMessage() {}
+
+func (x *ReleaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ReleaseRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ReleaseRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ReleaseRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *ReleaseRequest) GetReleasedAt() int64 {
+	if x != nil {
+		return x.ReleasedAt
+	}
+	return 0
+}
+
+type ReleaseResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Released      bool                   `protobuf:"varint,1,opt,name=released,proto3" json:"released,omitempty"`   // owner held a reference that was removed
+	Remaining     int64                  `protobuf:"varint,2,opt,name=remaining,proto3" json:"remaining,omitempty"` // references left on the block
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseResponse) Reset() {
+	*x = ReleaseResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseResponse) ProtoMessage() {}
+
+func (x *ReleaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ReleaseResponse) GetReleased() bool {
+	if x != nil {
+		return x.Released
+	}
+	return false
+}
+
+func (x *ReleaseResponse) GetRemaining() int64 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *ReleaseResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ReferencedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hashes        []string               `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	Tenant        string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReferencedRequest) Reset() {
+	*x = ReferencedRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReferencedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReferencedRequest) ProtoMessage() {}
+
+func (x *ReferencedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReferencedRequest.ProtoReflect.Descriptor instead.
+func (*ReferencedRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ReferencedRequest) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+func (x *ReferencedRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type ReferencedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Referenced    []string               `protobuf:"bytes,1,rep,name=referenced,proto3" json:"referenced,omitempty"` // subset of the requested hashes the tenant holds a reference to
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReferencedResponse) Reset() {
+	*x = ReferencedResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReferencedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReferencedResponse) ProtoMessage() {}
+
+func (x *ReferencedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReferencedResponse.ProtoReflect.Descriptor instead.
+func (*ReferencedResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReferencedResponse) GetReferenced() []string {
+	if x != nil {
+		return x.Referenced
+	}
+	return nil
+}
+
+func (x *ReferencedResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageRequest) Reset() {
+	*x = GetUsageRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageRequest) ProtoMessage() {}
+
+func (x *GetUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageRequest.ProtoReflect.Descriptor instead.
+func (*GetUsageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetUsageRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type GetUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bytes         int64                  `protobuf:"varint,1,opt,name=bytes,proto3" json:"bytes,omitempty"` // size of the blocks the tenant references
+	Blocks        int64                  `protobuf:"varint,2,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageResponse) Reset() {
+	*x = GetUsageResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageResponse) ProtoMessage() {}
+
+func (x *GetUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetUsageResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetUsageResponse) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *GetUsageResponse) GetBlocks() int64 {
+	if x != nil {
+		return x.Blocks
+	}
+	return 0
+}
+
+func (x *GetUsageResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListCollectableRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	UnreferencedBefore int64                  `protobuf:"varint,1,opt,name=unreferenced_before,json=unreferencedBefore,proto3" json:"unreferenced_before,omitempty"` // unix seconds
+	Limit              int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ListCollectableRequest) Reset() {
+	*x = ListCollectableRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCollectableRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCollectableRequest) ProtoMessage() {}
+
+func (x *ListCollectableRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCollectableRequest.ProtoReflect.Descriptor instead.
+func (*ListCollectableRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ListCollectableRequest) GetUnreferencedBefore() int64 {
+	if x != nil {
+		return x.UnreferencedBefore
+	}
+	return 0
+}
+
+func (x *ListCollectableRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListDeletingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDeletingRequest) Reset() {
+	*x = ListDeletingRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDeletingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDeletingRequest) ProtoMessage() {}
+
+func (x *ListDeletingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDeletingRequest.ProtoReflect.Descriptor instead.
+func (*ListDeletingRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ListDeletingRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListEntriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEntriesResponse) Reset() {
+	*x = ListEntriesResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEntriesResponse) ProtoMessage() {}
+
+func (x *ListEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEntriesResponse.ProtoReflect.Descriptor instead.
+func (*ListEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListEntriesResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListEntriesResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type MarkDeletingRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Hash               string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	UnreferencedBefore int64                  `protobuf:"varint,2,opt,name=unreferenced_before,json=unreferencedBefore,proto3" json:"unreferenced_before,omitempty"` // unix seconds; the block must still have been unreferenced since before this
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *MarkDeletingRequest) Reset() {
+	*x = MarkDeletingRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkDeletingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkDeletingRequest) ProtoMessage() {}
+
+func (x *MarkDeletingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkDeletingRequest.ProtoReflect.Descriptor instead.
+func (*MarkDeletingRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *MarkDeletingRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *MarkDeletingRequest) GetUnreferencedBefore() int64 {
+	if x != nil {
+		return x.UnreferencedBefore
+	}
+	return 0
+}
+
+type MarkDeletingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Marked        bool                   `protobuf:"varint,1,opt,name=marked,proto3" json:"marked,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkDeletingResponse) Reset() {
+	*x = MarkDeletingResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkDeletingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkDeletingResponse) ProtoMessage() {}
+
+func (x *MarkDeletingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkDeletingResponse.ProtoReflect.Descriptor instead.
+func (*MarkDeletingResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *MarkDeletingResponse) GetMarked() bool {
+	if x != nil {
+		return x.Marked
+	}
+	return false
+}
+
+func (x *MarkDeletingResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type RemoveEntryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveEntryRequest) Reset() {
+	*x = RemoveEntryRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveEntryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveEntryRequest) ProtoMessage() {}
+
+func (x *RemoveEntryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveEntryRequest.ProtoReflect.Descriptor instead.
+func (*RemoveEntryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RemoveEntryRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type RemoveEntryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveEntryResponse) Reset() {
+	*x = RemoveEntryResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveEntryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveEntryResponse) ProtoMessage() {}
+
+func (x *RemoveEntryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveEntryResponse.ProtoReflect.Descriptor instead.
+func (*RemoveEntryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *RemoveEntryResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RemoveEntryResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListVolumeEntriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVolumeEntriesRequest) Reset() {
+	*x = ListVolumeEntriesRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVolumeEntriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVolumeEntriesRequest) ProtoMessage() {}
+
+func (x *ListVolumeEntriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVolumeEntriesRequest.ProtoReflect.Descriptor instead.
+func (*ListVolumeEntriesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ListVolumeEntriesRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *ListVolumeEntriesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetVolumeUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CellId        string                 `protobuf:"bytes,1,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVolumeUsageRequest) Reset() {
+	*x = GetVolumeUsageRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVolumeUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVolumeUsageRequest) ProtoMessage() {}
+
+func (x *GetVolumeUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVolumeUsageRequest.ProtoReflect.Descriptor instead.
+func (*GetVolumeUsageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *GetVolumeUsageRequest) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+type VolumeUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	LiveBytes     int64                  `protobuf:"varint,2,opt,name=live_bytes,json=liveBytes,proto3" json:"live_bytes,omitempty"`
+	Blocks        int64                  `protobuf:"varint,3,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VolumeUsage) Reset() {
+	*x = VolumeUsage{}
+	mi := &file_proto_blockindex_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VolumeUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VolumeUsage) ProtoMessage() {}
+
+func (x *VolumeUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VolumeUsage.ProtoReflect.Descriptor instead.
+func (*VolumeUsage) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *VolumeUsage) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *VolumeUsage) GetLiveBytes() int64 {
+	if x != nil {
+		return x.LiveBytes
+	}
+	return 0
+}
+
+func (x *VolumeUsage) GetBlocks() int64 {
+	if x != nil {
+		return x.Blocks
+	}
+	return 0
+}
+
+type GetVolumeUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Volumes       []*VolumeUsage         `protobuf:"bytes,1,rep,name=volumes,proto3" json:"volumes,omitempty"`
+	Unlocated     int64                  `protobuf:"varint,2,opt,name=unlocated,proto3" json:"unlocated,omitempty"` // entries of the cell with no recorded volume
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVolumeUsageResponse) Reset() {
+	*x = GetVolumeUsageResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVolumeUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVolumeUsageResponse) ProtoMessage() {}
+
+func (x *GetVolumeUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVolumeUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetVolumeUsageResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *GetVolumeUsageResponse) GetVolumes() []*VolumeUsage {
+	if x != nil {
+		return x.Volumes
+	}
+	return nil
+}
+
+func (x *GetVolumeUsageResponse) GetUnlocated() int64 {
+	if x != nil {
+		return x.Unlocated
+	}
+	return 0
+}
+
+func (x *GetVolumeUsageResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Intent records a Put that is writing replicas but has not been indexed yet
+type Intent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"` // assigned by BeginPut
+	Hash          string                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	CellId        string                 `protobuf:"bytes,3,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	BucketId      string                 `protobuf:"bytes,4,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,5,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	Size          int64                  `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	Owner         string                 `protobuf:"bytes,7,opt,name=owner,proto3" json:"owner,omitempty"`                           // reference added when the intent is committed
+	State         string                 `protobuf:"bytes,8,opt,name=state,proto3" json:"state,omitempty"`                           // "pending" or "sweeping"
+	CreatedAt     int64                  `protobuf:"varint,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix seconds
+	Tenant        string                 `protobuf:"bytes,10,opt,name=tenant,proto3" json:"tenant,omitempty"`                        // tenant the committed reference is held for
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Intent) Reset() {
+	*x = Intent{}
+	mi := &file_proto_blockindex_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Intent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Intent) ProtoMessage() {}
+
+func (x *Intent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Intent.ProtoReflect.Descriptor instead.
+func (*Intent) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *Intent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Intent) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Intent) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+func (x *Intent) GetBucketId() string {
+	if x != nil {
+		return x.BucketId
+	}
+	return ""
+}
+
+func (x *Intent) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *Intent) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Intent) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Intent) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Intent) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Intent) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type BeginPutRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Intents       []*Intent              `protobuf:"bytes,1,rep,name=intents,proto3" json:"intents,omitempty"`                       // ids, states and creation times are ignored
+	CreatedAt     int64                  `protobuf:"varint,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix seconds; 0 is stamped with the time the index applies the call
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BeginPutRequest) Reset() {
+	*x = BeginPutRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeginPutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginPutRequest) ProtoMessage() {}
+
+func (x *BeginPutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginPutRequest.ProtoReflect.Descriptor instead.
+func (*BeginPutRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *BeginPutRequest) GetIntents() []*Intent {
+	if x != nil {
+		return x.Intents
+	}
+	return nil
+}
+
+func (x *BeginPutRequest) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type BeginPutResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []int64                `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"` // in request order
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BeginPutResponse) Reset() {
+	*x = BeginPutResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeginPutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginPutResponse) ProtoMessage() {}
+
+func (x *BeginPutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginPutResponse.ProtoReflect.Descriptor instead.
+func (*BeginPutResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *BeginPutResponse) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *BeginPutResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type CommitPutRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Claimed       bool                   `protobuf:"varint,2,opt,name=claimed,proto3" json:"claimed,omitempty"`                            // set by the sweeper to commit an intent it has claimed
+	Hash          string                 `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`                                   // the intent's block, which routes the call to its index shard
+	CommittedAt   int64                  `protobuf:"varint,4,opt,name=committed_at,json=committedAt,proto3" json:"committed_at,omitempty"` // unix seconds; 0 is stamped with the time the index applies the call
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitPutRequest) Reset() {
+	*x = CommitPutRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitPutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitPutRequest) ProtoMessage() {}
+
+func (x *CommitPutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitPutRequest.ProtoReflect.Descriptor instead.
+func (*CommitPutRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *CommitPutRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *CommitPutRequest) GetClaimed() bool {
+	if x != nil {
+		return x.Claimed
+	}
+	return false
+}
+
+func (x *CommitPutRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *CommitPutRequest) GetCommittedAt() int64 {
+	if x != nil {
+		return x.CommittedAt
+	}
+	return 0
+}
+
+type CommitPutResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"` // the block is indexed and referenced by the intent's owner
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Deleting      bool                   `protobuf:"varint,3,opt,name=deleting,proto3" json:"deleting,omitempty"`   // the block is being garbage collected; nothing was committed
+	Duplicate     bool                   `protobuf:"varint,4,opt,name=duplicate,proto3" json:"duplicate,omitempty"` // the block was already indexed elsewhere, so the intent's replicas are surplus
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitPutResponse) Reset() {
+	*x = CommitPutResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitPutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitPutResponse) ProtoMessage() {}
+
+func (x *CommitPutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitPutResponse.ProtoReflect.Descriptor instead.
+func (*CommitPutResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *CommitPutResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CommitPutResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CommitPutResponse) GetDeleting() bool {
+	if x != nil {
+		return x.Deleting
+	}
+	return false
+}
+
+func (x *CommitPutResponse) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
+
+type AbortPutRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hash          string                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"` // the intent's block, which routes the call to its index shard
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AbortPutRequest) Reset() {
+	*x = AbortPutRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AbortPutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AbortPutRequest) ProtoMessage() {}
+
+func (x *AbortPutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AbortPutRequest.ProtoReflect.Descriptor instead.
+func (*AbortPutRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *AbortPutRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AbortPutRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type AbortPutResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Indexed       bool                   `protobuf:"varint,3,opt,name=indexed,proto3" json:"indexed,omitempty"` // the block is indexed in the intent's bucket, whose replicas must be kept
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AbortPutResponse) Reset() {
+	*x = AbortPutResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AbortPutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AbortPutResponse) ProtoMessage() {}
+
+func (x *AbortPutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AbortPutResponse.ProtoReflect.Descriptor instead.
+func (*AbortPutResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *AbortPutResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AbortPutResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *AbortPutResponse) GetIndexed() bool {
+	if x != nil {
+		return x.Indexed
+	}
+	return false
+}
+
+type ListIntentsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CreatedBefore int64                  `protobuf:"varint,1,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"` // unix seconds; claimed intents are listed regardless
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListIntentsRequest) Reset() {
+	*x = ListIntentsRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIntentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIntentsRequest) ProtoMessage() {}
+
+func (x *ListIntentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIntentsRequest.ProtoReflect.Descriptor instead.
+func (*ListIntentsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ListIntentsRequest) GetCreatedBefore() int64 {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return 0
+}
+
+func (x *ListIntentsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListIntentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Intents       []*Intent              `protobuf:"bytes,1,rep,name=intents,proto3" json:"intents,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListIntentsResponse) Reset() {
+	*x = ListIntentsResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIntentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIntentsResponse) ProtoMessage() {}
+
+func (x *ListIntentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIntentsResponse.ProtoReflect.Descriptor instead.
+func (*ListIntentsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *ListIntentsResponse) GetIntents() []*Intent {
+	if x != nil {
+		return x.Intents
+	}
+	return nil
+}
+
+func (x *ListIntentsResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ClaimIntentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	CreatedBefore int64                  `protobuf:"varint,2,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"` // unix seconds; pending intents must be older than this
+	Hash          string                 `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`                                         // the intent's block, which routes the call to its index shard
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClaimIntentRequest) Reset() {
+	*x = ClaimIntentRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClaimIntentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimIntentRequest) ProtoMessage() {}
+
+func (x *ClaimIntentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimIntentRequest.ProtoReflect.Descriptor instead.
+func (*ClaimIntentRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *ClaimIntentRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ClaimIntentRequest) GetCreatedBefore() int64 {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return 0
+}
+
+func (x *ClaimIntentRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type ClaimIntentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Claimed       bool                   `protobuf:"varint,1,opt,name=claimed,proto3" json:"claimed,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Indexed       bool                   `protobuf:"varint,3,opt,name=indexed,proto3" json:"indexed,omitempty"` // the block is indexed in the intent's bucket, whose replicas must be kept
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClaimIntentResponse) Reset() {
+	*x = ClaimIntentResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClaimIntentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimIntentResponse) ProtoMessage() {}
+
+func (x *ClaimIntentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimIntentResponse.ProtoReflect.Descriptor instead.
+func (*ClaimIntentResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *ClaimIntentResponse) GetClaimed() bool {
+	if x != nil {
+		return x.Claimed
+	}
+	return false
+}
+
+func (x *ClaimIntentResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ClaimIntentResponse) GetIndexed() bool {
+	if x != nil {
+		return x.Indexed
+	}
+	return false
+}
+
+// MigrationRequest names a range of hashes moving between index nodes
+type MigrationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         string                 `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"` // inclusive
+	End           string                 `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`     // exclusive; empty for the end of the hash space
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigrationRequest) Reset() {
+	*x = MigrationRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigrationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigrationRequest) ProtoMessage() {}
+
+func (x *MigrationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigrationRequest.ProtoReflect.Descriptor instead.
+func (*MigrationRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *MigrationRequest) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *MigrationRequest) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+type MigrationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigrationResponse) Reset() {
+	*x = MigrationResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigrationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigrationResponse) ProtoMessage() {}
+
+func (x *MigrationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigrationResponse.ProtoReflect.Descriptor instead.
+func (*MigrationResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *MigrationResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *MigrationResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Ref is a reference to a block held by a tenant's owner
+type Ref struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ref) Reset() {
+	*x = Ref{}
+	mi := &file_proto_blockindex_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ref) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ref) ProtoMessage() {}
+
+func (x *Ref) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ref.ProtoReflect.Descriptor instead.
+func (*Ref) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *Ref) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *Ref) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Ref) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+// BlockRecord is everything the index stores about one block
+type BlockRecord struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Entry          *Entry                 `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	State          string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	CreatedAt      int64                  `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix seconds
+	VerifiedAt     int64                  `protobuf:"varint,4,opt,name=verified_at,json=verifiedAt,proto3" json:"verified_at,omitempty"`
+	UnreferencedAt int64                  `protobuf:"varint,5,opt,name=unreferenced_at,json=unreferencedAt,proto3" json:"unreferenced_at,omitempty"`
+	Refs           []*Ref                 `protobuf:"bytes,6,rep,name=refs,proto3" json:"refs,omitempty"`
+	Missing        bool                   `protobuf:"varint,7,opt,name=missing,proto3" json:"missing,omitempty"` // the block is not indexed; importing removes it
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BlockRecord) Reset() {
+	*x = BlockRecord{}
+	mi := &file_proto_blockindex_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockRecord) ProtoMessage() {}
+
+func (x *BlockRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockRecord.ProtoReflect.Descriptor instead.
+func (*BlockRecord) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *BlockRecord) GetEntry() *Entry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+func (x *BlockRecord) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *BlockRecord) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *BlockRecord) GetVerifiedAt() int64 {
+	if x != nil {
+		return x.VerifiedAt
+	}
+	return 0
+}
+
+func (x *BlockRecord) GetUnreferencedAt() int64 {
+	if x != nil {
+		return x.UnreferencedAt
+	}
+	return 0
+}
+
+func (x *BlockRecord) GetRefs() []*Ref {
+	if x != nil {
+		return x.Refs
+	}
+	return nil
+}
+
+func (x *BlockRecord) GetMissing() bool {
+	if x != nil {
+		return x.Missing
+	}
+	return false
+}
+
+type ExportRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         string                 `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           string                 `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	After         string                 `protobuf:"bytes,3,opt,name=after,proto3" json:"after,omitempty"` // resume after this hash
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRangeRequest) Reset() {
+	*x = ExportRangeRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRangeRequest) ProtoMessage() {}
+
+func (x *ExportRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRangeRequest.ProtoReflect.Descriptor instead.
+func (*ExportRangeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *ExportRangeRequest) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *ExportRangeRequest) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+func (x *ExportRangeRequest) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+func (x *ExportRangeRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ExportBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hashes        []string               `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportBlocksRequest) Reset() {
+	*x = ExportBlocksRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportBlocksRequest) ProtoMessage() {}
+
+func (x *ExportBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportBlocksRequest.ProtoReflect.Descriptor instead.
+func (*ExportBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *ExportBlocksRequest) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+type ExportResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Records       []*BlockRecord         `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"` // sorted by hash
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportResponse) Reset() {
+	*x = ExportResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportResponse) ProtoMessage() {}
+
+func (x *ExportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportResponse.ProtoReflect.Descriptor instead.
+func (*ExportResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *ExportResponse) GetRecords() []*BlockRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+func (x *ExportResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ImportBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Records       []*BlockRecord         `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	ReplaceRange  bool                   `protobuf:"varint,2,opt,name=replace_range,json=replaceRange,proto3" json:"replace_range,omitempty"` // the records are every block from start to end; others there are removed
+	Start         string                 `protobuf:"bytes,3,opt,name=start,proto3" json:"start,omitempty"`
+	End           string                 `protobuf:"bytes,4,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportBlocksRequest) Reset() {
+	*x = ImportBlocksRequest{}
+	mi := &file_proto_blockindex_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportBlocksRequest) ProtoMessage() {}
+
+func (x *ImportBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportBlocksRequest.ProtoReflect.Descriptor instead.
+func (*ImportBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *ImportBlocksRequest) GetRecords() []*BlockRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+func (x *ImportBlocksRequest) GetReplaceRange() bool {
+	if x != nil {
+		return x.ReplaceRange
+	}
+	return false
+}
+
+func (x *ImportBlocksRequest) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *ImportBlocksRequest) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+type ImportBlocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportBlocksResponse) Reset() {
+	*x = ImportBlocksResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportBlocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportBlocksResponse) ProtoMessage() {}
+
+func (x *ImportBlocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportBlocksResponse.ProtoReflect.Descriptor instead.
+func (*ImportBlocksResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *ImportBlocksResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ImportBlocksResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type TakeChangesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hashes        []string               `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"` // blocks of the range written since the last call
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TakeChangesResponse) Reset() {
+	*x = TakeChangesResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TakeChangesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TakeChangesResponse) ProtoMessage() {}
+
+func (x *TakeChangesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TakeChangesResponse.ProtoReflect.Descriptor instead.
+func (*TakeChangesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *TakeChangesResponse) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+func (x *TakeChangesResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type FreezeRangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Intents       int64                  `protobuf:"varint,1,opt,name=intents,proto3" json:"intents,omitempty"` // uncommitted Puts left in the range
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FreezeRangeResponse) Reset() {
+	*x = FreezeRangeResponse{}
+	mi := &file_proto_blockindex_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FreezeRangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FreezeRangeResponse) ProtoMessage() {}
+
+func (x *FreezeRangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_blockindex_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FreezeRangeResponse.ProtoReflect.Descriptor instead.
+func (*FreezeRangeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_blockindex_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *FreezeRangeResponse) GetIntents() int64 {
+	if x != nil {
+		return x.Intents
+	}
+	return 0
+}
+
+func (x *FreezeRangeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_proto_blockindex_proto protoreflect.FileDescriptor
+
+const file_proto_blockindex_proto_rawDesc = "" +
+	"\n" +
+	"\x16proto/blockindex.proto\x12\n" +
+	"blockindex\"\xc7\x01\n" +
+	"\x0fPutEntryRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x17\n" +
+	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12\x1b\n" +
+	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12\x1a\n" +
+	"\bchecksum\x18\x04 \x01(\tR\bchecksum\x12\x1b\n" +
+	"\tvolume_id\x18\x05 \x01(\tR\bvolumeId\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\a \x01(\x03R\tcreatedAt\"\x8d\x01\n" +
+	"\x10PutEntryResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1a\n" +
+	"\bdeleting\x18\x03 \x01(\bR\bdeleting\x12-\n" +
+	"\bexisting\x18\x04 \x01(\v2\x11.blockindex.EntryR\bexisting\"\xb1\x02\n" +
+	"\x12UpdateEntryRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x17\n" +
+	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12\x1b\n" +
+	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12\x1a\n" +
+	"\bchecksum\x18\x04 \x01(\tR\bchecksum\x12\x1b\n" +
+	"\tvolume_id\x18\x05 \x01(\tR\bvolumeId\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\x12(\n" +
+	"\x10expected_cell_id\x18\a \x01(\tR\x0eexpectedCellId\x12,\n" +
+	"\x12expected_bucket_id\x18\b \x01(\tR\x10expectedBucketId\x12,\n" +
+	"\x12expected_volume_id\x18\t \x01(\tR\x10expectedVolumeId\"\xaa\x01\n" +
+	"\x13UpdateEntryResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1a\n" +
+	"\bdeleting\x18\x03 \x01(\bR\bdeleting\x12\x1a\n" +
+	"\bconflict\x18\x04 \x01(\bR\bconflict\x12+\n" +
+	"\acurrent\x18\x05 \x01(\v2\x11.blockindex.EntryR\acurrent\"%\n" +
+	"\x0fGetEntryRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\"\x81\x02\n" +
+	"\x10GetEntryResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x17\n" +
+	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12\x1b\n" +
+	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12\x1a\n" +
+	"\bchecksum\x18\x04 \x01(\tR\bchecksum\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x1b\n" +
+	"\tvolume_id\x18\x06 \x01(\tR\bvolumeId\x12\x12\n" +
+	"\x04size\x18\a \x01(\x03R\x04size\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\b \x01(\x03R\tcreatedAt\x12\x1f\n" +
+	"\vverified_at\x18\t \x01(\x03R\n" +
+	"verifiedAt\"#\n" +
+	"\rExistsRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\"(\n" +
+	"\x0eExistsResponse\x12\x16\n" +
+	"\x06exists\x18\x01 \x01(\bR\x06exists\",\n" +
+	"\x12ExistsBatchRequest\x12\x16\n" +
+	"\x06hashes\x18\x01 \x03(\tR\x06hashes\"G\n" +
+	"\x13ExistsBatchResponse\x12\x1a\n" +
+	"\bexisting\x18\x01 \x03(\tR\bexisting\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x9e\x01\n" +
+	"\x05Entry\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x17\n" +
+	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12\x1b\n" +
+	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12\x1a\n" +
+	"\bchecksum\x18\x04 \x01(\tR\bchecksum\x12\x1b\n" +
+	"\tvolume_id\x18\x05 \x01(\tR\bvolumeId\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\"+\n" +
+	"\x11GetEntriesRequest\x12\x16\n" +
+	"\x06hashes\x18\x01 \x03(\tR\x06hashes\"W\n" +
+	"\x12GetEntriesResponse\x12+\n" +
+	"\aentries\x18\x01 \x03(\v2\x11.blockindex.EntryR\aentries\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"^\n" +
+	"\x13MarkVerifiedRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x1f\n" +
+	"\vverified_at\x18\x03 \x01(\x03R\n" +
+	"verifiedAt\"F\n" +
+	"\x14MarkVerifiedResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"u\n" +
+	"\x0eAddRefsRequest\x12\x16\n" +
+	"\x06hashes\x18\x01 \x03(\tR\x06hashes\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x16\n" +
+	"\x06tenant\x18\x03 \x01(\tR\x06tenant\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\"Y\n" +
+	"\x0fAddRefsResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x03(\tR\x05found\x12\x1a\n" +
+	"\bdeleting\x18\x02 \x03(\tR\bdeleting\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"s\n" +
+	"\x0eReleaseRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x16\n" +
+	"\x06tenant\x18\x03 \x01(\tR\x06tenant\x12\x1f\n" +
+	"\vreleased_at\x18\x04 \x01(\x03R\n" +
+	"releasedAt\"a\n" +
+	"\x0fReleaseResponse\x12\x1a\n" +
+	"\breleased\x18\x01 \x01(\bR\breleased\x12\x1c\n" +
+	"\tremaining\x18\x02 \x01(\x03R\tremaining\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"C\n" +
+	"\x11ReferencedRequest\x12\x16\n" +
+	"\x06hashes\x18\x01 \x03(\tR\x06hashes\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\"J\n" +
+	"\x12ReferencedResponse\x12\x1e\n" +
+	"\n" +
+	"referenced\x18\x01 \x03(\tR\n" +
+	"referenced\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\")\n" +
+	"\x0fGetUsageRequest\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\"V\n" +
+	"\x10GetUsageResponse\x12\x14\n" +
+	"\x05bytes\x18\x01 \x01(\x03R\x05bytes\x12\x16\n" +
+	"\x06blocks\x18\x02 \x01(\x03R\x06blocks\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"_\n" +
+	"\x16ListCollectableRequest\x12/\n" +
+	"\x13unreferenced_before\x18\x01 \x01(\x03R\x12unreferencedBefore\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"+\n" +
+	"\x13ListDeletingRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"X\n" +
+	"\x13ListEntriesResponse\x12+\n" +
+	"\aentries\x18\x01 \x03(\v2\x11.blockindex.EntryR\aentries\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"Z\n" +
+	"\x13MarkDeletingRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12/\n" +
+	"\x13unreferenced_before\x18\x02 \x01(\x03R\x12unreferencedBefore\"D\n" +
+	"\x14MarkDeletingResponse\x12\x16\n" +
+	"\x06marked\x18\x01 \x01(\bR\x06marked\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"(\n" +
+	"\x12RemoveEntryRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\"E\n" +
+	"\x13RemoveEntryResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"M\n" +
+	"\x18ListVolumeEntriesRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"0\n" +
+	"\x15GetVolumeUsageRequest\x12\x17\n" +
+	"\acell_id\x18\x01 \x01(\tR\x06cellId\"a\n" +
+	"\vVolumeUsage\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12\x1d\n" +
+	"\n" +
+	"live_bytes\x18\x02 \x01(\x03R\tliveBytes\x12\x16\n" +
+	"\x06blocks\x18\x03 \x01(\x03R\x06blocks\"\x7f\n" +
+	"\x16GetVolumeUsageResponse\x121\n" +
+	"\avolumes\x18\x01 \x03(\v2\x17.blockindex.VolumeUsageR\avolumes\x12\x1c\n" +
+	"\tunlocated\x18\x02 \x01(\x03R\tunlocated\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xf6\x01\n" +
+	"\x06Intent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\tR\x04hash\x12\x17\n" +
+	"\acell_id\x18\x03 \x01(\tR\x06cellId\x12\x1b\n" +
+	"\tbucket_id\x18\x04 \x01(\tR\bbucketId\x12\x1b\n" +
+	"\tvolume_id\x18\x05 \x01(\tR\bvolumeId\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\x12\x14\n" +
+	"\x05owner\x18\a \x01(\tR\x05owner\x12\x14\n" +
+	"\x05state\x18\b \x01(\tR\x05state\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\t \x01(\x03R\tcreatedAt\x12\x16\n" +
+	"\x06tenant\x18\n" +
+	" \x01(\tR\x06tenant\"^\n" +
+	"\x0fBeginPutRequest\x12,\n" +
+	"\aintents\x18\x01 \x03(\v2\x12.blockindex.IntentR\aintents\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x02 \x01(\x03R\tcreatedAt\":\n" +
+	"\x10BeginPutResponse\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x03R\x03ids\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"s\n" +
+	"\x10CommitPutRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\aclaimed\x18\x02 \x01(\bR\aclaimed\x12\x12\n" +
+	"\x04hash\x18\x03 \x01(\tR\x04hash\x12!\n" +
+	"\fcommitted_at\x18\x04 \x01(\x03R\vcommittedAt\"}\n" +
+	"\x11CommitPutResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1a\n" +
+	"\bdeleting\x18\x03 \x01(\bR\bdeleting\x12\x1c\n" +
+	"\tduplicate\x18\x04 \x01(\bR\tduplicate\"5\n" +
+	"\x0fAbortPutRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\tR\x04hash\"\\\n" +
+	"\x10AbortPutResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x18\n" +
+	"\aindexed\x18\x03 \x01(\bR\aindexed\"Q\n" +
+	"\x12ListIntentsRequest\x12%\n" +
+	"\x0ecreated_before\x18\x01 \x01(\x03R\rcreatedBefore\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"Y\n" +
+	"\x13ListIntentsResponse\x12,\n" +
+	"\aintents\x18\x01 \x03(\v2\x12.blockindex.IntentR\aintents\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"_\n" +
+	"\x12ClaimIntentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12%\n" +
+	"\x0ecreated_before\x18\x02 \x01(\x03R\rcreatedBefore\x12\x12\n" +
+	"\x04hash\x18\x03 \x01(\tR\x04hash\"_\n" +
+	"\x13ClaimIntentResponse\x12\x18\n" +
+	"\aclaimed\x18\x01 \x01(\bR\aclaimed\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x18\n" +
+	"\aindexed\x18\x03 \x01(\bR\aindexed\":\n" +
+	"\x10MigrationRequest\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\tR\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\tR\x03end\"C\n" +
+	"\x11MigrationResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"R\n" +
+	"\x03Ref\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\x03R\tcreatedAt\"\xf4\x01\n" +
+	"\vBlockRecord\x12'\n" +
+	"\x05entry\x18\x01 \x01(\v2\x11.blockindex.EntryR\x05entry\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\x03R\tcreatedAt\x12\x1f\n" +
+	"\vverified_at\x18\x04 \x01(\x03R\n" +
+	"verifiedAt\x12'\n" +
+	"\x0funreferenced_at\x18\x05 \x01(\x03R\x0eunreferencedAt\x12#\n" +
+	"\x04refs\x18\x06 \x03(\v2\x0f.blockindex.RefR\x04refs\x12\x18\n" +
+	"\amissing\x18\a \x01(\bR\amissing\"h\n" +
+	"\x12ExportRangeRequest\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\tR\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\tR\x03end\x12\x14\n" +
+	"\x05after\x18\x03 \x01(\tR\x05after\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"-\n" +
+	"\x13ExportBlocksRequest\x12\x16\n" +
+	"\x06hashes\x18\x01 \x03(\tR\x06hashes\"Y\n" +
+	"\x0eExportResponse\x121\n" +
+	"\arecords\x18\x01 \x03(\v2\x17.blockindex.BlockRecordR\arecords\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x95\x01\n" +
+	"\x13ImportBlocksRequest\x121\n" +
+	"\arecords\x18\x01 \x03(\v2\x17.blockindex.BlockRecordR\arecords\x12#\n" +
+	"\rreplace_range\x18\x02 \x01(\bR\freplaceRange\x12\x14\n" +
+	"\x05start\x18\x03 \x01(\tR\x05start\x12\x10\n" +
+	"\x03end\x18\x04 \x01(\tR\x03end\"F\n" +
+	"\x14ImportBlocksResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"C\n" +
+	"\x13TakeChangesResponse\x12\x16\n" +
+	"\x06hashes\x18\x01 \x03(\tR\x06hashes\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"E\n" +
+	"\x13FreezeRangeResponse\x12\x18\n" +
+	"\aintents\x18\x01 \x01(\x03R\aintents\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error2\xdb\x11\n" +
+	"\x11BlockIndexService\x12E\n" +
+	"\bPutEntry\x12\x1b.blockindex.PutEntryRequest\x1a\x1c.blockindex.PutEntryResponse\x12N\n" +
+	"\vUpdateEntry\x12\x1e.blockindex.UpdateEntryRequest\x1a\x1f.blockindex.UpdateEntryResponse\x12E\n" +
+	"\bGetEntry\x12\x1b.blockindex.GetEntryRequest\x1a\x1c.blockindex.GetEntryResponse\x12?\n" +
+	"\x06Exists\x12\x19.blockindex.ExistsRequest\x1a\x1a.blockindex.ExistsResponse\x12N\n" +
+	"\vExistsBatch\x12\x1e.blockindex.ExistsBatchRequest\x1a\x1f.blockindex.ExistsBatchResponse\x12K\n" +
+	"\n" +
+	"GetEntries\x12\x1d.blockindex.GetEntriesRequest\x1a\x1e.blockindex.GetEntriesResponse\x12Q\n" +
+	"\fMarkVerified\x12\x1f.blockindex.MarkVerifiedRequest\x1a .blockindex.MarkVerifiedResponse\x12B\n" +
+	"\aAddRefs\x12\x1a.blockindex.AddRefsRequest\x1a\x1b.blockindex.AddRefsResponse\x12B\n" +
+	"\aRelease\x12\x1a.blockindex.ReleaseRequest\x1a\x1b.blockindex.ReleaseResponse\x12K\n" +
+	"\n" +
+	"Referenced\x12\x1d.blockindex.ReferencedRequest\x1a\x1e.blockindex.ReferencedResponse\x12E\n" +
+	"\bGetUsage\x12\x1b.blockindex.GetUsageRequest\x1a\x1c.blockindex.GetUsageResponse\x12V\n" +
+	"\x0fListCollectable\x12\".blockindex.ListCollectableRequest\x1a\x1f.blockindex.ListEntriesResponse\x12Q\n" +
+	"\fMarkDeleting\x12\x1f.blockindex.MarkDeletingRequest\x1a .blockindex.MarkDeletingResponse\x12P\n" +
+	"\fListDeleting\x12\x1f.blockindex.ListDeletingRequest\x1a\x1f.blockindex.ListEntriesResponse\x12N\n" +
+	"\vRemoveEntry\x12\x1e.blockindex.RemoveEntryRequest\x1a\x1f.blockindex.RemoveEntryResponse\x12Z\n" +
+	"\x11ListVolumeEntries\x12$.blockindex.ListVolumeEntriesRequest\x1a\x1f.blockindex.ListEntriesResponse\x12W\n" +
+	"\x0eGetVolumeUsage\x12!.blockindex.GetVolumeUsageRequest\x1a\".blockindex.GetVolumeUsageResponse\x12E\n" +
+	"\bBeginPut\x12\x1b.blockindex.BeginPutRequest\x1a\x1c.blockindex.BeginPutResponse\x12H\n" +
+	"\tCommitPut\x12\x1c.blockindex.CommitPutRequest\x1a\x1d.blockindex.CommitPutResponse\x12E\n" +
+	"\bAbortPut\x12\x1b.blockindex.AbortPutRequest\x1a\x1c.blockindex.AbortPutResponse\x12N\n" +
+	"\vListIntents\x12\x1e.blockindex.ListIntentsRequest\x1a\x1f.blockindex.ListIntentsResponse\x12N\n" +
+	"\vClaimIntent\x12\x1e.blockindex.ClaimIntentRequest\x1a\x1f.blockindex.ClaimIntentResponse\x12M\n" +
+	"\x0eBeginMigration\x12\x1c.blockindex.MigrationRequest\x1a\x1d.blockindex.MigrationResponse\x12I\n" +
+	"\vExportRange\x12\x1e.blockindex.ExportRangeRequest\x1a\x1a.blockindex.ExportResponse\x12K\n" +
+	"\fExportBlocks\x12\x1f.blockindex.ExportBlocksRequest\x1a\x1a.blockindex.ExportResponse\x12Q\n" +
+	"\fImportBlocks\x12\x1f.blockindex.ImportBlocksRequest\x1a .blockindex.ImportBlocksResponse\x12L\n" +
+	"\vTakeChanges\x12\x1c.blockindex.MigrationRequest\x1a\x1f.blockindex.TakeChangesResponse\x12L\n" +
+	"\vFreezeRange\x12\x1c.blockindex.MigrationRequest\x1a\x1f.blockindex.FreezeRangeResponse\x12K\n" +
+	"\fEndMigration\x12\x1c.blockindex.MigrationRequest\x1a\x1d.blockindex.MigrationResponseB\x1aZ\x18bharani/proto/blockindexb\x06proto3"
 
 var (
 	file_proto_blockindex_proto_rawDescOnce sync.Once
@@ -392,27 +3651,140 @@ func file_proto_blockindex_proto_rawDescGZIP() []byte {
 	return file_proto_blockindex_proto_rawDescData
 }
 
-var file_proto_blockindex_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_blockindex_proto_msgTypes = make([]protoimpl.MessageInfo, 56)
 var file_proto_blockindex_proto_goTypes = []any{
-	(*PutEntryRequest)(nil),  // 0: blockindex.PutEntryRequest
-	(*PutEntryResponse)(nil), // 1: blockindex.PutEntryResponse
-	(*GetEntryRequest)(nil),  // 2: blockindex.GetEntryRequest
-	(*GetEntryResponse)(nil), // 3: blockindex.GetEntryResponse
-	(*ExistsRequest)(nil),    // 4: blockindex.ExistsRequest
-	(*ExistsResponse)(nil),   // 5: blockindex.ExistsResponse
+	(*PutEntryRequest)(nil),          // 0: blockindex.PutEntryRequest
+	(*PutEntryResponse)(nil),         // 1: blockindex.PutEntryResponse
+	(*UpdateEntryRequest)(nil),       // 2: blockindex.UpdateEntryRequest
+	(*UpdateEntryResponse)(nil),      // 3: blockindex.UpdateEntryResponse
+	(*GetEntryRequest)(nil),          // 4: blockindex.GetEntryRequest
+	(*GetEntryResponse)(nil),         // 5: blockindex.GetEntryResponse
+	(*ExistsRequest)(nil),            // 6: blockindex.ExistsRequest
+	(*ExistsResponse)(nil),           // 7: blockindex.ExistsResponse
+	(*ExistsBatchRequest)(nil),       // 8: blockindex.ExistsBatchRequest
+	(*ExistsBatchResponse)(nil),      // 9: blockindex.ExistsBatchResponse
+	(*Entry)(nil),                    // 10: blockindex.Entry
+	(*GetEntriesRequest)(nil),        // 11: blockindex.GetEntriesRequest
+	(*GetEntriesResponse)(nil),       // 12: blockindex.GetEntriesResponse
+	(*MarkVerifiedRequest)(nil),      // 13: blockindex.MarkVerifiedRequest
+	(*MarkVerifiedResponse)(nil),     // 14: blockindex.MarkVerifiedResponse
+	(*AddRefsRequest)(nil),           // 15: blockindex.AddRefsRequest
+	(*AddRefsResponse)(nil),          // 16: blockindex.AddRefsResponse
+	(*ReleaseRequest)(nil),           // 17: blockindex.ReleaseRequest
+	(*ReleaseResponse)(nil),          // 18: blockindex.ReleaseResponse
+	(*ReferencedRequest)(nil),        // 19: blockindex.ReferencedRequest
+	(*ReferencedResponse)(nil),       // 20: blockindex.ReferencedResponse
+	(*GetUsageRequest)(nil),          // 21: blockindex.GetUsageRequest
+	(*GetUsageResponse)(nil),         // 22: blockindex.GetUsageResponse
+	(*ListCollectableRequest)(nil),   // 23: blockindex.ListCollectableRequest
+	(*ListDeletingRequest)(nil),      // 24: blockindex.ListDeletingRequest
+	(*ListEntriesResponse)(nil),      // 25: blockindex.ListEntriesResponse
+	(*MarkDeletingRequest)(nil),      // 26: blockindex.MarkDeletingRequest
+	(*MarkDeletingResponse)(nil),     // 27: blockindex.MarkDeletingResponse
+	(*RemoveEntryRequest)(nil),       // 28: blockindex.RemoveEntryRequest
+	(*RemoveEntryResponse)(nil),      // 29: blockindex.RemoveEntryResponse
+	(*ListVolumeEntriesRequest)(nil), // 30: blockindex.ListVolumeEntriesRequest
+	(*GetVolumeUsageRequest)(nil),    // 31: blockindex.GetVolumeUsageRequest
+	(*VolumeUsage)(nil),              // 32: blockindex.VolumeUsage
+	(*GetVolumeUsageResponse)(nil),   // 33: blockindex.GetVolumeUsageResponse
+	(*Intent)(nil),                   // 34: blockindex.Intent
+	(*BeginPutRequest)(nil),          // 35: blockindex.BeginPutRequest
+	(*BeginPutResponse)(nil),         // 36: blockindex.BeginPutResponse
+	(*CommitPutRequest)(nil),         // 37: blockindex.CommitPutRequest
+	(*CommitPutResponse)(nil),        // 38: blockindex.CommitPutResponse
+	(*AbortPutRequest)(nil),          // 39: blockindex.AbortPutRequest
+	(*AbortPutResponse)(nil),         // 40: blockindex.AbortPutResponse
+	(*ListIntentsRequest)(nil),       // 41: blockindex.ListIntentsRequest
+	(*ListIntentsResponse)(nil),      // 42: blockindex.ListIntentsResponse
+	(*ClaimIntentRequest)(nil),       // 43: blockindex.ClaimIntentRequest
+	(*ClaimIntentResponse)(nil),      // 44: blockindex.ClaimIntentResponse
+	(*MigrationRequest)(nil),         // 45: blockindex.MigrationRequest
+	(*MigrationResponse)(nil),        // 46: blockindex.MigrationResponse
+	(*Ref)(nil),                      // 47: blockindex.Ref
+	(*BlockRecord)(nil),              // 48: blockindex.BlockRecord
+	(*ExportRangeRequest)(nil),       // 49: blockindex.ExportRangeRequest
+	(*ExportBlocksRequest)(nil),      // 50: blockindex.ExportBlocksRequest
+	(*ExportResponse)(nil),           // 51: blockindex.ExportResponse
+	(*ImportBlocksRequest)(nil),      // 52: blockindex.ImportBlocksRequest
+	(*ImportBlocksResponse)(nil),     // 53: blockindex.ImportBlocksResponse
+	(*TakeChangesResponse)(nil),      // 54: blockindex.TakeChangesResponse
+	(*FreezeRangeResponse)(nil),      // 55: blockindex.FreezeRangeResponse
 }
 var file_proto_blockindex_proto_depIdxs = []int32{
-	0, // 0: blockindex.BlockIndexService.PutEntry:input_type -> blockindex.PutEntryRequest
-	2, // 1: blockindex.BlockIndexService.GetEntry:input_type -> blockindex.GetEntryRequest
-	4, // 2: blockindex.BlockIndexService.Exists:input_type -> blockindex.ExistsRequest
-	1, // 3: blockindex.BlockIndexService.PutEntry:output_type -> blockindex.PutEntryResponse
-	3, // 4: blockindex.BlockIndexService.GetEntry:output_type -> blockindex.GetEntryResponse
-	5, // 5: blockindex.BlockIndexService.Exists:output_type -> blockindex.ExistsResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	10, // 0: blockindex.PutEntryResponse.existing:type_name -> blockindex.Entry
+	10, // 1: blockindex.UpdateEntryResponse.current:type_name -> blockindex.Entry
+	10, // 2: blockindex.GetEntriesResponse.entries:type_name -> blockindex.Entry
+	10, // 3: blockindex.ListEntriesResponse.entries:type_name -> blockindex.Entry
+	32, // 4: blockindex.GetVolumeUsageResponse.volumes:type_name -> blockindex.VolumeUsage
+	34, // 5: blockindex.BeginPutRequest.intents:type_name -> blockindex.Intent
+	34, // 6: blockindex.ListIntentsResponse.intents:type_name -> blockindex.Intent
+	10, // 7: blockindex.BlockRecord.entry:type_name -> blockindex.Entry
+	47, // 8: blockindex.BlockRecord.refs:type_name -> blockindex.Ref
+	48, // 9: blockindex.ExportResponse.records:type_name -> blockindex.BlockRecord
+	48, // 10: blockindex.ImportBlocksRequest.records:type_name -> blockindex.BlockRecord
+	0,  // 11: blockindex.BlockIndexService.PutEntry:input_type -> blockindex.PutEntryRequest
+	2,  // 12: blockindex.BlockIndexService.UpdateEntry:input_type -> blockindex.UpdateEntryRequest
+	4,  // 13: blockindex.BlockIndexService.GetEntry:input_type -> blockindex.GetEntryRequest
+	6,  // 14: blockindex.BlockIndexService.Exists:input_type -> blockindex.ExistsRequest
+	8,  // 15: blockindex.BlockIndexService.ExistsBatch:input_type -> blockindex.ExistsBatchRequest
+	11, // 16: blockindex.BlockIndexService.GetEntries:input_type -> blockindex.GetEntriesRequest
+	13, // 17: blockindex.BlockIndexService.MarkVerified:input_type -> blockindex.MarkVerifiedRequest
+	15, // 18: blockindex.BlockIndexService.AddRefs:input_type -> blockindex.AddRefsRequest
+	17, // 19: blockindex.BlockIndexService.Release:input_type -> blockindex.ReleaseRequest
+	19, // 20: blockindex.BlockIndexService.Referenced:input_type -> blockindex.ReferencedRequest
+	21, // 21: blockindex.BlockIndexService.GetUsage:input_type -> blockindex.GetUsageRequest
+	23, // 22: blockindex.BlockIndexService.ListCollectable:input_type -> blockindex.ListCollectableRequest
+	26, // 23: blockindex.BlockIndexService.MarkDeleting:input_type -> blockindex.MarkDeletingRequest
+	24, // 24: blockindex.BlockIndexService.ListDeleting:input_type -> blockindex.ListDeletingRequest
+	28, // 25: blockindex.BlockIndexService.RemoveEntry:input_type -> blockindex.RemoveEntryRequest
+	30, // 26: blockindex.BlockIndexService.ListVolumeEntries:input_type -> blockindex.ListVolumeEntriesRequest
+	31, // 27: blockindex.BlockIndexService.GetVolumeUsage:input_type -> blockindex.GetVolumeUsageRequest
+	35, // 28: blockindex.BlockIndexService.BeginPut:input_type -> blockindex.BeginPutRequest
+	37, // 29: blockindex.BlockIndexService.CommitPut:input_type -> blockindex.CommitPutRequest
+	39, // 30: blockindex.BlockIndexService.AbortPut:input_type -> blockindex.AbortPutRequest
+	41, // 31: blockindex.BlockIndexService.ListIntents:input_type -> blockindex.ListIntentsRequest
+	43, // 32: blockindex.BlockIndexService.ClaimIntent:input_type -> blockindex.ClaimIntentRequest
+	45, // 33: blockindex.BlockIndexService.BeginMigration:input_type -> blockindex.MigrationRequest
+	49, // 34: blockindex.BlockIndexService.ExportRange:input_type -> blockindex.ExportRangeRequest
+	50, // 35: blockindex.BlockIndexService.ExportBlocks:input_type -> blockindex.ExportBlocksRequest
+	52, // 36: blockindex.BlockIndexService.ImportBlocks:input_type -> blockindex.ImportBlocksRequest
+	45, // 37: blockindex.BlockIndexService.TakeChanges:input_type -> blockindex.MigrationRequest
+	45, // 38: blockindex.BlockIndexService.FreezeRange:input_type -> blockindex.MigrationRequest
+	45, // 39: blockindex.BlockIndexService.EndMigration:input_type -> blockindex.MigrationRequest
+	1,  // 40: blockindex.BlockIndexService.PutEntry:output_type -> blockindex.PutEntryResponse
+	3,  // 41: blockindex.BlockIndexService.UpdateEntry:output_type -> blockindex.UpdateEntryResponse
+	5,  // 42: blockindex.BlockIndexService.GetEntry:output_type -> blockindex.GetEntryResponse
+	7,  // 43: blockindex.BlockIndexService.Exists:output_type -> blockindex.ExistsResponse
+	9,  // 44: blockindex.BlockIndexService.ExistsBatch:output_type -> blockindex.ExistsBatchResponse
+	12, // 45: blockindex.BlockIndexService.GetEntries:output_type -> blockindex.GetEntriesResponse
+	14, // 46: blockindex.BlockIndexService.MarkVerified:output_type -> blockindex.MarkVerifiedResponse
+	16, // 47: blockindex.BlockIndexService.AddRefs:output_type -> blockindex.AddRefsResponse
+	18, // 48: blockindex.BlockIndexService.Release:output_type -> blockindex.ReleaseResponse
+	20, // 49: blockindex.BlockIndexService.Referenced:output_type -> blockindex.ReferencedResponse
+	22, // 50: blockindex.BlockIndexService.GetUsage:output_type -> blockindex.GetUsageResponse
+	25, // 51: blockindex.BlockIndexService.ListCollectable:output_type -> blockindex.ListEntriesResponse
+	27, // 52: blockindex.BlockIndexService.MarkDeleting:output_type -> blockindex.MarkDeletingResponse
+	25, // 53: blockindex.BlockIndexService.ListDeleting:output_type -> blockindex.ListEntriesResponse
+	29, // 54: blockindex.BlockIndexService.RemoveEntry:output_type -> blockindex.RemoveEntryResponse
+	25, // 55: blockindex.BlockIndexService.ListVolumeEntries:output_type -> blockindex.ListEntriesResponse
+	33, // 56: blockindex.BlockIndexService.GetVolumeUsage:output_type -> blockindex.GetVolumeUsageResponse
+	36, // 57: blockindex.BlockIndexService.BeginPut:output_type -> blockindex.BeginPutResponse
+	38, // 58: blockindex.BlockIndexService.CommitPut:output_type -> blockindex.CommitPutResponse
+	40, // 59: blockindex.BlockIndexService.AbortPut:output_type -> blockindex.AbortPutResponse
+	42, // 60: blockindex.BlockIndexService.ListIntents:output_type -> blockindex.ListIntentsResponse
+	44, // 61: blockindex.BlockIndexService.ClaimIntent:output_type -> blockindex.ClaimIntentResponse
+	46, // 62: blockindex.BlockIndexService.BeginMigration:output_type -> blockindex.MigrationResponse
+	51, // 63: blockindex.BlockIndexService.ExportRange:output_type -> blockindex.ExportResponse
+	51, // 64: blockindex.BlockIndexService.ExportBlocks:output_type -> blockindex.ExportResponse
+	53, // 65: blockindex.BlockIndexService.ImportBlocks:output_type -> blockindex.ImportBlocksResponse
+	54, // 66: blockindex.BlockIndexService.TakeChanges:output_type -> blockindex.TakeChangesResponse
+	55, // 67: blockindex.BlockIndexService.FreezeRange:output_type -> blockindex.FreezeRangeResponse
+	46, // 68: blockindex.BlockIndexService.EndMigration:output_type -> blockindex.MigrationResponse
+	40, // [40:69] is the sub-list for method output_type
+	11, // [11:40] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_proto_blockindex_proto_init() }
@@ -426,7 +3798,7 @@ func file_proto_blockindex_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_blockindex_proto_rawDesc), len(file_proto_blockindex_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   56,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,9 +19,35 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BlockIndexService_PutEntry_FullMethodName = "/blockindex.BlockIndexService/PutEntry"
-	BlockIndexService_GetEntry_FullMethodName = "/blockindex.BlockIndexService/GetEntry"
-	BlockIndexService_Exists_FullMethodName   = "/blockindex.BlockIndexService/Exists"
+	BlockIndexService_PutEntry_FullMethodName          = "/blockindex.BlockIndexService/PutEntry"
+	BlockIndexService_UpdateEntry_FullMethodName       = "/blockindex.BlockIndexService/UpdateEntry"
+	BlockIndexService_GetEntry_FullMethodName          = "/blockindex.BlockIndexService/GetEntry"
+	BlockIndexService_Exists_FullMethodName            = "/blockindex.BlockIndexService/Exists"
+	BlockIndexService_ExistsBatch_FullMethodName       = "/blockindex.BlockIndexService/ExistsBatch"
+	BlockIndexService_GetEntries_FullMethodName        = "/blockindex.BlockIndexService/GetEntries"
+	BlockIndexService_MarkVerified_FullMethodName      = "/blockindex.BlockIndexService/MarkVerified"
+	BlockIndexService_AddRefs_FullMethodName           = "/blockindex.BlockIndexService/AddRefs"
+	BlockIndexService_Release_FullMethodName           = "/blockindex.BlockIndexService/Release"
+	BlockIndexService_Referenced_FullMethodName        = "/blockindex.BlockIndexService/Referenced"
+	BlockIndexService_GetUsage_FullMethodName          = "/blockindex.BlockIndexService/GetUsage"
+	BlockIndexService_ListCollectable_FullMethodName   = "/blockindex.BlockIndexService/ListCollectable"
+	BlockIndexService_MarkDeleting_FullMethodName      = "/blockindex.BlockIndexService/MarkDeleting"
+	BlockIndexService_ListDeleting_FullMethodName      = "/blockindex.BlockIndexService/ListDeleting"
+	BlockIndexService_RemoveEntry_FullMethodName       = "/blockindex.BlockIndexService/RemoveEntry"
+	BlockIndexService_ListVolumeEntries_FullMethodName = "/blockindex.BlockIndexService/ListVolumeEntries"
+	BlockIndexService_GetVolumeUsage_FullMethodName    = "/blockindex.BlockIndexService/GetVolumeUsage"
+	BlockIndexService_BeginPut_FullMethodName          = "/blockindex.BlockIndexService/BeginPut"
+	BlockIndexService_CommitPut_FullMethodName         = "/blockindex.BlockIndexService/CommitPut"
+	BlockIndexService_AbortPut_FullMethodName          = "/blockindex.BlockIndexService/AbortPut"
+	BlockIndexService_ListIntents_FullMethodName       = "/blockindex.BlockIndexService/ListIntents"
+	BlockIndexService_ClaimIntent_FullMethodName       = "/blockindex.BlockIndexService/ClaimIntent"
+	BlockIndexService_BeginMigration_FullMethodName    = "/blockindex.BlockIndexService/BeginMigration"
+	BlockIndexService_ExportRange_FullMethodName       = "/blockindex.BlockIndexService/ExportRange"
+	BlockIndexService_ExportBlocks_FullMethodName      = "/blockindex.BlockIndexService/ExportBlocks"
+	BlockIndexService_ImportBlocks_FullMethodName      = "/blockindex.BlockIndexService/ImportBlocks"
+	BlockIndexService_TakeChanges_FullMethodName       = "/blockindex.BlockIndexService/TakeChanges"
+	BlockIndexService_FreezeRange_FullMethodName       = "/blockindex.BlockIndexService/FreezeRange"
+	BlockIndexService_EndMigration_FullMethodName      = "/blockindex.BlockIndexService/EndMigration"
 )
 
 // BlockIndexServiceClient is the client API for BlockIndexService service.
@@ -31,8 +57,35 @@ const (
 // BlockIndex service for mapping block hashes to storage locations
 type BlockIndexServiceClient interface {
 	PutEntry(ctx context.Context, in *PutEntryRequest, opts ...grpc.CallOption) (*PutEntryResponse, error)
+	UpdateEntry(ctx context.Context, in *UpdateEntryRequest, opts ...grpc.CallOption) (*UpdateEntryResponse, error)
 	GetEntry(ctx context.Context, in *GetEntryRequest, opts ...grpc.CallOption) (*GetEntryResponse, error)
 	Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error)
+	ExistsBatch(ctx context.Context, in *ExistsBatchRequest, opts ...grpc.CallOption) (*ExistsBatchResponse, error)
+	GetEntries(ctx context.Context, in *GetEntriesRequest, opts ...grpc.CallOption) (*GetEntriesResponse, error)
+	MarkVerified(ctx context.Context, in *MarkVerifiedRequest, opts ...grpc.CallOption) (*MarkVerifiedResponse, error)
+	AddRefs(ctx context.Context, in *AddRefsRequest, opts ...grpc.CallOption) (*AddRefsResponse, error)
+	Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error)
+	Referenced(ctx context.Context, in *ReferencedRequest, opts ...grpc.CallOption) (*ReferencedResponse, error)
+	GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error)
+	ListCollectable(ctx context.Context, in *ListCollectableRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error)
+	MarkDeleting(ctx context.Context, in *MarkDeletingRequest, opts ...grpc.CallOption) (*MarkDeletingResponse, error)
+	ListDeleting(ctx context.Context, in *ListDeletingRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error)
+	RemoveEntry(ctx context.Context, in *RemoveEntryRequest, opts ...grpc.CallOption) (*RemoveEntryResponse, error)
+	ListVolumeEntries(ctx context.Context, in *ListVolumeEntriesRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error)
+	GetVolumeUsage(ctx context.Context, in *GetVolumeUsageRequest, opts ...grpc.CallOption) (*GetVolumeUsageResponse, error)
+	BeginPut(ctx context.Context, in *BeginPutRequest, opts ...grpc.CallOption) (*BeginPutResponse, error)
+	CommitPut(ctx context.Context, in *CommitPutRequest, opts ...grpc.CallOption) (*CommitPutResponse, error)
+	AbortPut(ctx context.Context, in *AbortPutRequest, opts ...grpc.CallOption) (*AbortPutResponse, error)
+	ListIntents(ctx context.Context, in *ListIntentsRequest, opts ...grpc.CallOption) (*ListIntentsResponse, error)
+	ClaimIntent(ctx context.Context, in *ClaimIntentRequest, opts ...grpc.CallOption) (*ClaimIntentResponse, error)
+	// Range migration between index nodes, driven by the master
+	BeginMigration(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*MigrationResponse, error)
+	ExportRange(ctx context.Context, in *ExportRangeRequest, opts ...grpc.CallOption) (*ExportResponse, error)
+	ExportBlocks(ctx context.Context, in *ExportBlocksRequest, opts ...grpc.CallOption) (*ExportResponse, error)
+	ImportBlocks(ctx context.Context, in *ImportBlocksRequest, opts ...grpc.CallOption) (*ImportBlocksResponse, error)
+	TakeChanges(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*TakeChangesResponse, error)
+	FreezeRange(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*FreezeRangeResponse, error)
+	EndMigration(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*MigrationResponse, error)
 }
 
 type blockIndexServiceClient struct {
@@ -53,6 +106,16 @@ func (c *blockIndexServiceClient) PutEntry(ctx context.Context, in *PutEntryRequ
 	return out, nil
 }
 
+func (c *blockIndexServiceClient) UpdateEntry(ctx context.Context, in *UpdateEntryRequest, opts ...grpc.CallOption) (*UpdateEntryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateEntryResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_UpdateEntry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *blockIndexServiceClient) GetEntry(ctx context.Context, in *GetEntryRequest, opts ...grpc.CallOption) (*GetEntryResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetEntryResponse)
@@ -73,6 +136,256 @@ func (c *blockIndexServiceClient) Exists(ctx context.Context, in *ExistsRequest,
 	return out, nil
 }
 
+func (c *blockIndexServiceClient) ExistsBatch(ctx context.Context, in *ExistsBatchRequest, opts ...grpc.CallOption) (*ExistsBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExistsBatchResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ExistsBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) GetEntries(ctx context.Context, in *GetEntriesRequest, opts ...grpc.CallOption) (*GetEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetEntriesResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_GetEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) MarkVerified(ctx context.Context, in *MarkVerifiedRequest, opts ...grpc.CallOption) (*MarkVerifiedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MarkVerifiedResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_MarkVerified_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) AddRefs(ctx context.Context, in *AddRefsRequest, opts ...grpc.CallOption) (*AddRefsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddRefsResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_AddRefs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleaseResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_Release_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) Referenced(ctx context.Context, in *ReferencedRequest, opts ...grpc.CallOption) (*ReferencedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReferencedResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_Referenced_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUsageResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_GetUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ListCollectable(ctx context.Context, in *ListCollectableRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEntriesResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ListCollectable_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) MarkDeleting(ctx context.Context, in *MarkDeletingRequest, opts ...grpc.CallOption) (*MarkDeletingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MarkDeletingResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_MarkDeleting_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ListDeleting(ctx context.Context, in *ListDeletingRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEntriesResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ListDeleting_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) RemoveEntry(ctx context.Context, in *RemoveEntryRequest, opts ...grpc.CallOption) (*RemoveEntryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveEntryResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_RemoveEntry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ListVolumeEntries(ctx context.Context, in *ListVolumeEntriesRequest, opts ...grpc.CallOption) (*ListEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEntriesResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ListVolumeEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) GetVolumeUsage(ctx context.Context, in *GetVolumeUsageRequest, opts ...grpc.CallOption) (*GetVolumeUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVolumeUsageResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_GetVolumeUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) BeginPut(ctx context.Context, in *BeginPutRequest, opts ...grpc.CallOption) (*BeginPutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BeginPutResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_BeginPut_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) CommitPut(ctx context.Context, in *CommitPutRequest, opts ...grpc.CallOption) (*CommitPutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommitPutResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_CommitPut_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) AbortPut(ctx context.Context, in *AbortPutRequest, opts ...grpc.CallOption) (*AbortPutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AbortPutResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_AbortPut_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ListIntents(ctx context.Context, in *ListIntentsRequest, opts ...grpc.CallOption) (*ListIntentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListIntentsResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ListIntents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ClaimIntent(ctx context.Context, in *ClaimIntentRequest, opts ...grpc.CallOption) (*ClaimIntentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClaimIntentResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ClaimIntent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) BeginMigration(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*MigrationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MigrationResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_BeginMigration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ExportRange(ctx context.Context, in *ExportRangeRequest, opts ...grpc.CallOption) (*ExportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ExportRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ExportBlocks(ctx context.Context, in *ExportBlocksRequest, opts ...grpc.CallOption) (*ExportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ExportBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) ImportBlocks(ctx context.Context, in *ImportBlocksRequest, opts ...grpc.CallOption) (*ImportBlocksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportBlocksResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_ImportBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) TakeChanges(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*TakeChangesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TakeChangesResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_TakeChanges_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) FreezeRange(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*FreezeRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FreezeRangeResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_FreezeRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockIndexServiceClient) EndMigration(ctx context.Context, in *MigrationRequest, opts ...grpc.CallOption) (*MigrationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MigrationResponse)
+	err := c.cc.Invoke(ctx, BlockIndexService_EndMigration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BlockIndexServiceServer is the server API for BlockIndexService service.
 // All implementations should embed UnimplementedBlockIndexServiceServer
 // for forward compatibility.
@@ -80,8 +393,35 @@ func (c *blockIndexServiceClient) Exists(ctx context.Context, in *ExistsRequest,
 // BlockIndex service for mapping block hashes to storage locations
 type BlockIndexServiceServer interface {
 	PutEntry(context.Context, *PutEntryRequest) (*PutEntryResponse, error)
+	UpdateEntry(context.Context, *UpdateEntryRequest) (*UpdateEntryResponse, error)
 	GetEntry(context.Context, *GetEntryRequest) (*GetEntryResponse, error)
 	Exists(context.Context, *ExistsRequest) (*ExistsResponse, error)
+	ExistsBatch(context.Context, *ExistsBatchRequest) (*ExistsBatchResponse, error)
+	GetEntries(context.Context, *GetEntriesRequest) (*GetEntriesResponse, error)
+	MarkVerified(context.Context, *MarkVerifiedRequest) (*MarkVerifiedResponse, error)
+	AddRefs(context.Context, *AddRefsRequest) (*AddRefsResponse, error)
+	Release(context.Context, *ReleaseRequest) (*ReleaseResponse, error)
+	Referenced(context.Context, *ReferencedRequest) (*ReferencedResponse, error)
+	GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error)
+	ListCollectable(context.Context, *ListCollectableRequest) (*ListEntriesResponse, error)
+	MarkDeleting(context.Context, *MarkDeletingRequest) (*MarkDeletingResponse, error)
+	ListDeleting(context.Context, *ListDeletingRequest) (*ListEntriesResponse, error)
+	RemoveEntry(context.Context, *RemoveEntryRequest) (*RemoveEntryResponse, error)
+	ListVolumeEntries(context.Context, *ListVolumeEntriesRequest) (*ListEntriesResponse, error)
+	GetVolumeUsage(context.Context, *GetVolumeUsageRequest) (*GetVolumeUsageResponse, error)
+	BeginPut(context.Context, *BeginPutRequest) (*BeginPutResponse, error)
+	CommitPut(context.Context, *CommitPutRequest) (*CommitPutResponse, error)
+	AbortPut(context.Context, *AbortPutRequest) (*AbortPutResponse, error)
+	ListIntents(context.Context, *ListIntentsRequest) (*ListIntentsResponse, error)
+	ClaimIntent(context.Context, *ClaimIntentRequest) (*ClaimIntentResponse, error)
+	// Range migration between index nodes, driven by the master
+	BeginMigration(context.Context, *MigrationRequest) (*MigrationResponse, error)
+	ExportRange(context.Context, *ExportRangeRequest) (*ExportResponse, error)
+	ExportBlocks(context.Context, *ExportBlocksRequest) (*ExportResponse, error)
+	ImportBlocks(context.Context, *ImportBlocksRequest) (*ImportBlocksResponse, error)
+	TakeChanges(context.Context, *MigrationRequest) (*TakeChangesResponse, error)
+	FreezeRange(context.Context, *MigrationRequest) (*FreezeRangeResponse, error)
+	EndMigration(context.Context, *MigrationRequest) (*MigrationResponse, error)
 }
 
 // UnimplementedBlockIndexServiceServer should be embedded to have
@@ -94,12 +434,90 @@ type UnimplementedBlockIndexServiceServer struct{}
 func (UnimplementedBlockIndexServiceServer) PutEntry(context.Context, *PutEntryRequest) (*PutEntryResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method PutEntry not implemented")
 }
+func (UnimplementedBlockIndexServiceServer) UpdateEntry(context.Context, *UpdateEntryRequest) (*UpdateEntryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateEntry not implemented")
+}
 func (UnimplementedBlockIndexServiceServer) GetEntry(context.Context, *GetEntryRequest) (*GetEntryResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetEntry not implemented")
 }
 func (UnimplementedBlockIndexServiceServer) Exists(context.Context, *ExistsRequest) (*ExistsResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Exists not implemented")
 }
+func (UnimplementedBlockIndexServiceServer) ExistsBatch(context.Context, *ExistsBatchRequest) (*ExistsBatchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExistsBatch not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) GetEntries(context.Context, *GetEntriesRequest) (*GetEntriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetEntries not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) MarkVerified(context.Context, *MarkVerifiedRequest) (*MarkVerifiedResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method MarkVerified not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) AddRefs(context.Context, *AddRefsRequest) (*AddRefsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddRefs not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) Release(context.Context, *ReleaseRequest) (*ReleaseResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) Referenced(context.Context, *ReferencedRequest) (*ReferencedResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Referenced not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUsage not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ListCollectable(context.Context, *ListCollectableRequest) (*ListEntriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListCollectable not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) MarkDeleting(context.Context, *MarkDeletingRequest) (*MarkDeletingResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method MarkDeleting not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ListDeleting(context.Context, *ListDeletingRequest) (*ListEntriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListDeleting not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) RemoveEntry(context.Context, *RemoveEntryRequest) (*RemoveEntryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveEntry not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ListVolumeEntries(context.Context, *ListVolumeEntriesRequest) (*ListEntriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListVolumeEntries not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) GetVolumeUsage(context.Context, *GetVolumeUsageRequest) (*GetVolumeUsageResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetVolumeUsage not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) BeginPut(context.Context, *BeginPutRequest) (*BeginPutResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BeginPut not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) CommitPut(context.Context, *CommitPutRequest) (*CommitPutResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CommitPut not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) AbortPut(context.Context, *AbortPutRequest) (*AbortPutResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AbortPut not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ListIntents(context.Context, *ListIntentsRequest) (*ListIntentsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListIntents not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ClaimIntent(context.Context, *ClaimIntentRequest) (*ClaimIntentResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ClaimIntent not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) BeginMigration(context.Context, *MigrationRequest) (*MigrationResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BeginMigration not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ExportRange(context.Context, *ExportRangeRequest) (*ExportResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExportRange not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ExportBlocks(context.Context, *ExportBlocksRequest) (*ExportResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExportBlocks not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) ImportBlocks(context.Context, *ImportBlocksRequest) (*ImportBlocksResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ImportBlocks not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) TakeChanges(context.Context, *MigrationRequest) (*TakeChangesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method TakeChanges not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) FreezeRange(context.Context, *MigrationRequest) (*FreezeRangeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FreezeRange not implemented")
+}
+func (UnimplementedBlockIndexServiceServer) EndMigration(context.Context, *MigrationRequest) (*MigrationResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method EndMigration not implemented")
+}
 func (UnimplementedBlockIndexServiceServer) testEmbeddedByValue() {}
 
 // UnsafeBlockIndexServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -138,6 +556,24 @@ func _BlockIndexService_PutEntry_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BlockIndexService_UpdateEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).UpdateEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_UpdateEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).UpdateEntry(ctx, req.(*UpdateEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _BlockIndexService_GetEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetEntryRequest)
 	if err := dec(in); err != nil {
@@ -174,24 +610,578 @@ func _BlockIndexService_Exists_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
-// BlockIndexService_ServiceDesc is the grpc.ServiceDesc for BlockIndexService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var BlockIndexService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "blockindex.BlockIndexService",
-	HandlerType: (*BlockIndexServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "PutEntry",
-			Handler:    _BlockIndexService_PutEntry_Handler,
-		},
-		{
-			MethodName: "GetEntry",
-			Handler:    _BlockIndexService_GetEntry_Handler,
-		},
-		{
-			MethodName: "Exists",
-			Handler:    _BlockIndexService_Exists_Handler,
+func _BlockIndexService_ExistsBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExistsBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ExistsBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ExistsBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ExistsBatch(ctx, req.(*ExistsBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_GetEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).GetEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_GetEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).GetEntries(ctx, req.(*GetEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_MarkVerified_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkVerifiedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).MarkVerified(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_MarkVerified_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).MarkVerified(ctx, req.(*MarkVerifiedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_AddRefs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRefsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).AddRefs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_AddRefs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).AddRefs(ctx, req.(*AddRefsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).Release(ctx, req.(*ReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_Referenced_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReferencedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).Referenced(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_Referenced_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).Referenced(ctx, req.(*ReferencedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_GetUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).GetUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_GetUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).GetUsage(ctx, req.(*GetUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ListCollectable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCollectableRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ListCollectable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ListCollectable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ListCollectable(ctx, req.(*ListCollectableRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_MarkDeleting_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkDeletingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).MarkDeleting(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_MarkDeleting_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).MarkDeleting(ctx, req.(*MarkDeletingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ListDeleting_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeletingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ListDeleting(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ListDeleting_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ListDeleting(ctx, req.(*ListDeletingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_RemoveEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).RemoveEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_RemoveEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).RemoveEntry(ctx, req.(*RemoveEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ListVolumeEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVolumeEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ListVolumeEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ListVolumeEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ListVolumeEntries(ctx, req.(*ListVolumeEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_GetVolumeUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVolumeUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).GetVolumeUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_GetVolumeUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).GetVolumeUsage(ctx, req.(*GetVolumeUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_BeginPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeginPutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).BeginPut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_BeginPut_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).BeginPut(ctx, req.(*BeginPutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_CommitPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitPutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).CommitPut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_CommitPut_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).CommitPut(ctx, req.(*CommitPutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_AbortPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AbortPutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).AbortPut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_AbortPut_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).AbortPut(ctx, req.(*AbortPutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ListIntents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListIntentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ListIntents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ListIntents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ListIntents(ctx, req.(*ListIntentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ClaimIntent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClaimIntentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ClaimIntent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ClaimIntent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ClaimIntent(ctx, req.(*ClaimIntentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_BeginMigration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).BeginMigration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_BeginMigration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).BeginMigration(ctx, req.(*MigrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ExportRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ExportRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ExportRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ExportRange(ctx, req.(*ExportRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ExportBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ExportBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ExportBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ExportBlocks(ctx, req.(*ExportBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_ImportBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).ImportBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_ImportBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).ImportBlocks(ctx, req.(*ImportBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_TakeChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).TakeChanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_TakeChanges_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).TakeChanges(ctx, req.(*MigrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_FreezeRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).FreezeRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_FreezeRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).FreezeRange(ctx, req.(*MigrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockIndexService_EndMigration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockIndexServiceServer).EndMigration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockIndexService_EndMigration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockIndexServiceServer).EndMigration(ctx, req.(*MigrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BlockIndexService_ServiceDesc is the grpc.ServiceDesc for BlockIndexService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BlockIndexService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "blockindex.BlockIndexService",
+	HandlerType: (*BlockIndexServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PutEntry",
+			Handler:    _BlockIndexService_PutEntry_Handler,
+		},
+		{
+			MethodName: "UpdateEntry",
+			Handler:    _BlockIndexService_UpdateEntry_Handler,
+		},
+		{
+			MethodName: "GetEntry",
+			Handler:    _BlockIndexService_GetEntry_Handler,
+		},
+		{
+			MethodName: "Exists",
+			Handler:    _BlockIndexService_Exists_Handler,
+		},
+		{
+			MethodName: "ExistsBatch",
+			Handler:    _BlockIndexService_ExistsBatch_Handler,
+		},
+		{
+			MethodName: "GetEntries",
+			Handler:    _BlockIndexService_GetEntries_Handler,
+		},
+		{
+			MethodName: "MarkVerified",
+			Handler:    _BlockIndexService_MarkVerified_Handler,
+		},
+		{
+			MethodName: "AddRefs",
+			Handler:    _BlockIndexService_AddRefs_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _BlockIndexService_Release_Handler,
+		},
+		{
+			MethodName: "Referenced",
+			Handler:    _BlockIndexService_Referenced_Handler,
+		},
+		{
+			MethodName: "GetUsage",
+			Handler:    _BlockIndexService_GetUsage_Handler,
+		},
+		{
+			MethodName: "ListCollectable",
+			Handler:    _BlockIndexService_ListCollectable_Handler,
+		},
+		{
+			MethodName: "MarkDeleting",
+			Handler:    _BlockIndexService_MarkDeleting_Handler,
+		},
+		{
+			MethodName: "ListDeleting",
+			Handler:    _BlockIndexService_ListDeleting_Handler,
+		},
+		{
+			MethodName: "RemoveEntry",
+			Handler:    _BlockIndexService_RemoveEntry_Handler,
+		},
+		{
+			MethodName: "ListVolumeEntries",
+			Handler:    _BlockIndexService_ListVolumeEntries_Handler,
+		},
+		{
+			MethodName: "GetVolumeUsage",
+			Handler:    _BlockIndexService_GetVolumeUsage_Handler,
+		},
+		{
+			MethodName: "BeginPut",
+			Handler:    _BlockIndexService_BeginPut_Handler,
+		},
+		{
+			MethodName: "CommitPut",
+			Handler:    _BlockIndexService_CommitPut_Handler,
+		},
+		{
+			MethodName: "AbortPut",
+			Handler:    _BlockIndexService_AbortPut_Handler,
+		},
+		{
+			MethodName: "ListIntents",
+			Handler:    _BlockIndexService_ListIntents_Handler,
+		},
+		{
+			MethodName: "ClaimIntent",
+			Handler:    _BlockIndexService_ClaimIntent_Handler,
+		},
+		{
+			MethodName: "BeginMigration",
+			Handler:    _BlockIndexService_BeginMigration_Handler,
+		},
+		{
+			MethodName: "ExportRange",
+			Handler:    _BlockIndexService_ExportRange_Handler,
+		},
+		{
+			MethodName: "ExportBlocks",
+			Handler:    _BlockIndexService_ExportBlocks_Handler,
+		},
+		{
+			MethodName: "ImportBlocks",
+			Handler:    _BlockIndexService_ImportBlocks_Handler,
+		},
+		{
+			MethodName: "TakeChanges",
+			Handler:    _BlockIndexService_TakeChanges_Handler,
+		},
+		{
+			MethodName: "FreezeRange",
+			Handler:    _BlockIndexService_FreezeRange_Handler,
+		},
+		{
+			MethodName: "EndMigration",
+			Handler:    _BlockIndexService_EndMigration_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
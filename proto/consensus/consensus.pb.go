@@ -0,0 +1,184 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v4.24.3
+// source: proto/consensus.proto
+
+package consensus
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Command is a call to a replicated service, as forwarded to the leader and
+// as recorded in the Raft log
+type Command struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`   // full gRPC method name, e.g. "/blockindex.BlockIndexService/PutEntry"
+	Request       []byte                 `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"` // serialized request message
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Command) Reset() {
+	*x = Command{}
+	mi := &file_proto_consensus_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Command) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Command) ProtoMessage() {}
+
+func (x *Command) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_consensus_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Command.ProtoReflect.Descriptor instead.
+func (*Command) Descriptor() ([]byte, []int) {
+	return file_proto_consensus_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Command) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *Command) GetRequest() []byte {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+type ForwardResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Response      []byte                 `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"` // serialized response message
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForwardResponse) Reset() {
+	*x = ForwardResponse{}
+	mi := &file_proto_consensus_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForwardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForwardResponse) ProtoMessage() {}
+
+func (x *ForwardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_consensus_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForwardResponse.ProtoReflect.Descriptor instead.
+func (*ForwardResponse) Descriptor() ([]byte, []int) {
+	return file_proto_consensus_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ForwardResponse) GetResponse() []byte {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+var File_proto_consensus_proto protoreflect.FileDescriptor
+
+const file_proto_consensus_proto_rawDesc = "" +
+	"\n" +
+	"\x15proto/consensus.proto\x12\tconsensus\";\n" +
+	"\aCommand\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x18\n" +
+	"\arequest\x18\x02 \x01(\fR\arequest\"-\n" +
+	"\x0fForwardResponse\x12\x1a\n" +
+	"\bresponse\x18\x01 \x01(\fR\bresponse2M\n" +
+	"\x10ConsensusService\x129\n" +
+	"\aForward\x12\x12.consensus.Command\x1a\x1a.consensus.ForwardResponseB\x19Z\x17bharani/proto/consensusb\x06proto3"
+
+var (
+	file_proto_consensus_proto_rawDescOnce sync.Once
+	file_proto_consensus_proto_rawDescData []byte
+)
+
+func file_proto_consensus_proto_rawDescGZIP() []byte {
+	file_proto_consensus_proto_rawDescOnce.Do(func() {
+		file_proto_consensus_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_consensus_proto_rawDesc), len(file_proto_consensus_proto_rawDesc)))
+	})
+	return file_proto_consensus_proto_rawDescData
+}
+
+var file_proto_consensus_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_consensus_proto_goTypes = []any{
+	(*Command)(nil),         // 0: consensus.Command
+	(*ForwardResponse)(nil), // 1: consensus.ForwardResponse
+}
+var file_proto_consensus_proto_depIdxs = []int32{
+	0, // 0: consensus.ConsensusService.Forward:input_type -> consensus.Command
+	1, // 1: consensus.ConsensusService.Forward:output_type -> consensus.ForwardResponse
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_consensus_proto_init() }
+func file_proto_consensus_proto_init() {
+	if File_proto_consensus_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_consensus_proto_rawDesc), len(file_proto_consensus_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_consensus_proto_goTypes,
+		DependencyIndexes: file_proto_consensus_proto_depIdxs,
+		MessageInfos:      file_proto_consensus_proto_msgTypes,
+	}.Build()
+	File_proto_consensus_proto = out.File
+	file_proto_consensus_proto_goTypes = nil
+	file_proto_consensus_proto_depIdxs = nil
+}
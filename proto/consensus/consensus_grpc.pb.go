@@ -0,0 +1,125 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v4.24.3
+// source: proto/consensus.proto
+
+package consensus
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ConsensusService_Forward_FullMethodName = "/consensus.ConsensusService/Forward"
+)
+
+// ConsensusServiceClient is the client API for ConsensusService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Consensus service through which the replicas of a metadata service hand
+// calls to their Raft leader
+type ConsensusServiceClient interface {
+	Forward(ctx context.Context, in *Command, opts ...grpc.CallOption) (*ForwardResponse, error)
+}
+
+type consensusServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConsensusServiceClient(cc grpc.ClientConnInterface) ConsensusServiceClient {
+	return &consensusServiceClient{cc}
+}
+
+func (c *consensusServiceClient) Forward(ctx context.Context, in *Command, opts ...grpc.CallOption) (*ForwardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ForwardResponse)
+	err := c.cc.Invoke(ctx, ConsensusService_Forward_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConsensusServiceServer is the server API for ConsensusService service.
+// All implementations should embed UnimplementedConsensusServiceServer
+// for forward compatibility.
+//
+// Consensus service through which the replicas of a metadata service hand
+// calls to their Raft leader
+type ConsensusServiceServer interface {
+	Forward(context.Context, *Command) (*ForwardResponse, error)
+}
+
+// UnimplementedConsensusServiceServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedConsensusServiceServer struct{}
+
+func (UnimplementedConsensusServiceServer) Forward(context.Context, *Command) (*ForwardResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Forward not implemented")
+}
+func (UnimplementedConsensusServiceServer) testEmbeddedByValue() {}
+
+// UnsafeConsensusServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConsensusServiceServer will
+// result in compilation errors.
+type UnsafeConsensusServiceServer interface {
+	mustEmbedUnimplementedConsensusServiceServer()
+}
+
+func RegisterConsensusServiceServer(s grpc.ServiceRegistrar, srv ConsensusServiceServer) {
+	// If the following call panics, it indicates UnimplementedConsensusServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ConsensusService_ServiceDesc, srv)
+}
+
+func _ConsensusService_Forward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Command)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsensusServiceServer).Forward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsensusService_Forward_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsensusServiceServer).Forward(ctx, req.(*Command))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ConsensusService_ServiceDesc is the grpc.ServiceDesc for ConsensusService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ConsensusService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "consensus.ConsensusService",
+	HandlerType: (*ConsensusServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Forward",
+			Handler:    _ConsensusService_Forward_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/consensus.proto",
+}
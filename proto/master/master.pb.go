@@ -26,6 +26,7 @@ type RegisterOSDRequest struct {
 	OsdAddress     string                 `protobuf:"bytes,1,opt,name=osd_address,json=osdAddress,proto3" json:"osd_address,omitempty"`
 	CellId         string                 `protobuf:"bytes,2,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
 	AvailableSpace int64                  `protobuf:"varint,3,opt,name=available_space,json=availableSpace,proto3" json:"available_space,omitempty"`
+	ZoneId         string                 `protobuf:"bytes,4,opt,name=zone_id,json=zoneId,proto3" json:"zone_id,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -81,6 +82,13 @@ func (x *RegisterOSDRequest) GetAvailableSpace() int64 {
 	return 0
 }
 
+func (x *RegisterOSDRequest) GetZoneId() string {
+	if x != nil {
+		return x.ZoneId
+	}
+	return ""
+}
+
 type RegisterOSDResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -138,6 +146,8 @@ type HeartbeatRequest struct {
 	OsdAddress     string                 `protobuf:"bytes,1,opt,name=osd_address,json=osdAddress,proto3" json:"osd_address,omitempty"`
 	Healthy        bool                   `protobuf:"varint,2,opt,name=healthy,proto3" json:"healthy,omitempty"`
 	AvailableSpace int64                  `protobuf:"varint,3,opt,name=available_space,json=availableSpace,proto3" json:"available_space,omitempty"`
+	CellId         string                 `protobuf:"bytes,4,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	ZoneId         string                 `protobuf:"bytes,5,opt,name=zone_id,json=zoneId,proto3" json:"zone_id,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -193,6 +203,20 @@ func (x *HeartbeatRequest) GetAvailableSpace() int64 {
 	return 0
 }
 
+func (x *HeartbeatRequest) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetZoneId() string {
+	if x != nil {
+		return x.ZoneId
+	}
+	return ""
+}
+
 type HeartbeatResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -517,47 +541,1169 @@ func (x *TriggerRepairResponse) GetError() string {
 	return ""
 }
 
-var File_proto_master_proto protoreflect.FileDescriptor
+type AllocateVolumeRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	CellId            string                 `protobuf:"bytes,1,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	ReplicationFactor int32                  `protobuf:"varint,2,opt,name=replication_factor,json=replicationFactor,proto3" json:"replication_factor,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
 
-const file_proto_master_proto_rawDesc = "" +
-	"\n" +
-	"\x12proto/master.proto\x12\x06master\"w\n" +
-	"\x12RegisterOSDRequest\x12\x1f\n" +
-	"\vosd_address\x18\x01 \x01(\tR\n" +
-	"osdAddress\x12\x17\n" +
-	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12'\n" +
-	"\x0favailable_space\x18\x03 \x01(\x03R\x0eavailableSpace\"E\n" +
-	"\x13RegisterOSDResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"v\n" +
-	"\x10HeartbeatRequest\x12\x1f\n" +
-	"\vosd_address\x18\x01 \x01(\tR\n" +
-	"osdAddress\x12\x18\n" +
-	"\ahealthy\x18\x02 \x01(\bR\ahealthy\x12'\n" +
-	"\x0favailable_space\x18\x03 \x01(\x03R\x0eavailableSpace\"-\n" +
-	"\x11HeartbeatResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"0\n" +
-	"\x15GetOpenVolumesRequest\x12\x17\n" +
-	"\acell_id\x18\x01 \x01(\tR\x06cellId\"7\n" +
-	"\x16GetOpenVolumesResponse\x12\x1d\n" +
-	"\n" +
-	"volume_ids\x18\x01 \x03(\tR\tvolumeIds\"1\n" +
-	"\x12CloseVolumeRequest\x12\x1b\n" +
-	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"E\n" +
-	"\x13CloseVolumeResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"D\n" +
-	"\x14TriggerRepairRequest\x12,\n" +
-	"\x12failed_osd_address\x18\x01 \x01(\tR\x10failedOsdAddress\"G\n" +
-	"\x15TriggerRepairResponse\x12\x18\n" +
+func (x *AllocateVolumeRequest) Reset() {
+	*x = AllocateVolumeRequest{}
+	mi := &file_proto_master_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateVolumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateVolumeRequest) ProtoMessage() {}
+
+func (x *AllocateVolumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateVolumeRequest.ProtoReflect.Descriptor instead.
+func (*AllocateVolumeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AllocateVolumeRequest) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+func (x *AllocateVolumeRequest) GetReplicationFactor() int32 {
+	if x != nil {
+		return x.ReplicationFactor
+	}
+	return 0
+}
+
+type AllocateVolumeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,2,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	OsdAddresses  []string               `protobuf:"bytes,3,rep,name=osd_addresses,json=osdAddresses,proto3" json:"osd_addresses,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateVolumeResponse) Reset() {
+	*x = AllocateVolumeResponse{}
+	mi := &file_proto_master_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateVolumeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateVolumeResponse) ProtoMessage() {}
+
+func (x *AllocateVolumeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateVolumeResponse.ProtoReflect.Descriptor instead.
+func (*AllocateVolumeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AllocateVolumeResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AllocateVolumeResponse) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *AllocateVolumeResponse) GetOsdAddresses() []string {
+	if x != nil {
+		return x.OsdAddresses
+	}
+	return nil
+}
+
+func (x *AllocateVolumeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type DrainOSDRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OsdAddress    string                 `protobuf:"bytes,1,opt,name=osd_address,json=osdAddress,proto3" json:"osd_address,omitempty"`
+	Draining      bool                   `protobuf:"varint,2,opt,name=draining,proto3" json:"draining,omitempty"` // false returns the OSD to service
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainOSDRequest) Reset() {
+	*x = DrainOSDRequest{}
+	mi := &file_proto_master_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainOSDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainOSDRequest) ProtoMessage() {}
+
+func (x *DrainOSDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainOSDRequest.ProtoReflect.Descriptor instead.
+func (*DrainOSDRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DrainOSDRequest) GetOsdAddress() string {
+	if x != nil {
+		return x.OsdAddress
+	}
+	return ""
+}
+
+func (x *DrainOSDRequest) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+type DrainOSDResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainOSDResponse) Reset() {
+	*x = DrainOSDResponse{}
+	mi := &file_proto_master_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainOSDResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainOSDResponse) ProtoMessage() {}
+
+func (x *DrainOSDResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainOSDResponse.ProtoReflect.Descriptor instead.
+func (*DrainOSDResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DrainOSDResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DrainOSDResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ReportUnderReplicatedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,2,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	BucketId      string                 `protobuf:"bytes,3,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
+	MissingOsds   []string               `protobuf:"bytes,4,rep,name=missing_osds,json=missingOsds,proto3" json:"missing_osds,omitempty"` // replicas that did not acknowledge the write
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportUnderReplicatedRequest) Reset() {
+	*x = ReportUnderReplicatedRequest{}
+	mi := &file_proto_master_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportUnderReplicatedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportUnderReplicatedRequest) ProtoMessage() {}
+
+func (x *ReportUnderReplicatedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportUnderReplicatedRequest.ProtoReflect.Descriptor instead.
+func (*ReportUnderReplicatedRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ReportUnderReplicatedRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ReportUnderReplicatedRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *ReportUnderReplicatedRequest) GetBucketId() string {
+	if x != nil {
+		return x.BucketId
+	}
+	return ""
+}
+
+func (x *ReportUnderReplicatedRequest) GetMissingOsds() []string {
+	if x != nil {
+		return x.MissingOsds
+	}
+	return nil
+}
+
+type ReportUnderReplicatedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportUnderReplicatedResponse) Reset() {
+	*x = ReportUnderReplicatedResponse{}
+	mi := &file_proto_master_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportUnderReplicatedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportUnderReplicatedResponse) ProtoMessage() {}
+
+func (x *ReportUnderReplicatedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportUnderReplicatedResponse.ProtoReflect.Descriptor instead.
+func (*ReportUnderReplicatedResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ReportUnderReplicatedResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportUnderReplicatedResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListOSDsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CellId        string                 `protobuf:"bytes,1,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"` // optional filter
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOSDsRequest) Reset() {
+	*x = ListOSDsRequest{}
+	mi := &file_proto_master_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOSDsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOSDsRequest) ProtoMessage() {}
+
+func (x *ListOSDsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOSDsRequest.ProtoReflect.Descriptor instead.
+func (*ListOSDsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListOSDsRequest) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+type OSDStatus struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Address        string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	CellId         string                 `protobuf:"bytes,2,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	ZoneId         string                 `protobuf:"bytes,3,opt,name=zone_id,json=zoneId,proto3" json:"zone_id,omitempty"`
+	Healthy        bool                   `protobuf:"varint,4,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Draining       bool                   `protobuf:"varint,5,opt,name=draining,proto3" json:"draining,omitempty"`
+	AvailableSpace int64                  `protobuf:"varint,6,opt,name=available_space,json=availableSpace,proto3" json:"available_space,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *OSDStatus) Reset() {
+	*x = OSDStatus{}
+	mi := &file_proto_master_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OSDStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OSDStatus) ProtoMessage() {}
+
+func (x *OSDStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OSDStatus.ProtoReflect.Descriptor instead.
+func (*OSDStatus) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *OSDStatus) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *OSDStatus) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+func (x *OSDStatus) GetZoneId() string {
+	if x != nil {
+		return x.ZoneId
+	}
+	return ""
+}
+
+func (x *OSDStatus) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *OSDStatus) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+func (x *OSDStatus) GetAvailableSpace() int64 {
+	if x != nil {
+		return x.AvailableSpace
+	}
+	return 0
+}
+
+type ListOSDsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Osds          []*OSDStatus           `protobuf:"bytes,1,rep,name=osds,proto3" json:"osds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOSDsResponse) Reset() {
+	*x = ListOSDsResponse{}
+	mi := &file_proto_master_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOSDsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOSDsResponse) ProtoMessage() {}
+
+func (x *ListOSDsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOSDsResponse.ProtoReflect.Descriptor instead.
+func (*ListOSDsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListOSDsResponse) GetOsds() []*OSDStatus {
+	if x != nil {
+		return x.Osds
+	}
+	return nil
+}
+
+type ReserveSpaceRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	CellId            string                 `protobuf:"bytes,1,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	Size              int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`                                                    // bytes about to be written
+	ReplicationFactor int32                  `protobuf:"varint,3,opt,name=replication_factor,json=replicationFactor,proto3" json:"replication_factor,omitempty"` // used if a new volume has to be allocated
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ReserveSpaceRequest) Reset() {
+	*x = ReserveSpaceRequest{}
+	mi := &file_proto_master_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReserveSpaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReserveSpaceRequest) ProtoMessage() {}
+
+func (x *ReserveSpaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReserveSpaceRequest.ProtoReflect.Descriptor instead.
+func (*ReserveSpaceRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ReserveSpaceRequest) GetCellId() string {
+	if x != nil {
+		return x.CellId
+	}
+	return ""
+}
+
+func (x *ReserveSpaceRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ReserveSpaceRequest) GetReplicationFactor() int32 {
+	if x != nil {
+		return x.ReplicationFactor
+	}
+	return 0
+}
+
+type ReserveSpaceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,2,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	BucketId      string                 `protobuf:"bytes,3,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
+	OsdAddresses  []string               `protobuf:"bytes,4,rep,name=osd_addresses,json=osdAddresses,proto3" json:"osd_addresses,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReserveSpaceResponse) Reset() {
+	*x = ReserveSpaceResponse{}
+	mi := &file_proto_master_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReserveSpaceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReserveSpaceResponse) ProtoMessage() {}
+
+func (x *ReserveSpaceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReserveSpaceResponse.ProtoReflect.Descriptor instead.
+func (*ReserveSpaceResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReserveSpaceResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReserveSpaceResponse) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *ReserveSpaceResponse) GetBucketId() string {
+	if x != nil {
+		return x.BucketId
+	}
+	return ""
+}
+
+func (x *ReserveSpaceResponse) GetOsdAddresses() []string {
+	if x != nil {
+		return x.OsdAddresses
+	}
+	return nil
+}
+
+func (x *ReserveSpaceResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ReportCorruptionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,2,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	BucketId      string                 `protobuf:"bytes,3,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
+	OsdAddress    string                 `protobuf:"bytes,4,opt,name=osd_address,json=osdAddress,proto3" json:"osd_address,omitempty"` // replica that returned data not matching the hash
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportCorruptionRequest) Reset() {
+	*x = ReportCorruptionRequest{}
+	mi := &file_proto_master_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportCorruptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportCorruptionRequest) ProtoMessage() {}
+
+func (x *ReportCorruptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportCorruptionRequest.ProtoReflect.Descriptor instead.
+func (*ReportCorruptionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReportCorruptionRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ReportCorruptionRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *ReportCorruptionRequest) GetBucketId() string {
+	if x != nil {
+		return x.BucketId
+	}
+	return ""
+}
+
+func (x *ReportCorruptionRequest) GetOsdAddress() string {
+	if x != nil {
+		return x.OsdAddress
+	}
+	return ""
+}
+
+type ReportCorruptionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportCorruptionResponse) Reset() {
+	*x = ReportCorruptionResponse{}
+	mi := &file_proto_master_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportCorruptionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportCorruptionResponse) ProtoMessage() {}
+
+func (x *ReportCorruptionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportCorruptionResponse.ProtoReflect.Descriptor instead.
+func (*ReportCorruptionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ReportCorruptionResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportCorruptionResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetShardMapRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShardMapRequest) Reset() {
+	*x = GetShardMapRequest{}
+	mi := &file_proto_master_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShardMapRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShardMapRequest) ProtoMessage() {}
+
+func (x *GetShardMapRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShardMapRequest.ProtoReflect.Descriptor instead.
+func (*GetShardMapRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{23}
+}
+
+// IndexShard is a range of block hashes served by one index node
+type IndexShard struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         string                 `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"` // inclusive; empty for the start of the hash space
+	End           string                 `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`     // exclusive; empty for the end of the hash space
+	Address       string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"` // set while the range moves to address; requests go here until the move completes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexShard) Reset() {
+	*x = IndexShard{}
+	mi := &file_proto_master_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexShard) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexShard) ProtoMessage() {}
+
+func (x *IndexShard) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexShard.ProtoReflect.Descriptor instead.
+func (*IndexShard) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *IndexShard) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *IndexShard) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+func (x *IndexShard) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *IndexShard) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type GetShardMapResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int64                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Shards        []*IndexShard          `protobuf:"bytes,2,rep,name=shards,proto3" json:"shards,omitempty"` // sorted by start; empty when the index is not sharded
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShardMapResponse) Reset() {
+	*x = GetShardMapResponse{}
+	mi := &file_proto_master_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShardMapResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShardMapResponse) ProtoMessage() {}
+
+func (x *GetShardMapResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShardMapResponse.ProtoReflect.Descriptor instead.
+func (*GetShardMapResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetShardMapResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *GetShardMapResponse) GetShards() []*IndexShard {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+type AddIndexShardRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"` // index node to move half of the widest range to
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddIndexShardRequest) Reset() {
+	*x = AddIndexShardRequest{}
+	mi := &file_proto_master_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddIndexShardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddIndexShardRequest) ProtoMessage() {}
+
+func (x *AddIndexShardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddIndexShardRequest.ProtoReflect.Descriptor instead.
+func (*AddIndexShardRequest) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *AddIndexShardRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type AddIndexShardResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Start         string                 `protobuf:"bytes,3,opt,name=start,proto3" json:"start,omitempty"` // range being moved to the new node
+	End           string                 `protobuf:"bytes,4,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddIndexShardResponse) Reset() {
+	*x = AddIndexShardResponse{}
+	mi := &file_proto_master_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddIndexShardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddIndexShardResponse) ProtoMessage() {}
+
+func (x *AddIndexShardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_master_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddIndexShardResponse.ProtoReflect.Descriptor instead.
+func (*AddIndexShardResponse) Descriptor() ([]byte, []int) {
+	return file_proto_master_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *AddIndexShardResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AddIndexShardResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *AddIndexShardResponse) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *AddIndexShardResponse) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+var File_proto_master_proto protoreflect.FileDescriptor
+
+const file_proto_master_proto_rawDesc = "" +
+	"\n" +
+	"\x12proto/master.proto\x12\x06master\"\x90\x01\n" +
+	"\x12RegisterOSDRequest\x12\x1f\n" +
+	"\vosd_address\x18\x01 \x01(\tR\n" +
+	"osdAddress\x12\x17\n" +
+	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12'\n" +
+	"\x0favailable_space\x18\x03 \x01(\x03R\x0eavailableSpace\x12\x17\n" +
+	"\azone_id\x18\x04 \x01(\tR\x06zoneId\"E\n" +
+	"\x13RegisterOSDResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xa8\x01\n" +
+	"\x10HeartbeatRequest\x12\x1f\n" +
+	"\vosd_address\x18\x01 \x01(\tR\n" +
+	"osdAddress\x12\x18\n" +
+	"\ahealthy\x18\x02 \x01(\bR\ahealthy\x12'\n" +
+	"\x0favailable_space\x18\x03 \x01(\x03R\x0eavailableSpace\x12\x17\n" +
+	"\acell_id\x18\x04 \x01(\tR\x06cellId\x12\x17\n" +
+	"\azone_id\x18\x05 \x01(\tR\x06zoneId\"-\n" +
+	"\x11HeartbeatResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"0\n" +
+	"\x15GetOpenVolumesRequest\x12\x17\n" +
+	"\acell_id\x18\x01 \x01(\tR\x06cellId\"7\n" +
+	"\x16GetOpenVolumesResponse\x12\x1d\n" +
+	"\n" +
+	"volume_ids\x18\x01 \x03(\tR\tvolumeIds\"1\n" +
+	"\x12CloseVolumeRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"E\n" +
+	"\x13CloseVolumeResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"D\n" +
+	"\x14TriggerRepairRequest\x12,\n" +
+	"\x12failed_osd_address\x18\x01 \x01(\tR\x10failedOsdAddress\"G\n" +
+	"\x15TriggerRepairResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"_\n" +
+	"\x15AllocateVolumeRequest\x12\x17\n" +
+	"\acell_id\x18\x01 \x01(\tR\x06cellId\x12-\n" +
+	"\x12replication_factor\x18\x02 \x01(\x05R\x11replicationFactor\"\x8a\x01\n" +
+	"\x16AllocateVolumeResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1b\n" +
+	"\tvolume_id\x18\x02 \x01(\tR\bvolumeId\x12#\n" +
+	"\rosd_addresses\x18\x03 \x03(\tR\fosdAddresses\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"N\n" +
+	"\x0fDrainOSDRequest\x12\x1f\n" +
+	"\vosd_address\x18\x01 \x01(\tR\n" +
+	"osdAddress\x12\x1a\n" +
+	"\bdraining\x18\x02 \x01(\bR\bdraining\"B\n" +
+	"\x10DrainOSDResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x8f\x01\n" +
+	"\x1cReportUnderReplicatedRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x1b\n" +
+	"\tvolume_id\x18\x02 \x01(\tR\bvolumeId\x12\x1b\n" +
+	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12!\n" +
+	"\fmissing_osds\x18\x04 \x03(\tR\vmissingOsds\"O\n" +
+	"\x1dReportUnderReplicatedResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"*\n" +
+	"\x0fListOSDsRequest\x12\x17\n" +
+	"\acell_id\x18\x01 \x01(\tR\x06cellId\"\xb6\x01\n" +
+	"\tOSDStatus\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x17\n" +
+	"\acell_id\x18\x02 \x01(\tR\x06cellId\x12\x17\n" +
+	"\azone_id\x18\x03 \x01(\tR\x06zoneId\x12\x18\n" +
+	"\ahealthy\x18\x04 \x01(\bR\ahealthy\x12\x1a\n" +
+	"\bdraining\x18\x05 \x01(\bR\bdraining\x12'\n" +
+	"\x0favailable_space\x18\x06 \x01(\x03R\x0eavailableSpace\"9\n" +
+	"\x10ListOSDsResponse\x12%\n" +
+	"\x04osds\x18\x01 \x03(\v2\x11.master.OSDStatusR\x04osds\"q\n" +
+	"\x13ReserveSpaceRequest\x12\x17\n" +
+	"\acell_id\x18\x01 \x01(\tR\x06cellId\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12-\n" +
+	"\x12replication_factor\x18\x03 \x01(\x05R\x11replicationFactor\"\xa5\x01\n" +
+	"\x14ReserveSpaceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1b\n" +
+	"\tvolume_id\x18\x02 \x01(\tR\bvolumeId\x12\x1b\n" +
+	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12#\n" +
+	"\rosd_addresses\x18\x04 \x03(\tR\fosdAddresses\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"\x88\x01\n" +
+	"\x17ReportCorruptionRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x1b\n" +
+	"\tvolume_id\x18\x02 \x01(\tR\bvolumeId\x12\x1b\n" +
+	"\tbucket_id\x18\x03 \x01(\tR\bbucketId\x12\x1f\n" +
+	"\vosd_address\x18\x04 \x01(\tR\n" +
+	"osdAddress\"J\n" +
+	"\x18ReportCorruptionResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x14\n" +
+	"\x12GetShardMapRequest\"f\n" +
+	"\n" +
+	"IndexShard\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\tR\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\tR\x03end\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\"[\n" +
+	"\x13GetShardMapResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x03R\aversion\x12*\n" +
+	"\x06shards\x18\x02 \x03(\v2\x12.master.IndexShardR\x06shards\"0\n" +
+	"\x14AddIndexShardRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"o\n" +
+	"\x15AddIndexShardResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error2\x80\x03\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x14\n" +
+	"\x05start\x18\x03 \x01(\tR\x05start\x12\x10\n" +
+	"\x03end\x18\x04 \x01(\tR\x03end2\xed\a\n" +
 	"\rMasterService\x12F\n" +
 	"\vRegisterOSD\x12\x1a.master.RegisterOSDRequest\x1a\x1b.master.RegisterOSDResponse\x12@\n" +
 	"\tHeartbeat\x12\x18.master.HeartbeatRequest\x1a\x19.master.HeartbeatResponse\x12O\n" +
 	"\x0eGetOpenVolumes\x12\x1d.master.GetOpenVolumesRequest\x1a\x1e.master.GetOpenVolumesResponse\x12F\n" +
 	"\vCloseVolume\x12\x1a.master.CloseVolumeRequest\x1a\x1b.master.CloseVolumeResponse\x12L\n" +
-	"\rTriggerRepair\x12\x1c.master.TriggerRepairRequest\x1a\x1d.master.TriggerRepairResponseB\x16Z\x14bharani/proto/masterb\x06proto3"
+	"\rTriggerRepair\x12\x1c.master.TriggerRepairRequest\x1a\x1d.master.TriggerRepairResponse\x12O\n" +
+	"\x0eAllocateVolume\x12\x1d.master.AllocateVolumeRequest\x1a\x1e.master.AllocateVolumeResponse\x12=\n" +
+	"\bDrainOSD\x12\x17.master.DrainOSDRequest\x1a\x18.master.DrainOSDResponse\x12d\n" +
+	"\x15ReportUnderReplicated\x12$.master.ReportUnderReplicatedRequest\x1a%.master.ReportUnderReplicatedResponse\x12=\n" +
+	"\bListOSDs\x12\x17.master.ListOSDsRequest\x1a\x18.master.ListOSDsResponse\x12I\n" +
+	"\fReserveSpace\x12\x1b.master.ReserveSpaceRequest\x1a\x1c.master.ReserveSpaceResponse\x12U\n" +
+	"\x10ReportCorruption\x12\x1f.master.ReportCorruptionRequest\x1a .master.ReportCorruptionResponse\x12F\n" +
+	"\vGetShardMap\x12\x1a.master.GetShardMapRequest\x1a\x1b.master.GetShardMapResponse\x12L\n" +
+	"\rAddIndexShard\x12\x1c.master.AddIndexShardRequest\x1a\x1d.master.AddIndexShardResponseB\x16Z\x14bharani/proto/masterb\x06proto3"
 
 var (
 	file_proto_master_proto_rawDescOnce sync.Once
@@ -571,35 +1717,71 @@ func file_proto_master_proto_rawDescGZIP() []byte {
 	return file_proto_master_proto_rawDescData
 }
 
-var file_proto_master_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_proto_master_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_proto_master_proto_goTypes = []any{
-	(*RegisterOSDRequest)(nil),     // 0: master.RegisterOSDRequest
-	(*RegisterOSDResponse)(nil),    // 1: master.RegisterOSDResponse
-	(*HeartbeatRequest)(nil),       // 2: master.HeartbeatRequest
-	(*HeartbeatResponse)(nil),      // 3: master.HeartbeatResponse
-	(*GetOpenVolumesRequest)(nil),  // 4: master.GetOpenVolumesRequest
-	(*GetOpenVolumesResponse)(nil), // 5: master.GetOpenVolumesResponse
-	(*CloseVolumeRequest)(nil),     // 6: master.CloseVolumeRequest
-	(*CloseVolumeResponse)(nil),    // 7: master.CloseVolumeResponse
-	(*TriggerRepairRequest)(nil),   // 8: master.TriggerRepairRequest
-	(*TriggerRepairResponse)(nil),  // 9: master.TriggerRepairResponse
+	(*RegisterOSDRequest)(nil),            // 0: master.RegisterOSDRequest
+	(*RegisterOSDResponse)(nil),           // 1: master.RegisterOSDResponse
+	(*HeartbeatRequest)(nil),              // 2: master.HeartbeatRequest
+	(*HeartbeatResponse)(nil),             // 3: master.HeartbeatResponse
+	(*GetOpenVolumesRequest)(nil),         // 4: master.GetOpenVolumesRequest
+	(*GetOpenVolumesResponse)(nil),        // 5: master.GetOpenVolumesResponse
+	(*CloseVolumeRequest)(nil),            // 6: master.CloseVolumeRequest
+	(*CloseVolumeResponse)(nil),           // 7: master.CloseVolumeResponse
+	(*TriggerRepairRequest)(nil),          // 8: master.TriggerRepairRequest
+	(*TriggerRepairResponse)(nil),         // 9: master.TriggerRepairResponse
+	(*AllocateVolumeRequest)(nil),         // 10: master.AllocateVolumeRequest
+	(*AllocateVolumeResponse)(nil),        // 11: master.AllocateVolumeResponse
+	(*DrainOSDRequest)(nil),               // 12: master.DrainOSDRequest
+	(*DrainOSDResponse)(nil),              // 13: master.DrainOSDResponse
+	(*ReportUnderReplicatedRequest)(nil),  // 14: master.ReportUnderReplicatedRequest
+	(*ReportUnderReplicatedResponse)(nil), // 15: master.ReportUnderReplicatedResponse
+	(*ListOSDsRequest)(nil),               // 16: master.ListOSDsRequest
+	(*OSDStatus)(nil),                     // 17: master.OSDStatus
+	(*ListOSDsResponse)(nil),              // 18: master.ListOSDsResponse
+	(*ReserveSpaceRequest)(nil),           // 19: master.ReserveSpaceRequest
+	(*ReserveSpaceResponse)(nil),          // 20: master.ReserveSpaceResponse
+	(*ReportCorruptionRequest)(nil),       // 21: master.ReportCorruptionRequest
+	(*ReportCorruptionResponse)(nil),      // 22: master.ReportCorruptionResponse
+	(*GetShardMapRequest)(nil),            // 23: master.GetShardMapRequest
+	(*IndexShard)(nil),                    // 24: master.IndexShard
+	(*GetShardMapResponse)(nil),           // 25: master.GetShardMapResponse
+	(*AddIndexShardRequest)(nil),          // 26: master.AddIndexShardRequest
+	(*AddIndexShardResponse)(nil),         // 27: master.AddIndexShardResponse
 }
 var file_proto_master_proto_depIdxs = []int32{
-	0, // 0: master.MasterService.RegisterOSD:input_type -> master.RegisterOSDRequest
-	2, // 1: master.MasterService.Heartbeat:input_type -> master.HeartbeatRequest
-	4, // 2: master.MasterService.GetOpenVolumes:input_type -> master.GetOpenVolumesRequest
-	6, // 3: master.MasterService.CloseVolume:input_type -> master.CloseVolumeRequest
-	8, // 4: master.MasterService.TriggerRepair:input_type -> master.TriggerRepairRequest
-	1, // 5: master.MasterService.RegisterOSD:output_type -> master.RegisterOSDResponse
-	3, // 6: master.MasterService.Heartbeat:output_type -> master.HeartbeatResponse
-	5, // 7: master.MasterService.GetOpenVolumes:output_type -> master.GetOpenVolumesResponse
-	7, // 8: master.MasterService.CloseVolume:output_type -> master.CloseVolumeResponse
-	9, // 9: master.MasterService.TriggerRepair:output_type -> master.TriggerRepairResponse
-	5, // [5:10] is the sub-list for method output_type
-	0, // [0:5] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	17, // 0: master.ListOSDsResponse.osds:type_name -> master.OSDStatus
+	24, // 1: master.GetShardMapResponse.shards:type_name -> master.IndexShard
+	0,  // 2: master.MasterService.RegisterOSD:input_type -> master.RegisterOSDRequest
+	2,  // 3: master.MasterService.Heartbeat:input_type -> master.HeartbeatRequest
+	4,  // 4: master.MasterService.GetOpenVolumes:input_type -> master.GetOpenVolumesRequest
+	6,  // 5: master.MasterService.CloseVolume:input_type -> master.CloseVolumeRequest
+	8,  // 6: master.MasterService.TriggerRepair:input_type -> master.TriggerRepairRequest
+	10, // 7: master.MasterService.AllocateVolume:input_type -> master.AllocateVolumeRequest
+	12, // 8: master.MasterService.DrainOSD:input_type -> master.DrainOSDRequest
+	14, // 9: master.MasterService.ReportUnderReplicated:input_type -> master.ReportUnderReplicatedRequest
+	16, // 10: master.MasterService.ListOSDs:input_type -> master.ListOSDsRequest
+	19, // 11: master.MasterService.ReserveSpace:input_type -> master.ReserveSpaceRequest
+	21, // 12: master.MasterService.ReportCorruption:input_type -> master.ReportCorruptionRequest
+	23, // 13: master.MasterService.GetShardMap:input_type -> master.GetShardMapRequest
+	26, // 14: master.MasterService.AddIndexShard:input_type -> master.AddIndexShardRequest
+	1,  // 15: master.MasterService.RegisterOSD:output_type -> master.RegisterOSDResponse
+	3,  // 16: master.MasterService.Heartbeat:output_type -> master.HeartbeatResponse
+	5,  // 17: master.MasterService.GetOpenVolumes:output_type -> master.GetOpenVolumesResponse
+	7,  // 18: master.MasterService.CloseVolume:output_type -> master.CloseVolumeResponse
+	9,  // 19: master.MasterService.TriggerRepair:output_type -> master.TriggerRepairResponse
+	11, // 20: master.MasterService.AllocateVolume:output_type -> master.AllocateVolumeResponse
+	13, // 21: master.MasterService.DrainOSD:output_type -> master.DrainOSDResponse
+	15, // 22: master.MasterService.ReportUnderReplicated:output_type -> master.ReportUnderReplicatedResponse
+	18, // 23: master.MasterService.ListOSDs:output_type -> master.ListOSDsResponse
+	20, // 24: master.MasterService.ReserveSpace:output_type -> master.ReserveSpaceResponse
+	22, // 25: master.MasterService.ReportCorruption:output_type -> master.ReportCorruptionResponse
+	25, // 26: master.MasterService.GetShardMap:output_type -> master.GetShardMapResponse
+	27, // 27: master.MasterService.AddIndexShard:output_type -> master.AddIndexShardResponse
+	15, // [15:28] is the sub-list for method output_type
+	2,  // [2:15] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_master_proto_init() }
@@ -613,7 +1795,7 @@ func file_proto_master_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_master_proto_rawDesc), len(file_proto_master_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,11 +19,19 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MasterService_RegisterOSD_FullMethodName    = "/master.MasterService/RegisterOSD"
-	MasterService_Heartbeat_FullMethodName      = "/master.MasterService/Heartbeat"
-	MasterService_GetOpenVolumes_FullMethodName = "/master.MasterService/GetOpenVolumes"
-	MasterService_CloseVolume_FullMethodName    = "/master.MasterService/CloseVolume"
-	MasterService_TriggerRepair_FullMethodName  = "/master.MasterService/TriggerRepair"
+	MasterService_RegisterOSD_FullMethodName           = "/master.MasterService/RegisterOSD"
+	MasterService_Heartbeat_FullMethodName             = "/master.MasterService/Heartbeat"
+	MasterService_GetOpenVolumes_FullMethodName        = "/master.MasterService/GetOpenVolumes"
+	MasterService_CloseVolume_FullMethodName           = "/master.MasterService/CloseVolume"
+	MasterService_TriggerRepair_FullMethodName         = "/master.MasterService/TriggerRepair"
+	MasterService_AllocateVolume_FullMethodName        = "/master.MasterService/AllocateVolume"
+	MasterService_DrainOSD_FullMethodName              = "/master.MasterService/DrainOSD"
+	MasterService_ReportUnderReplicated_FullMethodName = "/master.MasterService/ReportUnderReplicated"
+	MasterService_ListOSDs_FullMethodName              = "/master.MasterService/ListOSDs"
+	MasterService_ReserveSpace_FullMethodName          = "/master.MasterService/ReserveSpace"
+	MasterService_ReportCorruption_FullMethodName      = "/master.MasterService/ReportCorruption"
+	MasterService_GetShardMap_FullMethodName           = "/master.MasterService/GetShardMap"
+	MasterService_AddIndexShard_FullMethodName         = "/master.MasterService/AddIndexShard"
 )
 
 // MasterServiceClient is the client API for MasterService service.
@@ -37,6 +45,14 @@ type MasterServiceClient interface {
 	GetOpenVolumes(ctx context.Context, in *GetOpenVolumesRequest, opts ...grpc.CallOption) (*GetOpenVolumesResponse, error)
 	CloseVolume(ctx context.Context, in *CloseVolumeRequest, opts ...grpc.CallOption) (*CloseVolumeResponse, error)
 	TriggerRepair(ctx context.Context, in *TriggerRepairRequest, opts ...grpc.CallOption) (*TriggerRepairResponse, error)
+	AllocateVolume(ctx context.Context, in *AllocateVolumeRequest, opts ...grpc.CallOption) (*AllocateVolumeResponse, error)
+	DrainOSD(ctx context.Context, in *DrainOSDRequest, opts ...grpc.CallOption) (*DrainOSDResponse, error)
+	ReportUnderReplicated(ctx context.Context, in *ReportUnderReplicatedRequest, opts ...grpc.CallOption) (*ReportUnderReplicatedResponse, error)
+	ListOSDs(ctx context.Context, in *ListOSDsRequest, opts ...grpc.CallOption) (*ListOSDsResponse, error)
+	ReserveSpace(ctx context.Context, in *ReserveSpaceRequest, opts ...grpc.CallOption) (*ReserveSpaceResponse, error)
+	ReportCorruption(ctx context.Context, in *ReportCorruptionRequest, opts ...grpc.CallOption) (*ReportCorruptionResponse, error)
+	GetShardMap(ctx context.Context, in *GetShardMapRequest, opts ...grpc.CallOption) (*GetShardMapResponse, error)
+	AddIndexShard(ctx context.Context, in *AddIndexShardRequest, opts ...grpc.CallOption) (*AddIndexShardResponse, error)
 }
 
 type masterServiceClient struct {
@@ -97,6 +113,86 @@ func (c *masterServiceClient) TriggerRepair(ctx context.Context, in *TriggerRepa
 	return out, nil
 }
 
+func (c *masterServiceClient) AllocateVolume(ctx context.Context, in *AllocateVolumeRequest, opts ...grpc.CallOption) (*AllocateVolumeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AllocateVolumeResponse)
+	err := c.cc.Invoke(ctx, MasterService_AllocateVolume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) DrainOSD(ctx context.Context, in *DrainOSDRequest, opts ...grpc.CallOption) (*DrainOSDResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainOSDResponse)
+	err := c.cc.Invoke(ctx, MasterService_DrainOSD_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) ReportUnderReplicated(ctx context.Context, in *ReportUnderReplicatedRequest, opts ...grpc.CallOption) (*ReportUnderReplicatedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportUnderReplicatedResponse)
+	err := c.cc.Invoke(ctx, MasterService_ReportUnderReplicated_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) ListOSDs(ctx context.Context, in *ListOSDsRequest, opts ...grpc.CallOption) (*ListOSDsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListOSDsResponse)
+	err := c.cc.Invoke(ctx, MasterService_ListOSDs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) ReserveSpace(ctx context.Context, in *ReserveSpaceRequest, opts ...grpc.CallOption) (*ReserveSpaceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReserveSpaceResponse)
+	err := c.cc.Invoke(ctx, MasterService_ReserveSpace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) ReportCorruption(ctx context.Context, in *ReportCorruptionRequest, opts ...grpc.CallOption) (*ReportCorruptionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportCorruptionResponse)
+	err := c.cc.Invoke(ctx, MasterService_ReportCorruption_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) GetShardMap(ctx context.Context, in *GetShardMapRequest, opts ...grpc.CallOption) (*GetShardMapResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetShardMapResponse)
+	err := c.cc.Invoke(ctx, MasterService_GetShardMap_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) AddIndexShard(ctx context.Context, in *AddIndexShardRequest, opts ...grpc.CallOption) (*AddIndexShardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddIndexShardResponse)
+	err := c.cc.Invoke(ctx, MasterService_AddIndexShard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MasterServiceServer is the server API for MasterService service.
 // All implementations should embed UnimplementedMasterServiceServer
 // for forward compatibility.
@@ -108,6 +204,14 @@ type MasterServiceServer interface {
 	GetOpenVolumes(context.Context, *GetOpenVolumesRequest) (*GetOpenVolumesResponse, error)
 	CloseVolume(context.Context, *CloseVolumeRequest) (*CloseVolumeResponse, error)
 	TriggerRepair(context.Context, *TriggerRepairRequest) (*TriggerRepairResponse, error)
+	AllocateVolume(context.Context, *AllocateVolumeRequest) (*AllocateVolumeResponse, error)
+	DrainOSD(context.Context, *DrainOSDRequest) (*DrainOSDResponse, error)
+	ReportUnderReplicated(context.Context, *ReportUnderReplicatedRequest) (*ReportUnderReplicatedResponse, error)
+	ListOSDs(context.Context, *ListOSDsRequest) (*ListOSDsResponse, error)
+	ReserveSpace(context.Context, *ReserveSpaceRequest) (*ReserveSpaceResponse, error)
+	ReportCorruption(context.Context, *ReportCorruptionRequest) (*ReportCorruptionResponse, error)
+	GetShardMap(context.Context, *GetShardMapRequest) (*GetShardMapResponse, error)
+	AddIndexShard(context.Context, *AddIndexShardRequest) (*AddIndexShardResponse, error)
 }
 
 // UnimplementedMasterServiceServer should be embedded to have
@@ -132,6 +236,30 @@ func (UnimplementedMasterServiceServer) CloseVolume(context.Context, *CloseVolum
 func (UnimplementedMasterServiceServer) TriggerRepair(context.Context, *TriggerRepairRequest) (*TriggerRepairResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method TriggerRepair not implemented")
 }
+func (UnimplementedMasterServiceServer) AllocateVolume(context.Context, *AllocateVolumeRequest) (*AllocateVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AllocateVolume not implemented")
+}
+func (UnimplementedMasterServiceServer) DrainOSD(context.Context, *DrainOSDRequest) (*DrainOSDResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DrainOSD not implemented")
+}
+func (UnimplementedMasterServiceServer) ReportUnderReplicated(context.Context, *ReportUnderReplicatedRequest) (*ReportUnderReplicatedResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReportUnderReplicated not implemented")
+}
+func (UnimplementedMasterServiceServer) ListOSDs(context.Context, *ListOSDsRequest) (*ListOSDsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListOSDs not implemented")
+}
+func (UnimplementedMasterServiceServer) ReserveSpace(context.Context, *ReserveSpaceRequest) (*ReserveSpaceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReserveSpace not implemented")
+}
+func (UnimplementedMasterServiceServer) ReportCorruption(context.Context, *ReportCorruptionRequest) (*ReportCorruptionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReportCorruption not implemented")
+}
+func (UnimplementedMasterServiceServer) GetShardMap(context.Context, *GetShardMapRequest) (*GetShardMapResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetShardMap not implemented")
+}
+func (UnimplementedMasterServiceServer) AddIndexShard(context.Context, *AddIndexShardRequest) (*AddIndexShardResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddIndexShard not implemented")
+}
 func (UnimplementedMasterServiceServer) testEmbeddedByValue() {}
 
 // UnsafeMasterServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -242,6 +370,150 @@ func _MasterService_TriggerRepair_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MasterService_AllocateVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateVolumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).AllocateVolume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_AllocateVolume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).AllocateVolume(ctx, req.(*AllocateVolumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_DrainOSD_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainOSDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).DrainOSD(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_DrainOSD_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).DrainOSD(ctx, req.(*DrainOSDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_ReportUnderReplicated_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportUnderReplicatedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).ReportUnderReplicated(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_ReportUnderReplicated_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).ReportUnderReplicated(ctx, req.(*ReportUnderReplicatedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_ListOSDs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOSDsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).ListOSDs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_ListOSDs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).ListOSDs(ctx, req.(*ListOSDsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_ReserveSpace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveSpaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).ReserveSpace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_ReserveSpace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).ReserveSpace(ctx, req.(*ReserveSpaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_ReportCorruption_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportCorruptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).ReportCorruption(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_ReportCorruption_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).ReportCorruption(ctx, req.(*ReportCorruptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_GetShardMap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShardMapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).GetShardMap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_GetShardMap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).GetShardMap(ctx, req.(*GetShardMapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_AddIndexShard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddIndexShardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).AddIndexShard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_AddIndexShard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).AddIndexShard(ctx, req.(*AddIndexShardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MasterService_ServiceDesc is the grpc.ServiceDesc for MasterService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -269,6 +541,38 @@ var MasterService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "TriggerRepair",
 			Handler:    _MasterService_TriggerRepair_Handler,
 		},
+		{
+			MethodName: "AllocateVolume",
+			Handler:    _MasterService_AllocateVolume_Handler,
+		},
+		{
+			MethodName: "DrainOSD",
+			Handler:    _MasterService_DrainOSD_Handler,
+		},
+		{
+			MethodName: "ReportUnderReplicated",
+			Handler:    _MasterService_ReportUnderReplicated_Handler,
+		},
+		{
+			MethodName: "ListOSDs",
+			Handler:    _MasterService_ListOSDs_Handler,
+		},
+		{
+			MethodName: "ReserveSpace",
+			Handler:    _MasterService_ReserveSpace_Handler,
+		},
+		{
+			MethodName: "ReportCorruption",
+			Handler:    _MasterService_ReportCorruption_Handler,
+		},
+		{
+			MethodName: "GetShardMap",
+			Handler:    _MasterService_GetShardMap_Handler,
+		},
+		{
+			MethodName: "AddIndexShard",
+			Handler:    _MasterService_AddIndexShard_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/master.proto",
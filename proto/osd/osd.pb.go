@@ -349,6 +349,118 @@ func (x *HealthCheckResponse) GetStatus() string {
 	return ""
 }
 
+type DeleteBlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	BucketId      string                 `protobuf:"bytes,2,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
+	VolumeId      string                 `protobuf:"bytes,3,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBlockRequest) Reset() {
+	*x = DeleteBlockRequest{}
+	mi := &file_proto_osd_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBlockRequest) ProtoMessage() {}
+
+func (x *DeleteBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_osd_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBlockRequest.ProtoReflect.Descriptor instead.
+func (*DeleteBlockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_osd_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteBlockRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *DeleteBlockRequest) GetBucketId() string {
+	if x != nil {
+		return x.BucketId
+	}
+	return ""
+}
+
+func (x *DeleteBlockRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+type DeleteBlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"` // also true if the block was already absent
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBlockResponse) Reset() {
+	*x = DeleteBlockResponse{}
+	mi := &file_proto_osd_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBlockResponse) ProtoMessage() {}
+
+func (x *DeleteBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_osd_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBlockResponse.ProtoReflect.Descriptor instead.
+func (*DeleteBlockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_osd_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteBlockResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteBlockResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 var File_proto_osd_proto protoreflect.FileDescriptor
 
 const file_proto_osd_proto_rawDesc = "" +
@@ -373,12 +485,20 @@ const file_proto_osd_proto_rawDesc = "" +
 	"\x12HealthCheckRequest\"G\n" +
 	"\x13HealthCheckResponse\x12\x18\n" +
 	"\ahealthy\x18\x01 \x01(\bR\ahealthy\x12\x16\n" +
-	"\x06status\x18\x02 \x01(\tR\x06status2\xc0\x01\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"b\n" +
+	"\x12DeleteBlockRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x1b\n" +
+	"\tbucket_id\x18\x02 \x01(\tR\bbucketId\x12\x1b\n" +
+	"\tvolume_id\x18\x03 \x01(\tR\bvolumeId\"E\n" +
+	"\x13DeleteBlockResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error2\x82\x02\n" +
 	"\n" +
 	"OSDService\x127\n" +
 	"\bPutBlock\x12\x14.osd.PutBlockRequest\x1a\x15.osd.PutBlockResponse\x127\n" +
 	"\bGetBlock\x12\x14.osd.GetBlockRequest\x1a\x15.osd.GetBlockResponse\x12@\n" +
-	"\vHealthCheck\x12\x17.osd.HealthCheckRequest\x1a\x18.osd.HealthCheckResponseB\x13Z\x11bharani/proto/osdb\x06proto3"
+	"\vHealthCheck\x12\x17.osd.HealthCheckRequest\x1a\x18.osd.HealthCheckResponse\x12@\n" +
+	"\vDeleteBlock\x12\x17.osd.DeleteBlockRequest\x1a\x18.osd.DeleteBlockResponseB\x13Z\x11bharani/proto/osdb\x06proto3"
 
 var (
 	file_proto_osd_proto_rawDescOnce sync.Once
@@ -392,7 +512,7 @@ func file_proto_osd_proto_rawDescGZIP() []byte {
 	return file_proto_osd_proto_rawDescData
 }
 
-var file_proto_osd_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_osd_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_proto_osd_proto_goTypes = []any{
 	(*PutBlockRequest)(nil),     // 0: osd.PutBlockRequest
 	(*PutBlockResponse)(nil),    // 1: osd.PutBlockResponse
@@ -400,16 +520,20 @@ var file_proto_osd_proto_goTypes = []any{
 	(*GetBlockResponse)(nil),    // 3: osd.GetBlockResponse
 	(*HealthCheckRequest)(nil),  // 4: osd.HealthCheckRequest
 	(*HealthCheckResponse)(nil), // 5: osd.HealthCheckResponse
+	(*DeleteBlockRequest)(nil),  // 6: osd.DeleteBlockRequest
+	(*DeleteBlockResponse)(nil), // 7: osd.DeleteBlockResponse
 }
 var file_proto_osd_proto_depIdxs = []int32{
 	0, // 0: osd.OSDService.PutBlock:input_type -> osd.PutBlockRequest
 	2, // 1: osd.OSDService.GetBlock:input_type -> osd.GetBlockRequest
 	4, // 2: osd.OSDService.HealthCheck:input_type -> osd.HealthCheckRequest
-	1, // 3: osd.OSDService.PutBlock:output_type -> osd.PutBlockResponse
-	3, // 4: osd.OSDService.GetBlock:output_type -> osd.GetBlockResponse
-	5, // 5: osd.OSDService.HealthCheck:output_type -> osd.HealthCheckResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
+	6, // 3: osd.OSDService.DeleteBlock:input_type -> osd.DeleteBlockRequest
+	1, // 4: osd.OSDService.PutBlock:output_type -> osd.PutBlockResponse
+	3, // 5: osd.OSDService.GetBlock:output_type -> osd.GetBlockResponse
+	5, // 6: osd.OSDService.HealthCheck:output_type -> osd.HealthCheckResponse
+	7, // 7: osd.OSDService.DeleteBlock:output_type -> osd.DeleteBlockResponse
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -426,7 +550,7 @@ func file_proto_osd_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_osd_proto_rawDesc), len(file_proto_osd_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -22,6 +22,7 @@ const (
 	OSDService_PutBlock_FullMethodName    = "/osd.OSDService/PutBlock"
 	OSDService_GetBlock_FullMethodName    = "/osd.OSDService/GetBlock"
 	OSDService_HealthCheck_FullMethodName = "/osd.OSDService/HealthCheck"
+	OSDService_DeleteBlock_FullMethodName = "/osd.OSDService/DeleteBlock"
 )
 
 // OSDServiceClient is the client API for OSDService service.
@@ -33,6 +34,7 @@ type OSDServiceClient interface {
 	PutBlock(ctx context.Context, in *PutBlockRequest, opts ...grpc.CallOption) (*PutBlockResponse, error)
 	GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error)
 	HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
+	DeleteBlock(ctx context.Context, in *DeleteBlockRequest, opts ...grpc.CallOption) (*DeleteBlockResponse, error)
 }
 
 type oSDServiceClient struct {
@@ -73,6 +75,16 @@ func (c *oSDServiceClient) HealthCheck(ctx context.Context, in *HealthCheckReque
 	return out, nil
 }
 
+func (c *oSDServiceClient) DeleteBlock(ctx context.Context, in *DeleteBlockRequest, opts ...grpc.CallOption) (*DeleteBlockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteBlockResponse)
+	err := c.cc.Invoke(ctx, OSDService_DeleteBlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OSDServiceServer is the server API for OSDService service.
 // All implementations should embed UnimplementedOSDServiceServer
 // for forward compatibility.
@@ -82,6 +94,7 @@ type OSDServiceServer interface {
 	PutBlock(context.Context, *PutBlockRequest) (*PutBlockResponse, error)
 	GetBlock(context.Context, *GetBlockRequest) (*GetBlockResponse, error)
 	HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
+	DeleteBlock(context.Context, *DeleteBlockRequest) (*DeleteBlockResponse, error)
 }
 
 // UnimplementedOSDServiceServer should be embedded to have
@@ -100,6 +113,9 @@ func (UnimplementedOSDServiceServer) GetBlock(context.Context, *GetBlockRequest)
 func (UnimplementedOSDServiceServer) HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method HealthCheck not implemented")
 }
+func (UnimplementedOSDServiceServer) DeleteBlock(context.Context, *DeleteBlockRequest) (*DeleteBlockResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteBlock not implemented")
+}
 func (UnimplementedOSDServiceServer) testEmbeddedByValue() {}
 
 // UnsafeOSDServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -174,6 +190,24 @@ func _OSDService_HealthCheck_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OSDService_DeleteBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OSDServiceServer).DeleteBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OSDService_DeleteBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OSDServiceServer).DeleteBlock(ctx, req.(*DeleteBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // OSDService_ServiceDesc is the grpc.ServiceDesc for OSDService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -193,6 +227,10 @@ var OSDService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "HealthCheck",
 			Handler:    _OSDService_HealthCheck_Handler,
 		},
+		{
+			MethodName: "DeleteBlock",
+			Handler:    _OSDService_DeleteBlock_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/osd.proto",
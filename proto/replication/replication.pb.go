@@ -26,6 +26,7 @@ type CreateVolumeRequest struct {
 	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
 	OsdAddresses  []string               `protobuf:"bytes,2,rep,name=osd_addresses,json=osdAddresses,proto3" json:"osd_addresses,omitempty"`
 	CellId        string                 `protobuf:"bytes,3,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix seconds; 0 is stamped with the time the table applies the call
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,6 +82,13 @@ func (x *CreateVolumeRequest) GetCellId() string {
 	return ""
 }
 
+func (x *CreateVolumeRequest) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
 type CreateVolumeResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -186,6 +194,9 @@ type GetVolumeResponse struct {
 	CellId        string                 `protobuf:"bytes,5,opt,name=cell_id,json=cellId,proto3" json:"cell_id,omitempty"`
 	State         string                 `protobuf:"bytes,6,opt,name=state,proto3" json:"state,omitempty"` // "open" or "closed"
 	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	UpdatedAt     int64                  `protobuf:"varint,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`    // unix seconds of the last change, e.g. closing the volume
+	DataShards    int32                  `protobuf:"varint,9,opt,name=data_shards,json=dataShards,proto3" json:"data_shards,omitempty"` // non-zero for erasure-coded volumes, whose osd_addresses hold shard i at index i
+	ParityShards  int32                  `protobuf:"varint,10,opt,name=parity_shards,json=parityShards,proto3" json:"parity_shards,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -269,12 +280,36 @@ func (x *GetVolumeResponse) GetError() string {
 	return ""
 }
 
+func (x *GetVolumeResponse) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+func (x *GetVolumeResponse) GetDataShards() int32 {
+	if x != nil {
+		return x.DataShards
+	}
+	return 0
+}
+
+func (x *GetVolumeResponse) GetParityShards() int32 {
+	if x != nil {
+		return x.ParityShards
+	}
+	return 0
+}
+
 type UpdateVolumeRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
 	OsdAddresses  []string               `protobuf:"bytes,2,rep,name=osd_addresses,json=osdAddresses,proto3" json:"osd_addresses,omitempty"`
 	Generation    int64                  `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
-	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"` // optional, "open" or "closed"
+	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`                              // optional, "open" or "closed"
+	DataShards    int32                  `protobuf:"varint,5,opt,name=data_shards,json=dataShards,proto3" json:"data_shards,omitempty"` // when set, the volume becomes erasure coded with osd_addresses in shard order
+	ParityShards  int32                  `protobuf:"varint,6,opt,name=parity_shards,json=parityShards,proto3" json:"parity_shards,omitempty"`
+	UpdatedAt     int64                  `protobuf:"varint,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // unix seconds; 0 is stamped with the time the table applies the call
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -337,6 +372,27 @@ func (x *UpdateVolumeRequest) GetState() string {
 	return ""
 }
 
+func (x *UpdateVolumeRequest) GetDataShards() int32 {
+	if x != nil {
+		return x.DataShards
+	}
+	return 0
+}
+
+func (x *UpdateVolumeRequest) GetParityShards() int32 {
+	if x != nil {
+		return x.ParityShards
+	}
+	return 0
+}
+
+func (x *UpdateVolumeRequest) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
 type UpdateVolumeResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -477,20 +533,118 @@ func (x *ListVolumesResponse) GetVolumeIds() []string {
 	return nil
 }
 
+type DeleteVolumeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteVolumeRequest) Reset() {
+	*x = DeleteVolumeRequest{}
+	mi := &file_proto_replication_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteVolumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteVolumeRequest) ProtoMessage() {}
+
+func (x *DeleteVolumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_replication_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteVolumeRequest.ProtoReflect.Descriptor instead.
+func (*DeleteVolumeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_replication_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteVolumeRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+type DeleteVolumeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteVolumeResponse) Reset() {
+	*x = DeleteVolumeResponse{}
+	mi := &file_proto_replication_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteVolumeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteVolumeResponse) ProtoMessage() {}
+
+func (x *DeleteVolumeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_replication_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteVolumeResponse.ProtoReflect.Descriptor instead.
+func (*DeleteVolumeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_replication_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteVolumeResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteVolumeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 var File_proto_replication_proto protoreflect.FileDescriptor
 
 const file_proto_replication_proto_rawDesc = "" +
 	"\n" +
-	"\x17proto/replication.proto\x12\vreplication\"p\n" +
+	"\x17proto/replication.proto\x12\vreplication\"\x8f\x01\n" +
 	"\x13CreateVolumeRequest\x12\x1b\n" +
 	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12#\n" +
 	"\rosd_addresses\x18\x02 \x03(\tR\fosdAddresses\x12\x17\n" +
-	"\acell_id\x18\x03 \x01(\tR\x06cellId\"F\n" +
+	"\acell_id\x18\x03 \x01(\tR\x06cellId\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\"F\n" +
 	"\x14CreateVolumeResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\"/\n" +
 	"\x10GetVolumeRequest\x12\x1b\n" +
-	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"\xd0\x01\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"\xb5\x02\n" +
 	"\x11GetVolumeResponse\x12\x14\n" +
 	"\x05found\x18\x01 \x01(\bR\x05found\x12\x1b\n" +
 	"\tvolume_id\x18\x02 \x01(\tR\bvolumeId\x12#\n" +
@@ -500,14 +654,25 @@ const file_proto_replication_proto_rawDesc = "" +
 	"generation\x12\x17\n" +
 	"\acell_id\x18\x05 \x01(\tR\x06cellId\x12\x14\n" +
 	"\x05state\x18\x06 \x01(\tR\x05state\x12\x14\n" +
-	"\x05error\x18\a \x01(\tR\x05error\"\x8d\x01\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\x03R\tupdatedAt\x12\x1f\n" +
+	"\vdata_shards\x18\t \x01(\x05R\n" +
+	"dataShards\x12#\n" +
+	"\rparity_shards\x18\n" +
+	" \x01(\x05R\fparityShards\"\xf2\x01\n" +
 	"\x13UpdateVolumeRequest\x12\x1b\n" +
 	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12#\n" +
 	"\rosd_addresses\x18\x02 \x03(\tR\fosdAddresses\x12\x1e\n" +
 	"\n" +
 	"generation\x18\x03 \x01(\x03R\n" +
 	"generation\x12\x14\n" +
-	"\x05state\x18\x04 \x01(\tR\x05state\"F\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x1f\n" +
+	"\vdata_shards\x18\x05 \x01(\x05R\n" +
+	"dataShards\x12#\n" +
+	"\rparity_shards\x18\x06 \x01(\x05R\fparityShards\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\x03R\tupdatedAt\"F\n" +
 	"\x14UpdateVolumeResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\"-\n" +
@@ -515,12 +680,18 @@ const file_proto_replication_proto_rawDesc = "" +
 	"\acell_id\x18\x01 \x01(\tR\x06cellId\"4\n" +
 	"\x13ListVolumesResponse\x12\x1d\n" +
 	"\n" +
-	"volume_ids\x18\x01 \x03(\tR\tvolumeIds2\xe1\x02\n" +
+	"volume_ids\x18\x01 \x03(\tR\tvolumeIds\"2\n" +
+	"\x13DeleteVolumeRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"F\n" +
+	"\x14DeleteVolumeResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error2\xb6\x03\n" +
 	"\x17ReplicationTableService\x12S\n" +
 	"\fCreateVolume\x12 .replication.CreateVolumeRequest\x1a!.replication.CreateVolumeResponse\x12J\n" +
 	"\tGetVolume\x12\x1d.replication.GetVolumeRequest\x1a\x1e.replication.GetVolumeResponse\x12S\n" +
 	"\fUpdateVolume\x12 .replication.UpdateVolumeRequest\x1a!.replication.UpdateVolumeResponse\x12P\n" +
-	"\vListVolumes\x12\x1f.replication.ListVolumesRequest\x1a .replication.ListVolumesResponseB\x1bZ\x19bharani/proto/replicationb\x06proto3"
+	"\vListVolumes\x12\x1f.replication.ListVolumesRequest\x1a .replication.ListVolumesResponse\x12S\n" +
+	"\fDeleteVolume\x12 .replication.DeleteVolumeRequest\x1a!.replication.DeleteVolumeResponseB\x1bZ\x19bharani/proto/replicationb\x06proto3"
 
 var (
 	file_proto_replication_proto_rawDescOnce sync.Once
@@ -534,7 +705,7 @@ func file_proto_replication_proto_rawDescGZIP() []byte {
 	return file_proto_replication_proto_rawDescData
 }
 
-var file_proto_replication_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_replication_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_proto_replication_proto_goTypes = []any{
 	(*CreateVolumeRequest)(nil),  // 0: replication.CreateVolumeRequest
 	(*CreateVolumeResponse)(nil), // 1: replication.CreateVolumeResponse
@@ -544,18 +715,22 @@ var file_proto_replication_proto_goTypes = []any{
 	(*UpdateVolumeResponse)(nil), // 5: replication.UpdateVolumeResponse
 	(*ListVolumesRequest)(nil),   // 6: replication.ListVolumesRequest
 	(*ListVolumesResponse)(nil),  // 7: replication.ListVolumesResponse
+	(*DeleteVolumeRequest)(nil),  // 8: replication.DeleteVolumeRequest
+	(*DeleteVolumeResponse)(nil), // 9: replication.DeleteVolumeResponse
 }
 var file_proto_replication_proto_depIdxs = []int32{
 	0, // 0: replication.ReplicationTableService.CreateVolume:input_type -> replication.CreateVolumeRequest
 	2, // 1: replication.ReplicationTableService.GetVolume:input_type -> replication.GetVolumeRequest
 	4, // 2: replication.ReplicationTableService.UpdateVolume:input_type -> replication.UpdateVolumeRequest
 	6, // 3: replication.ReplicationTableService.ListVolumes:input_type -> replication.ListVolumesRequest
-	1, // 4: replication.ReplicationTableService.CreateVolume:output_type -> replication.CreateVolumeResponse
-	3, // 5: replication.ReplicationTableService.GetVolume:output_type -> replication.GetVolumeResponse
-	5, // 6: replication.ReplicationTableService.UpdateVolume:output_type -> replication.UpdateVolumeResponse
-	7, // 7: replication.ReplicationTableService.ListVolumes:output_type -> replication.ListVolumesResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
+	8, // 4: replication.ReplicationTableService.DeleteVolume:input_type -> replication.DeleteVolumeRequest
+	1, // 5: replication.ReplicationTableService.CreateVolume:output_type -> replication.CreateVolumeResponse
+	3, // 6: replication.ReplicationTableService.GetVolume:output_type -> replication.GetVolumeResponse
+	5, // 7: replication.ReplicationTableService.UpdateVolume:output_type -> replication.UpdateVolumeResponse
+	7, // 8: replication.ReplicationTableService.ListVolumes:output_type -> replication.ListVolumesResponse
+	9, // 9: replication.ReplicationTableService.DeleteVolume:output_type -> replication.DeleteVolumeResponse
+	5, // [5:10] is the sub-list for method output_type
+	0, // [0:5] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -572,7 +747,7 @@ func file_proto_replication_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_replication_proto_rawDesc), len(file_proto_replication_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,7 @@ const (
 	ReplicationTableService_GetVolume_FullMethodName    = "/replication.ReplicationTableService/GetVolume"
 	ReplicationTableService_UpdateVolume_FullMethodName = "/replication.ReplicationTableService/UpdateVolume"
 	ReplicationTableService_ListVolumes_FullMethodName  = "/replication.ReplicationTableService/ListVolumes"
+	ReplicationTableService_DeleteVolume_FullMethodName = "/replication.ReplicationTableService/DeleteVolume"
 )
 
 // ReplicationTableServiceClient is the client API for ReplicationTableService service.
@@ -35,6 +36,7 @@ type ReplicationTableServiceClient interface {
 	GetVolume(ctx context.Context, in *GetVolumeRequest, opts ...grpc.CallOption) (*GetVolumeResponse, error)
 	UpdateVolume(ctx context.Context, in *UpdateVolumeRequest, opts ...grpc.CallOption) (*UpdateVolumeResponse, error)
 	ListVolumes(ctx context.Context, in *ListVolumesRequest, opts ...grpc.CallOption) (*ListVolumesResponse, error)
+	DeleteVolume(ctx context.Context, in *DeleteVolumeRequest, opts ...grpc.CallOption) (*DeleteVolumeResponse, error)
 }
 
 type replicationTableServiceClient struct {
@@ -85,6 +87,16 @@ func (c *replicationTableServiceClient) ListVolumes(ctx context.Context, in *Lis
 	return out, nil
 }
 
+func (c *replicationTableServiceClient) DeleteVolume(ctx context.Context, in *DeleteVolumeRequest, opts ...grpc.CallOption) (*DeleteVolumeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteVolumeResponse)
+	err := c.cc.Invoke(ctx, ReplicationTableService_DeleteVolume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ReplicationTableServiceServer is the server API for ReplicationTableService service.
 // All implementations should embed UnimplementedReplicationTableServiceServer
 // for forward compatibility.
@@ -95,6 +107,7 @@ type ReplicationTableServiceServer interface {
 	GetVolume(context.Context, *GetVolumeRequest) (*GetVolumeResponse, error)
 	UpdateVolume(context.Context, *UpdateVolumeRequest) (*UpdateVolumeResponse, error)
 	ListVolumes(context.Context, *ListVolumesRequest) (*ListVolumesResponse, error)
+	DeleteVolume(context.Context, *DeleteVolumeRequest) (*DeleteVolumeResponse, error)
 }
 
 // UnimplementedReplicationTableServiceServer should be embedded to have
@@ -116,6 +129,9 @@ func (UnimplementedReplicationTableServiceServer) UpdateVolume(context.Context,
 func (UnimplementedReplicationTableServiceServer) ListVolumes(context.Context, *ListVolumesRequest) (*ListVolumesResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ListVolumes not implemented")
 }
+func (UnimplementedReplicationTableServiceServer) DeleteVolume(context.Context, *DeleteVolumeRequest) (*DeleteVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteVolume not implemented")
+}
 func (UnimplementedReplicationTableServiceServer) testEmbeddedByValue() {}
 
 // UnsafeReplicationTableServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -208,6 +224,24 @@ func _ReplicationTableService_ListVolumes_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ReplicationTableService_DeleteVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteVolumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicationTableServiceServer).DeleteVolume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReplicationTableService_DeleteVolume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationTableServiceServer).DeleteVolume(ctx, req.(*DeleteVolumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ReplicationTableService_ServiceDesc is the grpc.ServiceDesc for ReplicationTableService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -231,6 +265,10 @@ var ReplicationTableService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListVolumes",
 			Handler:    _ReplicationTableService_ListVolumes_Handler,
 		},
+		{
+			MethodName: "DeleteVolume",
+			Handler:    _ReplicationTableService_DeleteVolume_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/replication.proto",